@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/artifacts"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// showArtifacts lists the artifacts published for a ticket, or for every
+// ticket when ticketID is empty
+func showArtifacts(ticketID string) {
+	cfg := loadCIConfig()
+
+	records, err := artifacts.LoadHistory(cfg.Metrics.OutputPath, ticketID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	if len(records) == 0 {
+		if ticketID != "" {
+			fmt.Println(i18n.T("artifacts.none_for", ticketID))
+		} else {
+			fmt.Println(i18n.T("artifacts.none"))
+		}
+		return
+	}
+
+	for _, record := range records {
+		if record.Commit != "" {
+			fmt.Println(i18n.T("artifacts.record_at", record.TicketID, shortCommit(record.Commit), timefmt.Timestamp(record.PublishedAt)))
+		} else {
+			fmt.Println(i18n.T("artifacts.record", record.TicketID, timefmt.Timestamp(record.PublishedAt)))
+		}
+		for _, artifact := range record.Artifacts {
+			fmt.Printf("   %-40s %10d  %s\n", artifact.Path, artifact.Size, artifact.URL)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/audit"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// showAuditLog prints the most recent control commands and who issued them
+// limit of zero shows the whole journal
+func showAuditLog(limit int) {
+	cfg := loadCIConfig()
+
+	entries, err := audit.Load(cfg.State.Path, loadCipher(cfg))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println(i18n.T("audit.none"))
+		return
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	for _, entry := range entries {
+		status := "✅"
+		if !entry.OK {
+			status = "❌"
+		}
+		fmt.Println(i18n.T("audit.entry", status, timefmt.Timestamp(entry.Time), entry.Command, formatArgs(entry.Args), entry.Caller))
+		if entry.Error != "" {
+			fmt.Printf("   %s\n", entry.Error)
+		}
+	}
+}
+
+// formatArgs renders command arguments as sorted key=value pairs
+func formatArgs(args map[string]string) string {
+	pairs := make([]string, 0, len(args))
+	for k, v := range args {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
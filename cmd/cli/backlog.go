@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/backlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/config"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// exportBacklog writes a snapshot of the backlog to a tar file
+func exportBacklog(path string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.config_failed", err))
+		os.Exit(1)
+	}
+
+	cipher := loadCipher(cfg)
+	nodes, err := collectGraphNodes(cfg, cipher)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.collect_failed", err))
+		os.Exit(1)
+	}
+
+	statuses := make(map[string]string)
+	for _, n := range nodes {
+		statuses[n.Ticket.ID] = n.Status
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.create_failed", path, err))
+		os.Exit(1)
+	}
+
+	manifest, err := backlog.Export(f, cfg.Scheduler.BacklogPath, cfg.State.Path, statuses, cipher)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		fmt.Fprintln(os.Stderr, i18n.T("backlog.export_failed", err))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.T("backlog.exported", len(manifest.Entries), manifest.DeadLetters, path))
+}
+
+// importBacklog restores a backlog snapshot from a tar file
+func importBacklog(path string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.config_failed", err))
+		os.Exit(1)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.open_failed", path, err))
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	result, err := backlog.Import(f, cfg.Scheduler.BacklogPath, cfg.State.Path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("backlog.import_failed", err))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.T("backlog.imported", timefmt.Timestamp(result.Manifest.CreatedAt)))
+	fmt.Printf("   %s\n", i18n.T("backlog.requeued", result.Requeued))
+	fmt.Printf("   %s\n", i18n.T("backlog.restored", result.Restored))
+	fmt.Printf("   %s\n", i18n.T("backlog.dead_letters", result.DeadLetters))
+	if result.Skipped > 0 {
+		fmt.Printf("   %s\n", i18n.T("backlog.skipped", result.Skipped))
+	}
+}
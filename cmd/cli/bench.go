@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/bench"
+	"github.com/brettsmith212/amp-orchestrator/internal/config"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+)
+
+// runBenchmark runs an experiment and prints (or writes) a comparison report
+func runBenchmark(experimentPath, reportPath string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.config_failed", err))
+		os.Exit(1)
+	}
+
+	exp, err := bench.LoadExperiment(experimentPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("bench.invalid", err))
+		os.Exit(1)
+	}
+
+	t, err := ticket.Load(exp.Ticket)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("enqueue.load_failed", err))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.T("bench.running", len(exp.Variants), exp.Runs, t.ID))
+
+	report, err := bench.Run(exp, t, worker.Config{
+		RepoPath:    cfg.Repository.Path,
+		WorkDir:     cfg.Repository.Workdir,
+		CIStatusDir: cfg.CI.StatusPath,
+		SkipCI:      cfg.Testing.SkipCI,
+		SkipAmp:     cfg.Testing.SkipAmp,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("bench.failed", err))
+		os.Exit(1)
+	}
+
+	markdown := report.Markdown()
+	if reportPath == "" {
+		fmt.Print(markdown)
+		return
+	}
+
+	if err := os.WriteFile(reportPath, []byte(markdown), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("bench.write_failed", err))
+		os.Exit(1)
+	}
+	fmt.Println(i18n.T("bench.done", report.ID, reportPath))
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/config"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// fullCommitHash matches a complete SHA-1 commit hash, which can be waited on
+// before anything about it is known locally
+var fullCommitHash = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// defaultCIWaitTimeout bounds `ci wait` when no timeout is given
+const defaultCIWaitTimeout = 10 * time.Minute
+
+// ciStatusReader reads statuses from the configured status store, so
+// tickets run by remote workers are found too
+func ciStatusReader(cfg *config.Config) *ci.StatusReader {
+	store, err := ci.NewStatusStore(cfg.CI.StatusStore, cfg.CI.StatusPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("ci.store_failed", err))
+		os.Exit(1)
+	}
+	return ci.NewStoreStatusReader(store)
+}
+
+// showCIStatus prints the CI status for a commit or ticket, or its full
+// tier's; it exits non-zero unless CI passed, so it can gate merges
+func showCIStatus(ref string, asJSON, full bool) {
+	cfg := loadCIConfig()
+	reader := ciStatusReader(cfg)
+
+	commitHash, err := resolveCICommit(cfg, reader, ref)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	if full {
+		reader = ci.NewStatusReader(ci.FullStatusDir(cfg.CI.StatusPath))
+	}
+	status, err := reader.GetStatus(commitHash)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	printCIStatus(status, asJSON)
+	if !status.Passed() {
+		os.Exit(1)
+	}
+}
+
+// waitForCIStatus blocks until a CI status, or its full tier's, appears for
+// a commit or ticket
+// Exit codes: 0 passed, 1 failed or error, 2 timed out
+func waitForCIStatus(ref string, timeout time.Duration, full bool) {
+	cfg := loadCIConfig()
+	reader := ciStatusReader(cfg)
+
+	commitHash, err := resolveCICommit(cfg, reader, ref)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	if full {
+		reader = ci.NewStatusReader(ci.FullStatusDir(cfg.CI.StatusPath))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	fmt.Fprintln(os.Stderr, i18n.T("ci.waiting", timeout, shortCommit(commitHash)))
+	status, err := reader.WaitForStatus(ctx, commitHash, time.Second)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		if errors.Is(err, context.DeadlineExceeded) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+
+	printCIStatus(status, false)
+	if !status.Passed() {
+		os.Exit(1)
+	}
+}
+
+// rerunCI asks the daemon to re-trigger CI for a branch or ticket
+func rerunCI(target string) {
+	cfg := loadCIConfig()
+
+	client := connectDaemon(cfg)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := client.SendCommand(ctx, "ci_rerun", map[string]string{"target": target})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	if !response.OK {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", response.Error))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.T("cli.done", response.Message))
+	fmt.Printf("   %s\n", i18n.T("ci.follow_hint", os.Args[0], target))
+}
+
+// connectDaemon connects to the daemon's IPC socket, or ipc.websocket.url,
+// authenticating with $ORCHESTRATOR_IPC_TOKEN when it is set, and exits on
+// failure
+func connectDaemon(cfg *config.Config) *ipc.Client {
+	client, err := dialDaemon(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		fmt.Fprintln(os.Stderr, i18n.T("cli.daemon_hint"))
+		os.Exit(1)
+	}
+	return client
+}
+
+// dialDaemon connects to the daemon like connectDaemon but returns failures
+func dialDaemon(cfg *config.Config) (*ipc.Client, error) {
+	ipcSocketPath := cfg.IPC.SocketPath
+	if ipcSocketPath == "" {
+		ipcSocketPath = "~/.orchestrator.sock"
+	}
+
+	client := ipc.NewClient(ipcSocketPath)
+	if ws := cfg.IPC.WebSocket; ws.URL != "" {
+		// A daemon on another machine, reached through its WebSocket bridge
+		token := os.Getenv(ws.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("%s must hold the token of %s", ws.TokenEnv, ws.URL)
+		}
+		client = ipc.NewWebSocketClient(ws.URL, token)
+	}
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+
+	if token := os.Getenv(ipc.TokenEnv); token != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := client.Authenticate(ctx, token); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to authenticate with daemon: %w", err)
+		}
+	}
+
+	if cfg.IPC.Framing != "" && cfg.IPC.Framing != ipc.FramingJSON {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := client.NegotiateFraming(ctx, cfg.IPC.Framing); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("cli.framing_declined", cfg.IPC.Framing, err))
+		}
+	}
+
+	return client, nil
+}
+
+// showFlakyTests lists test packages that have passed only on retry
+func showFlakyTests() {
+	cfg := loadCIConfig()
+
+	stats, err := ci.LoadFlakyStats(cfg.Metrics.OutputPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	if len(stats) == 0 {
+		fmt.Println(i18n.T("ci.flaky.none"))
+		return
+	}
+
+	fmt.Println(i18n.T("ci.flaky.title", len(stats)))
+	for _, stat := range stats {
+		fmt.Printf("   %s\n", i18n.T("ci.flaky.package", stat.Package, stat.Count, timefmt.Timestamp(stat.LastSeen)))
+		if len(stat.Tickets) > 0 {
+			fmt.Printf("      %s\n", i18n.T("ci.flaky.tickets", strings.Join(stat.Tickets, ", ")))
+		}
+	}
+}
+
+// loadCIConfig loads the config or exits
+func loadCIConfig() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.config_failed", err))
+		fmt.Fprintln(os.Stderr, i18n.T("cli.config_hint"))
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// resolveCICommit turns a commit hash, abbreviated hash or ticket ID into
+// the full commit hash used as the CI status key
+func resolveCICommit(cfg *config.Config, reader *ci.StatusReader, ref string) (string, error) {
+	if reader.HasStatus(ref) || fullCommitHash.MatchString(ref) {
+		return ref, nil
+	}
+
+	repo := gitutils.NewRepo(cfg.Repository.Path)
+	if branches, err := repo.ListBranches(); err == nil {
+		if candidates := gitutils.FindAgentBranches(branches, ref); len(candidates) > 0 {
+			return resolveAgentBranches(repo, reader, ref, candidates)
+		}
+	}
+
+	// Fall back to the ticket's latest recorded CI attempt, e.g. after its branch is deleted
+	if statuses, err := reader.GetByTicket(ref); err == nil && len(statuses) > 0 {
+		return statuses[len(statuses)-1].Commit, nil
+	}
+
+	commitHash, err := repo.GetBranchCommit(ref)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a known commit or ticket ID", ref)
+	}
+	return commitHash, nil
+}
+
+// resolveAgentBranches picks the commit of a ticket's agent branch. When
+// several workers left a branch for the same attempt, the one whose CI
+// status is newest wins; without any status the choice is left to the user.
+func resolveAgentBranches(repo *gitutils.GitRepo, reader *ci.StatusReader, ticketID string, candidates []string) (string, error) {
+	var newest *ci.Status
+	var newestCommit string
+	for _, branch := range candidates {
+		commitHash, err := repo.GetBranchCommit(branch)
+		if err != nil {
+			return "", err
+		}
+		if len(candidates) == 1 {
+			return commitHash, nil
+		}
+		if status, err := reader.GetStatus(commitHash); err == nil && (newest == nil || status.Timestamp.After(newest.Timestamp)) {
+			newest, newestCommit = status, commitHash
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("%s has several agent branches and none has a CI status; name one of: %s", ticketID, strings.Join(candidates, ", "))
+	}
+	return newestCommit, nil
+}
+
+// printCIStatus prints a status as indented JSON or a readable summary
+func printCIStatus(status *ci.Status, asJSON bool) {
+	if asJSON {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("ci.format_failed", err))
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	icon := "❌"
+	switch status.Status {
+	case "PASS":
+		icon = "✅"
+	case "FLAKY":
+		icon = "⚠️"
+	case "SKIPPED":
+		icon = "⏭️"
+	}
+
+	fmt.Println(i18n.T("ci.status.title", icon, status.Status, shortCommit(status.Commit)))
+	fmt.Printf("   %s\n", i18n.T("ci.status.commit", status.Commit))
+	if status.Ref != "" {
+		fmt.Printf("   %s\n", i18n.T("ci.status.ref", status.Ref))
+	}
+	if status.TicketID != "" {
+		fmt.Printf("   %s\n", i18n.T("ci.status.ticket", status.TicketID))
+	}
+	if status.Profile != "" {
+		fmt.Printf("   %s\n", i18n.T("ci.status.profile", status.Profile))
+	}
+	if status.Tier != "" {
+		fmt.Printf("   %s\n", i18n.T("ci.status.tier", status.Tier))
+	}
+	if !status.Timestamp.IsZero() {
+		fmt.Printf("   %s\n", i18n.T("ci.status.finished", timefmt.Timestamp(status.Timestamp)))
+	}
+	if len(status.Flaky) > 0 {
+		fmt.Printf("   %s\n", i18n.T("ci.status.flaky", strings.Join(status.Flaky, ", ")))
+	}
+	if len(status.Cells) > 0 {
+		fmt.Printf("   %s\n", i18n.T("ci.status.matrix"))
+		for _, cell := range status.Cells {
+			note := ""
+			if cell.AllowFailure {
+				note = i18n.T("ci.status.allowed_to_fail")
+			}
+			fmt.Printf("     %-20s %s%s\n", cell.Name, cell.Status, note)
+		}
+	}
+	if output := strings.TrimSpace(status.Output); output != "" {
+		fmt.Printf("   %s\n", i18n.T("ci.status.output"))
+		for _, line := range strings.Split(output, "\n") {
+			fmt.Printf("     %s\n", line)
+		}
+	}
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commitHash string) string {
+	if len(commitHash) > 8 {
+		return commitHash[:8]
+	}
+	return commitHash
+}
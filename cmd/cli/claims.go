@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/claim"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// showClaims lists which daemon owns each ticket in the shared repository
+func showClaims() {
+	cfg := loadCIConfig()
+
+	claims, err := claim.List(cfg.Repository.Path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	if len(claims) == 0 {
+		fmt.Println(i18n.T("claims.none"))
+		return
+	}
+
+	lease := time.Duration(cfg.Coordination.LeaseSeconds) * time.Second
+	now := time.Now()
+	for _, c := range claims {
+		state := i18n.T("claims.held")
+		switch {
+		case c.Done:
+			state = i18n.T("claims.done")
+		case c.Expired(lease, now):
+			state = i18n.T("claims.expired")
+		}
+		fmt.Println(i18n.T("claims.entry", state, c.TicketID, c.Node, timefmt.Timestamp(c.ClaimedAt)))
+	}
+}
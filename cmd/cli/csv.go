@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/backlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/config"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/search"
+)
+
+// exportCSV writes every known ticket to a CSV file, or stdout when path is
+// empty, most urgent first
+func exportCSV(path string) {
+	cfg := loadCIConfig()
+	results := collectTickets(cfg)
+
+	var rows []backlog.CSVRow
+	for _, r := range results {
+		// Tickets seen only in the completion history have nothing to plan with
+		if r.Ticket.Title == "" {
+			continue
+		}
+		rows = append(rows, backlog.CSVRow{Ticket: r.Ticket, Status: r.Status})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Ticket.Priority != rows[j].Ticket.Priority {
+			return rows[i].Ticket.Priority < rows[j].Ticket.Priority
+		}
+		return rows[i].Ticket.ID < rows[j].Ticket.ID
+	})
+
+	var out io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("cli.create_failed", path, err))
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := backlog.ExportCSV(out, rows); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("csv.export_failed", err))
+		os.Exit(1)
+	}
+	if path != "" {
+		fmt.Println(i18n.T("csv.exported", len(rows), path))
+	}
+}
+
+// importCSV writes the queued rows of a CSV file to the backlog as YAML
+// tickets
+func importCSV(path string) {
+	cfg := loadCIConfig()
+	known := make(map[string]string)
+	for _, r := range collectTickets(cfg) {
+		known[r.Ticket.ID] = r.Status
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.open_failed", path, err))
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	result, err := backlog.ImportCSV(f, cfg.Scheduler.BacklogPath, known)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("csv.import_failed", path, err))
+		os.Exit(1)
+	}
+	fmt.Println(i18n.T("csv.imported", path))
+	fmt.Printf("   %s\n", i18n.T("csv.created", result.Created))
+	fmt.Printf("   %s\n", i18n.T("csv.updated", result.Updated))
+	if result.Skipped > 0 {
+		fmt.Printf("   %s\n", i18n.T("csv.skipped", result.Skipped))
+	}
+}
+
+// collectTickets reads every ticket the orchestrator knows about
+func collectTickets(cfg *config.Config) []search.Result {
+	results, err := search.Collect(search.Paths{
+		BacklogPath: cfg.Scheduler.BacklogPath,
+		StateDir:    cfg.State.Path,
+		MetricsDir:  cfg.Metrics.OutputPath,
+	}, loadCipher(cfg))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.collect_failed", err))
+		os.Exit(1)
+	}
+	return results
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/config"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/graph"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/watch"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// renderGraph prints the dependency/lock graph of the backlog
+func renderGraph(format string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.config_failed", err))
+		fmt.Fprintln(os.Stderr, i18n.T("cli.config_hint"))
+		os.Exit(1)
+	}
+
+	nodes, err := collectGraphNodes(cfg, loadCipher(cfg))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.collect_failed", err))
+		os.Exit(1)
+	}
+
+	g := graph.New(nodes)
+	switch format {
+	case "dot":
+		fmt.Print(g.DOT())
+	case "mermaid":
+		fmt.Print(g.Mermaid())
+	default:
+		fmt.Fprintln(os.Stderr, i18n.T("graph.unknown_format", format))
+		os.Exit(1)
+	}
+}
+
+// collectGraphNodes gathers tickets from the backlog and derives their status
+// Tickets still in the backlog are queued; processed tickets are resolved
+// against agent branches and their CI results
+func collectGraphNodes(cfg *config.Config, cipher *encryption.Cipher) ([]graph.Node, error) {
+	var nodes []graph.Node
+
+	ignore, err := watch.LoadIgnore(cfg.Scheduler.BacklogPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := loadTicketFiles(cfg.Scheduler.BacklogPath, ignore, cipher)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range pending {
+		nodes = append(nodes, graph.Node{Ticket: t, Status: graph.StatusQueued})
+	}
+
+	processed, err := loadTicketFiles(filepath.Join(cfg.Scheduler.BacklogPath, "processed"), nil, cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := gitutils.NewRepo(cfg.Repository.Path)
+	branches, err := repo.ListBranches()
+	if err != nil {
+		// Without the repository we can only report what the backlog knows
+		branches = nil
+	}
+	statusReader := ci.NewStatusReader(cfg.CI.StatusPath)
+
+	for _, t := range processed {
+		nodes = append(nodes, graph.Node{
+			Ticket: t,
+			Status: processedStatus(t.ID, branches, repo, statusReader),
+		})
+	}
+
+	return nodes, nil
+}
+
+// processedStatus determines the status of a ticket the daemon has picked up
+func processedStatus(ticketID string, branches []string, repo *gitutils.GitRepo, statusReader *ci.StatusReader) string {
+	branch := gitutils.FindAgentBranch(branches, ticketID)
+	if branch == "" {
+		return graph.StatusQueued
+	}
+
+	commitHash, err := repo.GetBranchCommit(branch)
+	if err != nil || !statusReader.HasStatus(commitHash) {
+		return graph.StatusInFlight
+	}
+
+	passing, err := statusReader.IsPassing(commitHash)
+	if errors.Is(err, ci.ErrStatusPending) {
+		return graph.StatusInFlight
+	}
+	if err != nil {
+		return graph.StatusUnknown
+	}
+	if passing {
+		return graph.StatusCompleted
+	}
+	return graph.StatusFailed
+}
+
+// loadTicketFiles loads every YAML ticket in a directory, skipping invalid
+// files and those matched by ignore; encrypted files are read with cipher
+func loadTicketFiles(dir string, ignore *watch.IgnoreMatcher, cipher *encryption.Cipher) ([]*ticket.Ticket, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var tickets []*ticket.Ticket
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		if ignore.Match(entry.Name(), false) {
+			continue
+		}
+
+		t, err := cipher.LoadTicket(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("cli.skipping", entry.Name(), err))
+			continue
+		}
+		tickets = append(tickets, t)
+	}
+
+	return tickets, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/artifacts"
+	"github.com/brettsmith212/amp-orchestrator/internal/config"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// loadCipher returns the configured cipher, exiting if the key cannot be loaded
+func loadCipher(cfg *config.Config) *encryption.Cipher {
+	cipher, err := encryption.Load(cfg.Encryption)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("inspect.key_failed", err))
+		os.Exit(1)
+	}
+	return cipher
+}
+
+// inspectTicket prints a ticket from the backlog or its archive, decrypting
+// it if needed, along with its published artifacts
+// Given a path to a file instead, such as a downloaded agent log, it prints
+// the decrypted contents
+func inspectTicket(target string) {
+	cfg := loadCIConfig()
+	cipher := loadCipher(cfg)
+
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		data, err := cipher.ReadFile(target)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("cli.read_failed", target, err))
+			os.Exit(1)
+		}
+		os.Stdout.Write(data)
+		return
+	}
+
+	path, err := findTicketFile(cfg.Scheduler.BacklogPath, target, cipher)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("inspect.not_found_error", target, cfg.Scheduler.BacklogPath, err))
+		os.Exit(1)
+	}
+	if path == "" {
+		fmt.Fprintln(os.Stderr, i18n.T("inspect.not_found", target, cfg.Scheduler.BacklogPath))
+		os.Exit(1)
+	}
+
+	data, err := cipher.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.read_failed", path, err))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.T("inspect.file", path))
+	fmt.Println(string(data))
+	printProvenance(data)
+
+	records, err := artifacts.LoadHistory(cfg.Metrics.OutputPath, target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("inspect.artifacts_failed", err))
+		return
+	}
+	for _, record := range records {
+		fmt.Println(i18n.T("inspect.artifacts", timefmt.Timestamp(record.PublishedAt)))
+		for _, artifact := range record.Artifacts {
+			fmt.Printf("   %-40s %s\n", artifact.Path, artifact.URL)
+		}
+	}
+}
+
+// printProvenance summarises who enqueued a ticket and whether the file
+// still matches its recorded checksum
+func printProvenance(data []byte) {
+	t, err := ticket.LoadFromBytes(data)
+	if err != nil || t.Provenance == nil {
+		return
+	}
+	p := t.Provenance
+	fmt.Println(i18n.T("inspect.provenance", p.EnqueuedBy, p.Source, timefmt.Timestamp(p.EnqueuedAt)))
+	if err := t.VerifyProvenance(data); err != nil {
+		fmt.Printf("   %s\n", i18n.T("cli.warning", err))
+		return
+	}
+	fmt.Printf("   %s\n", i18n.T("inspect.verified", p.Checksum))
+}
+
+// findTicketFile returns the file holding ticketID, looking in the backlog,
+// then its processed and rejected archives
+// If it is not found and some files could not be decrypted, ErrNoKey is returned
+func findTicketFile(backlogPath, ticketID string, cipher *encryption.Cipher) (string, error) {
+	var skipped error
+	for _, dir := range []string{
+		backlogPath,
+		filepath.Join(backlogPath, "processed"),
+		filepath.Join(backlogPath, "rejected"),
+	} {
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, path := range matches {
+				t, err := cipher.LoadTicket(path)
+				if errors.Is(err, encryption.ErrNoKey) {
+					skipped = err
+					continue
+				}
+				if err == nil && t.ID == ticketID {
+					return path, nil
+				}
+			}
+		}
+	}
+	return "", skipped
+}
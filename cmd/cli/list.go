@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// listRefreshInterval is how often --watch redraws the table
+const listRefreshInterval = 2 * time.Second
+
+// listTickets prints the queued and in-flight tickets as a table, redrawing
+// it until interrupted with --watch
+func listTickets(args []string) {
+	sortBy := queue.SortPriority
+	watch := false
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--watch":
+			watch = true
+		case arg == "--sort" && i+1 < len(args):
+			i++
+			sortBy = args[i]
+		case strings.HasPrefix(arg, "--sort="):
+			sortBy = strings.TrimPrefix(arg, "--sort=")
+		default:
+			fmt.Fprintln(os.Stderr, i18n.T("usage.list", os.Args[0]))
+			os.Exit(1)
+		}
+	}
+	if err := queue.SortListings(nil, sortBy); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	cfg := loadCIConfig()
+	client := connectDaemon(cfg)
+	defer client.Close()
+
+	if !watch {
+		listings, err := fetchListings(client)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+			os.Exit(1)
+		}
+		queue.SortListings(listings, sortBy)
+		printListings(listings, time.Now())
+		return
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(listRefreshInterval)
+	defer ticker.Stop()
+	for {
+		listings, err := fetchListings(client)
+		fmt.Print("\033[H\033[2J")
+		if err != nil {
+			fmt.Println(i18n.T("cli.error", err))
+		} else {
+			queue.SortListings(listings, sortBy)
+			printListings(listings, time.Now())
+		}
+		fmt.Printf("\n%s\n", i18n.T("list.refreshed", sortBy, timefmt.Clock(time.Now())))
+
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchListings asks the daemon for its queued and in-flight tickets
+func fetchListings(client *ipc.Client) ([]queue.Listing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := client.SendCommand(ctx, "list", nil)
+	if err != nil {
+		return nil, err
+	}
+	if !response.OK {
+		return nil, errors.New(response.Error)
+	}
+	var listings []queue.Listing
+	if err := json.Unmarshal([]byte(response.Message), &listings); err != nil {
+		return nil, fmt.Errorf("invalid ticket list from daemon: %w", err)
+	}
+	return listings, nil
+}
+
+// printListings writes the table, one row per ticket
+func printListings(listings []queue.Listing, now time.Time) {
+	if len(listings) == 0 {
+		fmt.Println(i18n.T("list.none"))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, i18n.T("list.header"))
+	for _, l := range listings {
+		worker, phase := "-", i18n.T("list.queued")
+		if l.WorkerID != 0 {
+			worker = strconv.Itoa(l.WorkerID)
+			phase = l.Phase
+			if phase == "" {
+				phase = i18n.T("list.starting")
+			}
+		}
+		estimate := "-"
+		if l.EstimateMin > 0 {
+			estimate = (time.Duration(l.EstimateMin) * time.Minute).String()
+		}
+		fmt.Fprintf(w, "%s\tP%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", l.ID, l.Priority, formatAge(now.Sub(l.EnqueuedAt)),
+			estimate, joinOrDash(l.Locks), joinOrDash(l.Dependencies), worker, phase, l.Title)
+	}
+	w.Flush()
+}
+
+// formatAge shortens a duration to its largest unit, e.g. 3d or 45m
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return "<1m"
+	}
+}
+
+func joinOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ",")
+}
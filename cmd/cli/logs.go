@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticketlog"
+)
+
+// logPollInterval is how often --follow checks the log for new lines
+const logPollInterval = 500 * time.Millisecond
+
+// showTicketLogs prints the log of a ticket's latest run, or of every run
+// with --all; -n keeps only the last lines, and -f then keeps printing lines
+// as they are written, moving on to the next run's log when one starts
+func showTicketLogs(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, i18n.T("usage.logs", os.Args[0]))
+		os.Exit(1)
+	}
+	var ticketID string
+	all, follow, lines := false, false, 0
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--all":
+			all = true
+		case arg == "-f" || arg == "--follow":
+			follow = true
+		case arg == "-n" && i+1 < len(args):
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 1 {
+				usage()
+			}
+			lines = n
+		case !strings.HasPrefix(arg, "-") && ticketID == "":
+			ticketID = arg
+		default:
+			usage()
+		}
+	}
+	if ticketID == "" {
+		usage()
+	}
+
+	cfg := loadCIConfig()
+	cipher := loadCipher(cfg)
+
+	files, err := ticketlog.Files(cfg.TicketLogs.Path, ticketID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	if len(files) == 0 && !follow {
+		fmt.Println(i18n.T("logs.none", ticketID, cfg.TicketLogs.Path))
+		if !cfg.TicketLogs.Enabled {
+			fmt.Println(i18n.T("logs.disabled"))
+		}
+		return
+	}
+	if !all && len(files) > 1 {
+		files = files[len(files)-1:]
+	}
+
+	var offset int64
+	for i, path := range files {
+		if len(files) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("==> %s <==\n", path)
+		}
+		if offset, err = printLog(path, cipher, lines); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+			os.Exit(1)
+		}
+	}
+	if !follow {
+		return
+	}
+
+	current := ""
+	if len(files) > 0 {
+		current = files[len(files)-1]
+	}
+	followLog(cfg.TicketLogs.Path, ticketID, current, offset, cipher)
+}
+
+// printLog prints a log file, or its last lines when lines is above zero,
+// and returns how much of it was read
+func printLog(path string, cipher *encryption.Cipher, lines int) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	consumed, err := ticketlog.Copy(&out, f, cipher)
+	if err != nil {
+		return consumed, fmt.Errorf("%s: %w", path, err)
+	}
+	text := out.String()
+	if lines > 0 {
+		all := strings.SplitAfter(text, "\n")
+		if all[len(all)-1] == "" {
+			all = all[:len(all)-1]
+		}
+		if len(all) > lines {
+			text = strings.Join(all[len(all)-lines:], "")
+		}
+	}
+	fmt.Print(text)
+	return consumed, nil
+}
+
+// followLog prints lines appended to a ticket's latest log until
+// interrupted, switching to a newer run's log once it appears
+func followLog(root, ticketID, current string, offset int64, cipher *encryption.Cipher) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+
+		if current != "" {
+			consumed, err := copyFrom(current, offset, cipher)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+				os.Exit(1)
+			}
+			offset += consumed
+		}
+
+		files, err := ticketlog.Files(root, ticketID)
+		if err != nil || len(files) == 0 || files[len(files)-1] == current {
+			continue
+		}
+		current, offset = files[len(files)-1], 0
+		fmt.Printf("\n==> %s <==\n", current)
+	}
+}
+
+// copyFrom prints the complete lines of a log file past offset
+func copyFrom(path string, offset int64, cipher *encryption.Cipher) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return ticketlog.Copy(os.Stdout, f, cipher)
+}
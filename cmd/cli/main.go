@@ -6,9 +6,14 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/brettsmith212/amp-orchestrator"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
 	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
 	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
 )
@@ -31,54 +36,281 @@ func main() {
 		
 	case "validate":
 		if len(os.Args) != 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s validate <ticket-file.yaml>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, i18n.T("usage.validate", os.Args[0]))
 			os.Exit(1)
 		}
 		validateTicket(os.Args[2])
 		
 	case "enqueue":
 		if len(os.Args) != 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s enqueue <ticket-file.yaml>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, i18n.T("usage.enqueue", os.Args[0]))
 			os.Exit(1)
 		}
 		enqueueTicket(os.Args[2])
 		
+	case "cancel":
+		if len(os.Args) != 3 {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.cancel", os.Args[0]))
+			os.Exit(1)
+		}
+		cancelTicket(os.Args[2])
+
+	case "approve":
+		if len(os.Args) != 3 && len(os.Args) != 4 {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.approve", os.Args[0]))
+			os.Exit(1)
+		}
+		version := ""
+		if len(os.Args) == 4 {
+			version = os.Args[3]
+		}
+		approveTicket(os.Args[2], version)
+		
 	case "tui":
 		startTUI()
 		
+	case "graph":
+		format := "dot"
+		if len(os.Args) > 3 {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.graph", os.Args[0]))
+			os.Exit(1)
+		}
+		if len(os.Args) == 3 {
+			format = os.Args[2]
+		}
+		renderGraph(format)
+		
+	case "backlog":
+		if len(os.Args) != 4 || (os.Args[2] != "export" && os.Args[2] != "import") {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.backlog", os.Args[0]))
+			os.Exit(1)
+		}
+		if os.Args[2] == "export" {
+			exportBacklog(os.Args[3])
+		} else {
+			importBacklog(os.Args[3])
+		}
+		
+	case "export":
+		if len(os.Args) < 3 || len(os.Args) > 4 || os.Args[2] != "csv" {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.export", os.Args[0]))
+			os.Exit(1)
+		}
+		var path string
+		if len(os.Args) == 4 {
+			path = os.Args[3]
+		}
+		exportCSV(path)
+		
+	case "import":
+		if len(os.Args) != 4 || os.Args[2] != "csv" {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.import", os.Args[0]))
+			os.Exit(1)
+		}
+		importCSV(os.Args[3])
+		
+	case "bench":
+		if len(os.Args) < 3 || len(os.Args) > 4 {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.bench", os.Args[0]))
+			os.Exit(1)
+		}
+		reportPath := ""
+		if len(os.Args) == 4 {
+			reportPath = os.Args[3]
+		}
+		runBenchmark(os.Args[2], reportPath)
+		
+	case "artifacts":
+		if len(os.Args) > 3 {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.artifacts", os.Args[0]))
+			os.Exit(1)
+		}
+		ticketID := ""
+		if len(os.Args) == 3 {
+			ticketID = os.Args[2]
+		}
+		showArtifacts(ticketID)
+		
+	case "audit":
+		limit := 20
+		if len(os.Args) > 3 {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.audit", os.Args[0]))
+			os.Exit(1)
+		}
+		if len(os.Args) == 3 {
+			if os.Args[2] == "all" {
+				limit = 0
+			} else if n, err := strconv.Atoi(os.Args[2]); err == nil && n > 0 {
+				limit = n
+			} else {
+				fmt.Fprintln(os.Stderr, i18n.T("cli.invalid_count", os.Args[2]))
+				os.Exit(1)
+			}
+		}
+		showAuditLog(limit)
+		
+	case "claims":
+		showClaims()
+		
+	case "search":
+		searchTickets(os.Args[2:])
+		
+	case "list":
+		listTickets(os.Args[2:])
+		
+	case "watch":
+		watchEvents(os.Args[2:])
+		
+	case "status":
+		showStatus(os.Args[2:])
+		
+	case "metrics":
+		switch {
+		case len(os.Args) == 3 && os.Args[2] == "report":
+			showMetricsReport()
+		case len(os.Args) >= 3 && os.Args[2] == "summary":
+			writeWeeklySummary(os.Args[3:])
+		default:
+			fmt.Fprintln(os.Stderr, i18n.T("usage.metrics", os.Args[0]))
+			os.Exit(1)
+		}
+		
+	case "timeline":
+		if len(os.Args) != 3 {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.timeline", os.Args[0]))
+			os.Exit(1)
+		}
+		showTimeline(os.Args[2])
+		
+	case "logs":
+		showTicketLogs(os.Args[2:])
+		
+	case "sign":
+		switch {
+		case len(os.Args) == 4 && os.Args[2] == "keygen":
+			generateSigningKey(os.Args[3])
+		case len(os.Args) == 4:
+			signTicketFile(os.Args[2], os.Args[3])
+		default:
+			fmt.Fprintln(os.Stderr, i18n.T("usage.sign", os.Args[0]))
+			os.Exit(1)
+		}
+		
+	case "inspect":
+		if len(os.Args) != 3 {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.inspect", os.Args[0]))
+			os.Exit(1)
+		}
+		inspectTicket(os.Args[2])
+		
+	case "ci":
+		ciUsage := func() {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.ci", os.Args[0]))
+			os.Exit(1)
+		}
+		if len(os.Args) == 3 && os.Args[2] == "flaky" {
+			showFlakyTests()
+			return
+		}
+		if len(os.Args) < 4 {
+			ciUsage()
+		}
+		// --full reads the full CI tier, which gates merges under ci.quick_tests
+		var ciArgs []string
+		asJSON, full := false, false
+		for _, arg := range os.Args[4:] {
+			switch arg {
+			case "--json":
+				asJSON = true
+			case "--full":
+				full = true
+			default:
+				ciArgs = append(ciArgs, arg)
+			}
+		}
+		switch os.Args[2] {
+		case "status":
+			if len(ciArgs) > 0 {
+				ciUsage()
+			}
+			showCIStatus(os.Args[3], asJSON, full)
+		case "wait":
+			if len(ciArgs) > 1 || asJSON {
+				ciUsage()
+			}
+			timeout := defaultCIWaitTimeout
+			if len(ciArgs) == 1 {
+				d, err := time.ParseDuration(ciArgs[0])
+				if err != nil || d <= 0 {
+					fmt.Fprintln(os.Stderr, i18n.T("ci.invalid_timeout", ciArgs[0]))
+					os.Exit(1)
+				}
+				timeout = d
+			}
+			waitForCIStatus(os.Args[3], timeout, full)
+		case "rerun":
+			if len(os.Args) != 4 {
+				ciUsage()
+			}
+			rerunCI(os.Args[3])
+		default:
+			ciUsage()
+		}
+
+	case "worker":
+		workerUsage := func() {
+			fmt.Fprintln(os.Stderr, i18n.T("usage.worker", os.Args[0]))
+			os.Exit(1)
+		}
+		if len(os.Args) < 4 {
+			workerUsage()
+		}
+		switch os.Args[2] {
+		case "restart":
+			if len(os.Args) != 4 {
+				workerUsage()
+			}
+			restartWorker(os.Args[3])
+		case "pause":
+			pauseWorker(os.Args[3], strings.Join(os.Args[4:], " "))
+		case "resume":
+			if len(os.Args) != 4 {
+				workerUsage()
+			}
+			resumeWorker(os.Args[3])
+		default:
+			workerUsage()
+		}
+		
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+		fmt.Fprintln(os.Stderr, i18n.T("cli.unknown_command", command))
 		printUsage()
 		os.Exit(1)
 	}
 }
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s <command> [args]\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "\nCommands:\n")
-	fmt.Fprintf(os.Stderr, "  init [name]      Initialize a new orchestrator project\n")
-	fmt.Fprintf(os.Stderr, "  validate <file>  Validate a ticket YAML file\n")
-	fmt.Fprintf(os.Stderr, "  enqueue <file>   Enqueue a ticket by copying it to the backlog directory\n")
-	fmt.Fprintf(os.Stderr, "  tui              Start the text-based user interface\n")
+	fmt.Fprintln(os.Stderr, i18n.T("usage.commands", os.Args[0]))
 }
 
 func validateTicket(filePath string) {
 	// Load and validate the ticket
 	t, err := ticket.Load(filePath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Validation failed: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("validate.failed", err))
 		os.Exit(1)
 	}
+	exitOnPolicyError(t)
 	
-	fmt.Printf("✅ Ticket validation passed\n")
-	fmt.Printf("   ID: %s\n", t.ID)
-	fmt.Printf("   Title: %s\n", t.Title)
-	fmt.Printf("   Priority: %d\n", t.Priority)
+	fmt.Println(i18n.T("validate.passed"))
+	fmt.Printf("   %s\n", i18n.T("ticket.id", t.ID))
+	fmt.Printf("   %s\n", i18n.T("ticket.title", t.Title))
+	fmt.Printf("   %s\n", i18n.T("ticket.priority", t.Priority))
 	if len(t.Locks) > 0 {
-		fmt.Printf("   Locks: %v\n", t.Locks)
+		fmt.Printf("   %s\n", i18n.T("ticket.locks", t.Locks))
 	}
 	if len(t.Dependencies) > 0 {
-		fmt.Printf("   Dependencies: %v\n", t.Dependencies)
+		fmt.Printf("   %s\n", i18n.T("ticket.dependencies", t.Dependencies))
 	}
 }
 
@@ -86,9 +318,10 @@ func enqueueTicket(filePath string) {
 	// First validate the ticket
 	t, err := ticket.Load(filePath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to load ticket: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("enqueue.load_failed", err))
 		os.Exit(1)
 	}
+	exitOnPolicyError(t)
 	
 	// Determine backlog directory
 	// Default to ./backlog, but could be made configurable
@@ -99,7 +332,7 @@ func enqueueTicket(filePath string) {
 	
 	// Create backlog directory if it doesn't exist
 	if err := os.MkdirAll(backlogDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to create backlog directory: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("enqueue.mkdir_failed", err))
 		os.Exit(1)
 	}
 	
@@ -112,7 +345,7 @@ func enqueueTicket(filePath string) {
 		// File exists, check if it's the same ticket
 		existingTicket, loadErr := ticket.Load(destPath)
 		if loadErr == nil && existingTicket.ID == t.ID {
-			fmt.Printf("⚠️  Ticket %s is already in the backlog\n", t.ID)
+			fmt.Println(i18n.T("enqueue.already_queued", t.ID))
 			return
 		}
 		
@@ -131,20 +364,37 @@ func enqueueTicket(filePath string) {
 	// Read source file
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to read source file: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("enqueue.read_failed", err))
+		os.Exit(1)
+	}
+
+	// Record who enqueued the ticket and its checksum; the daemon rejects
+	// the file if it changes before being picked up
+	enqueuedBy := "cli"
+	if u, err := user.Current(); err == nil {
+		enqueuedBy += ":" + u.Username
+	}
+	source, _ := filepath.Abs(filePath)
+	data, provenance, err := ticket.Stamp(data, ticket.Provenance{
+		EnqueuedBy: enqueuedBy,
+		Source:     source,
+		EnqueuedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
 		os.Exit(1)
 	}
 	
 	// Write to backlog directory
 	if err := os.WriteFile(destPath, data, 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to write to backlog: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("enqueue.write_failed", err))
 		os.Exit(1)
 	}
 	
-	fmt.Printf("✅ Enqueued ticket %s\n", t.ID)
-	fmt.Printf("   File: %s\n", destPath)
-	fmt.Printf("   Title: %s\n", t.Title)
-	fmt.Printf("   Priority: %d\n", t.Priority)
+	fmt.Println(i18n.T("enqueue.done", t.ID, provenance.Checksum))
+	fmt.Printf("   %s\n", i18n.T("ticket.file", destPath))
+	fmt.Printf("   %s\n", i18n.T("ticket.title", t.Title))
+	fmt.Printf("   %s\n", i18n.T("ticket.priority", t.Priority))
 	
 	log.Printf("Enqueued ticket %s: %s", t.ID, t.Title)
 }
@@ -155,53 +405,53 @@ func initProject(projectName string) {
 		projectName = getProjectNameInteractive()
 	}
 
-	fmt.Printf("🚀 Initializing Amp Orchestrator project: %s\n\n", projectName)
+	fmt.Printf("%s\n\n", i18n.T("init.title", projectName))
 
 	// Create project directory if it doesn't exist
 	if err := os.MkdirAll(projectName, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to create project directory: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("init.mkdir_failed", err))
 		os.Exit(1)
 	}
 
 	// Change into the project directory
 	if err := os.Chdir(projectName); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to enter project directory: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("init.chdir_failed", err))
 		os.Exit(1)
 	}
 
 	// Check if already initialized
 	if isInitialized() {
-		fmt.Fprintf(os.Stderr, "❌ Project directory already initialized (found config.yaml)\n")
-		fmt.Fprintf(os.Stderr, "   Use --force to reinitialize (not implemented yet)\n")
+		fmt.Fprintln(os.Stderr, i18n.T("init.already_initialized"))
+		fmt.Fprintf(os.Stderr, "   %s\n", i18n.T("init.force_hint"))
 		os.Exit(1)
 	}
 
 	// Check prerequisites
-	fmt.Println("📋 Checking prerequisites...")
+	fmt.Println(i18n.T("init.step.prerequisites"))
 	checkPrerequisites()
 
 	// Create directory structure
-	fmt.Println("📁 Creating directory structure...")
+	fmt.Println(i18n.T("init.step.directories"))
 	createDirectories()
 
 	// Initialize git repository
-	fmt.Println("🔧 Initializing git repository...")
+	fmt.Println(i18n.T("init.step.git"))
 	initGitRepo()
 
 	// Copy/create configuration
-	fmt.Println("⚙️  Setting up configuration...")
+	fmt.Println(i18n.T("init.step.config"))
 	setupConfig(projectName)
 
 	// Copy scripts
-	fmt.Println("📜 Setting up scripts...")
+	fmt.Println(i18n.T("init.step.scripts"))
 	copyScripts()
 
 	// Create sample ticket
-	fmt.Println("🎫 Creating sample ticket...")
+	fmt.Println(i18n.T("init.step.sample"))
 	createSampleTicket(projectName)
 
 	// Final instructions
-	fmt.Printf("\n✅ Project initialized successfully!\n\n")
+	fmt.Printf("\n%s\n\n", i18n.T("init.done"))
 	printNextSteps(projectName)
 }
 
@@ -217,7 +467,7 @@ func getProjectNameInteractive() string {
 		defaultName := filepath.Base(cwd)
 		if defaultName != "" && defaultName != "." && defaultName != "/" {
 			reader := bufio.NewReader(os.Stdin)
-			fmt.Printf("Project name [%s]: ", defaultName)
+			fmt.Print(i18n.T("init.project_name_default", defaultName))
 			input, _ := reader.ReadString('\n')
 			input = strings.TrimSpace(input)
 			if input == "" {
@@ -229,7 +479,7 @@ func getProjectNameInteractive() string {
 
 	// Fallback to asking
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Project name: ")
+	fmt.Print(i18n.T("init.project_name"))
 	input, _ := reader.ReadString('\n')
 	return strings.TrimSpace(input)
 }
@@ -250,15 +500,15 @@ func checkPrerequisites() {
 	for _, check := range checks {
 		cmd := exec.Command(check.command, check.args...)
 		if err := cmd.Run(); err != nil {
-			fmt.Printf("   ❌ %s not found\n", check.name)
+			fmt.Printf("   %s\n", i18n.T("init.tool_missing", check.name))
 			allGood = false
 		} else {
-			fmt.Printf("   ✅ %s\n", check.name)
+			fmt.Printf("   %s\n", i18n.T("init.tool_found", check.name))
 		}
 	}
 
 	if !allGood {
-		fmt.Fprintf(os.Stderr, "\n❌ Missing prerequisites. Please install missing tools and try again.\n")
+		fmt.Fprintf(os.Stderr, "\n%s\n", i18n.T("init.tools_missing"))
 		os.Exit(1)
 	}
 }
@@ -274,34 +524,34 @@ func createDirectories() {
 
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Failed to create directory %s: %v\n", dir, err)
+			fmt.Fprintln(os.Stderr, i18n.T("init.dir_failed", dir, err))
 			os.Exit(1)
 		}
-		fmt.Printf("   ✅ Created %s/\n", dir)
+		fmt.Printf("   %s\n", i18n.T("init.dir_created", dir))
 	}
 }
 
 func initGitRepo() {
 	// Check if repo.git already exists
 	if _, err := os.Stat("repo.git"); err == nil {
-		fmt.Println("   ⚠️  repo.git already exists, skipping git initialization")
+		fmt.Printf("   %s\n", i18n.T("init.git_exists"))
 		return
 	}
 
 	// Initialize bare repository
 	if err := gitutils.InitBareRepo("repo.git"); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to initialize git repository: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("init.git_failed", err))
 		os.Exit(1)
 	}
 
 	// Create initial commit
 	repo := gitutils.NewRepo("repo.git")
 	if err := repo.CreateInitialCommit(); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to create initial commit: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("init.commit_failed", err))
 		os.Exit(1)
 	}
 
-	fmt.Println("   ✅ Initialized bare git repository")
+	fmt.Printf("   %s\n", i18n.T("init.git_created"))
 }
 
 func setupConfig(projectName string) {
@@ -313,55 +563,460 @@ func setupConfig(projectName string) {
 		// Copy from sample
 		data, err := os.ReadFile("config.sample.yaml")
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Failed to read config.sample.yaml: %v\n", err)
+			fmt.Fprintln(os.Stderr, i18n.T("init.sample_config_failed", err))
 			os.Exit(1)
 		}
 
 		if err := os.WriteFile("config.yaml", data, 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Failed to create config.yaml: %v\n", err)
+			fmt.Fprintln(os.Stderr, i18n.T("init.config_failed", err))
 			os.Exit(1)
 		}
 	}
 
-	fmt.Println("   ✅ Created config.yaml")
+	fmt.Printf("   %s\n", i18n.T("init.config_created"))
 }
 
 func createBasicConfig(projectName string) {
 	config := `# Amp Orchestrator Configuration
+# Relative paths are resolved against the directory holding this file, so the
+# daemon can be started from anywhere
 
 # Repository Settings
 repository:
   path: "./repo.git"  # Path to bare git repository
   workdir: "./tmp"    # Path to working directory for agents
+  # Tickets get a scratch directory (workdir/scratch/<ticket-id>, exported as
+  # ORCHESTRATOR_SCRATCH_DIR) for artifacts that shouldn't be committed
+  scratch_retention_days: 7  # Delete scratch directories untouched this long (0 = keep forever)
 
 # Agent Settings
 agents:
   count: 3           # Number of agents to run in parallel
   timeout: 1800      # Timeout in seconds for agent tasks (30 minutes)
+  max_failures: 3    # Tickets failing in a row before a worker stops taking more (0 = never)
+  retry_branch: reset  # Retried tickets: reset the old branch to main, or "attempt" for a new agent-X/<id>-attempt-N branch
+  rate_limit:
+    max_per_hour: 0         # Agent calls per hour across all workers (0 = unlimited)
+    max_concurrent: 0       # Agent processes running at once (0 = one per worker)
+    worker_max_per_hour: 0  # Agent calls per hour for each worker (0 = unlimited)
+    max_retries: 3          # Retries when the agent reports a rate-limit error
+    backoff_seconds: 30     # Base delay for exponential backoff between retries
+  limits:                   # Per-process limits for agent and CI runs (0 = unlimited)
+    nice: 0                 # Scheduling niceness, 1-19
+    memory_mb: 0            # Virtual memory per process
+    cpu_seconds: 0          # CPU time per process
+    max_processes: 0        # Processes per user while the agent runs
+    disk_quota_mb: 0        # Max size of a worker's directory before it stops taking tickets
+  backend: local            # local, or kubernetes to run each ticket as a Job
+  # kubernetes:             # The Job clones repo_url, runs the agent and pushes the ticket's branch
+  #   image: "registry.example.com/amp-agent:latest"  # See examples/kube-job-entrypoint.sh
+  #   repo_url: "git://orchestrator.default.svc/repo.git"  # repository.path as reachable from pods
+  #   namespace: agents
+  #   cpu: "2"              # Requested and limited per Job
+  #   memory: "4Gi"
+  #   secret_name: amp-credentials  # Exposed to the agent as env vars, e.g. AMP_API_KEY
+  #   node_selector: {}
+  #   ttl_seconds: 3600     # Delete finished Jobs after this (0 = keep)
+  experiment:               # Try each agent count from min_count to max_count in turn, ignoring count;
+    enabled: false          # orchestrator metrics report recommends the count with the best throughput
+    min_count: 1
+    max_count: 4
+    period_minutes: 60      # How long each count runs before switching
+  housekeeping:             # Chores idle workers run while no ticket is queued; they stop when one arrives
+    chores: []              # Any of prefetch, gc, warm_cache, prune_worktrees
+    interval_minutes: 60    # Each chore runs at most this often across all workers
+    upstream: ""            # Remote name or URL the prefetch chore downloads from
+  regression_tests:         # Fixes for tickets of type bug must add or change a test
+    enabled: false
+    patterns: []            # gitignore-style test paths ([] = *_test.go, test_*.py, *.spec.ts, tests/ and the like)
+    on_missing: retry       # retry (ask the agent for a test) or fail
+    retries: 1              # Times the agent is asked before the ticket fails
+  commits:                  # How the agent's work is committed on the ticket's branch
+    split: single           # single, directory (one commit per top-level directory) or plan (the agent groups changes into logical commits)
+    max_commits: 0          # Further groups are folded into the last commit (0 = no limit)
+  file_guard:               # Checked before committing the agent's changes
+    enabled: false
+    max_file_kb: 1024       # Largest file the agent may add or change (0 = no limit)
+    binaries: true          # Also catch binary files
+    blocklist:              # gitignore-style paths the agent may not add or change
+      - node_modules/
+      - bower_components/
+      - vendor/
+      - .venv/
+      - venv/
+      - __pycache__/
+      - "*.pyc"
+    action: reject          # reject (fail the ticket with guarded_files) or warn (commit and report the files)
+  attribution:              # Commits <dir>/<ticket-id>.json with the ticket, agent version, prompt hash and timestamps
+    enabled: false
+    dir: .orchestrator/provenance
+  license_scan:             # Fails tickets whose changes add a disallowed license or copied code (code license_violation)
+    enabled: false
+    command: ""             # Run instead of the patterns with the added and modified files on stdin; exiting non-zero fails the ticket with its output
+    patterns: []            # Regular expressions for added lines when no command is set; empty uses GPL/AGPL/SSPL headers and Stack Overflow links
+  precheck:                 # Quick CI run in the worktree before committing; failures go back to the agent
+    enabled: false
+    command: ""             # Shell command; empty runs "go vet ./... && go test -short ./..."
+    retries: 1              # Times the agent is asked to fix a failing run before the ticket fails (code precheck_failed)
+    timeout_seconds: 600
+  pre_push:                 # Policy hook that can veto the agent's changes before they are committed and pushed
+    enabled: false
+    command: ""             # Runs from the daemon's directory, not the worktree; gets the diff against main on stdin and the ticket as JSON in ORCHESTRATOR_TICKET; exiting non-zero vetoes (code push_vetoed) with its output, which the agent sees on retry
+    timeout_seconds: 120
+  retry:                    # Failed tickets are requeued with backoff; ticket_failed is sent once attempts run out
+    max_attempts: 1         # Runs per ticket, the first included (1 = never retry)
+    backoff_seconds: 60     # Before the first retry, doubling for each one after
+    max_backoff_seconds: 3600
+    on: [agent_failed, ci_failed, push_failed, push_vetoed, timeout]  # Failure codes worth retrying
 
 # Scheduler Settings
 scheduler:
   poll_interval: 5   # Seconds between checking for new tickets
   backlog_path: "./backlog"  # Directory to watch for new ticket files
   stale_timeout: 900 # Seconds to wait before considering an agent stale (15 minutes)
+  min_free_mb: 0     # Stop dispatching tickets and run git gc below this much free disk, e.g. 1024 (0 = off)
+  disk_check_interval: 30  # Seconds between free space checks of the workdir and repository
+  pause_when_main_red: false  # Hold new tickets while CI on main is failing; tracks main as in ci.main
+  reservations: []   # Workers kept free for urgent tickets, e.g. one for priority 1:
+  # - priority: 1    # Tickets at this priority or more urgent may use the reserved workers
+  #   workers: 1
+  preemption:
+    enabled: false     # Checkpoint and requeue low priority work when an urgent ticket waits for a busy pool
+    urgent_priority: 1 # Tickets at this priority or more urgent may preempt
+    victim_priority: 4 # Only tickets at this priority or less urgent are preempted
+  quotas: []         # Per-tag limits so one team's tickets can't monopolize the workers (0 = no limit):
+  # - tag: team-payments
+  #   max_queued: 20            # More tickets with the tag wait in the backlog
+  #   max_in_flight: 2          # Tickets with the tag worked on at once
+  #   max_daily_invocations: 50 # Agent runs started per UTC day
+  processed_retention:   # Move old backlog/processed files into backlog/archive/*.tar.gz (0 = no limit)
+    max_age_days: 0      # Archive tickets processed longer ago than this
+    max_files: 0         # Keep at most this many processed ticket files
+  ticket_defaults: ""     # Tags, locks, estimate_min and environment merged into every ticket ("" = backlog/_defaults.yaml)
 
 # CI Settings
 ci:
   status_path: "./ci-status"  # Path to store CI status files
-  quick_tests: true   # Run quick tests for fast feedback
+  quick_tests: true   # Local backend: tickets complete on go vet + go test -short; the full suite then
+                      #   runs in the background and gates the merge (ci status --full)
+  retention_days: 30  # Delete CI statuses older than this (0 = keep forever)
+  backend: local      # local (ci.sh), github (Checks API) or buildkite
+  timeout: 1800       # Seconds a CI run may take (0 = no limit)
+  test_retries: 2     # Retry failed test packages; passing on retry marks CI FLAKY, not FAIL
+  # go test tuning for the local backend (0 = go test's default)
+  # test:
+  #   p: 4              # Packages built and tested at once (-p)
+  #   parallel: 8       # Parallel tests per package (-parallel)
+  #   count: 1          # -count=1 bypasses the test cache
+  #   shards: -1        # Split packages across go test processes (-1 = one per CPU core); without p they share the CPUs
+  go_cache_dir: "./go-cache"  # Persistent module/build cache for CI and agents ("" = go's defaults)
+  # External providers build the branch after it is pushed to this remote
+  # remote: origin
+  # poll_interval: 10
+  # github:
+  #   repo: owner/name
+  #   token_env: GITHUB_TOKEN
+  # buildkite:
+  #   org: my-org
+  #   pipeline: my-pipeline
+  #   token_env: BUILDKITE_API_TOKEN
+  # Run ci.sh once per cell (local backend); only cells without
+  # allow_failure decide whether the ticket passes
+  # matrix:
+  #   - name: go1.23
+  #     go_version: "1.23.4"
+  #   - name: alpine
+  #     image: golang:1.24-alpine
+  #     env: ["CGO_ENABLED=0"]
+  #   - name: race
+  #     env: ["GOFLAGS=-race"]
+  #     allow_failure: true
+  # Ticket tags select a CI profile; the first profile with a matching tag
+  # wins. command and env apply to the local backend only
+  # profiles:
+  #   - name: frontend
+  #     tags: ["frontend"]
+  #     command: "npm ci && npm test"
+  #   - name: docs
+  #     tags: ["docs"]
+  #     skip: true      # Record a SKIPPED status instead of running CI
+  # Skip CI when the agent only changed these paths (gitignore syntax)
+  docs_paths: []      # e.g. ["docs/", "*.md"]
+  # Track CI on the main branch; its health shows in status and the TUI
+  main:
+    enabled: false
+    interval_seconds: 30   # How often main's tip is checked; tips the hook missed get CI run
+  # Scan dependencies with govulncheck, npm audit and cargo audit (local
+  # backend); findings are recorded in the CI status
+  vuln_scan:
+    enabled: false
+    fail_on_new: false     # Fail tickets introducing critical vulnerabilities main doesn't have (code vulnerable)
+  # Where statuses are shared: file keeps them in status_path; sqlite keeps them
+  # in one database (needs the sqlite3 shell); s3 lets remote workers report
+  # without a shared filesystem (status_path stays the local copy)
+  status_store:
+    backend: file          # file, sqlite or s3
+    # sqlite:
+    #   path: "./ci-status.db"
+    # s3:                  # Statuses are kept under <prefix>/ci-status/
+    #   endpoint: "https://s3.us-east-1.amazonaws.com"
+    #   bucket: "my-orchestrator"
+    #   prefix: "orchestrator"
 
 # IPC Settings
 ipc:
   socket_path: "~/.orchestrator.sock"  # Unix socket for client communication
+  # socket:                     # Who may attach; unset leaves the socket to the umask
+  #   mode: "0660"              # Socket file mode
+  #   group: orchestrator       # Group (name or ID) given the socket, and its directory with dir_mode
+  #   dir_mode: "0750"          # Create and keep the socket's own directory at this mode; it may
+  #                             # hold nothing else, e.g. socket_path: /run/orchestrator/orchestrator.sock
+  # auth:                       # Role-based access; without tokens every client is an admin
+  #   default_role: viewer      # Role before authenticating: none, viewer, operator or admin
+  #   tokens:                   # Clients present $ORCHESTRATOR_IPC_TOKEN
+  #     - name: deploy-bot
+  #       token_env: DEPLOY_BOT_TOKEN
+  #       role: operator        # viewer: events/status, operator: enqueue/cancel/rerun, admin: scale/pause/approve
+  framing: json                 # Event framing clients ask for: json lines, or deflate (compressed,
+                                #   length-prefixed frames for busy event streams; older daemons fall back to json)
+  # websocket:                  # Events and commands over WebSockets, for TUIs on other machines
+  #   listen_addr: "127.0.0.1:7421"  # Daemon: serve /ipc here; reachable from other hosts only with TLS or behind a TLS-terminating proxy
+  #   tls_cert: /etc/orchestrator/bridge.crt  # Daemon: serve wss:// with this certificate and key
+  #   tls_key: /etc/orchestrator/bridge.key
+  #   url: "wss://build-01:7421/ipc"  # CLI/TUI: connect here instead of socket_path
+  #   token_env: ORCHESTRATOR_WS_TOKEN  # Shared token, set on both ends
+  #   role: viewer              # What the shared token may do; authenticate with an ipc.auth token for more
 
 # Metrics Settings
 metrics:
   enabled: true
   output_path: "./metrics"  # Directory to store metrics CSV files
+  forecast_window_days: 7   # Days of completed tickets the backlog burn-down forecast is based on
+  summary:                  # Weekly summary: completions, failures by error code, flaky tests, cost, trend
+    enabled: false          # Write each finished week's summary to output_path (orchestrator metrics summary writes one on demand)
+    format: markdown        # markdown or html
+    trend_weeks: 8          # Weeks of throughput in the trend
+    agent_cost_per_hour: 0  # Estimated cost of an agent hour; 0 leaves cost estimates out
+    ci_cost_per_hour: 0     # Estimated cost of a CI hour
+
+# State Settings
+state:
+  path: "./state"  # Versioned on-disk state (snapshots, journals)
+
+# Event Log (optional)
+# Keeps every event the daemon publishes in state/events.jsonl (encrypted like the
+# other journals) so orchestrator watch --replay --since 2h shows what happened
+# while no client was attached
+event_log:
+  enabled: false
+  max_size_mb: 10  # Rotate to events.jsonl.1 once the log reaches this size
+  max_files: 5     # Rotated logs kept
+
+# How timestamps are shown in the CLI, TUI, timelines and daemon log. Events,
+# CI status files and metrics CSVs are always RFC3339 in UTC
+time:
+  timezone: Local            # IANA name such as Europe/Berlin, or UTC
+  format: "2006-01-02 15:04:05"  # Go layout, or rfc3339, rfc1123, datetime
+  clock_format: "15:04:05"   # Times of day in the TUI and timelines
+
+# Language of CLI and TUI messages. Empty follows ORCHESTRATOR_LOCALE, then
+# LC_ALL, LC_MESSAGES and LANG; locales without a catalog fall back to English
+locale: ""
+
+# Multi-daemon coordination (optional)
+# Daemons on several machines can share repository.path and the backlog (e.g. over
+# NFS); each ticket is claimed through a ref in the bare repo so only one runs it
+coordination:
+  enabled: false
+  # node_id: "build-01"   # Defaults to the hostname; must be unique per daemon
+  lease_seconds: 300      # Claims of a daemon that stops renewing can be taken over after this
+
+# Remote workers (optional)
+# Worker processes on other machines (orchestrator-worker) connect over TCP with an
+# ipc.auth token of at least the operator role, clone repo_url, run tickets and push
+# the branches back; requires ipc.auth.tokens
+remote:
+  enabled: false
+  listen_address: "127.0.0.1:7420"       # Plain TCP without TLS: set e.g. 10.0.0.5:7420 explicitly only on a trusted network, VPN or TLS tunnel
+  # repo_url: "git://build-01/repo.git"  # repository.path as the workers reach it (git://, ssh:// or a shared path)
+  lease_seconds: 300                     # Tickets of a worker that stops reporting are requeued after this
+
+# Web Dashboard (optional)
+# Queue, workers, CI results, ticket timelines and live events in the browser;
+# with ipc.auth.tokens, open it as http://host:port/?token=<viewer token>
+dashboard:
+  enabled: false
+  listen_address: "127.0.0.1:8080"  # Addresses other hosts can reach require ipc.auth.tokens
+
+# HTTP API (optional)
+# JSON endpoints for automation and remote dashboards: GET /api/v1/queue,
+# /status, /workers and /ci?since=2h, POST /api/v1/tickets and
+# DELETE /api/v1/tickets/<id>; with ipc.auth.tokens, send
+# "Authorization: Bearer <token>" (viewer to read, operator to change)
+api:
+  listen_addr: ""  # e.g. "127.0.0.1:8081"; addresses other hosts can reach require ipc.auth.tokens, and without them the API is read-only
+
+# Validation Hook (optional)
+# Each ticket is checked before enqueue; rejected tickets go to backlog/rejected
+validation:
+  url: ""             # POST ticket JSON, expects {"allow": bool, "reason": "..."}
+  command: ""         # Or a shell command: ticket JSON on stdin, non-zero exit rejects
+  timeout: 10         # Seconds to wait for the hook
+  fail_open: false    # Enqueue tickets when the hook itself fails; otherwise they wait for the next scan
+
+# Policy Rules (optional)
+# Checked by validate, enqueue and the watcher; violating tickets are rejected
+policy:
+  rules: []
+  #  - name: "p1-needs-estimate"
+  #    when: { priority: 1 }
+  #    require: ["estimate_min"]
+  #  - name: "prod-deploy-approval"
+  #    when: { tags: ["prod-deploy"] }
+  #    require: ["requires_approval"]
+  #    message: "prod deploys need requires_approval: true"   # Held until orchestrator approve <id>
+  allowed_locks: []   # Empty allows any lock name
+
+# Artifact Store
+# Files matching a ticket's artifacts: globs are published here after CI passes
+artifacts:
+  backend: local      # local or s3 (any S3-compatible service)
+  dir: "./artifacts"  # Local backend: artifacts/<ticket-id>/<path>
+  # s3:
+  #   endpoint: "https://s3.us-east-1.amazonaws.com"
+  #   bucket: "my-builds"
+  #   region: "us-east-1"
+  #   prefix: "orchestrator"
+  #   access_key_env: "AWS_ACCESS_KEY_ID"
+  #   secret_key_env: "AWS_SECRET_ACCESS_KEY"
+
+# Object Storage (optional)
+# With backend s3, agent logs and CI outputs are uploaded per ticket
+# (logs/<ticket-id>/, ci/<ticket-id>/) and artifacts default to the same
+# bucket under artifacts/; local CI statuses keep only a pointer to their
+# output. The lifecycle rules are merged into the bucket's lifecycle
+# configuration at startup, leaving rules with other IDs alone.
+storage:
+  backend: none       # none or s3
+  # s3:
+  #   endpoint: "https://s3.us-east-1.amazonaws.com"
+  #   bucket: "my-orchestrator"
+  #   region: "us-east-1"
+  #   prefix: "orchestrator"
+  lifecycle:
+    logs_days: 30       # 0 = keep forever
+    ci_days: 30
+    artifacts_days: 90
+
+encryption:
+  enabled: false      # Encrypt archived tickets and agent logs with AES-256-GCM
+  key_env: ORCHESTRATOR_ENCRYPTION_KEY  # 32-byte key, base64 or hex: openssl rand -base64 32
+  # key_file: "/etc/orchestrator/encryption.key"  # Takes precedence over key_env
+
+# Environments (optional)
+# Tickets with an environment field get that environment's settings; tickets
+# naming an environment not listed here are rejected
+environments: {}
+  # staging:
+  #   repository: "./staging.git"  # Bare repository its tickets run against (default repository.path)
+  #   ci_profile: ""               # CI profile from ci.profiles, in place of selection by tag
+  # prod:
+  #   repository: "./prod.git"
+  #   require_approval: true       # Reject tickets without requires_approval: true; those with it
+  #                                # wait in the backlog until orchestrator approve <id>
+
+# Ticket Signing (optional)
+# Tickets signed with "orchestrator sign" carry an ed25519 signature over their
+# canonical YAML; "orchestrator sign keygen <name>" prints a public key to add here
+signing:
+  require_signed_tickets: false  # Reject unsigned tickets, e.g. ones written by event rules
+  public_keys: {}     # Key name to base64 public key; tickets by other keys are rejected
+  #  release-pipeline: "base64-public-key"
+
+# Definition of Done (optional)
+# Tickets may list done checks (name + run) that run on main once the ticket's
+# commit is merged; a failing check enqueues an urgent revert ticket, or with
+# on_failure: rollback reverts the merge on main
+verify:
+  enabled: false
+  interval_seconds: 60       # How often main is checked for merged tickets
+  timeout: 600               # Seconds each check may take (0 = no limit)
+  on_failure: revert_ticket  # revert_ticket or rollback
+  main_ci: false             # Also enqueue a revert ticket for the merge that breaks CI on main
+
+# Merge Conflicts
+# Each completed ticket's branch is merged into main in memory; when git
+# cannot merge it, a resolve-<id> ticket asks an agent to merge the branch
+# and resolve the conflicts
+conflicts:
+  enabled: false
+  priority: 0                # Of resolution tickets (0 = the conflicting ticket's)
+
+# GitHub Pull Requests
+# Completed tickets' branches are pushed to the remote and get a pull request,
+# titled and described from the ticket, with CI's result as a commit status
+integrations:
+  github:
+    enabled: false
+    repo: ""                 # owner/name
+    remote: origin           # Git remote (name or URL) branches are pushed to
+    base: main               # Branch pull requests merge into
+    token_env: GITHUB_TOKEN  # Environment variable holding the API token
+    api_url: ""              # For GitHub Enterprise, e.g. https://github.example.com/api/v3
+    draft: false
+    status_context: amp-orchestrator/ci
+
+# Completion Reports
+# Each completed ticket gets reports/<ticket-id>.md: the ticket, the agent's
+# summary, diffstat, CI results, phase timings and links to its branch,
+# artifacts and agent logs
+reports:
+  enabled: false
+  path: ./reports
+  branch_url: ""       # e.g. https://github.com/acme/widgets/tree/{branch}
+  notify_urls: []      # Webhooks sent {"text": <markdown>, "ticket_id", "title", "path"}
+
+# Ticket Logs
+# Each run of a ticket logs the git, agent and other commands its worker ran,
+# with their output and the CI result, to logs/<ticket-id>/<start>-agent-N.log;
+# read them with orchestrator logs <ticket-id>. Lines are encrypted when
+# encryption is enabled
+ticket_logs:
+  enabled: true
+  path: ./logs
+
+# Ticket Description Templates
+# Descriptions may use {{ .ProjectName }}, {{ .Date }} (YYYY-MM-DD) and
+# {{ .Vars.<name> }}, filled in as tickets are enqueued; unknown names reject the ticket
+templating:
+  project_name: ""    # Defaults to the repository's name
+  vars: {}            # Names are lowercased, e.g. team: payments for {{ .Vars.team }}
+
+# Event Rules (optional)
+# Each rule watches one event type (as shown by the TUI and ipc events) and fires
+# its actions once match has held count times within window_minutes. Templates see
+# the event's data, e.g. {{.ticket.id}}; slug makes a value safe for ticket IDs.
+rules: []
+  # - name: fix-flaky
+  #   event: ci_flaky
+  #   per: package          # Count each flaky package separately
+  #   count: 2
+  #   window_minutes: 1440
+  #   enqueue:              # Written to scheduler.backlog_path
+  #     id: "fix-flaky-{{slug .package}}"
+  #     title: "Fix flaky tests in {{.package}}"
+  #     priority: 2
+  #   notify:
+  #     message: "{{.package}} is flaky, enqueued a fix"
+  #     url: "https://hooks.example.com/orchestrator"  # Optional webhook
+  # - name: release-done
+  #   event: ticket_complete
+  #   match: { ticket.id: "^release-" }
+  #   command: "./scripts/announce.sh {{.ticket.id}}"  # Event JSON on stdin; fields arrive as quoted variables, never as shell code
 `
 
 	if err := os.WriteFile("config.yaml", []byte(config), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to create config.yaml: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("init.config_failed", err))
 		os.Exit(1)
 	}
 }
@@ -373,7 +1028,7 @@ func copyScripts() {
 	if _, err := os.Stat("../scripts"); err == nil {
 		cmd := exec.Command("cp", "-r", "../scripts/", "./")
 		if err := cmd.Run(); err == nil {
-			fmt.Println("   ✅ Copied scripts directory from project")
+			fmt.Printf("   %s\n", i18n.T("init.scripts_copied"))
 			scriptsCreated = true
 		}
 	}
@@ -383,7 +1038,7 @@ func copyScripts() {
 		data, err := os.ReadFile("../ci.sh")
 		if err == nil {
 			if err := os.WriteFile("ci.sh", data, 0755); err == nil {
-				fmt.Println("   ✅ Copied ci.sh from project")
+				fmt.Printf("   %s\n", i18n.T("init.ci_copied"))
 				scriptsCreated = true
 			}
 		}
@@ -396,92 +1051,21 @@ func copyScripts() {
 }
 
 func createBasicScripts() {
-	// Create a basic ci.sh script
-	ciScript := `#!/bin/bash
-
-set -euo pipefail
-
-# CI Script for Amp Orchestrator
-# This script is called by workers to run tests
-
-REPO_DIR="$1"
-REF_NAME="$2"
-COMMIT_HASH="$3"
-
-echo "Running CI for $REF_NAME ($COMMIT_HASH)"
-
-# Store the original working directory
-ORIGINAL_DIR="$(pwd)"
-
-# Create status directory if it doesn't exist  
-STATUS_DIR="$ORIGINAL_DIR/ci-status"
-mkdir -p "$STATUS_DIR"
-
-# Create a temporary working directory
-WORK_DIR=$(mktemp -d)
-echo "Using working directory: $WORK_DIR"
-
-# Cleanup function to run on exit
-cleanup() {
-  echo "Cleaning up $WORK_DIR"
-  rm -rf "$WORK_DIR"
-}
-trap cleanup EXIT
-
-# Clone the repository into working directory
-git clone "$REPO_DIR" "$WORK_DIR/repo"
-cd "$WORK_DIR/repo"
-
-# Checkout the specific commit
-git checkout "$COMMIT_HASH"
-
-echo "Running tests..."
-
-# Initialize status
-STATUS="PASS"
-OUTPUT=""
-
-# Run Go tests if go.mod exists
-if [ -f go.mod ]; then
-  if ! OUTPUT=$(go test ./... 2>&1); then
-    STATUS="FAIL"
-  fi
-else
-  # No tests found
-  OUTPUT="No tests to run"
-fi
-
-# Create status JSON file properly escaped
-jq -n \
-  --arg ref "$REF_NAME" \
-  --arg commit "$COMMIT_HASH" \
-  --arg status "$STATUS" \
-  --arg timestamp "$(date -u +"%Y-%m-%dT%H:%M:%SZ")" \
-  --arg output "$OUTPUT" \
-  '{
-    ref: $ref,
-    commit: $commit,
-    status: $status,
-    timestamp: $timestamp,
-    output: $output
-  }' > "$STATUS_DIR/$COMMIT_HASH.json"
-
-echo "CI completed with status: $STATUS"
-echo "Status saved to $STATUS_DIR/$COMMIT_HASH.json"
-`
+	// The same ci.sh the orchestrator is developed with
+	ciScript := orchestrator.CIScript
 
 	if err := os.WriteFile("scripts/ci.sh", []byte(ciScript), 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to create ci.sh script: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("init.ci_script_failed", err))
 		os.Exit(1)
 	}
 
 	// Also copy to current directory for direct access
 	if err := os.WriteFile("ci.sh", []byte(ciScript), 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to create ci.sh: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("init.ci_failed", err))
 		os.Exit(1)
 	}
 
-	fmt.Println("   ✅ Created basic CI script")
+	fmt.Printf("   %s\n", i18n.T("init.ci_created"))
 }
 
 func createSampleTicket(projectName string) {
@@ -499,24 +1083,13 @@ tags:
 `, projectName)
 
 	if err := os.WriteFile("sample-ticket.yaml", []byte(sampleTicket), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to create sample ticket: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("init.sample_failed", err))
 		os.Exit(1)
 	}
 
-	fmt.Println("   ✅ Created sample-ticket.yaml")
+	fmt.Printf("   %s\n", i18n.T("init.sample_created"))
 }
 
 func printNextSteps(projectName string) {
-	fmt.Println("🎯 Next steps:")
-	fmt.Printf("   1. Enter the directory:  cd %s\n", projectName)
-	fmt.Println("   2. Copy orchestrator binaries to the project directory")
-	fmt.Println("   3. Start the daemon:     ./orchestrator-daemon")
-	fmt.Println("   4. Validate the sample:  ./orchestrator validate sample-ticket.yaml")
-	fmt.Println("   5. Enqueue the sample:   ./orchestrator enqueue sample-ticket.yaml")
-	fmt.Println("   6. Watch the magic! ✨")
-	fmt.Println("")
-	fmt.Println("📚 Learn more:")
-	fmt.Println("   • Read docs/DEMO.md for detailed walkthrough")
-	fmt.Println("   • Create custom tickets in YAML format")
-	fmt.Println("   • Monitor worker activity in daemon logs")
+	fmt.Println(i18n.T("init.next_steps", projectName))
 }
\ No newline at end of file
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/concurrency"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/summary"
+	"github.com/brettsmith212/amp-orchestrator/internal/throughput"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+)
+
+// showMetricsReport summarises ticket throughput per day and, when the daemon
+// is running, forecasts when its backlog clears
+func showMetricsReport() {
+	cfg := loadCIConfig()
+
+	completions, err := throughput.Load(cfg.Metrics.OutputPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	days := cfg.Metrics.ForecastWindowDays
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	perDay := make([]int, days)
+	var recent, timed int
+	var total time.Duration
+	for _, c := range completions {
+		if day := int(today.Sub(c.Time.Truncate(24*time.Hour)).Hours() / 24); day >= 0 && day < days {
+			perDay[day]++
+			recent++
+		}
+		if c.Duration > 0 {
+			timed++
+			total += c.Duration
+		}
+	}
+
+	fmt.Println(i18n.T("metrics.completed", recent, days, len(completions)))
+	if timed > 0 {
+		fmt.Printf("   %s\n", i18n.T("metrics.average", timeline.FormatDuration(total/time.Duration(timed))))
+	}
+	for day := days - 1; day >= 0; day-- {
+		fmt.Printf("   %s  %-20s %d\n", today.AddDate(0, 0, -day).Format("2006-01-02"), strings.Repeat("#", min(perDay[day], 20)), perDay[day])
+	}
+
+	showResidency(cfg.Metrics.OutputPath, days)
+	showConcurrencyTrials(cfg.Metrics.OutputPath, cfg.Agents.Count)
+
+	client, err := dialDaemon(cfg)
+	if err != nil {
+		fmt.Println(i18n.T("metrics.forecast", i18n.T("metrics.forecast_offline")))
+		return
+	}
+	defer client.Close()
+
+	report, err := fetchStatus(client)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	fmt.Println(i18n.T("metrics.forecast", report.Forecast))
+}
+
+// showResidency summarises how long tickets of each priority waited in the
+// queue over the last days
+func showResidency(metricsDir string, days int) {
+	residencies, err := throughput.LoadResidency(metricsDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	stats := throughput.SummarizeResidency(residencies, time.Now(), time.Duration(days)*24*time.Hour)
+	if len(stats) == 0 {
+		return
+	}
+
+	fmt.Println(i18n.T("metrics.residency", days))
+	for _, s := range stats {
+		fmt.Printf("   P%d  %s\n", s.Priority, formatResidency(s))
+	}
+}
+
+// showConcurrencyTrials summarises concurrency experiments and recommends an
+// agents.count when there are results
+func showConcurrencyTrials(metricsDir string, current int) {
+	trials, err := concurrency.Load(metricsDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	if len(trials) == 0 {
+		return
+	}
+
+	agents, stats, ok := concurrency.Recommend(trials)
+	fmt.Println(i18n.T("metrics.concurrency", len(trials)))
+	for _, stat := range stats {
+		fmt.Printf("   %s\n", i18n.T("metrics.concurrency_trial",
+			stat.Agents, stat.Trials, stat.Hours, stat.PerHour, timeline.FormatDuration(stat.AvgCI)))
+	}
+	switch {
+	case !ok:
+		fmt.Printf("   %s\n", i18n.T("metrics.recommend_unknown"))
+	case agents == current:
+		fmt.Printf("   %s\n", i18n.T("metrics.recommend_current", agents))
+	default:
+		fmt.Printf("   %s\n", i18n.T("metrics.recommend", agents, current))
+	}
+}
+
+// writeWeeklySummary writes the summary of the week in progress, or of the
+// week containing --week (YYYY-MM-DD), to the metrics directory
+func writeWeeklySummary(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, i18n.T("usage.metrics_summary", os.Args[0]))
+		os.Exit(1)
+	}
+	cfg := loadCIConfig()
+	config := cfg.Metrics.Summary
+	day := time.Now()
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			usage()
+		}
+		switch args[i] {
+		case "--week":
+			i++
+			parsed, err := time.ParseInLocation(time.DateOnly, args[i], timefmt.Location())
+			if err != nil {
+				usage()
+			}
+			day = parsed
+		case "--format":
+			i++
+			config.Format = args[i]
+		default:
+			usage()
+		}
+	}
+	if err := config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	path, err := summary.Write(config, summary.Sources{
+		MetricsDir: cfg.Metrics.OutputPath,
+		StateDir:   cfg.State.Path,
+		Cipher:     loadCipher(cfg),
+	}, summary.WeekStart(day, timefmt.Location()))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	fmt.Println(i18n.T("metrics.summary_written", path))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/config"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/policy"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// checkTicketPolicy returns an error if the ticket breaks the configured
+// policy rules or its environment's guardrails. Without a config there is
+// no policy to apply, but a config that fails to load is an error rather
+// than a pass.
+func checkTicketPolicy(t *ticket.Ticket) error {
+	cfg, err := config.Load()
+	if errors.Is(err, config.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load config for the policy check: %w", err)
+	}
+
+	if err := cfg.Environments.Check(t); err != nil {
+		return err
+	}
+	return cfg.Policy.Check(t)
+}
+
+// exitOnPolicyError exits listing the violations if checkTicketPolicy
+// rejects the ticket
+func exitOnPolicyError(t *ticket.Ticket) {
+	err := checkTicketPolicy(t)
+	if err == nil {
+		return
+	}
+
+	var violations policy.Violations
+	if !errors.As(err, &violations) {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.T("policy.violations", t.ID, len(violations)))
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "   - %s\n", i18n.T("policy.violation", v.Rule, v.Field, v.Message))
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/search"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// searchStatusIcons marks each result's status
+var searchStatusIcons = map[string]string{
+	search.StatusQueued:    "📋",
+	search.StatusProcessed: "⚙️ ",
+	search.StatusCompleted: "✅",
+	search.StatusFailed:    "❌",
+	search.StatusRejected:  "🚫",
+}
+
+// searchTickets finds tickets across the backlog, its archives, the
+// dead-letter journal and the completion history
+func searchTickets(args []string) {
+	query, err := search.ParseArgs(args, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		fmt.Fprintln(os.Stderr, i18n.T("usage.search", os.Args[0]))
+		os.Exit(1)
+	}
+
+	cfg := loadCIConfig()
+	results, err := search.Collect(search.Paths{
+		BacklogPath: cfg.Scheduler.BacklogPath,
+		StateDir:    cfg.State.Path,
+		MetricsDir:  cfg.Metrics.OutputPath,
+	}, loadCipher(cfg))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("search.failed", err))
+		os.Exit(1)
+	}
+
+	matched := search.Filter(results, query)
+	if len(matched) == 0 {
+		fmt.Println(i18n.T("search.none"))
+		return
+	}
+	for _, r := range matched {
+		fmt.Printf("%s %-10s %-30s %s  %s\n", searchStatusIcons[r.Status], r.Status, r.Ticket.ID,
+			timefmt.Timestamp(r.Time), r.Ticket.Title)
+		details := "   " + i18n.T("search.sources", strings.Join(r.Sources, ", "))
+		if len(r.Ticket.Tags) > 0 {
+			details += i18n.T("search.tags", strings.Join(r.Ticket.Tags, ", "))
+		}
+		fmt.Println(details)
+		if r.Message != "" {
+			fmt.Printf("   %s\n", r.Message)
+		}
+	}
+	fmt.Printf("\n%s\n", i18n.T("search.matched", len(matched), len(results)))
+}
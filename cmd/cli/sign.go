@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/signing"
+)
+
+// generateSigningKey writes a new private key to <name>.key and prints the
+// public key to add to signing.public_keys
+func generateSigningKey(name string) {
+	publicKey, privateKey, err := signing.GenerateKey()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+
+	path := name + ".key"
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.create_failed", path, err))
+		os.Exit(1)
+	}
+	if _, err := fmt.Fprintln(file, privateKey); err != nil {
+		file.Close()
+		fmt.Fprintln(os.Stderr, i18n.T("cli.write_failed", path, err))
+		os.Exit(1)
+	}
+	if err := file.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.write_failed", path, err))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.T("sign.key_written", path))
+	fmt.Printf("   %s\n\n", i18n.T("sign.trust_hint"))
+	fmt.Printf("signing:\n  public_keys:\n    %s: \"%s\"\n", filepath.Base(name), publicKey)
+}
+
+// signTicketFile signs a ticket file in place with a private key; the key's
+// name is the key file's name without .key
+func signTicketFile(ticketPath, keyPath string) {
+	key, err := signing.LoadPrivateKey(keyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	keyName := strings.TrimSuffix(filepath.Base(keyPath), ".key")
+
+	data, err := os.ReadFile(ticketPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("sign.read_failed", err))
+		os.Exit(1)
+	}
+	signed, err := signing.SignFile(data, keyName, key)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("sign.failed", ticketPath, err))
+		os.Exit(1)
+	}
+	if err := os.WriteFile(ticketPath, signed, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("sign.write_failed", err))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.T("sign.done", ticketPath, keyName))
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/throughput"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+)
+
+// showStatus prints a snapshot of the daemon's queue, workers and recent
+// completions with the backlog burn-down forecast; --plain prints the same
+// as labeled lines without emoji, and --follow then keeps printing events
+// as they happen, in place of the TUI
+func showStatus(args []string) {
+	plain, follow := false, false
+	for _, arg := range args {
+		switch arg {
+		case "--plain":
+			plain = true
+		case "--follow":
+			follow = true
+		default:
+			fmt.Fprintln(os.Stderr, i18n.T("status.usage", os.Args[0]))
+			os.Exit(1)
+		}
+	}
+	if follow && !plain {
+		fmt.Fprintln(os.Stderr, i18n.T("status.follow_needs_plain"))
+		os.Exit(1)
+	}
+
+	fail := func(err error) {
+		if plain {
+			fmt.Fprintln(os.Stderr, i18n.T("status.plain.error", err))
+		} else {
+			fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		}
+		fmt.Fprintln(os.Stderr, i18n.T("cli.daemon_hint"))
+		os.Exit(1)
+	}
+
+	cfg := loadCIConfig()
+
+	client, err := dialDaemon(cfg)
+	if err != nil {
+		fail(err)
+	}
+	defer client.Close()
+
+	report, err := fetchStatus(client)
+	if err != nil {
+		fail(err)
+	}
+
+	if !plain {
+		printStatus(report)
+		return
+	}
+	for _, line := range plainStatusLines(report) {
+		fmt.Println(line)
+	}
+	if !follow {
+		return
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	for {
+		select {
+		case <-interrupt:
+			return
+		case event, ok := <-client.Events():
+			if !ok {
+				fmt.Fprintln(os.Stderr, i18n.T("status.plain.disconnected"))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("status.plain.event", formatEventLine(event)))
+		}
+	}
+}
+
+// printStatus prints a status report for a terminal
+func printStatus(report throughput.Report) {
+	fmt.Println(i18n.T("status.queued", report.Queued))
+	fmt.Println(i18n.T("status.in_progress", report.InProgress))
+	fmt.Println(i18n.T("status.forecast", report.Forecast))
+	if b := report.Backlog; b != nil {
+		fmt.Println(i18n.T("status.processed", i18n.T("status.processed_detail", b.Processed, b.Archived, b.Archives)))
+	}
+	if m := report.Main; m != nil {
+		fmt.Printf("%s\n", formatMainHealth(*m))
+	}
+
+	if len(report.Queue) > 0 {
+		fmt.Printf("\n%s\n", i18n.T("status.queue"))
+		for _, listing := range report.Queue {
+			fmt.Printf("   %s\n", formatQueuedTicket(listing))
+		}
+	}
+	if len(report.Recent) > 0 {
+		fmt.Printf("\n%s\n", i18n.T("status.recent"))
+		for _, c := range report.Recent {
+			fmt.Printf("   %s\n", formatCompletion(c))
+		}
+	}
+	if len(report.Residency) > 0 {
+		fmt.Printf("\n%s\n", i18n.T("status.residency"))
+		for _, stats := range report.Residency {
+			fmt.Printf("   %s\n", i18n.T("status.residency_class", stats.Priority, formatResidency(stats)))
+		}
+	}
+
+	if len(report.Workers) == 0 {
+		return
+	}
+	var total ipc.WorkerStats
+	fmt.Printf("\n%s\n", i18n.T("status.agents"))
+	for _, stats := range report.Workers {
+		line := formatWorkerStats(stats)
+		if stats.Phase != "" {
+			line = i18n.T("status.agent_phase", line, stats.Phase)
+		}
+		fmt.Printf("   %s\n", i18n.T("status.agent", stats.WorkerID, line))
+		total.TicketsCompleted += stats.TicketsCompleted
+		total.TicketsFailed += stats.TicketsFailed
+	}
+	fmt.Printf("   %s\n", i18n.T("status.agents_total", i18n.T("status.totals", total.TicketsCompleted, total.TicketsFailed)))
+}
+
+// plainStatusLines renders a status report as flat "label: value" lines,
+// with no box drawing, color or emoji, for screen readers and dumb terminals
+func plainStatusLines(report throughput.Report) []string {
+	lines := []string{
+		i18n.T("status.plain.queued", report.Queued),
+		i18n.T("status.plain.in_progress", report.InProgress),
+		i18n.T("status.plain.forecast", report.Forecast),
+	}
+	if b := report.Backlog; b != nil {
+		lines = append(lines, i18n.T("status.plain.processed", i18n.T("status.processed_detail", b.Processed, b.Archived, b.Archives)))
+	}
+	if m := report.Main; m != nil {
+		lines = append(lines, i18n.T("status.plain.main", describeMainHealth(*m)))
+	}
+	for _, listing := range report.Queue {
+		lines = append(lines, i18n.T("status.plain.queued_ticket", formatQueuedTicket(listing)))
+	}
+	for _, c := range report.Recent {
+		lines = append(lines, i18n.T("status.plain.completed_ticket", formatCompletion(c)))
+	}
+	for _, stats := range report.Residency {
+		lines = append(lines, i18n.T("status.plain.residency", stats.Priority, formatResidency(stats)))
+	}
+
+	var total ipc.WorkerStats
+	for _, stats := range report.Workers {
+		lines = append(lines, i18n.T("status.plain.agent", stats.WorkerID, plainWorkerLine(stats)))
+		if stats.LastError != "" {
+			lines = append(lines, i18n.T("status.plain.agent_error", stats.WorkerID, stats.LastError))
+		}
+		total.TicketsCompleted += stats.TicketsCompleted
+		total.TicketsFailed += stats.TicketsFailed
+	}
+	if len(report.Workers) > 0 {
+		lines = append(lines, i18n.T("status.plain.agents_total", i18n.T("status.totals", total.TicketsCompleted, total.TicketsFailed)))
+	}
+	return lines
+}
+
+// plainWorkerLine describes what a worker is doing, then its running totals
+func plainWorkerLine(stats ipc.WorkerStats) string {
+	var parts []string
+	if stats.Status != "" {
+		parts = append(parts, stats.Status)
+	}
+	if stats.Paused {
+		parts = append(parts, i18n.T("status.plain.paused"))
+	}
+	if stats.Ticket != "" {
+		parts = append(parts, i18n.T("status.plain.ticket", stats.Ticket))
+	}
+	if stats.Phase != "" {
+		parts = append(parts, i18n.T("status.plain.phase", stats.Phase))
+	}
+	if stats.CIStep != "" {
+		parts = append(parts, i18n.T("status.plain.ci_step", stats.CIStep, stats.CIElapsed.Round(time.Second)))
+	}
+	return strings.Join(append(parts, formatWorkerStats(stats)), ", ")
+}
+
+// formatMainHealth summarises CI on main on one line
+func formatMainHealth(health ipc.MainHealth) string {
+	switch {
+	case health.CheckedAt.IsZero():
+		return i18n.T("status.main_unchecked", describeMainHealth(health))
+	case health.Red:
+		return i18n.T("status.main_red", describeMainHealth(health))
+	}
+	return i18n.T("status.main_green", describeMainHealth(health))
+}
+
+// describeMainHealth describes CI on main without decoration
+func describeMainHealth(health ipc.MainHealth) string {
+	if health.CheckedAt.IsZero() {
+		return i18n.T("status.main.unchecked")
+	}
+	commit := health.Commit
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+	if !health.Red {
+		line := i18n.T("status.main.green", timefmt.Timestamp(health.Since), health.Branch, commit)
+		if health.Status == "PENDING" {
+			line += i18n.T("status.main.pending")
+		}
+		return line
+	}
+	line := i18n.T("status.main.red", timefmt.Timestamp(health.Since), health.Branch, commit, health.Status)
+	if health.Held {
+		line += i18n.T("status.main.held")
+	}
+	return line
+}
+
+// formatQueuedTicket describes a queued ticket on one line
+func formatQueuedTicket(listing queue.Listing) string {
+	line := i18n.T("status.ticket.queued", listing.ID, listing.Priority, listing.Title)
+	if time.Now().Before(listing.RetryAfter) {
+		line += i18n.T("status.ticket.retrying", timefmt.Timestamp(listing.RetryAfter))
+	}
+	return line
+}
+
+// formatCompletion describes a completed ticket on one line
+func formatCompletion(c throughput.Completion) string {
+	line := i18n.T("status.ticket.completed", c.TicketID, c.WorkerID, timefmt.Timestamp(c.Time))
+	if c.Duration > 0 {
+		line += i18n.T("status.ticket.took", c.Duration.Round(time.Second))
+	}
+	return line
+}
+
+// formatResidency summarises how long one priority's tickets waited to be
+// dispatched
+func formatResidency(stats throughput.ResidencyStats) string {
+	return i18n.T("status.residency.detail", stats.Count, timeline.FormatDuration(stats.P50), timeline.FormatDuration(stats.P90),
+		timeline.FormatDuration(stats.P99), timeline.FormatDuration(stats.Max))
+}
+
+// formatWorkerStats summarises a worker's running totals on one line
+func formatWorkerStats(stats ipc.WorkerStats) string {
+	parts := []string{i18n.T("status.totals", stats.TicketsCompleted, stats.TicketsFailed)}
+	if stats.TicketsCompleted > 0 {
+		parts = append(parts, i18n.T("status.average", stats.AverageDuration.Round(time.Second)))
+	}
+	return strings.Join(append(parts, i18n.T("status.uptime", stats.Uptime.Round(time.Minute))), ", ")
+}
+
+// fetchStatus asks the daemon for its queue and forecast
+func fetchStatus(client *ipc.Client) (throughput.Report, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var report throughput.Report
+	response, err := client.SendCommand(ctx, "status", nil)
+	if err != nil {
+		return report, err
+	}
+	if !response.OK {
+		return report, errors.New(response.Error)
+	}
+	if err := json.Unmarshal([]byte(response.Message), &report); err != nil {
+		return report, fmt.Errorf("invalid status from daemon: %w", err)
+	}
+	return report, nil
+}
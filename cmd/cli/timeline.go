@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+)
+
+// showTimeline prints a ticket's phases as a Gantt-style chart
+func showTimeline(ticketID string) {
+	cfg := loadCIConfig()
+
+	tl, err := timeline.Assemble(cfg.State.Path, loadCipher(cfg), cfg.CI.StatusPath, ticketID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	if len(tl.Steps) == 0 {
+		fmt.Println(i18n.T("timeline.none", ticketID))
+		return
+	}
+
+	timeline.Render(os.Stdout, tl, 40)
+}
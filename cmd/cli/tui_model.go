@@ -1,10 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/brettsmith212/amp-orchestrator/internal/eventrate"
 	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
 )
 
@@ -13,10 +16,16 @@ type Model struct {
 	tickets   []TicketInfo
 	agents    []AgentInfo
 	events    []EventInfo
+	forecast  string // Backlog burn-down shown in the header
 	ipcClient *ipc.Client
 	quitting  bool
 	width     int
 	height    int
+
+	showHelp     bool   // Help overlay in place of the panels
+	paletteOpen  bool   // Keys go to the command palette
+	paletteInput string
+	notice       string // Reply to the last palette command
 }
 
 // TicketInfo represents a ticket in the UI
@@ -38,6 +47,7 @@ type AgentInfo struct {
 	CurrentTicket *string
 	LastActivity  time.Time
 	Message       string
+	Stats         *ipc.WorkerStats // Running totals, if the daemon sent them
 }
 
 // EventInfo represents a recent event
@@ -47,14 +57,30 @@ type EventInfo struct {
 	Message   string
 }
 
-// eventMsg wraps IPC events for Bubble Tea
-type eventMsg struct {
-	event ipc.Event
+// eventBatchMsg carries the IPC events that arrived within one frame
+type eventBatchMsg struct {
+	events []ipc.Event
 }
 
+// Events are read a frame at a time, so a burst redraws the panels at most
+// once per frame; burstThreshold or more events of one type from one worker
+// in a frame share a line in the events panel. watch --replay still has
+// every event.
+const (
+	frameInterval  = 100 * time.Millisecond
+	maxFrameEvents = 1000
+	burstThreshold = 5
+)
+
 // tickMsg is sent periodically to update the UI
 type tickMsg time.Time
 
+// forecastMsg carries the daemon's latest backlog forecast
+type forecastMsg string
+
+// forecastInterval is how often the header's forecast is refreshed
+const forecastInterval = 30 * time.Second
+
 // NewModel creates a new TUI model
 func NewModel(client *ipc.Client) Model {
 	return Model{
@@ -71,6 +97,7 @@ func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		listenForEvents(m.ipcClient),
 		tickCmd(),
+		fetchForecast(m.ipcClient, 0),
 	)
 }
 
@@ -78,20 +105,55 @@ func (m Model) Init() tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.paletteOpen {
+			return m.updatePalette(msg)
+		}
 		switch msg.String() {
 		case "ctrl+c", "q":
 			m.quitting = true
 			return m, tea.Quit
+		case "?":
+			m.showHelp = !m.showHelp
+		case ":":
+			m.paletteOpen = true
+			m.paletteInput = ""
+		case "esc":
+			m.showHelp = false
+		}
+
+	case commandResultMsg:
+		if msg.err != nil {
+			m.notice = "❌ " + msg.err.Error()
+		} else {
+			m.notice = "✅ " + msg.message
 		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 
-	case eventMsg:
-		m = m.handleIPCEvent(msg.event)
+	case eventBatchMsg:
+		for _, burst := range eventrate.Summarize(msg.events, burstThreshold) {
+			if !burst.Summarized() {
+				m = m.handleIPCEvent(burst.Events[0])
+				continue
+			}
+			for _, event := range burst.Events {
+				m, _ = m.applyIPCEvent(event)
+			}
+			last := burst.Events[len(burst.Events)-1]
+			m.events = append(m.events, EventInfo{
+				Timestamp: last.Timestamp,
+				Type:      string(burst.Type),
+				Message:   burst.String(),
+			})
+		}
 		return m, listenForEvents(m.ipcClient)
 
+	case forecastMsg:
+		m.forecast = string(msg)
+		return m, fetchForecast(m.ipcClient, forecastInterval)
+
 	case tickMsg:
 		// Clean up old events (keep last 50)
 		if len(m.events) > 50 {
@@ -107,8 +169,41 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-// handleIPCEvent processes incoming IPC events and updates the model
+// updatePalette edits the command palette's input, running it on enter
+func (m Model) updatePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		m.quitting = true
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.paletteOpen = false
+	case tea.KeyEnter:
+		m.paletteOpen = false
+		m.notice = ""
+		return m, runPaletteCommand(m.ipcClient, m.paletteInput)
+	case tea.KeyBackspace:
+		if len(m.paletteInput) > 0 {
+			runes := []rune(m.paletteInput)
+			m.paletteInput = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		m.paletteInput += " "
+	case tea.KeyRunes:
+		m.paletteInput += string(msg.Runes)
+	}
+	return m, nil
+}
+
+// handleIPCEvent processes an incoming IPC event and adds it to the events log
 func (m Model) handleIPCEvent(event ipc.Event) Model {
+	m, eventInfo := m.applyIPCEvent(event)
+	m.events = append(m.events, eventInfo)
+	return m
+}
+
+// applyIPCEvent updates the model for an IPC event, returning its line for
+// the events log
+func (m Model) applyIPCEvent(event ipc.Event) (Model, EventInfo) {
 	timestamp := event.Timestamp
 	
 	// Add to events log
@@ -139,6 +234,66 @@ func (m Model) handleIPCEvent(event ipc.Event) Model {
 			}
 		}
 
+	case ipc.EventTypeTicketRejected:
+		if ticketEvent, ok := event.Data.(map[string]interface{}); ok {
+			if ticket, ok := ticketEvent["ticket"].(map[string]interface{}); ok {
+				reason, _ := ticketEvent["message"].(string)
+				eventInfo.Message = formatTicketRejectedMessage(ticket["id"].(string), reason)
+			}
+		}
+
+	case ipc.EventTypeResourceLimitExceeded:
+		if limitEvent, ok := event.Data.(map[string]interface{}); ok {
+			workerID := int(limitEvent["worker_id"].(float64))
+			ticketID := ""
+			if ticket, ok := limitEvent["ticket"].(map[string]interface{}); ok {
+				ticketID, _ = ticket["id"].(string)
+			}
+			message, _ := limitEvent["message"].(string)
+			eventInfo.Message = formatResourceLimitMessage(workerID, ticketID, message)
+		}
+
+	case ipc.EventTypeDiskSpace:
+		if diskEvent, ok := event.Data.(map[string]interface{}); ok {
+			low, _ := diskEvent["low"].(bool)
+			message, _ := diskEvent["message"].(string)
+			eventInfo.Message = formatDiskSpaceMessage(low, message)
+		}
+
+	case ipc.EventTypeControlCommand:
+		if commandEvent, ok := event.Data.(map[string]interface{}); ok {
+			var caller ipc.Caller
+			if c, ok := commandEvent["caller"].(map[string]interface{}); ok {
+				caller.Token, _ = c["token"].(string)
+				caller.User, _ = c["user"].(string)
+				uid, _ := c["uid"].(float64)
+				pid, _ := c["pid"].(float64)
+				caller.UID, caller.PID = int(uid), int(pid)
+			}
+			command, _ := commandEvent["command"].(string)
+			target := ""
+			if args, ok := commandEvent["args"].(map[string]interface{}); ok {
+				target, _ = args["target"].(string)
+			}
+			errMsg, _ := commandEvent["error"].(string)
+			eventInfo.Message = formatControlCommandMessage(command, target, caller, errMsg)
+		}
+
+	case ipc.EventTypePolicyViolation:
+		if violationEvent, ok := event.Data.(map[string]interface{}); ok {
+			if ticket, ok := violationEvent["ticket"].(map[string]interface{}); ok {
+				var rules []string
+				if violations, ok := violationEvent["violations"].([]interface{}); ok {
+					for _, v := range violations {
+						if violation, ok := v.(map[string]interface{}); ok {
+							rules = append(rules, violation["rule"].(string))
+						}
+					}
+				}
+				eventInfo.Message = formatPolicyViolationMessage(ticket["id"].(string), rules)
+			}
+		}
+
 	case ipc.EventTypeTicketStarted:
 		if ticketEvent, ok := event.Data.(map[string]interface{}); ok {
 			if ticket, ok := ticketEvent["ticket"].(map[string]interface{}); ok {
@@ -174,8 +329,155 @@ func (m Model) handleIPCEvent(event ipc.Event) Model {
 					}
 				}
 				
-				eventInfo.Message = formatTicketCompleteMessage(ticketID, workerID)
+				summary, _ := ticket["summary"].(string)
+				eventInfo.Message = formatTicketCompleteMessage(ticketID, workerID, summary)
+			}
+		}
+
+	case ipc.EventTypeAgentAuthError:
+		if authEvent, ok := event.Data.(map[string]interface{}); ok {
+			// The ticket went back on the queue
+			if ticket, ok := authEvent["ticket"].(map[string]interface{}); ok {
+				ticketID := ticket["id"].(string)
+				for i := range m.tickets {
+					if m.tickets[i].ID == ticketID {
+						m.tickets[i].Status = "queued"
+						m.tickets[i].AssignedTo = 0
+						m.tickets[i].StartedAt = nil
+						break
+					}
+				}
+			}
+
+			message, _ := authEvent["message"].(string)
+			eventInfo.Message = formatAgentAuthErrorMessage(message)
+		}
+
+	case ipc.EventTypeTicketRetrying:
+		if retryEvent, ok := event.Data.(map[string]interface{}); ok {
+			// The ticket went back on the queue to wait out its backoff
+			if ticket, ok := retryEvent["ticket"].(map[string]interface{}); ok {
+				ticketID := ticket["id"].(string)
+				for i := range m.tickets {
+					if m.tickets[i].ID == ticketID {
+						m.tickets[i].Status = "queued"
+						m.tickets[i].AssignedTo = 0
+						m.tickets[i].StartedAt = nil
+						break
+					}
+				}
+
+				message, _ := retryEvent["message"].(string)
+				eventInfo.Message = formatTicketRetryingMessage(ticketID, message)
+			}
+		}
+
+	case ipc.EventTypeTicketCancelled:
+		if cancelEvent, ok := event.Data.(map[string]interface{}); ok {
+			if ticket, ok := cancelEvent["ticket"].(map[string]interface{}); ok {
+				ticketID := ticket["id"].(string)
+				for i := range m.tickets {
+					if m.tickets[i].ID == ticketID {
+						m.tickets[i].Status = "cancelled"
+						m.tickets[i].AssignedTo = 0
+						break
+					}
+				}
+
+				message, _ := cancelEvent["message"].(string)
+				eventInfo.Message = formatTicketCancelledMessage(ticketID, message)
+			}
+		}
+
+	case ipc.EventTypeDispatchBlocked, ipc.EventTypeDispatchResumed:
+		if dispatchEvent, ok := event.Data.(map[string]interface{}); ok {
+			message, _ := dispatchEvent["message"].(string)
+			eventInfo.Message = formatDispatchMessage(event.Type == ipc.EventTypeDispatchBlocked, message)
+		}
+
+	case ipc.EventTypeQuotaExceeded:
+		if quotaEvent, ok := event.Data.(map[string]interface{}); ok {
+			message, _ := quotaEvent["message"].(string)
+			eventInfo.Message = formatQuotaExceededMessage(message)
+		}
+
+	case ipc.EventTypeLockAcquired, ipc.EventTypeLockReleased, ipc.EventTypeLockWaiting:
+		if lockEvent, ok := event.Data.(map[string]interface{}); ok {
+			message, _ := lockEvent["message"].(string)
+			eventInfo.Message = formatLockMessage(message)
+		}
+
+	case ipc.EventTypeRegressionTest:
+		if checkEvent, ok := event.Data.(map[string]interface{}); ok {
+			passed, _ := checkEvent["passed"].(bool)
+			message, _ := checkEvent["message"].(string)
+			eventInfo.Message = formatRegressionTestMessage(passed, message)
+		}
+
+	case ipc.EventTypeGuardedFiles:
+		if guardEvent, ok := event.Data.(map[string]interface{}); ok {
+			rejected, _ := guardEvent["rejected"].(bool)
+			message, _ := guardEvent["message"].(string)
+			eventInfo.Message = formatGuardedFilesMessage(rejected, message)
+		}
+
+	case ipc.EventTypeMergeConflict:
+		if conflictEvent, ok := event.Data.(map[string]interface{}); ok {
+			ticketID, _ := conflictEvent["ticket_id"].(string)
+			message, _ := conflictEvent["message"].(string)
+			eventInfo.Message = formatMergeConflictMessage(ticketID, message)
+		}
+
+	case ipc.EventTypePullRequest:
+		if prEvent, ok := event.Data.(map[string]interface{}); ok {
+			ticketID, _ := prEvent["ticket_id"].(string)
+			url, _ := prEvent["url"].(string)
+			eventInfo.Message = formatPullRequestMessage(ticketID, url)
+		}
+
+	case ipc.EventTypeCIFullTier:
+		if tierEvent, ok := event.Data.(map[string]interface{}); ok {
+			ticketID := ""
+			if ticketData, ok := tierEvent["ticket"].(map[string]interface{}); ok {
+				ticketID, _ = ticketData["id"].(string)
 			}
+			message, _ := tierEvent["message"].(string)
+			eventInfo.Message = formatCIFullTierMessage(ticketID, message)
+		}
+
+	case ipc.EventTypeCIRunning:
+		if runningEvent, ok := event.Data.(map[string]interface{}); ok {
+			workerID, _ := runningEvent["worker_id"].(float64)
+			step, _ := runningEvent["step"].(string)
+			elapsed, _ := runningEvent["elapsed"].(float64)
+			message, _ := runningEvent["message"].(string)
+			for i := range m.agents {
+				if m.agents[i].ID == int(workerID) && m.agents[i].Stats != nil {
+					// Shown on the agent's progress line, counting on from here
+					m.agents[i].Stats.CIStep = step
+					m.agents[i].Stats.CIElapsed = time.Duration(elapsed)
+					m.agents[i].LastActivity = timestamp
+				}
+			}
+			eventInfo.Message = formatCIRunningMessage(int(workerID), message)
+		}
+
+	case ipc.EventTypeMainHealth:
+		if healthEvent, ok := event.Data.(map[string]interface{}); ok {
+			branch, _ := healthEvent["branch"].(string)
+			commit, _ := healthEvent["commit"].(string)
+			red, _ := healthEvent["red"].(bool)
+			held, _ := healthEvent["held"].(bool)
+			eventInfo.Message = formatMainHealthMessage(branch, commit, red, held)
+		}
+
+	case ipc.EventTypeTicketVerified:
+		if verifyEvent, ok := event.Data.(map[string]interface{}); ok {
+			ticketID, _ := verifyEvent["ticket_id"].(string)
+			passed, _ := verifyEvent["passed"].(bool)
+			action, _ := verifyEvent["action"].(string)
+			message, _ := verifyEvent["message"].(string)
+			eventInfo.Message = formatTicketVerifiedMessage(ticketID, passed, action, message)
 		}
 
 	case ipc.EventTypeWorkerStatus:
@@ -183,6 +485,7 @@ func (m Model) handleIPCEvent(event ipc.Event) Model {
 			workerID := int(workerEvent["worker_id"].(float64))
 			status := workerEvent["status"].(string)
 			message := workerEvent["message"].(string)
+			stats := parseWorkerStats(workerEvent["stats"])
 			
 			// Update or create agent info
 			agentFound := false
@@ -191,8 +494,10 @@ func (m Model) handleIPCEvent(event ipc.Event) Model {
 					m.agents[i].Status = status
 					m.agents[i].Message = message
 					m.agents[i].LastActivity = timestamp
+					m.agents[i].Stats = stats
 					
-					if status == "idle" {
+					if status == "idle" || status == "error" {
+						// An error names the ticket that caused it in its message
 						m.agents[i].CurrentTicket = nil
 					} else if status == "working" {
 						// Find current ticket for this worker
@@ -214,6 +519,7 @@ func (m Model) handleIPCEvent(event ipc.Event) Model {
 					Status:       status,
 					Message:      message,
 					LastActivity: timestamp,
+					Stats:        stats,
 				}
 				m.agents = append(m.agents, agent)
 			}
@@ -222,19 +528,54 @@ func (m Model) handleIPCEvent(event ipc.Event) Model {
 		}
 	}
 
-	// Add to events log
-	m.events = append(m.events, eventInfo)
-	
-	return m
+	return m, eventInfo
 }
 
-// listenForEvents creates a command to listen for the next IPC event
+// parseWorkerStats decodes the totals attached to a worker_status event
+func parseWorkerStats(data interface{}) *ipc.WorkerStats {
+	if data == nil {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var stats ipc.WorkerStats
+	if err := json.Unmarshal(raw, &stats); err != nil {
+		return nil
+	}
+	return &stats
+}
+
+// listenForEvents creates a command that reads the next frame's IPC events
 func listenForEvents(client *ipc.Client) tea.Cmd {
 	return func() tea.Msg {
-		select {
-		case event := <-client.Events():
-			return eventMsg{event: event}
+		events, ok := eventrate.Collect(client.Events(), frameInterval, maxFrameEvents)
+		if !ok {
+			return nil
 		}
+		return eventBatchMsg{events: events}
+	}
+}
+
+// fetchForecast creates a command that asks the daemon for its backlog
+// forecast, and main's CI health when tracked, after a delay
+func fetchForecast(client *ipc.Client, delay time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		time.Sleep(delay)
+		report, err := fetchStatus(client)
+		if err != nil {
+			return forecastMsg("forecast unavailable: " + err.Error())
+		}
+		forecast := report.Forecast.String()
+		if m := report.Main; m != nil && !m.CheckedAt.IsZero() {
+			if m.Red {
+				forecast += " | 🔴 main is red"
+			} else {
+				forecast += " | 🟢 main is green"
+			}
+		}
+		return forecastMsg(forecast)
 	}
 }
 
@@ -257,12 +598,128 @@ func formatTicketEnqueuedMessage(ticket TicketInfo) string {
 	return "Enqueued: " + ticket.ID + " - " + ticket.Title
 }
 
+func formatTicketRejectedMessage(ticketID, reason string) string {
+	return "Rejected: " + ticketID + " - " + reason
+}
+
+func formatPolicyViolationMessage(ticketID string, rules []string) string {
+	return "Policy violation: " + ticketID + " - " + strings.Join(rules, ", ")
+}
+
 func formatTicketStartedMessage(ticketID string, workerID int) string {
 	return formatWorker(workerID) + " started: " + ticketID
 }
 
-func formatTicketCompleteMessage(ticketID string, workerID int) string {
-	return formatWorker(workerID) + " completed: " + ticketID
+func formatTicketCompleteMessage(ticketID string, workerID int, summary string) string {
+	message := formatWorker(workerID) + " completed: " + ticketID
+	if summary != "" {
+		message += " - " + summary
+	}
+	return message
+}
+
+func formatDispatchMessage(blocked bool, message string) string {
+	if blocked {
+		return "PAUSED: " + message
+	}
+	return "Resumed: " + message
+}
+
+func formatMainHealthMessage(branch, commit string, red, held bool) string {
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+	if !red {
+		return "Main green: " + branch + " at " + commit
+	}
+	message := "Main red: " + branch + " at " + commit
+	if held {
+		message += " - dispatch held"
+	}
+	return message
+}
+
+func formatTicketVerifiedMessage(ticketID string, passed bool, action, message string) string {
+	if passed {
+		return "Verified: " + ticketID + " - " + message
+	}
+	return "Verification failed: " + ticketID + " - " + message + " (" + action + ")"
+}
+
+func formatRegressionTestMessage(passed bool, message string) string {
+	if passed {
+		return "Regression test: " + message
+	}
+	return "Regression test missing: " + message
+}
+
+func formatGuardedFilesMessage(rejected bool, message string) string {
+	if rejected {
+		return "Files rejected: " + message
+	}
+	return "Files warning: " + message
+}
+
+func formatTicketRetryingMessage(ticketID, message string) string {
+	return "Retrying " + ticketID + ": " + message
+}
+
+func formatTicketCancelledMessage(ticketID, message string) string {
+	return "Cancelled " + ticketID + ": " + message
+}
+
+func formatPullRequestMessage(ticketID, url string) string {
+	return "Pull request: " + ticketID + " - " + url
+}
+
+func formatMergeConflictMessage(ticketID, message string) string {
+	return "Merge conflict: " + ticketID + " - " + message
+}
+
+func formatCIRunningMessage(workerID int, message string) string {
+	return formatWorker(workerID) + " CI: " + message
+}
+
+func formatCIFullTierMessage(ticketID, message string) string {
+	return "Full CI: " + ticketID + " - " + message
+}
+
+func formatLockMessage(message string) string {
+	return "Lock: " + message
+}
+
+func formatQuotaExceededMessage(message string) string {
+	return "QUOTA: " + message
+}
+
+func formatAgentAuthErrorMessage(message string) string {
+	return "CRITICAL: " + message
+}
+
+func formatResourceLimitMessage(workerID int, ticketID, message string) string {
+	if ticketID == "" {
+		return formatWorker(workerID) + " over limit: " + message
+	}
+	return formatWorker(workerID) + " killed " + ticketID + ": " + message
+}
+
+func formatDiskSpaceMessage(low bool, message string) string {
+	if low {
+		return "WARNING: " + message
+	}
+	return message
+}
+
+func formatControlCommandMessage(command, target string, caller ipc.Caller, errMsg string) string {
+	message := command
+	if target != "" {
+		message += " " + target
+	}
+	message += " by " + caller.String()
+	if errMsg != "" {
+		message += " failed: " + errMsg
+	}
+	return message
 }
 
 func formatWorkerStatusMessage(workerID int, status, message string) string {
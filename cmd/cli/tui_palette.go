@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandResultMsg carries the daemon's reply to a palette command
+type commandResultMsg struct {
+	message string
+	err     error
+}
+
+// keyBindings are listed in the help overlay with the message key of their
+// description
+var keyBindings = [][2]string{
+	{"?", "tui.key.help"},
+	{":", "tui.key.palette"},
+	{"enter", "tui.key.run"},
+	{"esc", "tui.key.close"},
+	{"q, ctrl+c", "tui.key.quit"},
+}
+
+// paletteCommands are listed in the help overlay like keyBindings
+var paletteCommands = [][2]string{
+	{"enqueue <file>", "tui.command.enqueue"},
+	{"cancel <id>", "tui.command.cancel"},
+	{"approve <id> [version]", "tui.command.approve"},
+	{"scale <n>", "tui.command.scale"},
+	{"pause [reason]", "tui.command.pause"},
+	{"resume", "tui.command.resume"},
+	{"worker pause <id> [reason]", "tui.command.worker_pause"},
+	{"worker resume <id>", "tui.command.worker_resume"},
+}
+
+// runPaletteCommand parses a palette line and sends it to the daemon
+func runPaletteCommand(client *ipc.Client, line string) tea.Cmd {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name, args, err := paletteRequest(fields)
+	if err != nil {
+		return func() tea.Msg { return commandResultMsg{err: err} }
+	}
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		response, err := client.SendCommand(ctx, name, args)
+		if err != nil {
+			return commandResultMsg{err: err}
+		}
+		if !response.OK {
+			return commandResultMsg{err: fmt.Errorf("%s", response.Error)}
+		}
+		return commandResultMsg{message: response.Message}
+	}
+}
+
+// paletteRequest maps a palette command onto a daemon command and its
+// arguments; enqueue reads the ticket file here, since the daemon may not
+// share the client's working directory
+func paletteRequest(fields []string) (string, map[string]string, error) {
+	rest := strings.Join(fields[1:], " ")
+	switch fields[0] {
+	case "enqueue":
+		if len(fields) != 2 {
+			return "", nil, errors.New(i18n.T("tui.palette.usage.enqueue"))
+		}
+		data, err := os.ReadFile(fields[1])
+		if err != nil {
+			return "", nil, err
+		}
+		return "ticket_enqueue", map[string]string{"name": filepath.Base(fields[1]), "data": string(data)}, nil
+
+	case "cancel":
+		if len(fields) != 2 {
+			return "", nil, errors.New(i18n.T("tui.palette.usage.cancel"))
+		}
+		return "ticket_cancel", map[string]string{"id": fields[1]}, nil
+
+	case "approve":
+		if len(fields) != 2 && len(fields) != 3 {
+			return "", nil, errors.New(i18n.T("tui.palette.usage.approve"))
+		}
+		args := map[string]string{"id": fields[1]}
+		if len(fields) == 3 {
+			args["version"] = fields[2]
+		}
+		return "ticket_approve", args, nil
+
+	case "scale":
+		if len(fields) != 2 {
+			return "", nil, errors.New(i18n.T("tui.palette.usage.scale"))
+		}
+		return "pool_scale", map[string]string{"count": fields[1]}, nil
+
+	case "pause":
+		return "pool_pause", map[string]string{"reason": rest}, nil
+
+	case "resume":
+		return "pool_resume", nil, nil
+
+	case "worker":
+		if len(fields) < 3 {
+			return "", nil, errors.New(i18n.T("tui.palette.usage.worker"))
+		}
+		switch fields[1] {
+		case "pause":
+			return "worker_pause", map[string]string{"id": fields[2], "reason": strings.Join(fields[3:], " ")}, nil
+		case "resume":
+			return "worker_resume", map[string]string{"id": fields[2]}, nil
+		}
+		return "", nil, errors.New(i18n.T("tui.palette.usage.worker"))
+	}
+	return "", nil, errors.New(i18n.T("tui.palette.unknown", fields[0]))
+}
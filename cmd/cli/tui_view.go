@@ -2,11 +2,12 @@ package main
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
 )
 
 // Styles for the TUI
@@ -44,7 +45,7 @@ var (
 // View renders the TUI
 func (m Model) View() string {
 	if m.quitting {
-		return "Goodbye! 👋\n"
+		return i18n.T("tui.goodbye") + "\n"
 	}
 
 	// Calculate dimensions for panels
@@ -67,11 +68,17 @@ func (m Model) View() string {
 	}
 
 	// Header
-	header := titleStyle.Render("🤖 Amp Orchestrator - Real-time Status")
+	header := titleStyle.Render(i18n.T("tui.title"))
+	if m.forecast != "" {
+		header = lipgloss.JoinVertical(lipgloss.Center, header, dimStyle.Render("📈 "+m.forecast))
+	}
 	
 	// Calculate panel dimensions
 	panelWidth := (width - 6) / 2 // Account for borders and margins
 	panelHeight := height - 12     // Account for header, footer, and events panel
+	if m.forecast != "" {
+		panelHeight-- // Forecast line under the title
+	}
 	
 	// Ensure minimum panel dimensions
 	if panelWidth < 30 {
@@ -81,6 +88,10 @@ func (m Model) View() string {
 		panelHeight = 8
 	}
 
+	if m.showHelp {
+		return lipgloss.JoinVertical(lipgloss.Center, header, m.renderHelp(width-4), m.renderFooter())
+	}
+
 	// Render tickets panel
 	ticketsPanel := m.renderTicketsPanel(panelWidth, panelHeight)
 	
@@ -93,27 +104,51 @@ func (m Model) View() string {
 	// Arrange panels side by side
 	topPanels := lipgloss.JoinHorizontal(lipgloss.Top, ticketsPanel, agentsPanel)
 	
-	// Footer with help text
-	footer := dimStyle.Render("Press q or Ctrl+C to quit")
-	
 	// Combine all sections
 	return lipgloss.JoinVertical(
 		lipgloss.Center,
 		header,
 		topPanels,
 		eventsPanel,
-		footer,
+		m.renderFooter(),
 	)
 }
 
+// renderFooter renders the command palette while it is open, otherwise the
+// last command's reply and the help text
+func (m Model) renderFooter() string {
+	if m.paletteOpen {
+		return boldStyle.Render(":" + m.paletteInput + "█")
+	}
+	footer := dimStyle.Render(i18n.T("tui.footer"))
+	if m.notice != "" {
+		footer = lipgloss.JoinVertical(lipgloss.Center, m.notice, footer)
+	}
+	return footer
+}
+
+// renderHelp renders the help overlay listing keybindings and palette commands
+func (m Model) renderHelp(width int) string {
+	var content strings.Builder
+	content.WriteString(boldStyle.Render(i18n.T("tui.help.keys")) + "\n")
+	for _, binding := range keyBindings {
+		content.WriteString(fmt.Sprintf("  %-28s %s\n", binding[0], dimStyle.Render(i18n.T(binding[1]))))
+	}
+	content.WriteString("\n" + boldStyle.Render(i18n.T("tui.help.commands")) + "\n")
+	for _, command := range paletteCommands {
+		content.WriteString(fmt.Sprintf("  %-28s %s\n", command[0], dimStyle.Render(i18n.T(command[1]))))
+	}
+	return panelStyle.Width(width).Render(i18n.T("tui.help.title") + "\n\n" + strings.TrimRight(content.String(), "\n"))
+}
+
 // renderTicketsPanel renders the tickets panel
 func (m Model) renderTicketsPanel(width, height int) string {
-	title := "📋 Tickets"
+	title := i18n.T("tui.tickets.title")
 	
 	var content strings.Builder
 	
 	if len(m.tickets) == 0 {
-		content.WriteString(dimStyle.Render("No tickets yet..."))
+		content.WriteString(dimStyle.Render(i18n.T("tui.tickets.empty")))
 	} else {
 		// Show recent tickets (limit to fit in panel)
 		maxTickets := height - 5 // Account for title, borders, and padding
@@ -143,12 +178,12 @@ func (m Model) renderTicketsPanel(width, height int) string {
 
 // renderAgentsPanel renders the agents panel
 func (m Model) renderAgentsPanel(width, height int) string {
-	title := "🤖 Agents"
+	title := i18n.T("tui.agents.title")
 	
 	var content strings.Builder
 	
 	if len(m.agents) == 0 {
-		content.WriteString(dimStyle.Render("No agents connected..."))
+		content.WriteString(dimStyle.Render(i18n.T("tui.agents.empty")))
 	} else {
 		for i, agent := range m.agents {
 			content.WriteString(m.renderAgentLine(agent))
@@ -167,12 +202,12 @@ func (m Model) renderAgentsPanel(width, height int) string {
 
 // renderEventsPanel renders the events panel
 func (m Model) renderEventsPanel(width, height int) string {
-	title := "📡 Recent Events"
+	title := i18n.T("tui.events.title")
 	
 	var content strings.Builder
 	
 	if len(m.events) == 0 {
-		content.WriteString(dimStyle.Render("Waiting for events..."))
+		content.WriteString(dimStyle.Render(i18n.T("tui.events.empty")))
 	} else {
 		// Show recent events (limit to fit in panel)
 		maxEvents := height - 5 // Account for title, borders, and padding
@@ -208,19 +243,23 @@ func (m Model) renderTicketLine(ticket TicketInfo) string {
 	switch ticket.Status {
 	case "queued":
 		statusIcon = "⏳"
-		statusText = "Queued"
+		statusText = i18n.T("tui.ticket.queued")
 		style = queuedStyle
 	case "processing":
 		statusIcon = "⚙️"
-		statusText = "Processing"
+		statusText = i18n.T("tui.ticket.processing")
 		style = workingStyle
 	case "completed":
 		statusIcon = "✅"
-		statusText = "Completed"
+		statusText = i18n.T("tui.ticket.completed")
 		style = completedStyle
+	case "cancelled":
+		statusIcon = "🚫"
+		statusText = i18n.T("tui.ticket.cancelled")
+		style = dimStyle
 	default:
 		statusIcon = "❓"
-		statusText = "Unknown"
+		statusText = i18n.T("tui.ticket.unknown")
 		style = dimStyle
 	}
 	
@@ -231,7 +270,7 @@ func (m Model) renderTicketLine(ticket TicketInfo) string {
 	// Add worker info if assigned
 	workerPart := ""
 	if ticket.AssignedTo > 0 {
-		workerPart = dimStyle.Render(" (Worker " + strconv.Itoa(ticket.AssignedTo) + ")")
+		workerPart = dimStyle.Render(i18n.T("tui.ticket.worker", ticket.AssignedTo))
 	}
 	
 	// Format title (truncate if too long)
@@ -249,57 +288,90 @@ func (m Model) renderTicketLine(ticket TicketInfo) string {
 
 // renderAgentLine renders a single agent line
 func (m Model) renderAgentLine(agent AgentInfo) string {
-	var statusIcon string
+	var statusIcon, statusText string
 	var style lipgloss.Style
 	
 	switch agent.Status {
 	case "idle":
 		statusIcon = "😴"
+		statusText = i18n.T("tui.agent.idle")
 		style = idleStyle
 	case "working":
 		statusIcon = "⚙️"
+		statusText = i18n.T("tui.agent.working")
 		style = workingStyle
 	case "error":
 		statusIcon = "❌"
+		statusText = i18n.T("tui.agent.error")
 		style = errorStyle
 	default:
 		statusIcon = "🤖"
+		statusText = strings.Title(agent.Status)
 		style = dimStyle
 	}
 	
 	// Format agent line
-	agentID := boldStyle.Render("Agent " + strconv.Itoa(agent.ID))
-	status := style.Render(statusIcon + " " + strings.Title(agent.Status))
+	agentID := boldStyle.Render(i18n.T("tui.agent.name", agent.ID))
+	status := style.Render(statusIcon + " " + statusText)
+	paused := agent.Stats != nil && agent.Stats.Paused
+	if paused {
+		status += dimStyle.Render(i18n.T("tui.agent.paused"))
+	}
 	
 	// Current activity
 	activity := ""
 	if agent.CurrentTicket != nil {
-		activity = "\n  " + dimStyle.Render("Working on: " + *agent.CurrentTicket)
+		working := i18n.T("tui.agent.working_on", *agent.CurrentTicket)
+		if agent.Stats != nil && agent.Stats.Phase != "" {
+			working += " (" + agent.Stats.Phase + ")"
+		}
+		activity = "\n  " + dimStyle.Render(working)
+		if progress := formatCIProgress(agent); progress != "" {
+			activity += "\n  " + workingStyle.Render(progress)
+		}
+	} else if agent.Status == "idle" && paused {
+		activity = "\n  " + dimStyle.Render(i18n.T("tui.agent.resume_hint", agent.ID))
 	} else if agent.Status == "idle" {
-		activity = "\n  " + dimStyle.Render("Ready for work")
+		activity = "\n  " + dimStyle.Render(i18n.T("tui.agent.ready"))
+	} else if agent.Status == "error" && agent.Message != "" {
+		activity = "\n  " + errorStyle.Render(agent.Message)
 	}
 	
 	// Last activity time
 	timeSince := time.Since(agent.LastActivity)
 	timeStr := ""
 	if timeSince < time.Minute {
-		timeStr = "now"
+		timeStr = i18n.T("tui.agent.now")
 	} else if timeSince < time.Hour {
-		timeStr = fmt.Sprintf("%dm ago", int(timeSince.Minutes()))
+		timeStr = i18n.T("tui.agent.minutes_ago", int(timeSince.Minutes()))
 	} else {
-		timeStr = agent.LastActivity.Format("15:04")
+		timeStr = timefmt.Clock(agent.LastActivity)
 	}
 	
+	if agent.Stats != nil {
+		activity += "\n  " + dimStyle.Render(formatWorkerStats(*agent.Stats))
+	}
+
 	return fmt.Sprintf("%s %s\n  %s%s", 
 		status, 
 		agentID, 
-		dimStyle.Render("Last activity: " + timeStr),
+		dimStyle.Render(i18n.T("tui.agent.last_activity", timeStr)),
 		activity)
 }
 
+// formatCIProgress describes the step the agent's CI run is executing, or
+// returns "" outside the ci phase
+func formatCIProgress(agent AgentInfo) string {
+	if agent.Stats == nil || agent.Stats.Phase != "ci" || agent.Stats.CIStep == "" {
+		return ""
+	}
+	elapsed := agent.Stats.CIElapsed + time.Since(agent.LastActivity)
+	return i18n.T("tui.agent.ci_progress", agent.Stats.CIStep, elapsed.Round(time.Second))
+}
+
 // renderEventLine renders a single event line
 func (m Model) renderEventLine(event EventInfo) string {
-	timestamp := eventTimeStyle.Render(event.Timestamp.Format("15:04:05"))
+	timestamp := eventTimeStyle.Render(timefmt.Clock(event.Timestamp))
 	eventType := eventTypeStyle.Render("[" + event.Type + "]")
 	message := event.Message
 	
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/eventlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/search"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// watchEvents prints the daemon's events as they happen, after replaying
+// the event log with --replay
+func watchEvents(args []string) {
+	replay := false
+	var since time.Time
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value, hasValue := "", false
+		if name, v, ok := strings.Cut(arg, "="); ok && name == "--since" {
+			arg, value, hasValue = name, v, true
+		}
+		switch {
+		case arg == "--replay":
+			replay = true
+		case arg == "--since" && (hasValue || i+1 < len(args)):
+			if !hasValue {
+				i++
+				value = args[i]
+			}
+			t, err := search.ParseSince(value, time.Now())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+				os.Exit(1)
+			}
+			since = t
+		default:
+			fmt.Fprintln(os.Stderr, i18n.T("usage.watch", os.Args[0]))
+			os.Exit(1)
+		}
+	}
+	if !since.IsZero() && !replay {
+		fmt.Fprintln(os.Stderr, i18n.T("watch.since_needs_replay"))
+		os.Exit(1)
+	}
+
+	cfg := loadCIConfig()
+
+	// Connect first so nothing published while the log is read is missed
+	client, dialErr := dialDaemon(cfg)
+	if dialErr != nil && !replay {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", dialErr))
+		fmt.Fprintln(os.Stderr, i18n.T("cli.daemon_hint"))
+		os.Exit(1)
+	}
+
+	var replayedThrough time.Time
+	if replay {
+		if !cfg.EventLog.Enabled {
+			fmt.Fprintln(os.Stderr, i18n.T("watch.log_disabled"))
+		}
+		events, err := eventlog.Load(cfg.State.Path, since, loadCipher(cfg))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("watch.read_failed", err))
+			os.Exit(1)
+		}
+		for _, event := range events {
+			fmt.Println(formatEventLine(event))
+		}
+		if len(events) > 0 {
+			replayedThrough = events[len(events)-1].Timestamp
+		}
+		fmt.Println(i18n.T("watch.replayed", len(events)))
+	}
+	if dialErr != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("watch.not_following", dialErr))
+		return
+	}
+	defer client.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	for {
+		select {
+		case <-interrupt:
+			return
+		case event, ok := <-client.Events():
+			if !ok {
+				fmt.Fprintln(os.Stderr, i18n.T("watch.disconnected"))
+				return
+			}
+			// Events logged after we connected were already replayed
+			if !event.Timestamp.After(replayedThrough) {
+				continue
+			}
+			fmt.Println(formatEventLine(event))
+		}
+	}
+}
+
+// formatEventLine summarises an event on one line: its time and type, then
+// the worker, ticket and message when it has them, or else its data
+func formatEventLine(event ipc.Event) string {
+	line := fmt.Sprintf("%s  %-20s", timefmt.Timestamp(event.Timestamp), event.Type)
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return line
+	}
+
+	var parts []string
+	if workerID, ok := data["worker_id"].(float64); ok && workerID != 0 {
+		parts = append(parts, fmt.Sprintf("agent %d", int(workerID)))
+	}
+	for _, key := range []string{"ticket", "current_ticket", "next_ticket"} {
+		if t, ok := data[key].(map[string]interface{}); ok {
+			if id, ok := t["id"].(string); ok {
+				parts = append(parts, id)
+				break
+			}
+		}
+	}
+	for _, key := range []string{"status", "phase", "code"} {
+		if s, ok := data[key].(string); ok && s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if message, ok := data["message"].(string); ok && message != "" {
+		parts = append(parts, message)
+	}
+	if len(parts) == 0 {
+		raw, _ := json.Marshal(data)
+		return line + " " + string(raw)
+	}
+	return line + " " + strings.Join(parts, "  ")
+}
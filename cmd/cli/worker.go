@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+)
+
+// restartWorker asks the daemon to clear a worker's error state
+func restartWorker(id string) {
+	sendWorkerCommand("worker_restart", map[string]string{"id": id})
+}
+
+// pauseWorker asks the daemon to stop a worker taking tickets once its
+// current one is done
+func pauseWorker(id, reason string) {
+	sendWorkerCommand("worker_pause", map[string]string{"id": id, "reason": reason})
+}
+
+// resumeWorker asks the daemon to let a paused worker take tickets again
+func resumeWorker(id string) {
+	sendWorkerCommand("worker_resume", map[string]string{"id": id})
+}
+
+// cancelTicket asks the daemon to drop a queued ticket or stop the worker
+// running it
+func cancelTicket(id string) {
+	sendWorkerCommand("ticket_cancel", map[string]string{"id": id})
+}
+
+// approveTicket asks the daemon to let the held version of a
+// requires_approval ticket leave the backlog; version picks one when
+// several are held
+func approveTicket(id, version string) {
+	sendWorkerCommand("ticket_approve", map[string]string{"id": id, "version": version})
+}
+
+// sendWorkerCommand sends a worker control command and prints the reply
+func sendWorkerCommand(name string, args map[string]string) {
+	cfg := loadCIConfig()
+
+	client := connectDaemon(cfg)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := client.SendCommand(ctx, name, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
+		os.Exit(1)
+	}
+	if !response.OK {
+		fmt.Fprintln(os.Stderr, i18n.T("cli.error", response.Error))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.T("cli.done", response.Message))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/approval"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+)
+
+// enqueueTicketData writes a ticket file sent by a client into the backlog,
+// stamped with the caller, for the watcher to validate and enqueue
+func enqueueTicketData(backlogDir, name, data string, caller ipc.Caller) (string, error) {
+	t, err := ticket.LoadFromBytes([]byte(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid ticket: %w", err)
+	}
+	// Validate also keeps the ID safe to use as the file name below
+	if err := t.Validate(); err != nil {
+		return "", fmt.Errorf("invalid ticket: %w", err)
+	}
+
+	stamped, provenance, err := ticket.Stamp([]byte(data), ticket.Provenance{
+		EnqueuedBy: "ipc:" + caller.String(),
+		Source:     name,
+		EnqueuedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(backlogDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backlog directory: %w", err)
+	}
+
+	// Named after the ticket, whose ID was checked above, never after the
+	// client's file, which could point outside the backlog
+	path := filepath.Join(backlogDir, t.ID+".yaml")
+	for i := 1; ; i++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			path = filepath.Join(backlogDir, fmt.Sprintf("%s-%d.yaml", t.ID, i))
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to write to backlog: %w", err)
+		}
+		_, err = file.Write(stamped)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("failed to write to backlog: %w", err)
+		}
+		break
+	}
+
+	log.Printf("Ticket %s written to the backlog by %s", t.ID, caller)
+	return fmt.Sprintf("Enqueued ticket %s (%s)", t.ID, provenance.Checksum), nil
+}
+
+// cancelTicket removes a queued ticket, or has the worker running it kill
+// its agent and clean up; the worker publishes ticket_cancelled once done
+func cancelTicket(ticketQueue *queue.Queue, workers []*worker.Worker, ipcServer *ipc.Server, id string, caller ipc.Caller) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("a ticket ID is required")
+	}
+	reason := "cancelled by " + caller.String()
+	if t := ticketQueue.Take(id); t != nil {
+		log.Printf("Queued ticket %s cancelled by %s", id, caller)
+		ipcServer.PublishTicketCancelled(t, 0, reason)
+		return fmt.Sprintf("Cancelled queued ticket %s", id), nil
+	}
+	for _, w := range workers {
+		if w.Cancel(id, reason) {
+			log.Printf("Ticket %s on worker %d cancelled by %s", id, w.ID, caller)
+			return fmt.Sprintf("Cancelling ticket %s on worker %d", id, w.ID), nil
+		}
+	}
+	return "", fmt.Errorf("ticket %s is not queued or running", id)
+}
+
+// approveTicket records an admin's approval of the held version of a
+// requires_approval ticket; the watcher's next scan enqueues it from the
+// backlog
+func approveTicket(approvals *approval.Store, id, version string, caller ipc.Caller) (string, error) {
+	entry, err := approvals.Approve(id, version, caller.String())
+	if err != nil {
+		return "", err
+	}
+	if entry.By != caller.String() {
+		return fmt.Sprintf("Ticket %s was already approved by %s", id, entry.By), nil
+	}
+	log.Printf("Ticket %s (version %.12s) approved by %s", id, entry.Digest, caller)
+	return fmt.Sprintf("Approved ticket %s (version %.12s)", id, entry.Digest), nil
+}
+
+// pausePool stops every worker taking tickets; tickets in progress finish
+func pausePool(pauseGate *worker.PauseGate, reason string, caller ipc.Caller) (string, error) {
+	if reason == "" {
+		reason = "paused by " + caller.String()
+	}
+	if !pauseGate.Pause(reason) {
+		_, current := pauseGate.Paused()
+		return "Worker pool is already paused: " + current, nil
+	}
+	log.Printf("Pausing worker pool: %s", reason)
+	return "Worker pool paused: " + reason, nil
+}
+
+// resumePool lets the workers take tickets again, as SIGHUP does
+func resumePool(pauseGate *worker.PauseGate, caller ipc.Caller) (string, error) {
+	paused, reason := pauseGate.Paused()
+	if !paused {
+		return "Worker pool was not paused", nil
+	}
+	pauseGate.Resume()
+	log.Printf("Resuming worker pool (was paused: %s) at the request of %s", reason, caller)
+	return "Worker pool resumed", nil
+}
+
+// scalePool keeps the first n workers taking tickets and the rest on standby
+func scalePool(setAgents func(int), workerCount int, count string, caller ipc.Caller) (string, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n < 1 || n > workerCount {
+		return "", fmt.Errorf("worker count must be between 1 and %d, got %q", workerCount, count)
+	}
+	setAgents(n)
+	log.Printf("Worker pool scaled to %d of %d by %s", n, workerCount, caller)
+	return fmt.Sprintf("Scaled to %d of %d workers", n, workerCount), nil
+}
@@ -2,19 +2,58 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/brettsmith212/amp-orchestrator/internal/api"
+	"github.com/brettsmith212/amp-orchestrator/internal/approval"
+	"github.com/brettsmith212/amp-orchestrator/internal/audit"
+	"github.com/brettsmith212/amp-orchestrator/internal/backlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/chaos"
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/claim"
+	"github.com/brettsmith212/amp-orchestrator/internal/concurrency"
+	"github.com/brettsmith212/amp-orchestrator/internal/conflict"
 	"github.com/brettsmith212/amp-orchestrator/internal/config"
+	"github.com/brettsmith212/amp-orchestrator/internal/dashboard"
+	"github.com/brettsmith212/amp-orchestrator/internal/deadletter"
+	"github.com/brettsmith212/amp-orchestrator/internal/diskspace"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/eventlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/github"
+	"github.com/brettsmith212/amp-orchestrator/internal/hook"
 	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/kube"
+	"github.com/brettsmith212/amp-orchestrator/internal/locks"
+	"github.com/brettsmith212/amp-orchestrator/internal/policy"
 	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/quota"
+	"github.com/brettsmith212/amp-orchestrator/internal/ratelimit"
+	"github.com/brettsmith212/amp-orchestrator/internal/remote"
+	"github.com/brettsmith212/amp-orchestrator/internal/report"
+	"github.com/brettsmith212/amp-orchestrator/internal/rules"
+	"github.com/brettsmith212/amp-orchestrator/internal/scratch"
+	"github.com/brettsmith212/amp-orchestrator/internal/signing"
+	"github.com/brettsmith212/amp-orchestrator/internal/state"
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
+	"github.com/brettsmith212/amp-orchestrator/internal/summary"
 	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/throughput"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+	"github.com/brettsmith212/amp-orchestrator/internal/verify"
 	"github.com/brettsmith212/amp-orchestrator/internal/watch"
 	"github.com/brettsmith212/amp-orchestrator/internal/worker"
 	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
@@ -31,12 +70,24 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Log lines carry timestamps in the configured timezone and layout
+	log.SetFlags(0)
+	log.SetOutput(timefmt.LogWriter(os.Stderr))
+
 	// Log config loaded successfully
 	log.Printf("Configuration loaded successfully")
+	log.Printf("Project root: %s", cfg.Root)
 	log.Printf("Repository path: %s", cfg.Repository.Path)
 	log.Printf("Running with %d agents", cfg.Agents.Count)
 	log.Printf("Backlog path: %s", cfg.Scheduler.BacklogPath)
 
+	// Open the versioned state directory, migrating older layouts
+	stateDir, err := state.Open(cfg.State.Path)
+	if err != nil {
+		log.Fatalf("Failed to open state directory: %v", err)
+	}
+	log.Printf("State directory %s at format version %d", stateDir.Path, stateDir.Version)
+
 	// Create backlog directory if it doesn't exist
 	if err := os.MkdirAll(cfg.Scheduler.BacklogPath, 0755); err != nil {
 		log.Fatalf("Failed to create backlog directory: %v", err)
@@ -65,11 +116,101 @@ func main() {
 		}
 	}
 
-	// Install git hooks for CI integration
-	if err := installGitHooks(cfg.Repository.Path); err != nil {
-		log.Printf("Warning: Failed to install git hooks: %v", err)
+	// CI runs and agents share persistent module and build caches
+	goCacheEnv, err := ci.GoCacheEnv(cfg.CI.GoCacheDir)
+	if err != nil {
+		log.Fatalf("Failed to set up Go cache: %v", err)
+	}
+
+	// Archived tickets, agent logs and CI outputs are encrypted at rest when configured
+	cipher, err := encryption.Load(cfg.Encryption)
+	if err != nil {
+		log.Fatalf("Failed to set up encryption: %v", err)
+	}
+	if cipher.Encrypts() {
+		log.Printf("Encrypting archived tickets and logs at rest")
+	}
+
+	// Agent logs and CI outputs are offloaded to object storage when configured
+	var objectStore storage.Store
+	artifactConfig := cfg.Artifacts
+	if cfg.Storage.Backend == storage.BackendS3 {
+		s3Store, err := storage.NewS3Store(cfg.Storage.S3)
+		if err != nil {
+			log.Fatalf("Failed to set up object storage: %v", err)
+		}
+		objectStore = s3Store
+
+		if rules := cfg.Storage.Lifecycle.Rules(); len(rules) > 0 {
+			lifecycleCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := s3Store.ApplyLifecycle(lifecycleCtx, rules); err != nil {
+				log.Printf("Failed to apply object storage lifecycle rules: %v", err)
+			}
+			cancel()
+		}
+
+		// Artifacts share the bucket unless they have one of their own
+		if artifactConfig.Backend != storage.BackendS3 {
+			artifactConfig = storage.Config{Backend: storage.BackendS3, S3: cfg.Storage.S3}
+			artifactConfig.S3.Prefix = path.Join(cfg.Storage.S3.Prefix, strings.TrimSuffix(storage.ArtifactsPrefix, "/"))
+		}
+	}
+
+	// Tickets' artifacts are published here once CI passes
+	artifactStore, err := storage.NewStore(artifactConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up artifact store: %v", err)
+	}
+
+	// Set up the CI backend; external providers need no local hook
+	ciBackend, err := ci.NewBackend(ci.BackendConfig{
+		Backend:      cfg.CI.Backend,
+		StatusDir:    cfg.CI.StatusPath,
+		Timeout:      time.Duration(cfg.CI.Timeout) * time.Second,
+		TestRetries:  cfg.CI.TestRetries,
+		TestFlags:    cfg.CI.Test,
+		VulnScan:     cfg.CI.VulnScan,
+		Env:          goCacheEnv,
+		Matrix:       cfg.CI.Matrix,
+		Limits:       cfg.Agents.Limits,
+		Remote:       cfg.CI.Remote,
+		PollInterval: time.Duration(cfg.CI.PollInterval) * time.Second,
+		GitHub:       cfg.CI.GitHub,
+		Buildkite:    cfg.CI.Buildkite,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up CI backend: %v", err)
+	}
+
+	// Statuses are shared through the status store; status_path keeps the local copy CI writes
+	ciStatusStore, err := ci.NewStatusStore(cfg.CI.StatusStore, cfg.CI.StatusPath)
+	if err != nil {
+		log.Fatalf("Failed to set up CI status store: %v", err)
+	}
+	if cfg.CI.StatusStore.Backend == ci.StoreS3 {
+		log.Printf("Sharing CI statuses through s3 bucket %s", cfg.CI.StatusStore.S3.Bucket)
+	}
+
+	// Chaos mode injects failures to exercise retries, dead letters and recovery
+	monkey := chaos.New(cfg.Testing.Chaos)
+	ciBackend = monkey.WrapBackend(ciBackend)
+
+	if cfg.CI.Backend == "" || cfg.CI.Backend == ci.BackendLocal {
+		// Install git hooks for CI integration
+		if err := installGitHooks(cfg.Repository.Path); err != nil {
+			log.Printf("Warning: Failed to install git hooks: %v", err)
+		} else {
+			log.Printf("Installed git hooks for CI integration")
+		}
 	} else {
-		log.Printf("Installed git hooks for CI integration")
+		log.Printf("Using %s CI backend", cfg.CI.Backend)
+	}
+
+	// Track CI on main; with pause_when_main_red, workers hold new tickets while it is red
+	var mainTracker *ci.MainTracker
+	mainRedGate := worker.NewPauseGate()
+	if cfg.CI.Main.Enabled || cfg.Scheduler.PauseWhenMainRed {
+		mainTracker = ci.NewMainTracker(cfg.Repository.Path, ciBackend, cfg.CI.StatusPath)
 	}
 
 	// Initialize priority queue
@@ -81,7 +222,231 @@ func main() {
 	if ipcSocketPath == "" {
 		ipcSocketPath = "~/.orchestrator.sock"
 	}
+	ipcAuth, err := ipc.NewAuthenticator(cfg.IPC.Auth)
+	if err != nil {
+		log.Fatalf("Failed to set up IPC auth: %v", err)
+	}
 	ipcServer := ipc.NewServer(ipcSocketPath)
+	ipcServer.SetSocketPermissions(cfg.IPC.Socket)
+	ipcServer.SetAuthenticator(ipcAuth)
+	if monkey != nil {
+		ipcServer.SetDisconnector(monkey.Disconnect)
+	}
+
+	// Every control command and who issued it is kept in the audit journal
+	journal := audit.Open(stateDir.Path, cipher)
+	ipcServer.SetCommandRecorder(func(record ipc.CommandRecord) {
+		if err := journal.Record(record); err != nil {
+			log.Printf("Failed to record %s in audit journal: %v", record.Name, err)
+		}
+	})
+
+	// Skipped CI runs and enqueued tickets are audited alongside commands
+	ipcServer.AddEventObserver(func(event ipc.Event) {
+		if err := journal.RecordCISkip(event); err != nil {
+			log.Printf("Failed to record CI skip in audit journal: %v", err)
+		}
+		if err := journal.RecordEnqueue(event); err != nil {
+			log.Printf("Failed to record enqueue in audit journal: %v", err)
+		}
+		if err := journal.RecordRegressionTest(event); err != nil {
+			log.Printf("Failed to record regression test check in audit journal: %v", err)
+		}
+	})
+
+	// With event_log.enabled every event is kept for orchestrator watch --replay
+	if cfg.EventLog.Enabled {
+		events, err := eventlog.Open(stateDir.Path, cfg.EventLog, cipher)
+		if err != nil {
+			log.Fatalf("Failed to open event log: %v", err)
+		}
+		defer events.Close()
+		ipcServer.AddEventObserver(func(event ipc.Event) {
+			if err := events.Record(event); err != nil {
+				log.Printf("Failed to record %s event: %v", event.Type, err)
+			}
+		})
+	}
+
+	// Tickets workers gave up on are kept with their error code
+	deadLetters := deadletter.Open(stateDir.Path, cipher)
+	ipcServer.AddEventObserver(func(event ipc.Event) {
+		if err := deadLetters.Record(event); err != nil {
+			log.Printf("Failed to record dead letter: %v", err)
+		}
+	})
+
+	// Ticket events are kept for timelines
+	timelineJournal := timeline.Open(stateDir.Path, cipher)
+	ipcServer.AddEventObserver(func(event ipc.Event) {
+		if err := timelineJournal.Record(event); err != nil {
+			log.Printf("Failed to record %s in timeline journal: %v", event.Type, err)
+		}
+	})
+
+	// Completed tickets feed the throughput metrics behind the backlog forecast
+	throughputDir := ""
+	if cfg.Metrics.Enabled {
+		throughputDir = cfg.Metrics.OutputPath
+	}
+	recorder := throughput.NewRecorder(throughputDir)
+	ipcServer.AddEventObserver(func(event ipc.Event) {
+		if err := recorder.Record(event); err != nil {
+			log.Printf("Failed to record throughput: %v", err)
+		}
+	})
+
+	// Worker slots reserved for urgent tickets are held back from the rest
+	var slots *queue.Slots
+	if len(cfg.Scheduler.Reservations) > 0 {
+		slots = queue.NewSlots(cfg.Agents.Count, cfg.Scheduler.Reservations)
+	}
+
+	// A concurrency experiment starts max_count workers and keeps all but the
+	// number under trial on standby; operators can scale the pool the same way
+	workerCount := cfg.Agents.Count
+	if exp := cfg.Agents.Experiment; exp.Enabled {
+		workerCount = exp.MaxCount
+	}
+	standby := make([]*worker.PauseGate, workerCount)
+	for i := range standby {
+		standby[i] = worker.NewPauseGate()
+	}
+	setAgents := func(n int, reason string) {
+		slots.SetSize(n)
+		for i, gate := range standby {
+			if i < n {
+				gate.Resume()
+			} else {
+				gate.Pause(reason)
+			}
+		}
+	}
+	var experiment *concurrency.Controller
+	if exp := cfg.Agents.Experiment; exp.Enabled {
+		setAgents(exp.MinCount, "concurrency experiment")
+		experiment = concurrency.New(concurrency.Config{
+			MinAgents:  exp.MinCount,
+			MaxAgents:  exp.MaxCount,
+			Period:     time.Duration(exp.PeriodMinutes) * time.Minute,
+			MetricsDir: cfg.Metrics.OutputPath,
+			SetAgents: func(n int) {
+				setAgents(n, "concurrency experiment")
+			},
+			Backlog: ticketQueue.Len,
+		})
+		ipcServer.AddEventObserver(experiment.Record)
+		log.Printf("Running a concurrency experiment with %d to %d agents", exp.MinCount, exp.MaxCount)
+	}
+
+	// Configured rules react to events as they are published
+	if len(cfg.Rules) > 0 {
+		engine, err := rules.New(cfg.Rules, cfg.Scheduler.BacklogPath)
+		if err != nil {
+			log.Fatalf("Failed to set up event rules: %v", err)
+		}
+		engine.SetNotifier(ipcServer.PublishRuleTriggered)
+		ipcServer.AddEventObserver(func(event ipc.Event) {
+			// A rule's own notifications never trigger rules
+			if event.Type != ipc.EventTypeRuleTriggered {
+				engine.Handle(string(event.Type), event.Data)
+			}
+		})
+		log.Printf("Loaded %d event rules", len(cfg.Rules))
+	}
+
+	// Completed tickets with a definition of done are checked once merged
+	var doneVerifier *verify.Verifier
+	if cfg.Verify.Enabled {
+		doneVerifier, err = verify.New(cfg.Verify, cfg.Repository.Path, cfg.Environments, cfg.Repository.Workdir, cfg.Scheduler.BacklogPath, stateDir.Path)
+		if err != nil {
+			log.Fatalf("Failed to set up verifier: %v", err)
+		}
+		if cfg.Verify.MainCI {
+			if err := doneVerifier.WatchMainCI(cfg.CI.StatusPath); err != nil {
+				log.Fatalf("Failed to watch CI on main: %v", err)
+			}
+		}
+		doneVerifier.SetResultHandler(ipcServer.PublishTicketVerified)
+		ipcServer.AddEventObserver(func(event ipc.Event) {
+			if err := doneVerifier.Record(event); err != nil {
+				log.Printf("Failed to track ticket for verification: %v", err)
+			}
+		})
+	}
+
+	// Completed branches git cannot merge into main get a resolution ticket
+	var resolver *conflict.Resolver
+	if cfg.Conflicts.Enabled {
+		resolver = conflict.New(cfg.Conflicts, cfg.Repository.Path, cfg.Environments, cfg.Scheduler.BacklogPath)
+		resolver.SetConflictHandler(ipcServer.PublishMergeConflict)
+		ipcServer.AddEventObserver(resolver.Record)
+	}
+
+	// Completed branches are pushed to GitHub with a pull request each
+	var pullRequests *github.Publisher
+	if cfg.Integrations.GitHub.Enabled {
+		pullRequests, err = github.New(cfg.Integrations.GitHub, cfg.Repository.Path, cfg.Environments, cfg.CI.StatusPath)
+		if err != nil {
+			log.Fatalf("Failed to set up GitHub integration: %v", err)
+		}
+		pullRequests.SetResultHandler(ipcServer.PublishPullRequest)
+		ipcServer.AddEventObserver(pullRequests.Record)
+	}
+
+	// Completed tickets get a Markdown report of what the agent did
+	var reports *report.Writer
+	if cfg.Reports.Enabled {
+		reports = report.New(cfg.Reports, cfg.Repository.Path, cfg.Environments, cfg.CI.StatusPath, func(ticketID string) (timeline.Timeline, error) {
+			return timeline.Assemble(stateDir.Path, cipher, cfg.CI.StatusPath, ticketID)
+		})
+		reports.SetCipher(cipher)
+		ipcServer.AddEventObserver(reports.Record)
+	}
+
+	// The dashboard records events from the start and serves once workers exist
+	var dash *dashboard.Server
+	var workers []*worker.Worker
+	if cfg.Dashboard.Enabled {
+		ciStatus := ci.NewStoreStatusReader(ciStatusStore)
+		dash = dashboard.New(dashboard.Config{
+			Auth:  ipcAuth,
+			Queue: ticketQueue.List,
+			Workers: func() []worker.WorkerStatus {
+				statuses := make([]worker.WorkerStatus, len(workers))
+				for i, w := range workers {
+					statuses[i] = w.GetStatus()
+				}
+				return statuses
+			},
+			CI: func() ([]*ci.Status, error) {
+				return ciStatus.ListSince(time.Now().Add(-24 * time.Hour))
+			},
+			Timeline: func(ticketID string) (timeline.Timeline, error) {
+				return timeline.Assemble(stateDir.Path, cipher, cfg.CI.StatusPath, ticketID)
+			},
+		})
+		ipcServer.AddEventObserver(dash.Publish)
+	}
+
+	// Setup graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// With ci.quick_tests, tickets complete on the quick CI tier and the full
+	// suite runs afterwards, gating the merge
+	localCI := cfg.CI.Backend == "" || cfg.CI.Backend == ci.BackendLocal
+	if cfg.CI.QuickTests && localCI && !cfg.Testing.SkipCI {
+		fullTier := ci.NewFullTier(ctx, ciBackend, cfg.Repository.Path, cfg.Environments, cfg.CI.Profiles, cfg.CI.StatusPath)
+		fullTier.SetResultHandler(ipcServer.PublishCIFullTier)
+		ipcServer.AddEventObserver(func(event ipc.Event) {
+			if err := fullTier.Check(event); err != nil {
+				log.Printf("Failed to start the full CI tier: %v", err)
+			}
+		})
+		log.Printf("Gating tickets on the quick CI tier; the full tier runs once they complete")
+	}
+
 	if err := ipcServer.Start(); err != nil {
 		log.Printf("Warning: Failed to start IPC server: %v", err)
 		ipcServer = nil
@@ -89,38 +454,262 @@ func main() {
 		log.Printf("Started IPC server on %s", ipcSocketPath)
 	}
 
+	// TUIs and scripts on other machines get the same events and commands over WebSockets
+	if cfg.IPC.WebSocket.ListenAddr != "" {
+		if ipcServer == nil {
+			log.Fatalf("The WebSocket bridge needs the IPC server, which failed to start")
+		}
+		if loopback, _ := config.IsLoopback(cfg.IPC.WebSocket.ListenAddr); !loopback && !cfg.IPC.WebSocket.TLS() {
+			log.Printf("Warning: the WebSocket bridge on %s has no TLS, so its token crosses the network in the clear; set ipc.websocket.tls_cert and tls_key or put it behind a TLS-terminating proxy", cfg.IPC.WebSocket.ListenAddr)
+		}
+		if err := ipcServer.StartWebSocket(cfg.IPC.WebSocket); err != nil {
+			log.Fatalf("Failed to start the WebSocket bridge: %v", err)
+		}
+	}
+
 	// Initialize backlog watcher
 	watcherConfig := watch.Config{
 		BacklogPath:    cfg.Scheduler.BacklogPath,
 		TickerInterval: time.Duration(cfg.Scheduler.PollInterval) * time.Second,
+		DefaultsPath:   cfg.Scheduler.TicketDefaults,
+		Templating:     cfg.Templating,
 	}
 
 	watcher, err := watch.New(watcherConfig, ticketQueue)
 	if err != nil {
 		log.Fatalf("Failed to create backlog watcher: %v", err)
 	}
+	watcher.SetCipher(cipher)
 
-	// Set up IPC event publishing for watcher
+	// Daemons sharing the repository claim each ticket before enqueueing it
+	var claimer *claim.Claimer
+	if cfg.Coordination.Enabled {
+		node := cfg.Coordination.NodeID
+		if node == "" {
+			if node, err = os.Hostname(); err != nil {
+				log.Fatalf("Failed to determine node ID: %v", err)
+			}
+		}
+		claimer = claim.New(cfg.Repository.Path, node, time.Duration(cfg.Coordination.LeaseSeconds)*time.Second)
+		watcher.SetClaimer(func(t *ticket.Ticket) (bool, error) {
+			return claimer.Claim(t.ID)
+		})
+		log.Printf("Coordinating with other daemons as node %s", node)
+	}
+
+	// Tickets whose lock sets intersect are never worked on at once; a
+	// locked ticket stays queued until the holder finishes
+	ticketLocks := locks.New()
+	ticketLocks.SetPublisher(func(e locks.Event) {
+		log.Printf("Locks: %v", e)
+		if ipcServer == nil {
+			return
+		}
+		event := ipc.LockEvent{Ticket: e.Ticket, Locks: e.Locks, HeldBy: e.HeldBy, Message: e.String()}
+		switch e.Kind {
+		case locks.Acquired:
+			ipcServer.PublishLockAcquired(event)
+		case locks.Released:
+			ipcServer.PublishLockReleased(event)
+		case locks.Waiting:
+			ipcServer.PublishLockWaiting(event)
+		}
+	})
+
+	// Per-tag quotas keep one team's tickets from monopolizing the workers;
+	// tickets requeued for retry wait out their backoff
+	quotas := quota.New(cfg.Scheduler.Quotas)
+	if quotas == nil {
+		ticketQueue.SetGate(queue.Gates(ticketLocks, queue.RetryBackoff()))
+	} else {
+		ticketQueue.SetGate(queue.Gates(ticketLocks, queue.RetryBackoff(), quotas))
+		quotas.SetPublisher(func(x quota.Exceeded) {
+			message := fmt.Sprintf("Ticket %s held back: %v", x.Ticket.ID, x)
+			log.Print(message)
+			if ipcServer != nil {
+				ipcServer.PublishQuotaExceeded(ipc.QuotaExceededEvent{
+					Ticket:  x.Ticket,
+					Tag:     x.Tag,
+					Limit:   x.Limit,
+					Max:     x.Max,
+					Current: x.Current,
+					Message: message,
+				})
+			}
+		})
+		log.Printf("Enforcing quotas for %d tags", len(cfg.Scheduler.Quotas))
+	}
+
+	// Tickets with requires_approval stay in the backlog until an admin
+	// runs orchestrator approve; quotas are checked once they are approved
+	approvals, err := approval.Open(stateDir.Path, cipher)
+	if err != nil {
+		log.Fatalf("Failed to load approvals: %v", err)
+	}
+	watcher.SetAdmitter(func(t *ticket.Ticket) error {
+		if err := approvals.Admit(t); err != nil {
+			return err
+		}
+		return quotas.Admit(t, ticketQueue.List())
+	})
+
+	// Check ticket signatures, then the environment's guardrails and the
+	// policy rules, then the external validation hook
+	verifier, err := signing.NewVerifier(cfg.Signing)
+	if err != nil {
+		log.Fatalf("Failed to load ticket signing keys: %v", err)
+	}
+	if cfg.Signing.RequireSignedTickets {
+		log.Printf("Rejecting tickets without a valid signature")
+	}
+	validator := hook.New(hook.Config{
+		URL:     cfg.Validation.URL,
+		Command: cfg.Validation.Command,
+		Timeout: time.Duration(cfg.Validation.Timeout) * time.Second,
+	})
+	if validator != nil {
+		log.Printf("Validating tickets with external hook before enqueue")
+	}
+	watcher.SetValidator(func(t *ticket.Ticket) error {
+		if err := verifier.Verify(t); err != nil {
+			return err
+		}
+		if err := cfg.Environments.Check(t); err != nil {
+			return err
+		}
+		if err := cfg.Policy.Check(t); err != nil {
+			return err
+		}
+
+		decision, err := validator.Validate(t)
+		if err != nil {
+			if cfg.Validation.FailOpen {
+				log.Printf("Warning: Validation hook failed for %s, enqueueing anyway: %v", t.ID, err)
+				return nil
+			}
+			// The hook being down says nothing about the ticket; try again
+			// on the next scan rather than rejecting it
+			return fmt.Errorf("%w: validation hook failed: %w", watch.ErrValidatorUnavailable, err)
+		}
+		if !decision.Allow {
+			if decision.Reason == "" {
+				return errors.New("rejected by validation hook")
+			}
+			return errors.New(decision.Reason)
+		}
+		return nil
+	})
+
+	// Enqueuing a requires_approval ticket spends its approval, and is
+	// published to IPC clients
+	watcher.SetEventPublisher(func(t *ticket.Ticket) {
+		if err := approvals.Consume(t); err != nil {
+			log.Printf("Failed to record the use of ticket %s's approval: %v", t.ID, err)
+		}
+		if ipcServer == nil {
+			return
+		}
+		ipcServer.PublishTicketEnqueued(t)
+		// Also publish queue update
+		var nextTicket *ticket.Ticket
+		if ticketQueue.Len() > 0 {
+			nextTicket = ticketQueue.Peek()
+		}
+		ipcServer.PublishQueueUpdated(ticketQueue.Len(), nextTicket)
+	})
 	if ipcServer != nil {
-		watcher.SetEventPublisher(func(t *ticket.Ticket) {
-			ipcServer.PublishTicketEnqueued(t)
-			// Also publish queue update
-			var nextTicket *ticket.Ticket
-			if ticketQueue.Len() > 0 {
-				nextTicket = ticketQueue.Peek()
+		watcher.SetRejectionPublisher(func(t *ticket.Ticket, reason error) {
+			var violations policy.Violations
+			if errors.As(reason, &violations) {
+				ipcServer.PublishPolicyViolation(t, violations)
+				return
 			}
-			ipcServer.PublishQueueUpdated(ticketQueue.Len(), nextTicket)
+			ipcServer.PublishTicketRejected(t, reason.Error())
 		})
 	}
 
-	// Setup graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Handle commands from CLI clients
+	if ipcServer != nil {
+		ciReruns := &sync.Map{} // Commits with a rerun in progress, to who asked
+		ipcServer.HandleCommand("ci_rerun", ipc.RoleOperator, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return rerunCI(repo, ciBackend, ciStatusStore, cfg.CI.StatusPath, ciReruns, args["target"], caller)
+		})
+		ipcServer.HandleQuietCommand("status", ipc.RoleViewer, func(caller ipc.Caller, args map[string]string) (string, error) {
+			report, err := recorder.Report(ticketQueue.Len(), time.Duration(cfg.Metrics.ForecastWindowDays)*24*time.Hour)
+			if err != nil {
+				return "", err
+			}
+			for _, w := range workers {
+				report.Workers = append(report.Workers, *workerStats(w.GetStatus()))
+			}
+			for _, t := range ticketQueue.List() {
+				report.Queue = append(report.Queue, queue.NewListing(t))
+			}
+			if err := queue.SortListings(report.Queue, queue.SortPriority); err != nil {
+				return "", err
+			}
+			if stats, err := backlog.Stats(cfg.Scheduler.BacklogPath); err != nil {
+				log.Printf("Failed to count processed tickets: %v", err)
+			} else {
+				report.Backlog = &stats
+			}
+			if mainTracker != nil {
+				health := mainTracker.Health()
+				health.Held, _ = mainRedGate.Paused()
+				report.Main = &health
+			}
+			data, err := json.Marshal(report)
+			return string(data), err
+		})
+		ipcServer.HandleQuietCommand("list", ipc.RoleViewer, func(caller ipc.Caller, args map[string]string) (string, error) {
+			var listings []queue.Listing
+			for _, w := range workers {
+				status := w.GetStatus()
+				if status.CurrentTicket == nil || status.CurrentTicket.Ticket == nil {
+					continue
+				}
+				listing := queue.NewListing(status.CurrentTicket.Ticket)
+				listing.WorkerID = status.ID
+				listing.Phase = status.Phase
+				listings = append(listings, listing)
+			}
+			for _, t := range ticketQueue.List() {
+				listings = append(listings, queue.NewListing(t))
+			}
+			data, err := json.Marshal(listings)
+			return string(data), err
+		})
+	}
 
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Hand tickets to worker processes on other machines
+	if cfg.Remote.Enabled {
+		if ipcServer == nil {
+			log.Fatalf("Remote workers need the IPC server, which failed to start")
+		}
+		coordinator := remote.NewCoordinator(remote.Config{
+			RepoURL:       cfg.Remote.RepoURL,
+			FirstWorkerID: workerCount + 1,
+			Lease:         time.Duration(cfg.Remote.LeaseSeconds) * time.Second,
+			LogDir:        filepath.Join(cfg.Repository.Workdir, "remote-logs"),
+			Claims:        claimer,
+			Quotas:        quotas,
+			Locks:         ticketLocks,
+		}, ticketQueue, ipcServer)
+		coordinator.Register(ipcServer)
+		if loopback, _ := config.IsLoopback(cfg.Remote.ListenAddress); !loopback {
+			log.Printf("Warning: remote workers connect to %s without TLS, so their tokens and tickets cross the network in the clear; put it behind a VPN or TLS tunnel", cfg.Remote.ListenAddress)
+		}
+		if err := ipcServer.StartTCP(cfg.Remote.ListenAddress); err != nil {
+			log.Fatalf("Failed to listen for remote workers: %v", err)
+		}
+		go coordinator.Run(ctx)
+		log.Printf("Accepting remote workers on %s (repository %s)", cfg.Remote.ListenAddress, cfg.Remote.RepoURL)
+	}
+
 	// Start watcher in a goroutine
 	go func() {
 		log.Printf("Starting backlog watcher...")
@@ -129,36 +718,153 @@ func main() {
 		}
 	}()
 
+	// Shared amp thread registry for tickets with a context group
+	threads, err := worker.NewThreadRegistry(filepath.Join(stateDir.Path, "threads.json"))
+	if err != nil {
+		log.Fatalf("Failed to load amp thread registry: %v", err)
+	}
+
+	// Shared quota for agent calls across all workers
+	rateLimit := cfg.Agents.RateLimit
+	globalLimiter := ratelimit.New(rateLimit.MaxPerHour, rateLimit.MaxConcurrent)
+
+	// Workers stop picking up tickets while the agent is logged out
+	pauseGate := worker.NewPauseGate()
+
+	// ...and while the workdir or repository is running out of space
+	lowDiskGate := worker.NewPauseGate()
+
+	// With the kubernetes backend, agents run as Jobs instead of local processes
+	var jobs worker.JobRunner
+	if cfg.Agents.Backend == kube.BackendKubernetes {
+		runner, err := kube.NewRunner(cfg.Agents.Kubernetes, time.Duration(cfg.Agents.Timeout)*time.Second)
+		if err != nil {
+			log.Fatalf("Failed to set up kubernetes backend: %v", err)
+		}
+		jobs = runner
+		log.Printf("Running agents as kubernetes jobs with image %s", cfg.Agents.Kubernetes.Image)
+	}
+
+	// Flaky test metrics are kept alongside the other metrics
+	metricsDir := ""
+	if cfg.Metrics.Enabled {
+		metricsDir = cfg.Metrics.OutputPath
+	}
+	ticketLogDir := ""
+	if cfg.TicketLogs.Enabled {
+		ticketLogDir = cfg.TicketLogs.Path
+	}
+
+	// Idle workers share the configured chores
+	housekeeper := worker.NewHousekeeper(cfg.Agents.Housekeeping)
+
 	// Start workers
-	workers := make([]*worker.Worker, cfg.Agents.Count)
-	for i := 0; i < cfg.Agents.Count; i++ {
+	workers = make([]*worker.Worker, workerCount)
+	for i := 0; i < workerCount; i++ {
 		workerConfig := worker.Config{
-			ID:          i + 1,
-			RepoPath:    cfg.Repository.Path,
-			WorkDir:     cfg.Repository.Workdir,
-			CIStatusDir: cfg.CI.StatusPath,
-			SkipCI:      cfg.Testing.SkipCI,
-			SkipAmp:     cfg.Testing.SkipAmp,
+			ID:               i + 1,
+			RepoPath:         cfg.Repository.Path,
+			WorkDir:          cfg.Repository.Workdir,
+			CIStatusDir:      cfg.CI.StatusPath,
+			CIStatusStore:    ciStatusStore,
+			CIBackend:        ciBackend,
+			CIProfiles:       cfg.CI.Profiles,
+			VulnScan:         cfg.CI.VulnScan,
+			QuickTests:       cfg.CI.QuickTests && localCI,
+			DocsPaths:        cfg.CI.DocsPaths,
+			Regression:       cfg.Agents.RegressionTests,
+			Commits:          cfg.Agents.Commits,
+			FileGuard:        cfg.Agents.FileGuard,
+			Attribution:      cfg.Agents.Attribution,
+			LicenseScan:      cfg.Agents.LicenseScan,
+			Precheck:         cfg.Agents.Precheck,
+			PrePush:          cfg.Agents.PrePush,
+			Retry:            cfg.Agents.Retry,
+			MetricsDir:       metricsDir,
+			TicketLogDir:     ticketLogDir,
+			SkipCI:           cfg.Testing.SkipCI,
+			SkipAmp:          cfg.Testing.SkipAmp,
+			Threads:          threads,
+			Pause:            pauseGate,
+			LowDisk:          lowDiskGate,
+			MainRed:          mainRedGate,
+			Limits:           cfg.Agents.Limits,
+			MaxFailures:      cfg.Agents.MaxFailures,
+			RetryBranch:      cfg.Agents.RetryBranch,
+			Environments:     cfg.Environments,
+			Chaos:            monkey,
+			Housekeeper:      housekeeper,
+			Env:              goCacheEnv,
+			ArtifactStore:    artifactStore,
+			ObjectStore:      objectStore,
+			Cipher:           cipher,
+			Claims:           claimer,
+			Quotas:           quotas,
+			Locks:            ticketLocks,
+			Jobs:             jobs,
+			Slots:            slots,
+			GlobalLimiter:    globalLimiter,
+			WorkerMaxPerHour: rateLimit.WorkerMaxPerHour,
+			RateLimitRetries: rateLimit.MaxRetries,
+			RateLimitBackoff: time.Duration(rateLimit.BackoffSeconds) * time.Second,
 		}
 
+		workerConfig.Standby = standby[i]
+
 		workers[i] = worker.New(workerConfig, ticketQueue)
 
 		// Set up IPC event publishing for worker
 		if ipcServer != nil {
-			workers[i].SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+			w := workers[i]
+			w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+				stats := workerStats(w.GetStatus())
 				switch eventType {
 				case "started":
 				if t != nil {
 				 // Worker started processing a ticket
 				  ipcServer.PublishTicketStarted(t, workerID)
-				 ipcServer.PublishWorkerStatus(workerID, "working", t, message)
+				 ipcServer.PublishWorkerStats(workerID, "working", t, message, stats)
 				} else {
 				  // Worker just started and is ready (idle)
-					ipcServer.PublishWorkerStatus(workerID, "idle", nil, message)
+					ipcServer.PublishWorkerStats(workerID, "idle", nil, message, stats)
 				}
 			case "completed":
 				ipcServer.PublishTicketComplete(t, workerID)
-				ipcServer.PublishWorkerStatus(workerID, "idle", nil, message)
+				ipcServer.PublishWorkerStats(workerID, "idle", nil, message, stats)
+			case "auth_error":
+				ipcServer.PublishAgentAuthError(workerID, t, message)
+				ipcServer.PublishWorkerStats(workerID, "error", nil, message, stats)
+			case "limit_exceeded":
+				ipcServer.PublishResourceLimitExceeded(workerID, t, message)
+			case "ci_flaky":
+				ipcServer.PublishCIFlaky(workerID, t, message)
+			case "not_ready":
+				ipcServer.PublishWorkerStats(workerID, "error", nil, message, stats)
+			case "ci_skipped":
+				ipcServer.PublishCISkipped(workerID, t, message)
+			case "ci_running":
+				ipcServer.PublishCIRunning(workerID, t, stats.CIStep, stats.CIElapsed, message)
+			case "regression_test_passed", "regression_test_missing":
+				ipcServer.PublishRegressionTest(workerID, t, eventType == "regression_test_passed", message)
+			case "files_rejected", "files_warned":
+				ipcServer.PublishGuardedFiles(workerID, t, eventType == "files_rejected", message)
+			case "phase":
+				ipcServer.PublishTicketPhase(workerID, t, message)
+				ipcServer.PublishWorkerStats(workerID, "working", t, "Entered "+message+" phase", stats)
+			case "failed":
+				ipcServer.PublishTicketFailed(t, workerID, ipc.ErrorCode(t.FailureCode), message)
+				ipcServer.PublishWorkerStats(workerID, "idle", nil, message, stats)
+			case "error":
+				ipcServer.PublishWorkerStats(workerID, "error", t, message, stats)
+			case "preempted":
+				ipcServer.PublishTicketPreempted(t, workerID, message)
+				ipcServer.PublishWorkerStats(workerID, "idle", nil, message, stats)
+			case "retrying":
+				ipcServer.PublishTicketRetrying(t, workerID, message)
+				ipcServer.PublishWorkerStats(workerID, "idle", nil, message, stats)
+			case "cancelled":
+				ipcServer.PublishTicketCancelled(t, workerID, message)
+				ipcServer.PublishWorkerStats(workerID, "idle", nil, message, stats)
 			}
 			})
 		}
@@ -172,6 +878,113 @@ func main() {
 		}(workers[i])
 	}
 
+	if experiment != nil {
+		go experiment.Run(ctx)
+	}
+
+	if doneVerifier != nil {
+		go doneVerifier.Run(ctx)
+		log.Printf("Verifying merged tickets every %ds (on failure: %s)", cfg.Verify.IntervalSeconds, cfg.Verify.OnFailure)
+	}
+
+	if cfg.Metrics.Enabled && cfg.Metrics.Summary.Enabled {
+		summaries := summary.NewScheduler(cfg.Metrics.Summary, summary.Sources{
+			MetricsDir: cfg.Metrics.OutputPath,
+			StateDir:   stateDir.Path,
+			Cipher:     cipher,
+		})
+		go summaries.Run(ctx)
+		log.Printf("Writing weekly summaries to %s", cfg.Metrics.OutputPath)
+	}
+
+	if reports != nil {
+		go reports.Run(ctx)
+		log.Printf("Writing completion reports to %s", cfg.Reports.Path)
+	}
+
+	if resolver != nil {
+		go resolver.Run(ctx)
+	}
+
+	if pullRequests != nil {
+		go pullRequests.Run(ctx)
+		log.Printf("Opening pull requests on %s against %s", cfg.Integrations.GitHub.Repo, cfg.Integrations.GitHub.Base)
+	}
+
+	if p := cfg.Scheduler.Preemption; p.Enabled {
+		preemptor := worker.NewPreemptor(ticketQueue, workers, p.UrgentPriority, p.VictimPriority)
+		go preemptor.Run(ctx, time.Duration(cfg.Scheduler.PollInterval)*time.Second)
+		log.Printf("Preempting priority %d+ tickets for priority %d and more urgent", p.VictimPriority, p.UrgentPriority)
+	}
+
+	if ipcServer != nil {
+		ipcServer.HandleCommand("worker_restart", ipc.RoleOperator, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return restartWorker(workers, args["id"], caller)
+		})
+		ipcServer.HandleCommand("ticket_enqueue", ipc.RoleOperator, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return enqueueTicketData(cfg.Scheduler.BacklogPath, args["name"], args["data"], caller)
+		})
+		ipcServer.HandleCommand("ticket_cancel", ipc.RoleOperator, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return cancelTicket(ticketQueue, workers, ipcServer, args["id"], caller)
+		})
+		ipcServer.HandleCommand("ticket_approve", ipc.RoleAdmin, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return approveTicket(approvals, args["id"], args["version"], caller)
+		})
+		ipcServer.HandleCommand("pool_pause", ipc.RoleAdmin, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return pausePool(pauseGate, args["reason"], caller)
+		})
+		ipcServer.HandleCommand("pool_resume", ipc.RoleAdmin, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return resumePool(pauseGate, caller)
+		})
+		ipcServer.HandleCommand("pool_scale", ipc.RoleAdmin, func(caller ipc.Caller, args map[string]string) (string, error) {
+			if experiment != nil {
+				return "", fmt.Errorf("the concurrency experiment sets the number of workers")
+			}
+			return scalePool(func(n int) { setAgents(n, "scaled down by "+caller.String()) }, workerCount, args["count"], caller)
+		})
+		ipcServer.HandleCommand("worker_pause", ipc.RoleOperator, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return pauseWorker(workers, args["id"], args["reason"], caller, ipcServer)
+		})
+		ipcServer.HandleCommand("worker_resume", ipc.RoleOperator, func(caller ipc.Caller, args map[string]string) (string, error) {
+			return resumeWorker(workers, args["id"], caller, ipcServer)
+		})
+	}
+
+	if dash != nil {
+		if err := dash.Start(cfg.Dashboard.ListenAddress); err != nil {
+			log.Fatalf("Failed to start dashboard: %v", err)
+		}
+		log.Printf("Serving dashboard on http://%s", dash.Addr())
+	}
+
+	// The HTTP API runs the same commands as the socket for remote clients
+	var apiServer *api.Server
+	if cfg.API.ListenAddr != "" {
+		if ipcServer == nil {
+			log.Fatalf("The HTTP API needs the IPC server, which failed to start")
+		}
+		ciStatus := ci.NewStoreStatusReader(ciStatusStore)
+		apiServer = api.New(api.Config{
+			Auth:     ipcAuth,
+			Commands: ipcServer,
+			Workers: func() []worker.WorkerStatus {
+				statuses := make([]worker.WorkerStatus, len(workers))
+				for i, w := range workers {
+					statuses[i] = w.GetStatus()
+				}
+				return statuses
+			},
+			CI: ciStatus.ListSince,
+		})
+		if err := apiServer.Start(cfg.API.ListenAddr); err != nil {
+			log.Fatalf("Failed to start HTTP API: %v", err)
+		}
+		log.Printf("Serving HTTP API on http://%s/api/v1", apiServer.Addr())
+		if ipcAuth == nil {
+			log.Printf("The HTTP API is read-only until ipc.auth.tokens are configured")
+		}
+	}
+
 	// Log periodic queue and worker status
 	go func() {
 		ticker := time.NewTicker(30 * time.Second)
@@ -201,6 +1014,147 @@ func main() {
 		}
 	}()
 
+	// Resume the worker pool on SIGHUP once the agent is logged in again
+	resumeChan := make(chan os.Signal, 1)
+	signal.Notify(resumeChan, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-resumeChan:
+				if paused, reason := pauseGate.Paused(); paused {
+					log.Printf("Resuming worker pool (was paused: %s)", reason)
+					pauseGate.Resume()
+				}
+			}
+		}
+	}()
+
+	// Apply backpressure when free disk space runs low
+	if cfg.Scheduler.MinFreeMB > 0 {
+		go monitorDiskSpace(ctx, cfg, repo, lowDiskGate, ipcServer)
+	}
+
+	// Follow CI on main, holding dispatch while it is red if configured
+	if mainTracker != nil {
+		go monitorMainCI(ctx, cfg, mainTracker, mainRedGate, ipcServer)
+	}
+
+	// Renew ticket claims well within their lease
+	if claimer != nil {
+		go func() {
+			ticker := time.NewTicker(time.Duration(cfg.Coordination.LeaseSeconds) * time.Second / 3)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				lost, err := claimer.Renew()
+				if err != nil {
+					log.Printf("Failed to renew ticket claims: %v", err)
+				}
+				// The other daemon now runs a lost ticket, so it must not run here too
+				for _, ticketID := range lost {
+					if ticketQueue.Remove(ticketID) {
+						log.Printf("Warning: Claim on %s was taken over by another daemon, removed it from the queue", ticketID)
+						continue
+					}
+					cancelled := false
+					for _, w := range workers {
+						if w.Cancel(ticketID, "claim taken over by another daemon") {
+							log.Printf("Warning: Claim on %s was taken over by another daemon, cancelled it on worker %d", ticketID, w.ID)
+							cancelled = true
+							break
+						}
+					}
+					if !cancelled {
+						log.Printf("Warning: Claim on %s was taken over by another daemon", ticketID)
+					}
+				}
+			}
+		}()
+	}
+
+	// Prune old CI statuses at startup and once a day
+	if cfg.CI.RetentionDays > 0 {
+		go func() {
+			// The full CI tier's statuses age out with the quick tier's
+			statusReaders := []*ci.StatusReader{ci.NewStatusReader(cfg.CI.StatusPath), ci.NewStatusReader(ci.FullStatusDir(cfg.CI.StatusPath))}
+			if cfg.CI.StatusStore.Backend == ci.StoreS3 {
+				statusReaders = append(statusReaders, ci.NewStoreStatusReader(ciStatusStore))
+			}
+			retention := time.Duration(cfg.CI.RetentionDays) * 24 * time.Hour
+			ticker := time.NewTicker(24 * time.Hour)
+			defer ticker.Stop()
+
+			for {
+				for _, statusReader := range statusReaders {
+					if removed, err := statusReader.Prune(retention); err != nil {
+						log.Printf("Failed to prune CI statuses: %v", err)
+					} else if removed > 0 {
+						log.Printf("Pruned %d CI statuses older than %d days", removed, cfg.CI.RetentionDays)
+					}
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	// Prune ticket scratch directories at startup and once a day
+	if cfg.Repository.ScratchRetentionDays > 0 {
+		go func() {
+			retention := time.Duration(cfg.Repository.ScratchRetentionDays) * 24 * time.Hour
+			ticker := time.NewTicker(24 * time.Hour)
+			defer ticker.Stop()
+
+			for {
+				if removed, err := scratch.Prune(cfg.Repository.Workdir, retention); err != nil {
+					log.Printf("Failed to prune scratch directories: %v", err)
+				} else if removed > 0 {
+					log.Printf("Pruned %d scratch directories untouched for %d days", removed, cfg.Repository.ScratchRetentionDays)
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	// Archive processed ticket files at startup and once a day
+	if retention := cfg.Scheduler.ProcessedRetention; retention.Enabled() {
+		go func() {
+			ticker := time.NewTicker(24 * time.Hour)
+			defer ticker.Stop()
+
+			for {
+				if archived, err := backlog.PruneProcessed(cfg.Scheduler.BacklogPath, retention, time.Now()); err != nil {
+					log.Printf("Failed to archive processed tickets: %v", err)
+				} else if archived > 0 {
+					log.Printf("Archived %d processed ticket files", archived)
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
 	log.Printf("Orchestrator initialized and ready")
 
 	// Wait for shutdown signal
@@ -217,11 +1171,312 @@ func main() {
 		}
 	}
 
+	if dash != nil {
+		if err := dash.Stop(); err != nil {
+			log.Printf("Error stopping dashboard: %v", err)
+		}
+	}
+
+	if apiServer != nil {
+		if err := apiServer.Stop(); err != nil {
+			log.Printf("Error stopping HTTP API: %v", err)
+		}
+	}
+
 	// Give components time to shut down gracefully
 	time.Sleep(1 * time.Second)
 	log.Printf("Orchestrator stopped")
 }
 
+// monitorDiskSpace stops dispatch and runs git gc while the workdir or
+// repository filesystem is below the free space threshold, resuming once
+// space is available again
+func monitorDiskSpace(ctx context.Context, cfg *config.Config, repo *gitutils.GitRepo, gate *worker.PauseGate, ipcServer *ipc.Server) {
+	monitor := diskspace.NewMonitor(cfg.Scheduler.MinFreeMB, cfg.Repository.Workdir, cfg.Repository.Path)
+	ticker := time.NewTicker(time.Duration(cfg.Scheduler.DiskCheckInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		changed, err := monitor.Check()
+		if err != nil {
+			log.Printf("Failed to check free disk space: %v", err)
+		}
+
+		for _, status := range changed {
+			var message string
+			if status.Low {
+				message = fmt.Sprintf("%s has %d MB free, below the %d MB threshold; pausing dispatch",
+					status.Path, status.FreeMB, monitor.MinFreeMB())
+			} else {
+				message = fmt.Sprintf("%s is back to %d MB free", status.Path, status.FreeMB)
+			}
+			log.Print(message)
+			if ipcServer != nil {
+				ipcServer.PublishDiskSpace(status.Path, status.FreeMB, monitor.MinFreeMB(), status.Low, message)
+			}
+		}
+
+		if monitor.Low() {
+			if gate.Pause("low disk space") {
+				log.Printf("Running git gc to reclaim space")
+				if err := repo.GC(); err != nil {
+					log.Printf("git gc failed: %v", err)
+				}
+			}
+		} else if paused, _ := gate.Paused(); paused {
+			log.Printf("Resuming dispatch, disk space recovered")
+			gate.Resume()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// monitorMainCI checks CI on main every interval, publishing when main turns
+// red or green and holding dispatch while it is red if configured
+func monitorMainCI(ctx context.Context, cfg *config.Config, tracker *ci.MainTracker, gate *worker.PauseGate, ipcServer *ipc.Server) {
+	ticker := time.NewTicker(time.Duration(cfg.CI.Main.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		health, changed, err := tracker.Check(ctx)
+		if err != nil {
+			log.Printf("Failed to check CI on main: %v", err)
+		}
+
+		if cfg.Scheduler.PauseWhenMainRed && !health.CheckedAt.IsZero() {
+			blocked, resumed := worker.HoldWhileRed(gate, health)
+			event := ipc.DispatchEvent{Reason: "main_red", Branch: health.Branch, Commit: health.Commit, Status: health.Status}
+			switch {
+			case blocked:
+				event.Message = fmt.Sprintf("CI on %s is failing at %s; holding new tickets until it passes", health.Branch, health.Commit[:8])
+				log.Print(event.Message)
+				if ipcServer != nil {
+					ipcServer.PublishDispatchBlocked(event)
+				}
+			case resumed:
+				event.Message = fmt.Sprintf("CI on %s passed at %s; resuming dispatch", health.Branch, health.Commit[:8])
+				log.Print(event.Message)
+				if ipcServer != nil {
+					ipcServer.PublishDispatchResumed(event)
+				}
+			}
+		}
+
+		if changed {
+			health.Held, _ = gate.Paused()
+			if health.Red {
+				log.Printf("CI on %s turned red at %s (%s)", health.Branch, health.Commit[:8], health.Status)
+			} else {
+				log.Printf("CI on %s is green at %s", health.Branch, health.Commit[:8])
+			}
+			if ipcServer != nil {
+				ipcServer.PublishMainHealth(health)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rerunCI re-triggers CI for the tip of a branch, or of a ticket's agent branch,
+// without re-running the agent. The CI run continues in the background; while
+// it does, the commit's status is PENDING and further reruns of it are refused.
+func rerunCI(repo *gitutils.GitRepo, backend ci.Backend, store ci.StatusStore, statusPath string, running *sync.Map, target string, caller ipc.Caller) (string, error) {
+	if target == "" {
+		return "", errors.New("missing branch or ticket ID")
+	}
+
+	branches, err := repo.ListBranches()
+	if err != nil {
+		return "", fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	branch := ""
+	for _, b := range branches {
+		if b == target {
+			branch = b
+			break
+		}
+	}
+	if branch == "" {
+		branch = gitutils.FindAgentBranch(branches, target)
+	}
+	if branch == "" {
+		return "", fmt.Errorf("no branch found for %s", target)
+	}
+
+	commitHash, err := repo.GetBranchCommit(branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit for %s: %w", branch, err)
+	}
+
+	if by, busy := running.LoadOrStore(commitHash, caller.String()); busy {
+		return "", fmt.Errorf("CI is already being re-run for %s (commit %s) at the request of %s", branch, commitHash[:8], by)
+	}
+
+	// Replace the previous result with a pending one, so anyone waiting sees
+	// the new run rather than the old verdict or no status at all
+	local := &ci.FileStatusStore{Dir: statusPath}
+	previous, _ := local.Get(commitHash) // Restored if the rerun can't be started
+	pending := &ci.Status{Ref: "refs/heads/" + branch, Commit: commitHash, Status: ci.StatusPending, Output: "re-run requested by " + caller.String()}
+	if previous != nil {
+		pending.TicketID = previous.TicketID
+	}
+	if err := local.Put(pending); err != nil {
+		running.Delete(commitHash)
+		return "", fmt.Errorf("failed to mark CI status pending: %w", err)
+	}
+	if err := ci.PublishStatus(store, statusPath, commitHash); err != nil {
+		log.Printf("Failed to share pending CI status for %s: %v", branch, err)
+	}
+
+	go func() {
+		defer running.Delete(commitHash)
+		log.Printf("Re-running CI for %s (commit %s) requested by %s", branch, commitHash[:8], caller)
+		run := ci.Run{RepoPath: repo.Path, Branch: branch, Commit: commitHash}
+		if err := backend.Run(context.Background(), run); err != nil {
+			log.Printf("CI rerun for %s failed: %v", branch, err)
+			// The run never produced a verdict; don't leave the commit pending
+			if status, err := local.Get(commitHash); err == nil && status.Pending() {
+				restorePreviousStatus(store, local, previous, commitHash)
+				return
+			}
+		} else {
+			log.Printf("CI rerun for %s finished", branch)
+		}
+		if err := ci.PublishStatus(store, statusPath, commitHash); err != nil {
+			log.Printf("Failed to share CI status for %s: %v", branch, err)
+		}
+	}()
+
+	return fmt.Sprintf("CI re-triggered for %s (commit %s)", branch, commitHash[:8]), nil
+}
+
+// restorePreviousStatus puts back the status a failed rerun replaced, or
+// removes the pending one if there was none, locally and in the shared store
+func restorePreviousStatus(store ci.StatusStore, local *ci.FileStatusStore, previous *ci.Status, commitHash string) {
+	for _, s := range []ci.StatusStore{local, store} {
+		var err error
+		if previous != nil {
+			err = s.Put(previous)
+		} else {
+			err = s.Delete(commitHash)
+		}
+		if err != nil {
+			log.Printf("Failed to clear pending CI status for %s: %v", commitHash[:8], err)
+		}
+	}
+}
+
+// workerStats picks the running totals sent with worker_status events out of
+// a worker's status
+func workerStats(status worker.WorkerStatus) *ipc.WorkerStats {
+	stats := &ipc.WorkerStats{
+		WorkerID:         status.ID,
+		Status:           status.Status,
+		LastError:        status.LastError,
+		TicketsCompleted: status.TicketsCompleted,
+		TicketsFailed:    status.FailedTicketCount,
+		AverageDuration:  status.AverageDuration,
+		Phase:            status.Phase,
+		Uptime:           status.Uptime,
+		CIStep:           status.CIStep,
+		CIElapsed:        status.CIElapsed,
+		Paused:           status.Paused,
+	}
+	if status.CurrentTicket != nil {
+		stats.Ticket = status.CurrentTicket.ID
+	}
+	return stats
+}
+
+// restartWorker clears a worker's error state so it takes tickets again
+func restartWorker(workers []*worker.Worker, id string, caller ipc.Caller) (string, error) {
+	w, err := findWorker(workers, id)
+	if err != nil {
+		return "", err
+	}
+
+	status := w.GetStatus()
+	w.Restart()
+	log.Printf("Worker %d restart requested by %s", w.ID, caller)
+	if status.Status != "error" {
+		return fmt.Sprintf("Worker %d was not in an error state; prerequisites will be re-checked", w.ID), nil
+	}
+	return fmt.Sprintf("Worker %d restarted after %d failed tickets (last error: %s)", w.ID, status.FailedTicketCount, status.LastError), nil
+}
+
+// findWorker returns the worker with the ID given as a command argument
+func findWorker(workers []*worker.Worker, id string) (*worker.Worker, error) {
+	workerID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid worker ID %q", id)
+	}
+	for _, w := range workers {
+		if w.ID == workerID {
+			return w, nil
+		}
+	}
+	return nil, fmt.Errorf("no worker %d", workerID)
+}
+
+// pauseWorker stops one worker taking tickets once its current one is done
+func pauseWorker(workers []*worker.Worker, id, reason string, caller ipc.Caller, ipcServer *ipc.Server) (string, error) {
+	w, err := findWorker(workers, id)
+	if err != nil {
+		return "", err
+	}
+	if reason == "" {
+		reason = "paused by " + caller.String()
+	}
+	if !w.Pause(reason) {
+		return fmt.Sprintf("Worker %d is already paused", w.ID), nil
+	}
+	log.Printf("Worker %d paused by %s: %s", w.ID, caller, reason)
+
+	status := w.GetStatus()
+	message := fmt.Sprintf("Worker %d paused: %s", w.ID, reason)
+	if status.CurrentTicket != nil {
+		message += fmt.Sprintf("; finishing %s first", status.CurrentTicket.ID)
+	}
+	publishWorkerStatus(ipcServer, status, message)
+	return message, nil
+}
+
+// resumeWorker lets a paused worker take tickets again
+func resumeWorker(workers []*worker.Worker, id string, caller ipc.Caller, ipcServer *ipc.Server) (string, error) {
+	w, err := findWorker(workers, id)
+	if err != nil {
+		return "", err
+	}
+	if !w.Resume() {
+		return fmt.Sprintf("Worker %d was not paused", w.ID), nil
+	}
+	log.Printf("Worker %d resumed by %s", w.ID, caller)
+
+	message := fmt.Sprintf("Worker %d resumed", w.ID)
+	publishWorkerStatus(ipcServer, w.GetStatus(), message)
+	return message, nil
+}
+
+// publishWorkerStatus announces a worker's current status
+func publishWorkerStatus(ipcServer *ipc.Server, status worker.WorkerStatus, message string) {
+	var current *ticket.Ticket
+	if status.CurrentTicket != nil {
+		current = status.CurrentTicket.Ticket
+	}
+	ipcServer.PublishWorkerStats(status.ID, status.Status, current, message, workerStats(status))
+}
+
 // installGitHooks installs the post-receive hook for CI integration
 func installGitHooks(repoPath string) error {
 	// Find the ci.sh script path (relative to the daemon executable)
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"gopkg.in/yaml.v3"
+)
+
+// e2eConfig runs one agent with the mock implementation and the local CI
+// backend; every path is relative to the harness's temporary directory
+const e2eConfig = `repository:
+  path: "./repo.git"
+  workdir: "./work"
+agents:
+  count: 1
+scheduler:
+  poll_interval: 1
+  backlog_path: "./backlog"
+ci:
+  status_path: "./ci-status"
+  test_retries: 0
+  go_cache_dir: ""
+ipc:
+  socket_path: %q
+metrics:
+  output_path: "./metrics"
+state:
+  path: "./state"
+testing:
+  skip_amp: true
+`
+
+// harness is a daemon running in a temporary directory
+type harness struct {
+	root   string // Project root the daemon is built from
+	dir    string // Temporary directory holding the daemon's config and data
+	daemon *exec.Cmd
+	exited chan error
+	client *ipc.Client
+}
+
+func main() {
+	root := flag.String("root", ".", "Project root holding cmd/daemon and ci.sh")
+	timeout := flag.Duration("timeout", 5*time.Minute, "How long the whole pipeline may take")
+	keep := flag.Bool("keep", false, "Keep the temporary directory for inspection")
+	flag.Parse()
+
+	fmt.Println("Amp Orchestrator end-to-end test starting...")
+
+	h, err := newHarness(*root)
+	if err != nil {
+		log.Fatalf("Failed to set up harness: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	err = h.run(ctx)
+	cancel()
+	h.stop()
+
+	if *keep || err != nil {
+		fmt.Printf("Daemon directory kept at %s (log in daemon.log)\n", h.dir)
+	} else {
+		os.RemoveAll(h.dir)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ End-to-end test failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✅ End-to-end test passed")
+}
+
+// newHarness creates the temporary directory and the daemon's config
+func newHarness(root string) (*harness, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "amp-e2e-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	socketPath := filepath.Join(dir, "orchestrator.sock")
+	config := fmt.Sprintf(e2eConfig, socketPath)
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return &harness{root: root, dir: dir, client: ipc.NewClient(socketPath)}, nil
+}
+
+// run drives a ticket through enqueue → process → CI → merge and checks the
+// repository's history afterwards
+func (h *harness) run(ctx context.Context) error {
+	step("Building daemon")
+	if err := h.build(ctx); err != nil {
+		return err
+	}
+
+	step("Starting daemon in %s", h.dir)
+	if err := h.start(); err != nil {
+		return err
+	}
+	if err := h.connect(ctx); err != nil {
+		return err
+	}
+
+	t := &ticket.Ticket{
+		ID:          fmt.Sprintf("e2e-%d", time.Now().Unix()),
+		Title:       "End-to-end greeting",
+		Description: "Print a greeting from the end-to-end test",
+		Priority:    1,
+	}
+	step("Enqueueing ticket %s", t.ID)
+	if err := h.enqueue(t); err != nil {
+		return err
+	}
+
+	step("Waiting for ticket %s to be processed", t.ID)
+	done, err := h.waitForTicket(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if done.Branch == "" {
+		return fmt.Errorf("ticket %s completed without a branch", t.ID)
+	}
+
+	step("Checking CI for %s", done.Branch)
+	repo := filepath.Join(h.dir, "repo.git")
+	commit, err := git(repo, "rev-parse", done.Branch)
+	if err != nil {
+		return err
+	}
+	status, err := ci.NewStatusReader(filepath.Join(h.dir, "ci-status")).GetStatus(commit)
+	if err != nil {
+		return fmt.Errorf("no CI status for %s: %w", commit, err)
+	}
+	if !status.Passed() {
+		return fmt.Errorf("CI for %s reported %s:\n%s", commit, status.Status, status.Output)
+	}
+
+	step("Merging %s", done.Branch)
+	mainBranch, err := h.merge(done.Branch)
+	if err != nil {
+		return err
+	}
+
+	step("Checking history of %s", mainBranch)
+	return checkHistory(repo, mainBranch, commit, t)
+}
+
+// build compiles the daemon into bin/ next to a copy of ci.sh, where the
+// daemon looks for its CI script
+func (h *harness) build(ctx context.Context) error {
+	binDir := filepath.Join(h.dir, "bin")
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", filepath.Join(binDir, "orchestrator-daemon"), "./cmd/daemon")
+	cmd.Dir = h.root
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to build daemon: %w\n%s", err, output)
+	}
+
+	script, err := os.ReadFile(filepath.Join(h.root, "ci.sh"))
+	if err != nil {
+		return fmt.Errorf("failed to read ci.sh: %w", err)
+	}
+	return os.WriteFile(filepath.Join(h.dir, "ci.sh"), script, 0755)
+}
+
+// start runs the daemon in the background, logging to daemon.log
+func (h *harness) start() error {
+	logFile, err := os.Create(filepath.Join(h.dir, "daemon.log"))
+	if err != nil {
+		return fmt.Errorf("failed to create daemon log: %w", err)
+	}
+
+	h.daemon = exec.Command(filepath.Join(h.dir, "bin", "orchestrator-daemon"))
+	h.daemon.Dir = h.dir
+	h.daemon.Stdout = logFile
+	h.daemon.Stderr = logFile
+	h.daemon.Env = append(os.Environ(), gitIdentity()...)
+	if err := h.daemon.Start(); err != nil {
+		logFile.Close()
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	h.exited = make(chan error, 1)
+	go func() {
+		h.exited <- h.daemon.Wait()
+		logFile.Close()
+	}()
+	return nil
+}
+
+// connect waits for the daemon's IPC socket and subscribes to its events
+func (h *harness) connect(ctx context.Context) error {
+	for {
+		if err := h.client.Connect(); err == nil {
+			return nil
+		}
+		select {
+		case err := <-h.exited:
+			return fmt.Errorf("daemon exited before accepting connections: %v", err)
+		case <-ctx.Done():
+			return fmt.Errorf("daemon did not accept connections: %w", ctx.Err())
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// enqueue writes the ticket into the daemon's backlog the way orchestrator
+// enqueue does, with a provenance block
+func (h *harness) enqueue(t *ticket.Ticket) error {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+	path := filepath.Join(h.dir, "backlog", t.ID+".yaml")
+	stamped, _, err := ticket.Stamp(data, ticket.Provenance{
+		EnqueuedBy: "cli:e2e",
+		Source:     path,
+		EnqueuedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Written aside and renamed, so the watcher never sees a partial file
+	tmp := filepath.Join(h.dir, t.ID+".yaml")
+	if err := os.WriteFile(tmp, stamped, 0644); err != nil {
+		return fmt.Errorf("failed to write ticket: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// waitForTicket follows the daemon's events until the ticket completes
+func (h *harness) waitForTicket(ctx context.Context, ticketID string) (*ticket.Ticket, error) {
+	for {
+		select {
+		case event, ok := <-h.client.Events():
+			if !ok {
+				return nil, errors.New("lost connection to the daemon")
+			}
+			var data ipc.TicketEvent
+			if !decodeEvent(event, &data) || data.Ticket == nil || data.Ticket.ID != ticketID {
+				continue
+			}
+			switch event.Type {
+			case ipc.EventTypeTicketStarted:
+				step("Worker %d started %s", data.WorkerID, ticketID)
+			case ipc.EventTypeTicketComplete:
+				return data.Ticket, nil
+			case ipc.EventTypeTicketFailed, ipc.EventTypeTicketRejected:
+				return nil, fmt.Errorf("ticket %s %s: %s", ticketID, strings.TrimPrefix(string(event.Type), "ticket_"), data.Message)
+			}
+		case err := <-h.exited:
+			return nil, fmt.Errorf("daemon exited while processing %s: %v", ticketID, err)
+		case <-ctx.Done():
+			return nil, fmt.Errorf("ticket %s did not complete: %w", ticketID, ctx.Err())
+		}
+	}
+}
+
+// merge merges the ticket's branch into the main branch with a merge commit,
+// as a reviewer would, and returns the main branch's name
+func (h *harness) merge(branch string) (string, error) {
+	repo := filepath.Join(h.dir, "repo.git")
+	mainBranch := "main"
+	if _, err := git(repo, "rev-parse", "--verify", "refs/heads/main"); err != nil {
+		mainBranch = "master"
+	}
+
+	clone := filepath.Join(h.dir, "merge")
+	if output, err := exec.Command("git", "clone", "--branch", mainBranch, repo, clone).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to clone repository: %w\n%s", err, output)
+	}
+	if _, err := gitIn(clone, "merge", "--no-ff", "-m", "Merge "+branch, "origin/"+branch); err != nil {
+		return "", err
+	}
+	if _, err := gitIn(clone, "push", "origin", mainBranch); err != nil {
+		return "", err
+	}
+	return mainBranch, nil
+}
+
+// stop shuts the daemon down, killing it if it doesn't exit in time
+func (h *harness) stop() {
+	h.client.Close()
+	if h.daemon == nil || h.daemon.Process == nil {
+		return
+	}
+	h.daemon.Process.Signal(os.Interrupt)
+	select {
+	case <-h.exited:
+	case <-time.After(30 * time.Second):
+		h.daemon.Process.Kill()
+		<-h.exited
+	}
+}
+
+// checkHistory asserts that the main branch ends in a merge of the ticket's
+// commit, which holds the mock implementation of the ticket
+func checkHistory(repo, mainBranch, commit string, t *ticket.Ticket) error {
+	parents, err := git(repo, "rev-list", "--parents", "-n", "1", mainBranch)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(parents)
+	if len(fields) != 3 {
+		return fmt.Errorf("expected %s to end in a merge commit, got parents %v", mainBranch, fields[1:])
+	}
+	if fields[2] != commit {
+		return fmt.Errorf("expected the merge to bring in %s, got %s", commit, fields[2])
+	}
+
+	subject, err := git(repo, "log", "-1", "--format=%s", commit)
+	if err != nil {
+		return err
+	}
+	if want := "Implement " + t.Title; subject != want {
+		return fmt.Errorf("expected the ticket's commit to be %q, got %q", want, subject)
+	}
+
+	mainGo, err := git(repo, "show", mainBranch+":main.go")
+	if err != nil {
+		return fmt.Errorf("main.go missing from %s: %w", mainBranch, err)
+	}
+	if !strings.Contains(mainGo, t.Title) {
+		return fmt.Errorf("main.go on %s does not mention %q", mainBranch, t.Title)
+	}
+
+	count, err := git(repo, "rev-list", "--count", mainBranch)
+	if err != nil {
+		return err
+	}
+	if count != "3" {
+		return fmt.Errorf("expected 3 commits on %s (initial, ticket, merge), got %s", mainBranch, count)
+	}
+	return nil
+}
+
+// decodeEvent converts an event's generic payload into out
+func decodeEvent(event ipc.Event, out interface{}) bool {
+	raw, err := json.Marshal(event.Data)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(raw, out) == nil
+}
+
+// git runs a git command against a bare repository and returns its trimmed output
+func git(repo string, args ...string) (string, error) {
+	return run(exec.Command("git", append([]string{"--git-dir", repo}, args...)...))
+}
+
+// gitIn runs a git command in a working tree
+func gitIn(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), gitIdentity()...)
+	return run(cmd)
+}
+
+func run(cmd *exec.Cmd) (string, error) {
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", strings.Join(cmd.Args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// gitIdentity fills in a committer for agents and merges when the
+// environment has none, e.g. on a CI runner
+func gitIdentity() []string {
+	var env []string
+	for _, v := range []struct{ name, value string }{
+		{"GIT_AUTHOR_NAME", "Amp Orchestrator E2E"},
+		{"GIT_AUTHOR_EMAIL", "e2e@localhost"},
+		{"GIT_COMMITTER_NAME", "Amp Orchestrator E2E"},
+		{"GIT_COMMITTER_EMAIL", "e2e@localhost"},
+	} {
+		if os.Getenv(v.name) == "" {
+			env = append(env, v.name+"="+v.value)
+		}
+	}
+	return env
+}
+
+// step prints a progress line
+func step(format string, args ...interface{}) {
+	fmt.Printf("▶ "+format+"\n", args...)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/remote"
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+)
+
+// reconnectDelay is how long to wait before reconnecting to the daemon
+const reconnectDelay = 10 * time.Second
+
+func main() {
+	hostname, _ := os.Hostname()
+
+	daemon := flag.String("daemon", "", "Daemon's remote.listen_address (host:port)")
+	name := flag.String("name", hostname, "Worker name, unique across remote workers")
+	workDir := flag.String("workdir", "./remote-work", "Directory for the repository clone, worktrees and CI status")
+	poll := flag.Duration("poll", 10*time.Second, "How often to ask for work while idle")
+	skipCI := flag.Bool("skip-ci", false, "Skip CI (for testing)")
+	skipAmp := flag.Bool("skip-amp", false, "Skip the amp CLI and create mock files (for testing)")
+	statusEndpoint := flag.String("ci-status-s3-endpoint", "", "S3 endpoint of the daemon's ci.status_store; CI statuses are published there")
+	statusBucket := flag.String("ci-status-s3-bucket", "", "Bucket of the daemon's ci.status_store")
+	statusPrefix := flag.String("ci-status-s3-prefix", "", "Key prefix of the daemon's ci.status_store")
+	statusRegion := flag.String("ci-status-s3-region", "", "Region of the daemon's ci.status_store (default us-east-1)")
+	flag.Parse()
+
+	if *daemon == "" || *name == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s -daemon <host:port> [-name <name>] [-workdir <dir>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "The auth token is read from $%s\n", ipc.TokenEnv)
+		os.Exit(1)
+	}
+
+	token := os.Getenv(ipc.TokenEnv)
+	if token == "" {
+		log.Fatalf("%s must hold a token with at least the operator role", ipc.TokenEnv)
+	}
+
+	// Statuses are published to the daemon's shared store; credentials come from $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY
+	var statusStore ci.StatusStore
+	if *statusEndpoint != "" {
+		store, err := ci.NewStatusStore(ci.StoreConfig{
+			Backend: ci.StoreS3,
+			S3:      storage.S3Config{Endpoint: *statusEndpoint, Bucket: *statusBucket, Prefix: *statusPrefix, Region: *statusRegion},
+		}, "")
+		if err != nil {
+			log.Fatalf("Failed to set up CI status store: %v", err)
+		}
+		statusStore = store
+	}
+
+	fmt.Printf("Amp Orchestrator remote worker %s starting...\n", *name)
+
+	agent := remote.NewAgent(remote.AgentConfig{
+		Address:      *daemon,
+		Token:        token,
+		Name:         *name,
+		WorkDir:      *workDir,
+		PollInterval: *poll,
+		Worker: worker.Config{
+			CIStatusStore: statusStore,
+			SkipCI:        *skipCI,
+			SkipAmp:       *skipAmp,
+		},
+	})
+
+	// Stream this process's log to the daemon while a ticket runs
+	log.SetOutput(io.MultiWriter(os.Stderr, agent.LogWriter()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Printf("Shutting down remote worker...")
+		cancel()
+	}()
+
+	for {
+		err := agent.Run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Lost connection to %s: %v; reconnecting in %v", *daemon, err, reconnectDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
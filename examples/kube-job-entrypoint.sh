@@ -0,0 +1,27 @@
+#!/bin/bash
+# Entrypoint for agents.backend: kubernetes images
+# Clones the orchestrator's repository, runs amp on the ticket's prompt and
+# pushes the ticket's branch back. The orchestrator reads the SUMMARY: line
+# from the Job's logs.
+set -euo pipefail
+
+git clone --quiet "$ORCHESTRATOR_REPO_URL" /work
+cd /work
+
+if git rev-parse --verify --quiet "origin/$ORCHESTRATOR_BRANCH" >/dev/null; then
+    git checkout --quiet -b "$ORCHESTRATOR_BRANCH" "origin/$ORCHESTRATOR_BRANCH"
+else
+    git checkout --quiet -b "$ORCHESTRATOR_BRANCH"
+fi
+
+echo "Running amp for ticket $ORCHESTRATOR_TICKET_ID"
+echo "$ORCHESTRATOR_PROMPT" | amp
+
+git add -A
+if git diff --cached --quiet; then
+    echo "Agent made no changes"
+    exit 1
+fi
+git -c user.name="Amp Agent" -c user.email="amp@orchestrator" \
+    commit --quiet -m "Implement ticket $ORCHESTRATOR_TICKET_ID"
+git push --quiet origin "$ORCHESTRATOR_BRANCH"
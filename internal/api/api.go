@@ -0,0 +1,246 @@
+// Package api serves the daemon's queue, workers and CI results over HTTP,
+// and takes tickets and cancellations, for remote dashboards and automation
+// that cannot reach the local socket. Changes run through the IPC server's
+// commands, so they are checked, audited and announced like any other
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+)
+
+// Bounds on requests and responses
+const (
+	maxTicketSize  = 64 * 1024      // Matches the largest IPC command
+	defaultCISince = 24 * time.Hour // CI results returned without ?since=
+)
+
+// ticketTypes are the Content-Types a ticket may be posted as; form and
+// text/plain bodies, which a web page can send cross-site, are refused
+var ticketTypes = map[string]bool{
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"application/json":   true,
+}
+
+// Commands runs the daemon's registered IPC commands; *ipc.Server does
+type Commands interface {
+	Execute(caller ipc.Caller, role ipc.Role, name string, args map[string]string) ipc.CommandResponse
+}
+
+// Config holds what the API needs from the daemon
+type Config struct {
+	Auth     *ipc.Authenticator                          // Nil makes every read an admin's and refuses changes; otherwise a token is required
+	Commands Commands                                    // Runs list, status, ticket_enqueue and ticket_cancel
+	Workers  func() []worker.WorkerStatus                // Local workers
+	CI       func(since time.Time) ([]*ci.Status, error) // CI results recorded since a time
+}
+
+// Server is the HTTP API
+type Server struct {
+	config   Config
+	server   *http.Server
+	listener net.Listener
+}
+
+// New creates an API server
+func New(config Config) *Server {
+	return &Server{config: config}
+}
+
+// Start serves the API on address (host:port)
+func (s *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("API server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the API listens on
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop waits briefly for requests in flight and closes the listener
+func (s *Server) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// Handler returns the API's routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/queue", s.command(ipc.RoleViewer, "list"))
+	mux.HandleFunc("GET /api/v1/status", s.command(ipc.RoleViewer, "status"))
+	mux.HandleFunc("GET /api/v1/workers", s.authorize(ipc.RoleViewer, s.handleWorkers))
+	mux.HandleFunc("GET /api/v1/ci", s.authorize(ipc.RoleViewer, s.handleCI))
+	mux.HandleFunc("POST /api/v1/tickets", s.change(ipc.RoleOperator, s.handleEnqueue))
+	mux.HandleFunc("DELETE /api/v1/tickets/{id}", s.change(ipc.RoleOperator, s.handleCancel))
+	return mux
+}
+
+// handler is an API route run for an authenticated caller
+type handler func(w http.ResponseWriter, r *http.Request, caller ipc.Caller, role ipc.Role)
+
+// authorize resolves the caller from an Authorization: Bearer token and
+// requires role of it
+func (s *Server) authorize(required ipc.Role, next handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		name, role, err := s.config.Auth.Authenticate(token)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "a valid bearer token is required")
+			return
+		}
+		if !role.Allows(required) {
+			writeError(w, http.StatusForbidden, "this requires the "+string(required)+" role")
+			return
+		}
+		next(w, r, ipc.Caller{Token: name, Addr: r.RemoteAddr}, role)
+	}
+}
+
+// change guards a route that changes the queue. Browsers attach no bearer
+// token on their own, so requiring one, even on loopback without
+// ipc.auth, keeps a web page from forging changes; requests a browser marks
+// with an Origin are refused outright.
+func (s *Server) change(required ipc.Role, next handler) http.HandlerFunc {
+	guarded := s.authorize(required, next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, "cross-origin requests cannot change the queue")
+			return
+		}
+		if s.config.Auth == nil {
+			writeError(w, http.StatusForbidden, "changing the queue over HTTP needs ipc.auth.tokens and a bearer token")
+			return
+		}
+		guarded(w, r)
+	}
+}
+
+// command serves a read-only IPC command, whose message is JSON
+func (s *Server) command(required ipc.Role, name string) http.HandlerFunc {
+	return s.authorize(required, func(w http.ResponseWriter, r *http.Request, caller ipc.Caller, role ipc.Role) {
+		response := s.config.Commands.Execute(caller, role, name, nil)
+		if !response.OK {
+			writeError(w, http.StatusInternalServerError, response.Error)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, response.Message+"\n")
+	})
+}
+
+func (s *Server) handleWorkers(w http.ResponseWriter, r *http.Request, caller ipc.Caller, role ipc.Role) {
+	workers := []worker.WorkerStatus{}
+	if s.config.Workers != nil {
+		workers = append(workers, s.config.Workers()...)
+	}
+	writeJSON(w, http.StatusOK, workers)
+}
+
+// handleCI returns CI results newest first, from the last ?since= (a
+// duration such as 2h) or the last day
+func (s *Server) handleCI(w http.ResponseWriter, r *http.Request, caller ipc.Caller, role ipc.Role) {
+	window := defaultCISince
+	if value := r.URL.Query().Get("since"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "since must be a positive duration such as 2h")
+			return
+		}
+		window = d
+	}
+
+	statuses := []*ci.Status{}
+	if s.config.CI != nil {
+		found, err := s.config.CI(time.Now().Add(-window))
+		if err != nil {
+			log.Printf("API: failed to read CI results: %v", err)
+			writeError(w, http.StatusInternalServerError, "failed to read CI results")
+			return
+		}
+		statuses = append(statuses, found...)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Timestamp.After(statuses[j].Timestamp)
+	})
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// handleEnqueue adds the ticket in the body, YAML or JSON, to the backlog;
+// ?name= records where it came from
+func (s *Server) handleEnqueue(w http.ResponseWriter, r *http.Request, caller ipc.Caller, role ipc.Role) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); !ticketTypes[mediaType] {
+		writeError(w, http.StatusUnsupportedMediaType, "the ticket must be sent as application/yaml or application/json")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTicketSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "the ticket is too large")
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "api"
+	}
+	response := s.config.Commands.Execute(caller, role, "ticket_enqueue", map[string]string{"name": name, "data": string(data)})
+	if !response.OK {
+		writeError(w, http.StatusBadRequest, response.Error)
+		return
+	}
+	// The backlog watcher queues the ticket on its next scan
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": response.Message})
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request, caller ipc.Caller, role ipc.Role) {
+	response := s.config.Commands.Execute(caller, role, "ticket_cancel", map[string]string{"id": r.PathValue("id")})
+	if !response.OK {
+		writeError(w, http.StatusNotFound, response.Error)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": response.Message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("API: failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+)
+
+// newTestAPI serves the API over an IPC server with fake enqueue, cancel and
+// list commands, returning the server and the commands it recorded
+func newTestAPI(t *testing.T, auth *ipc.Authenticator) (*httptest.Server, *[]ipc.CommandRecord) {
+	t.Helper()
+	commands := ipc.NewServer(filepath.Join(t.TempDir(), "test.sock"))
+	var records []ipc.CommandRecord
+	commands.SetCommandRecorder(func(record ipc.CommandRecord) {
+		records = append(records, record)
+	})
+	commands.HandleQuietCommand("list", ipc.RoleViewer, func(caller ipc.Caller, args map[string]string) (string, error) {
+		return `[{"id":"feat-1"}]`, nil
+	})
+	commands.HandleCommand("ticket_enqueue", ipc.RoleOperator, func(caller ipc.Caller, args map[string]string) (string, error) {
+		if !strings.Contains(args["data"], "id:") {
+			return "", errors.New("invalid ticket: id is required")
+		}
+		return "Enqueued ticket from " + args["name"], nil
+	})
+	commands.HandleCommand("ticket_cancel", ipc.RoleOperator, func(caller ipc.Caller, args map[string]string) (string, error) {
+		if args["id"] != "feat-1" {
+			return "", errors.New("ticket " + args["id"] + " is not queued or running")
+		}
+		return "Cancelled queued ticket feat-1 for " + caller.String(), nil
+	})
+
+	now := time.Now()
+	s := New(Config{
+		Auth:     auth,
+		Commands: commands,
+		Workers: func() []worker.WorkerStatus {
+			return []worker.WorkerStatus{{ID: 1, IsRunning: true}}
+		},
+		CI: func(since time.Time) ([]*ci.Status, error) {
+			var statuses []*ci.Status
+			for _, s := range []*ci.Status{
+				{Ref: "agent-1/feat-0", Status: "FAIL", Timestamp: now.Add(-2 * time.Hour)},
+				{Ref: "agent-1/feat-1", Status: "PASS", Timestamp: now},
+			} {
+				if !s.Timestamp.Before(since) {
+					statuses = append(statuses, s)
+				}
+			}
+			return statuses, nil
+		},
+	})
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+	return server, &records
+}
+
+// call makes a request with an optional bearer token and decodes the reply
+func call(t *testing.T, method, url, token, body string, wantStatus int, v interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/yaml")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: expected status %d, got %d", method, url, wantStatus, resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Failed to decode %s %s: %v", method, url, err)
+		}
+	}
+}
+
+func TestReadEndpoints(t *testing.T) {
+	server, _ := newTestAPI(t, nil)
+
+	var queue []map[string]string
+	call(t, "GET", server.URL+"/api/v1/queue", "", "", http.StatusOK, &queue)
+	if len(queue) != 1 || queue[0]["id"] != "feat-1" {
+		t.Errorf("Unexpected queue %v", queue)
+	}
+
+	var workers []worker.WorkerStatus
+	call(t, "GET", server.URL+"/api/v1/workers", "", "", http.StatusOK, &workers)
+	if len(workers) != 1 || !workers[0].IsRunning {
+		t.Errorf("Unexpected workers %+v", workers)
+	}
+
+	var statuses []*ci.Status
+	call(t, "GET", server.URL+"/api/v1/ci", "", "", http.StatusOK, &statuses)
+	if len(statuses) != 2 || statuses[0].Ref != "agent-1/feat-1" {
+		t.Errorf("Expected both CI results, newest first, got %+v", statuses)
+	}
+	call(t, "GET", server.URL+"/api/v1/ci?since=1h", "", "", http.StatusOK, &statuses)
+	if len(statuses) != 1 {
+		t.Errorf("Expected the last hour's CI result, got %+v", statuses)
+	}
+	call(t, "GET", server.URL+"/api/v1/ci?since=yesterday", "", "", http.StatusBadRequest, nil)
+
+	// The status command is not registered here
+	call(t, "GET", server.URL+"/api/v1/status", "", "", http.StatusInternalServerError, nil)
+}
+
+// newOperatorAuth returns an authenticator with one operator token, bot
+func newOperatorAuth(t *testing.T) *ipc.Authenticator {
+	t.Helper()
+	t.Setenv("API_TEST_OPERATOR", "operator-secret")
+	auth, err := ipc.NewAuthenticator(ipc.AuthConfig{Tokens: []ipc.TokenConfig{
+		{Name: "bot", TokenEnv: "API_TEST_OPERATOR", Role: ipc.RoleOperator},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	return auth
+}
+
+func TestEnqueueAndCancel(t *testing.T) {
+	server, records := newTestAPI(t, newOperatorAuth(t))
+	token := "operator-secret"
+
+	var reply map[string]string
+	call(t, "POST", server.URL+"/api/v1/tickets?name=deploy.yaml", token, "id: feat-2\ntitle: Deploy\n", http.StatusAccepted, &reply)
+	if reply["message"] != "Enqueued ticket from deploy.yaml" {
+		t.Errorf("Unexpected reply %v", reply)
+	}
+	call(t, "POST", server.URL+"/api/v1/tickets", token, "title: No ID\n", http.StatusBadRequest, &reply)
+	if !strings.Contains(reply["error"], "id is required") {
+		t.Errorf("Expected the command's error, got %v", reply)
+	}
+	call(t, "POST", server.URL+"/api/v1/tickets", token, strings.Repeat("x", maxTicketSize+1), http.StatusRequestEntityTooLarge, nil)
+
+	call(t, "DELETE", server.URL+"/api/v1/tickets/feat-1", token, "", http.StatusOK, &reply)
+	if !strings.HasPrefix(reply["message"], "Cancelled queued ticket feat-1 for bot (127.0.0.1:") {
+		t.Errorf("Expected the caller in the reply, got %v", reply)
+	}
+	call(t, "DELETE", server.URL+"/api/v1/tickets/feat-9", token, "", http.StatusNotFound, nil)
+
+	// Changes are recorded like commands sent over the socket
+	if len(*records) != 4 || (*records)[0].Name != "ticket_enqueue" || (*records)[0].Args["name"] != "deploy.yaml" {
+		t.Errorf("Expected every change recorded, got %+v", *records)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	t.Setenv("API_TEST_VIEWER", "viewer-secret")
+	t.Setenv("API_TEST_OPERATOR", "operator-secret")
+	auth, err := ipc.NewAuthenticator(ipc.AuthConfig{Tokens: []ipc.TokenConfig{
+		{Name: "dash", TokenEnv: "API_TEST_VIEWER", Role: ipc.RoleViewer},
+		{Name: "bot", TokenEnv: "API_TEST_OPERATOR", Role: ipc.RoleOperator},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	server, records := newTestAPI(t, auth)
+
+	call(t, "GET", server.URL+"/api/v1/queue", "", "", http.StatusUnauthorized, nil)
+	call(t, "GET", server.URL+"/api/v1/queue", "wrong", "", http.StatusUnauthorized, nil)
+	call(t, "GET", server.URL+"/api/v1/queue", "viewer-secret", "", http.StatusOK, nil)
+	call(t, "DELETE", server.URL+"/api/v1/tickets/feat-1", "viewer-secret", "", http.StatusForbidden, nil)
+
+	var reply map[string]string
+	call(t, "DELETE", server.URL+"/api/v1/tickets/feat-1", "operator-secret", "", http.StatusOK, &reply)
+	if !strings.Contains(reply["message"], "for bot (127.0.0.1:") {
+		t.Errorf("Expected the token's name in the reply, got %v", reply)
+	}
+	if len(*records) != 1 || (*records)[0].Caller.Token != "bot" {
+		t.Errorf("Expected the cancel attributed to bot, got %+v", *records)
+	}
+}
+
+func TestChangesRefusedFromBrowsers(t *testing.T) {
+	send := func(server *httptest.Server, method, path string, header map[string]string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader("id: feat-2\n"))
+		if err != nil {
+			t.Fatalf("Failed to build request: %v", err)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Without ipc.auth, reads stay open but nothing can be changed
+	open, records := newTestAPI(t, nil)
+	if got := send(open, "POST", "/api/v1/tickets", map[string]string{"Content-Type": "application/yaml"}); got != http.StatusForbidden {
+		t.Errorf("Expected an unauthenticated enqueue to be refused, got %d", got)
+	}
+	if got := send(open, "DELETE", "/api/v1/tickets/feat-1", nil); got != http.StatusForbidden {
+		t.Errorf("Expected an unauthenticated cancel to be refused, got %d", got)
+	}
+
+	server, _ := newTestAPI(t, newOperatorAuth(t))
+	bearer := "Bearer operator-secret"
+	if got := send(server, "POST", "/api/v1/tickets", map[string]string{"Authorization": bearer, "Content-Type": "text/plain"}); got != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected a text/plain ticket to be refused, got %d", got)
+	}
+	if got := send(server, "POST", "/api/v1/tickets", map[string]string{"Authorization": bearer, "Content-Type": "application/yaml", "Origin": "https://evil.example"}); got != http.StatusForbidden {
+		t.Errorf("Expected a cross-origin enqueue to be refused, got %d", got)
+	}
+	if got := send(server, "POST", "/api/v1/tickets", map[string]string{"Authorization": bearer, "Content-Type": "application/yaml; charset=utf-8"}); got != http.StatusAccepted {
+		t.Errorf("Expected a YAML ticket to be accepted, got %d", got)
+	}
+	if len(*records) != 0 {
+		t.Errorf("Expected no refused change to run, got %+v", *records)
+	}
+}
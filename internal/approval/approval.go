@@ -0,0 +1,186 @@
+package approval
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/journal"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// FileName is the journal's name within the state directory
+const FileName = "approvals.jsonl"
+
+// shortDigest is how much of a digest is shown to name a ticket version
+const shortDigest = 12
+
+// ErrPending is wrapped by errors for tickets held back until approved
+var ErrPending = errors.New("waiting for approval")
+
+// Entry records who approved which content of a ticket and when, or, with
+// Used set, that the approved ticket was enqueued and the approval spent
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Ticket string    `json:"ticket"`
+	Digest string    `json:"digest"` // SHA-256 of the ticket's canonical YAML
+	By     string    `json:"by,omitempty"`
+	Used   bool      `json:"used,omitempty"`
+}
+
+// Store keeps approvals in a JSON lines journal so they survive restarts
+// With an encrypting cipher each line is sealed and base64 encoded
+type Store struct {
+	file     *journal.Writer
+	mu       sync.Mutex
+	approved map[string]Entry           // Unspent approvals by ticket ID
+	held     map[string]map[string]bool // Digests of each ticket ID's held versions
+}
+
+// Open loads the approvals journal in stateDir
+func Open(stateDir string, cipher *encryption.Cipher) (*Store, error) {
+	path := filepath.Join(stateDir, FileName)
+	s := &Store{
+		file:     journal.NewWriter(path, cipher),
+		approved: make(map[string]Entry),
+		held:     make(map[string]map[string]bool),
+	}
+	err := journal.Read(path, cipher, func(data []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		if !entry.Used {
+			s.approved[entry.Ticket] = entry
+		} else if s.approved[entry.Ticket].Digest == entry.Digest {
+			delete(s.approved, entry.Ticket)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Digest returns the hash an approval is bound to: the ticket's canonical
+// YAML, so any change to what was approved needs a new approval
+func Digest(t *ticket.Ticket) (string, error) {
+	data, err := t.Canonical()
+	if err != nil {
+		return "", fmt.Errorf("failed to hash ticket %s: %w", t.ID, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Admit holds back tickets with requires_approval unless their content is
+// what was approved; it is meant to be the watcher's admitter, so held
+// tickets stay in the backlog and are picked up by the scan after approval
+func (s *Store) Admit(t *ticket.Ticket) error {
+	if !t.RequiresApproval {
+		return nil
+	}
+	digest, err := Digest(t)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.approved[t.ID].Digest == digest {
+		return nil
+	}
+	if s.held[t.ID] == nil {
+		s.held[t.ID] = make(map[string]bool)
+	}
+	s.held[t.ID][digest] = true
+	return fmt.Errorf("%w of version %s: run orchestrator approve %s", ErrPending, digest[:shortDigest], t.ID)
+}
+
+// Approve records that by approved a held version of a ticket. version is
+// a prefix of its digest, needed only when several different versions have
+// been held; approving again is a no-op that returns the standing approval.
+func (s *Store) Approve(ticketID, version, by string) (Entry, error) {
+	if ticketID == "" {
+		return Entry{}, fmt.Errorf("a ticket ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.approved[ticketID]; ok {
+		return entry, nil
+	}
+	var matches []string
+	for digest := range s.held[ticketID] {
+		if strings.HasPrefix(digest, version) {
+			matches = append(matches, digest)
+		}
+	}
+	if len(matches) == 0 && version == "" {
+		return Entry{}, fmt.Errorf("ticket %s is not waiting for approval", ticketID)
+	}
+	if len(matches) == 0 {
+		return Entry{}, fmt.Errorf("ticket %s has no version %s waiting for approval", ticketID, version)
+	}
+	if len(matches) > 1 {
+		var versions []string
+		for _, digest := range matches {
+			versions = append(versions, digest[:shortDigest])
+		}
+		sort.Strings(versions)
+		return Entry{}, fmt.Errorf("ticket %s has %d versions waiting for approval (%s); name the one to approve", ticketID, len(matches), strings.Join(versions, ", "))
+	}
+
+	digest := matches[0]
+	entry := Entry{Time: time.Now().UTC(), Ticket: ticketID, Digest: digest, By: by}
+	if err := s.file.Append(entry); err != nil {
+		return Entry{}, err
+	}
+	s.approved[ticketID] = entry
+	delete(s.held, ticketID)
+	return entry, nil
+}
+
+// Consume spends the approval of a ticket that has been enqueued, so a
+// later ticket with the same ID needs approving again
+func (s *Store) Consume(t *ticket.Ticket) error {
+	if !t.RequiresApproval {
+		return nil
+	}
+	digest, err := Digest(t)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.approved[t.ID]
+	if !ok || entry.Digest != digest {
+		return nil
+	}
+	if err := s.file.Append(Entry{Time: time.Now().UTC(), Ticket: t.ID, Digest: digest, Used: true}); err != nil {
+		return err
+	}
+	delete(s.approved, t.ID)
+	return nil
+}
+
+// Approved reports whether the ticket has an unspent approval
+func (s *Store) Approved(ticketID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.approved[ticketID]
+	return ok
+}
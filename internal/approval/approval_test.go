@@ -0,0 +1,110 @@
+package approval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func deploy(description string) *ticket.Ticket {
+	return &ticket.Ticket{ID: "deploy-1", Title: "Deploy", Description: description, Priority: 1, RequiresApproval: true}
+}
+
+func TestAdmitWaitsForApproval(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if err := store.Admit(&ticket.Ticket{ID: "feat-1"}); err != nil {
+		t.Errorf("Expected tickets without requires_approval to be admitted, got %v", err)
+	}
+	if _, err := store.Approve("deploy-1", "", "uid 1000"); err == nil {
+		t.Error("Expected approving a ticket that isn't held to fail")
+	}
+	if err := store.Admit(deploy("ship v1")); !errors.Is(err, ErrPending) {
+		t.Fatalf("Expected ErrPending, got %v", err)
+	}
+
+	first, err := store.Approve("deploy-1", "", "uid 1000")
+	if err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if again, _ := store.Approve("deploy-1", "", "uid 1001"); again.By != first.By {
+		t.Errorf("Expected the first approval to stand, got %+v", again)
+	}
+
+	// The approval covers the content that was held, not the ID
+	if err := store.Admit(deploy("ship v2")); !errors.Is(err, ErrPending) {
+		t.Errorf("Expected changed content to need approval, got %v", err)
+	}
+	if err := store.Admit(deploy("ship v1")); err != nil {
+		t.Errorf("Expected the approved ticket to be admitted, got %v", err)
+	}
+
+	// Approvals survive a restart
+	reopened, err := Open(dir, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := reopened.Admit(deploy("ship v1")); err != nil {
+		t.Errorf("Expected the approval to survive reopening, got %v", err)
+	}
+}
+
+func TestConsumeSpendsApproval(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	store.Admit(deploy("ship v1"))
+	if _, err := store.Approve("deploy-1", "", "uid 1000"); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if err := store.Consume(deploy("ship v1")); err != nil {
+		t.Fatalf("Consume failed: %v", err)
+	}
+
+	// The same ticket enqueued again needs a new approval, even after a restart
+	if err := store.Admit(deploy("ship v1")); !errors.Is(err, ErrPending) {
+		t.Errorf("Expected a spent approval to hold the ticket, got %v", err)
+	}
+	reopened, err := Open(dir, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if reopened.Approved("deploy-1") {
+		t.Error("Expected the spent approval to stay spent after reopening")
+	}
+}
+
+func TestApproveRefusesSeveralHeldVersions(t *testing.T) {
+	store, err := Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	store.Admit(deploy("ship v1"))
+	store.Admit(deploy("ship v2"))
+	if _, err := store.Approve("deploy-1", "", "uid 1000"); err == nil {
+		t.Error("Expected an error when two versions of the ticket are held")
+	}
+	v2, err := Digest(deploy("ship v2"))
+	if err != nil {
+		t.Fatalf("Digest failed: %v", err)
+	}
+	if _, err := store.Approve("deploy-1", v2[:8], "uid 1000"); err != nil {
+		t.Fatalf("Expected naming a version to approve it, got %v", err)
+	}
+	if err := store.Admit(deploy("ship v1")); !errors.Is(err, ErrPending) {
+		t.Errorf("Expected the other version to stay held, got %v", err)
+	}
+	if err := store.Admit(deploy("ship v2")); err != nil {
+		t.Errorf("Expected the named version to be admitted, got %v", err)
+	}
+	if _, err := store.Approve("", "", "uid 1000"); err == nil {
+		t.Error("Expected an error for an empty ticket ID")
+	}
+}
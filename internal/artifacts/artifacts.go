@@ -0,0 +1,168 @@
+package artifacts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
+)
+
+// HistoryFile is the JSON lines file in the metrics directory recording every
+// publication, so artifacts can be found from a ticket ID later
+const HistoryFile = "artifacts.jsonl"
+
+// Artifact is a published file
+type Artifact struct {
+	Path string `json:"path"` // Relative to the worktree
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+// Record is one publication in the history file
+type Record struct {
+	TicketID    string     `json:"ticket_id"`
+	Commit      string     `json:"commit,omitempty"`
+	PublishedAt time.Time  `json:"published_at"`
+	Artifacts   []Artifact `json:"artifacts"`
+}
+
+// Collect returns the worktree-relative paths of regular files matching the
+// globs, sorted and without duplicates. Globs use filepath.Match syntax and
+// are relative to the worktree; a matching directory contributes every file
+// below it. Nothing under .git is collected.
+func Collect(worktree string, globs []string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, glob := range globs {
+		clean := filepath.Clean(glob)
+		if filepath.IsAbs(glob) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("artifact glob %q must stay within the worktree", glob)
+		}
+
+		matches, err := filepath.Glob(filepath.Join(worktree, glob))
+		if err != nil {
+			return nil, fmt.Errorf("invalid artifact glob %q: %w", glob, err)
+		}
+
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() && d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				rel, err := filepath.Rel(worktree, path)
+				if err != nil {
+					return err
+				}
+				if rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)) {
+					return nil
+				}
+				seen[filepath.ToSlash(rel)] = true
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to collect artifacts: %w", err)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Publish collects a ticket's artifacts from the worktree and stores them
+// under <ticket-id>/<path>
+func Publish(ctx context.Context, store storage.Store, ticketID, worktree string, globs []string) ([]Artifact, error) {
+	paths, err := Collect(worktree, globs)
+	if err != nil {
+		return nil, err
+	}
+
+	published := make([]Artifact, 0, len(paths))
+	for _, rel := range paths {
+		path := filepath.Join(worktree, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			return published, fmt.Errorf("failed to stat artifact %s: %w", rel, err)
+		}
+
+		url, err := store.Put(ctx, ticketID+"/"+rel, path)
+		if err != nil {
+			return published, fmt.Errorf("failed to publish artifact %s: %w", rel, err)
+		}
+		published = append(published, Artifact{Path: rel, URL: url, Size: info.Size()})
+	}
+
+	return published, nil
+}
+
+// AppendHistory adds a publication to the history file in the metrics directory
+func AppendHistory(metricsDir string, record Record) error {
+	if err := os.MkdirAll(metricsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal artifact record: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(metricsDir, HistoryFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open artifact history: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write artifact history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory returns the publications for a ticket, oldest first
+// An empty ticket ID returns every publication
+func LoadHistory(metricsDir, ticketID string) ([]Record, error) {
+	file, err := os.Open(filepath.Join(metricsDir, HistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open artifact history: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// Skip a line torn by a crash mid-write
+			continue
+		}
+		if ticketID == "" || record.TicketID == ticketID {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read artifact history: %w", err)
+	}
+
+	return records, nil
+}
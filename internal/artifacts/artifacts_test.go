@@ -0,0 +1,115 @@
+package artifacts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
+)
+
+// writeFiles creates files with their own names as content
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	worktree := t.TempDir()
+	writeFiles(t, worktree, "dist/app.bin", "dist/docs/index.html", "coverage.out", "main.go", ".git/HEAD")
+
+	paths, err := Collect(worktree, []string{"dist", "*.out", "dist/*.bin", "missing/*"})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	want := []string{"coverage.out", "dist/app.bin", "dist/docs/index.html"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Collect() = %v, want %v", paths, want)
+	}
+
+	// A glob matching the worktree root never collects .git
+	paths, err = Collect(worktree, []string{"."})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	for _, path := range paths {
+		if strings.HasPrefix(path, ".git") {
+			t.Errorf("Expected .git to be skipped, got %v", paths)
+		}
+	}
+
+	for _, glob := range []string{"../secrets", "/etc/passwd", "dist/../../x"} {
+		if _, err := Collect(worktree, []string{glob}); err == nil {
+			t.Errorf("Expected error for glob %q, got nil", glob)
+		}
+	}
+}
+
+func TestPublishLocal(t *testing.T) {
+	worktree := t.TempDir()
+	storeDir := t.TempDir()
+	writeFiles(t, worktree, "dist/app.bin", "main.go")
+
+	store := &storage.LocalStore{Dir: storeDir}
+	published, err := Publish(context.Background(), store, "feat-1", worktree, []string{"dist/*"})
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if len(published) != 1 || published[0].Path != "dist/app.bin" || published[0].Size != int64(len("dist/app.bin")) {
+		t.Fatalf("Unexpected published artifacts: %+v", published)
+	}
+
+	dest := filepath.Join(storeDir, "feat-1", "dist", "app.bin")
+	if published[0].URL != "file://"+filepath.ToSlash(dest) {
+		t.Errorf("Expected URL for %s, got %s", dest, published[0].URL)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != "dist/app.bin" {
+		t.Errorf("Expected copied artifact, got %q (err %v)", data, err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	if records, err := LoadHistory(metricsDir, ""); err != nil || records != nil {
+		t.Errorf("Expected no history yet, got %v (err %v)", records, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, record := range []Record{
+		{TicketID: "feat-1", Commit: "aaa", PublishedAt: now, Artifacts: []Artifact{{Path: "a.bin", URL: "file:///a.bin", Size: 1}}},
+		{TicketID: "feat-2", Commit: "bbb", PublishedAt: now},
+		{TicketID: "feat-1", Commit: "ccc", PublishedAt: now.Add(time.Minute)},
+	} {
+		if err := AppendHistory(metricsDir, record); err != nil {
+			t.Fatalf("AppendHistory failed: %v", err)
+		}
+	}
+
+	records, err := LoadHistory(metricsDir, "feat-1")
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Commit != "aaa" || records[1].Commit != "ccc" {
+		t.Errorf("Expected [aaa ccc] for feat-1, got %+v", records)
+	}
+	if records[0].Artifacts[0].URL != "file:///a.bin" {
+		t.Errorf("Expected artifact URL to round-trip, got %+v", records[0].Artifacts)
+	}
+
+	if records, _ := LoadHistory(metricsDir, ""); len(records) != 3 {
+		t.Errorf("Expected 3 records in total, got %d", len(records))
+	}
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/journal"
+)
+
+// FileName is the journal's name within the state directory
+const FileName = "audit.jsonl"
+
+// Entry records one control command and who issued it
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Command string            `json:"command"`
+	Args    map[string]string `json:"args,omitempty"`
+	Caller  ipc.Caller        `json:"caller"`
+	OK      bool              `json:"ok"`
+	Message string            `json:"message,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// SystemCaller is the caller of entries the orchestrator records on its own behalf
+var SystemCaller = ipc.Caller{Token: "orchestrator"}
+
+// Journal appends entries to a JSON lines file
+// With an encrypting cipher each line is sealed and base64 encoded
+type Journal struct {
+	file *journal.Writer
+}
+
+// Open returns the journal in stateDir
+func Open(stateDir string, cipher *encryption.Cipher) *Journal {
+	return &Journal{file: journal.NewWriter(filepath.Join(stateDir, FileName), cipher)}
+}
+
+// Record appends an IPC command to the journal
+func (j *Journal) Record(record ipc.CommandRecord) error {
+	return j.Append(Entry{
+		Time:    record.Time.UTC(),
+		Command: record.Name,
+		Args:    record.Args,
+		Caller:  record.Caller,
+		OK:      record.Response.OK,
+		Message: record.Response.Message,
+		Error:   record.Response.Error,
+	})
+}
+
+// RecordCISkip appends a CI run a worker skipped, from a ci_skipped event
+func (j *Journal) RecordCISkip(event ipc.Event) error {
+	skip, ok := event.Data.(ipc.TicketEvent)
+	if event.Type != ipc.EventTypeCISkipped || !ok || skip.Ticket == nil {
+		return nil
+	}
+	return j.Append(Entry{
+		Time:    event.Timestamp.UTC(),
+		Command: "ci_skip",
+		Args:    map[string]string{"ticket": skip.Ticket.ID, "worker": strconv.Itoa(skip.WorkerID)},
+		Caller:  SystemCaller,
+		OK:      true,
+		Message: skip.Message,
+	})
+}
+
+// RecordRegressionTest appends the outcome of a bug fix's regression test
+// check, from a regression_test event
+func (j *Journal) RecordRegressionTest(event ipc.Event) error {
+	check, ok := event.Data.(ipc.RegressionTestEvent)
+	if event.Type != ipc.EventTypeRegressionTest || !ok || check.Ticket == nil {
+		return nil
+	}
+	return j.Append(Entry{
+		Time:    event.Timestamp.UTC(),
+		Command: "regression_test",
+		Args:    map[string]string{"ticket": check.Ticket.ID, "worker": strconv.Itoa(check.WorkerID)},
+		Caller:  SystemCaller,
+		OK:      check.Passed,
+		Message: check.Message,
+	})
+}
+
+// RecordEnqueue appends a ticket the watcher enqueued, with its provenance,
+// from a ticket_enqueued event
+func (j *Journal) RecordEnqueue(event ipc.Event) error {
+	enqueued, ok := event.Data.(ipc.TicketEvent)
+	if event.Type != ipc.EventTypeTicketEnqueued || !ok || enqueued.Ticket == nil {
+		return nil
+	}
+	args := map[string]string{"ticket": enqueued.Ticket.ID}
+	if p := enqueued.Ticket.Provenance; p != nil {
+		args["enqueued_by"] = p.EnqueuedBy
+		args["source"] = p.Source
+		args["checksum"] = p.Checksum
+	}
+	return j.Append(Entry{
+		Time:    event.Timestamp.UTC(),
+		Command: "enqueue",
+		Args:    args,
+		Caller:  SystemCaller,
+		OK:      true,
+		Message: enqueued.Message,
+	})
+}
+
+// Append writes an entry to the end of the journal
+func (j *Journal) Append(entry Entry) error {
+	return j.file.Append(entry)
+}
+
+// Load reads every entry in the journal in stateDir, oldest first
+// Encrypted lines are decrypted with cipher
+func Load(stateDir string, cipher *encryption.Cipher) ([]Entry, error) {
+	var entries []Entry
+	err := journal.Read(filepath.Join(stateDir, FileName), cipher, func(data []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
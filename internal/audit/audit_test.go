@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestJournalRecordAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	journal := Open(dir, nil)
+
+	caller := ipc.Caller{Token: "deploy-bot", User: "alice", UID: 1000, PID: 4242}
+	if err := journal.Record(ipc.CommandRecord{
+		Time:     time.Now(),
+		Name:     "ci_rerun",
+		Args:     map[string]string{"target": "feat-1"},
+		Caller:   caller,
+		Response: ipc.CommandResponse{OK: true, Message: "Re-running CI"},
+	}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := journal.Append(Entry{Time: time.Now(), Command: "pause", Caller: ipc.Caller{UID: 0, PID: 1}, Error: "permission denied"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	entries, err := Load(dir, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Command != "ci_rerun" || entries[0].Caller != caller || !entries[0].OK || entries[0].Args["target"] != "feat-1" {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].OK || entries[1].Error != "permission denied" {
+		t.Errorf("Unexpected second entry %+v", entries[1])
+	}
+
+	if info, _ := os.Stat(filepath.Join(dir, FileName)); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected journal mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestJournalEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	// Entries written before encryption was enabled stay readable
+	if err := Open(dir, nil).Append(Entry{Command: "ci_rerun", Caller: ipc.Caller{Token: "before"}}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := Open(dir, cipher).Append(Entry{Command: "ci_rerun", Caller: ipc.Caller{Token: "secret-bot"}}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, FileName))
+	if bytes.Contains(data, []byte("secret-bot")) {
+		t.Error("Expected encrypted entry not to be stored in plaintext")
+	}
+
+	entries, err := Load(dir, cipher)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Caller.Token != "before" || entries[1].Caller.Token != "secret-bot" {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+
+	if _, err := Load(dir, nil); !errors.Is(err, encryption.ErrNoKey) {
+		t.Errorf("Expected ErrNoKey without a key, got %v", err)
+	}
+}
+
+func TestLoadMissingJournal(t *testing.T) {
+	entries, err := Load(t.TempDir(), nil)
+	if err != nil || entries != nil {
+		t.Errorf("Expected no entries, got %v (err %v)", entries, err)
+	}
+}
+
+func TestJournalRecordCISkip(t *testing.T) {
+	dir := t.TempDir()
+	journal := Open(dir, nil)
+
+	events := []ipc.Event{
+		{Type: ipc.EventTypeCISkipped, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "docs-1"}, WorkerID: 2, Message: "ticket sets skip_ci"}},
+		{Type: ipc.EventTypeTicketComplete, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "docs-1"}}},
+	}
+	for _, event := range events {
+		if err := journal.RecordCISkip(event); err != nil {
+			t.Fatalf("RecordCISkip failed: %v", err)
+		}
+	}
+
+	entries, err := Load(dir, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected only the skip to be recorded, got %d entries", len(entries))
+	}
+	entry := entries[0]
+	if entry.Command != "ci_skip" || entry.Args["ticket"] != "docs-1" || entry.Args["worker"] != "2" ||
+		entry.Caller != SystemCaller || entry.Message != "ticket sets skip_ci" {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+}
+
+func TestJournalRecordRegressionTest(t *testing.T) {
+	dir := t.TempDir()
+	journal := Open(dir, nil)
+
+	bug := &ticket.Ticket{ID: "bug-1"}
+	events := []ipc.Event{
+		{Type: ipc.EventTypeRegressionTest, Timestamp: time.Now(), Data: ipc.RegressionTestEvent{Ticket: bug, WorkerID: 3, Message: "the fix changes no test files"}},
+		{Type: ipc.EventTypeRegressionTest, Timestamp: time.Now(), Data: ipc.RegressionTestEvent{Ticket: bug, WorkerID: 3, Passed: true, Message: "regression test in parser_test.go"}},
+		{Type: ipc.EventTypeCISkipped, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: bug}},
+	}
+	for _, event := range events {
+		if err := journal.RecordRegressionTest(event); err != nil {
+			t.Fatalf("RecordRegressionTest failed: %v", err)
+		}
+	}
+
+	entries, err := Load(dir, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected both checks to be recorded, got %d entries", len(entries))
+	}
+	if entry := entries[0]; entry.Command != "regression_test" || entry.OK || entry.Args["ticket"] != "bug-1" || entry.Args["worker"] != "3" {
+		t.Errorf("Unexpected entry for the missing test %+v", entry)
+	}
+	if entry := entries[1]; !entry.OK || entry.Message != "regression test in parser_test.go" {
+		t.Errorf("Unexpected entry for the passed check %+v", entry)
+	}
+}
+
+func TestJournalRecordEnqueue(t *testing.T) {
+	dir := t.TempDir()
+	journal := Open(dir, nil)
+
+	provenance := &ticket.Provenance{EnqueuedBy: "cli:alice", Source: "/tmp/feat-1.yaml", Checksum: "sha256:abc"}
+	events := []ipc.Event{
+		{Type: ipc.EventTypeTicketEnqueued, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-1", Provenance: provenance}, Message: "Ticket feat-1 enqueued"}},
+		{Type: ipc.EventTypeTicketStarted, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-1"}}},
+	}
+	for _, event := range events {
+		if err := journal.RecordEnqueue(event); err != nil {
+			t.Fatalf("RecordEnqueue failed: %v", err)
+		}
+	}
+
+	entries, err := Load(dir, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected only the enqueue to be recorded, got %d entries", len(entries))
+	}
+	entry := entries[0]
+	if entry.Command != "enqueue" || entry.Args["ticket"] != "feat-1" || entry.Args["enqueued_by"] != "cli:alice" ||
+		entry.Args["source"] != "/tmp/feat-1.yaml" || entry.Args["checksum"] != "sha256:abc" {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+}
@@ -0,0 +1,336 @@
+package backlog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"gopkg.in/yaml.v3"
+)
+
+// CSVColumns are the columns ExportCSV writes; ImportCSV needs id and title
+// and ignores columns it does not know, such as a planner's notes
+var CSVColumns = []string{"id", "title", "priority", "estimate_min", "tags", "status", "description"}
+
+// CSVRow is one ticket in a spreadsheet
+type CSVRow struct {
+	Ticket *ticket.Ticket
+	Status string // e.g. queued, processed, completed; see search for the statuses
+}
+
+// CSVImportResult summarises what an import changed
+type CSVImportResult struct {
+	Created int // New ticket files written to the backlog
+	Updated int // Ticket files still waiting in the backlog that were edited
+	Skipped int // Rows for tickets already picked up or finished
+}
+
+// ExportCSV writes tickets to w as CSV with a header row; tags are joined
+// with commas
+func ExportCSV(w io.Writer, rows []CSVRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		t := row.Ticket
+		estimate := ""
+		if t.EstimateMin > 0 {
+			estimate = strconv.Itoa(t.EstimateMin)
+		}
+		record := []string{t.ID, t.Title, strconv.Itoa(t.Priority), estimate, strings.Join(t.Tags, ", "), row.Status, t.Description}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvTicket is a parsed row and which of its cells were filled in
+type csvTicket struct {
+	line   int
+	ticket ticket.Ticket
+	set    map[string]bool
+}
+
+// ImportCSV writes the rows of a CSV export back to the backlog as YAML
+// tickets: a new ID becomes a new ticket file, and a ticket whose file is
+// still waiting in the backlog directory has the row's fields written into
+// it. Rows for tickets in known (ID to status) that were already picked up,
+// and rows whose status is anything but queued, are skipped. Every row is
+// checked before anything is written.
+func ImportCSV(r io.Reader, backlogPath string, known map[string]string) (*CSVImportResult, error) {
+	rows, err := readCSVTickets(r)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := pendingFiles(backlogPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []error
+	seen := make(map[string]int)
+	updates := make(map[string][]byte) // Pending ticket files and their edited contents
+	for _, row := range rows {
+		id := row.ticket.ID
+		if line, ok := seen[id]; ok {
+			problems = append(problems, fmt.Errorf("line %d: ticket %s is also on line %d", row.line, id, line))
+		}
+		seen[id] = row.line
+
+		var err error
+		if path, ok := pending[id]; ok {
+			updates[path], err = editTicketFile(path, row)
+		} else if known[id] == "" {
+			err = row.ticket.Validate()
+			if err == nil && (id != filepath.Base(id) || strings.HasPrefix(id, ".")) {
+				err = fmt.Errorf("ticket ID %q cannot be used as a file name", id)
+			}
+		}
+		if err != nil {
+			problems = append(problems, fmt.Errorf("line %d: %w", row.line, err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	if err := os.MkdirAll(backlogPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backlog directory: %w", err)
+	}
+	result := &CSVImportResult{}
+	for _, row := range rows {
+		switch path, ok := pending[row.ticket.ID]; {
+		case ok:
+			if err := os.WriteFile(path, updates[path], 0644); err != nil {
+				return result, fmt.Errorf("failed to write ticket %s: %w", row.ticket.ID, err)
+			}
+			result.Updated++
+		case known[row.ticket.ID] != "":
+			result.Skipped++
+		default:
+			if err := createTicketFile(backlogPath, &row.ticket); err != nil {
+				return result, fmt.Errorf("line %d: %w", row.line, err)
+			}
+			result.Created++
+		}
+	}
+	return result, nil
+}
+
+// readCSVTickets parses the rows to import, leaving out blank rows and
+// rows whose status says the ticket is past the queue
+func readCSVTickets(r io.Reader) ([]csvTicket, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // Spreadsheets drop trailing empty cells
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"id", "title"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header has no %s column", required)
+		}
+	}
+
+	var rows []csvTicket
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		cell := func(name string) (string, bool) {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return "", false
+			}
+			value := strings.TrimSpace(record[i])
+			return value, value != ""
+		}
+
+		id, ok := cell("id")
+		if !ok {
+			if strings.TrimSpace(strings.Join(record, "")) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: ticket ID is required", line)
+		}
+		if status, ok := cell("status"); ok && !strings.EqualFold(status, "queued") {
+			continue
+		}
+
+		row := csvTicket{line: line, ticket: ticket.Ticket{ID: id, Priority: 3}, set: make(map[string]bool)}
+		if title, ok := cell("title"); ok {
+			row.ticket.Title = title
+			row.set["title"] = true
+		}
+		if description, ok := cell("description"); ok {
+			row.ticket.Description = description
+			row.set["description"] = true
+		}
+		if priority, ok := cell("priority"); ok {
+			if row.ticket.Priority, err = strconv.Atoi(priority); err != nil {
+				return nil, fmt.Errorf("line %d: priority %q is not a number", line, priority)
+			}
+			row.set["priority"] = true
+		}
+		if estimate, ok := cell("estimate_min"); ok {
+			if row.ticket.EstimateMin, err = strconv.Atoi(estimate); err != nil || row.ticket.EstimateMin < 0 {
+				return nil, fmt.Errorf("line %d: estimate_min %q is not a number of minutes", line, estimate)
+			}
+			row.set["estimate_min"] = true
+		}
+		if _, ok := columns["tags"]; ok {
+			tags, _ := cell("tags")
+			for _, tag := range strings.Split(tags, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					row.ticket.Tags = append(row.ticket.Tags, tag)
+				}
+			}
+			row.set["tags"] = true
+		}
+		rows = append(rows, row)
+	}
+}
+
+// pendingFiles maps the IDs of tickets waiting in the backlog directory to
+// their files
+func pendingFiles(backlogPath string) (map[string]string, error) {
+	entries, err := os.ReadDir(backlogPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", backlogPath, err)
+	}
+	pending := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !isTicketFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(backlogPath, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ticket file %s: %w", entry.Name(), err)
+		}
+		var t struct {
+			ID string `yaml:"id"`
+		}
+		if yaml.Unmarshal(data, &t) == nil && t.ID != "" {
+			pending[t.ID] = path
+		}
+	}
+	return pending, nil
+}
+
+// createTicketFile writes a new ticket to the backlog, named after its ID
+func createTicketFile(backlogPath string, t *ticket.Ticket) error {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to encode ticket %s: %w", t.ID, err)
+	}
+	path := filepath.Join(backlogPath, t.ID+".yaml")
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write ticket %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+// editTicketFile returns a ticket file with the cells a row filled in
+// written into it, keeping its other fields and comments
+func editTicketFile(path string, row csvTicket) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s is not a YAML ticket", path)
+	}
+	fields := doc.Content[0]
+	if hasKey(fields, "signature") {
+		return nil, fmt.Errorf("ticket %s is signed; edit and re-sign %s instead", row.ticket.ID, path)
+	}
+
+	t := row.ticket
+	if row.set["title"] {
+		setScalar(fields, "title", "!!str", t.Title)
+	}
+	if row.set["description"] {
+		setScalar(fields, "description", "!!str", t.Description)
+	}
+	if row.set["priority"] {
+		setScalar(fields, "priority", "!!int", strconv.Itoa(t.Priority))
+	}
+	if row.set["estimate_min"] {
+		setScalar(fields, "estimate_min", "!!int", strconv.Itoa(t.EstimateMin))
+	}
+	if row.set["tags"] {
+		tags := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, tag := range t.Tags {
+			tags.Content = append(tags.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: tag})
+		}
+		setNode(fields, "tags", tags)
+	}
+
+	var updated bytes.Buffer
+	encoder := yaml.NewEncoder(&updated)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := ticket.LoadFromBytes(updated.Bytes()); err != nil {
+		return nil, fmt.Errorf("ticket %s: %w", t.ID, err)
+	}
+	return updated.Bytes(), nil
+}
+
+// hasKey reports whether a YAML mapping has key
+func hasKey(mapping *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+// setScalar sets a YAML mapping's key to a value with the given tag
+func setScalar(mapping *yaml.Node, key, tag, value string) {
+	setNode(mapping, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value})
+}
+
+// setNode replaces a YAML mapping's value for key, or appends the key
+func setNode(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value.LineComment = mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
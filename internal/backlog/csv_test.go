@@ -0,0 +1,119 @@
+package backlog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	rows := []CSVRow{
+		{Ticket: &ticket.Ticket{ID: "feat-a", Title: "Login, with SSO", Description: "Add SSO", Priority: 2, EstimateMin: 90, Tags: []string{"auth", "web"}}, Status: "queued"},
+		{Ticket: &ticket.Ticket{ID: "feat-b", Title: "Old work", Description: "Done already", Priority: 3}, Status: "completed"},
+	}
+	if err := ExportCSV(&out, rows); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	want := "id,title,priority,estimate_min,tags,status,description\n" +
+		"feat-a,\"Login, with SSO\",2,90,\"auth, web\",queued,Add SSO\n" +
+		"feat-b,Old work,3,,,completed,Done already\n"
+	if out.String() != want {
+		t.Fatalf("Got CSV\n%s\nwant\n%s", out.String(), want)
+	}
+
+	backlogPath := filepath.Join(t.TempDir(), "backlog")
+	result, err := ImportCSV(&out, backlogPath, nil)
+	if err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	if result.Created != 1 || result.Updated != 0 || result.Skipped != 0 {
+		t.Errorf("Expected only the queued ticket created, got %+v", result)
+	}
+	imported, err := ticket.Load(filepath.Join(backlogPath, "feat-a.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load imported ticket: %v", err)
+	}
+	if imported.Title != "Login, with SSO" || imported.Priority != 2 || imported.EstimateMin != 90 || !slices.Equal(imported.Tags, []string{"auth", "web"}) {
+		t.Errorf("Imported ticket does not match the row: %+v", imported)
+	}
+}
+
+func TestImportCSVUpdatesPendingTickets(t *testing.T) {
+	backlogPath := filepath.Join(t.TempDir(), "backlog")
+	if err := os.MkdirAll(backlogPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	original := "# Groomed in the planning meeting\nid: feat-a\ntitle: Login\ndescription: Add SSO\npriority: 3 # was 4\nlocks: [auth]\n"
+	if err := os.WriteFile(filepath.Join(backlogPath, "login.yaml"), []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "ID,Title,Priority,Tags,Notes\n" +
+		"feat-a,Login with SSO,1,\"auth, web\",bump it\n" +
+		"feat-running,Running,2,,\n" +
+		",,,,\n" +
+		"feat-new,Brand new,4,,\n"
+	known := map[string]string{"feat-running": "processed"}
+
+	// feat-new has no description, so nothing is written
+	if _, err := ImportCSV(strings.NewReader(input), backlogPath, known); err == nil || !strings.Contains(err.Error(), "line 5") {
+		t.Fatalf("Expected the row without a description to be reported, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(backlogPath, "login.yaml")); string(data) != original {
+		t.Fatalf("Expected no changes after a failed import, got\n%s", data)
+	}
+
+	input = strings.Replace(input, "feat-new,Brand new,4,,", "", 1)
+	result, err := ImportCSV(strings.NewReader(input), backlogPath, known)
+	if err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	if result.Created != 0 || result.Updated != 1 || result.Skipped != 1 {
+		t.Errorf("Expected one update and one skip, got %+v", result)
+	}
+
+	data, err := os.ReadFile(filepath.Join(backlogPath, "login.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := ticket.LoadFromBytes(data)
+	if err != nil {
+		t.Fatalf("Updated ticket is invalid: %v\n%s", err, data)
+	}
+	if updated.Title != "Login with SSO" || updated.Priority != 1 || updated.Description != "Add SSO" || !slices.Equal(updated.Locks, []string{"auth"}) {
+		t.Errorf("Expected the row's cells written over the file's, got %+v", updated)
+	}
+	if !strings.Contains(string(data), "# Groomed in the planning meeting") {
+		t.Errorf("Expected comments kept, got\n%s", data)
+	}
+}
+
+func TestImportCSVRejectsBadRows(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no id column", "title\nLogin\n"},
+		{"bad priority", "id,title,description,priority\nfeat-a,Login,SSO,high\n"},
+		{"priority out of range", "id,title,description,priority\nfeat-a,Login,SSO,9\n"},
+		{"duplicate", "id,title,description\nfeat-a,Login,SSO\nfeat-a,Again,SSO\n"},
+		{"path in id", "id,title,description\n../feat-a,Login,SSO\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backlogPath := t.TempDir()
+			if _, err := ImportCSV(strings.NewReader(tt.input), backlogPath, nil); err == nil {
+				t.Error("Expected an error")
+			}
+			if entries, _ := os.ReadDir(backlogPath); len(entries) != 0 {
+				t.Errorf("Expected nothing written, got %v", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package backlog
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveDir holds the compressed archives of expired processed tickets
+const archiveDir = "archive"
+
+// Retention limits how many processed ticket files stay in backlog/processed
+// Files beyond either limit are moved into a compressed archive
+type Retention struct {
+	MaxAgeDays int `mapstructure:"max_age_days"` // Archive files processed longer ago than this; 0 disables
+	MaxFiles   int `mapstructure:"max_files"`    // Archive the oldest files beyond this many; 0 disables
+}
+
+// Enabled reports whether any retention limit is set
+func (r Retention) Enabled() bool {
+	return r.MaxAgeDays > 0 || r.MaxFiles > 0
+}
+
+// Validate checks the retention limits
+func (r Retention) Validate() error {
+	if r.MaxAgeDays < 0 || r.MaxFiles < 0 {
+		return errors.New("max_age_days and max_files cannot be negative")
+	}
+	return nil
+}
+
+// ProcessedStats counts the processed ticket files kept in the backlog
+type ProcessedStats struct {
+	Processed int `json:"processed"` // Files still in backlog/processed
+	Archived  int `json:"archived"`  // Files moved into archives
+	Archives  int `json:"archives"`  // Archive files in backlog/archive
+}
+
+// processedFile is a processed ticket file and when it was processed
+type processedFile struct {
+	name     string
+	modified time.Time
+}
+
+// PruneProcessed moves processed ticket files beyond the retention limits
+// into a new gzipped tar in backlog/archive and returns how many were moved
+// A file's modification time is taken as the time it was processed
+func PruneProcessed(backlogPath string, r Retention, now time.Time) (int, error) {
+	if !r.Enabled() {
+		return 0, nil
+	}
+
+	files, err := listProcessed(backlogPath)
+	if err != nil {
+		return 0, err
+	}
+
+	// Newest first, so everything past MaxFiles is the oldest
+	sort.Slice(files, func(i, j int) bool { return files[i].modified.After(files[j].modified) })
+	cutoff := now.Add(-time.Duration(r.MaxAgeDays) * 24 * time.Hour)
+	var expired []processedFile
+	for i, f := range files {
+		if (r.MaxFiles > 0 && i >= r.MaxFiles) || (r.MaxAgeDays > 0 && f.modified.Before(cutoff)) {
+			expired = append(expired, f)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	dir := filepath.Join(backlogPath, archiveDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	archivePath := filepath.Join(dir, fmt.Sprintf("processed-%s.tar.gz", now.UTC().Format("20060102-150405")))
+	if err := writeArchive(archivePath, filepath.Join(backlogPath, "processed"), expired); err != nil {
+		os.Remove(archivePath)
+		return 0, err
+	}
+
+	// Files are only removed once the archive holding them is complete
+	for _, f := range expired {
+		if err := os.Remove(filepath.Join(backlogPath, "processed", f.name)); err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("failed to remove archived ticket file %s: %w", f.name, err)
+		}
+	}
+	return len(expired), nil
+}
+
+// Stats counts processed ticket files in the backlog and its archives
+func Stats(backlogPath string) (ProcessedStats, error) {
+	var stats ProcessedStats
+	files, err := listProcessed(backlogPath)
+	if err != nil {
+		return stats, err
+	}
+	stats.Processed = len(files)
+
+	entries, err := os.ReadDir(filepath.Join(backlogPath, archiveDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, fmt.Errorf("failed to read archive directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tar.gz") {
+			continue
+		}
+		count, err := countArchive(filepath.Join(backlogPath, archiveDir, entry.Name()))
+		if err != nil {
+			return stats, err
+		}
+		stats.Archives++
+		stats.Archived += count
+	}
+	return stats, nil
+}
+
+// listProcessed returns the ticket files in backlog/processed
+func listProcessed(backlogPath string) ([]processedFile, error) {
+	entries, err := os.ReadDir(filepath.Join(backlogPath, "processed"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read processed directory: %w", err)
+	}
+
+	var files []processedFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isTicketFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, processedFile{name: entry.Name(), modified: info.ModTime()})
+	}
+	return files, nil
+}
+
+// writeArchive writes files from dir into a gzipped tar at path
+// Encrypted tickets are archived as they are on disk
+func writeArchive(path, dir string, files []processedFile) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(dir, f.name))
+		if err != nil {
+			return fmt.Errorf("failed to read ticket file %s: %w", f.name, err)
+		}
+		if err := writeTarFile(tw, f.name, data, f.modified); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress archive: %w", err)
+	}
+	return out.Close()
+}
+
+// countArchive returns the number of files in a gzipped tar
+func countArchive(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive %s: %w", filepath.Base(path), err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	count := 0
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read archive %s: %w", filepath.Base(path), err)
+		}
+		count++
+	}
+}
+
+// ArchivedFile is a processed ticket file read back from an archive
+type ArchivedFile struct {
+	Archive   string // Archive file name in backlog/archive
+	Name      string
+	Data      []byte // As archived, so possibly encrypted
+	Processed time.Time
+}
+
+// ReadArchived returns every ticket file in the backlog's archives
+func ReadArchived(backlogPath string) ([]ArchivedFile, error) {
+	entries, err := os.ReadDir(filepath.Join(backlogPath, archiveDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read archive directory: %w", err)
+	}
+
+	var files []ArchivedFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tar.gz") {
+			continue
+		}
+		archived, err := readArchive(filepath.Join(backlogPath, archiveDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, archived...)
+	}
+	return files, nil
+}
+
+// readArchive returns the files in a gzipped tar
+func readArchive(path string) ([]ArchivedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", filepath.Base(path), err)
+	}
+	defer gz.Close()
+
+	var files []ArchivedFile
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive %s: %w", filepath.Base(path), err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive %s: %w", header.Name, filepath.Base(path), err)
+		}
+		files = append(files, ArchivedFile{Archive: filepath.Base(path), Name: header.Name, Data: data, Processed: header.ModTime})
+	}
+}
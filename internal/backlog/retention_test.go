@@ -0,0 +1,87 @@
+package backlog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneProcessed(t *testing.T) {
+	backlogPath := t.TempDir()
+	processed := filepath.Join(backlogPath, "processed")
+	now := time.Now()
+
+	ages := map[string]time.Duration{
+		"old.yaml":    40 * 24 * time.Hour,
+		"older.yaml":  50 * 24 * time.Hour,
+		"recent.yaml": 2 * 24 * time.Hour,
+		"new.yaml":    time.Hour,
+		"newest.yaml": time.Minute,
+	}
+	for name, age := range ages {
+		writeTicket(t, processed, name, name[:len(name)-5])
+		modified := now.Add(-age)
+		os.Chtimes(filepath.Join(processed, name), modified, modified)
+	}
+
+	// Nothing happens without a limit
+	if archived, err := PruneProcessed(backlogPath, Retention{}, now); err != nil || archived != 0 {
+		t.Fatalf("Expected nothing archived without limits, got %d (err %v)", archived, err)
+	}
+
+	archived, err := PruneProcessed(backlogPath, Retention{MaxAgeDays: 30}, now)
+	if err != nil {
+		t.Fatalf("PruneProcessed failed: %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("Expected 2 files past max_age_days archived, got %d", archived)
+	}
+	for _, name := range []string{"old.yaml", "older.yaml"} {
+		if _, err := os.Stat(filepath.Join(processed, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s moved out of processed", name)
+		}
+	}
+
+	// The oldest files beyond max_files go into a second archive
+	archived, err = PruneProcessed(backlogPath, Retention{MaxFiles: 2}, now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("PruneProcessed failed: %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("Expected 1 file beyond max_files archived, got %d", archived)
+	}
+	if _, err := os.Stat(filepath.Join(processed, "recent.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected the oldest remaining file archived")
+	}
+
+	stats, err := Stats(backlogPath)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats != (ProcessedStats{Processed: 2, Archived: 3, Archives: 2}) {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	files, err := ReadArchived(backlogPath)
+	if err != nil {
+		t.Fatalf("ReadArchived failed: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, f := range files {
+		names[f.Name] = len(f.Data) > 0 && !f.Processed.IsZero()
+	}
+	if len(names) != 3 || !names["old.yaml"] || !names["older.yaml"] || !names["recent.yaml"] {
+		t.Errorf("Expected the 3 archived files back with contents, got %v", names)
+	}
+}
+
+func TestStatsWithoutProcessedTickets(t *testing.T) {
+	stats, err := Stats(t.TempDir())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats != (ProcessedStats{}) {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
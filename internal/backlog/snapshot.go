@@ -0,0 +1,329 @@
+package backlog
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/deadletter"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/graph"
+	"github.com/brettsmith212/amp-orchestrator/internal/journal"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// SnapshotVersion is the current snapshot archive format version
+const SnapshotVersion = 1
+
+// manifestName is the name of the manifest entry inside a snapshot archive
+const manifestName = "manifest.json"
+
+// deadLetterName is the name of the dead-letter journal inside a snapshot
+// archive
+const deadLetterName = "state/" + deadletter.FileName
+
+// Entry describes a single ticket stored in a snapshot
+type Entry struct {
+	ID        string `json:"id"`
+	File      string `json:"file"`
+	Status    string `json:"status"`
+	Processed bool   `json:"processed"`
+}
+
+// Manifest describes the contents of a snapshot archive
+type Manifest struct {
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	Entries     []Entry   `json:"entries"`
+	DeadLetters int       `json:"dead_letters,omitempty"` // Lines of the dead-letter journal archived
+}
+
+// ImportResult summarises what an import restored
+type ImportResult struct {
+	Manifest    *Manifest
+	Requeued    int // Tickets placed back in the backlog for processing
+	Restored    int // Tickets restored to backlog/processed as history
+	Skipped     int // Tickets whose file already existed
+	DeadLetters int // Dead-letter entries added to the journal
+}
+
+// Export writes a tar snapshot of the backlog directory and of the
+// dead-letter journal in stateDir to w
+// statuses maps ticket IDs to their current status and is recorded in the manifest
+// Encrypted tickets are read with cipher and archived as they are on disk, as
+// are the journal's lines
+func Export(w io.Writer, backlogPath, stateDir string, statuses map[string]string, cipher *encryption.Cipher) (*Manifest, error) {
+	manifest := &Manifest{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	type fileData struct {
+		name string
+		data []byte
+	}
+	var files []fileData
+
+	dirs := []struct {
+		path      string
+		processed bool
+	}{
+		{backlogPath, false},
+		{filepath.Join(backlogPath, "processed"), true},
+	}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", dir.path, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !isTicketFile(entry.Name()) {
+				continue
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir.path, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ticket file %s: %w", entry.Name(), err)
+			}
+
+			plaintext, err := cipher.Decrypt(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ticket file %s: %w", entry.Name(), err)
+			}
+
+			t, err := ticket.LoadFromBytes(plaintext)
+			if err != nil {
+				// Invalid tickets are never processed, so they are not worth preserving
+				continue
+			}
+
+			status := statuses[t.ID]
+			if status == "" {
+				status = graph.StatusUnknown
+				if !dir.processed {
+					status = graph.StatusQueued
+				}
+			}
+
+			archiveName := "pending/" + entry.Name()
+			if dir.processed {
+				archiveName = "processed/" + entry.Name()
+			}
+
+			manifest.Entries = append(manifest.Entries, Entry{
+				ID:        t.ID,
+				File:      archiveName,
+				Status:    status,
+				Processed: dir.processed,
+			})
+			files = append(files, fileData{name: archiveName, data: data})
+		}
+	}
+
+	if stateDir != "" {
+		deadLetters, err := journal.Lines(filepath.Join(stateDir, deadletter.FileName))
+		if err != nil {
+			return nil, err
+		}
+		if len(deadLetters) > 0 {
+			manifest.DeadLetters = len(deadLetters)
+			files = append(files, fileData{name: deadLetterName, data: []byte(strings.Join(deadLetters, "\n") + "\n")})
+		}
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeTarFile(tw, manifestName, manifestJSON, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := writeTarFile(tw, f.name, f.data, manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize snapshot: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Import restores a tar snapshot into the backlog directory and the
+// dead-letter journal in stateDir
+// Tickets that had not completed are placed back in the backlog so the daemon
+// picks them up again; finished tickets are restored to backlog/processed
+// Existing files are never overwritten, and dead-letter entries the journal
+// already has are not added again
+func Import(r io.Reader, backlogPath, stateDir string) (*ImportResult, error) {
+	tr := tar.NewReader(r)
+
+	var manifest *Manifest
+	files := make(map[string][]byte)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from snapshot: %w", hdr.Name, err)
+		}
+
+		if hdr.Name == manifestName {
+			manifest = &Manifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse snapshot manifest: %w", err)
+			}
+			continue
+		}
+		files[hdr.Name] = data
+	}
+
+	if manifest == nil {
+		return nil, errors.New("snapshot has no manifest")
+	}
+	if manifest.Version > SnapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d is newer than supported version %d", manifest.Version, SnapshotVersion)
+	}
+
+	processedDir := filepath.Join(backlogPath, "processed")
+	if err := os.MkdirAll(processedDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create processed directory: %w", err)
+	}
+
+	result := &ImportResult{Manifest: manifest}
+	for _, entry := range manifest.Entries {
+		data, ok := files[entry.File]
+		if !ok {
+			return nil, fmt.Errorf("snapshot is missing %s for ticket %s", entry.File, entry.ID)
+		}
+
+		requeue := needsRequeue(entry.Status)
+		destDir := processedDir
+		if requeue {
+			destDir = backlogPath
+		}
+
+		// Only the base name is trusted to keep writes inside the backlog
+		destPath := filepath.Join(destDir, filepath.Base(entry.File))
+		if _, err := os.Stat(destPath); err == nil {
+			result.Skipped++
+			continue
+		}
+
+		if err := os.WriteFile(destPath, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to restore ticket %s: %w", entry.ID, err)
+		}
+
+		if requeue {
+			result.Requeued++
+		} else {
+			result.Restored++
+		}
+	}
+
+	if data, ok := files[deadLetterName]; ok && stateDir != "" {
+		added, err := mergeDeadLetters(filepath.Join(stateDir, deadletter.FileName), data)
+		if err != nil {
+			return nil, err
+		}
+		result.DeadLetters = added
+	}
+
+	return result, nil
+}
+
+// mergeDeadLetters appends the archived journal lines the journal at path
+// doesn't have yet, returning how many were added
+func mergeDeadLetters(path string, data []byte) (int, error) {
+	existing, err := journal.Lines(path)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, line := range existing {
+		seen[line] = true
+	}
+
+	var missing []byte
+	added := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		missing = append(missing, line+"\n"...)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	f, _, err := journal.OpenAppend(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Write(missing); err != nil {
+		return 0, fmt.Errorf("failed to restore dead letters: %w", err)
+	}
+	return added, nil
+}
+
+// needsRequeue reports whether a ticket with the given status should run again
+func needsRequeue(status string) bool {
+	switch status {
+	case graph.StatusQueued, graph.StatusInFlight, graph.StatusUnknown, "":
+		return true
+	}
+	return false
+}
+
+// writeTarFile writes a single regular file entry to the tar writer
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s header: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// isTicketFile checks if the file is a YAML ticket file
+func isTicketFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yaml" || ext == ".yml"
+}
@@ -0,0 +1,171 @@
+package backlog
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/deadletter"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/graph"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func writeTicket(t *testing.T, dir, name, id string) {
+	t.Helper()
+	content := "id: \"" + id + "\"\ntitle: \"Ticket " + id + "\"\ndescription: \"Test ticket\"\npriority: 2\n"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write ticket: %v", err)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	srcBacklog := filepath.Join(t.TempDir(), "backlog")
+	writeTicket(t, srcBacklog, "pending.yaml", "feat-pending")
+	writeTicket(t, filepath.Join(srcBacklog, "processed"), "done.yaml", "feat-done")
+	writeTicket(t, filepath.Join(srcBacklog, "processed"), "running.yaml", "feat-running")
+
+	// Invalid tickets and non-YAML files are not exported
+	if err := os.WriteFile(filepath.Join(srcBacklog, "notes.txt"), []byte("ignore"), 0644); err != nil {
+		t.Fatalf("Failed to write notes: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcBacklog, "broken.yaml"), []byte("id: \"x\""), 0644); err != nil {
+		t.Fatalf("Failed to write broken ticket: %v", err)
+	}
+
+	statuses := map[string]string{
+		"feat-done":    graph.StatusCompleted,
+		"feat-running": graph.StatusInFlight,
+	}
+
+	srcState := t.TempDir()
+	failure := deadletter.Entry{Time: time.Now().UTC(), Ticket: &ticket.Ticket{ID: "feat-failed"}, Code: ipc.ErrorCodeCIFailed}
+	if err := deadletter.Open(srcState, nil).Append(failure); err != nil {
+		t.Fatalf("Failed to record dead letter: %v", err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Export(&buf, srcBacklog, srcState, statuses, nil)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(manifest.Entries) != 3 {
+		t.Fatalf("Expected 3 manifest entries, got %d", len(manifest.Entries))
+	}
+
+	if manifest.DeadLetters != 1 {
+		t.Fatalf("Expected 1 dead letter in the manifest, got %d", manifest.DeadLetters)
+	}
+
+	dstBacklog := filepath.Join(t.TempDir(), "backlog")
+	dstState := t.TempDir()
+	result, err := Import(bytes.NewReader(buf.Bytes()), dstBacklog, dstState)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if result.Requeued != 2 {
+		t.Errorf("Expected 2 requeued tickets, got %d", result.Requeued)
+	}
+	if result.Restored != 1 {
+		t.Errorf("Expected 1 restored ticket, got %d", result.Restored)
+	}
+	if result.DeadLetters != 1 {
+		t.Errorf("Expected 1 dead letter added, got %d", result.DeadLetters)
+	}
+
+	expectedFiles := []string{
+		filepath.Join(dstBacklog, "pending.yaml"),
+		filepath.Join(dstBacklog, "running.yaml"),
+		filepath.Join(dstBacklog, "processed", "done.yaml"),
+	}
+	for _, path := range expectedFiles {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be restored: %v", path, err)
+		}
+	}
+
+	// Importing again must not overwrite anything
+	result, err = Import(bytes.NewReader(buf.Bytes()), dstBacklog, dstState)
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if result.Skipped != 3 {
+		t.Errorf("Expected 3 skipped tickets on re-import, got %d", result.Skipped)
+	}
+	if result.DeadLetters != 0 {
+		t.Errorf("Expected no dead letters added on re-import, got %d", result.DeadLetters)
+	}
+
+	failures, err := deadletter.Load(dstState, nil)
+	if err != nil || len(failures) != 1 || failures[0].Ticket.ID != "feat-failed" || failures[0].Code != ipc.ErrorCodeCIFailed {
+		t.Errorf("Expected the dead letter to be restored once, got %+v (err %v)", failures, err)
+	}
+}
+
+func TestImportRequiresManifest(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := writeTarFile(tw, "pending/a.yaml", []byte("id: a"), time.Now()); err != nil {
+		t.Fatalf("Failed to write tar entry: %v", err)
+	}
+	tw.Close()
+
+	if _, err := Import(&buf, t.TempDir(), ""); err == nil {
+		t.Error("Expected error for snapshot without manifest, got nil")
+	}
+}
+
+func TestExportEncryptedTickets(t *testing.T) {
+	backlogPath := filepath.Join(t.TempDir(), "backlog")
+	processedDir := filepath.Join(backlogPath, "processed")
+	writeTicket(t, processedDir, "done.yaml", "feat-secret")
+
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	path := filepath.Join(processedDir, "done.yaml")
+	plaintext, _ := os.ReadFile(path)
+	if err := cipher.WriteFile(path, plaintext, 0600); err != nil {
+		t.Fatalf("Failed to encrypt ticket: %v", err)
+	}
+
+	// Without the key the export fails rather than silently dropping the ticket
+	if _, err := Export(&bytes.Buffer{}, backlogPath, "", nil, nil); err == nil {
+		t.Error("Expected export of encrypted tickets without a key to fail")
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Export(&buf, backlogPath, "", nil, cipher)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(manifest.Entries) != 1 || manifest.Entries[0].ID != "feat-secret" {
+		t.Fatalf("Unexpected manifest entries: %+v", manifest.Entries)
+	}
+
+	// The archive keeps the ticket encrypted
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatal("Ticket missing from snapshot")
+		}
+		if hdr.Name == "processed/done.yaml" {
+			data, _ := io.ReadAll(tr)
+			if !encryption.IsEncrypted(data) {
+				t.Error("Expected ticket to stay encrypted in the snapshot")
+			}
+			break
+		}
+	}
+}
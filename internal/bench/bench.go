@@ -0,0 +1,245 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+	"gopkg.in/yaml.v3"
+)
+
+// validVariantName restricts variant names to characters safe in branch names
+var validVariantName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Variant is one agent configuration under comparison
+type Variant struct {
+	Name           string   `yaml:"name"`
+	PromptTemplate string   `yaml:"prompt_template,omitempty"` // Path to a text/template file
+	AgentCommand   string   `yaml:"agent_command,omitempty"`
+	AgentArgs      []string `yaml:"agent_args,omitempty"`
+}
+
+// Experiment runs one ticket several times for each variant
+type Experiment struct {
+	Ticket   string    `yaml:"ticket"`
+	Runs     int       `yaml:"runs"`
+	Variants []Variant `yaml:"variants"`
+}
+
+// LoadExperiment loads an experiment definition from a YAML file
+// Relative ticket and template paths are resolved against the file's directory
+func LoadExperiment(path string) (*Experiment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read experiment file %s: %w", path, err)
+	}
+
+	var exp Experiment
+	if err := yaml.Unmarshal(data, &exp); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+	}
+
+	baseDir := filepath.Dir(path)
+	exp.Ticket = resolvePath(baseDir, exp.Ticket)
+	for i := range exp.Variants {
+		exp.Variants[i].PromptTemplate = resolvePath(baseDir, exp.Variants[i].PromptTemplate)
+	}
+
+	if err := exp.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed for experiment in %s: %w", path, err)
+	}
+
+	return &exp, nil
+}
+
+// Validate checks that the experiment is runnable
+func (e *Experiment) Validate() error {
+	if e.Ticket == "" {
+		return errors.New("experiment ticket is required")
+	}
+
+	if e.Runs < 1 {
+		return errors.New("experiment runs must be at least 1")
+	}
+
+	if len(e.Variants) == 0 {
+		return errors.New("experiment needs at least one variant")
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range e.Variants {
+		if !validVariantName.MatchString(v.Name) {
+			return fmt.Errorf("invalid variant name %q (use letters, digits, '.', '_' or '-')", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variant name %q", v.Name)
+		}
+		seen[v.Name] = true
+	}
+
+	return nil
+}
+
+// Outcome is the result of one run of one variant
+type Outcome struct {
+	Variant string
+	Run     int
+	Result  worker.RunResult
+	Diff    *gitutils.DiffStat
+}
+
+// Summary aggregates the outcomes of a single variant
+type Summary struct {
+	Variant       string
+	Runs          int
+	Implemented   int
+	CIPassed      int
+	AvgFiles      float64
+	AvgInsertions float64
+	AvgDeletions  float64
+	AvgDuration   time.Duration
+}
+
+// PassRate returns the fraction of runs whose CI passed
+func (s Summary) PassRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.CIPassed) / float64(s.Runs)
+}
+
+// Report holds the results of an experiment
+type Report struct {
+	ID        string
+	TicketID  string
+	Outcomes  []Outcome
+	Summaries []Summary
+}
+
+// Run executes the experiment sequentially, one isolated branch per run
+// base supplies repository, work directory and CI settings shared by all runs
+func Run(exp *Experiment, t *ticket.Ticket, base worker.Config) (*Report, error) {
+	report := &Report{
+		ID:       time.Now().Format("20060102-150405"),
+		TicketID: t.ID,
+	}
+
+	repo := gitutils.NewRepo(base.RepoPath)
+	workerID := 0
+
+	for _, variant := range exp.Variants {
+		cfg := base
+		cfg.WorkDir = filepath.Join(base.WorkDir, "bench", report.ID)
+		cfg.AgentCommand = variant.AgentCommand
+		cfg.AgentArgs = variant.AgentArgs
+		cfg.Threads = nil
+
+		if variant.PromptTemplate != "" {
+			tmpl, err := template.ParseFiles(variant.PromptTemplate)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load prompt template for variant %s: %w", variant.Name, err)
+			}
+			cfg.PromptTemplate = tmpl
+		}
+
+		summary := Summary{Variant: variant.Name}
+		var totalDuration time.Duration
+		var totalFiles, totalInsertions, totalDeletions int
+
+		for run := 1; run <= exp.Runs; run++ {
+			workerID++
+			cfg.ID = workerID
+			cfg.BranchPrefix = fmt.Sprintf("bench/%s/%s-%d", report.ID, variant.Name, run)
+
+			log.Printf("Benchmark %s: running variant %s (%d/%d)", report.ID, variant.Name, run, exp.Runs)
+
+			// Each run gets its own copy since workers annotate the ticket
+			runTicket := *t
+			result := worker.New(cfg, queue.New()).Run(context.Background(), &runTicket)
+
+			outcome := Outcome{Variant: variant.Name, Run: run, Result: result}
+			if result.Implemented {
+				diff, err := repo.GetDiffStat(result.Branch)
+				if err != nil {
+					log.Printf("Benchmark %s: failed to get diff for %s: %v", report.ID, result.Branch, err)
+				} else {
+					outcome.Diff = diff
+					totalFiles += diff.FilesChanged
+					totalInsertions += diff.Insertions
+					totalDeletions += diff.Deletions
+				}
+				summary.Implemented++
+			}
+			if result.CIPassed {
+				summary.CIPassed++
+			}
+			summary.Runs++
+			totalDuration += result.Duration
+
+			report.Outcomes = append(report.Outcomes, outcome)
+		}
+
+		if summary.Implemented > 0 {
+			summary.AvgFiles = float64(totalFiles) / float64(summary.Implemented)
+			summary.AvgInsertions = float64(totalInsertions) / float64(summary.Implemented)
+			summary.AvgDeletions = float64(totalDeletions) / float64(summary.Implemented)
+		}
+		summary.AvgDuration = totalDuration / time.Duration(summary.Runs)
+
+		report.Summaries = append(report.Summaries, summary)
+	}
+
+	return report, nil
+}
+
+// Markdown renders the report as a Markdown document
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Benchmark %s\n\n", r.ID)
+	fmt.Fprintf(&b, "Ticket: `%s`\n\n", r.TicketID)
+
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| Variant | Runs | Implemented | CI pass rate | Avg files | Avg +lines | Avg -lines | Avg duration |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, s := range r.Summaries {
+		fmt.Fprintf(&b, "| %s | %d | %d | %.0f%% | %.1f | %.1f | %.1f | %s |\n",
+			s.Variant, s.Runs, s.Implemented, s.PassRate()*100,
+			s.AvgFiles, s.AvgInsertions, s.AvgDeletions, s.AvgDuration.Round(time.Second))
+	}
+
+	b.WriteString("\n## Runs\n\n")
+	b.WriteString("| Variant | Run | Branch | Result |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, o := range r.Outcomes {
+		result := "CI passed"
+		switch {
+		case o.Result.Err != nil:
+			result = strings.ReplaceAll(o.Result.Err.Error(), "\n", " ")
+		case !o.Result.CIPassed:
+			result = "CI skipped"
+		}
+		fmt.Fprintf(&b, "| %s | %d | `%s` | %s |\n", o.Variant, o.Run, o.Result.Branch, result)
+	}
+
+	return b.String()
+}
+
+// resolvePath makes a relative path relative to baseDir
+func resolvePath(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
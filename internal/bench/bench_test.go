@@ -0,0 +1,137 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestLoadExperiment(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `ticket: "ticket.yaml"
+runs: 2
+variants:
+  - name: "default"
+  - name: "terse"
+    prompt_template: "prompts/terse.tmpl"
+    agent_args: ["--model", "fast"]
+`
+	path := filepath.Join(tmpDir, "experiment.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write experiment: %v", err)
+	}
+
+	exp, err := LoadExperiment(path)
+	if err != nil {
+		t.Fatalf("LoadExperiment failed: %v", err)
+	}
+
+	if exp.Ticket != filepath.Join(tmpDir, "ticket.yaml") {
+		t.Errorf("Expected ticket path to be resolved, got %s", exp.Ticket)
+	}
+	if exp.Variants[1].PromptTemplate != filepath.Join(tmpDir, "prompts", "terse.tmpl") {
+		t.Errorf("Expected template path to be resolved, got %s", exp.Variants[1].PromptTemplate)
+	}
+	if len(exp.Variants[1].AgentArgs) != 2 {
+		t.Errorf("Expected 2 agent args, got %d", len(exp.Variants[1].AgentArgs))
+	}
+}
+
+func TestExperimentValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  Experiment
+	}{
+		{"missing ticket", Experiment{Runs: 1, Variants: []Variant{{Name: "a"}}}},
+		{"no runs", Experiment{Ticket: "t.yaml", Variants: []Variant{{Name: "a"}}}},
+		{"no variants", Experiment{Ticket: "t.yaml", Runs: 1}},
+		{"bad name", Experiment{Ticket: "t.yaml", Runs: 1, Variants: []Variant{{Name: "has space"}}}},
+		{"duplicate", Experiment{Ticket: "t.yaml", Runs: 1, Variants: []Variant{{Name: "a"}, {Name: "a"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.exp.Validate(); err == nil {
+				t.Error("Expected validation error, got nil")
+			}
+		})
+	}
+}
+
+func TestRunProducesReport(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := gitutils.NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	exp := &Experiment{
+		Ticket:   "unused.yaml",
+		Runs:     2,
+		Variants: []Variant{{Name: "a"}, {Name: "b"}},
+	}
+	testTicket := &ticket.Ticket{
+		ID:          "feat-bench",
+		Title:       "Benchmark feature",
+		Description: "A feature used to compare variants",
+		Priority:    1,
+		CreatedAt:   time.Now(),
+	}
+
+	base := worker.Config{
+		RepoPath:    repoPath,
+		WorkDir:     filepath.Join(tmpDir, "work"),
+		CIStatusDir: filepath.Join(tmpDir, "ci-status"),
+		SkipCI:      true,
+		SkipAmp:     true,
+	}
+
+	report, err := Run(exp, testTicket, base)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(report.Outcomes) != 4 {
+		t.Fatalf("Expected 4 outcomes, got %d", len(report.Outcomes))
+	}
+
+	for _, s := range report.Summaries {
+		if s.Runs != 2 || s.Implemented != 2 {
+			t.Errorf("Expected 2 implemented runs for %s, got %d/%d", s.Variant, s.Implemented, s.Runs)
+		}
+		if s.AvgFiles == 0 {
+			t.Errorf("Expected non-zero average files for %s", s.Variant)
+		}
+	}
+
+	branches, err := repo.ListBranches()
+	if err != nil {
+		t.Fatalf("Failed to list branches: %v", err)
+	}
+	benchBranches := 0
+	for _, b := range branches {
+		if strings.HasPrefix(b, "bench/"+report.ID+"/") {
+			benchBranches++
+		}
+	}
+	if benchBranches != 4 {
+		t.Errorf("Expected 4 isolated bench branches, got %d: %v", benchBranches, branches)
+	}
+
+	md := report.Markdown()
+	if !strings.Contains(md, "| a | 2 | 2 |") {
+		t.Errorf("Expected summary row for variant a, got:\n%s", md)
+	}
+}
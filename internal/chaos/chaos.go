@@ -0,0 +1,130 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+)
+
+// ErrInjected marks a failure injected by chaos mode rather than a real one
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Config sets how often each kind of failure is injected
+// Probabilities run from 0 (never) to 1 (always)
+type Config struct {
+	Enabled        bool    `mapstructure:"enabled"`
+	Seed           int64   `mapstructure:"seed"`             // Repeat a run's failures; 0 seeds from the clock
+	AgentFailure   float64 `mapstructure:"agent_failure"`    // Agent runs reported as failed after they finish
+	CIFailure      float64 `mapstructure:"ci_failure"`       // CI runs failed before they start
+	CIDelay        float64 `mapstructure:"ci_delay"`         // CI runs held back before they start
+	CIDelaySeconds int     `mapstructure:"ci_delay_seconds"` // How long a delayed CI run is held
+	IPCDisconnect  float64 `mapstructure:"ipc_disconnect"`   // Clients dropped instead of sent an event
+}
+
+// Validate checks the probabilities and delay
+func (c Config) Validate() error {
+	for name, p := range map[string]float64{
+		"agent_failure":  c.AgentFailure,
+		"ci_failure":     c.CIFailure,
+		"ci_delay":       c.CIDelay,
+		"ipc_disconnect": c.IPCDisconnect,
+	} {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if c.CIDelaySeconds < 0 {
+		return errors.New("ci_delay_seconds cannot be negative")
+	}
+	return nil
+}
+
+// Monkey decides when to inject failures
+// A nil Monkey never injects any, so callers need not check for chaos mode
+type Monkey struct {
+	config Config
+	mu     sync.Mutex
+	rng    *rand.Rand
+}
+
+// New returns a Monkey for config, or nil when chaos mode is disabled
+func New(config Config) *Monkey {
+	if !config.Enabled {
+		return nil
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Printf("Chaos mode enabled (seed %d): failures will be injected", seed)
+	return &Monkey{config: config, rng: rand.New(rand.NewSource(seed))}
+}
+
+// roll reports whether an event with probability p happens
+func (m *Monkey) roll(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rng.Float64() < p
+}
+
+// AgentFailure returns an injected error for an agent run, or nil
+func (m *Monkey) AgentFailure() error {
+	if m != nil && m.roll(m.config.AgentFailure) {
+		return fmt.Errorf("%w: agent run", ErrInjected)
+	}
+	return nil
+}
+
+// Disconnect reports whether an IPC client should be dropped
+func (m *Monkey) Disconnect() bool {
+	return m != nil && m.roll(m.config.IPCDisconnect)
+}
+
+// WrapBackend returns a CI backend that delays and fails runs before
+// handing them to backend; with a nil Monkey backend is returned unchanged
+func (m *Monkey) WrapBackend(backend ci.Backend) ci.Backend {
+	if m == nil {
+		return backend
+	}
+	return &chaosBackend{monkey: m, backend: backend}
+}
+
+// chaosBackend injects CI delays and failures
+type chaosBackend struct {
+	monkey  *Monkey
+	backend ci.Backend
+}
+
+// Run delays or fails the run at the configured rates, then runs it
+func (b *chaosBackend) Run(ctx context.Context, run ci.Run) error {
+	if b.monkey.roll(b.monkey.config.CIDelay) {
+		delay := time.Duration(b.monkey.config.CIDelaySeconds) * time.Second
+		log.Printf("Chaos: delaying CI for %s by %s", run.Branch, delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	if b.monkey.roll(b.monkey.config.CIFailure) {
+		return fmt.Errorf("%w: CI run for %s", ErrInjected, run.Branch)
+	}
+	return b.backend.Run(ctx, run)
+}
+
+// Preflight checks the wrapped backend, if it can be checked
+func (b *chaosBackend) Preflight(ctx context.Context) error {
+	if p, ok := b.backend.(ci.Preflighter); ok {
+		return p.Preflight(ctx)
+	}
+	return nil
+}
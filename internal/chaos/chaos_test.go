@@ -0,0 +1,80 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+)
+
+type countingBackend struct{ runs int }
+
+func (b *countingBackend) Run(ctx context.Context, run ci.Run) error {
+	b.runs++
+	return nil
+}
+
+func TestDisabledMonkeyInjectsNothing(t *testing.T) {
+	m := New(Config{AgentFailure: 1, IPCDisconnect: 1})
+	if m != nil {
+		t.Fatal("Expected no monkey while chaos mode is disabled")
+	}
+	if err := m.AgentFailure(); err != nil {
+		t.Errorf("Expected no agent failure, got %v", err)
+	}
+	if m.Disconnect() {
+		t.Error("Expected no disconnect")
+	}
+	backend := &countingBackend{}
+	if m.WrapBackend(backend) != ci.Backend(backend) {
+		t.Error("Expected the backend to be returned unwrapped")
+	}
+}
+
+func TestMonkeyInjectsFailures(t *testing.T) {
+	m := New(Config{Enabled: true, Seed: 1, AgentFailure: 1, CIFailure: 1, IPCDisconnect: 1})
+	if err := m.AgentFailure(); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected an injected agent failure, got %v", err)
+	}
+	if !m.Disconnect() {
+		t.Error("Expected a disconnect")
+	}
+
+	backend := &countingBackend{}
+	if err := m.WrapBackend(backend).Run(context.Background(), ci.Run{Branch: "agent-1/feat"}); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected an injected CI failure, got %v", err)
+	}
+	if backend.runs != 0 {
+		t.Errorf("Expected a failed CI run not to reach the backend, got %d runs", backend.runs)
+	}
+}
+
+func TestMonkeyDelaysCI(t *testing.T) {
+	m := New(Config{Enabled: true, CIDelay: 1, CIDelaySeconds: 60})
+	backend := &countingBackend{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.WrapBackend(backend).Run(ctx, ci.Run{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the delayed run to stop with its context, got %v", err)
+	}
+
+	m = New(Config{Enabled: true, CIDelay: 1})
+	if err := m.WrapBackend(backend).Run(context.Background(), ci.Run{}); err != nil || backend.runs != 1 {
+		t.Errorf("Expected the run to reach the backend after its delay, got %v with %d runs", err, backend.runs)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{AgentFailure: 0.5, CIDelaySeconds: 10}).Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+	if err := (Config{IPCDisconnect: -0.1}).Validate(); err == nil {
+		t.Error("Expected error for a negative probability")
+	}
+	if err := (Config{CIDelaySeconds: -1}).Validate(); err == nil {
+		t.Error("Expected error for a negative delay")
+	}
+}
@@ -0,0 +1,361 @@
+package ci
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/limits"
+	"github.com/brettsmith212/amp-orchestrator/internal/scratch"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// DefaultTestRetries is how often ci.sh retries failed test packages
+const DefaultTestRetries = 2
+
+// Backend names accepted in BackendConfig
+const (
+	BackendLocal     = "local"
+	BackendGitHub    = "github"
+	BackendBuildkite = "buildkite"
+)
+
+// Run identifies the branch tip CI runs against
+type Run struct {
+	RepoPath   string
+	Branch     string
+	Commit     string
+	TicketID   string   // Optional
+	ScratchDir string   // Optional; exported to ci.sh as ORCHESTRATOR_SCRATCH_DIR
+	Profile    *Profile // Optional; selected from the ticket's tags
+	Tier       string   // Optional; TierQuick or TierFull, local backend only
+}
+
+// Backend runs CI for a branch tip and records the result as a status file
+// Run blocks until the result is written, the timeout passes or ctx is done
+type Backend interface {
+	Run(ctx context.Context, run Run) error
+}
+
+// Preflighter is a backend that can check it is able to run before any
+// ticket needs it
+type Preflighter interface {
+	Preflight(ctx context.Context) error
+}
+
+// GitHubConfig selects the repository whose check runs are read
+type GitHubConfig struct {
+	Repo     string `mapstructure:"repo"`      // owner/name
+	TokenEnv string `mapstructure:"token_env"` // Defaults to GITHUB_TOKEN
+	APIURL   string `mapstructure:"api_url"`   // Defaults to https://api.github.com
+}
+
+// BuildkiteConfig selects the pipeline whose builds are read
+type BuildkiteConfig struct {
+	Org      string `mapstructure:"org"`
+	Pipeline string `mapstructure:"pipeline"`
+	TokenEnv string `mapstructure:"token_env"` // Defaults to BUILDKITE_API_TOKEN
+	APIURL   string `mapstructure:"api_url"`   // Defaults to https://api.buildkite.com
+}
+
+// BackendConfig holds CI backend settings
+type BackendConfig struct {
+	Backend      string         // local, github or buildkite; defaults to local
+	StatusDir    string         // Where remote results are written
+	Timeout      time.Duration  // Bounds a single run; 0 means no limit
+	TestRetries  int            // Retries of failed test packages in ci.sh; 0 disables
+	TestFlags    TestFlags      // Local backend only; go test parallelism and sharding
+	VulnScan     VulnScanConfig // Local backend only; dependency vulnerability scanners run by ci.sh
+	Env          []string       // Local backend only; extra variables for ci.sh, e.g. from GoCacheEnv
+	Matrix       []MatrixCell   // Local backend only; runs ci.sh once per cell
+	Limits       limits.Limits  // Local backend only; resource limits for ci.sh
+	Runner       command.Runner // Local backend only; runs ci.sh, defaults to command.Default
+	Remote       string         // Git remote external providers build from; defaults to origin
+	PollInterval time.Duration  // Defaults to 10 seconds
+	GitHub       GitHubConfig
+	Buildkite    BuildkiteConfig
+}
+
+// NewBackend creates the configured CI backend
+func NewBackend(config BackendConfig) (Backend, error) {
+	pollInterval := config.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 10 * time.Second
+	}
+
+	remote := config.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+
+	var p provider
+	switch config.Backend {
+	case "", BackendLocal:
+		return NewLocalBackend(config), nil
+	case BackendGitHub:
+		github, err := newGitHubProvider(config.GitHub)
+		if err != nil {
+			return nil, err
+		}
+		p = github
+	case BackendBuildkite:
+		buildkite, err := newBuildkiteProvider(config.Buildkite)
+		if err != nil {
+			return nil, err
+		}
+		p = buildkite
+	default:
+		return nil, fmt.Errorf("unknown CI backend %q", config.Backend)
+	}
+
+	if len(config.Matrix) > 0 {
+		return nil, fmt.Errorf("the CI matrix is only supported by the local backend")
+	}
+
+	return &remoteBackend{
+		provider:     p,
+		statusDir:    config.StatusDir,
+		timeout:      config.Timeout,
+		remote:       remote,
+		pollInterval: pollInterval,
+	}, nil
+}
+
+// LocalBackend runs ci.sh, which writes the status file itself
+type LocalBackend struct {
+	timeout     time.Duration
+	testRetries int
+	matrix      []MatrixCell
+	statusDir   string
+	limits      limits.Limits
+	testFlags   TestFlags
+	vulnScan    VulnScanConfig
+	env         []string
+	runner      command.Runner
+}
+
+// NewLocalBackend creates a backend that runs ci.sh
+// Only Timeout, TestRetries, TestFlags, VulnScan, Env, Matrix, Limits, Runner and StatusDir are used from config
+func NewLocalBackend(config BackendConfig) *LocalBackend {
+	statusDir := config.StatusDir
+	if statusDir == "" {
+		// Where ci.sh writes when run from the current directory
+		statusDir = "ci-status"
+	}
+
+	return &LocalBackend{
+		timeout:     config.Timeout,
+		testRetries: config.TestRetries,
+		matrix:      config.Matrix,
+		statusDir:   statusDir,
+		limits:      config.Limits,
+		testFlags:   config.TestFlags,
+		vulnScan:    config.VulnScan,
+		env:         config.Env,
+		runner:      command.Or(config.Runner),
+	}
+}
+
+// Run executes ci.sh <repo_path> <ref_name> <commit_hash> [ticket_id], once
+// per matrix cell when a matrix is configured
+func (b *LocalBackend) Run(ctx context.Context, run Run) error {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	defer clearProgress(b.progressDir(run), run.Commit)
+
+	if len(b.matrix) > 0 {
+		return b.runMatrix(ctx, run)
+	}
+
+	output, err := b.runScript(ctx, run, nil)
+	if err != nil {
+		if limitErr := b.limits.Check(ctx, err, output); limitErr != nil {
+			return fmt.Errorf("CI script failed: %w", limitErr)
+		}
+		return fmt.Errorf("CI script failed: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// runScript runs ci.sh with extra environment variables and returns its output
+func (b *LocalBackend) runScript(ctx context.Context, run Run, env []string) ([]byte, error) {
+	// Get absolute path to repository
+	absRepoPath, err := filepath.Abs(run.RepoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute repo path: %w", err)
+	}
+
+	scriptPath, err := scriptPath()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{absRepoPath, "refs/heads/" + run.Branch, run.Commit}
+	if run.TicketID != "" {
+		args = append(args, run.TicketID)
+	}
+
+	cmd := command.Context(ctx, scriptPath, args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("CI_TEST_RETRIES=%d", b.testRetries))
+	cmd.Env = append(cmd.Env, b.env...)
+	if run.ScratchDir != "" {
+		cmd.Env = append(cmd.Env, scratch.EnvVar+"="+run.ScratchDir)
+	}
+	cmd.Env = append(cmd.Env, b.testFlags.env()...)
+	cmd.Env = append(cmd.Env, b.vulnScan.env()...)
+	cmd.Env = append(cmd.Env, run.Profile.env()...)
+	cmd.Env = append(cmd.Env, tierEnv(run.Tier)...)
+	progress, err := progressEnv(b.progressDir(run), run.Commit)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = append(cmd.Env, progress...)
+	if run.Tier == TierFull {
+		// Kept apart from the quick tier's status for the same commit; ci.sh
+		// writes it from inside its clone, so the path must be absolute
+		statusDir, err := filepath.Abs(FullStatusDir(b.statusDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute CI status path: %w", err)
+		}
+		if err := os.MkdirAll(statusDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create CI status directory: %w", err)
+		}
+		cmd.Env = append(cmd.Env, "CI_STATUS_FILE="+filepath.Join(statusDir, run.Commit+".json"))
+	}
+	cmd.Env = append(cmd.Env, env...)
+	b.limits.Apply(cmd)
+	return b.runner.CombinedOutput(cmd)
+}
+
+// progressDir is where ci.sh records the step it is executing for a run
+func (b *LocalBackend) progressDir(run Run) string {
+	if run.Tier == TierFull {
+		return FullStatusDir(b.statusDir)
+	}
+	return b.statusDir
+}
+
+// Preflight checks that ci.sh can be found and parses, without running CI
+func (b *LocalBackend) Preflight(ctx context.Context) error {
+	path, err := scriptPath()
+	if err != nil {
+		return err
+	}
+	if path, err = exec.LookPath(path); err != nil {
+		return fmt.Errorf("CI script is not runnable: %w", err)
+	}
+
+	output, err := b.runner.CombinedOutput(command.Context(ctx, "bash", "-n", path))
+	if err != nil {
+		return fmt.Errorf("CI script %s does not parse: %w\n%s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// provider reads CI results from an external service
+type provider interface {
+	// check returns the status for a commit once every job has finished,
+	// or nil while CI is still pending
+	check(ctx context.Context, branch, commit string) (*Status, error)
+}
+
+// maxProviderBackoff caps the wait between retries of a failing provider
+const maxProviderBackoff = 5 * time.Minute
+
+// remoteBackend pushes the branch so an external service builds it, then
+// polls the service and records its verdict in the status directory
+type remoteBackend struct {
+	provider     provider
+	statusDir    string
+	timeout      time.Duration
+	remote       string
+	pollInterval time.Duration
+}
+
+// Run pushes the branch and waits for the provider's result
+func (b *remoteBackend) Run(ctx context.Context, run Run) error {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	defer clearProgress(b.statusDir, run.Commit)
+
+	b.progress(run, "pushing "+run.Branch)
+	if err := gitutils.NewRepo(run.RepoPath).WithContext(ctx).PushBranch(b.remote, run.Branch); err != nil {
+		return fmt.Errorf("failed to push %s for CI: %w", run.Branch, err)
+	}
+	b.progress(run, "waiting for the CI provider")
+
+	backoff := b.pollInterval
+	for {
+		wait := b.pollInterval
+		status, err := b.provider.check(ctx, run.Branch, run.Commit)
+		switch {
+		case err != nil && (!transient(err) || ctx.Err() != nil):
+			return err
+		case err != nil:
+			// An outage or throttling shouldn't fail the ticket; back off
+			// and ask again until the timeout
+			log.Printf("CI provider check for %s failed, retrying in %s: %v", run.Branch, backoff, err)
+			wait = backoff
+			backoff = min(backoff*2, maxProviderBackoff)
+		case status != nil:
+			status.Ref = "refs/heads/" + run.Branch
+			status.Commit = run.Commit
+			status.TicketID = run.TicketID
+			status.Profile = run.Profile.name()
+			return WriteStatus(b.statusDir, status)
+		default:
+			backoff = b.pollInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("CI for %s did not finish: %w", run.Branch, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// progress records the step of a remote run; failing to is only logged
+func (b *remoteBackend) progress(run Run, step string) {
+	if err := WriteProgress(b.statusDir, run.Commit, step); err != nil {
+		log.Printf("Failed to record CI progress for %s: %v", run.Branch, err)
+	}
+}
+
+// WriteStatus records a status in the status directory as <commit>.json
+// The file is written under a temporary name and renamed so readers never
+// see a partial file
+func WriteStatus(statusDir string, status *Status) error {
+	return (&FileStatusStore{Dir: statusDir}).Put(status)
+}
+
+// withTimeout bounds ctx by timeout when it is positive
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// scriptPath locates ci.sh in the project root (parent of bin/), falling back
+// to the current working directory
+func scriptPath() (string, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine executable path: %w", err)
+	}
+
+	path := filepath.Join(filepath.Dir(filepath.Dir(execPath)), "ci.sh")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		path = "ci.sh"
+	}
+
+	return path, nil
+}
@@ -0,0 +1,281 @@
+package ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestNewBackend(t *testing.T) {
+	if backend, err := NewBackend(BackendConfig{}); err != nil {
+		t.Fatalf("Expected local backend by default, got error: %v", err)
+	} else if _, ok := backend.(*LocalBackend); !ok {
+		t.Errorf("Expected *LocalBackend, got %T", backend)
+	}
+
+	if _, err := NewBackend(BackendConfig{Backend: "jenkins"}); err == nil {
+		t.Error("Expected error for unknown backend, got nil")
+	}
+
+	t.Setenv("TEST_GITHUB_TOKEN", "")
+	config := BackendConfig{Backend: BackendGitHub, GitHub: GitHubConfig{Repo: "owner/name", TokenEnv: "TEST_GITHUB_TOKEN"}}
+	if _, err := NewBackend(config); err == nil {
+		t.Error("Expected error when the GitHub token is missing, got nil")
+	}
+
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+	if _, err := NewBackend(config); err != nil {
+		t.Errorf("Expected GitHub backend, got error: %v", err)
+	}
+}
+
+func TestGitHubProvider_Check(t *testing.T) {
+	var response atomic.Value
+	response.Store(`{"total_count": 0, "check_runs": []}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/name/commits/abc123/check-runs" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", got)
+		}
+		w.Write([]byte(response.Load().(string)))
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+	p, err := newGitHubProvider(GitHubConfig{Repo: "owner/name", TokenEnv: "TEST_GITHUB_TOKEN", APIURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// No check runs yet
+	if status, err := p.check(ctx, "agent-1/feat", "abc123"); err != nil || status != nil {
+		t.Fatalf("Expected pending result, got %v (err %v)", status, err)
+	}
+
+	// One run still in progress
+	response.Store(`{"total_count": 2, "check_runs": [
+		{"name": "test", "status": "completed", "conclusion": "success"},
+		{"name": "lint", "status": "in_progress"}]}`)
+	if status, err := p.check(ctx, "agent-1/feat", "abc123"); err != nil || status != nil {
+		t.Fatalf("Expected pending result, got %v (err %v)", status, err)
+	}
+
+	response.Store(`{"total_count": 2, "check_runs": [
+		{"name": "test", "status": "completed", "conclusion": "success"},
+		{"name": "lint", "status": "completed", "conclusion": "skipped"}]}`)
+	status, err := p.check(ctx, "agent-1/feat", "abc123")
+	if err != nil || status == nil || status.Status != "PASS" {
+		t.Fatalf("Expected PASS, got %v (err %v)", status, err)
+	}
+
+	response.Store(`{"total_count": 2, "check_runs": [
+		{"name": "test", "status": "completed", "conclusion": "failure", "html_url": "https://example.com/run/1"},
+		{"name": "lint", "status": "completed", "conclusion": "success"}]}`)
+	status, err = p.check(ctx, "agent-1/feat", "abc123")
+	if err != nil || status == nil || status.Status != "FAIL" {
+		t.Fatalf("Expected FAIL, got %v (err %v)", status, err)
+	}
+	if !strings.Contains(status.Output, "test: failure https://example.com/run/1") {
+		t.Errorf("Expected failing run in output, got %q", status.Output)
+	}
+}
+
+func TestGitHubProvider_CheckFollowsPages(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "2" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?per_page=100&page=2>; rel="next", <%s%s?per_page=100&page=2>; rel="last"`,
+				server.URL, r.URL.Path, server.URL, r.URL.Path))
+			w.Write([]byte(`{"total_count": 2, "check_runs": [{"name": "test", "status": "completed", "conclusion": "success"}]}`))
+			return
+		}
+		w.Write([]byte(`{"total_count": 2, "check_runs": [{"name": "lint", "status": "completed", "conclusion": "failure"}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+	p, err := newGitHubProvider(GitHubConfig{Repo: "owner/name", TokenEnv: "TEST_GITHUB_TOKEN", APIURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	status, err := p.check(context.Background(), "agent-1/feat", "abc123")
+	if err != nil || status == nil || status.Status != "FAIL" {
+		t.Fatalf("Expected the failing run on the second page to fail the commit, got %v (err %v)", status, err)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&statusError{code: http.StatusBadGateway, message: "502 Bad Gateway"}, true},
+		{&statusError{code: http.StatusTooManyRequests, message: "429 Too Many Requests"}, true},
+		{&statusError{code: http.StatusForbidden, message: "403 Forbidden: You have exceeded a secondary rate limit"}, true},
+		{&statusError{code: http.StatusForbidden, message: "403 Forbidden: Resource not accessible"}, false},
+		{&statusError{code: http.StatusNotFound, message: "404 Not Found"}, false},
+		{fmt.Errorf("failed to read GitHub check runs: %w", &url.Error{Op: "Get", URL: "https://api.github.com", Err: io.ErrUnexpectedEOF}), true},
+		{errors.New("failed to parse response"), false},
+	}
+	for _, tt := range tests {
+		if got := transient(tt.err); got != tt.want {
+			t.Errorf("transient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBuildkiteProvider_Check(t *testing.T) {
+	var response atomic.Value
+	response.Store(`[{"number": 7, "state": "running"}]`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/organizations/acme/pipelines/app/builds" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("commit") != "abc123" || r.URL.Query().Get("branch") != "agent-1/feat" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(response.Load().(string)))
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_BUILDKITE_TOKEN", "secret")
+	p, err := newBuildkiteProvider(BuildkiteConfig{Org: "acme", Pipeline: "app", TokenEnv: "TEST_BUILDKITE_TOKEN", APIURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if status, err := p.check(ctx, "agent-1/feat", "abc123"); err != nil || status != nil {
+		t.Fatalf("Expected pending result, got %v (err %v)", status, err)
+	}
+
+	response.Store(`[{"number": 8, "state": "failed", "web_url": "https://buildkite.com/acme/app/builds/8"}]`)
+	status, err := p.check(ctx, "agent-1/feat", "abc123")
+	if err != nil || status == nil || status.Status != "FAIL" {
+		t.Fatalf("Expected FAIL, got %v (err %v)", status, err)
+	}
+
+	response.Store(`[{"number": 9, "state": "passed"}]`)
+	status, err = p.check(ctx, "agent-1/feat", "abc123")
+	if err != nil || status == nil || status.Status != "PASS" {
+		t.Fatalf("Expected PASS, got %v (err %v)", status, err)
+	}
+}
+
+func TestRemoteBackend_Run(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A bare repo with an agent branch, and an empty remote to push to
+	repoPath := filepath.Join(tmpDir, "repo.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init repo: %v", err)
+	}
+	repo := gitutils.NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	if err := exec.Command("git", "--git-dir", repoPath, "branch", "agent-1/feat", "HEAD").Run(); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	commit, err := repo.GetBranchCommit("agent-1/feat")
+	if err != nil {
+		t.Fatalf("Failed to get commit: %v", err)
+	}
+
+	remotePath := filepath.Join(tmpDir, "remote.git")
+	if err := gitutils.InitBareRepo(remotePath); err != nil {
+		t.Fatalf("Failed to init remote: %v", err)
+	}
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Write([]byte(`{"total_count": 1, "check_runs": [{"name": "test", "status": "queued"}]}`))
+			return
+		case 2:
+			// An outage is retried rather than failing the run
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"total_count": 1, "check_runs": [{"name": "test", "status": "completed", "conclusion": "success"}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+	statusDir := filepath.Join(tmpDir, "ci-status")
+	backend, err := NewBackend(BackendConfig{
+		Backend:      BackendGitHub,
+		StatusDir:    statusDir,
+		Timeout:      5 * time.Second,
+		Remote:       remotePath,
+		PollInterval: 10 * time.Millisecond,
+		GitHub:       GitHubConfig{Repo: "owner/name", TokenEnv: "TEST_GITHUB_TOKEN", APIURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	run := Run{RepoPath: repoPath, Branch: "agent-1/feat", Commit: commit, TicketID: "feat"}
+	if err := backend.Run(context.Background(), run); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if pushed, err := gitutils.NewRepo(remotePath).GetBranchCommit("agent-1/feat"); err != nil || pushed != commit {
+		t.Errorf("Expected branch pushed at %s, got %s (err %v)", commit, pushed, err)
+	}
+
+	status, err := NewStatusReader(statusDir).GetLatestForBranch("agent-1/feat")
+	if err != nil {
+		t.Fatalf("Expected status to be written: %v", err)
+	}
+	if status.Status != "PASS" || status.Commit != commit || status.TicketID != "feat" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestLocalBackend_Preflight(t *testing.T) {
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	backend := NewLocalBackend(BackendConfig{})
+
+	if err := backend.Preflight(context.Background()); err == nil {
+		t.Error("Expected a missing ci.sh to fail")
+	}
+
+	script := filepath.Join(binDir, "ci.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/bash\nif true; then\n"), 0755); err != nil {
+		t.Fatalf("Failed to write ci.sh: %v", err)
+	}
+	if err := backend.Preflight(context.Background()); err == nil {
+		t.Error("Expected a ci.sh with a syntax error to fail")
+	}
+
+	if err := os.WriteFile(script, []byte("#!/bin/bash\necho ok\n"), 0755); err != nil {
+		t.Fatalf("Failed to write ci.sh: %v", err)
+	}
+	if err := backend.Preflight(context.Background()); err != nil {
+		t.Errorf("Expected a valid ci.sh to pass, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package ci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// buildkiteProvider reads builds for a commit from the Buildkite REST API
+type buildkiteProvider struct {
+	apiURL   string
+	org      string
+	pipeline string
+	token    string
+	client   *http.Client
+}
+
+// buildkiteBuild is the subset of a build that is used
+type buildkiteBuild struct {
+	Number int    `json:"number"`
+	State  string `json:"state"`
+	WebURL string `json:"web_url"`
+}
+
+// buildkiteFinished lists the build states that won't change any more
+var buildkiteFinished = map[string]bool{
+	"passed":   true,
+	"failed":   true,
+	"canceled": true,
+	"skipped":  true,
+	"not_run":  true,
+}
+
+// newBuildkiteProvider validates the config and reads the API token
+func newBuildkiteProvider(config BuildkiteConfig) (*buildkiteProvider, error) {
+	if config.Org == "" || config.Pipeline == "" {
+		return nil, fmt.Errorf("ci.buildkite.org and ci.buildkite.pipeline must be set")
+	}
+
+	tokenEnv := config.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "BUILDKITE_API_TOKEN"
+	}
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("Buildkite CI backend needs a token in $%s", tokenEnv)
+	}
+
+	apiURL := config.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.buildkite.com"
+	}
+
+	return &buildkiteProvider{
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		org:      config.Org,
+		pipeline: config.Pipeline,
+		token:    token,
+		client:   &http.Client{},
+	}, nil
+}
+
+// check reports the newest build of the commit once it has finished
+func (p *buildkiteProvider) check(ctx context.Context, branch, commit string) (*Status, error) {
+	query := url.Values{"commit": {commit}, "branch": {branch}}
+	endpoint := fmt.Sprintf("%s/v2/organizations/%s/pipelines/%s/builds?%s",
+		p.apiURL, url.PathEscape(p.org), url.PathEscape(p.pipeline), query.Encode())
+
+	var builds []buildkiteBuild
+	err := getJSON(ctx, p.client, endpoint, map[string]string{
+		"Authorization": "Bearer " + p.token,
+	}, &builds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Buildkite builds: %w", err)
+	}
+
+	// Builds are returned newest first; none yet means the webhook hasn't fired
+	if len(builds) == 0 || !buildkiteFinished[builds[0].State] {
+		return nil, nil
+	}
+
+	build := builds[0]
+	status := &Status{
+		Status: "FAIL",
+		Output: fmt.Sprintf("Build #%d %s %s", build.Number, build.State, build.WebURL),
+	}
+	if build.State == "passed" {
+		status.Status = "PASS"
+	}
+
+	return status, nil
+}
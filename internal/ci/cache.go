@@ -0,0 +1,32 @@
+package ci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// GoCacheEnv returns GOMODCACHE and GOCACHE settings pointing at persistent
+// directories under dir, creating them if needed. Fresh CI clones and agent
+// worktrees then reuse downloaded modules and build results.
+// An empty dir returns nil, leaving go's defaults in place.
+func GoCacheEnv(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute Go cache path: %w", err)
+	}
+
+	modCache := filepath.Join(absDir, "mod")
+	buildCache := filepath.Join(absDir, "build")
+	for _, d := range []string{modCache, buildCache} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create Go cache directory: %w", err)
+		}
+	}
+
+	return []string{"GOMODCACHE=" + modCache, "GOCACHE=" + buildCache}, nil
+}
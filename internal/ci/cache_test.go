@@ -0,0 +1,30 @@
+package ci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoCacheEnv(t *testing.T) {
+	if env, err := GoCacheEnv(""); err != nil || env != nil {
+		t.Errorf("Expected nil env for empty dir, got %v (err %v)", env, err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "go-cache")
+	env, err := GoCacheEnv(dir)
+	if err != nil {
+		t.Fatalf("GoCacheEnv failed: %v", err)
+	}
+
+	want := []string{"GOMODCACHE=" + filepath.Join(dir, "mod"), "GOCACHE=" + filepath.Join(dir, "build")}
+	if len(env) != 2 || env[0] != want[0] || env[1] != want[1] {
+		t.Errorf("GoCacheEnv() = %v, want %v", env, want)
+	}
+
+	for _, sub := range []string{"mod", "build"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			t.Errorf("Expected %s cache directory to be created", sub)
+		}
+	}
+}
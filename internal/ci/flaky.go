@@ -0,0 +1,141 @@
+package ci
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// FlakyMetricsFile is the CSV in the metrics directory that records every
+// test package that passed only on retry
+const FlakyMetricsFile = "flaky_tests.csv"
+
+// flakyHeader is the first row of FlakyMetricsFile
+var flakyHeader = []string{"timestamp", "commit", "ticket_id", "package"}
+
+// FlakyStat summarises how often a test package has been flaky
+type FlakyStat struct {
+	Package  string
+	Count    int
+	LastSeen time.Time
+	Tickets  []string // Tickets whose CI hit the flake, in first-seen order
+}
+
+// RecordFlaky appends the status's flaky packages to the metrics CSV
+func RecordFlaky(metricsDir string, status *Status) error {
+	if len(status.Flaky) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(metricsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+
+	path := filepath.Join(metricsDir, FlakyMetricsFile)
+	_, statErr := os.Stat(path)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open flaky test metrics: %w", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if os.IsNotExist(statErr) {
+		w.Write(flakyHeader)
+	}
+	timestamp := timefmt.Machine(status.Timestamp)
+	for _, pkg := range status.Flaky {
+		w.Write([]string{timestamp, status.Commit, status.TicketID, pkg})
+	}
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write flaky test metrics: %w", err)
+	}
+	return nil
+}
+
+// LoadFlakyStats aggregates the metrics CSV per package, most flaky first
+// A missing file means nothing has been flaky yet
+func LoadFlakyStats(metricsDir string) ([]FlakyStat, error) {
+	return LoadFlakyStatsBetween(metricsDir, time.Time{}, time.Time{})
+}
+
+// LoadFlakyStatsBetween aggregates the flakes recorded at or after from and
+// before to, like LoadFlakyStats; a zero to has no end
+func LoadFlakyStatsBetween(metricsDir string, from, to time.Time) ([]FlakyStat, error) {
+	file, err := os.Open(filepath.Join(metricsDir, FlakyMetricsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open flaky test metrics: %w", err)
+	}
+	defer file.Close()
+
+	stats := make(map[string]*FlakyStat)
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = len(flakyHeader)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read flaky test metrics: %w", err)
+		}
+		if record[0] == flakyHeader[0] {
+			continue
+		}
+
+		seen, err := time.Parse(time.RFC3339, record[0])
+		if (!from.IsZero() || !to.IsZero()) && (err != nil || seen.Before(from) || (!to.IsZero() && !seen.Before(to))) {
+			continue
+		}
+
+		pkg := record[3]
+		stat, ok := stats[pkg]
+		if !ok {
+			stat = &FlakyStat{Package: pkg}
+			stats[pkg] = stat
+		}
+		stat.Count++
+
+		if err == nil && seen.After(stat.LastSeen) {
+			stat.LastSeen = seen
+		}
+		if ticketID := record[2]; ticketID != "" && !contains(stat.Tickets, ticketID) {
+			stat.Tickets = append(stat.Tickets, ticketID)
+		}
+	}
+
+	result := make([]FlakyStat, 0, len(stats))
+	for _, stat := range stats {
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Package < result[j].Package
+	})
+
+	return result, nil
+}
+
+// contains reports whether s is in list
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package ci
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlakyMetrics(t *testing.T) {
+	metricsDir := t.TempDir()
+
+	// Nothing recorded yet
+	stats, err := LoadFlakyStats(metricsDir)
+	if err != nil || len(stats) != 0 {
+		t.Fatalf("Expected no stats, got %v (err %v)", stats, err)
+	}
+
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	records := []*Status{
+		{Commit: "aaa", TicketID: "feat-1", Status: "FLAKY", Timestamp: first, Flaky: []string{"example.com/a", "example.com/b"}},
+		{Commit: "bbb", TicketID: "feat-2", Status: "FLAKY", Timestamp: second, Flaky: []string{"example.com/a"}},
+		{Commit: "ccc", TicketID: "feat-3", Status: "PASS", Timestamp: second},
+	}
+	for _, status := range records {
+		if err := RecordFlaky(metricsDir, status); err != nil {
+			t.Fatalf("RecordFlaky failed: %v", err)
+		}
+	}
+
+	stats, err = LoadFlakyStats(metricsDir)
+	if err != nil {
+		t.Fatalf("LoadFlakyStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 flaky packages, got %d: %v", len(stats), stats)
+	}
+
+	top := stats[0]
+	if top.Package != "example.com/a" || top.Count != 2 {
+		t.Errorf("Expected example.com/a flaky twice first, got %+v", top)
+	}
+	if !top.LastSeen.Equal(second) {
+		t.Errorf("Expected last seen %v, got %v", second, top.LastSeen)
+	}
+	if len(top.Tickets) != 2 || top.Tickets[0] != "feat-1" || top.Tickets[1] != "feat-2" {
+		t.Errorf("Expected tickets [feat-1 feat-2], got %v", top.Tickets)
+	}
+
+	if stats[1].Package != "example.com/b" || stats[1].Count != 1 {
+		t.Errorf("Expected example.com/b flaky once, got %+v", stats[1])
+	}
+
+	// Only the flakes of the second hour
+	stats, err = LoadFlakyStatsBetween(metricsDir, second, second.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("LoadFlakyStatsBetween failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Count != 1 || stats[0].Tickets[0] != "feat-2" {
+		t.Errorf("Expected example.com/a flaky once for feat-2, got %+v", stats)
+	}
+}
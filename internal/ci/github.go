@@ -0,0 +1,181 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// githubProvider reads check runs for a commit from the GitHub Checks API
+type githubProvider struct {
+	apiURL string
+	repo   string
+	token  string
+	client *http.Client
+}
+
+// checkRuns is the subset of the check-runs response that is used
+type checkRuns struct {
+	TotalCount int `json:"total_count"`
+	CheckRuns  []struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		HTMLURL    string `json:"html_url"`
+	} `json:"check_runs"`
+}
+
+// newGitHubProvider validates the config and reads the API token
+func newGitHubProvider(config GitHubConfig) (*githubProvider, error) {
+	if config.Repo == "" || !strings.Contains(config.Repo, "/") {
+		return nil, fmt.Errorf("ci.github.repo must be owner/name, got %q", config.Repo)
+	}
+
+	tokenEnv := config.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "GITHUB_TOKEN"
+	}
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("GitHub CI backend needs a token in $%s", tokenEnv)
+	}
+
+	apiURL := config.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+
+	return &githubProvider{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		repo:   config.Repo,
+		token:  token,
+		client: &http.Client{},
+	}, nil
+}
+
+// check passes once every check run has completed successfully and fails as
+// soon as all have completed with any other conclusion
+func (p *githubProvider) check(ctx context.Context, branch, commit string) (*Status, error) {
+	next := fmt.Sprintf("%s/repos/%s/commits/%s/check-runs?per_page=100", p.apiURL, p.repo, commit)
+
+	// Follow the Link header, so commits with more runs than fit on a page
+	// are judged on all of them
+	var runs checkRuns
+	for next != "" {
+		var page checkRuns
+		var err error
+		next, err = getJSONPage(ctx, p.client, next, map[string]string{
+			"Accept":        "application/vnd.github+json",
+			"Authorization": "Bearer " + p.token,
+		}, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GitHub check runs: %w", err)
+		}
+		runs.TotalCount = page.TotalCount
+		runs.CheckRuns = append(runs.CheckRuns, page.CheckRuns...)
+	}
+
+	// Checks may not have been created yet right after the push
+	if runs.TotalCount == 0 || len(runs.CheckRuns) == 0 {
+		return nil, nil
+	}
+
+	status := &Status{Status: "PASS"}
+	var lines []string
+	for _, run := range runs.CheckRuns {
+		if run.Status != "completed" {
+			return nil, nil
+		}
+		switch run.Conclusion {
+		case "success", "neutral", "skipped":
+		default:
+			status.Status = "FAIL"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s %s", run.Name, run.Conclusion, run.HTMLURL))
+	}
+	status.Output = strings.Join(lines, "\n")
+
+	return status, nil
+}
+
+// statusError is a response outside the 2xx range
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// transient reports whether a provider error may clear up by itself: the
+// request never got an answer, or the service was throttling or failing
+func transient(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		if statusErr.code == http.StatusForbidden {
+			// GitHub's secondary rate limit answers 403
+			return strings.Contains(strings.ToLower(statusErr.message), "rate limit")
+		}
+		return statusErr.code == http.StatusTooManyRequests || statusErr.code >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// getJSON performs a GET request and decodes a successful JSON response
+func getJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, v interface{}) error {
+	_, err := getJSONPage(ctx, client, url, headers, v)
+	return err
+}
+
+// getJSONPage is getJSON for paginated responses, also returning the URL of
+// the next page from the Link header, or "" on the last one
+func getJSONPage(ctx context.Context, client *http.Client, url string, headers map[string]string, v interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4*1024*1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", &statusError{code: resp.StatusCode, message: resp.Status + ": " + strings.TrimSpace(string(body))}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nextLink(resp.Header.Get("Link")), nil
+}
+
+// nextLink returns the rel="next" target of a Link header
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		params := strings.Split(link, ";")
+		for _, param := range params[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(params[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
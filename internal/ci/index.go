@@ -0,0 +1,205 @@
+package ci
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// indexEntry is a parsed status and the stored state it was parsed from
+type indexEntry struct {
+	status  *Status
+	modTime time.Time
+	size    int64
+}
+
+// index caches parsed statuses so lookups by branch, ticket and time don't
+// re-read every status. It is refreshed incrementally from the store's
+// listing: only new or changed statuses are read.
+type index struct {
+	entries  map[string]*indexEntry // Keyed by commit
+	byTime   []*Status              // All statuses, oldest first
+	byBranch map[string][]*Status   // Oldest first per branch
+	byTicket map[string][]*Status   // Oldest first per ticket ID
+	dirty    bool
+	mu       sync.Mutex
+}
+
+// refresh brings the index up to date with the store
+// Statuses that fail to parse are skipped; they may still be being written
+func (idx *index) refresh(store StatusStore) error {
+	listed, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	if idx.entries == nil {
+		idx.entries = make(map[string]*indexEntry)
+	}
+
+	seen := make(map[string]bool, len(listed))
+	for _, e := range listed {
+		seen[e.Commit] = true
+
+		if cached, ok := idx.entries[e.Commit]; ok && cached.modTime.Equal(e.ModTime) && cached.size == e.Size {
+			continue
+		}
+
+		status, err := store.Get(e.Commit)
+		if errors.Is(err, ErrStatusNotFound) {
+			continue
+		}
+		idx.dirty = true
+		if err != nil {
+			delete(idx.entries, e.Commit)
+			continue
+		}
+		if status.Commit == "" {
+			status.Commit = e.Commit
+		}
+		if status.Timestamp.IsZero() {
+			status.Timestamp = e.ModTime
+		}
+		if status.TicketID == "" {
+			// Statuses written before ticket IDs were recorded
+			status.TicketID = ticketFromBranch(status.Branch())
+		}
+
+		idx.entries[e.Commit] = &indexEntry{status: status, modTime: e.ModTime, size: e.Size}
+	}
+
+	for commit := range idx.entries {
+		if !seen[commit] {
+			delete(idx.entries, commit)
+			idx.dirty = true
+		}
+	}
+
+	if idx.dirty || idx.byBranch == nil {
+		idx.rebuild()
+	}
+
+	return nil
+}
+
+// rebuild regenerates the lookup tables from the parsed entries
+// Pending statuses stay cached but are left out; they aren't results yet
+func (idx *index) rebuild() {
+	idx.byTime = idx.byTime[:0]
+	for _, entry := range idx.entries {
+		if !entry.status.Pending() {
+			idx.byTime = append(idx.byTime, entry.status)
+		}
+	}
+	sort.Slice(idx.byTime, func(i, j int) bool {
+		a, b := idx.byTime[i], idx.byTime[j]
+		if a.Timestamp.Equal(b.Timestamp) {
+			return a.Commit < b.Commit
+		}
+		return a.Timestamp.Before(b.Timestamp)
+	})
+
+	idx.byBranch = make(map[string][]*Status)
+	idx.byTicket = make(map[string][]*Status)
+	for _, s := range idx.byTime {
+		idx.byBranch[s.Branch()] = append(idx.byBranch[s.Branch()], s)
+		if s.TicketID != "" {
+			idx.byTicket[s.TicketID] = append(idx.byTicket[s.TicketID], s)
+		}
+	}
+
+	idx.dirty = false
+}
+
+// lookup refreshes the index and runs fn against it
+// Returned slices are copies, safe to keep after the lock is released
+func (sr *StatusReader) lookup(fn func(idx *index) []*Status) ([]*Status, error) {
+	sr.index.mu.Lock()
+	defer sr.index.mu.Unlock()
+
+	if err := sr.index.refresh(sr.store); err != nil {
+		return nil, err
+	}
+	return append([]*Status(nil), fn(sr.index)...), nil
+}
+
+// Branch returns the branch name of the status ref, without refs/heads/
+func (s *Status) Branch() string {
+	return strings.TrimPrefix(s.Ref, "refs/heads/")
+}
+
+// ticketFromBranch returns the ticket ID of an agent-X/<ticket-id> branch, or ""
+func ticketFromBranch(branch string) string {
+	if !strings.HasPrefix(branch, "agent-") {
+		return ""
+	}
+	if i := strings.Index(branch, "/"); i >= 0 {
+		return branch[i+1:]
+	}
+	return ""
+}
+
+// GetLatestForBranch returns the most recent status for a branch
+// The branch may be given with or without the refs/heads/ prefix
+func (sr *StatusReader) GetLatestForBranch(branch string) (*Status, error) {
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	statuses, err := sr.lookup(func(idx *index) []*Status { return idx.byBranch[branch] })
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("CI status not found for branch %s", branch)
+	}
+	return statuses[len(statuses)-1], nil
+}
+
+// GetByTicket returns a ticket's CI attempts in chronological order
+// Retries on other agent branches are included
+func (sr *StatusReader) GetByTicket(ticketID string) ([]*Status, error) {
+	return sr.lookup(func(idx *index) []*Status { return idx.byTicket[ticketID] })
+}
+
+// ListSince returns the statuses recorded at or after since, oldest first
+func (sr *StatusReader) ListSince(since time.Time) ([]*Status, error) {
+	return sr.lookup(func(idx *index) []*Status {
+		i := sort.Search(len(idx.byTime), func(i int) bool { return !idx.byTime[i].Timestamp.Before(since) })
+		return idx.byTime[i:]
+	})
+}
+
+// Prune deletes statuses older than maxAge, always keeping the latest status
+// of each branch so in-flight lookups still resolve. It returns the number
+// of statuses removed.
+func (sr *StatusReader) Prune(maxAge time.Duration) (int, error) {
+	sr.index.mu.Lock()
+	defer sr.index.mu.Unlock()
+
+	if err := sr.index.refresh(sr.store); err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for commit, entry := range sr.index.entries {
+		s := entry.status
+		branchStatuses := sr.index.byBranch[s.Branch()]
+		if !s.Timestamp.Before(cutoff) || branchStatuses[len(branchStatuses)-1] == s {
+			continue
+		}
+		if err := sr.store.Delete(commit); err != nil {
+			return removed, err
+		}
+		delete(sr.index.entries, commit)
+		removed++
+	}
+
+	if removed > 0 {
+		// Rebuild after the loop so each branch's latest status stays protected
+		sr.index.rebuild()
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,148 @@
+package ci
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeStatus writes a status file named after its commit
+func writeStatus(t *testing.T, dir string, status Status) {
+	t.Helper()
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("Failed to marshal status: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, status.Commit+".json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write status file: %v", err)
+	}
+}
+
+func TestStatusReader_GetLatestForBranch(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Now().UTC()
+
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-1/feat-1", Commit: "aaa", Status: "FAIL", Timestamp: now.Add(-2 * time.Minute)})
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-1/feat-1", Commit: "bbb", Status: "PASS", Timestamp: now.Add(-time.Minute)})
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-2/feat-2", Commit: "ccc", Status: "PASS", Timestamp: now})
+
+	reader := NewStatusReader(tempDir)
+
+	status, err := reader.GetLatestForBranch("agent-1/feat-1")
+	if err != nil {
+		t.Fatalf("GetLatestForBranch failed: %v", err)
+	}
+	if status.Commit != "bbb" {
+		t.Errorf("Expected latest commit bbb, got %s", status.Commit)
+	}
+
+	// The refs/heads/ prefix is optional
+	if status, err := reader.GetLatestForBranch("refs/heads/agent-2/feat-2"); err != nil || status.Commit != "ccc" {
+		t.Errorf("Expected commit ccc for full ref, got %v (err %v)", status, err)
+	}
+
+	if _, err := reader.GetLatestForBranch("agent-3/missing"); err == nil {
+		t.Error("Expected error for branch without statuses, got nil")
+	}
+}
+
+func TestStatusReader_GetByTicketAndSince(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Now().UTC()
+
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-1/feat-1", Commit: "aaa", Status: "FAIL", Timestamp: now.Add(-time.Hour)})
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-2/feat-1", Commit: "bbb", Status: "PASS", Timestamp: now})
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/main", Commit: "ccc", Status: "PASS", Timestamp: now.Add(-30 * time.Minute)})
+	// A recorded ticket ID wins over the branch name
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/hotfix", Commit: "ddd", TicketID: "feat-2", Status: "PASS", Timestamp: now.Add(-time.Hour)})
+
+	reader := NewStatusReader(tempDir)
+
+	statuses, err := reader.GetByTicket("feat-1")
+	if err != nil {
+		t.Fatalf("GetByTicket failed: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Commit != "aaa" || statuses[1].Commit != "bbb" {
+		t.Errorf("Expected [aaa bbb] for feat-1, got %v", statuses)
+	}
+
+	statuses, err = reader.GetByTicket("feat-2")
+	if err != nil {
+		t.Fatalf("GetByTicket failed: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Commit != "ddd" {
+		t.Errorf("Expected [ddd] for feat-2, got %v", statuses)
+	}
+
+	statuses, err = reader.ListSince(now.Add(-45 * time.Minute))
+	if err != nil {
+		t.Fatalf("ListSince failed: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Commit != "ccc" || statuses[1].Commit != "bbb" {
+		t.Errorf("Expected [ccc bbb] since 45 minutes ago, got %v", statuses)
+	}
+}
+
+func TestStatusReader_IndexRefresh(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Now().UTC()
+
+	reader := NewStatusReader(tempDir)
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-1/feat-1", Commit: "aaa", Status: "FAIL", Timestamp: now.Add(-time.Minute)})
+
+	if status, err := reader.GetLatestForBranch("agent-1/feat-1"); err != nil || status.Status != "FAIL" {
+		t.Fatalf("Expected FAIL status, got %v (err %v)", status, err)
+	}
+
+	// New files are picked up by the next lookup
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-1/feat-1", Commit: "bbb", Status: "PASS", Timestamp: now})
+	if status, err := reader.GetLatestForBranch("agent-1/feat-1"); err != nil || status.Commit != "bbb" {
+		t.Fatalf("Expected new commit bbb, got %v (err %v)", status, err)
+	}
+
+	// Removed files drop out of the index
+	if err := os.Remove(filepath.Join(tempDir, "bbb.json")); err != nil {
+		t.Fatalf("Failed to remove status file: %v", err)
+	}
+	if status, err := reader.GetLatestForBranch("agent-1/feat-1"); err != nil || status.Commit != "aaa" {
+		t.Fatalf("Expected commit aaa after removal, got %v (err %v)", status, err)
+	}
+}
+
+func TestStatusReader_Prune(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Now().UTC()
+	old := now.Add(-48 * time.Hour)
+
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-1/feat-1", Commit: "aaa", Status: "FAIL", Timestamp: old.Add(-time.Hour)})
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-1/feat-1", Commit: "bbb", Status: "PASS", Timestamp: old})
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-2/feat-2", Commit: "ccc", Status: "FAIL", Timestamp: old})
+	writeStatus(t, tempDir, Status{Ref: "refs/heads/agent-2/feat-2", Commit: "ddd", Status: "PASS", Timestamp: now})
+
+	reader := NewStatusReader(tempDir)
+
+	removed, err := reader.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 statuses pruned, got %d", removed)
+	}
+
+	// The latest status of each branch survives, however old
+	for commit, want := range map[string]bool{"aaa": false, "bbb": true, "ccc": false, "ddd": true} {
+		if got := reader.HasStatus(commit); got != want {
+			t.Errorf("HasStatus(%s) = %v, want %v", commit, got, want)
+		}
+	}
+
+	statuses, err := reader.ListStatuses()
+	if err != nil {
+		t.Fatalf("ListStatuses failed: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Errorf("Expected 2 statuses after pruning, got %d", len(statuses))
+	}
+}
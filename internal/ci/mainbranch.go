@@ -0,0 +1,127 @@
+package ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// MainConfig controls CI tracking on the main branch
+type MainConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	IntervalSeconds int  `mapstructure:"interval_seconds"` // How often main's tip is checked
+}
+
+// Validate checks the main branch tracking settings
+func (c MainConfig) Validate() error {
+	if c.Enabled && c.IntervalSeconds < 1 {
+		return errors.New("interval_seconds must be at least 1")
+	}
+	return nil
+}
+
+// MainTracker follows CI on the main branch
+// Statuses for main normally come from the post-receive hook; a tip still
+// without one on the next check, e.g. one moved without a push through the
+// hook, has CI run by the tracker
+type MainTracker struct {
+	repo     *gitutils.GitRepo
+	backend  Backend // Optional; without it tips are never run
+	statuses *StatusReader
+
+	mu      sync.Mutex
+	health  ipc.MainHealth
+	pending string // Tip seen without a status on the last check
+	running string // Tip the tracker is running CI on
+}
+
+// NewMainTracker creates a tracker for the repository's main branch
+func NewMainTracker(repoPath string, backend Backend, statusDir string) *MainTracker {
+	return &MainTracker{
+		repo:     gitutils.NewRepo(repoPath),
+		backend:  backend,
+		statuses: NewStatusReader(statusDir),
+	}
+}
+
+// Check reads CI's verdict on main's tip and reports whether main turned red
+// or green since the last check; the first check counts as a change when
+// main is red
+// While the tip has no status, main stays as red or green as its latest
+// finished run left it
+func (t *MainTracker) Check(ctx context.Context) (ipc.MainHealth, bool, error) {
+	repo := t.repo.WithContext(ctx)
+	branch, err := repo.MainBranch()
+	if err != nil {
+		return t.Health(), false, err
+	}
+	tip, err := repo.GetBranchCommit(branch)
+	if err != nil {
+		return t.Health(), false, fmt.Errorf("failed to resolve %s: %w", branch, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := t.health
+	health := ipc.MainHealth{Branch: branch, Commit: tip, Red: previous.Red, CheckedAt: time.Now()}
+	status, err := t.statuses.GetStatus(tip)
+	if err == nil {
+		health.Status = status.Status
+		health.Red = !status.Passed()
+		t.pending = ""
+	} else {
+		health.Status = StatusPending
+		if previous.CheckedAt.IsZero() {
+			if latest, err := t.statuses.GetLatestForBranch(branch); err == nil {
+				health.Red = !latest.Passed()
+			}
+		}
+		// A rerun already in progress will record the tip's status
+		if t.pending == tip && !errors.Is(err, ErrStatusPending) {
+			t.run(ctx, branch, tip)
+		}
+		t.pending = tip
+	}
+
+	changed := health.Red != previous.Red || (previous.CheckedAt.IsZero() && health.Red)
+	health.Since = previous.Since
+	if changed || health.Since.IsZero() {
+		health.Since = health.CheckedAt
+	}
+	t.health = health
+	return health, changed, nil
+}
+
+// run starts CI on a tip in the background unless it is already running;
+// the caller holds mu
+func (t *MainTracker) run(ctx context.Context, branch, tip string) {
+	if t.backend == nil || t.running == tip {
+		return
+	}
+	t.running = tip
+	go func() {
+		log.Printf("Running CI for %s at %s", branch, tip[:8])
+		if err := t.backend.Run(ctx, Run{RepoPath: t.repo.Path, Branch: branch, Commit: tip}); err != nil {
+			log.Printf("CI for %s at %s failed to run: %v", branch, tip[:8], err)
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.running == tip {
+			t.running = ""
+		}
+	}()
+}
+
+// Health returns the verdict from the last check
+func (t *MainTracker) Health() ipc.MainHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.health
+}
@@ -0,0 +1,124 @@
+package ci
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// passingBackend records a PASS status for every run and reports the runs
+type passingBackend struct {
+	statusDir string
+	runs      chan Run
+}
+
+func (b *passingBackend) Run(ctx context.Context, run Run) error {
+	err := WriteStatus(b.statusDir, &Status{Ref: "refs/heads/" + run.Branch, Commit: run.Commit, Status: "PASS"})
+	b.runs <- run
+	return err
+}
+
+func TestMainTracker(t *testing.T) {
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "repo.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init repo: %v", err)
+	}
+	repo := gitutils.NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	branch, err := repo.MainBranch()
+	if err != nil {
+		t.Fatalf("Failed to find main branch: %v", err)
+	}
+	tip, _ := repo.GetBranchCommit(branch)
+
+	statusDir := filepath.Join(tmpDir, "ci-status")
+	backend := &passingBackend{statusDir: statusDir, runs: make(chan Run, 1)}
+	tracker := NewMainTracker(repoPath, backend, statusDir)
+	ctx := context.Background()
+
+	health, changed, err := tracker.Check(ctx)
+	if err != nil || changed || health.Red || health.Status != "PENDING" || health.Commit != tip {
+		t.Fatalf("Expected main to be pending and not red, got %+v changed=%v err=%v", health, changed, err)
+	}
+
+	// The hook reports a failure on the tip
+	if err := WriteStatus(statusDir, &Status{Ref: "refs/heads/" + branch, Commit: tip, Status: "FAIL"}); err != nil {
+		t.Fatalf("Failed to write status: %v", err)
+	}
+	health, changed, _ = tracker.Check(ctx)
+	if !changed || !health.Red || health.Status != "FAIL" {
+		t.Fatalf("Expected main to turn red, got %+v changed=%v", health, changed)
+	}
+	redSince := health.Since
+	if _, changed, _ = tracker.Check(ctx); changed {
+		t.Error("Expected no change while main stays red")
+	}
+
+	// Main moves without a status; it stays red, and CI is run on the
+	// second check
+	tree := gitOutput(t, repoPath, "rev-parse", tip+"^{tree}")
+	next := gitOutput(t, repoPath, "commit-tree", tree, "-p", tip, "-m", "Revert")
+	gitOutput(t, repoPath, "update-ref", "refs/heads/"+branch, next)
+
+	health, changed, _ = tracker.Check(ctx)
+	if changed || !health.Red || health.Status != "PENDING" || !health.Since.Equal(redSince) {
+		t.Fatalf("Expected main to stay red while pending, got %+v changed=%v", health, changed)
+	}
+	select {
+	case run := <-backend.runs:
+		t.Fatalf("Expected the hook to get a check's grace, got run %+v", run)
+	default:
+	}
+
+	tracker.Check(ctx)
+	select {
+	case run := <-backend.runs:
+		if run.Commit != next || run.Branch != branch {
+			t.Errorf("Expected CI on %s at %s, got %+v", branch, next, run)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected CI to run on the new tip")
+	}
+	health, changed, _ = tracker.Check(ctx)
+	if !changed || health.Red || health.Status != "PASS" {
+		t.Errorf("Expected main to turn green, got %+v changed=%v", health, changed)
+	}
+
+	// A new tracker picks up main's last finished run while the tip is pending
+	WriteStatus(statusDir, &Status{Ref: "refs/heads/" + branch, Commit: next, Status: "FAIL"})
+	gitOutput(t, repoPath, "update-ref", "refs/heads/"+branch, gitOutput(t, repoPath, "commit-tree", tree, "-p", next, "-m", "More"))
+	health, changed, _ = NewMainTracker(repoPath, nil, statusDir).Check(ctx)
+	if !changed || !health.Red || health.Status != "PENDING" {
+		t.Errorf("Expected a restarted tracker to find main red, got %+v changed=%v", health, changed)
+	}
+}
+
+func TestMainConfig_Validate(t *testing.T) {
+	if err := (MainConfig{}).Validate(); err != nil {
+		t.Errorf("Expected disabled config to be valid, got %v", err)
+	}
+	if err := (MainConfig{Enabled: true}).Validate(); err == nil {
+		t.Error("Expected error for a zero interval")
+	}
+}
+
+func gitOutput(t *testing.T, gitDir string, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", append([]string{"--git-dir", gitDir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
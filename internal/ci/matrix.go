@@ -0,0 +1,171 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MatrixCell is one CI environment: a Go version, a container image and/or
+// a set of environment variables
+type MatrixCell struct {
+	Name         string   `mapstructure:"name"`
+	GoVersion    string   `mapstructure:"go_version"`    // Selected via GOTOOLCHAIN, e.g. 1.23.4
+	Image        string   `mapstructure:"image"`         // Run tests inside this docker image
+	Env          []string `mapstructure:"env"`           // KEY=VALUE pairs
+	AllowFailure bool     `mapstructure:"allow_failure"` // Failures don't fail the ticket
+}
+
+// CellResult is a matrix cell's outcome within a combined status
+type CellResult struct {
+	Name         string   `json:"name"`
+	Status       string   `json:"status"`
+	AllowFailure bool     `json:"allow_failure,omitempty"`
+	Output       string   `json:"output,omitempty"`
+	Flaky        []string `json:"flaky,omitempty"`
+}
+
+// ValidateMatrix checks that cells are named uniquely and env entries are KEY=VALUE
+func ValidateMatrix(cells []MatrixCell) error {
+	seen := make(map[string]bool, len(cells))
+	for i, cell := range cells {
+		if cell.Name == "" {
+			return fmt.Errorf("matrix cell %d has no name", i+1)
+		}
+		if seen[cell.Name] {
+			return fmt.Errorf("matrix cell %s is defined twice", cell.Name)
+		}
+		seen[cell.Name] = true
+
+		for _, kv := range cell.Env {
+			if key, _, ok := strings.Cut(kv, "="); !ok || key == "" {
+				return fmt.Errorf("matrix cell %s: env entry %q is not KEY=VALUE", cell.Name, kv)
+			}
+		}
+	}
+	return nil
+}
+
+// runMatrix runs ci.sh once per cell and writes the combined status
+func (b *LocalBackend) runMatrix(ctx context.Context, run Run) error {
+	tmpDir, err := os.MkdirTemp("", "ci-matrix-")
+	if err != nil {
+		return fmt.Errorf("failed to create matrix directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cells := make([]CellResult, 0, len(b.matrix))
+	var vulns []Vulnerability
+	for i, cell := range b.matrix {
+		if ctx.Err() != nil {
+			return fmt.Errorf("CI matrix for %s did not finish: %w", run.Branch, ctx.Err())
+		}
+
+		statusFile := filepath.Join(tmpDir, fmt.Sprintf("cell-%d.json", i))
+		env := append(append([]string(nil), cell.Env...),
+			"CI_STATUS_FILE="+statusFile,
+			"CI_GO_VERSION="+cell.GoVersion,
+			"CI_IMAGE="+cell.Image,
+			"CI_ENV_NAMES="+strings.Join(envNames(cell.Env), " "),
+			"CI_CELL="+cell.Name,
+		)
+
+		result := CellResult{Name: cell.Name, AllowFailure: cell.AllowFailure}
+		output, runErr := b.runScript(ctx, run, env)
+		if status, err := readStatusFile(statusFile); err == nil {
+			result.Status = status.Status
+			result.Output = status.Output
+			result.Flaky = status.Flaky
+			vulns = mergeVulnerabilities(vulns, status.Vulnerabilities)
+		} else {
+			// The script died before recording a result
+			result.Status = "FAIL"
+			result.Output = strings.TrimSpace(string(output))
+			if limitErr := b.limits.Check(ctx, runErr, output); limitErr != nil {
+				result.Output = fmt.Sprintf("%v\n%s", limitErr, result.Output)
+			} else if runErr != nil {
+				result.Output = fmt.Sprintf("%v\n%s", runErr, result.Output)
+			}
+		}
+		cells = append(cells, result)
+	}
+
+	status := combineCells(run, cells)
+	status.Vulnerabilities = vulns
+	statusDir := b.statusDir
+	if run.Tier == TierFull {
+		statusDir = FullStatusDir(statusDir)
+	}
+	return WriteStatus(statusDir, status)
+}
+
+// combineCells folds cell results into one status
+// Only cells that aren't allowed to fail decide the overall result
+func combineCells(run Run, cells []CellResult) *Status {
+	status := &Status{
+		Ref:      "refs/heads/" + run.Branch,
+		Commit:   run.Commit,
+		TicketID: run.TicketID,
+		Profile:  run.Profile.name(),
+		Tier:     run.Tier,
+		Status:   "PASS",
+		Cells:    cells,
+	}
+
+	var summary, failures []string
+	for _, cell := range cells {
+		line := fmt.Sprintf("%s: %s", cell.Name, cell.Status)
+		if cell.AllowFailure {
+			line += " (allowed to fail)"
+		}
+		summary = append(summary, line)
+
+		if cell.Status != "PASS" && cell.Status != "FLAKY" {
+			failures = append(failures, fmt.Sprintf("--- %s ---\n%s", cell.Name, cell.Output))
+		}
+		if cell.AllowFailure {
+			continue
+		}
+
+		switch cell.Status {
+		case "PASS":
+		case "FLAKY":
+			if status.Status == "PASS" {
+				status.Status = "FLAKY"
+			}
+			status.Flaky = append(status.Flaky, cell.Flaky...)
+		default:
+			status.Status = "FAIL"
+		}
+	}
+
+	status.Output = strings.Join(append(summary, failures...), "\n")
+	return status
+}
+
+// envNames returns the variable names of KEY=VALUE pairs
+func envNames(env []string) []string {
+	names := make([]string, 0, len(env))
+	for _, kv := range env {
+		if key, _, ok := strings.Cut(kv, "="); ok {
+			names = append(names, key)
+		}
+	}
+	return names
+}
+
+// readStatusFile parses a status file written by ci.sh
+func readStatusFile(path string) (*Status, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
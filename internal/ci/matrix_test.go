@@ -0,0 +1,99 @@
+package ci
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateMatrix(t *testing.T) {
+	tests := []struct {
+		name    string
+		cells   []MatrixCell
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"valid", []MatrixCell{{Name: "a", Env: []string{"CGO_ENABLED=0"}}, {Name: "b", GoVersion: "1.23.4"}}, false},
+		{"unnamed", []MatrixCell{{GoVersion: "1.23.4"}}, true},
+		{"duplicate", []MatrixCell{{Name: "a"}, {Name: "a"}}, true},
+		{"bad env", []MatrixCell{{Name: "a", Env: []string{"CGO_ENABLED"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateMatrix(tt.cells); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMatrix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCombineCells(t *testing.T) {
+	run := Run{Branch: "agent-1/feat", Commit: "abc123", TicketID: "feat"}
+
+	tests := []struct {
+		name  string
+		cells []CellResult
+		want  string
+	}{
+		{"all pass", []CellResult{{Name: "a", Status: "PASS"}, {Name: "b", Status: "PASS"}}, "PASS"},
+		{"required fails", []CellResult{{Name: "a", Status: "PASS"}, {Name: "b", Status: "FAIL"}}, "FAIL"},
+		{"optional fails", []CellResult{{Name: "a", Status: "PASS"}, {Name: "b", Status: "FAIL", AllowFailure: true}}, "PASS"},
+		{"required flaky", []CellResult{{Name: "a", Status: "FLAKY", Flaky: []string{"pkg"}}, {Name: "b", Status: "PASS"}}, "FLAKY"},
+		{"flaky and failing", []CellResult{{Name: "a", Status: "FLAKY"}, {Name: "b", Status: "FAIL"}}, "FAIL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := combineCells(run, tt.cells)
+			if status.Status != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, status.Status)
+			}
+			if status.Commit != "abc123" || status.TicketID != "feat" || len(status.Cells) != len(tt.cells) {
+				t.Errorf("Unexpected combined status %+v", status)
+			}
+		})
+	}
+}
+
+func TestLocalBackend_Matrix(t *testing.T) {
+	// A stand-in ci.sh that records the RESULT variable set by each cell
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+printf '{"ref":"%s","commit":"%s","status":"%s","output":"go %s"}' "$2" "$3" "$RESULT" "$CI_GO_VERSION" > "$CI_STATUS_FILE"
+`
+	if err := os.WriteFile(filepath.Join(binDir, "ci.sh"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake ci.sh: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	statusDir := t.TempDir()
+	backend := NewLocalBackend(BackendConfig{
+		StatusDir: statusDir,
+		Matrix: []MatrixCell{
+			{Name: "stable", GoVersion: "1.24.0", Env: []string{"RESULT=PASS"}},
+			{Name: "old", GoVersion: "1.22.0", Env: []string{"RESULT=FAIL"}, AllowFailure: true},
+		},
+	})
+
+	run := Run{RepoPath: t.TempDir(), Branch: "agent-1/feat", Commit: "abc123", TicketID: "feat"}
+	if err := backend.Run(context.Background(), run); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	status, err := NewStatusReader(statusDir).GetStatus("abc123")
+	if err != nil {
+		t.Fatalf("Expected combined status: %v", err)
+	}
+	if status.Status != "PASS" {
+		t.Errorf("Expected PASS with only an optional cell failing, got %s", status.Status)
+	}
+	if len(status.Cells) != 2 || status.Cells[1].Status != "FAIL" || status.Cells[1].Output != "go 1.22.0" {
+		t.Errorf("Unexpected cells %+v", status.Cells)
+	}
+	if !strings.Contains(status.Output, "old: FAIL (allowed to fail)") {
+		t.Errorf("Expected cell summary in output, got %q", status.Output)
+	}
+}
@@ -0,0 +1,101 @@
+package ci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Profile is a CI configuration chosen by a ticket's tags, e.g. npm tests
+// for frontend tickets or no CI at all for docs
+type Profile struct {
+	Name    string   `mapstructure:"name"`
+	Tags    []string `mapstructure:"tags"`    // Tickets with any of these tags use the profile
+	Command string   `mapstructure:"command"` // Local backend only; replaces go test in ci.sh
+	Env     []string `mapstructure:"env"`     // Local backend only; KEY=VALUE pairs for ci.sh
+	Skip    bool     `mapstructure:"skip"`    // Record a SKIPPED status instead of running CI
+}
+
+// ValidateProfiles checks that profiles are named uniquely, claim at least
+// one tag each and that no tag selects two profiles
+func ValidateProfiles(profiles []Profile) error {
+	names := make(map[string]bool, len(profiles))
+	tags := make(map[string]string)
+	for i, p := range profiles {
+		if p.Name == "" {
+			return fmt.Errorf("profile %d has no name", i+1)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("profile %s is defined twice", p.Name)
+		}
+		names[p.Name] = true
+
+		if len(p.Tags) == 0 {
+			return fmt.Errorf("profile %s has no tags", p.Name)
+		}
+		for _, tag := range p.Tags {
+			if other, ok := tags[tag]; ok {
+				return fmt.Errorf("tag %s selects both profile %s and %s", tag, other, p.Name)
+			}
+			tags[tag] = p.Name
+		}
+
+		if p.Skip && (p.Command != "" || len(p.Env) > 0) {
+			return fmt.Errorf("profile %s skips CI, so command and env have no effect", p.Name)
+		}
+		for _, kv := range p.Env {
+			if key, _, ok := strings.Cut(kv, "="); !ok || key == "" {
+				return fmt.Errorf("profile %s: env entry %q is not KEY=VALUE", p.Name, kv)
+			}
+		}
+	}
+	return nil
+}
+
+// SelectProfile returns the first profile matching one of the tags, or nil
+// when the default CI applies
+func SelectProfile(profiles []Profile, tags []string) *Profile {
+	for i := range profiles {
+		for _, want := range profiles[i].Tags {
+			for _, tag := range tags {
+				if tag == want {
+					return &profiles[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// env returns the variables ci.sh reads the profile from
+func (p *Profile) env() []string {
+	if p == nil {
+		return nil
+	}
+	env := append([]string(nil), p.Env...)
+	env = append(env, "CI_PROFILE="+p.Name)
+	if p.Command != "" {
+		env = append(env, "CI_TEST_COMMAND="+p.Command)
+	}
+	return env
+}
+
+// name returns the profile's name, or "" for the default CI
+func (p *Profile) name() string {
+	if p == nil {
+		return ""
+	}
+	return p.Name
+}
+
+// WriteSkipped records that CI was skipped for a commit and why
+func WriteSkipped(statusDir string, run Run, reason string) error {
+	return WriteStatus(statusDir, &Status{
+		Ref:      "refs/heads/" + run.Branch,
+		Commit:   run.Commit,
+		TicketID: run.TicketID,
+		Status:   "SKIPPED",
+		Profile:  run.Profile.name(),
+		Tier:     run.Tier,
+		Output:   "CI skipped: " + reason,
+	})
+}
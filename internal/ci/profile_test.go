@@ -0,0 +1,101 @@
+package ci
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []Profile
+		valid    bool
+	}{
+		{"none", nil, true},
+		{"valid", []Profile{
+			{Name: "frontend", Tags: []string{"frontend", "ui"}, Command: "npm test", Env: []string{"CI=true"}},
+			{Name: "docs", Tags: []string{"docs"}, Skip: true},
+		}, true},
+		{"unnamed", []Profile{{Tags: []string{"docs"}}}, false},
+		{"duplicate name", []Profile{{Name: "a", Tags: []string{"x"}}, {Name: "a", Tags: []string{"y"}}}, false},
+		{"no tags", []Profile{{Name: "a"}}, false},
+		{"shared tag", []Profile{{Name: "a", Tags: []string{"x"}}, {Name: "b", Tags: []string{"x"}}}, false},
+		{"skip with command", []Profile{{Name: "a", Tags: []string{"x"}, Skip: true, Command: "make"}}, false},
+		{"bad env", []Profile{{Name: "a", Tags: []string{"x"}, Env: []string{"NOVALUE"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateProfiles(tt.profiles); (err == nil) != tt.valid {
+				t.Errorf("ValidateProfiles() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestSelectProfile(t *testing.T) {
+	profiles := []Profile{
+		{Name: "frontend", Tags: []string{"frontend"}},
+		{Name: "docs", Tags: []string{"docs"}, Skip: true},
+	}
+
+	if p := SelectProfile(profiles, []string{"docs", "frontend"}); p == nil || p.Name != "frontend" {
+		t.Errorf("Expected the first matching profile to win, got %v", p)
+	}
+	if p := SelectProfile(profiles, []string{"backend"}); p != nil {
+		t.Errorf("Expected no profile for unmapped tags, got %s", p.Name)
+	}
+	if p := SelectProfile(nil, []string{"docs"}); p != nil {
+		t.Errorf("Expected no profile without any configured, got %s", p.Name)
+	}
+}
+
+func TestWriteSkipped(t *testing.T) {
+	statusDir := t.TempDir()
+	run := Run{Branch: "agent-1/docs-1", Commit: "abc123", TicketID: "docs-1", Profile: &Profile{Name: "docs", Skip: true}}
+	if err := WriteSkipped(statusDir, run, "profile docs skips CI"); err != nil {
+		t.Fatalf("WriteSkipped failed: %v", err)
+	}
+
+	status, err := NewStatusReader(statusDir).GetStatus("abc123")
+	if err != nil {
+		t.Fatalf("Expected a status: %v", err)
+	}
+	if status.Status != "SKIPPED" || status.Profile != "docs" || status.TicketID != "docs-1" || status.Output != "CI skipped: profile docs skips CI" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if !status.Passed() {
+		t.Error("Expected a skipped run to count as passed")
+	}
+}
+
+func TestLocalBackend_Profile(t *testing.T) {
+	// A stand-in ci.sh that records the profile and command it was given
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+printf '{"ref":"%s","commit":"%s","status":"PASS","profile":"%s","output":"%s %s"}' "$2" "$3" "$CI_PROFILE" "$CI_TEST_COMMAND" "$NODE_ENV" > "$CI_STATUS_FILE"
+`
+	if err := os.WriteFile(filepath.Join(binDir, "ci.sh"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake ci.sh: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	statusDir := t.TempDir()
+	t.Setenv("CI_STATUS_FILE", filepath.Join(statusDir, "abc123.json"))
+	backend := NewLocalBackend(BackendConfig{StatusDir: statusDir})
+
+	profile := &Profile{Name: "frontend", Tags: []string{"frontend"}, Command: "npm test", Env: []string{"NODE_ENV=test"}}
+	run := Run{RepoPath: t.TempDir(), Branch: "agent-1/ui", Commit: "abc123", Profile: profile}
+	if err := backend.Run(context.Background(), run); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	status, err := NewStatusReader(statusDir).GetStatus("abc123")
+	if err != nil {
+		t.Fatalf("Expected a status: %v", err)
+	}
+	if status.Profile != "frontend" || status.Output != "npm test test" {
+		t.Errorf("Expected the profile's command and env to reach ci.sh, got %+v", status)
+	}
+}
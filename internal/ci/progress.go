@@ -0,0 +1,55 @@
+package ci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProgressFile is where a running CI job records the step it is executing,
+// beside the commit's status file; it is removed once the run finishes
+func ProgressFile(statusDir, commit string) string {
+	return filepath.Join(statusDir, commit+".progress")
+}
+
+// ReadProgress returns the step CI is executing for a commit, or "" when
+// no run has reported one
+func ReadProgress(statusDir, commit string) string {
+	data, err := os.ReadFile(ProgressFile(statusDir, commit))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// WriteProgress records the step CI is executing for a commit
+func WriteProgress(statusDir, commit, step string) error {
+	if err := os.MkdirAll(statusDir, 0755); err != nil {
+		return fmt.Errorf("failed to create CI status directory: %w", err)
+	}
+	path := ProgressFile(statusDir, commit)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(step+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write CI progress: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// clearProgress removes a commit's progress file once its run has finished
+func clearProgress(statusDir, commit string) {
+	os.Remove(ProgressFile(statusDir, commit))
+}
+
+// progressEnv returns the variable ci.sh records its steps through; the
+// path is absolute since ci.sh writes it from inside its clone
+func progressEnv(statusDir, commit string) ([]string, error) {
+	path, err := filepath.Abs(ProgressFile(statusDir, commit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute CI progress path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create CI status directory: %w", err)
+	}
+	return []string{"CI_PROGRESS_FILE=" + path}, nil
+}
@@ -0,0 +1,46 @@
+package ci
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	statusDir := filepath.Join(t.TempDir(), "ci-status")
+
+	if step := ReadProgress(statusDir, "abc123"); step != "" {
+		t.Errorf("Expected no step before the run reports one, got %q", step)
+	}
+	if err := WriteProgress(statusDir, "abc123", "go test"); err != nil {
+		t.Fatalf("WriteProgress failed: %v", err)
+	}
+	if step := ReadProgress(statusDir, "abc123"); step != "go test" {
+		t.Errorf("Expected step go test, got %q", step)
+	}
+
+	// The progress file is never mistaken for a status
+	if _, err := NewStatusReader(statusDir).GetLatestForBranch("main"); err == nil {
+		t.Error("Expected no status from a progress file")
+	}
+
+	clearProgress(statusDir, "abc123")
+	if step := ReadProgress(statusDir, "abc123"); step != "" {
+		t.Errorf("Expected the step cleared, got %q", step)
+	}
+}
+
+func TestProgressEnvIsAbsolute(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	env, err := progressEnv("ci-status", "abc123")
+	if err != nil {
+		t.Fatalf("progressEnv failed: %v", err)
+	}
+	if len(env) != 1 || !strings.HasPrefix(env[0], "CI_PROGRESS_FILE=") {
+		t.Fatalf("Expected CI_PROGRESS_FILE, got %v", env)
+	}
+	if path := strings.TrimPrefix(env[0], "CI_PROGRESS_FILE="); !filepath.IsAbs(path) || filepath.Base(path) != "abc123.progress" {
+		t.Errorf("Expected an absolute path to abc123.progress, got %s", path)
+	}
+}
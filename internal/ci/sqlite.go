@@ -0,0 +1,153 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// sqliteTimeout bounds each sqlite3 run, including waiting on another
+// writer's lock
+const sqliteTimeout = 30 * time.Second
+
+// sqliteSchema waits out other writers' locks and creates the statuses
+// table; every script starts with it so a new database needs no setup step
+const sqliteSchema = `.timeout 10000
+CREATE TABLE IF NOT EXISTS ci_status (
+	commit_hash TEXT PRIMARY KEY,
+	data TEXT NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+`
+
+// SQLiteConfig keeps statuses in one SQLite database instead of a file per
+// commit, for installations with thousands of them
+type SQLiteConfig struct {
+	Path   string `mapstructure:"path"`   // Database file, created on first use
+	Binary string `mapstructure:"binary"` // sqlite3 executable; defaults to sqlite3 on PATH
+}
+
+// SQLiteStatusStore keeps statuses as rows of a SQLite database. It drives
+// the sqlite3 command-line shell, as the rest of the orchestrator drives git
+// and kubectl, so it needs no database driver; every value reaches SQL as a
+// hex literal, never as text pasted into the statement.
+type SQLiteStatusStore struct {
+	path   string
+	binary string
+}
+
+// NewSQLiteStatusStore creates a store for the configured database, checking
+// that the sqlite3 shell can be found
+func NewSQLiteStatusStore(config SQLiteConfig) (*SQLiteStatusStore, error) {
+	binary := config.Binary
+	if binary == "" {
+		binary = "sqlite3"
+	}
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("the sqlite backend needs the sqlite3 shell: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create CI status database directory: %w", err)
+	}
+	return &SQLiteStatusStore{path: config.Path, binary: resolved}, nil
+}
+
+// Get reads the commit's row
+func (s *SQLiteStatusStore) Get(commit string) (*Status, error) {
+	output, err := s.exec(fmt.Sprintf("SELECT hex(data) FROM ci_status WHERE commit_hash = %s;", sqlText(commit)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CI status: %w", err)
+	}
+	value := strings.TrimSpace(output)
+	if value == "" {
+		return nil, ErrStatusNotFound
+	}
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CI status: %w", err)
+	}
+	return parseStatus(data)
+}
+
+// Put inserts or replaces the commit's row in one statement, so readers
+// never see a partial status
+func (s *SQLiteStatusStore) Put(status *Status) error {
+	data, err := marshalStatus(status)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(fmt.Sprintf("INSERT OR REPLACE INTO ci_status (commit_hash, data, updated_at) VALUES (%s, %s, %d);",
+		sqlText(status.Commit), sqlText(string(data)), time.Now().UnixNano()))
+	if err != nil {
+		return fmt.Errorf("failed to write CI status: %w", err)
+	}
+	return nil
+}
+
+// List returns an entry per row
+func (s *SQLiteStatusStore) List() ([]StatusEntry, error) {
+	output, err := s.exec("SELECT hex(commit_hash), updated_at, length(CAST(data AS BLOB)) FROM ci_status;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CI statuses: %w", err)
+	}
+
+	var entries []StatusEntry
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			continue
+		}
+		commit, err := hex.DecodeString(fields[0])
+		if err != nil {
+			continue
+		}
+		updatedAt, _ := strconv.ParseInt(fields[1], 10, 64)
+		size, _ := strconv.ParseInt(fields[2], 10, 64)
+		entries = append(entries, StatusEntry{Commit: string(commit), ModTime: time.Unix(0, updatedAt), Size: size})
+	}
+	return entries, nil
+}
+
+// Delete removes the commit's row if it exists
+func (s *SQLiteStatusStore) Delete(commit string) error {
+	if _, err := s.exec(fmt.Sprintf("DELETE FROM ci_status WHERE commit_hash = %s;", sqlText(commit))); err != nil {
+		return fmt.Errorf("failed to remove CI status %s: %w", commit, err)
+	}
+	return nil
+}
+
+// exec runs a script against the database after the schema, returning what
+// it printed
+func (s *SQLiteStatusStore) exec(script string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqliteTimeout)
+	defer cancel()
+
+	cmd := command.Context(ctx, s.binary, "-batch", "-bail", "-list", "-separator", "|", s.path)
+	cmd.Stdin = strings.NewReader(sqliteSchema + script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := command.Default.Output(cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", errors.New("sqlite3 timed out")
+		}
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// sqlText quotes a Go string as an SQL text value
+func sqlText(value string) string {
+	return "CAST(X'" + hex.EncodeToString([]byte(value)) + "' AS TEXT)"
+}
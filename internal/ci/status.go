@@ -1,102 +1,121 @@
 package ci
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/fs"
-	"os"
-	"path/filepath"
 	"time"
 )
 
+// StatusPending marks a commit whose CI has been re-triggered and has no
+// result yet
+const StatusPending = "PENDING"
+
+// ErrStatusPending is returned by StatusReader.GetStatus while a commit's CI
+// is being re-run
+var ErrStatusPending = errors.New("CI is still running")
+
 // Status represents the CI status for a commit
 type Status struct {
-	Ref       string    `json:"ref"`
-	Commit    string    `json:"commit"`
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Output    string    `json:"output"`
+	Ref       string       `json:"ref"`
+	Commit    string       `json:"commit"`
+	TicketID  string       `json:"ticket_id,omitempty"`
+	Status    string       `json:"status"`            // PASS, FAIL, FLAKY (passed only on retry), SKIPPED or PENDING
+	Profile   string       `json:"profile,omitempty"` // CI profile selected by the ticket's tags
+	Tier      string       `json:"tier,omitempty"`    // quick or full when ci.quick_tests tiers CI
+	Timestamp time.Time    `json:"timestamp"`
+	Output    string       `json:"output"`
+	Flaky     []string     `json:"flaky,omitempty"` // Test packages that passed on retry
+	Cells     []CellResult `json:"cells,omitempty"` // Per-cell outcomes of a matrix run
+
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"` // Dependency scanner findings when vuln_scan is enabled
+}
+
+// Passed reports whether CI passed, counting results that needed a retry
+// and runs a profile skipped
+func (s *Status) Passed() bool {
+	return s.Status == "PASS" || s.Status == "FLAKY" || s.Status == "SKIPPED"
+}
+
+// Pending reports whether the status is a placeholder for a run in progress
+func (s *Status) Pending() bool {
+	return s.Status == StatusPending
 }
 
-// StatusReader provides methods to read CI status files
+// StatusReader provides methods to read CI statuses from a StatusStore
 type StatusReader struct {
-	statusDir string
+	store StatusStore
+	index *index
 }
 
 // NewStatusReader creates a new StatusReader for the given status directory
 func NewStatusReader(statusDir string) *StatusReader {
+	return NewStoreStatusReader(&FileStatusStore{Dir: statusDir})
+}
+
+// NewStoreStatusReader creates a StatusReader over any status store
+func NewStoreStatusReader(store StatusStore) *StatusReader {
 	return &StatusReader{
-		statusDir: statusDir,
+		store: store,
+		index: &index{},
 	}
 }
 
 // GetStatus reads the CI status for a specific commit hash
+// A pending status is not a result; it is returned as ErrStatusPending
 func (sr *StatusReader) GetStatus(commitHash string) (*Status, error) {
-	filePath := filepath.Join(sr.statusDir, commitHash+".json")
-	
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("CI status not found for commit %s", commitHash)
-		}
-		return nil, fmt.Errorf("failed to read CI status file: %w", err)
+	status, err := sr.store.Get(commitHash)
+	if errors.Is(err, ErrStatusNotFound) {
+		return nil, fmt.Errorf("%w for commit %s", ErrStatusNotFound, commitHash)
 	}
-	
-	var status Status
-	if err := json.Unmarshal(data, &status); err != nil {
-		return nil, fmt.Errorf("failed to parse CI status JSON: %w", err)
+	if err == nil && status.Pending() {
+		return nil, fmt.Errorf("%w for commit %s", ErrStatusPending, commitHash)
 	}
-	
-	return &status, nil
+	return status, err
 }
 
-// ListStatuses returns all CI statuses in the status directory
+// ListStatuses returns all CI statuses in the status directory, oldest first
+// Files that can't be parsed (e.g. still being written) are skipped
 func (sr *StatusReader) ListStatuses() ([]*Status, error) {
-	var statuses []*Status
-	
-	err := filepath.WalkDir(sr.statusDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		
-		if d.IsDir() || filepath.Ext(path) != ".json" {
-			return nil
-		}
-		
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("failed to read status file %s: %w", path, err)
-		}
-		
-		var status Status
-		if err := json.Unmarshal(data, &status); err != nil {
-			return fmt.Errorf("failed to parse status JSON in %s: %w", path, err)
-		}
-		
-		statuses = append(statuses, &status)
-		return nil
-	})
-	
+	statuses, err := sr.lookup(func(idx *index) []*Status { return idx.byTime })
 	if err != nil {
 		return nil, fmt.Errorf("failed to list CI statuses: %w", err)
 	}
-	
 	return statuses, nil
 }
 
 // HasStatus checks if a CI status exists for the given commit
+// A status that can't be parsed yet, or is pending, still exists
 func (sr *StatusReader) HasStatus(commitHash string) bool {
-	filePath := filepath.Join(sr.statusDir, commitHash+".json")
-	_, err := os.Stat(filePath)
-	return err == nil
+	_, err := sr.store.Get(commitHash)
+	return !errors.Is(err, ErrStatusNotFound)
 }
 
-// IsPassing returns true if the CI status for the given commit is "PASS"
+// IsPassing returns true if the CI status for the given commit passed
 func (sr *StatusReader) IsPassing(commitHash string) (bool, error) {
 	status, err := sr.GetStatus(commitHash)
 	if err != nil {
 		return false, err
 	}
 	
-	return status.Status == "PASS", nil
-}
\ No newline at end of file
+	return status.Passed(), nil
+}
+
+// WaitForStatus polls until a CI status for the commit appears or ctx is done
+// A status file that can't be parsed yet is treated as still being written
+func (sr *StatusReader) WaitForStatus(ctx context.Context, commitHash string, pollInterval time.Duration) (*Status, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if status, err := sr.GetStatus(commitHash); err == nil {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no CI status for commit %s: %w", commitHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
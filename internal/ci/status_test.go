@@ -1,7 +1,9 @@
 package ci
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -110,6 +112,7 @@ func TestStatusReader_IsPassing(t *testing.T) {
 		expected bool
 	}{
 		{"passing", "PASS", true},
+		{"flaky", "FLAKY", true},
 		{"failing", "FAIL", false},
 		{"unknown", "UNKNOWN", false},
 	}
@@ -206,4 +209,76 @@ func TestStatusReader_InvalidJSON(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for invalid JSON, got nil")
 	}
-}
\ No newline at end of file
+}
+
+func TestStatusReader_WaitForStatus(t *testing.T) {
+	tempDir := t.TempDir()
+	reader := NewStatusReader(tempDir)
+	commitHash := "wait123"
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		data, _ := json.Marshal(Status{Commit: commitHash, Status: "FAIL", Output: "1 test failed"})
+		os.WriteFile(filepath.Join(tempDir, commitHash+".json"), data, 0644)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	status, err := reader.WaitForStatus(ctx, commitHash, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForStatus failed: %v", err)
+	}
+	if status.Status != "FAIL" {
+		t.Errorf("Expected status FAIL, got %s", status.Status)
+	}
+}
+
+func TestStatusReader_WaitForStatus_Timeout(t *testing.T) {
+	reader := NewStatusReader(t.TempDir())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := reader.WaitForStatus(ctx, "missing", 20*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestStatusReader_PendingIsNotAResult(t *testing.T) {
+	dir := t.TempDir()
+	store := &FileStatusStore{Dir: dir}
+	branch := "refs/heads/agent-1/feat-123"
+	for _, s := range []*Status{
+		{Ref: branch, Commit: "old", Status: "PASS", Timestamp: time.Now().Add(-time.Minute)},
+		{Ref: branch, Commit: "new", Status: StatusPending},
+	} {
+		if err := store.Put(s); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	reader := NewStatusReader(dir)
+	if _, err := reader.GetStatus("new"); !errors.Is(err, ErrStatusPending) {
+		t.Errorf("Expected ErrStatusPending, got %v", err)
+	}
+	if !reader.HasStatus("new") {
+		t.Error("Expected a pending status to exist")
+	}
+	if latest, err := reader.GetLatestForBranch(branch); err != nil || latest.Commit != "old" {
+		t.Errorf("Expected the last result to be the old commit's, got %+v (err %v)", latest, err)
+	}
+
+	// Waiters see the rerun's result, not the placeholder
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		store.Put(&Status{Ref: branch, Commit: "new", Status: "FAIL"})
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := reader.WaitForStatus(ctx, "new", 10*time.Millisecond)
+	if err != nil || status.Status != "FAIL" {
+		t.Fatalf("Expected to wait for FAIL, got %+v (err %v)", status, err)
+	}
+}
@@ -0,0 +1,292 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
+)
+
+// ErrStatusNotFound is returned by StatusStore.Get for commits without a status
+var ErrStatusNotFound = errors.New("CI status not found")
+
+// StatusEntry describes a stored status without reading it; ModTime and Size
+// change whenever the status is rewritten
+type StatusEntry struct {
+	Commit  string
+	ModTime time.Time
+	Size    int64
+}
+
+// StatusStore holds CI statuses keyed by commit hash
+type StatusStore interface {
+	Get(commit string) (*Status, error)
+	Put(status *Status) error
+	List() ([]StatusEntry, error)
+	Delete(commit string) error
+}
+
+// Status store backends accepted in StoreConfig
+const (
+	StoreFile   = "file"
+	StoreS3     = "s3"
+	StoreSQLite = "sqlite"
+)
+
+// StoreConfig selects where CI statuses are shared: the status directory
+// itself, a SQLite database, or an S3-compatible bucket that remote workers
+// can reach too
+type StoreConfig struct {
+	Backend string           `mapstructure:"backend"` // file, sqlite or s3; defaults to file
+	S3      storage.S3Config `mapstructure:"s3"`
+	SQLite  SQLiteConfig     `mapstructure:"sqlite"`
+}
+
+// Validate checks the settings required by the chosen backend
+func (c StoreConfig) Validate() error {
+	switch c.Backend {
+	case "", StoreFile:
+	case StoreS3:
+		if c.S3.Endpoint == "" || c.S3.Bucket == "" {
+			return errors.New("s3.endpoint and s3.bucket are required for the s3 backend")
+		}
+	case StoreSQLite:
+		if c.SQLite.Path == "" {
+			return errors.New("sqlite.path is required for the sqlite backend")
+		}
+	default:
+		return fmt.Errorf("unknown backend %q (expected file, sqlite or s3)", c.Backend)
+	}
+	return nil
+}
+
+// NewStatusStore creates the configured store; the file backend uses statusDir
+func NewStatusStore(config StoreConfig, statusDir string) (StatusStore, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	switch config.Backend {
+	case StoreS3:
+		s3, err := storage.NewS3Store(config.S3)
+		if err != nil {
+			return nil, err
+		}
+		return NewObjectStatusStore(s3, ""), nil
+	case StoreSQLite:
+		return NewSQLiteStatusStore(config.SQLite)
+	}
+	return &FileStatusStore{Dir: statusDir}, nil
+}
+
+// FileStatusStore keeps one <commit>.json file per status in Dir, the layout
+// ci.sh and the local backend write
+type FileStatusStore struct {
+	Dir string
+}
+
+// Get reads the commit's status file
+func (s *FileStatusStore) Get(commit string) (*Status, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, commit+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrStatusNotFound
+		}
+		return nil, fmt.Errorf("failed to read CI status file: %w", err)
+	}
+	return parseStatus(data)
+}
+
+// Put writes the status through a temporary file so readers never see a
+// partial status
+func (s *FileStatusStore) Put(status *Status) error {
+	data, err := marshalStatus(status)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create CI status directory: %w", err)
+	}
+
+	path := filepath.Join(s.Dir, status.Commit+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write CI status: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write CI status: %w", err)
+	}
+	return nil
+}
+
+// List returns an entry per status file; a missing directory holds none
+func (s *FileStatusStore) List() ([]StatusEntry, error) {
+	dirEntries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read CI status directory: %w", err)
+	}
+
+	entries := make([]StatusEntry, 0, len(dirEntries))
+	for _, d := range dirEntries {
+		name := d.Name()
+		if d.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, StatusEntry{Commit: strings.TrimSuffix(name, ".json"), ModTime: info.ModTime(), Size: info.Size()})
+	}
+	return entries, nil
+}
+
+// Delete removes the commit's status file if it exists
+func (s *FileStatusStore) Delete(commit string) error {
+	if err := os.Remove(filepath.Join(s.Dir, commit+".json")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove CI status %s: %w", commit, err)
+	}
+	return nil
+}
+
+// ObjectClient is the subset of an object store a status store needs;
+// storage.S3Store implements it
+type ObjectClient interface {
+	GetObject(ctx context.Context, key string) ([]byte, error)
+	PutObject(ctx context.Context, key string, data []byte) error
+	ListObjects(ctx context.Context, prefix string) ([]storage.ObjectInfo, error)
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// StatusPrefix is the default key prefix of statuses in an object store
+const StatusPrefix = "ci-status/"
+
+// objectTimeout bounds each object store request
+const objectTimeout = 30 * time.Second
+
+// ObjectStatusStore keeps one <prefix><commit>.json object per status, so
+// workers on other machines can report without a shared filesystem
+type ObjectStatusStore struct {
+	client ObjectClient
+	prefix string
+}
+
+// NewObjectStatusStore creates a store under prefix, StatusPrefix if empty
+func NewObjectStatusStore(client ObjectClient, prefix string) *ObjectStatusStore {
+	if prefix == "" {
+		prefix = StatusPrefix
+	}
+	return &ObjectStatusStore{client: client, prefix: strings.TrimSuffix(prefix, "/") + "/"}
+}
+
+// Get downloads the commit's status
+func (s *ObjectStatusStore) Get(commit string) (*Status, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), objectTimeout)
+	defer cancel()
+
+	data, err := s.client.GetObject(ctx, s.prefix+commit+".json")
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrStatusNotFound
+		}
+		return nil, fmt.Errorf("failed to read CI status: %w", err)
+	}
+	return parseStatus(data)
+}
+
+// Put uploads the status, replacing any earlier one for the commit
+func (s *ObjectStatusStore) Put(status *Status) error {
+	data, err := marshalStatus(status)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectTimeout)
+	defer cancel()
+	if err := s.client.PutObject(ctx, s.prefix+status.Commit+".json", data); err != nil {
+		return fmt.Errorf("failed to write CI status: %w", err)
+	}
+	return nil
+}
+
+// List returns an entry per status object under the prefix
+func (s *ObjectStatusStore) List() ([]StatusEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), objectTimeout)
+	defer cancel()
+
+	objects, err := s.client.ListObjects(ctx, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CI statuses: %w", err)
+	}
+
+	entries := make([]StatusEntry, 0, len(objects))
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, s.prefix)
+		if strings.Contains(name, "/") || path.Ext(name) != ".json" {
+			continue
+		}
+		entries = append(entries, StatusEntry{Commit: strings.TrimSuffix(name, ".json"), ModTime: object.LastModified, Size: object.Size})
+	}
+	return entries, nil
+}
+
+// Delete removes the commit's status object
+func (s *ObjectStatusStore) Delete(commit string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), objectTimeout)
+	defer cancel()
+
+	if err := s.client.DeleteObject(ctx, s.prefix+commit+".json"); err != nil {
+		return fmt.Errorf("failed to remove CI status %s: %w", commit, err)
+	}
+	return nil
+}
+
+// PublishStatus copies a commit's status from the local status directory to
+// store, for readers that don't share the directory. It does nothing when
+// store is nil or is the directory itself.
+func PublishStatus(store StatusStore, statusDir, commit string) error {
+	if store == nil {
+		return nil
+	}
+	if files, ok := store.(*FileStatusStore); ok && filepath.Clean(files.Dir) == filepath.Clean(statusDir) {
+		return nil
+	}
+
+	status, err := (&FileStatusStore{Dir: statusDir}).Get(commit)
+	if err != nil {
+		return err
+	}
+	return store.Put(status)
+}
+
+// parseStatus decodes a stored status
+func parseStatus(data []byte) (*Status, error) {
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse CI status JSON: %w", err)
+	}
+	return &status, nil
+}
+
+// marshalStatus encodes a status, stamping it with the current time if unset
+func marshalStatus(status *Status) ([]byte, error) {
+	if status.Timestamp.IsZero() {
+		status.Timestamp = time.Now().UTC()
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CI status: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,200 @@
+package ci
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
+)
+
+// memoryObjects is an in-memory ObjectClient
+type memoryObjects struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	times   map[string]time.Time
+}
+
+func newMemoryObjects() *memoryObjects {
+	return &memoryObjects{objects: make(map[string][]byte), times: make(map[string]time.Time)}
+}
+
+func (m *memoryObjects) GetObject(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return data, nil
+}
+
+func (m *memoryObjects) PutObject(ctx context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	m.times[key] = time.Now()
+	return nil
+}
+
+func (m *memoryObjects) ListObjects(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var objects []storage.ObjectInfo
+	for key, data := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, storage.ObjectInfo{Key: key, Size: int64(len(data)), LastModified: m.times[key]})
+		}
+	}
+	return objects, nil
+}
+
+func (m *memoryObjects) DeleteObject(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func TestStatusStores(t *testing.T) {
+	stores := map[string]StatusStore{
+		"file":   &FileStatusStore{Dir: t.TempDir()},
+		"object": NewObjectStatusStore(newMemoryObjects(), ""),
+	}
+	if sqlite, err := NewSQLiteStatusStore(SQLiteConfig{Path: filepath.Join(t.TempDir(), "ci", "status.db")}); err == nil {
+		stores["sqlite"] = sqlite
+	} else {
+		t.Logf("Skipping the sqlite store: %v", err)
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Get("aaa"); !errors.Is(err, ErrStatusNotFound) {
+				t.Fatalf("Expected ErrStatusNotFound, got %v", err)
+			}
+
+			if err := store.Put(&Status{Ref: "refs/heads/agent-1/feat-1", Commit: "aaa", Status: "PASS"}); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			status, err := store.Get("aaa")
+			if err != nil || status.Status != "PASS" || status.Timestamp.IsZero() {
+				t.Fatalf("Expected a timestamped PASS, got %+v (err %v)", status, err)
+			}
+
+			entries, err := store.List()
+			if err != nil || len(entries) != 1 || entries[0].Commit != "aaa" || entries[0].Size == 0 {
+				t.Fatalf("Expected one entry for aaa, got %+v (err %v)", entries, err)
+			}
+
+			if err := store.Delete("aaa"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if err := store.Delete("aaa"); err != nil {
+				t.Errorf("Deleting a missing status should succeed, got %v", err)
+			}
+			if entries, _ := store.List(); len(entries) != 0 {
+				t.Errorf("Expected no entries after Delete, got %+v", entries)
+			}
+		})
+	}
+}
+
+func TestSQLiteStatusStoreQuotesValues(t *testing.T) {
+	store, err := NewSQLiteStatusStore(SQLiteConfig{Path: filepath.Join(t.TempDir(), "status.db")})
+	if err != nil {
+		t.Skipf("sqlite3 unavailable: %v", err)
+	}
+
+	commit := "x'); DROP TABLE ci_status; --"
+	if err := store.Put(&Status{Commit: commit, Status: "FAIL", Output: "it's | broken\nagain"}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	status, err := store.Get(commit)
+	if err != nil || status.Output != "it's | broken\nagain" {
+		t.Fatalf("Expected the status back verbatim, got %+v (err %v)", status, err)
+	}
+	if entries, err := store.List(); err != nil || len(entries) != 1 || entries[0].Commit != commit {
+		t.Errorf("Expected one entry for the odd commit, got %+v (err %v)", entries, err)
+	}
+}
+
+func TestStatusReaderOverObjectStore(t *testing.T) {
+	objects := newMemoryObjects()
+	store := NewObjectStatusStore(objects, "team/ci-status")
+	reader := NewStoreStatusReader(store)
+	now := time.Now().UTC()
+
+	for _, s := range []*Status{
+		{Ref: "refs/heads/agent-1/feat-1", Commit: "aaa", Status: "FAIL", Timestamp: now.Add(-72 * time.Hour)},
+		{Ref: "refs/heads/agent-2/feat-1", Commit: "bbb", Status: "PASS", Timestamp: now.Add(-48 * time.Hour)},
+		{Ref: "refs/heads/agent-2/feat-1", Commit: "ccc", Status: "PASS", Timestamp: now},
+	} {
+		if err := store.Put(s); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	// Objects outside the store's prefix are ignored
+	objects.PutObject(context.Background(), "team/ci/feat-1/aaa.json", []byte("{}"))
+
+	statuses, err := reader.GetByTicket("feat-1")
+	if err != nil || len(statuses) != 3 || statuses[0].Commit != "aaa" {
+		t.Fatalf("Expected three attempts for feat-1, got %v (err %v)", statuses, err)
+	}
+	if _, err := reader.GetStatus("zzz"); err == nil || err.Error() != "CI status not found for commit zzz" {
+		t.Errorf("Unexpected error for a missing status: %v", err)
+	}
+
+	// aaa is old but still the latest status of agent-1/feat-1
+	removed, err := reader.Prune(24 * time.Hour)
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected one old status pruned, got %d (err %v)", removed, err)
+	}
+	if _, ok := objects.objects["team/ci-status/bbb.json"]; ok || len(objects.objects) != 3 {
+		t.Errorf("Expected only bbb removed, got %v", objects.objects)
+	}
+}
+
+func TestPublishStatus(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteStatus(dir, &Status{Ref: "refs/heads/agent-1/feat-1", Commit: "aaa", Status: "FAIL"}); err != nil {
+		t.Fatalf("WriteStatus failed: %v", err)
+	}
+
+	store := NewObjectStatusStore(newMemoryObjects(), "")
+	if err := PublishStatus(store, dir, "aaa"); err != nil {
+		t.Fatalf("PublishStatus failed: %v", err)
+	}
+	if status, err := store.Get("aaa"); err != nil || status.Status != "FAIL" {
+		t.Errorf("Expected the published FAIL, got %+v (err %v)", status, err)
+	}
+
+	if err := PublishStatus(store, dir, "bbb"); !errors.Is(err, ErrStatusNotFound) {
+		t.Errorf("Expected ErrStatusNotFound for an unknown commit, got %v", err)
+	}
+	// The status directory itself and no store are both no-ops
+	if err := PublishStatus(&FileStatusStore{Dir: dir + "/"}, dir, "bbb"); err != nil {
+		t.Errorf("Publishing to the status directory should do nothing, got %v", err)
+	}
+	if err := PublishStatus(nil, dir, "bbb"); err != nil {
+		t.Errorf("Publishing without a store should do nothing, got %v", err)
+	}
+}
+
+func TestStoreConfigValidate(t *testing.T) {
+	for _, config := range []StoreConfig{
+		{Backend: "postgres"},
+		{Backend: StoreSQLite},
+		{Backend: StoreS3, S3: storage.S3Config{Bucket: "ci"}},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Config %+v should be rejected", config)
+		}
+	}
+	if err := (StoreConfig{}).Validate(); err != nil {
+		t.Errorf("The default file backend should be valid, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package ci
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+)
+
+// TestFlags tunes how ci.sh invokes go test
+// Zero values leave go test's own defaults in place
+type TestFlags struct {
+	P        int `mapstructure:"p"`        // go test -p: packages built and tested at once
+	Parallel int `mapstructure:"parallel"` // go test -parallel: t.Parallel tests run at once per package
+	Count    int `mapstructure:"count"`    // go test -count; 1 disables the test cache
+	Shards   int `mapstructure:"shards"`   // Concurrent go test processes over a split package list; -1 uses one per CPU core
+}
+
+// Validate rejects negative flags
+func (f TestFlags) Validate() error {
+	if f.P < 0 || f.Parallel < 0 || f.Count < 0 {
+		return errors.New("p, parallel and count cannot be negative")
+	}
+	if f.Shards < -1 {
+		return errors.New("shards must be -1 (one per CPU core), 0 or positive")
+	}
+	return nil
+}
+
+// shards returns the number of go test processes to run
+func (f TestFlags) shards() int {
+	if f.Shards < 0 {
+		return runtime.NumCPU()
+	}
+	return f.Shards
+}
+
+// env returns the variables ci.sh reads the flags from
+func (f TestFlags) env() []string {
+	var env []string
+	if f.P > 0 {
+		env = append(env, "CI_TEST_P="+strconv.Itoa(f.P))
+	}
+	if f.Parallel > 0 {
+		env = append(env, "CI_TEST_PARALLEL="+strconv.Itoa(f.Parallel))
+	}
+	if f.Count > 0 {
+		env = append(env, "CI_TEST_COUNT="+strconv.Itoa(f.Count))
+	}
+	if shards := f.shards(); shards > 1 {
+		env = append(env, "CI_TEST_SHARDS="+strconv.Itoa(shards))
+	}
+	return env
+}
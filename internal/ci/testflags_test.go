@@ -0,0 +1,43 @@
+package ci
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestTestFlags_Env(t *testing.T) {
+	if env := (TestFlags{}).env(); len(env) != 0 {
+		t.Errorf("Expected no variables for zero flags, got %v", env)
+	}
+
+	flags := TestFlags{P: 4, Parallel: 8, Count: 1, Shards: 3}
+	want := []string{"CI_TEST_P=4", "CI_TEST_PARALLEL=8", "CI_TEST_COUNT=1", "CI_TEST_SHARDS=3"}
+	if env := flags.env(); !reflect.DeepEqual(env, want) {
+		t.Errorf("env() = %v, want %v", env, want)
+	}
+
+	// -1 shards across every CPU core
+	auto := TestFlags{Shards: -1}
+	if got := auto.shards(); got != runtime.NumCPU() {
+		t.Errorf("Expected %d shards, got %d", runtime.NumCPU(), got)
+	}
+	if runtime.NumCPU() > 1 {
+		want := []string{"CI_TEST_SHARDS=" + strconv.Itoa(runtime.NumCPU())}
+		if env := auto.env(); !reflect.DeepEqual(env, want) {
+			t.Errorf("env() = %v, want %v", env, want)
+		}
+	}
+}
+
+func TestTestFlags_Validate(t *testing.T) {
+	if err := (TestFlags{P: 2, Shards: -1}).Validate(); err != nil {
+		t.Errorf("Expected valid flags, got %v", err)
+	}
+	for _, flags := range []TestFlags{{P: -1}, {Parallel: -1}, {Count: -1}, {Shards: -2}} {
+		if err := flags.Validate(); err == nil {
+			t.Errorf("Expected error for %+v, got nil", flags)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package ci
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// CI tiers selected through Run.Tier; an empty tier runs the full suite
+// without tiering
+const (
+	TierQuick = "quick" // go vet and go test -short; gates the agent loop
+	TierFull  = "full"  // The whole suite, run after the ticket completes; gates the merge
+)
+
+// FullStatusDir is where full tier statuses are written, beside the quick
+// tier's in statusDir
+func FullStatusDir(statusDir string) string {
+	return filepath.Join(statusDir, TierFull)
+}
+
+// tierEnv returns the variables ci.sh reads the tier from
+func tierEnv(tier string) []string {
+	if tier == "" {
+		return nil
+	}
+	return []string{"CI_TIER=" + tier}
+}
+
+// FullTier runs the full suite for completed tickets whose agent loop was
+// gated by the quick tier, one run at a time, and records the results in
+// FullStatusDir
+type FullTier struct {
+	ctx          context.Context
+	backend      Backend
+	repoPath     string
+	environments environment.Environments
+	profiles     []Profile
+	quick        *StatusReader
+	statusDir    string
+	onResult     func(ipc.CIFullTierEvent) // Optional
+
+	mu      sync.Mutex // Serializes runs
+	pending sync.WaitGroup
+}
+
+// NewFullTier creates a runner for tickets on the repository at repoPath,
+// or on their environment's repository; runs stop once ctx is done
+func NewFullTier(ctx context.Context, backend Backend, repoPath string, environments environment.Environments, profiles []Profile, statusDir string) *FullTier {
+	return &FullTier{
+		ctx:          ctx,
+		backend:      backend,
+		repoPath:     repoPath,
+		environments: environments,
+		profiles:     profiles,
+		quick:        NewStatusReader(statusDir),
+		statusDir:    FullStatusDir(statusDir),
+	}
+}
+
+// SetResultHandler sets a function called with each full tier result
+func (f *FullTier) SetResultHandler(handler func(ipc.CIFullTierEvent)) {
+	f.onResult = handler
+}
+
+// Check starts the full tier for a completed ticket's branch in the
+// background, so the worker that finished the ticket can move on while the
+// suite runs
+func (f *FullTier) Check(event ipc.Event) error {
+	data, ok := event.Data.(ipc.TicketEvent)
+	if !ok || event.Type != ipc.EventTypeTicketComplete || data.Ticket == nil || data.Ticket.Branch == "" {
+		return nil
+	}
+	t := data.Ticket
+
+	run := Run{RepoPath: f.repoPath, Branch: t.Branch, TicketID: t.ID, Tier: TierFull}
+	settings, err := f.environments.Lookup(t.Environment)
+	if err == nil && settings.Repository != "" {
+		run.RepoPath = settings.Repository
+	}
+	run.Profile = SelectProfile(f.profiles, t.Tags)
+	if err == nil && settings.CIProfile != "" {
+		for i := range f.profiles {
+			if f.profiles[i].Name == settings.CIProfile {
+				run.Profile = &f.profiles[i]
+				break
+			}
+		}
+	}
+	run.Commit, err = gitutils.NewRepo(run.RepoPath).GetBranchCommit(t.Branch)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s for the full CI tier: %w", t.Branch, err)
+	}
+
+	f.pending.Add(1)
+	go func() {
+		defer f.pending.Done()
+		f.run(t, run)
+	}()
+	return nil
+}
+
+// run runs the full tier for one ticket, copying a skipped quick tier's
+// verdict instead of running CI the ticket didn't need
+func (f *FullTier) run(t *ticket.Ticket, run Run) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ctx.Err() != nil {
+		return
+	}
+
+	if quick, err := f.quick.GetStatus(run.Commit); err == nil && quick.Status == "SKIPPED" {
+		reason := strings.TrimPrefix(quick.Output, "CI skipped: ")
+		if err := WriteSkipped(f.statusDir, run, reason); err != nil {
+			log.Printf("Failed to record full CI tier for %s: %v", t.ID, err)
+			return
+		}
+	} else {
+		log.Printf("Running full CI tier for %s at %s", run.Branch, run.Commit[:8])
+		if err := f.backend.Run(f.ctx, run); err != nil {
+			log.Printf("Full CI tier for %s failed to run: %v", t.ID, err)
+			if f.ctx.Err() != nil {
+				return
+			}
+			status := &Status{Ref: "refs/heads/" + run.Branch, Commit: run.Commit, TicketID: t.ID, Status: "FAIL", Tier: TierFull, Profile: run.Profile.name(), Output: err.Error()}
+			if err := WriteStatus(f.statusDir, status); err != nil {
+				log.Printf("Failed to record full CI tier for %s: %v", t.ID, err)
+				return
+			}
+		}
+	}
+
+	status, err := NewStatusReader(f.statusDir).GetStatus(run.Commit)
+	if err != nil {
+		log.Printf("Full CI tier for %s left no status: %v", t.ID, err)
+		return
+	}
+	message := fmt.Sprintf("full CI %s for %s; ready to merge", status.Status, run.Branch)
+	if !status.Passed() {
+		message = fmt.Sprintf("full CI %s for %s; not ready to merge", status.Status, run.Branch)
+	}
+	log.Printf("Ticket %s: %s", t.ID, message)
+	if f.onResult != nil {
+		f.onResult(ipc.CIFullTierEvent{Ticket: t, Commit: run.Commit, Status: status.Status, Passed: status.Passed(), Message: message})
+	}
+}
+
+// Wait blocks until every started run has finished
+func (f *FullTier) Wait() {
+	f.pending.Wait()
+}
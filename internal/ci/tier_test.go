@@ -0,0 +1,118 @@
+package ci
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// tierBackend records the full tier's verdict where the local backend would
+type tierBackend struct {
+	statusDir string
+	status    string
+	runs      []Run
+}
+
+func (b *tierBackend) Run(ctx context.Context, run Run) error {
+	b.runs = append(b.runs, run)
+	if b.status == "" {
+		return errors.New("ci.sh not found")
+	}
+	return WriteStatus(FullStatusDir(b.statusDir), &Status{Ref: "refs/heads/" + run.Branch, Commit: run.Commit, Status: b.status, Tier: run.Tier})
+}
+
+func TestFullTier(t *testing.T) {
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+	repoPath := filepath.Join(t.TempDir(), "repo.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init repo: %v", err)
+	}
+	repo := gitutils.NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	branch, _ := repo.MainBranch()
+	tip, _ := repo.GetBranchCommit(branch)
+
+	profiles := []Profile{{Name: "frontend", Tags: []string{"frontend"}, Command: "npm test"}}
+	completed := func(tags ...string) ipc.Event {
+		return ipc.Event{Type: ipc.EventTypeTicketComplete, Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-1", Branch: branch, Tags: tags}}}
+	}
+
+	tests := []struct {
+		name       string
+		backend    string // Status the backend records; "" fails to run
+		quick      string // Quick tier status already recorded
+		wantStatus string
+		wantRuns   int
+	}{
+		{"passes", "PASS", "PASS", "PASS", 1},
+		{"fails", "FAIL", "PASS", "FAIL", 1},
+		{"does not run", "", "PASS", "FAIL", 1},
+		{"quick tier skipped", "PASS", "SKIPPED", "SKIPPED", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusDir := filepath.Join(t.TempDir(), "ci-status")
+			run := Run{Branch: branch, Commit: tip}
+			if tt.quick == "SKIPPED" {
+				if err := WriteSkipped(statusDir, run, "ticket sets skip_ci"); err != nil {
+					t.Fatalf("Failed to write quick status: %v", err)
+				}
+			} else if err := WriteStatus(statusDir, &Status{Ref: "refs/heads/" + branch, Commit: tip, Status: tt.quick, Tier: TierQuick}); err != nil {
+				t.Fatalf("Failed to write quick status: %v", err)
+			}
+
+			backend := &tierBackend{statusDir: statusDir, status: tt.backend}
+			full := NewFullTier(context.Background(), backend, repoPath, nil, profiles, statusDir)
+			var results []ipc.CIFullTierEvent
+			full.SetResultHandler(func(event ipc.CIFullTierEvent) { results = append(results, event) })
+
+			if err := full.Check(completed("frontend")); err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			full.Wait()
+
+			if len(backend.runs) != tt.wantRuns {
+				t.Fatalf("Expected %d runs, got %d", tt.wantRuns, len(backend.runs))
+			}
+			if tt.wantRuns > 0 && (backend.runs[0].Tier != TierFull || backend.runs[0].Profile == nil || backend.runs[0].Profile.Name != "frontend") {
+				t.Errorf("Expected a full tier run with the ticket's profile, got %+v", backend.runs[0])
+			}
+			if len(results) != 1 || results[0].Status != tt.wantStatus || results[0].Commit != tip {
+				t.Fatalf("Expected one %s result, got %+v", tt.wantStatus, results)
+			}
+			if results[0].Passed != (tt.wantStatus != "FAIL") {
+				t.Errorf("Expected passed=%v for %s", tt.wantStatus != "FAIL", tt.wantStatus)
+			}
+
+			// The quick tier's status is left alone
+			quick, err := NewStatusReader(statusDir).GetStatus(tip)
+			if err != nil || quick.Status != tt.quick {
+				t.Errorf("Expected the quick tier's %s status to remain, got %+v (%v)", tt.quick, quick, err)
+			}
+			if status, err := NewStatusReader(FullStatusDir(statusDir)).GetStatus(tip); err != nil || status.Tier != TierFull {
+				t.Errorf("Expected a full tier status, got %+v (%v)", status, err)
+			}
+		})
+	}
+
+	// Other events don't start the full tier
+	backend := &tierBackend{statusDir: t.TempDir(), status: "PASS"}
+	full := NewFullTier(context.Background(), backend, repoPath, nil, nil, backend.statusDir)
+	if err := full.Check(ipc.Event{Type: ipc.EventTypeTicketFailed, Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-2", Branch: branch}}}); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	full.Wait()
+	if len(backend.runs) != 0 {
+		t.Errorf("Expected no runs for a failed ticket, got %d", len(backend.runs))
+	}
+}
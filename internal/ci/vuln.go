@@ -0,0 +1,78 @@
+package ci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SeverityCritical is the severity that fails tickets with FailOnNew
+const SeverityCritical = "critical"
+
+// VulnScanConfig runs dependency vulnerability scanners in ci.sh:
+// govulncheck for Go modules, npm audit for package-lock.json and cargo
+// audit for Cargo.lock; scanners that aren't installed are skipped
+type VulnScanConfig struct {
+	Enabled   bool `mapstructure:"enabled"`     // Local backend only; findings are recorded in the CI status
+	FailOnNew bool `mapstructure:"fail_on_new"` // Fail tickets whose CI finds critical vulnerabilities not found on main
+}
+
+// Vulnerability is one finding of a dependency scanner
+type Vulnerability struct {
+	Tool     string `json:"tool"`     // govulncheck, npm or cargo
+	ID       string `json:"id"`       // Advisory ID, e.g. GO-2024-2687 or RUSTSEC-2024-0001
+	Package  string `json:"package"`  // Affected module, package or crate
+	Severity string `json:"severity"` // low, moderate, high, critical or unknown
+}
+
+// String formats the finding for failure messages
+func (v Vulnerability) String() string {
+	return fmt.Sprintf("%s %s in %s (%s)", v.Tool, v.ID, v.Package, v.Severity)
+}
+
+// key identifies a finding across scans of different commits
+func (v Vulnerability) key() string {
+	return v.Tool + "\x00" + v.ID + "\x00" + v.Package
+}
+
+// env returns the variables ci.sh reads the scan settings from
+func (c VulnScanConfig) env() []string {
+	if !c.Enabled {
+		return nil
+	}
+	return []string{"CI_VULN_SCAN=1"}
+}
+
+// NewCritical returns the critical findings of status that baseline, e.g.
+// main's latest status, doesn't have; without a baseline every critical
+// finding is new
+func NewCritical(status, baseline *Status) []Vulnerability {
+	known := make(map[string]bool)
+	if baseline != nil {
+		for _, v := range baseline.Vulnerabilities {
+			known[v.key()] = true
+		}
+	}
+
+	var found []Vulnerability
+	for _, v := range status.Vulnerabilities {
+		if strings.EqualFold(v.Severity, SeverityCritical) && !known[v.key()] {
+			found = append(found, v)
+		}
+	}
+	return found
+}
+
+// mergeVulnerabilities appends the findings of more that all doesn't have yet
+func mergeVulnerabilities(all, more []Vulnerability) []Vulnerability {
+	seen := make(map[string]bool, len(all))
+	for _, v := range all {
+		seen[v.key()] = true
+	}
+	for _, v := range more {
+		if !seen[v.key()] {
+			seen[v.key()] = true
+			all = append(all, v)
+		}
+	}
+	return all
+}
@@ -0,0 +1,47 @@
+package ci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVulnScanConfig_Env(t *testing.T) {
+	if env := (VulnScanConfig{FailOnNew: true}).env(); len(env) != 0 {
+		t.Errorf("Expected no variables while disabled, got %v", env)
+	}
+	want := []string{"CI_VULN_SCAN=1"}
+	if env := (VulnScanConfig{Enabled: true}).env(); !reflect.DeepEqual(env, want) {
+		t.Errorf("env() = %v, want %v", env, want)
+	}
+}
+
+func TestNewCritical(t *testing.T) {
+	netVuln := Vulnerability{Tool: "govulncheck", ID: "GO-2024-2687", Package: "golang.org/x/net", Severity: "critical"}
+	lodash := Vulnerability{Tool: "npm", ID: "https://github.com/advisories/GHSA-p6mc-m468-83gw", Package: "lodash", Severity: "Critical"}
+	minor := Vulnerability{Tool: "cargo", ID: "RUSTSEC-2024-0001", Package: "time", Severity: "low"}
+	status := &Status{Vulnerabilities: []Vulnerability{netVuln, lodash, minor}}
+
+	// Without a baseline every critical finding is new
+	if got := NewCritical(status, nil); !reflect.DeepEqual(got, []Vulnerability{netVuln, lodash}) {
+		t.Errorf("NewCritical without baseline = %v", got)
+	}
+
+	// Findings main already has are not the ticket's
+	main := &Status{Vulnerabilities: []Vulnerability{netVuln}}
+	if got := NewCritical(status, main); !reflect.DeepEqual(got, []Vulnerability{lodash}) {
+		t.Errorf("NewCritical against main = %v, want only %v", got, lodash)
+	}
+
+	if got := NewCritical(&Status{}, main); len(got) != 0 {
+		t.Errorf("Expected no findings for a clean status, got %v", got)
+	}
+}
+
+func TestMergeVulnerabilities(t *testing.T) {
+	a := Vulnerability{Tool: "govulncheck", ID: "GO-1", Package: "m", Severity: "critical"}
+	b := Vulnerability{Tool: "npm", ID: "GHSA-1", Package: "p", Severity: "high"}
+	merged := mergeVulnerabilities(mergeVulnerabilities(nil, []Vulnerability{a}), []Vulnerability{a, b})
+	if !reflect.DeepEqual(merged, []Vulnerability{a, b}) {
+		t.Errorf("mergeVulnerabilities = %v, want each finding once", merged)
+	}
+}
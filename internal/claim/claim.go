@@ -0,0 +1,284 @@
+package claim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// RefPrefix is where claims are stored in the shared bare repository
+const RefPrefix = "refs/orchestrator/claims/"
+
+// swapAttempts bounds retries when a ref update fails without the ref changing
+const swapAttempts = 3
+
+// Claim records which daemon owns a ticket
+type Claim struct {
+	TicketID  string    `json:"ticket_id"`
+	Node      string    `json:"node"`
+	ClaimedAt time.Time `json:"claimed_at"`
+	RenewedAt time.Time `json:"renewed_at"`
+	Done      bool      `json:"done,omitempty"` // Finished claims never expire
+}
+
+// Expired reports whether the owner stopped renewing the claim
+func (c Claim) Expired(lease time.Duration, now time.Time) bool {
+	return !c.Done && now.Sub(c.RenewedAt) > lease
+}
+
+// Claimer grants one daemon at a time ownership of a ticket
+// Claims are refs in the bare repository that every daemon shares, updated
+// with compare-and-swap so concurrent claims cannot both succeed
+// A nil *Claimer owns every ticket
+type Claimer struct {
+	repo  *gitutils.GitRepo
+	node  string
+	lease time.Duration
+	now   func() time.Time
+
+	mu   sync.Mutex
+	held map[string]string // Ticket ID to the hash of this node's claim
+}
+
+// New returns a claimer for node; claims not renewed within lease may be
+// taken over by other nodes
+func New(repoPath, node string, lease time.Duration) *Claimer {
+	return &Claimer{
+		repo:  gitutils.NewRepo(repoPath),
+		node:  node,
+		lease: lease,
+		now:   time.Now,
+		held:  make(map[string]string),
+	}
+}
+
+// Node returns the name this claimer records in its claims
+func (c *Claimer) Node() string {
+	if c == nil {
+		return ""
+	}
+	return c.node
+}
+
+// Claim tries to take ownership of a ticket and reports whether this node
+// now owns it; a claim this node already holds is adopted, e.g. after a restart
+func (c *Claimer) Claim(ticketID string) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ref := RefPrefix + ticketID
+	current, err := c.repo.ResolveRef(ref)
+	if err != nil {
+		return false, err
+	}
+
+	if current != "" {
+		existing, err := c.read(current)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case existing.Done:
+			return false, nil
+		case existing.Node == c.node:
+			c.held[ticketID] = current
+			return true, nil
+		case !existing.Expired(c.lease, c.now()):
+			return false, nil
+		}
+		log.Printf("Taking over expired claim on %s from %s", ticketID, existing.Node)
+	}
+
+	now := c.now().UTC()
+	hash, err := c.write(Claim{TicketID: ticketID, Node: c.node, ClaimedAt: now, RenewedAt: now})
+	if err != nil {
+		return false, err
+	}
+	if ok, err := c.swap(ref, hash, current); !ok {
+		return false, err
+	}
+
+	c.held[ticketID] = hash
+	return true, nil
+}
+
+// Renew extends the lease on every claim this node holds and returns the
+// tickets whose claims were lost to another node; a claim that fails to
+// renew doesn't stop the rest, and the failures are returned joined
+func (c *Claimer) Renew() ([]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lost []string
+	var errs []error
+	for ticketID, hash := range c.held {
+		claim, err := c.read(hash)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to renew claim on %s: %w", ticketID, err))
+			continue
+		}
+		claim.RenewedAt = c.now().UTC()
+
+		newHash, err := c.write(claim)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to renew claim on %s: %w", ticketID, err))
+			continue
+		}
+		ok, err := c.swap(RefPrefix+ticketID, newHash, hash)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to renew claim on %s: %w", ticketID, err))
+			continue
+		}
+		if !ok {
+			delete(c.held, ticketID)
+			lost = append(lost, ticketID)
+			continue
+		}
+		c.held[ticketID] = newHash
+	}
+	sort.Strings(lost)
+	return lost, errors.Join(errs...)
+}
+
+// Complete marks a held claim as done so no other node ever picks the ticket up
+func (c *Claimer) Complete(ticketID string) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash, ok := c.held[ticketID]
+	if !ok {
+		return nil
+	}
+	claim, err := c.read(hash)
+	if err != nil {
+		return err
+	}
+	claim.Done = true
+	claim.RenewedAt = c.now().UTC()
+
+	newHash, err := c.write(claim)
+	if err != nil {
+		return err
+	}
+	ok, err = c.swap(RefPrefix+ticketID, newHash, hash)
+	if err != nil {
+		return err
+	}
+	delete(c.held, ticketID)
+	if !ok {
+		return fmt.Errorf("claim on %s was taken over by another node", ticketID)
+	}
+	return nil
+}
+
+// Release gives up a held claim so any node may claim the ticket again, e.g.
+// after it failed for good; a claim another node took over is left alone
+func (c *Claimer) Release(ticketID string) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash, ok := c.held[ticketID]
+	if !ok {
+		return nil
+	}
+	delete(c.held, ticketID)
+	ref := RefPrefix + ticketID
+	if err := c.repo.DeleteRef(ref, hash); err != nil {
+		after, resolveErr := c.repo.ResolveRef(ref)
+		if resolveErr == nil && after != hash {
+			return fmt.Errorf("claim on %s was taken over by another node", ticketID)
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns every claim in the repository, ordered by ticket ID
+func List(repoPath string) ([]Claim, error) {
+	repo := gitutils.NewRepo(repoPath)
+	refs, err := repo.ListRefs(RefPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make([]Claim, 0, len(refs))
+	for ref, hash := range refs {
+		data, err := repo.ReadBlob(hash)
+		if err != nil {
+			return nil, err
+		}
+		var claim Claim
+		if err := json.Unmarshal(data, &claim); err != nil {
+			return nil, fmt.Errorf("invalid claim %s: %w", strings.TrimPrefix(ref, RefPrefix), err)
+		}
+		claims = append(claims, claim)
+	}
+	sort.Slice(claims, func(i, j int) bool { return claims[i].TicketID < claims[j].TicketID })
+	return claims, nil
+}
+
+// swap moves ref from oldHash to newHash, reporting false if another node
+// changed it first
+func (c *Claimer) swap(ref, newHash, oldHash string) (bool, error) {
+	var err error
+	for attempt := 0; attempt < swapAttempts; attempt++ {
+		if err = c.repo.UpdateRef(ref, newHash, oldHash); err == nil {
+			return true, nil
+		}
+		after, resolveErr := c.repo.ResolveRef(ref)
+		if resolveErr != nil {
+			return false, resolveErr
+		}
+		if after != oldHash {
+			return false, nil
+		}
+		// Unchanged ref: another node may still hold the ref lock
+		time.Sleep(50 * time.Millisecond)
+	}
+	return false, err
+}
+
+// read loads the claim stored in a blob
+func (c *Claimer) read(hash string) (Claim, error) {
+	var claim Claim
+	data, err := c.repo.ReadBlob(hash)
+	if err != nil {
+		return claim, err
+	}
+	if err := json.Unmarshal(data, &claim); err != nil {
+		return claim, fmt.Errorf("invalid claim %s: %w", hash, err)
+	}
+	return claim, nil
+}
+
+// write stores a claim as a blob and returns its hash
+func (c *Claimer) write(claim Claim) (string, error) {
+	data, err := json.Marshal(claim)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claim: %w", err)
+	}
+	return c.repo.HashObject(data)
+}
@@ -0,0 +1,198 @@
+package claim
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+	repoPath := filepath.Join(t.TempDir(), "shared.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	return repoPath
+}
+
+func TestClaimIsExclusive(t *testing.T) {
+	repoPath := newRepo(t)
+	a := New(repoPath, "node-a", time.Minute)
+	b := New(repoPath, "node-b", time.Minute)
+
+	if ok, err := a.Claim("feat-1"); err != nil || !ok {
+		t.Fatalf("Expected node-a to claim feat-1, got %v (err %v)", ok, err)
+	}
+	if ok, err := b.Claim("feat-1"); err != nil || ok {
+		t.Fatalf("Expected node-b to be refused, got %v (err %v)", ok, err)
+	}
+
+	// The owner re-claiming, e.g. after a restart, keeps the ticket
+	restarted := New(repoPath, "node-a", time.Minute)
+	if ok, err := restarted.Claim("feat-1"); err != nil || !ok {
+		t.Errorf("Expected node-a to adopt its own claim, got %v (err %v)", ok, err)
+	}
+
+	if err := a.Complete("feat-1"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if ok, _ := restarted.Claim("feat-1"); ok {
+		t.Error("Expected a completed ticket never to be claimed again")
+	}
+
+	claims, err := List(repoPath)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(claims) != 1 || claims[0].Node != "node-a" || !claims[0].Done {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+}
+
+func TestReleasedClaimCanBeClaimedAgain(t *testing.T) {
+	repoPath := newRepo(t)
+	a := New(repoPath, "node-a", time.Minute)
+	b := New(repoPath, "node-b", time.Minute)
+
+	if ok, err := a.Claim("feat-1"); err != nil || !ok {
+		t.Fatalf("Expected node-a to claim feat-1, got %v (err %v)", ok, err)
+	}
+	if err := a.Release("feat-1"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if ok, err := b.Claim("feat-1"); err != nil || !ok {
+		t.Fatalf("Expected node-b to claim the released ticket, got %v (err %v)", ok, err)
+	}
+	if err := a.Release("feat-1"); err != nil {
+		t.Errorf("Expected releasing a claim no longer held to do nothing, got %v", err)
+	}
+	if ok, _ := a.Claim("feat-1"); ok {
+		t.Error("Expected node-b's claim to survive node-a's release")
+	}
+}
+
+func TestConcurrentClaims(t *testing.T) {
+	repoPath := newRepo(t)
+
+	var wg sync.WaitGroup
+	results := make(chan string, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			ok, err := New(repoPath, node, time.Minute).Claim("feat-race")
+			if err != nil {
+				t.Errorf("%s: Claim failed: %v", node, err)
+			}
+			if ok {
+				results <- node
+			}
+		}(fmt.Sprintf("node-%d", i))
+	}
+	wg.Wait()
+	close(results)
+
+	var winners []string
+	for node := range results {
+		winners = append(winners, node)
+	}
+	if len(winners) != 1 {
+		t.Errorf("Expected exactly one node to win the claim, got %v", winners)
+	}
+}
+
+func TestExpiredClaimTakeover(t *testing.T) {
+	repoPath := newRepo(t)
+	now := time.Now()
+
+	a := New(repoPath, "node-a", time.Minute)
+	a.now = func() time.Time { return now }
+	b := New(repoPath, "node-b", time.Minute)
+	b.now = func() time.Time { return now.Add(30 * time.Second) }
+
+	if ok, _ := a.Claim("feat-1"); !ok {
+		t.Fatal("Expected node-a to claim feat-1")
+	}
+	if ok, _ := b.Claim("feat-1"); ok {
+		t.Fatal("Expected the lease to still be held")
+	}
+
+	// node-a stops renewing and its lease runs out
+	b.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if ok, err := b.Claim("feat-1"); err != nil || !ok {
+		t.Fatalf("Expected node-b to take over the expired claim, got %v (err %v)", ok, err)
+	}
+
+	lost, err := a.Renew()
+	if err != nil {
+		t.Fatalf("Renew failed: %v", err)
+	}
+	if len(lost) != 1 || lost[0] != "feat-1" {
+		t.Errorf("Expected node-a to learn it lost feat-1, got %v", lost)
+	}
+}
+
+func TestRenewKeepsLease(t *testing.T) {
+	repoPath := newRepo(t)
+	now := time.Now()
+
+	a := New(repoPath, "node-a", time.Minute)
+	a.now = func() time.Time { return now }
+	if ok, _ := a.Claim("feat-1"); !ok {
+		t.Fatal("Expected node-a to claim feat-1")
+	}
+
+	a.now = func() time.Time { return now.Add(50 * time.Second) }
+	if lost, err := a.Renew(); err != nil || len(lost) != 0 {
+		t.Fatalf("Renew returned %v (err %v)", lost, err)
+	}
+
+	b := New(repoPath, "node-b", time.Minute)
+	b.now = func() time.Time { return now.Add(90 * time.Second) }
+	if ok, _ := b.Claim("feat-1"); ok {
+		t.Error("Expected the renewed lease to keep node-b out")
+	}
+}
+
+func TestRenewContinuesPastErrors(t *testing.T) {
+	repoPath := newRepo(t)
+	now := time.Now()
+
+	a := New(repoPath, "node-a", time.Minute)
+	a.now = func() time.Time { return now }
+	for _, id := range []string{"feat-1", "feat-2"} {
+		if ok, _ := a.Claim(id); !ok {
+			t.Fatalf("Expected node-a to claim %s", id)
+		}
+	}
+	b := New(repoPath, "node-b", time.Minute)
+	b.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if ok, err := b.Claim("feat-2"); err != nil || !ok {
+		t.Fatalf("Expected node-b to take over feat-2, got %v (err %v)", ok, err)
+	}
+
+	// A claim that can't be read must not hide the lost one
+	a.held["feat-1"] = strings.Repeat("0", 40)
+	lost, err := a.Renew()
+	if err == nil || !strings.Contains(err.Error(), "feat-1") {
+		t.Errorf("Expected an error renewing feat-1, got %v", err)
+	}
+	if len(lost) != 1 || lost[0] != "feat-2" {
+		t.Errorf("Expected node-a to learn it lost feat-2, got %v", lost)
+	}
+}
+
+func TestNilClaimerOwnsEverything(t *testing.T) {
+	var c *Claimer
+	if ok, err := c.Claim("feat-1"); !ok || err != nil {
+		t.Errorf("Expected nil claimer to grant claims, got %v (err %v)", ok, err)
+	}
+	if err := c.Complete("feat-1"); err != nil {
+		t.Errorf("Complete failed: %v", err)
+	}
+}
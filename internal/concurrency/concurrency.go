@@ -0,0 +1,349 @@
+package concurrency
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// MetricsFile is the CSV in the metrics directory that records every trial
+const MetricsFile = "concurrency.csv"
+
+// header is the first row of MetricsFile
+var header = []string{"start", "end", "agents", "completed", "ci_runs", "ci_seconds", "starved"}
+
+// tolerance is how close to the best throughput a smaller pool must come to
+// be recommended; extra agents that add little throughput only add contention
+const tolerance = 0.95
+
+// Trial is a period run with a fixed number of active agents
+type Trial struct {
+	Start     time.Time
+	End       time.Time
+	Agents    int
+	Completed int           // Tickets completed during the trial
+	CIRuns    int           // CI runs that finished during the trial
+	CITime    time.Duration // Total time those runs took
+	Starved   bool          // The queue ran dry, so throughput was limited by demand
+}
+
+// PerHour returns the tickets completed per hour
+func (t Trial) PerHour() float64 {
+	hours := t.End.Sub(t.Start).Hours()
+	if hours <= 0 {
+		return 0
+	}
+	return float64(t.Completed) / hours
+}
+
+// AvgCI returns the average time a CI run took
+func (t Trial) AvgCI() time.Duration {
+	if t.CIRuns == 0 {
+		return 0
+	}
+	return t.CITime / time.Duration(t.CIRuns)
+}
+
+// Config controls an experiment
+type Config struct {
+	MinAgents  int
+	MaxAgents  int
+	Period     time.Duration // How long each trial runs
+	MetricsDir string        // Where trials are recorded
+
+	SetAgents func(n int) // Changes how many workers take tickets
+	Backlog   func() int  // Number of queued tickets, sampled to detect starvation
+}
+
+// Controller cycles the active worker count between the configured bounds,
+// recording the throughput and CI times of each trial
+type Controller struct {
+	config Config
+
+	mu      sync.Mutex
+	trial   *Trial
+	ciStart map[string]time.Time // Ticket ID to when its current CI run started
+}
+
+// New creates a controller; call Run to start the experiment
+func New(config Config) *Controller {
+	return &Controller{config: config, ciStart: make(map[string]time.Time)}
+}
+
+// Record handles an IPC event, counting completions and CI runs toward the
+// current trial
+func (c *Controller) Record(event ipc.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch data := event.Data.(type) {
+	case ipc.TicketPhaseEvent:
+		if data.Ticket == nil {
+			return
+		}
+		if data.Phase == "ci" {
+			c.ciStart[data.Ticket.ID] = event.Timestamp
+		} else if started, ok := c.ciStart[data.Ticket.ID]; ok {
+			c.finishCI(data.Ticket.ID, started, event.Timestamp)
+		}
+
+	case ipc.TicketEvent:
+		if data.Ticket == nil {
+			return
+		}
+		switch event.Type {
+		case ipc.EventTypeTicketComplete, ipc.EventTypeTicketFailed:
+			if started, ok := c.ciStart[data.Ticket.ID]; ok {
+				c.finishCI(data.Ticket.ID, started, event.Timestamp)
+			}
+			if event.Type == ipc.EventTypeTicketComplete && c.trial != nil {
+				c.trial.Completed++
+			}
+		}
+	}
+}
+
+// finishCI counts a CI run toward the current trial; callers hold c.mu
+func (c *Controller) finishCI(ticketID string, started, finished time.Time) {
+	delete(c.ciStart, ticketID)
+	if c.trial != nil {
+		c.trial.CIRuns++
+		c.trial.CITime += finished.Sub(started)
+	}
+}
+
+// Run cycles through the agent counts until ctx is cancelled
+// The trial in progress when it stops is discarded
+func (c *Controller) Run(ctx context.Context) {
+	sample := c.config.Period / 10
+	if sample > time.Minute {
+		sample = time.Minute
+	}
+	ticker := time.NewTicker(sample)
+	defer ticker.Stop()
+
+	for agents := c.config.MinAgents; ; agents = c.next(agents) {
+		c.begin(agents, time.Now())
+		log.Printf("Concurrency experiment: running %d agents for %v", agents, c.config.Period)
+
+		end := time.NewTimer(c.config.Period)
+	trial:
+		for {
+			select {
+			case <-ctx.Done():
+				end.Stop()
+				return
+			case <-ticker.C:
+				if c.config.Backlog != nil && c.config.Backlog() == 0 {
+					c.markStarved()
+				}
+			case <-end.C:
+				break trial
+			}
+		}
+
+		t := c.finish(time.Now())
+		log.Printf("Concurrency experiment: %d agents completed %d tickets (%.2f/hour), average CI %v",
+			t.Agents, t.Completed, t.PerHour(), t.AvgCI().Round(time.Second))
+		if err := Append(c.config.MetricsDir, t); err != nil {
+			log.Printf("Failed to record concurrency trial: %v", err)
+		}
+	}
+}
+
+// next returns the agent count to try after agents, wrapping at the bounds
+func (c *Controller) next(agents int) int {
+	if agents >= c.config.MaxAgents {
+		return c.config.MinAgents
+	}
+	return agents + 1
+}
+
+// begin starts a trial with the given number of agents
+func (c *Controller) begin(agents int, now time.Time) {
+	if c.config.SetAgents != nil {
+		c.config.SetAgents(agents)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trial = &Trial{Start: now, Agents: agents}
+}
+
+// markStarved notes that the current trial ran out of queued tickets
+func (c *Controller) markStarved() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.trial != nil {
+		c.trial.Starved = true
+	}
+}
+
+// finish ends the current trial and returns it
+func (c *Controller) finish(now time.Time) Trial {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := *c.trial
+	t.End = now
+	c.trial = nil
+	return t
+}
+
+// Append adds a trial to the metrics CSV
+func Append(metricsDir string, t Trial) error {
+	if err := os.MkdirAll(metricsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+
+	path := filepath.Join(metricsDir, MetricsFile)
+	_, statErr := os.Stat(path)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open concurrency metrics: %w", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if os.IsNotExist(statErr) {
+		w.Write(header)
+	}
+	w.Write([]string{
+		timefmt.Machine(t.Start),
+		timefmt.Machine(t.End),
+		strconv.Itoa(t.Agents),
+		strconv.Itoa(t.Completed),
+		strconv.Itoa(t.CIRuns),
+		strconv.Itoa(int(t.CITime.Seconds())),
+		strconv.FormatBool(t.Starved),
+	})
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write concurrency metrics: %w", err)
+	}
+	return nil
+}
+
+// Load reads every trial from the metrics CSV
+// A missing file means no experiment has run yet
+func Load(metricsDir string) ([]Trial, error) {
+	file, err := os.Open(filepath.Join(metricsDir, MetricsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open concurrency metrics: %w", err)
+	}
+	defer file.Close()
+
+	var trials []Trial
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = len(header)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read concurrency metrics: %w", err)
+		}
+		if record[0] == header[0] {
+			continue
+		}
+
+		start, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid trial start %q: %w", record[0], err)
+		}
+		end, err := time.Parse(time.RFC3339, record[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid trial end %q: %w", record[1], err)
+		}
+		agents, _ := strconv.Atoi(record[2])
+		completed, _ := strconv.Atoi(record[3])
+		ciRuns, _ := strconv.Atoi(record[4])
+		ciSeconds, _ := strconv.Atoi(record[5])
+		starved, _ := strconv.ParseBool(record[6])
+		trials = append(trials, Trial{
+			Start:     start,
+			End:       end,
+			Agents:    agents,
+			Completed: completed,
+			CIRuns:    ciRuns,
+			CITime:    time.Duration(ciSeconds) * time.Second,
+			Starved:   starved,
+		})
+	}
+	return trials, nil
+}
+
+// Stat aggregates the trials run with one agent count
+type Stat struct {
+	Agents    int
+	Trials    int
+	Hours     float64
+	Completed int
+	PerHour   float64
+	AvgCI     time.Duration
+}
+
+// Recommend aggregates trials per agent count, ignoring starved ones, and
+// picks the smallest count whose throughput comes within tolerance of the
+// best; ok is false until some trial has completed a ticket
+func Recommend(trials []Trial) (agents int, stats []Stat, ok bool) {
+	byAgents := make(map[int]*Stat)
+	ciRuns := make(map[int]int)
+	ciTime := make(map[int]time.Duration)
+	for _, t := range trials {
+		if t.Starved || !t.End.After(t.Start) {
+			continue
+		}
+		stat, found := byAgents[t.Agents]
+		if !found {
+			stat = &Stat{Agents: t.Agents}
+			byAgents[t.Agents] = stat
+		}
+		stat.Trials++
+		stat.Hours += t.End.Sub(t.Start).Hours()
+		stat.Completed += t.Completed
+		ciRuns[t.Agents] += t.CIRuns
+		ciTime[t.Agents] += t.CITime
+	}
+	if len(byAgents) == 0 {
+		return 0, nil, false
+	}
+
+	best := 0.0
+	for n, stat := range byAgents {
+		stat.PerHour = float64(stat.Completed) / stat.Hours
+		if ciRuns[n] > 0 {
+			stat.AvgCI = ciTime[n] / time.Duration(ciRuns[n])
+		}
+		if stat.PerHour > best {
+			best = stat.PerHour
+		}
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Agents < stats[j].Agents })
+	if best == 0 {
+		return 0, stats, false
+	}
+
+	for _, stat := range stats {
+		if stat.PerHour >= best*tolerance {
+			return stat.Agents, stats, true
+		}
+	}
+	return stats[len(stats)-1].Agents, stats, true
+}
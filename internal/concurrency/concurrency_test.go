@@ -0,0 +1,127 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestControllerRecordsTrial(t *testing.T) {
+	var active []int
+	c := New(Config{MinAgents: 1, MaxAgents: 2, SetAgents: func(n int) { active = append(active, n) }})
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	feat := func(id string) *ticket.Ticket { return &ticket.Ticket{ID: id} }
+
+	// CI that started before the trial still counts when it finishes during it
+	c.Record(ipc.Event{Type: ipc.EventTypeTicketPhase, Timestamp: at(-5), Data: ipc.TicketPhaseEvent{Ticket: feat("feat-0"), Phase: "ci"}})
+	c.begin(2, start)
+	events := []ipc.Event{
+		{Type: ipc.EventTypeTicketComplete, Timestamp: at(5), Data: ipc.TicketEvent{Ticket: feat("feat-0")}},
+		{Type: ipc.EventTypeTicketPhase, Timestamp: at(10), Data: ipc.TicketPhaseEvent{Ticket: feat("feat-1"), Phase: "ci"}},
+		{Type: ipc.EventTypeTicketPhase, Timestamp: at(16), Data: ipc.TicketPhaseEvent{Ticket: feat("feat-1"), Phase: "artifacts"}},
+		{Type: ipc.EventTypeTicketComplete, Timestamp: at(17), Data: ipc.TicketEvent{Ticket: feat("feat-1")}},
+		{Type: ipc.EventTypeTicketPhase, Timestamp: at(20), Data: ipc.TicketPhaseEvent{Ticket: feat("feat-2"), Phase: "ci"}},
+		{Type: ipc.EventTypeTicketFailed, Timestamp: at(30), Data: ipc.TicketEvent{Ticket: feat("feat-2")}},
+	}
+	for _, event := range events {
+		c.Record(event)
+	}
+	trial := c.finish(at(60))
+
+	if len(active) != 1 || active[0] != 2 {
+		t.Errorf("Expected the trial to set 2 agents, got %v", active)
+	}
+	if trial.Agents != 2 || trial.Completed != 2 || trial.CIRuns != 3 || trial.CITime != 26*time.Minute {
+		t.Errorf("Unexpected trial %+v", trial)
+	}
+	if trial.PerHour() != 2 || trial.AvgCI() != 26*time.Minute/3 {
+		t.Errorf("Unexpected rates %v/hour, avg CI %v", trial.PerHour(), trial.AvgCI())
+	}
+
+	if c.next(1) != 2 || c.next(2) != 1 {
+		t.Error("Expected counts to cycle between the bounds")
+	}
+}
+
+func TestRunAppendsTrials(t *testing.T) {
+	metricsDir := t.TempDir()
+	var mu sync.Mutex
+	var active []int
+	c := New(Config{
+		MinAgents:  1,
+		MaxAgents:  3,
+		Period:     20 * time.Millisecond,
+		MetricsDir: metricsDir,
+		SetAgents: func(n int) {
+			mu.Lock()
+			defer mu.Unlock()
+			active = append(active, n)
+		},
+		Backlog: func() int { return 0 },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		trials, err := Load(metricsDir)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if len(trials) >= 3 {
+			if trials[0].Agents != 1 || trials[1].Agents != 2 || trials[2].Agents != 3 {
+				t.Errorf("Expected trials of 1, 2 and 3 agents, got %+v", trials[:3])
+			}
+			if !trials[0].Starved {
+				t.Error("Expected an empty queue to mark the trial starved")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 3 trials, got %d", len(trials))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestRecommend(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(i int) time.Time { return start.Add(time.Duration(i) * time.Hour) }
+	trials := []Trial{
+		{Start: hour(0), End: hour(1), Agents: 1, Completed: 2, CIRuns: 2, CITime: 10 * time.Minute},
+		{Start: hour(1), End: hour(2), Agents: 2, Completed: 4, CIRuns: 4, CITime: 24 * time.Minute},
+		{Start: hour(2), End: hour(3), Agents: 3, Completed: 4, CIRuns: 4, CITime: 40 * time.Minute},
+		{Start: hour(3), End: hour(4), Agents: 1, Completed: 2},
+		{Start: hour(4), End: hour(5), Agents: 2, Completed: 5},
+		{Start: hour(5), End: hour(6), Agents: 3, Completed: 5},
+		// Starved trials say nothing about capacity
+		{Start: hour(6), End: hour(7), Agents: 3, Completed: 0, Starved: true},
+	}
+
+	agents, stats, ok := Recommend(trials)
+	if !ok || agents != 2 {
+		t.Fatalf("Expected 2 agents to be recommended, got %d (ok %v)", agents, ok)
+	}
+	if len(stats) != 3 || stats[2].Agents != 3 || stats[2].Trials != 2 || stats[2].PerHour != 4.5 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats[1].AvgCI != 6*time.Minute || stats[2].AvgCI != 10*time.Minute {
+		t.Errorf("Unexpected CI averages %v, %v", stats[1].AvgCI, stats[2].AvgCI)
+	}
+
+	if _, _, ok := Recommend(trials[6:]); ok {
+		t.Error("Expected no recommendation from starved trials")
+	}
+}
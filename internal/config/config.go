@@ -3,66 +3,251 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/brettsmith212/amp-orchestrator/internal/backlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/chaos"
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/conflict"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/eventlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/github"
+	"github.com/brettsmith212/amp-orchestrator/internal/i18n"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/kube"
+	"github.com/brettsmith212/amp-orchestrator/internal/limits"
+	"github.com/brettsmith212/amp-orchestrator/internal/policy"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/quota"
+	"github.com/brettsmith212/amp-orchestrator/internal/report"
+	"github.com/brettsmith212/amp-orchestrator/internal/rules"
+	"github.com/brettsmith212/amp-orchestrator/internal/signing"
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
+	"github.com/brettsmith212/amp-orchestrator/internal/summary"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticketlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+	"github.com/brettsmith212/amp-orchestrator/internal/verify"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
 	"github.com/spf13/viper"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Repository RepositoryConfig `mapstructure:"repository"`
-	Agents     AgentConfig      `mapstructure:"agents"`
-	Scheduler  SchedulerConfig  `mapstructure:"scheduler"`
-	CI         CIConfig         `mapstructure:"ci"`
-	IPC        IPCConfig        `mapstructure:"ipc"`
-	Metrics    MetricsConfig    `mapstructure:"metrics"`
-	Testing    TestingConfig    `mapstructure:"testing"`
+	Repository   RepositoryConfig   `mapstructure:"repository"`
+	Agents       AgentConfig        `mapstructure:"agents"`
+	Scheduler    SchedulerConfig    `mapstructure:"scheduler"`
+	CI           CIConfig           `mapstructure:"ci"`
+	IPC          IPCConfig          `mapstructure:"ipc"`
+	Metrics      MetricsConfig      `mapstructure:"metrics"`
+	Testing      TestingConfig      `mapstructure:"testing"`
+	State        StateConfig        `mapstructure:"state"`
+	Validation   ValidationConfig   `mapstructure:"validation"`
+	Policy       policy.Policy      `mapstructure:"policy"`
+	Signing      signing.Config     `mapstructure:"signing"` // Public keys trusted to sign tickets
+	Templating   ticket.Templating  `mapstructure:"templating"` // Variables for ticket description placeholders
+	Artifacts    storage.Config     `mapstructure:"artifacts"`
+	Storage      StorageConfig      `mapstructure:"storage"`
+	Encryption   encryption.Config  `mapstructure:"encryption"`
+	Coordination CoordinationConfig `mapstructure:"coordination"`
+	Remote       RemoteConfig       `mapstructure:"remote"`
+	Dashboard    DashboardConfig    `mapstructure:"dashboard"`
+	API          APIConfig          `mapstructure:"api"`
+	Rules        []rules.Rule       `mapstructure:"rules"` // Reactions to daemon events
+	Verify       verify.Config      `mapstructure:"verify"` // Definition of done checks run after tickets are merged
+	Conflicts    conflict.Config    `mapstructure:"conflicts"` // Resolution tickets for completed branches that conflict with main
+	Integrations IntegrationsConfig `mapstructure:"integrations"`
+	Reports      report.Config      `mapstructure:"reports"` // Markdown record of each completed ticket
+	TicketLogs   ticketlog.Config   `mapstructure:"ticket_logs"` // Commands each ticket ran, with their output
+	EventLog     eventlog.Config    `mapstructure:"event_log"` // Every IPC event kept in the state directory for replay
+	Time         timefmt.Config     `mapstructure:"time"`      // Timezone and layouts for timestamps shown to people
+	Locale       string             `mapstructure:"locale"`    // Language of CLI and TUI messages; empty follows ORCHESTRATOR_LOCALE, then LANG
+
+	Environments environment.Environments `mapstructure:"environments"` // Settings for tickets naming an environment
+
+	Root string `mapstructure:"-"` // Directory of config.yaml; relative paths are resolved against it
 }
 
 // RepositoryConfig holds git repository settings
 type RepositoryConfig struct {
-	Path    string `mapstructure:"path"`
-	Workdir string `mapstructure:"workdir"`
+	Path                 string `mapstructure:"path"`
+	Workdir              string `mapstructure:"workdir"`
+	ScratchRetentionDays int    `mapstructure:"scratch_retention_days"` // 0 keeps ticket scratch directories forever
 }
 
 // AgentConfig holds agent settings
 type AgentConfig struct {
-	Count   int `mapstructure:"count"`
-	Timeout int `mapstructure:"timeout"`
+	Count       int             `mapstructure:"count"`
+	Timeout     int             `mapstructure:"timeout"`
+	MaxFailures int             `mapstructure:"max_failures"` // Tickets failing in a row before a worker stops; 0 disables
+	RetryBranch string          `mapstructure:"retry_branch"` // reset or attempt: what a retry does with the earlier attempt's branch
+	RateLimit   RateLimitConfig `mapstructure:"rate_limit"`
+	Limits      limits.Limits   `mapstructure:"limits"`     // Resource limits for agent and CI processes
+	Backend     string          `mapstructure:"backend"`    // local or kubernetes
+	Kubernetes  kube.Config     `mapstructure:"kubernetes"` // Job settings for the kubernetes backend
+
+	Experiment ConcurrencyExperimentConfig `mapstructure:"experiment"` // Replaces count with a varying number of agents
+
+	Housekeeping worker.HousekeepingConfig `mapstructure:"housekeeping"` // Chores idle workers run between tickets
+
+	RegressionTests worker.RegressionTestConfig `mapstructure:"regression_tests"` // Fixes for bug tickets must add or change a test
+	Commits         worker.CommitConfig         `mapstructure:"commits"`          // How the agent's work is split into commits
+	FileGuard       worker.FileGuardConfig      `mapstructure:"file_guard"`       // Oversized files, binaries and blocked paths kept out of commits
+	Attribution     worker.AttributionConfig    `mapstructure:"attribution"`      // Provenance files committed with the agent's changes
+	LicenseScan     worker.LicenseScanConfig    `mapstructure:"license_scan"`     // Disallowed licenses and copied code kept out of commits
+	Precheck        worker.PrecheckConfig       `mapstructure:"precheck"`         // Quick CI run in the worktree before committing
+	PrePush         worker.PrePushConfig        `mapstructure:"pre_push"`         // Policy command that can veto the changes before they are pushed
+	Retry           worker.RetryConfig          `mapstructure:"retry"`            // Failed tickets requeued with backoff before ticket_failed
+}
+
+// ConcurrencyExperimentConfig cycles the number of active agents between
+// bounds to find the count with the best throughput for the machine
+type ConcurrencyExperimentConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	MinCount      int  `mapstructure:"min_count"`
+	MaxCount      int  `mapstructure:"max_count"`
+	PeriodMinutes int  `mapstructure:"period_minutes"` // How long each count is tried
+}
+
+// RateLimitConfig holds agent API quota settings (zero disables a limit)
+type RateLimitConfig struct {
+	MaxPerHour       int `mapstructure:"max_per_hour"`
+	MaxConcurrent    int `mapstructure:"max_concurrent"`
+	WorkerMaxPerHour int `mapstructure:"worker_max_per_hour"`
+	MaxRetries       int `mapstructure:"max_retries"`
+	BackoffSeconds   int `mapstructure:"backoff_seconds"`
 }
 
 // SchedulerConfig holds scheduler settings
 type SchedulerConfig struct {
-	PollInterval int    `mapstructure:"poll_interval"`
-	BacklogPath  string `mapstructure:"backlog_path"`
-	StaleTimeout int    `mapstructure:"stale_timeout"`
+	PollInterval      int    `mapstructure:"poll_interval"`
+	BacklogPath       string `mapstructure:"backlog_path"`
+	StaleTimeout      int    `mapstructure:"stale_timeout"`
+	MinFreeMB         int    `mapstructure:"min_free_mb"`         // Stop dispatching below this much free disk; 0 disables
+	DiskCheckInterval int    `mapstructure:"disk_check_interval"` // Seconds between free space checks
+	PauseWhenMainRed  bool   `mapstructure:"pause_when_main_red"` // Hold dispatch while CI on main fails; tracks main as ci.main does
+
+	Reservations []queue.Reservation `mapstructure:"reservations"` // Workers kept free for urgent tickets
+	Preemption   PreemptionConfig    `mapstructure:"preemption"`
+	Quotas       []quota.Quota       `mapstructure:"quotas"` // Per-tag limits so one team can't monopolize the orchestrator
+
+	ProcessedRetention backlog.Retention `mapstructure:"processed_retention"` // When processed ticket files are archived
+	TicketDefaults     string            `mapstructure:"ticket_defaults"`     // Fields merged into every ticket; "" uses _defaults.yaml in the backlog
+}
+
+// PreemptionConfig controls checkpointing low priority work for urgent tickets
+type PreemptionConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	UrgentPriority int  `mapstructure:"urgent_priority"` // Tickets at this priority or more urgent may preempt
+	VictimPriority int  `mapstructure:"victim_priority"` // Only tickets at this priority or less urgent are preempted
 }
 
 // CIConfig holds continuous integration settings
 type CIConfig struct {
-	StatusPath  string `mapstructure:"status_path"`
-	QuickTests  bool   `mapstructure:"quick_tests"`
+	StatusPath    string             `mapstructure:"status_path"`
+	QuickTests    bool               `mapstructure:"quick_tests"`
+	RetentionDays int                `mapstructure:"retention_days"` // 0 keeps statuses forever
+	Backend       string             `mapstructure:"backend"`        // local, github or buildkite
+	Timeout       int                `mapstructure:"timeout"`        // Seconds a CI run may take; 0 means no limit
+	TestRetries   int                `mapstructure:"test_retries"`   // Retries of failed test packages; 0 disables
+	Test          ci.TestFlags       `mapstructure:"test"`           // go test parallelism and sharding; local backend only
+	GoCacheDir    string             `mapstructure:"go_cache_dir"`   // Persistent GOMODCACHE/GOCACHE root for CI and agents; empty disables
+	Remote        string             `mapstructure:"remote"`         // Git remote external providers build from
+	PollInterval  int                `mapstructure:"poll_interval"`  // Seconds between external provider checks
+	GitHub        ci.GitHubConfig    `mapstructure:"github"`
+	Buildkite     ci.BuildkiteConfig `mapstructure:"buildkite"`
+	Matrix        []ci.MatrixCell    `mapstructure:"matrix"`       // Local backend only
+	Profiles      []ci.Profile       `mapstructure:"profiles"`     // Selected by ticket tags
+	DocsPaths     []string           `mapstructure:"docs_paths"`   // gitignore-style; CI is skipped when only these change
+	Main          ci.MainConfig      `mapstructure:"main"`         // CI tracking on the main branch
+	VulnScan      ci.VulnScanConfig  `mapstructure:"vuln_scan"`    // Dependency vulnerability scanners; local backend only
+	StatusStore   ci.StoreConfig     `mapstructure:"status_store"` // Where statuses are shared; status_path stays the local copy
+}
+
+// StorageConfig offloads per-ticket logs and CI outputs to object storage
+type StorageConfig struct {
+	Backend   string            `mapstructure:"backend"` // none or s3; with s3, artifacts default to the same bucket
+	S3        storage.S3Config  `mapstructure:"s3"`
+	Lifecycle storage.Lifecycle `mapstructure:"lifecycle"` // Expiration rules applied to the bucket at startup
 }
 
 // IPCConfig holds inter-process communication settings
 type IPCConfig struct {
-	SocketPath string `mapstructure:"socket_path"`
+	SocketPath string                `mapstructure:"socket_path"`
+	Socket     ipc.SocketPermissions `mapstructure:"socket"`    // Mode, group and directory mode of the socket
+	Auth       ipc.AuthConfig        `mapstructure:"auth"`      // Tokens and roles; empty leaves the socket open to its file permissions
+	Framing    string                `mapstructure:"framing"`   // How clients ask for events: json or deflate
+	WebSocket  ipc.WebSocketConfig   `mapstructure:"websocket"` // Events and commands over WebSockets for other machines
 }
 
 // MetricsConfig holds metrics collection settings
 type MetricsConfig struct {
-	Enabled    bool   `mapstructure:"enabled"`
-	OutputPath string `mapstructure:"output_path"`
+	Enabled            bool           `mapstructure:"enabled"`
+	OutputPath         string         `mapstructure:"output_path"`
+	ForecastWindowDays int            `mapstructure:"forecast_window_days"` // Days of throughput the backlog forecast is based on
+	Summary            summary.Config `mapstructure:"summary"`              // Weekly summaries written to output_path
 }
 
 // TestingConfig holds testing mode settings
 type TestingConfig struct {
-	SkipAmp bool `mapstructure:"skip_amp"`
-	SkipCI  bool `mapstructure:"skip_ci"`
+	SkipAmp bool         `mapstructure:"skip_amp"`
+	SkipCI  bool         `mapstructure:"skip_ci"`
+	Chaos   chaos.Config `mapstructure:"chaos"` // Injected failures for exercising recovery paths
+}
+
+// StateConfig holds persistent state settings
+type StateConfig struct {
+	Path string `mapstructure:"path"`
+}
+
+// CoordinationConfig lets daemons on several machines share one bare repository
+// and backlog; each ticket is claimed by a single daemon before it is enqueued
+type CoordinationConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	NodeID       string `mapstructure:"node_id"`       // Recorded in claims; defaults to the hostname
+	LeaseSeconds int    `mapstructure:"lease_seconds"` // Claims not renewed for this long may be taken over
+}
+
+// RemoteConfig lets worker processes on other machines take tickets over TCP
+// They clone repo_url, run the agent and CI locally and push the branch back
+type RemoteConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	ListenAddress string `mapstructure:"listen_address"` // TCP host:port remote workers connect to
+	RepoURL       string `mapstructure:"repo_url"`       // Git URL of repository.path as reachable from the workers
+	LeaseSeconds  int    `mapstructure:"lease_seconds"`  // Tickets of workers silent for this long are requeued
+}
+
+// IntegrationsConfig holds settings for external services
+type IntegrationsConfig struct {
+	GitHub github.Config `mapstructure:"github"` // Pull requests for completed tickets
+}
+
+// DashboardConfig holds the web dashboard settings
+type DashboardConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	ListenAddress string `mapstructure:"listen_address"` // HTTP host:port; non-loopback addresses require ipc.auth.tokens
 }
 
+// APIConfig holds the HTTP API settings
+type APIConfig struct {
+	ListenAddr string `mapstructure:"listen_addr"` // HTTP host:port; empty disables the API, non-loopback addresses require ipc.auth.tokens
+}
+
+// ValidationConfig holds the external ticket validation hook settings
+type ValidationConfig struct {
+	URL      string `mapstructure:"url"`
+	Command  string `mapstructure:"command"`
+	Timeout  int    `mapstructure:"timeout"`
+	FailOpen bool   `mapstructure:"fail_open"`
+}
+
+// ErrNotFound is returned by Load when no search path has a config file
+var ErrNotFound = errors.New("config file not found in any of the search paths")
+
 // Load loads the configuration from file
 func Load() (*Config, error) {
 	v := viper.New()
@@ -92,7 +277,7 @@ func Load() (*Config, error) {
 	}
 	
 	if !configFound {
-		return nil, errors.New("config file not found in any of the search paths")
+		return nil, ErrNotFound
 	}
 	
 	v.SetConfigName(strings.TrimSuffix(configFile, filepath.Ext(configFile)))
@@ -107,11 +292,31 @@ func Load() (*Config, error) {
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("error unmarshalling config: %w", err)
 	}
+
+	// Paths are relative to the config file, not the working directory
+	root, err := filepath.Abs(filepath.Dir(v.ConfigFileUsed()))
+	if err != nil {
+		return nil, fmt.Errorf("error resolving config directory: %w", err)
+	}
+	resolvePaths(&config, root)
+
+	// Ticket templates name the project after its repository unless told otherwise
+	if config.Templating.ProjectName == "" {
+		config.Templating.ProjectName = strings.TrimSuffix(filepath.Base(config.Repository.Path), ".git")
+	}
 	
 	// Validate the config
 	if err := validateConfig(&config); err != nil {
 		return nil, err
 	}
+
+	// Every command that loads the config shows timestamps its way
+	if err := timefmt.Set(config.Time); err != nil {
+		return nil, fmt.Errorf("invalid time: %w", err)
+	}
+	if err := i18n.Set(i18n.Detect(config.Locale)); err != nil {
+		return nil, fmt.Errorf("invalid locale: %w", err)
+	}
 	
 	return &config, nil
 }
@@ -121,30 +326,211 @@ func setDefaults(v *viper.Viper) {
 	// Repository defaults
 	v.SetDefault("repository.path", "./repo.git")
 	v.SetDefault("repository.workdir", "./tmp")
+	v.SetDefault("repository.scratch_retention_days", 7)
 	
 	// Agent defaults
 	v.SetDefault("agents.count", 3)
 	v.SetDefault("agents.timeout", 1800) // 30 minutes
+	v.SetDefault("agents.max_failures", 3)
+	v.SetDefault("agents.retry_branch", worker.RetryReset)
+	v.SetDefault("agents.rate_limit.max_per_hour", 0)
+	v.SetDefault("agents.rate_limit.max_concurrent", 0)
+	v.SetDefault("agents.rate_limit.worker_max_per_hour", 0)
+	v.SetDefault("agents.rate_limit.max_retries", 3)
+	v.SetDefault("agents.rate_limit.backoff_seconds", 30)
+	v.SetDefault("agents.limits.nice", 0)
+	v.SetDefault("agents.limits.memory_mb", 0)
+	v.SetDefault("agents.limits.cpu_seconds", 0)
+	v.SetDefault("agents.limits.max_processes", 0)
+	v.SetDefault("agents.limits.disk_quota_mb", 0)
+	v.SetDefault("agents.backend", kube.BackendLocal)
+	v.SetDefault("agents.experiment.enabled", false)
+	v.SetDefault("agents.experiment.min_count", 1)
+	v.SetDefault("agents.experiment.max_count", 4)
+	v.SetDefault("agents.experiment.period_minutes", 60)
+	v.SetDefault("agents.housekeeping.chores", []string{})
+	v.SetDefault("agents.housekeeping.interval_minutes", 60)
+	v.SetDefault("agents.housekeeping.upstream", "")
+	v.SetDefault("agents.regression_tests.enabled", false)
+	v.SetDefault("agents.regression_tests.patterns", []string{})
+	v.SetDefault("agents.regression_tests.on_missing", worker.RegressionRetry)
+	v.SetDefault("agents.regression_tests.retries", 1)
+	v.SetDefault("agents.commits.split", worker.CommitSingle)
+	v.SetDefault("agents.commits.max_commits", 0)
+	v.SetDefault("agents.file_guard.enabled", false)
+	v.SetDefault("agents.file_guard.max_file_kb", 1024)
+	v.SetDefault("agents.file_guard.binaries", true)
+	v.SetDefault("agents.file_guard.blocklist", worker.DefaultBlockedPaths)
+	v.SetDefault("agents.file_guard.action", worker.GuardReject)
+	v.SetDefault("agents.attribution.enabled", false)
+	v.SetDefault("agents.attribution.dir", worker.DefaultAttributionDir)
+	v.SetDefault("agents.license_scan.enabled", false)
+	v.SetDefault("agents.license_scan.command", "")
+	v.SetDefault("agents.license_scan.patterns", []string{})
+	v.SetDefault("agents.precheck.enabled", false)
+	v.SetDefault("agents.precheck.command", "")
+	v.SetDefault("agents.precheck.retries", 1)
+	v.SetDefault("agents.precheck.timeout_seconds", 600)
+	v.SetDefault("agents.pre_push.enabled", false)
+	v.SetDefault("agents.pre_push.command", "")
+	v.SetDefault("agents.pre_push.timeout_seconds", 120)
+	v.SetDefault("agents.retry.max_attempts", 1)
+	v.SetDefault("agents.retry.backoff_seconds", 60)
+	v.SetDefault("agents.retry.max_backoff_seconds", 3600)
+	v.SetDefault("agents.retry.on", worker.DefaultRetryCodes)
 	
 	// Scheduler defaults
 	v.SetDefault("scheduler.poll_interval", 5)
 	v.SetDefault("scheduler.backlog_path", "./backlog")
 	v.SetDefault("scheduler.stale_timeout", 900) // 15 minutes
+	v.SetDefault("scheduler.min_free_mb", 0)
+	v.SetDefault("scheduler.disk_check_interval", 30)
+	v.SetDefault("scheduler.pause_when_main_red", false)
+	v.SetDefault("scheduler.preemption.enabled", false)
+	v.SetDefault("scheduler.preemption.urgent_priority", 1)
+	v.SetDefault("scheduler.preemption.victim_priority", 4)
+	v.SetDefault("scheduler.processed_retention.max_age_days", 0)
+	v.SetDefault("scheduler.processed_retention.max_files", 0)
+	v.SetDefault("scheduler.ticket_defaults", "")
 	
 	// CI defaults
 	v.SetDefault("ci.status_path", "./ci-status")
 	v.SetDefault("ci.quick_tests", true)
+	v.SetDefault("ci.retention_days", 30)
+	v.SetDefault("ci.backend", ci.BackendLocal)
+	v.SetDefault("ci.timeout", 1800)
+	v.SetDefault("ci.test_retries", ci.DefaultTestRetries)
+	v.SetDefault("ci.go_cache_dir", "./go-cache")
+	v.SetDefault("ci.remote", "origin")
+	v.SetDefault("ci.poll_interval", 10)
+	v.SetDefault("ci.github.token_env", "GITHUB_TOKEN")
+	v.SetDefault("ci.buildkite.token_env", "BUILDKITE_API_TOKEN")
+	v.SetDefault("ci.main.enabled", false)
+	v.SetDefault("ci.main.interval_seconds", 30)
+	v.SetDefault("ci.vuln_scan.enabled", false)
+	v.SetDefault("ci.vuln_scan.fail_on_new", false)
+	v.SetDefault("ci.status_store.backend", ci.StoreFile)
+	v.SetDefault("ci.status_store.s3.region", "us-east-1")
+	v.SetDefault("ci.status_store.s3.access_key_env", "AWS_ACCESS_KEY_ID")
+	v.SetDefault("ci.status_store.s3.secret_key_env", "AWS_SECRET_ACCESS_KEY")
 	
 	// IPC defaults
 	v.SetDefault("ipc.socket_path", "~/.orchestrator.sock")
+	v.SetDefault("ipc.auth.default_role", ipc.RoleViewer)
+	v.SetDefault("ipc.framing", ipc.FramingJSON)
+	v.SetDefault("ipc.websocket.token_env", "ORCHESTRATOR_WS_TOKEN")
+	v.SetDefault("ipc.websocket.role", ipc.RoleViewer)
 	
 	// Metrics defaults
 	v.SetDefault("metrics.enabled", true)
 	v.SetDefault("metrics.output_path", "./metrics")
+	v.SetDefault("metrics.forecast_window_days", 7)
+	v.SetDefault("metrics.summary.enabled", false)
+	v.SetDefault("metrics.summary.format", summary.FormatMarkdown)
+	v.SetDefault("metrics.summary.trend_weeks", 8)
+	v.SetDefault("metrics.summary.agent_cost_per_hour", 0)
+	v.SetDefault("metrics.summary.ci_cost_per_hour", 0)
 
 	// Testing defaults
 	v.SetDefault("testing.skip_amp", false)
 	v.SetDefault("testing.skip_ci", false)
+	v.SetDefault("testing.chaos.enabled", false)
+	v.SetDefault("testing.chaos.ci_delay_seconds", 60)
+
+	// State defaults
+	v.SetDefault("state.path", "./state")
+
+	// Coordination defaults
+	v.SetDefault("coordination.enabled", false)
+	v.SetDefault("coordination.lease_seconds", 300)
+
+	// Remote worker defaults
+	v.SetDefault("remote.enabled", false)
+	v.SetDefault("remote.listen_address", "127.0.0.1:7420")
+	v.SetDefault("remote.lease_seconds", 300)
+	v.SetDefault("dashboard.enabled", false)
+	v.SetDefault("dashboard.listen_address", "127.0.0.1:8080")
+	v.SetDefault("api.listen_addr", "")
+
+	// Validation hook defaults
+	v.SetDefault("validation.url", "")
+	v.SetDefault("validation.command", "")
+	v.SetDefault("validation.timeout", 10)
+	v.SetDefault("validation.fail_open", false)
+
+	// Artifact store defaults
+	v.SetDefault("artifacts.backend", storage.BackendLocal)
+	v.SetDefault("artifacts.dir", "./artifacts")
+	v.SetDefault("artifacts.s3.region", "us-east-1")
+	v.SetDefault("artifacts.s3.access_key_env", "AWS_ACCESS_KEY_ID")
+	v.SetDefault("artifacts.s3.secret_key_env", "AWS_SECRET_ACCESS_KEY")
+
+	// Object storage defaults
+	v.SetDefault("storage.backend", "none")
+	v.SetDefault("storage.s3.region", "us-east-1")
+	v.SetDefault("storage.s3.access_key_env", "AWS_ACCESS_KEY_ID")
+	v.SetDefault("storage.s3.secret_key_env", "AWS_SECRET_ACCESS_KEY")
+	v.SetDefault("storage.lifecycle.logs_days", 30)
+	v.SetDefault("storage.lifecycle.ci_days", 30)
+	v.SetDefault("storage.lifecycle.artifacts_days", 90)
+
+	// Encryption defaults
+	v.SetDefault("encryption.enabled", false)
+	v.SetDefault("encryption.key_env", encryption.DefaultKeyEnv)
+
+	// Signing defaults
+	v.SetDefault("signing.require_signed_tickets", false)
+
+	// Templating defaults
+	v.SetDefault("templating.project_name", "")
+
+	// Verification defaults
+	v.SetDefault("verify.enabled", false)
+	v.SetDefault("verify.interval_seconds", 60)
+	v.SetDefault("verify.timeout", 600)
+	v.SetDefault("verify.on_failure", verify.OnFailureRevertTicket)
+	v.SetDefault("verify.main_ci", false)
+
+	// Conflict check defaults
+	v.SetDefault("conflicts.enabled", false)
+	v.SetDefault("conflicts.priority", 0)
+
+	// Integration defaults
+	v.SetDefault("integrations.github.enabled", false)
+	v.SetDefault("integrations.github.remote", "origin")
+	v.SetDefault("integrations.github.base", "main")
+	v.SetDefault("integrations.github.token_env", "GITHUB_TOKEN")
+	v.SetDefault("integrations.github.status_context", "amp-orchestrator/ci")
+
+	// Completion report defaults
+	v.SetDefault("reports.enabled", false)
+	v.SetDefault("reports.path", "./reports")
+	v.SetDefault("reports.branch_url", "")
+
+	// Ticket log defaults
+	v.SetDefault("ticket_logs.enabled", true)
+	v.SetDefault("ticket_logs.path", "./logs")
+
+	// Event log defaults
+	v.SetDefault("event_log.enabled", false)
+	v.SetDefault("event_log.max_size_mb", 10)
+	v.SetDefault("event_log.max_files", 5)
+
+	// Time defaults
+	v.SetDefault("time.timezone", "Local")
+	v.SetDefault("time.format", timefmt.DefaultFormat)
+	v.SetDefault("time.clock_format", timefmt.DefaultClockFormat)
+}
+
+// IsLoopback reports whether a host:port listen address only accepts
+// connections from this machine
+func IsLoopback(address string) (bool, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false, err
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback()), nil
 }
 
 // validateConfig validates the loaded configuration
@@ -157,6 +543,10 @@ func validateConfig(config *Config) error {
 	if config.Repository.Workdir == "" {
 		return errors.New("repository.workdir cannot be empty")
 	}
+
+	if config.Repository.ScratchRetentionDays < 0 {
+		return errors.New("repository.scratch_retention_days cannot be negative")
+	}
 	
 	// Validate agent config
 	if config.Agents.Count < 1 {
@@ -166,8 +556,104 @@ func validateConfig(config *Config) error {
 	if config.Agents.Timeout < 60 {
 		return errors.New("agents.timeout must be at least 60 seconds")
 	}
+
+	if config.Agents.MaxFailures < 0 {
+		return errors.New("agents.max_failures cannot be negative")
+	}
+
+	if config.Agents.RetryBranch != "" && !worker.ValidRetryBranch(config.Agents.RetryBranch) {
+		return fmt.Errorf("unknown agents.retry_branch %q (expected reset or attempt)", config.Agents.RetryBranch)
+	}
+
+	if err := config.Agents.RegressionTests.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.regression_tests: %w", err)
+	}
+
+	if err := config.Agents.Commits.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.commits: %w", err)
+	}
+
+	if err := config.Agents.FileGuard.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.file_guard: %w", err)
+	}
+
+	if err := config.Agents.Attribution.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.attribution: %w", err)
+	}
+
+	if err := config.Agents.LicenseScan.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.license_scan: %w", err)
+	}
+
+	if err := config.Agents.Precheck.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.precheck: %w", err)
+	}
+
+	if err := config.Agents.PrePush.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.pre_push: %w", err)
+	}
+
+	if err := config.Agents.Retry.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.retry: %w", err)
+	}
+
+	if err := config.Time.Validate(); err != nil {
+		return fmt.Errorf("invalid time: %w", err)
+	}
+
+	if _, ok := i18n.Match(config.Locale); config.Locale != "" && !ok {
+		return fmt.Errorf("locale %q has no message catalog; available: %s", config.Locale, strings.Join(i18n.Available(), ", "))
+	}
+
+	if err := config.Agents.Housekeeping.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.housekeeping: %w", err)
+	}
+
+	rl := config.Agents.RateLimit
+	if rl.MaxPerHour < 0 || rl.MaxConcurrent < 0 || rl.WorkerMaxPerHour < 0 {
+		return errors.New("agents.rate_limit limits cannot be negative")
+	}
+
+	if rl.MaxRetries < 0 || rl.BackoffSeconds < 0 {
+		return errors.New("agents.rate_limit retries and backoff cannot be negative")
+	}
+
+	if err := config.Agents.Limits.Validate(); err != nil {
+		return fmt.Errorf("invalid agents.limits: %w", err)
+	}
+
+	switch config.Agents.Backend {
+	case "", kube.BackendLocal:
+	case kube.BackendKubernetes:
+		if err := config.Agents.Kubernetes.Validate(); err != nil {
+			return fmt.Errorf("invalid agents.kubernetes: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown agents.backend %q (expected local or kubernetes)", config.Agents.Backend)
+	}
+
+	if exp := config.Agents.Experiment; exp.Enabled {
+		if exp.MinCount < 1 || exp.MaxCount <= exp.MinCount {
+			return errors.New("agents.experiment needs 1 <= min_count < max_count")
+		}
+		if exp.PeriodMinutes < 1 {
+			return errors.New("agents.experiment.period_minutes must be at least 1")
+		}
+		if !config.Metrics.Enabled {
+			return errors.New("agents.experiment requires metrics.enabled to record its trials")
+		}
+	}
 	
 	// Validate scheduler config
+	if err := queue.ValidateReservations(config.Scheduler.Reservations, config.Agents.Count); err != nil {
+		return fmt.Errorf("invalid scheduler.reservations: %w", err)
+	}
+	if exp := config.Agents.Experiment; exp.Enabled {
+		if err := queue.ValidateReservations(config.Scheduler.Reservations, exp.MinCount); err != nil {
+			return fmt.Errorf("invalid scheduler.reservations for agents.experiment.min_count: %w", err)
+		}
+	}
+
 	if config.Scheduler.PollInterval < 1 {
 		return errors.New("scheduler.poll_interval must be at least 1 second")
 	}
@@ -175,6 +661,229 @@ func validateConfig(config *Config) error {
 	if config.Scheduler.BacklogPath == "" {
 		return errors.New("scheduler.backlog_path cannot be empty")
 	}
+
+	if config.Scheduler.MinFreeMB < 0 {
+		return errors.New("scheduler.min_free_mb cannot be negative")
+	}
+
+	if config.Scheduler.MinFreeMB > 0 && config.Scheduler.DiskCheckInterval < 1 {
+		return errors.New("scheduler.disk_check_interval must be at least 1 second")
+	}
+
+	if p := config.Scheduler.Preemption; p.Enabled {
+		if p.UrgentPriority < 1 || p.VictimPriority > 5 || p.UrgentPriority >= p.VictimPriority {
+			return errors.New("scheduler.preemption requires 1 <= urgent_priority < victim_priority <= 5")
+		}
+	}
+
+	if err := config.Scheduler.ProcessedRetention.Validate(); err != nil {
+		return fmt.Errorf("invalid scheduler.processed_retention: %w", err)
+	}
+
+	if err := config.Testing.Chaos.Validate(); err != nil {
+		return fmt.Errorf("invalid testing.chaos: %w", err)
+	}
+
+	if err := config.Verify.Validate(); err != nil {
+		return fmt.Errorf("invalid verify config: %w", err)
+	}
+
+	if err := config.Conflicts.Validate(); err != nil {
+		return fmt.Errorf("invalid conflicts config: %w", err)
+	}
+
+	if err := config.Integrations.GitHub.Validate(); err != nil {
+		return fmt.Errorf("invalid integrations.github: %w", err)
+	}
+
+	if err := config.Reports.Validate(); err != nil {
+		return fmt.Errorf("invalid reports config: %w", err)
+	}
+
+	if err := config.TicketLogs.Validate(); err != nil {
+		return fmt.Errorf("invalid ticket_logs config: %w", err)
+	}
+
+	if err := config.EventLog.Validate(); err != nil {
+		return fmt.Errorf("invalid event_log config: %w", err)
+	}
+
+	// Validate state config
+	if config.State.Path == "" {
+		return errors.New("state.path cannot be empty")
+	}
+
+	if config.Metrics.ForecastWindowDays < 1 {
+		return errors.New("metrics.forecast_window_days must be at least 1")
+	}
+	if err := config.Metrics.Summary.Validate(); err != nil {
+		return fmt.Errorf("invalid metrics.summary: %w", err)
+	}
+
+	// Validate CI config
+	if config.CI.RetentionDays < 0 {
+		return errors.New("ci.retention_days cannot be negative")
+	}
+
+	if config.CI.Timeout < 0 || config.CI.PollInterval < 0 || config.CI.TestRetries < 0 {
+		return errors.New("ci.timeout, ci.poll_interval and ci.test_retries cannot be negative")
+	}
+
+	if err := config.CI.Test.Validate(); err != nil {
+		return fmt.Errorf("invalid ci.test: %w", err)
+	}
+
+	if err := config.CI.Main.Validate(); err != nil {
+		return fmt.Errorf("invalid ci.main: %w", err)
+	}
+
+	if err := config.CI.StatusStore.Validate(); err != nil {
+		return fmt.Errorf("invalid ci.status_store: %w", err)
+	}
+
+	if config.Scheduler.PauseWhenMainRed && config.CI.Main.IntervalSeconds < 1 {
+		return errors.New("ci.main.interval_seconds must be at least 1 when scheduler.pause_when_main_red is set")
+	}
+
+	if config.CI.VulnScan.Enabled && config.CI.Backend != "" && config.CI.Backend != ci.BackendLocal {
+		return errors.New("ci.vuln_scan is only supported by the local backend")
+	}
+
+	if err := ci.ValidateMatrix(config.CI.Matrix); err != nil {
+		return fmt.Errorf("invalid ci.matrix: %w", err)
+	}
+
+	if err := ci.ValidateProfiles(config.CI.Profiles); err != nil {
+		return fmt.Errorf("invalid ci.profiles: %w", err)
+	}
+
+	profileNames := make([]string, len(config.CI.Profiles))
+	for i, profile := range config.CI.Profiles {
+		profileNames[i] = profile.Name
+	}
+	if err := config.Environments.Validate(profileNames); err != nil {
+		return fmt.Errorf("invalid environments: %w", err)
+	}
+
+	switch config.CI.Backend {
+	case "", ci.BackendLocal:
+	case ci.BackendGitHub:
+		if config.CI.GitHub.Repo == "" {
+			return errors.New("ci.github.repo is required for the github backend")
+		}
+	case ci.BackendBuildkite:
+		if config.CI.Buildkite.Org == "" || config.CI.Buildkite.Pipeline == "" {
+			return errors.New("ci.buildkite.org and ci.buildkite.pipeline are required for the buildkite backend")
+		}
+	default:
+		return fmt.Errorf("unknown ci.backend %q (expected local, github or buildkite)", config.CI.Backend)
+	}
+
+	if len(config.CI.Matrix) > 0 && config.CI.Backend != "" && config.CI.Backend != ci.BackendLocal {
+		return errors.New("ci.matrix is only supported by the local backend")
+	}
+
+	// Validate validation hook config
+	if config.Validation.URL != "" && config.Validation.Command != "" {
+		return errors.New("validation.url and validation.command cannot both be set")
+	}
+
+	if config.Validation.Timeout < 0 {
+		return errors.New("validation.timeout cannot be negative")
+	}
+
+	if config.Coordination.Enabled && config.Coordination.LeaseSeconds <= 0 {
+		return errors.New("coordination.lease_seconds must be positive")
+	}
+
+	if config.Remote.Enabled {
+		if config.Remote.ListenAddress == "" || config.Remote.RepoURL == "" {
+			return errors.New("remote.listen_address and remote.repo_url are required when remote workers are enabled")
+		}
+		if _, err := IsLoopback(config.Remote.ListenAddress); err != nil {
+			return fmt.Errorf("invalid remote.listen_address: %w", err)
+		}
+		if config.Remote.LeaseSeconds <= 0 {
+			return errors.New("remote.lease_seconds must be positive")
+		}
+		if len(config.IPC.Auth.Tokens) == 0 {
+			return errors.New("remote workers require ipc.auth.tokens")
+		}
+	}
+
+	if config.Dashboard.Enabled {
+		loopback, err := IsLoopback(config.Dashboard.ListenAddress)
+		if err != nil {
+			return fmt.Errorf("invalid dashboard.listen_address: %w", err)
+		}
+		if !loopback && len(config.IPC.Auth.Tokens) == 0 {
+			return fmt.Errorf("dashboard.listen_address %s is reachable from other hosts; set ipc.auth.tokens", config.Dashboard.ListenAddress)
+		}
+	}
+
+	if config.API.ListenAddr != "" {
+		loopback, err := IsLoopback(config.API.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("invalid api.listen_addr: %w", err)
+		}
+		if !loopback && len(config.IPC.Auth.Tokens) == 0 {
+			return fmt.Errorf("api.listen_addr %s is reachable from other hosts; set ipc.auth.tokens", config.API.ListenAddr)
+		}
+	}
+
+	// Validate policy rules
+	if err := config.Policy.Validate(); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+
+	if err := config.Signing.Validate(); err != nil {
+		return fmt.Errorf("invalid signing: %w", err)
+	}
+
+	if err := config.IPC.Auth.Validate(); err != nil {
+		return fmt.Errorf("invalid ipc.auth: %w", err)
+	}
+	if err := config.IPC.Socket.Validate(); err != nil {
+		return fmt.Errorf("invalid ipc.socket: %w", err)
+	}
+	if err := ipc.ValidateFraming(config.IPC.Framing); err != nil {
+		return fmt.Errorf("invalid ipc.framing: %w", err)
+	}
+	if err := config.IPC.WebSocket.Validate(); err != nil {
+		return fmt.Errorf("invalid ipc.websocket: %w", err)
+	}
+
+	// Validate artifact store
+	if err := config.Artifacts.Validate(); err != nil {
+		return fmt.Errorf("invalid artifacts config: %w", err)
+	}
+
+	// Validate object storage
+	switch config.Storage.Backend {
+	case "", "none":
+	case storage.BackendS3:
+		if config.Storage.S3.Endpoint == "" || config.Storage.S3.Bucket == "" {
+			return errors.New("storage.s3.endpoint and storage.s3.bucket are required for the s3 backend")
+		}
+	default:
+		return fmt.Errorf("unknown storage.backend %q (expected none or s3)", config.Storage.Backend)
+	}
+
+	if err := config.Storage.Lifecycle.Validate(); err != nil {
+		return fmt.Errorf("invalid storage.lifecycle: %w", err)
+	}
+
+	if err := quota.Validate(config.Scheduler.Quotas); err != nil {
+		return fmt.Errorf("invalid scheduler.quotas: %w", err)
+	}
+
+	if err := rules.Validate(config.Rules); err != nil {
+		return fmt.Errorf("invalid rules: %w", err)
+	}
+
+	if err := validatePaths(config); err != nil {
+		return err
+	}
 	
 	return nil
 }
\ No newline at end of file
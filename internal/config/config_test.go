@@ -5,6 +5,16 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/brettsmith212/amp-orchestrator/internal/chaos"
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/eventlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/rules"
+	"github.com/brettsmith212/amp-orchestrator/internal/summary"
+	"github.com/brettsmith212/amp-orchestrator/internal/verify"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
 	"github.com/spf13/viper"
 )
 
@@ -67,6 +77,10 @@ func TestLoadDefaultConfig(t *testing.T) {
 	if config.Scheduler.BacklogPath != "./backlog" {
 		t.Errorf("Expected scheduler.backlog_path to be './backlog', got '%s'", config.Scheduler.BacklogPath)
 	}
+
+	if config.CI.RetentionDays != 30 {
+		t.Errorf("Expected ci.retention_days to be 30, got %d", config.CI.RetentionDays)
+	}
 }
 
 func TestValidateConfig(t *testing.T) {
@@ -85,6 +99,13 @@ func TestValidateConfig(t *testing.T) {
 			BacklogPath:  "./backlog",
 			StaleTimeout: 900,
 		},
+		State: StateConfig{
+			Path: "./state",
+		},
+		Metrics: MetricsConfig{
+			ForecastWindowDays: 7,
+			Summary:            summary.Config{Format: summary.FormatMarkdown, TrendWeeks: 8},
+		},
 	}
 
 	if err := validateConfig(validConfig); err != nil {
@@ -112,10 +133,331 @@ func TestValidateConfig(t *testing.T) {
 		t.Error("Expected error for invalid timeout, got nil")
 	}
 
+	// Test negative rate limit
+	invalidRateLimit := *validConfig
+	invalidRateLimit.Agents.RateLimit.MaxPerHour = -1
+	if err := validateConfig(&invalidRateLimit); err == nil {
+		t.Error("Expected error for negative rate limit, got nil")
+	}
+
+	// Test negative free space threshold
+	invalidMinFree := *validConfig
+	invalidMinFree.Scheduler.MinFreeMB = -1
+	if err := validateConfig(&invalidMinFree); err == nil {
+		t.Error("Expected error for negative min_free_mb, got nil")
+	}
+
+	// Test out of range niceness
+	invalidLimits := *validConfig
+	invalidLimits.Agents.Limits.Nice = 20
+	if err := validateConfig(&invalidLimits); err == nil {
+		t.Error("Expected error for niceness above 19, got nil")
+	}
+
+	// Test shard count below -1
+	invalidShards := *validConfig
+	invalidShards.CI.Test.Shards = -2
+	if err := validateConfig(&invalidShards); err == nil {
+		t.Error("Expected error for ci.test.shards below -1, got nil")
+	}
+
+	// Test negative scratch retention
+	invalidScratch := *validConfig
+	invalidScratch.Repository.ScratchRetentionDays = -1
+	if err := validateConfig(&invalidScratch); err == nil {
+		t.Error("Expected error for negative scratch_retention_days, got nil")
+	}
+
+	// Test unknown artifact backend
+	invalidArtifacts := *validConfig
+	invalidArtifacts.Artifacts.Backend = "ftp"
+	if err := validateConfig(&invalidArtifacts); err == nil {
+		t.Error("Expected error for unknown artifacts backend, got nil")
+	}
+
+	// Test s3 storage without a bucket
+	invalidStorage := *validConfig
+	invalidStorage.Storage.Backend = "s3"
+	invalidStorage.Storage.S3.Endpoint = "https://s3.example.com"
+	if err := validateConfig(&invalidStorage); err == nil {
+		t.Error("Expected error for s3 storage without a bucket, got nil")
+	}
+
+	// Test IPC token with an unknown role
+	invalidAuth := *validConfig
+	invalidAuth.IPC.Auth.Tokens = []ipc.TokenConfig{{Name: "bot", TokenEnv: "BOT_TOKEN", Role: "root"}}
+	if err := validateConfig(&invalidAuth); err == nil {
+		t.Error("Expected error for unknown ipc.auth role, got nil")
+	}
+
+	invalidFraming := *validConfig
+	invalidFraming.IPC.Framing = "protobuf"
+	if err := validateConfig(&invalidFraming); err == nil {
+		t.Error("Expected error for unknown ipc.framing, got nil")
+	}
+
+	invalidWebSocket := *validConfig
+	invalidWebSocket.IPC.WebSocket = ipc.WebSocketConfig{ListenAddr: "0.0.0.0:7421"}
+	if err := validateConfig(&invalidWebSocket); err == nil {
+		t.Error("Expected error for ipc.websocket without token_env, got nil")
+	}
+
+	invalidSocket := *validConfig
+	invalidSocket.IPC.Socket = ipc.SocketPermissions{Mode: "0999"}
+	if err := validateConfig(&invalidSocket); err == nil {
+		t.Error("Expected error for an invalid ipc.socket.mode, got nil")
+	}
+
+	// Test coordination without a lease
+	invalidCoordination := *validConfig
+	invalidCoordination.Coordination = CoordinationConfig{Enabled: true}
+	if err := validateConfig(&invalidCoordination); err == nil {
+		t.Error("Expected error for coordination without lease_seconds, got nil")
+	}
+
+	// Test remote workers without auth tokens
+	invalidRemote := *validConfig
+	invalidRemote.Remote = RemoteConfig{Enabled: true, ListenAddress: ":7420", RepoURL: "git://build/repo.git", LeaseSeconds: 300}
+	if err := validateConfig(&invalidRemote); err == nil {
+		t.Error("Expected error for remote workers without ipc.auth.tokens, got nil")
+	}
+
+	// Test remote workers on an address without a port
+	invalidRemoteAddress := *validConfig
+	invalidRemoteAddress.Remote = RemoteConfig{Enabled: true, ListenAddress: "build-01", RepoURL: "git://build/repo.git", LeaseSeconds: 300}
+	if err := validateConfig(&invalidRemoteAddress); err == nil {
+		t.Error("Expected error for an invalid remote.listen_address, got nil")
+	}
+
+	// Test kubernetes backend without an image
+	invalidKube := *validConfig
+	invalidKube.Agents.Backend = "kubernetes"
+	if err := validateConfig(&invalidKube); err == nil {
+		t.Error("Expected error for agents.backend kubernetes without an image, got nil")
+	}
+
+	// Test dashboard open to other hosts without auth tokens
+	invalidDashboard := *validConfig
+	invalidDashboard.Dashboard = DashboardConfig{Enabled: true, ListenAddress: ":8080"}
+	if err := validateConfig(&invalidDashboard); err == nil {
+		t.Error("Expected error for dashboard on all interfaces without auth tokens, got nil")
+	}
+
+	// Test HTTP API open to other hosts without auth tokens
+	invalidAPI := *validConfig
+	invalidAPI.API = APIConfig{ListenAddr: "0.0.0.0:8081"}
+	if err := validateConfig(&invalidAPI); err == nil {
+		t.Error("Expected error for API on all interfaces without auth tokens, got nil")
+	}
+
+	// Test event rule without an action
+	invalidRules := *validConfig
+	invalidRules.Rules = []rules.Rule{{Name: "noop", Event: "ci_flaky"}}
+	if err := validateConfig(&invalidRules); err == nil {
+		t.Error("Expected error for rule without an action, got nil")
+	}
+
+	// Test concurrency experiment without a range to try
+	invalidExperiment := *validConfig
+	invalidExperiment.Agents.Experiment = ConcurrencyExperimentConfig{Enabled: true, MinCount: 2, MaxCount: 2, PeriodMinutes: 60}
+	invalidExperiment.Metrics.Enabled = true
+	if err := validateConfig(&invalidExperiment); err == nil {
+		t.Error("Expected error for concurrency experiment with min_count equal to max_count, got nil")
+	}
+
+	// Test reservations that leave no worker for other tickets
+	invalidReservations := *validConfig
+	invalidReservations.Scheduler.Reservations = []queue.Reservation{{Priority: 1, Workers: 3}}
+	if err := validateConfig(&invalidReservations); err == nil {
+		t.Error("Expected error for reservations covering every worker, got nil")
+	}
+
+	// Test preemption that could interrupt equally urgent work
+	invalidPreemption := *validConfig
+	invalidPreemption.Scheduler.Preemption = PreemptionConfig{Enabled: true, UrgentPriority: 2, VictimPriority: 2}
+	if err := validateConfig(&invalidPreemption); err == nil {
+		t.Error("Expected error for preemption between equal priorities, got nil")
+	}
+
+	// Test a tag selecting two CI profiles
+	invalidProfiles := *validConfig
+	invalidProfiles.CI.Profiles = []ci.Profile{
+		{Name: "frontend", Tags: []string{"ui"}, Command: "npm test"},
+		{Name: "docs", Tags: []string{"ui"}, Skip: true},
+	}
+	if err := validateConfig(&invalidProfiles); err == nil {
+		t.Error("Expected error for a tag selecting two CI profiles, got nil")
+	}
+
+	// Test negative max failures
+	invalidMaxFailures := *validConfig
+	invalidMaxFailures.Agents.MaxFailures = -1
+	if err := validateConfig(&invalidMaxFailures); err == nil {
+		t.Error("Expected error for negative agents.max_failures, got nil")
+	}
+
+	// Test an unknown retry branch mode
+	invalidRetryBranch := *validConfig
+	invalidRetryBranch.Agents.RetryBranch = "rebase"
+	if err := validateConfig(&invalidRetryBranch); err == nil {
+		t.Error("Expected error for unknown agents.retry_branch, got nil")
+	}
+
+	// Test a pre-push hook without a command
+	invalidPrePush := *validConfig
+	invalidPrePush.Agents.PrePush = worker.PrePushConfig{Enabled: true}
+	if err := validateConfig(&invalidPrePush); err == nil {
+		t.Error("Expected error for agents.pre_push without a command, got nil")
+	}
+
+	// Test negative processed retention
+	invalidProcessedRetention := *validConfig
+	invalidProcessedRetention.Scheduler.ProcessedRetention.MaxFiles = -1
+	if err := validateConfig(&invalidProcessedRetention); err == nil {
+		t.Error("Expected error for negative scheduler.processed_retention.max_files, got nil")
+	}
+
+	// Test an environment naming an unknown CI profile
+	invalidEnvironment := *validConfig
+	invalidEnvironment.Environments = environment.Environments{"prod": {CIProfile: "missing"}}
+	if err := validateConfig(&invalidEnvironment); err == nil {
+		t.Error("Expected error for an environment with an unknown CI profile, got nil")
+	}
+
+	// Test a chaos probability above 1
+	invalidChaos := *validConfig
+	invalidChaos.Testing.Chaos = chaos.Config{Enabled: true, CIFailure: 1.5}
+	if err := validateConfig(&invalidChaos); err == nil {
+		t.Error("Expected error for testing.chaos.ci_failure above 1, got nil")
+	}
+
+	invalidVerify := *validConfig
+	invalidVerify.Verify = verify.Config{Enabled: true, IntervalSeconds: 60, OnFailure: "ignore"}
+	if err := validateConfig(&invalidVerify); err == nil {
+		t.Error("Expected error for an unknown verify.on_failure, got nil")
+	}
+
+	invalidEventLog := *validConfig
+	invalidEventLog.EventLog = eventlog.Config{Enabled: true}
+	if err := validateConfig(&invalidEventLog); err == nil {
+		t.Error("Expected error for a zero event_log.max_size_mb, got nil")
+	}
+
+	invalidMainCI := *validConfig
+	invalidMainCI.CI.Main = ci.MainConfig{Enabled: true}
+	if err := validateConfig(&invalidMainCI); err == nil {
+		t.Error("Expected error for a zero ci.main.interval_seconds, got nil")
+	}
+
+	invalidPause := *validConfig
+	invalidPause.Scheduler.PauseWhenMainRed = true
+	if err := validateConfig(&invalidPause); err == nil {
+		t.Error("Expected error for pause_when_main_red without a ci.main interval, got nil")
+	}
+
+	// Test a prefetch chore without an upstream
+	invalidHousekeeping := *validConfig
+	invalidHousekeeping.Agents.Housekeeping = worker.HousekeepingConfig{Chores: []string{worker.ChorePrefetch}, IntervalMinutes: 60}
+	if err := validateConfig(&invalidHousekeeping); err == nil {
+		t.Error("Expected error for a prefetch chore without an upstream, got nil")
+	}
+
+	// Test empty forecast window
+	invalidForecast := *validConfig
+	invalidForecast.Metrics.ForecastWindowDays = 0
+	if err := validateConfig(&invalidForecast); err == nil {
+		t.Error("Expected error for empty forecast window, got nil")
+	}
+
+	// Test negative CI retention
+	invalidRetention := *validConfig
+	invalidRetention.CI.RetentionDays = -1
+	if err := validateConfig(&invalidRetention); err == nil {
+		t.Error("Expected error for negative CI retention, got nil")
+	}
+
+	// Test unknown CI backend
+	invalidBackend := *validConfig
+	invalidBackend.CI.Backend = "jenkins"
+	if err := validateConfig(&invalidBackend); err == nil {
+		t.Error("Expected error for unknown CI backend, got nil")
+	}
+
+	// Test GitHub backend without a repository
+	missingGitHubRepo := *validConfig
+	missingGitHubRepo.CI.Backend = "github"
+	if err := validateConfig(&missingGitHubRepo); err == nil {
+		t.Error("Expected error for github backend without repo, got nil")
+	}
+
+	// Test matrix with a remote backend
+	remoteMatrix := *validConfig
+	remoteMatrix.CI.Backend = "buildkite"
+	remoteMatrix.CI.Buildkite = ci.BuildkiteConfig{Org: "acme", Pipeline: "app"}
+	remoteMatrix.CI.Matrix = []ci.MatrixCell{{Name: "go1.23", GoVersion: "1.23.4"}}
+	if err := validateConfig(&remoteMatrix); err == nil {
+		t.Error("Expected error for matrix with a remote backend, got nil")
+	}
+
+	// Test conflicting validation hooks
+	invalidValidation := *validConfig
+	invalidValidation.Validation = ValidationConfig{URL: "http://localhost/check", Command: "true"}
+	if err := validateConfig(&invalidValidation); err == nil {
+		t.Error("Expected error for both validation url and command, got nil")
+	}
+
 	// Test invalid poll interval
 	invalidPollInterval := *validConfig
 	invalidPollInterval.Scheduler.PollInterval = 0
 	if err := validateConfig(&invalidPollInterval); err == nil {
 		t.Error("Expected error for invalid poll interval, got nil")
 	}
-}
\ No newline at end of file
+
+	// Test invalid state path
+	invalidStatePath := *validConfig
+	invalidStatePath.State.Path = ""
+	if err := validateConfig(&invalidStatePath); err == nil {
+		t.Error("Expected error for empty state path, got nil")
+	}
+}
+func TestPolicyConfigUnmarshal(t *testing.T) {
+	content := `policy:
+  rules:
+    - name: "p1-needs-estimate"
+      when: { priority: 1 }
+      require: ["estimate_min"]
+    - name: "prod-deploy-approval"
+      when: { tags: ["prod-deploy"] }
+      require: ["requires_approval"]
+  allowed_locks: ["database"]
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	setDefaults(v)
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		t.Fatalf("Failed to unmarshal config: %v", err)
+	}
+
+	if len(config.Policy.Rules) != 2 {
+		t.Fatalf("Expected 2 policy rules, got %d", len(config.Policy.Rules))
+	}
+	if config.Policy.Rules[0].When.Priority != 1 {
+		t.Errorf("Expected first rule to match priority 1, got %d", config.Policy.Rules[0].When.Priority)
+	}
+	if len(config.Policy.Rules[1].When.Tags) != 1 || config.Policy.Rules[1].When.Tags[0] != "prod-deploy" {
+		t.Errorf("Expected second rule to match prod-deploy tag, got %v", config.Policy.Rules[1].When.Tags)
+	}
+	if err := config.Policy.Validate(); err != nil {
+		t.Errorf("Expected policy to be valid, got %v", err)
+	}
+}
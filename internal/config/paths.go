@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolvePaths makes the configured paths absolute, taking relative ones to
+// be relative to root, the directory holding config.yaml, rather than to
+// wherever the daemon was started. Paths starting with ~/ are left for the
+// code that opens them to expand
+func resolvePaths(config *Config, root string) {
+	config.Root = root
+	for _, path := range config.paths() {
+		*path.value = resolvePath(root, *path.value)
+	}
+	for name, settings := range config.Environments {
+		settings.Repository = resolvePath(root, settings.Repository)
+		config.Environments[name] = settings
+	}
+}
+
+func resolvePath(root, path string) string {
+	if path == "" || strings.HasPrefix(path, "~") || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// configPath is a path setting and its key, for error messages
+type configPath struct {
+	key   string
+	value *string
+	dir   bool // A directory the daemon creates and writes into
+}
+
+func (c *Config) paths() []configPath {
+	return []configPath{
+		{"repository.path", &c.Repository.Path, true},
+		{"repository.workdir", &c.Repository.Workdir, true},
+		{"scheduler.backlog_path", &c.Scheduler.BacklogPath, true},
+		{"scheduler.ticket_defaults", &c.Scheduler.TicketDefaults, false},
+		{"ci.status_path", &c.CI.StatusPath, true},
+		{"ci.go_cache_dir", &c.CI.GoCacheDir, true},
+		{"ipc.socket_path", &c.IPC.SocketPath, false},
+		{"metrics.output_path", &c.Metrics.OutputPath, true},
+		{"state.path", &c.State.Path, true},
+		{"artifacts.dir", &c.Artifacts.Dir, true},
+		{"encryption.key_file", &c.Encryption.KeyFile, false},
+	}
+}
+
+// validatePaths refuses directories that are not directories, that would
+// clobber the system or home directory, or that overlap in ways the daemon
+// would trip over: anything inside a bare repository, and the worktree,
+// backlog, CI status and state directories nested in one another
+func validatePaths(config *Config) error {
+	home, _ := os.UserHomeDir()
+	dirs := map[string]string{}
+	for _, path := range config.paths() {
+		if !path.dir || *path.value == "" || strings.HasPrefix(*path.value, "~") {
+			continue
+		}
+		abs, err := filepath.Abs(*path.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", path.key, err)
+		}
+		if abs == string(filepath.Separator) || (home != "" && abs == filepath.Clean(home)) {
+			return fmt.Errorf("%s %s would write into %s itself", path.key, *path.value, abs)
+		}
+		if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+			return fmt.Errorf("%s %s is not a directory", path.key, *path.value)
+		}
+		dirs[path.key] = abs
+	}
+
+	repos := map[string]string{"repository.path": dirs["repository.path"]}
+	for name, settings := range config.Environments {
+		if settings.Repository != "" && !strings.HasPrefix(settings.Repository, "~") {
+			abs, err := filepath.Abs(settings.Repository)
+			if err != nil {
+				return fmt.Errorf("invalid environments.%s.repository: %w", name, err)
+			}
+			repos["environments."+name+".repository"] = abs
+		}
+	}
+	for repoKey, repo := range repos {
+		for key, dir := range dirs {
+			if key != "repository.path" && within(dir, repo) {
+				return fmt.Errorf("%s %s is inside the bare repository %s (%s)", key, dir, repo, repoKey)
+			}
+		}
+		if within(repo, dirs["repository.workdir"]) {
+			return fmt.Errorf("%s %s is inside repository.workdir %s, where worktrees are created and removed", repoKey, repo, dirs["repository.workdir"])
+		}
+	}
+
+	managed := []string{"repository.workdir", "scheduler.backlog_path", "ci.status_path", "state.path"}
+	for i, a := range managed {
+		for _, b := range managed[i+1:] {
+			if dirs[a] == "" || dirs[b] == "" {
+				continue
+			}
+			if within(dirs[a], dirs[b]) || within(dirs[b], dirs[a]) {
+				return fmt.Errorf("%s %s and %s %s overlap; give each its own directory", a, dirs[a], b, dirs[b])
+			}
+		}
+	}
+	return nil
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	if path == "" || dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+)
+
+func TestLoadResolvesPathsAgainstConfigDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configDir := filepath.Join(home, ".config", "orchestrator")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	sample, err := os.ReadFile("../../config.sample.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read sample config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), sample, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// Started from a directory without a config, as a service manager would
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to chdir: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Root != configDir {
+		t.Errorf("Expected root %s, got %s", configDir, cfg.Root)
+	}
+	if want := filepath.Join(configDir, "repo.git"); cfg.Repository.Path != want {
+		t.Errorf("Expected repository.path %s, got %s", want, cfg.Repository.Path)
+	}
+	if want := filepath.Join(configDir, "backlog"); cfg.Scheduler.BacklogPath != want {
+		t.Errorf("Expected scheduler.backlog_path %s, got %s", want, cfg.Scheduler.BacklogPath)
+	}
+	if cfg.IPC.SocketPath != "~/.orchestrator.sock" {
+		t.Errorf("Expected the socket path to keep its ~, got %s", cfg.IPC.SocketPath)
+	}
+}
+
+func TestResolvePaths(t *testing.T) {
+	cfg := &Config{
+		Repository:   RepositoryConfig{Path: "repo.git", Workdir: "/var/lib/orchestrator/tmp"},
+		State:        StateConfig{Path: "./state"},
+		Environments: environment.Environments{"prod": {Repository: "../prod.git"}},
+	}
+	resolvePaths(cfg, "/srv/project")
+
+	if cfg.Repository.Path != "/srv/project/repo.git" {
+		t.Errorf("Expected a relative path to be resolved, got %s", cfg.Repository.Path)
+	}
+	if cfg.Repository.Workdir != "/var/lib/orchestrator/tmp" {
+		t.Errorf("Expected an absolute path to be kept, got %s", cfg.Repository.Workdir)
+	}
+	if cfg.State.Path != "/srv/project/state" {
+		t.Errorf("Expected ./state to be resolved, got %s", cfg.State.Path)
+	}
+	if cfg.Scheduler.BacklogPath != "" {
+		t.Errorf("Expected an empty path to stay empty, got %s", cfg.Scheduler.BacklogPath)
+	}
+	if cfg.Environments["prod"].Repository != "/srv/prod.git" {
+		t.Errorf("Expected environment repositories to be resolved, got %s", cfg.Environments["prod"].Repository)
+	}
+}
+
+func TestValidatePaths(t *testing.T) {
+	root := t.TempDir()
+	valid := func() *Config {
+		return &Config{
+			Repository: RepositoryConfig{Path: filepath.Join(root, "repo.git"), Workdir: filepath.Join(root, "tmp")},
+			Scheduler:  SchedulerConfig{BacklogPath: filepath.Join(root, "backlog")},
+			CI:         CIConfig{StatusPath: filepath.Join(root, "ci-status")},
+			State:      StateConfig{Path: filepath.Join(root, "state")},
+			Metrics:    MetricsConfig{OutputPath: filepath.Join(root, "state", "metrics")},
+		}
+	}
+	if err := validatePaths(valid()); err != nil {
+		t.Fatalf("Expected valid paths, got %v", err)
+	}
+
+	notDir := filepath.Join(root, "file")
+	os.WriteFile(notDir, nil, 0644)
+	home, _ := os.UserHomeDir()
+
+	tests := map[string]func(*Config){
+		"workdir inside repo":      func(c *Config) { c.Repository.Workdir = filepath.Join(c.Repository.Path, "tmp") },
+		"state inside repo":        func(c *Config) { c.State.Path = filepath.Join(c.Repository.Path, "state") },
+		"repo inside workdir":      func(c *Config) { c.Repository.Path = filepath.Join(c.Repository.Workdir, "repo.git") },
+		"backlog is workdir":       func(c *Config) { c.Scheduler.BacklogPath = c.Repository.Workdir },
+		"ci status inside backlog": func(c *Config) { c.CI.StatusPath = filepath.Join(c.Scheduler.BacklogPath, "ci") },
+		"workdir is root":          func(c *Config) { c.Repository.Workdir = "/" },
+		"workdir is home":          func(c *Config) { c.Repository.Workdir = home },
+		"state is a file":          func(c *Config) { c.State.Path = notDir },
+		"backlog in environment repo": func(c *Config) {
+			c.Environments = environment.Environments{"prod": {Repository: filepath.Join(root, "prod.git")}}
+			c.Scheduler.BacklogPath = filepath.Join(root, "prod.git", "backlog")
+		},
+	}
+	for name, mutate := range tests {
+		cfg := valid()
+		mutate(cfg)
+		if err := validatePaths(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
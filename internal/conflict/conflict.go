@@ -0,0 +1,255 @@
+// Package conflict checks completed tickets' branches against main and hands
+// conflicts git cannot merge to an agent as a follow-up ticket
+package conflict
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// Tag marks conflict resolution tickets
+const Tag = "conflict"
+
+// maxReport is how much of git's conflict messages goes into a follow-up ticket
+const maxReport = 8 << 10
+
+// maxPending bounds completed tickets waiting for a conflict check
+const maxPending = 256
+
+// Config controls conflict checks
+type Config struct {
+	Enabled  bool `mapstructure:"enabled"`
+	Priority int  `mapstructure:"priority"` // Of resolution tickets; 0 keeps the conflicting ticket's
+}
+
+// Validate checks the conflict settings
+func (c Config) Validate() error {
+	if c.Priority < 0 || c.Priority > 5 {
+		return errors.New("priority must be between 0 and 5")
+	}
+	return nil
+}
+
+// Resolver enqueues a resolution ticket for each completed ticket whose
+// branch conflicts with main
+type Resolver struct {
+	config       Config
+	repoPath     string
+	environments environment.Environments
+	backlogDir   string                       // Resolution tickets are written here
+	onConflict   func(ipc.MergeConflictEvent) // Optional
+	pending      chan ipc.Event
+}
+
+// New creates a resolver for tickets on the repository at repoPath, or on
+// their environment's repository
+func New(config Config, repoPath string, environments environment.Environments, backlogDir string) *Resolver {
+	return &Resolver{
+		config:       config,
+		repoPath:     repoPath,
+		environments: environments,
+		backlogDir:   backlogDir,
+		pending:      make(chan ipc.Event, maxPending),
+	}
+}
+
+// SetConflictHandler sets a function called with each conflict found
+func (r *Resolver) SetConflictHandler(handler func(ipc.MergeConflictEvent)) {
+	r.onConflict = handler
+}
+
+// Record queues completed tickets for Run to check, so git's merge doesn't
+// hold up publishing events; it is meant to be an event observer
+func (r *Resolver) Record(event ipc.Event) {
+	data, ok := event.Data.(ipc.TicketEvent)
+	if !ok || event.Type != ipc.EventTypeTicketComplete || data.Ticket == nil || data.Ticket.Branch == "" {
+		return
+	}
+	select {
+	case r.pending <- event:
+	default:
+		log.Printf("Too many tickets waiting for a conflict check; not checking %s", data.Ticket.ID)
+	}
+}
+
+// Run checks recorded tickets until ctx is done
+func (r *Resolver) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-r.pending:
+			if err := r.Check(event); err != nil {
+				log.Printf("Failed to check for merge conflicts: %v", err)
+			}
+		}
+	}
+}
+
+// Check merges a completed ticket's branch into main in memory and, if git
+// cannot, enqueues a ticket for an agent to resolve the conflicts. Other
+// events and tickets without a branch are ignored.
+func (r *Resolver) Check(event ipc.Event) error {
+	data, ok := event.Data.(ipc.TicketEvent)
+	if !ok || event.Type != ipc.EventTypeTicketComplete || data.Ticket == nil || data.Ticket.Branch == "" {
+		return nil
+	}
+	t := data.Ticket
+
+	repoPath := r.repoPath
+	if settings, err := r.environments.Lookup(t.Environment); err == nil && settings.Repository != "" {
+		repoPath = settings.Repository
+	}
+	repo := gitutils.NewRepo(repoPath)
+	files, messages, err := repo.MergeConflicts(t.Branch)
+	if err != nil {
+		return fmt.Errorf("failed to merge %s into main: %w", t.Branch, err)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	commit, err := repo.GetBranchCommit(t.Branch)
+	if err != nil {
+		return err
+	}
+
+	result := ipc.MergeConflictEvent{
+		TicketID: t.ID,
+		Branch:   t.Branch,
+		Commit:   commit,
+		Files:    files,
+	}
+	if t.Resolves != nil {
+		// A resolution that itself conflicts is left to a person rather than
+		// handed to yet another agent
+		result.Message = fmt.Sprintf("%s still conflicts with main in %s after resolving %s", t.Branch, strings.Join(files, ", "), t.Resolves.Ticket)
+	} else {
+		followUp, err := r.enqueue(t, commit, files, messages)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue conflict resolution for %s: %w", t.ID, err)
+		}
+		result.FollowUp = followUp.ID
+		result.Message = fmt.Sprintf("%s conflicts with main in %s; %s will resolve it", t.Branch, strings.Join(files, ", "), followUp.ID)
+	}
+
+	log.Printf("Ticket %s: %s", t.ID, result.Message)
+	if r.onConflict != nil {
+		r.onConflict(result)
+	}
+	return nil
+}
+
+// ResolutionID names the ticket that resolves a ticket's conflicts
+func ResolutionID(ticketID string) string {
+	return "resolve-" + ticketID
+}
+
+// enqueue writes a ticket to merge t's branch into main and resolve the
+// conflicts, unless one is already in the backlog
+func (r *Resolver) enqueue(t *ticket.Ticket, commit string, files []string, messages string) (*ticket.Ticket, error) {
+	if len(messages) > maxReport {
+		// Cut on a rune boundary so the ticket stays valid UTF-8
+		cut := maxReport
+		for cut > 0 && !utf8.RuneStart(messages[cut]) {
+			cut--
+		}
+		messages = messages[:cut]
+	}
+
+	priority := r.config.Priority
+	if priority == 0 {
+		priority = t.Priority
+	}
+	tags := append([]string{}, t.Tags...)
+	if !containsTag(tags, Tag) {
+		tags = append(tags, Tag)
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Ticket %s (%s) completed on branch %s at %s, but the branch no longer merges cleanly into main.\n\n", t.ID, t.Title, t.Branch, commit)
+	report.WriteString("Git could not merge:\n")
+	for _, file := range files {
+		fmt.Fprintf(&report, "- %s\n", file)
+	}
+	if messages != "" {
+		fmt.Fprintf(&report, "\n%s\n", messages)
+	}
+	fmt.Fprintf(&report, "\nMerge %s into this ticket's branch and resolve the conflicts, keeping what both main and %s set out to do.", t.Branch, t.ID)
+
+	now := time.Now()
+	followUp := &ticket.Ticket{
+		ID:          ResolutionID(t.ID),
+		Title:       "Resolve merge conflicts: " + t.Title,
+		Description: ticket.EscapeTemplate(report.String()),
+		Priority:    priority,
+		Locks:       t.Locks,
+		Tags:        tags,
+		Environment: t.Environment,
+		Done:        t.Done,
+		Resolves: &ticket.Resolution{
+			Ticket: t.ID,
+			Branch: t.Branch,
+			Commit: commit,
+			Files:  files,
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	data, err := followUp.ToYAML()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+	data, _, err = ticket.Stamp(data, ticket.Provenance{
+		EnqueuedBy: "conflict",
+		Source:     t.ID,
+		EnqueuedAt: now.UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.backlogDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backlog directory: %w", err)
+	}
+
+	path := filepath.Join(r.backlogDir, followUp.ID+".yaml")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		log.Printf("Conflict resolution ticket %s is already in the backlog", followUp.ID)
+		return followUp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Enqueued conflict resolution ticket %s for %s", followUp.ID, t.ID)
+	return followUp, nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, candidate := range tags {
+		if strings.EqualFold(candidate, tag) {
+			return true
+		}
+	}
+	return false
+}
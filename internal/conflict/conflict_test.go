@@ -0,0 +1,186 @@
+package conflict
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// newTestRepo returns a bare repository and a clone of it to commit in
+func newTestRepo(t *testing.T) (string, string) {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "repo.git")
+	clone := filepath.Join(tmpDir, "clone")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	git(t, tmpDir, "clone", "-q", repoPath, clone)
+	return repoPath, clone
+}
+
+// commit writes content to shared.txt on branch, starting it from base, and pushes it
+func commit(t *testing.T, clone, branch, base, content string) {
+	t.Helper()
+	git(t, clone, "checkout", "-q", "-B", branch, base)
+	if err := os.WriteFile(filepath.Join(clone, "shared.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write shared.txt: %v", err)
+	}
+	git(t, clone, "add", "shared.txt")
+	git(t, clone, "commit", "-q", "-m", "Write shared.txt on "+branch)
+	git(t, clone, "push", "-q", "origin", branch)
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func completed(tk *ticket.Ticket) ipc.Event {
+	return ipc.Event{Type: ipc.EventTypeTicketComplete, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: tk, WorkerID: 1}}
+}
+
+func TestCheckEnqueuesResolutionTicket(t *testing.T) {
+	repoPath, clone := newTestRepo(t)
+	main := git(t, clone, "rev-parse", "--abbrev-ref", "HEAD")
+	commit(t, clone, "agent-1/feat-a", main, "from feat-a\n")
+	commit(t, clone, main, main, "from main\n")
+
+	backlogDir := filepath.Join(t.TempDir(), "backlog")
+	resolver := New(Config{Enabled: true}, repoPath, environment.Environments{}, backlogDir)
+	var events []ipc.MergeConflictEvent
+	resolver.SetConflictHandler(func(event ipc.MergeConflictEvent) { events = append(events, event) })
+
+	tk := &ticket.Ticket{ID: "feat-a", Title: "Feature A", Priority: 2, Tags: []string{"team-a"}, Branch: "agent-1/feat-a"}
+	if err := resolver.Check(completed(tk)); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(events) != 1 || events[0].FollowUp != "resolve-feat-a" || len(events[0].Files) != 1 || events[0].Files[0] != "shared.txt" {
+		t.Fatalf("Expected a conflict in shared.txt resolved by resolve-feat-a, got %+v", events)
+	}
+
+	followUp, err := ticket.Load(filepath.Join(backlogDir, "resolve-feat-a.yaml"))
+	if err != nil {
+		t.Fatalf("Expected a resolution ticket in the backlog: %v", err)
+	}
+	if followUp.Resolves == nil || followUp.Resolves.Ticket != "feat-a" || followUp.Resolves.Branch != "agent-1/feat-a" || followUp.Resolves.Commit != events[0].Commit {
+		t.Errorf("Expected the resolution ticket to link back to feat-a, got %+v", followUp.Resolves)
+	}
+	if followUp.Priority != 2 || !containsTag(followUp.Tags, "team-a") || !containsTag(followUp.Tags, Tag) {
+		t.Errorf("Expected feat-a's priority and tags plus %s, got %d %v", Tag, followUp.Priority, followUp.Tags)
+	}
+	if !strings.Contains(followUp.Description, "CONFLICT") {
+		t.Errorf("Expected git's conflict report in the description, got %q", followUp.Description)
+	}
+	if followUp.Provenance == nil || followUp.Provenance.Source != "feat-a" {
+		t.Errorf("Expected provenance pointing at feat-a, got %+v", followUp.Provenance)
+	}
+
+	// A resolution that still conflicts is reported but not handed on again
+	resolution := &ticket.Ticket{ID: followUp.ID, Branch: "agent-1/feat-a", Resolves: followUp.Resolves}
+	if err := resolver.Check(completed(resolution)); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(events) != 2 || events[1].FollowUp != "" {
+		t.Errorf("Expected a conflict without a follow-up, got %+v", events)
+	}
+	if _, err := os.Stat(filepath.Join(backlogDir, "resolve-resolve-feat-a.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected no resolution ticket for a resolution")
+	}
+}
+
+func TestCheckIgnoresCleanBranches(t *testing.T) {
+	repoPath, clone := newTestRepo(t)
+	main := git(t, clone, "rev-parse", "--abbrev-ref", "HEAD")
+	commit(t, clone, "agent-1/feat-b", main, "from feat-b\n")
+
+	backlogDir := filepath.Join(t.TempDir(), "backlog")
+	resolver := New(Config{Enabled: true}, repoPath, environment.Environments{}, backlogDir)
+	resolver.SetConflictHandler(func(event ipc.MergeConflictEvent) {
+		t.Errorf("Expected no conflict, got %+v", event)
+	})
+
+	tk := &ticket.Ticket{ID: "feat-b", Title: "Feature B", Priority: 3, Branch: "agent-1/feat-b"}
+	if err := resolver.Check(completed(tk)); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if _, err := os.Stat(backlogDir); !os.IsNotExist(err) {
+		t.Error("Expected nothing written to the backlog")
+	}
+}
+
+func TestRunChecksRecordedTickets(t *testing.T) {
+	repoPath, clone := newTestRepo(t)
+	main := git(t, clone, "rev-parse", "--abbrev-ref", "HEAD")
+	commit(t, clone, "agent-1/feat-c", main, "from feat-c\n")
+	commit(t, clone, main, main, "from main\n")
+
+	resolver := New(Config{Enabled: true}, repoPath, environment.Environments{}, filepath.Join(t.TempDir(), "backlog"))
+	found := make(chan ipc.MergeConflictEvent, 1)
+	resolver.SetConflictHandler(func(event ipc.MergeConflictEvent) { found <- event })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go resolver.Run(ctx)
+
+	// Recording returns straight away; the merge happens on Run's goroutine
+	resolver.Record(ipc.Event{Type: ipc.EventTypeTicketStarted, Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-c", Branch: "agent-1/feat-c"}}})
+	resolver.Record(completed(&ticket.Ticket{ID: "feat-c", Title: "Feature C", Priority: 3, Branch: "agent-1/feat-c"}))
+	select {
+	case event := <-found:
+		if event.TicketID != "feat-c" {
+			t.Errorf("Expected a conflict for feat-c, got %+v", event)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the recorded ticket to be checked")
+	}
+}
+
+func TestEnqueueTruncatesReportOnRuneBoundary(t *testing.T) {
+	resolver := New(Config{Enabled: true}, "", environment.Environments{}, t.TempDir())
+	// A three-byte rune straddles maxReport
+	messages := strings.Repeat("a", maxReport-1) + "€" + strings.Repeat("b", 10)
+	tk := &ticket.Ticket{ID: "feat-d", Title: "Feature D", Priority: 3, Branch: "agent-1/feat-d"}
+	followUp, err := resolver.enqueue(tk, "abc123", []string{"shared.txt"}, messages)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if !utf8.ValidString(followUp.Description) {
+		t.Error("Expected the truncated report to be valid UTF-8")
+	}
+	if strings.Contains(followUp.Description, "€") || !strings.Contains(followUp.Description, strings.Repeat("a", maxReport-1)) {
+		t.Error("Expected the report cut just before the straddling rune")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{Priority: 1}).Validate(); err != nil {
+		t.Errorf("Expected priority 1 to be valid, got %v", err)
+	}
+	if err := (Config{Priority: 6}).Validate(); err == nil {
+		t.Error("Expected priority 6 to be rejected")
+	}
+}
@@ -0,0 +1,289 @@
+package dashboard
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+	"github.com/brettsmith212/amp-orchestrator/internal/websocket"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+)
+
+// Bounds on what the dashboard keeps in memory
+const (
+	maxEvents    = 100 // Recent events replayed when a page loads
+	maxCIResults = 50  // CI results shown
+	clientBuffer = 64  // Events waiting for a slow browser before it is dropped
+)
+
+//go:embed static
+var static embed.FS
+
+// Config holds the dashboard's settings and the daemon state it shows
+type Config struct {
+	Auth    *ipc.Authenticator           // Nil leaves the dashboard open; otherwise a viewer token is required
+	Queue   func() []*ticket.Ticket      // Queued tickets in order
+	Workers func() []worker.WorkerStatus // Local workers
+	CI      func() ([]*ci.Status, error) // Recent CI results
+
+	Timeline func(ticketID string) (timeline.Timeline, error) // A ticket's phases and markers
+}
+
+// State is the snapshot behind the dashboard's tables
+type State struct {
+	Queue   []*ticket.Ticket      `json:"queue"`
+	Workers []worker.WorkerStatus `json:"workers"`
+	CI      []*ci.Status          `json:"ci"`
+	Events  []ipc.Event           `json:"events"`
+}
+
+// Server serves the dashboard and streams the daemon's events to it
+type Server struct {
+	config   Config
+	server   *http.Server
+	listener net.Listener
+
+	mu      sync.Mutex
+	events  []ipc.Event
+	clients map[chan []byte]struct{}
+}
+
+// New creates a dashboard server
+func New(config Config) *Server {
+	return &Server{
+		config:  config,
+		clients: make(map[chan []byte]struct{}),
+	}
+}
+
+// Start serves the dashboard on address (host:port)
+func (s *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Dashboard server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the dashboard listens on
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop closes the listener and disconnects browsers
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	for client := range s.clients {
+		close(client)
+		delete(s.clients, client)
+	}
+	s.mu.Unlock()
+
+	if s.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// Handler returns the dashboard's HTTP routes
+func (s *Server) Handler() http.Handler {
+	assets, _ := fs.Sub(static, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServer(http.FS(assets)))
+	mux.HandleFunc("GET /api/state", s.handleState)
+	mux.HandleFunc("GET /api/tickets/{id}/timeline", s.handleTimeline)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	return s.authorize(mux)
+}
+
+// Publish records an event and sends it to connected browsers
+func (s *Server) Publish(event ipc.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Dashboard: failed to marshal %s event: %v", event.Type, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+	if len(s.events) > maxEvents {
+		s.events = s.events[len(s.events)-maxEvents:]
+	}
+
+	for client := range s.clients {
+		select {
+		case client <- data:
+		default:
+			// The browser isn't keeping up; it reconnects and reloads the state
+			close(client)
+			delete(s.clients, client)
+		}
+	}
+}
+
+// State collects the current snapshot
+func (s *Server) State() State {
+	state := State{Queue: []*ticket.Ticket{}, Workers: []worker.WorkerStatus{}, CI: []*ci.Status{}}
+	if s.config.Queue != nil {
+		state.Queue = append(state.Queue, s.config.Queue()...)
+	}
+	if s.config.Workers != nil {
+		state.Workers = append(state.Workers, s.config.Workers()...)
+	}
+	if s.config.CI != nil {
+		statuses, err := s.config.CI()
+		if err != nil {
+			log.Printf("Dashboard: failed to read CI results: %v", err)
+		}
+		sort.Slice(statuses, func(i, j int) bool {
+			return statuses[i].Timestamp.After(statuses[j].Timestamp)
+		})
+		if len(statuses) > maxCIResults {
+			statuses = statuses[:maxCIResults]
+		}
+		state.CI = append(state.CI, statuses...)
+	}
+
+	s.mu.Lock()
+	state.Events = append([]ipc.Event{}, s.events...)
+	s.mu.Unlock()
+	return state
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.State())
+}
+
+func (s *Server) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	if s.config.Timeline == nil {
+		http.Error(w, "timelines are not available", http.StatusNotFound)
+		return
+	}
+	tl, err := s.config.Timeline(r.PathValue("id"))
+	if err != nil {
+		log.Printf("Dashboard: failed to build timeline: %v", err)
+		http.Error(w, "failed to build timeline", http.StatusInternalServerError)
+		return
+	}
+	if len(tl.Steps) == 0 {
+		http.Error(w, "no events for this ticket", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, tl)
+}
+
+// handleEvents streams events to a browser over a WebSocket
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	client := make(chan []byte, clientBuffer)
+	s.mu.Lock()
+	s.clients[client] = struct{}{}
+	s.mu.Unlock()
+	defer s.removeClient(client)
+
+	// Browsers only send control frames; watch for close and answer pings
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := conn.ReadFrame()
+			if err != nil || opcode == websocket.OpClose {
+				return
+			}
+			if opcode == websocket.OpPing {
+				conn.WriteFrame(websocket.OpPong, payload)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case data, ok := <-client:
+			if !ok {
+				conn.WriteFrame(websocket.OpClose, nil)
+				return
+			}
+			if err := conn.WriteFrame(websocket.OpText, data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) removeClient(client chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[client]; ok {
+		close(client)
+		delete(s.clients, client)
+	}
+}
+
+// authorize requires a viewer token when IPC auth is configured, taken from
+// an Authorization: Bearer header or, for pages and WebSockets, ?token=
+func (s *Server) authorize(next http.Handler) http.Handler {
+	if s.config.Auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Static assets carry no daemon state
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimPrefix(header, "Bearer ")
+		}
+		if _, role, err := s.config.Auth.Authenticate(token); err != nil || !role.Allows(ipc.RoleViewer) {
+			http.Error(w, "a viewer token is required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Dashboard: failed to write response: %v", err)
+	}
+}
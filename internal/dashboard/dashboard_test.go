@@ -0,0 +1,217 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+	"github.com/brettsmith212/amp-orchestrator/internal/websocket"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+)
+
+func newTestServer(auth *ipc.Authenticator) *Server {
+	now := time.Now()
+	return New(Config{
+		Auth: auth,
+		Queue: func() []*ticket.Ticket {
+			return []*ticket.Ticket{{ID: "feat-2", Title: "Queued", Priority: 2}}
+		},
+		Workers: func() []worker.WorkerStatus {
+			return []worker.WorkerStatus{{ID: 1, IsRunning: true, CurrentTicket: &worker.TicketInfo{ID: "feat-1"}}}
+		},
+		CI: func() ([]*ci.Status, error) {
+			return []*ci.Status{
+				{Ref: "agent-1/feat-0", Status: "FAIL", Timestamp: now.Add(-time.Hour)},
+				{Ref: "agent-1/feat-1", Status: "PASS", Timestamp: now},
+			}, nil
+		},
+		Timeline: func(id string) (timeline.Timeline, error) {
+			if id != "feat-1" {
+				return timeline.Timeline{Ticket: id}, nil
+			}
+			return timeline.Timeline{Ticket: id, Start: now, End: now.Add(time.Minute), Steps: []timeline.Step{
+				{Name: "enqueued", Start: now, End: now.Add(time.Minute)},
+				{Name: "started", Start: now.Add(time.Minute), WorkerID: 1},
+			}}, nil
+		},
+	})
+}
+
+func ticketEvent(eventType ipc.EventType, id string, at time.Time) ipc.Event {
+	return ipc.Event{
+		Type:      eventType,
+		Timestamp: at,
+		Data:      ipc.TicketEvent{Ticket: &ticket.Ticket{ID: id}, WorkerID: 1, Message: string(eventType) + " " + id},
+	}
+}
+
+func TestStateAndTimeline(t *testing.T) {
+	s := newTestServer(nil)
+	start := time.Now().Add(-time.Minute)
+	s.Publish(ticketEvent(ipc.EventTypeTicketEnqueued, "feat-1", start))
+	s.Publish(ticketEvent(ipc.EventTypeTicketStarted, "feat-1", start.Add(10*time.Second)))
+	s.Publish(ipc.Event{Type: ipc.EventTypeDiskSpace, Timestamp: start, Data: ipc.DiskSpaceEvent{Path: "/work"}})
+
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	var state State
+	getJSON(t, server.URL+"/api/state", http.StatusOK, &state)
+	if len(state.Queue) != 1 || len(state.Workers) != 1 || len(state.Events) != 3 {
+		t.Errorf("Unexpected state %+v", state)
+	}
+	if len(state.CI) != 2 || state.CI[0].Ref != "agent-1/feat-1" {
+		t.Errorf("Expected newest CI result first, got %+v", state.CI)
+	}
+
+	var tl timeline.Timeline
+	getJSON(t, server.URL+"/api/tickets/feat-1/timeline", http.StatusOK, &tl)
+	if len(tl.Steps) != 2 || tl.Steps[0].Name != "enqueued" || tl.Steps[0].Duration() != time.Minute {
+		t.Errorf("Unexpected timeline %+v", tl)
+	}
+	if tl.Steps[1].WorkerID != 1 || !tl.Steps[1].End.IsZero() {
+		t.Errorf("Unexpected timeline step %+v", tl.Steps[1])
+	}
+	getJSON(t, server.URL+"/api/tickets/unknown/timeline", http.StatusNotFound, nil)
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "Amp Orchestrator") {
+		t.Errorf("Expected the embedded page, got %q", body)
+	}
+}
+
+func TestRequiresViewerToken(t *testing.T) {
+	t.Setenv("TEST_DASHBOARD_TOKEN", "viewer-secret")
+	auth, err := ipc.NewAuthenticator(ipc.AuthConfig{Tokens: []ipc.TokenConfig{
+		{Name: "browser", TokenEnv: "TEST_DASHBOARD_TOKEN", Role: ipc.RoleViewer},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	server := httptest.NewServer(newTestServer(auth).Handler())
+	defer server.Close()
+
+	getJSON(t, server.URL+"/api/state", http.StatusUnauthorized, nil)
+	getJSON(t, server.URL+"/api/state?token=wrong", http.StatusUnauthorized, nil)
+	getJSON(t, server.URL+"/api/state?token=viewer-secret", http.StatusOK, nil)
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/state", nil)
+	req.Header.Set("Authorization", "Bearer viewer-secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected bearer token to be accepted, got %v (err %v)", resp, err)
+	}
+
+	// The page itself loads without a token so it can pass one on
+	resp, err = http.Get(server.URL + "/app.js")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected static assets without a token, got %v (err %v)", resp, err)
+	}
+}
+
+func TestStreamsEventsOverWebSocket(t *testing.T) {
+	s := newTestServer(nil)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	handshake := "GET /api/events HTTP/1.1\r\n" +
+		"Host: dashboard\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(handshake)); err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	// Wait for the server to register the browser before publishing
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.clients)
+		s.mu.Unlock()
+		if n == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Publish(ticketEvent(ipc.EventTypeTicketComplete, "feat-1", time.Now()))
+
+	ws := websocket.NewConn(conn, reader, true)
+	opcode, payload, err := ws.ReadFrame()
+	if err != nil || opcode != websocket.OpText {
+		t.Fatalf("Expected a text frame, got opcode %d (err %v)", opcode, err)
+	}
+	var event struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil || event.Type != "ticket_complete" {
+		t.Errorf("Unexpected event %q (err %v)", payload, err)
+	}
+
+	// A masked close frame from the browser ends the stream
+	if _, err := conn.Write([]byte{0x80 | websocket.OpClose, 0x80, 1, 2, 3, 4}); err != nil {
+		t.Fatalf("Failed to send close: %v", err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.clients)
+		s.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the browser to be removed after closing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func getJSON(t *testing.T, url string, status int, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Fatalf("GET %s: expected status %d, got %d", url, status, resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("GET %s: invalid JSON: %v", url, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Dashboard client: polls /api/state and streams /api/events over a WebSocket.
+// A ?token= in the page URL is passed on when ipc.auth is configured.
+"use strict";
+
+const token = new URLSearchParams(location.search).get("token");
+const maxEvents = 100;
+
+function withToken(path) {
+  return token ? path + "?token=" + encodeURIComponent(token) : path;
+}
+
+function el(tag, text, className) {
+  const node = document.createElement(tag);
+  if (text !== undefined) node.textContent = text;
+  if (className) node.className = className;
+  return node;
+}
+
+function row(cells) {
+  const tr = el("tr");
+  for (const cell of cells) {
+    const td = el("td");
+    if (cell instanceof Node) td.appendChild(cell);
+    else td.textContent = cell === undefined || cell === null ? "" : cell;
+    tr.appendChild(td);
+  }
+  return tr;
+}
+
+function fill(id, rows) {
+  const body = document.querySelector("#" + id + " tbody");
+  body.replaceChildren(...rows);
+}
+
+function time(value) {
+  return value ? new Date(value).toLocaleTimeString() : "";
+}
+
+function duration(ms) {
+  const s = Math.round(ms / 1000);
+  if (s < 60) return s + "s";
+  if (s < 3600) return Math.floor(s / 60) + "m " + (s % 60) + "s";
+  return Math.floor(s / 3600) + "h " + Math.floor((s % 3600) / 60) + "m";
+}
+
+function ticketLink(id) {
+  const a = el("a", id);
+  a.onclick = () => showTimeline(id);
+  return a;
+}
+
+function renderState(state) {
+  fill("workers", state.workers.map(w => {
+    const status = w.current_ticket ? "working" : "idle";
+    return row([
+      "Worker " + w.id,
+      el("span", status, status),
+      w.current_ticket ? ticketLink(w.current_ticket.id) : "",
+    ]);
+  }));
+
+  document.getElementById("queue-length").textContent = "(" + state.queue.length + ")";
+  fill("queue", state.queue.map(t => row([ticketLink(t.id), t.title, t.priority])));
+
+  fill("ci", state.ci.map(s => row([
+    time(s.timestamp),
+    s.ticket_id ? ticketLink(s.ticket_id) : "",
+    s.ref,
+    el("code", (s.commit || "").slice(0, 8)),
+    el("span", s.status, s.status),
+  ])));
+}
+
+function describe(event) {
+  const data = event.data || {};
+  if (data.message) return data.message;
+  if (data.ticket) return data.ticket.id;
+  return "";
+}
+
+function addEvent(event) {
+  const list = document.getElementById("events");
+  const item = el("li");
+  item.appendChild(el("span", time(event.timestamp), "time"));
+  item.appendChild(el("strong", event.type + " "));
+  item.appendChild(document.createTextNode(describe(event)));
+  list.prepend(item);
+  while (list.children.length > maxEvents) list.lastChild.remove();
+}
+
+async function refresh() {
+  try {
+    const response = await fetch(withToken("api/state"));
+    if (!response.ok) throw new Error(response.statusText);
+    const state = await response.json();
+    renderState(state);
+    return state;
+  } catch (err) {
+    console.error("Failed to load state", err);
+  }
+}
+
+async function showTimeline(id) {
+  const section = document.getElementById("timeline-section");
+  document.getElementById("timeline-ticket").textContent = id;
+  section.hidden = false;
+
+  const response = await fetch(withToken("api/tickets/" + encodeURIComponent(id) + "/timeline"));
+  if (!response.ok) {
+    fill("timeline", [row(["", "No events recorded for this ticket yet"])]);
+    return;
+  }
+  const timeline = await response.json();
+  const start = new Date(timeline.start);
+  const total = Math.max(new Date(timeline.end) - start, 1);
+  fill("timeline", timeline.steps.map(step => {
+    const from = new Date(step.start);
+    const to = step.end && !step.end.startsWith("0001") ? new Date(step.end) : null;
+    const gantt = el("div", undefined, "gantt");
+    const bar = gantt.appendChild(el("div", undefined, step.marker ? "bar marker" : "bar"));
+    bar.style.marginLeft = ((from - start) / total * 100) + "%";
+    bar.style.width = to ? Math.max((to - from) / total * 100, 0.5) + "%" : "";
+    const detail = step.worker_id && !step.marker ? "worker " + step.worker_id : (step.detail || "");
+    return row([time(step.start), step.name, gantt, to ? duration(to - from) : "", detail]);
+  }));
+}
+
+function connect() {
+  const scheme = location.protocol === "https:" ? "wss:" : "ws:";
+  const url = new URL(withToken("api/events"), location.href);
+  url.protocol = scheme;
+
+  const status = document.getElementById("connection");
+  const socket = new WebSocket(url);
+  socket.onopen = () => {
+    status.textContent = "live";
+    status.className = "online";
+  };
+  socket.onmessage = message => {
+    const event = JSON.parse(message.data);
+    addEvent(event);
+    if (event.type !== "command_response") refresh();
+  };
+  socket.onclose = () => {
+    status.textContent = "offline";
+    status.className = "offline";
+    setTimeout(connect, 5000);
+  };
+}
+
+refresh().then(state => {
+  if (state) state.events.forEach(addEvent);
+  connect();
+});
+setInterval(refresh, 15000);
@@ -0,0 +1,77 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/journal"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// FileName is the journal's name within the state directory
+const FileName = "dead_letter.jsonl"
+
+// Entry records a ticket a worker gave up on and why
+type Entry struct {
+	Time     time.Time      `json:"time"`
+	Ticket   *ticket.Ticket `json:"ticket"`
+	WorkerID int            `json:"worker_id,omitempty"`
+	Code     ipc.ErrorCode  `json:"code"`
+	Message  string         `json:"message,omitempty"`
+}
+
+// Journal appends failed tickets to a JSON lines file
+// With an encrypting cipher each line is sealed and base64 encoded
+type Journal struct {
+	file *journal.Writer
+}
+
+// Open returns the journal in stateDir
+func Open(stateDir string, cipher *encryption.Cipher) *Journal {
+	return &Journal{file: journal.NewWriter(filepath.Join(stateDir, FileName), cipher)}
+}
+
+// Record appends the ticket of a ticket_failed event
+func (j *Journal) Record(event ipc.Event) error {
+	failed, ok := event.Data.(ipc.TicketEvent)
+	if event.Type != ipc.EventTypeTicketFailed || !ok || failed.Ticket == nil {
+		return nil
+	}
+	code := failed.Code
+	if code == "" {
+		code = ipc.ErrorCodeAgentFailed
+	}
+	return j.Append(Entry{
+		Time:     event.Timestamp.UTC(),
+		Ticket:   failed.Ticket,
+		WorkerID: failed.WorkerID,
+		Code:     code,
+		Message:  failed.Message,
+	})
+}
+
+// Append writes an entry to the end of the journal
+func (j *Journal) Append(entry Entry) error {
+	return j.file.Append(entry)
+}
+
+// Load reads every entry in the journal in stateDir, oldest first
+// Encrypted lines are decrypted with cipher
+func Load(stateDir string, cipher *encryption.Cipher) ([]Entry, error) {
+	var entries []Entry
+	err := journal.Read(filepath.Join(stateDir, FileName), cipher, func(data []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package deadletter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestJournalRecordAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	journal := Open(dir, nil)
+
+	failed := &ticket.Ticket{ID: "feat-1", Title: "Login page"}
+	events := []ipc.Event{
+		{Type: ipc.EventTypeTicketFailed, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: failed, WorkerID: 2, Code: ipc.ErrorCodeCIFailed, Message: "CI failed: tests failed"}},
+		{Type: ipc.EventTypeTicketFailed, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-2"}, Message: "amp CLI failed"}},
+		// Only failures are dead letters
+		{Type: ipc.EventTypeTicketComplete, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-3"}}},
+	}
+	for _, event := range events {
+		if err := journal.Record(event); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	entries, err := Load(dir, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Ticket.ID != "feat-1" || entries[0].Ticket.Title != "Login page" || entries[0].WorkerID != 2 || entries[0].Code != ipc.ErrorCodeCIFailed {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].Code != ipc.ErrorCodeAgentFailed {
+		t.Errorf("Expected failures without a code to count as agent failures, got %q", entries[1].Code)
+	}
+
+	if info, _ := os.Stat(filepath.Join(dir, FileName)); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected journal mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestJournalEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	entry := Entry{Time: time.Now(), Ticket: &ticket.Ticket{ID: "secret-1"}, Code: ipc.ErrorCodeAuth}
+	if err := Open(dir, cipher).Append(entry); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, FileName))
+	if bytes.Contains(data, []byte("secret-1")) {
+		t.Error("Expected the entry to be encrypted on disk")
+	}
+	entries, err := Load(dir, cipher)
+	if err != nil || len(entries) != 1 || entries[0].Ticket.ID != "secret-1" {
+		t.Errorf("Expected the entry back, got %+v (err %v)", entries, err)
+	}
+}
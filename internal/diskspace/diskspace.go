@@ -0,0 +1,81 @@
+package diskspace
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// FreeMB returns the space available to unprivileged users on the
+// filesystem holding path, in megabytes
+func FreeMB(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to stat filesystem of %s: %w", path, err)
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize) / (1024 * 1024), nil
+}
+
+// Status is the free space of one monitored path
+type Status struct {
+	Path   string
+	FreeMB uint64
+	Low    bool
+}
+
+// Monitor watches paths for free space dropping below a threshold
+type Monitor struct {
+	paths     []string
+	minFreeMB uint64
+	low       map[string]bool
+	freeMB    func(string) (uint64, error)
+}
+
+// NewMonitor creates a monitor for paths with the given threshold
+func NewMonitor(minFreeMB int, paths ...string) *Monitor {
+	return &Monitor{
+		paths:     paths,
+		minFreeMB: uint64(minFreeMB),
+		low:       make(map[string]bool),
+		freeMB:    FreeMB,
+	}
+}
+
+// Check measures every path and returns the ones whose state changed
+// since the previous check. Paths that can't be measured are skipped.
+func (m *Monitor) Check() ([]Status, error) {
+	var changed []Status
+	var firstErr error
+
+	for _, path := range m.paths {
+		free, err := m.freeMB(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		low := free < m.minFreeMB
+		if low != m.low[path] {
+			changed = append(changed, Status{Path: path, FreeMB: free, Low: low})
+		}
+		m.low[path] = low
+	}
+
+	return changed, firstErr
+}
+
+// Low reports whether any path was below the threshold at the last check
+func (m *Monitor) Low() bool {
+	for _, low := range m.low {
+		if low {
+			return true
+		}
+	}
+	return false
+}
+
+// MinFreeMB returns the threshold
+func (m *Monitor) MinFreeMB() uint64 {
+	return m.minFreeMB
+}
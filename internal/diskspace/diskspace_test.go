@@ -0,0 +1,65 @@
+package diskspace
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFreeMB(t *testing.T) {
+	free, err := FreeMB(t.TempDir())
+	if err != nil {
+		t.Fatalf("FreeMB failed: %v", err)
+	}
+	if free == 0 {
+		t.Error("Expected some free space in the temp directory")
+	}
+
+	if _, err := FreeMB("/does/not/exist"); err == nil {
+		t.Error("Expected error for missing path, got nil")
+	}
+}
+
+func TestMonitorCheck(t *testing.T) {
+	free := map[string]uint64{"/work": 5000, "/repo": 5000}
+	m := NewMonitor(1024, "/work", "/repo")
+	m.freeMB = func(path string) (uint64, error) {
+		if path == "/broken" {
+			return 0, errors.New("stat failed")
+		}
+		return free[path], nil
+	}
+
+	// Healthy paths start out unchanged
+	changed, err := m.Check()
+	if err != nil || len(changed) != 0 || m.Low() {
+		t.Fatalf("Expected no changes, got %v (err %v)", changed, err)
+	}
+
+	free["/work"] = 100
+	changed, err = m.Check()
+	if err != nil || len(changed) != 1 || changed[0].Path != "/work" || !changed[0].Low || changed[0].FreeMB != 100 {
+		t.Fatalf("Expected /work to turn low, got %v (err %v)", changed, err)
+	}
+	if !m.Low() {
+		t.Error("Expected monitor to report low space")
+	}
+
+	// Still low: no change reported
+	if changed, _ := m.Check(); len(changed) != 0 {
+		t.Errorf("Expected no repeated change, got %v", changed)
+	}
+
+	free["/work"] = 2000
+	changed, _ = m.Check()
+	if len(changed) != 1 || changed[0].Low {
+		t.Fatalf("Expected /work to recover, got %v", changed)
+	}
+	if m.Low() {
+		t.Error("Expected monitor to report enough space")
+	}
+
+	m.paths = append(m.paths, "/broken")
+	if _, err := m.Check(); err == nil {
+		t.Error("Expected error for unmeasurable path, got nil")
+	}
+}
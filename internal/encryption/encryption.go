@@ -0,0 +1,210 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// DefaultKeyEnv is the variable the key is read from when key_env is unset
+const DefaultKeyEnv = "ORCHESTRATOR_ENCRYPTION_KEY"
+
+// header marks encrypted files; anything else is treated as plaintext
+var header = []byte("AMPENC1\n")
+
+// ErrNoKey is returned when reading an encrypted file without a key
+var ErrNoKey = errors.New("file is encrypted but no encryption key is configured")
+
+// Config enables encryption at rest with an AES-256 key
+// The key is 32 bytes, base64 or hex encoded, e.g. from `openssl rand -base64 32`
+type Config struct {
+	Enabled bool   `mapstructure:"enabled"`
+	KeyEnv  string `mapstructure:"key_env"`  // Variable holding the key; defaults to ORCHESTRATOR_ENCRYPTION_KEY
+	KeyFile string `mapstructure:"key_file"` // Or a file holding the key; takes precedence
+}
+
+// Cipher encrypts and decrypts with AES-256-GCM
+// A nil *Cipher passes plaintext through and refuses encrypted data
+type Cipher struct {
+	aead        cipher.AEAD
+	decryptOnly bool // Set when a key is available but encryption is disabled
+}
+
+// Load returns the configured cipher, or nil when encryption is disabled
+// With encryption disabled a key is still loaded if one is available, so
+// files encrypted earlier stay readable
+func Load(config Config) (*Cipher, error) {
+	keyText, source, err := readKey(config)
+	if err != nil {
+		return nil, err
+	}
+	if keyText == "" {
+		if config.Enabled {
+			return nil, fmt.Errorf("encryption is enabled but %s is not set", source)
+		}
+		return nil, nil
+	}
+
+	key, err := decodeKey(keyText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key in %s: %w", source, err)
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	c.decryptOnly = !config.Enabled
+	return c, nil
+}
+
+// readKey returns the key text and where it was looked up
+func readKey(config Config) (string, string, error) {
+	if config.KeyFile != "" {
+		data, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			if os.IsNotExist(err) && !config.Enabled {
+				return "", config.KeyFile, nil
+			}
+			return "", config.KeyFile, fmt.Errorf("failed to read encryption key: %w", err)
+		}
+		return strings.TrimSpace(string(data)), config.KeyFile, nil
+	}
+
+	keyEnv := config.KeyEnv
+	if keyEnv == "" {
+		keyEnv = DefaultKeyEnv
+	}
+	return strings.TrimSpace(os.Getenv(keyEnv)), keyEnv, nil
+}
+
+// decodeKey accepts a 32 byte key as base64 or hex
+func decodeKey(text string) ([]byte, error) {
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, errors.New("expected 32 bytes, base64 or hex encoded")
+}
+
+// NewCipher creates a cipher from a 32 byte key
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// IsEncrypted reports whether data was produced by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, header)
+}
+
+// Encrypts reports whether the cipher writes encrypted data
+func (c *Cipher) Encrypts() bool {
+	return c != nil && !c.decryptOnly
+}
+
+// Encrypt seals data; a nil or decrypt-only cipher returns it unchanged
+func (c *Cipher) Encrypt(data []byte) ([]byte, error) {
+	if c == nil || c.decryptOnly {
+		return data, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := append([]byte(nil), header...)
+	out = append(out, nonce...)
+	return c.aead.Seal(out, nonce, data, header), nil
+}
+
+// Decrypt opens data produced by Encrypt; plaintext is returned unchanged
+func (c *Cipher) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrNoKey
+	}
+
+	sealed := data[len(header):]
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// ReadFile reads and decrypts a file
+func (c *Cipher) ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(data)
+}
+
+// WriteFile encrypts data and writes it atomically
+func (c *Cipher) WriteFile(path string, data []byte, perm os.FileMode) error {
+	sealed, err := c.Encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadTicket loads a ticket file that may be encrypted
+func (c *Cipher) LoadTicket(path string) (*ticket.Ticket, error) {
+	data, err := c.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ticket file %s: %w", path, err)
+	}
+	t, err := ticket.LoadFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ticket %s: %w", path, err)
+	}
+	return t, nil
+}
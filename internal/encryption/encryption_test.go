@@ -0,0 +1,150 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	c, err := NewCipher(newKey(t))
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+
+	plaintext := []byte("description: sensitive details\n")
+	sealed, err := c.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !IsEncrypted(sealed) || bytes.Contains(sealed, []byte("sensitive")) {
+		t.Fatalf("Expected sealed output, got %q", sealed)
+	}
+
+	opened, err := c.Decrypt(sealed)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Decrypt returned %q (err %v)", opened, err)
+	}
+
+	// Plaintext passes through, so files written before encryption stay readable
+	if opened, err := c.Decrypt(plaintext); err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Expected plaintext passthrough, got %q (err %v)", opened, err)
+	}
+
+	// Tampering is detected
+	sealed[len(sealed)-1] ^= 0xff
+	if _, err := c.Decrypt(sealed); err == nil {
+		t.Error("Expected error for tampered data, got nil")
+	}
+
+	// A different key cannot decrypt
+	other, _ := NewCipher(newKey(t))
+	sealed, _ = c.Encrypt(plaintext)
+	if _, err := other.Decrypt(sealed); err == nil {
+		t.Error("Expected error decrypting with the wrong key, got nil")
+	}
+
+	// Without a key, encrypted data is refused and plaintext passes through
+	var none *Cipher
+	if _, err := none.Decrypt(sealed); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+	if out, _ := none.Encrypt(plaintext); !bytes.Equal(out, plaintext) {
+		t.Errorf("Expected nil cipher to leave data unchanged, got %q", out)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	key := newKey(t)
+
+	// Disabled without a key means no cipher
+	t.Setenv("ORCHESTRATOR_ENCRYPTION_KEY", "")
+	if c, err := Load(Config{}); err != nil || c != nil {
+		t.Errorf("Expected nil cipher, got %v (err %v)", c, err)
+	}
+	if _, err := Load(Config{Enabled: true}); err == nil {
+		t.Error("Expected error when enabled without a key, got nil")
+	}
+
+	t.Setenv("ORCHESTRATOR_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(key))
+	c, err := Load(Config{Enabled: true})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	sealed, _ := c.Encrypt([]byte("secret"))
+	if !IsEncrypted(sealed) {
+		t.Error("Expected an enabled cipher to encrypt")
+	}
+
+	// Disabled with a key available decrypts but writes plaintext
+	readOnly, err := Load(Config{})
+	if err != nil || readOnly == nil {
+		t.Fatalf("Expected decrypt-only cipher, got %v (err %v)", readOnly, err)
+	}
+	if out, _ := readOnly.Encrypt([]byte("secret")); IsEncrypted(out) {
+		t.Error("Expected decrypt-only cipher to write plaintext")
+	}
+	if out, err := readOnly.Decrypt(sealed); err != nil || string(out) != "secret" {
+		t.Errorf("Expected decrypt-only cipher to decrypt, got %q (err %v)", out, err)
+	}
+
+	// Hex keys from a file
+	keyFile := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(keyFile, []byte(hex.EncodeToString(key)+"\n"), 0600)
+	fromFile, err := Load(Config{Enabled: true, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("Load from key file failed: %v", err)
+	}
+	if out, err := fromFile.Decrypt(sealed); err != nil || string(out) != "secret" {
+		t.Errorf("Expected the same key from the file, got %q (err %v)", out, err)
+	}
+
+	t.Setenv("ORCHESTRATOR_ENCRYPTION_KEY", "too-short")
+	if _, err := Load(Config{Enabled: true}); err == nil {
+		t.Error("Expected error for a malformed key, got nil")
+	}
+}
+
+func TestWriteFileAndLoadTicket(t *testing.T) {
+	c, _ := NewCipher(newKey(t))
+	path := filepath.Join(t.TempDir(), "feat-1.yaml")
+	yaml := []byte("id: feat-1\ntitle: Secret feature\ndescription: classified\npriority: 2\n")
+
+	if err := c.WriteFile(path, yaml, 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	raw, _ := os.ReadFile(path)
+	if !IsEncrypted(raw) {
+		t.Fatal("Expected the file to be encrypted on disk")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	tk, err := c.LoadTicket(path)
+	if err != nil {
+		t.Fatalf("LoadTicket failed: %v", err)
+	}
+	if tk.ID != "feat-1" || tk.Description != "classified" {
+		t.Errorf("Unexpected ticket %+v", tk)
+	}
+
+	var none *Cipher
+	if _, err := none.LoadTicket(path); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey without a key, got %v", err)
+	}
+}
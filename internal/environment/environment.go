@@ -0,0 +1,68 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// ErrUnknown is returned for a ticket naming an environment that is not configured
+var ErrUnknown = errors.New("unknown environment")
+
+// ErrApprovalRequired is returned for a ticket without requires_approval
+// destined for an environment that demands it
+var ErrApprovalRequired = errors.New("environment requires approval")
+
+// Settings are the guardrails for tickets destined for one environment
+type Settings struct {
+	Repository      string `mapstructure:"repository"`       // Bare repository its tickets run against; empty uses repository.path
+	CIProfile       string `mapstructure:"ci_profile"`       // CI profile its tickets run, in place of selection by tag
+	RequireApproval bool   `mapstructure:"require_approval"` // Only accept tickets with requires_approval: true
+}
+
+// Environments maps environment names to their settings
+// Names are case-insensitive, as config keys are
+type Environments map[string]Settings
+
+// Lookup returns the settings for a ticket's environment
+// Tickets without an environment get the zero settings
+func (e Environments) Lookup(name string) (Settings, error) {
+	if name == "" {
+		return Settings{}, nil
+	}
+	for configured, settings := range e {
+		if strings.EqualFold(configured, name) {
+			return settings, nil
+		}
+	}
+	return Settings{}, fmt.Errorf("%w %q", ErrUnknown, name)
+}
+
+// Check rejects tickets for unknown environments and tickets missing an
+// approval their environment requires
+func (e Environments) Check(t *ticket.Ticket) error {
+	settings, err := e.Lookup(t.Environment)
+	if err != nil {
+		return err
+	}
+	if settings.RequireApproval && !t.RequiresApproval {
+		return fmt.Errorf("%w: %s tickets need requires_approval: true", ErrApprovalRequired, t.Environment)
+	}
+	return nil
+}
+
+// Validate checks that CI profiles named by environments exist
+func (e Environments) Validate(profiles []string) error {
+	known := make(map[string]bool, len(profiles))
+	for _, name := range profiles {
+		known[name] = true
+	}
+	for name, settings := range e {
+		if settings.CIProfile != "" && !known[settings.CIProfile] {
+			return fmt.Errorf("environment %s uses unknown CI profile %q", name, settings.CIProfile)
+		}
+	}
+	return nil
+}
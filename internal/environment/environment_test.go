@@ -0,0 +1,50 @@
+package environment
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestCheck(t *testing.T) {
+	envs := Environments{
+		"staging": {Repository: "./staging.git"},
+		"prod":    {RequireApproval: true},
+	}
+
+	tests := []struct {
+		name   string
+		ticket ticket.Ticket
+		want   error
+	}{
+		{"no environment", ticket.Ticket{}, nil},
+		{"staging", ticket.Ticket{Environment: "staging"}, nil},
+		{"case-insensitive", ticket.Ticket{Environment: "Staging"}, nil},
+		{"unknown", ticket.Ticket{Environment: "qa"}, ErrUnknown},
+		{"prod without approval", ticket.Ticket{Environment: "prod"}, ErrApprovalRequired},
+		{"prod with approval", ticket.Ticket{Environment: "prod", RequiresApproval: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := envs.Check(&tt.ticket)
+			if (tt.want == nil && err != nil) || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if settings, err := envs.Lookup("staging"); err != nil || settings.Repository != "./staging.git" {
+		t.Errorf("Unexpected staging settings %+v (err %v)", settings, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	envs := Environments{"prod": {CIProfile: "full"}}
+	if err := envs.Validate([]string{"full"}); err != nil {
+		t.Errorf("Expected a known profile to validate, got %v", err)
+	}
+	if err := envs.Validate([]string{"frontend"}); err == nil {
+		t.Error("Expected error for an unknown CI profile")
+	}
+}
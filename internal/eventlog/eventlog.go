@@ -0,0 +1,193 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/journal"
+)
+
+// FileName is the log's name within the state directory; rotated logs get
+// .1 (newest) to .<max_files> appended
+const FileName = "events.jsonl"
+
+// Config controls whether the daemon keeps every IPC event on disk
+type Config struct {
+	Enabled   bool `mapstructure:"enabled"`
+	MaxSizeMB int  `mapstructure:"max_size_mb"` // Size at which the log is rotated
+	MaxFiles  int  `mapstructure:"max_files"`   // Rotated logs kept besides the current one
+}
+
+// Validate checks the event log settings
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxSizeMB < 1 {
+		return errors.New("max_size_mb must be at least 1")
+	}
+	if c.MaxFiles < 0 {
+		return errors.New("max_files cannot be negative")
+	}
+	return nil
+}
+
+// Log appends events to a JSON lines file, rotating it once it grows past
+// the configured size
+// With an encrypting cipher each line is sealed and base64 encoded
+type Log struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	cipher   *encryption.Cipher
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+}
+
+// Open opens the log in stateDir for appending
+func Open(stateDir string, cfg Config, cipher *encryption.Cipher) (*Log, error) {
+	l := &Log{
+		path:     filepath.Join(stateDir, FileName),
+		maxSize:  int64(cfg.MaxSizeMB) << 20,
+		maxFiles: cfg.MaxFiles,
+		cipher:   cipher,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	f, size, err := journal.OpenAppend(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	l.file, l.size = f, size
+	return nil
+}
+
+// Record appends an event, rotating the log first if the event would take
+// it past its size
+func (l *Log) Record(event ipc.Event) error {
+	line, err := journal.Encode(event, l.cipher)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errors.New("event log is closed")
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts each rotated log up one, dropping the oldest, and starts a
+// new log
+func (l *Log) rotate() error {
+	l.file.Close()
+	l.file = nil
+
+	os.Remove(rotatedPath(l.path, l.maxFiles))
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedPath(l.path, i), rotatedPath(l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate event log: %w", err)
+		}
+	}
+	if l.maxFiles > 0 {
+		if err := os.Rename(l.path, rotatedPath(l.path, 1)); err != nil {
+			return fmt.Errorf("failed to rotate event log: %w", err)
+		}
+	} else if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate event log: %w", err)
+	}
+	return l.open()
+}
+
+// Close closes the log; later events are not recorded
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func rotatedPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// Load reads the events in stateDir's current and rotated logs at or after
+// since, oldest first. Event data is decoded as JSON objects, as clients
+// receive it. Encrypted lines are decrypted with cipher
+func Load(stateDir string, since time.Time, cipher *encryption.Cipher) ([]ipc.Event, error) {
+	path := filepath.Join(stateDir, FileName)
+	files, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	// Rotated logs run from the highest number (oldest) down to the current log
+	rotated := make(map[string]bool, len(files))
+	for _, f := range files {
+		rotated[f] = true
+	}
+	var paths []string
+	for n := len(files); n >= 1; n-- {
+		if rotated[rotatedPath(path, n)] {
+			paths = append(paths, rotatedPath(path, n))
+		}
+	}
+	paths = append(paths, path)
+
+	var events []ipc.Event
+	for _, p := range paths {
+		loaded, err := loadFile(p, since, cipher)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, loaded...)
+	}
+	return events, nil
+}
+
+func loadFile(path string, since time.Time, cipher *encryption.Cipher) ([]ipc.Event, error) {
+	var events []ipc.Event
+	err := journal.Read(path, cipher, func(data []byte) error {
+		var event ipc.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		if !event.Timestamp.Before(since) {
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
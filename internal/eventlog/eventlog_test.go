@@ -0,0 +1,108 @@
+package eventlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	log, err := Open(dir, Config{Enabled: true, MaxSizeMB: 1, MaxFiles: 2}, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer log.Close()
+
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		event := ipc.Event{
+			Type:      ipc.EventTypeRuleTriggered,
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+			Data:      ipc.RuleTriggeredEvent{Rule: "rule", Message: string(rune('a' + i))},
+		}
+		if err := log.Record(event); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	events, err := Load(dir, start.Add(time.Minute), nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected the 2 events since the first, got %d", len(events))
+	}
+	data, ok := events[0].Data.(map[string]interface{})
+	if !ok || data["message"] != "b" || events[0].Type != ipc.EventTypeRuleTriggered {
+		t.Errorf("Unexpected event %+v", events[0])
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	log, err := Open(dir, Config{Enabled: true, MaxSizeMB: 1, MaxFiles: 2}, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer log.Close()
+
+	// Each event is about 400KB, so the log rotates every other event
+	filler := strings.Repeat("x", 400<<10)
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		event := ipc.Event{
+			Type:      ipc.EventTypeRuleTriggered,
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Data:      ipc.RuleTriggeredEvent{Rule: filler},
+		}
+		if err := log.Record(event); err != nil {
+			t.Fatalf("Record %d failed: %v", i, err)
+		}
+	}
+
+	for _, name := range []string{FileName, FileName + ".1", FileName + ".2"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Expected %s: %v", name, err)
+		}
+		if info.Size() > 1<<20 {
+			t.Errorf("Expected %s under 1MB, got %d bytes", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, FileName+".3")); !os.IsNotExist(err) {
+		t.Error("Expected only 2 rotated logs")
+	}
+
+	events, err := Load(dir, time.Time{}, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("Expected the last 6 events, got %d", len(events))
+	}
+	for i := 1; i < len(events); i++ {
+		if !events[i].Timestamp.After(events[i-1].Timestamp) {
+			t.Fatalf("Expected events oldest first, got %v after %v", events[i].Timestamp, events[i-1].Timestamp)
+		}
+	}
+	if !events[0].Timestamp.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("Expected the oldest kept event to be the third, got %v", events[0].Timestamp)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	if err := (Config{}).Validate(); err != nil {
+		t.Errorf("Expected disabled config to be valid, got %v", err)
+	}
+	if err := (Config{Enabled: true}).Validate(); err == nil {
+		t.Error("Expected error for a zero size")
+	}
+	if err := (Config{Enabled: true, MaxSizeMB: 1, MaxFiles: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative max_files")
+	}
+}
@@ -0,0 +1,98 @@
+// Package eventrate batches IPC events arriving in bursts and summarises the
+// repetitive ones, so a display can keep up with hundreds of events a second
+package eventrate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+)
+
+// Collect blocks for the next event, then gathers whatever else arrives
+// within window, up to limit events. It returns false once the channel is
+// closed and nothing was gathered.
+func Collect(events <-chan ipc.Event, window time.Duration, limit int) ([]ipc.Event, bool) {
+	first, ok := <-events
+	if !ok {
+		return nil, false
+	}
+	batch := []ipc.Event{first}
+
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	for len(batch) < limit {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return batch, true
+			}
+			batch = append(batch, event)
+		case <-timer.C:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// Burst is a run of events of one type from one worker that arrived in the
+// same batch, shown as a single line
+type Burst struct {
+	Type     ipc.EventType
+	WorkerID int // 0 for events not tied to a worker
+	Events   []ipc.Event
+}
+
+// Summarized reports whether the burst stands in for more than one event
+func (b Burst) Summarized() bool {
+	return len(b.Events) > 1
+}
+
+func (b Burst) String() string {
+	if b.WorkerID == 0 {
+		return fmt.Sprintf("%d %s events", len(b.Events), b.Type)
+	}
+	return fmt.Sprintf("worker %d: %d %s events", b.WorkerID, len(b.Events), b.Type)
+}
+
+// Summarize groups the events in a batch sharing a type and worker into one
+// burst once there are threshold or more of them, placed where the first
+// arrived; every other event is a burst of its own, in arrival order
+func Summarize(batch []ipc.Event, threshold int) []Burst {
+	type key struct {
+		eventType ipc.EventType
+		workerID  int
+	}
+	counts := make(map[key]int)
+	for _, event := range batch {
+		counts[key{event.Type, workerID(event)}]++
+	}
+
+	var bursts []Burst
+	index := make(map[key]int)
+	for _, event := range batch {
+		k := key{event.Type, workerID(event)}
+		if counts[k] < threshold {
+			bursts = append(bursts, Burst{Type: k.eventType, WorkerID: k.workerID, Events: []ipc.Event{event}})
+			continue
+		}
+		i, seen := index[k]
+		if !seen {
+			i = len(bursts)
+			index[k] = i
+			bursts = append(bursts, Burst{Type: k.eventType, WorkerID: k.workerID})
+		}
+		bursts[i].Events = append(bursts[i].Events, event)
+	}
+	return bursts
+}
+
+// workerID returns the worker an event came from, as decoded by the client
+func workerID(event ipc.Event) int {
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	id, _ := data["worker_id"].(float64)
+	return int(id)
+}
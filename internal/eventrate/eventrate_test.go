@@ -0,0 +1,85 @@
+package eventrate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+)
+
+func workerEvent(eventType ipc.EventType, workerID int) ipc.Event {
+	return ipc.Event{
+		Type: eventType,
+		Data: map[string]interface{}{"worker_id": float64(workerID)},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var batch []ipc.Event
+	batch = append(batch, workerEvent(ipc.EventTypeTicketStarted, 1))
+	for i := 0; i < 5; i++ {
+		batch = append(batch, workerEvent(ipc.EventTypeCIRunning, 3))
+	}
+	batch = append(batch, workerEvent(ipc.EventTypeCIRunning, 2))
+	batch = append(batch, ipc.Event{Type: ipc.EventTypeQueueUpdated, Data: map[string]interface{}{"queue_length": float64(1)}})
+	for i := 0; i < 4; i++ {
+		batch = append(batch, ipc.Event{Type: ipc.EventTypeQueueUpdated, Data: map[string]interface{}{"queue_length": float64(i + 2)}})
+	}
+
+	bursts := Summarize(batch, 5)
+
+	var got []string
+	for _, burst := range bursts {
+		if burst.Summarized() {
+			got = append(got, burst.String())
+		} else {
+			got = append(got, string(burst.Type))
+		}
+	}
+	want := []string{"ticket_started", "worker 3: 5 ci_running events", "ci_running", "5 queue_updated events"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+	last := bursts[3].Events[len(bursts[3].Events)-1]
+	if length := last.Data.(map[string]interface{})["queue_length"]; length != float64(5) {
+		t.Errorf("Expected a burst to keep its events in order, last queue length %v", length)
+	}
+}
+
+func TestSummarizeBelowThreshold(t *testing.T) {
+	batch := []ipc.Event{
+		workerEvent(ipc.EventTypeWorkerStatus, 1),
+		workerEvent(ipc.EventTypeWorkerStatus, 1),
+	}
+	bursts := Summarize(batch, 3)
+	if len(bursts) != 2 || bursts[0].Summarized() || bursts[1].Summarized() {
+		t.Errorf("Expected events below the threshold to stay separate, got %+v", bursts)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	events := make(chan ipc.Event, 10)
+	for i := 0; i < 10; i++ {
+		events <- workerEvent(ipc.EventTypeCIRunning, 1)
+	}
+
+	batch, ok := Collect(events, time.Second, 4)
+	if !ok || len(batch) != 4 {
+		t.Fatalf("Expected the batch to stop at the limit, got %d events", len(batch))
+	}
+
+	start := time.Now()
+	batch, ok = Collect(events, 50*time.Millisecond, 100)
+	if !ok || len(batch) != 6 {
+		t.Fatalf("Expected the remaining 6 events, got %d", len(batch))
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the batch to wait out its window, returned after %v", elapsed)
+	}
+
+	close(events)
+	if _, ok := Collect(events, time.Second, 100); ok {
+		t.Error("Expected a closed channel to end collection")
+	}
+}
@@ -0,0 +1,335 @@
+// Package github pushes completed tickets' branches to a GitHub repository,
+// opens a pull request for each and posts its CI result as a commit status
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// maxPending bounds completed tickets waiting to be published
+const maxPending = 256
+
+// Config controls the GitHub integration
+type Config struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	Repo          string `mapstructure:"repo"`           // owner/name
+	Remote        string `mapstructure:"remote"`         // Git remote name or URL branches are pushed to
+	Base          string `mapstructure:"base"`           // Branch pull requests merge into
+	TokenEnv      string `mapstructure:"token_env"`      // Environment variable holding the API token
+	APIURL        string `mapstructure:"api_url"`        // For GitHub Enterprise, e.g. https://github.example.com/api/v3
+	Draft         bool   `mapstructure:"draft"`          // Open pull requests as drafts
+	StatusContext string `mapstructure:"status_context"` // Name of the commit status CI results are posted under
+}
+
+// Validate checks the GitHub settings
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	owner, name, ok := strings.Cut(c.Repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("repo must be owner/name, got %q", c.Repo)
+	}
+	if c.Remote == "" || c.Base == "" || c.TokenEnv == "" || c.StatusContext == "" {
+		return errors.New("remote, base, token_env and status_context are required")
+	}
+	return nil
+}
+
+// Publisher turns ticket_complete events into pull requests
+type Publisher struct {
+	config       Config
+	repoPath     string
+	environments environment.Environments
+	ciStatus     *ci.StatusReader
+	apiURL       string
+	token        string
+	client       *http.Client
+	pending      chan *ticket.Ticket
+	onResult     func(ipc.PullRequestEvent) // Optional
+}
+
+// New creates a publisher for branches in the repository at repoPath, or in
+// their environment's repository, reading CI results from ciStatusDir
+func New(config Config, repoPath string, environments environment.Environments, ciStatusDir string) (*Publisher, error) {
+	token := os.Getenv(config.TokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("the GitHub integration needs a token in $%s", config.TokenEnv)
+	}
+	apiURL := config.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	return &Publisher{
+		config:       config,
+		repoPath:     repoPath,
+		environments: environments,
+		ciStatus:     ci.NewStatusReader(ciStatusDir),
+		apiURL:       strings.TrimSuffix(apiURL, "/"),
+		token:        token,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		pending:      make(chan *ticket.Ticket, maxPending),
+	}, nil
+}
+
+// SetResultHandler sets a function called with each pull request opened or
+// updated
+func (p *Publisher) SetResultHandler(handler func(ipc.PullRequestEvent)) {
+	p.onResult = handler
+}
+
+// Record queues a completed ticket's branch for Run to publish; a full
+// queue drops the pull request with a log line rather than stall events
+func (p *Publisher) Record(event ipc.Event) {
+	data, ok := event.Data.(ipc.TicketEvent)
+	if !ok || event.Type != ipc.EventTypeTicketComplete || data.Ticket == nil || data.Ticket.Branch == "" {
+		return
+	}
+	select {
+	case p.pending <- data.Ticket:
+	default:
+		log.Printf("Too many tickets waiting for GitHub; not opening a pull request for %s", data.Ticket.ID)
+	}
+}
+
+// Run publishes recorded tickets until ctx is done
+func (p *Publisher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-p.pending:
+			result, err := p.Publish(ctx, t)
+			if err != nil {
+				log.Printf("Failed to open a pull request for %s: %v", t.ID, err)
+				continue
+			}
+			log.Printf("Ticket %s: %s", t.ID, result.Message)
+			if p.onResult != nil {
+				p.onResult(result)
+			}
+		}
+	}
+}
+
+// pullRequest is the subset of the pull request resource that is used
+type pullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Publish pushes a ticket's branch, opens a pull request for it unless one
+// is already open, and posts the commit's CI result
+func (p *Publisher) Publish(ctx context.Context, t *ticket.Ticket) (ipc.PullRequestEvent, error) {
+	result := ipc.PullRequestEvent{TicketID: t.ID, Branch: t.Branch}
+
+	repoPath := p.repoPath
+	if settings, err := p.environments.Lookup(t.Environment); err == nil && settings.Repository != "" {
+		repoPath = settings.Repository
+	}
+	repo := gitutils.NewRepo(repoPath).WithContext(ctx)
+	commit, err := repo.GetBranchCommit(t.Branch)
+	if err != nil {
+		return result, err
+	}
+	result.Commit = commit
+	if err := repo.PushBranch(p.config.Remote, t.Branch); err != nil {
+		return result, fmt.Errorf("failed to push %s: %w", t.Branch, err)
+	}
+
+	pr, err := p.findPullRequest(ctx, t.Branch)
+	if err != nil {
+		return result, err
+	}
+	if pr == nil {
+		if pr, err = p.openPullRequest(ctx, t); err != nil {
+			return result, err
+		}
+		result.Created = true
+	}
+	result.Number = pr.Number
+	result.URL = pr.HTMLURL
+
+	verb := "Opened"
+	if !result.Created {
+		verb = "Updated"
+	}
+	result.Message = fmt.Sprintf("%s pull request #%d for %s: %s", verb, pr.Number, t.Branch, pr.HTMLURL)
+	if state, err := p.postStatus(ctx, t, commit); err != nil {
+		result.Message += fmt.Sprintf(" (failed to post CI status: %v)", err)
+	} else if state != "" {
+		result.Message += fmt.Sprintf(" (CI %s)", state)
+	}
+	return result, nil
+}
+
+// findPullRequest returns the open pull request for a branch, if any
+func (p *Publisher) findPullRequest(ctx context.Context, branch string) (*pullRequest, error) {
+	owner, _, _ := strings.Cut(p.config.Repo, "/")
+	query := url.Values{"state": {"open"}, "head": {owner + ":" + branch}, "base": {p.config.Base}}
+	var open []pullRequest
+	if err := p.call(ctx, http.MethodGet, "/pulls?"+query.Encode(), nil, &open); err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+	if len(open) == 0 {
+		return nil, nil
+	}
+	return &open[0], nil
+}
+
+// openPullRequest opens a pull request for a ticket's branch
+func (p *Publisher) openPullRequest(ctx context.Context, t *ticket.Ticket) (*pullRequest, error) {
+	body, err := Body(t)
+	if err != nil {
+		return nil, err
+	}
+	request := map[string]interface{}{
+		"title": Title(t),
+		"head":  t.Branch,
+		"base":  p.config.Base,
+		"body":  body,
+		"draft": p.config.Draft,
+	}
+	var pr pullRequest
+	if err := p.call(ctx, http.MethodPost, "/pulls", request, &pr); err != nil {
+		return nil, fmt.Errorf("failed to open pull request: %w", err)
+	}
+	return &pr, nil
+}
+
+// postStatus posts the commit's CI result as a commit status and returns
+// its state, or "" when no CI result was recorded for the commit
+func (p *Publisher) postStatus(ctx context.Context, t *ticket.Ticket, commit string) (string, error) {
+	status, err := p.ciStatus.GetStatus(commit)
+	if err != nil || status == nil {
+		return "", nil
+	}
+	state, description := "failure", "CI failed"
+	switch {
+	case status.Status == "SKIPPED":
+		state, description = "success", "CI skipped"
+	case status.Status == "FLAKY":
+		state, description = "success", fmt.Sprintf("CI passed after retrying %s", strings.Join(status.Flaky, ", "))
+	case status.Passed():
+		state, description = "success", "CI passed"
+	}
+	if status.Profile != "" {
+		description += " (" + status.Profile + " profile)"
+	}
+	if len(description) > 140 {
+		description = description[:137] + "..."
+	}
+
+	request := map[string]string{
+		"state":       state,
+		"context":     p.config.StatusContext,
+		"description": description,
+	}
+	if err := p.call(ctx, http.MethodPost, "/statuses/"+commit, request, nil); err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// call sends a request to the repository's API and decodes the response
+// into out when it is not nil
+func (p *Publisher) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.apiURL+"/repos/"+p.config.Repo+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4*1024*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New(resp.Status + ": " + strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// Title is a ticket's pull request title
+func Title(t *ticket.Ticket) string {
+	return fmt.Sprintf("%s (%s)", t.Title, t.ID)
+}
+
+// Body describes a ticket for its pull request: the description, the
+// agent's summary, artifacts and definition of done, with the ticket's
+// YAML at the end
+func Body(t *ticket.Ticket) (string, error) {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(t.Description) + "\n")
+	if t.Summary != "" {
+		fmt.Fprintf(&b, "\n## Summary\n\n%s\n", strings.TrimSpace(t.Summary))
+	}
+	if len(t.ArtifactURLs) > 0 {
+		b.WriteString("\n## Artifacts\n\n")
+		for _, artifact := range t.ArtifactURLs {
+			fmt.Fprintf(&b, "- %s\n", artifact)
+		}
+	}
+	if len(t.Done) > 0 {
+		b.WriteString("\n## Definition of done\n\n")
+		for _, check := range t.Done {
+			fmt.Fprintf(&b, "- [ ] %s: `%s`\n", check.Name, check.Run)
+		}
+	}
+
+	data, err := t.ToYAML()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ticket %s: %w", t.ID, err)
+	}
+	kind := t.Type
+	if kind == "" {
+		kind = ticket.TypeFeature
+	}
+	fmt.Fprintf(&b, "\n---\nTicket `%s`, %s, priority %d", t.ID, kind, t.Priority)
+	if len(t.Tags) > 0 {
+		fmt.Fprintf(&b, ", tagged %s", strings.Join(t.Tags, ", "))
+	}
+	fmt.Fprintf(&b, "\n\n<details><summary>Ticket YAML</summary>\n\n```yaml\n%s```\n\n</details>\n", data)
+	return b.String(), nil
+}
@@ -0,0 +1,233 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// fakeGitHub records API requests and serves one repository's pull requests
+type fakeGitHub struct {
+	mu       sync.Mutex
+	open     []map[string]interface{}
+	requests []string
+	statuses []map[string]string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/pulls":
+		if r.URL.Query().Get("head") != "acme:agent-1/feat-1" {
+			http.Error(w, "unexpected head "+r.URL.Query().Get("head"), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(f.open)
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/widgets/pulls":
+		var request map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&request)
+		request["number"] = 7
+		request["html_url"] = "https://github.com/acme/widgets/pull/7"
+		f.open = append(f.open, request)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(request)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/repos/acme/widgets/statuses/"):
+		var request map[string]string
+		json.NewDecoder(r.Body).Decode(&request)
+		f.statuses = append(f.statuses, request)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestRepo returns a bare repository with a commit on agent-1/feat-1
+// and an empty bare repository standing in for the GitHub remote
+func newTestRepo(t *testing.T) (string, string, string) {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "repo.git")
+	remote := filepath.Join(tmpDir, "github.git")
+	clone := filepath.Join(tmpDir, "clone")
+	for _, path := range []string{repoPath, remote} {
+		if err := gitutils.InitBareRepo(path); err != nil {
+			t.Fatalf("Failed to init bare repo: %v", err)
+		}
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	git(t, tmpDir, "clone", "-q", repoPath, clone)
+	git(t, clone, "checkout", "-q", "-b", "agent-1/feat-1")
+	if err := os.WriteFile(filepath.Join(clone, "feature.txt"), []byte("feature\n"), 0644); err != nil {
+		t.Fatalf("Failed to write feature.txt: %v", err)
+	}
+	git(t, clone, "add", "feature.txt")
+	git(t, clone, "commit", "-q", "-m", "Add feature")
+	git(t, clone, "push", "-q", "origin", "agent-1/feat-1")
+	return repoPath, remote, git(t, clone, "rev-parse", "HEAD")
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func newTestPublisher(t *testing.T, server *httptest.Server, repoPath, remote, statusDir string) *Publisher {
+	t.Helper()
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+	config := Config{
+		Enabled:       true,
+		Repo:          "acme/widgets",
+		Remote:        remote,
+		Base:          "main",
+		TokenEnv:      "TEST_GITHUB_TOKEN",
+		APIURL:        server.URL + "/",
+		StatusContext: "amp-orchestrator/ci",
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Config should be valid: %v", err)
+	}
+	publisher, err := New(config, repoPath, nil, statusDir)
+	if err != nil {
+		t.Fatalf("Failed to create publisher: %v", err)
+	}
+	return publisher
+}
+
+func TestPublish(t *testing.T) {
+	repoPath, remote, commit := newTestRepo(t)
+	statusDir := t.TempDir()
+	data, _ := json.Marshal(ci.Status{Commit: commit, Status: "PASS", Timestamp: time.Now()})
+	if err := os.WriteFile(filepath.Join(statusDir, commit+".json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write CI status: %v", err)
+	}
+
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	publisher := newTestPublisher(t, server, repoPath, remote, statusDir)
+
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Add feature", Description: "Adds a feature", Priority: 2, Branch: "agent-1/feat-1"}
+	result, err := publisher.Publish(t.Context(), tk)
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if !result.Created || result.Number != 7 || result.Commit != commit {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if !strings.Contains(result.Message, "CI success") {
+		t.Errorf("Message should report the CI status, got %q", result.Message)
+	}
+
+	pushed, err := gitutils.NewRepo(remote).GetBranchCommit("agent-1/feat-1")
+	if err != nil || pushed != commit {
+		t.Errorf("Branch should be pushed to the remote at %s, got %q (%v)", commit, pushed, err)
+	}
+	if len(fake.open) != 1 || fake.open[0]["title"] != "Add feature (feat-1)" || fake.open[0]["base"] != "main" {
+		t.Errorf("Unexpected pull request: %v", fake.open)
+	}
+	if len(fake.statuses) != 1 || fake.statuses[0]["state"] != "success" || fake.statuses[0]["context"] != "amp-orchestrator/ci" {
+		t.Errorf("Unexpected commit statuses: %v", fake.statuses)
+	}
+
+	// Publishing again finds the open pull request instead of opening another
+	result, err = publisher.Publish(t.Context(), tk)
+	if err != nil {
+		t.Fatalf("Second publish failed: %v", err)
+	}
+	if result.Created || result.Number != 7 || len(fake.open) != 1 {
+		t.Errorf("Pull request should be reused, got %+v with %d open", result, len(fake.open))
+	}
+}
+
+func TestPublish_NoCIStatus(t *testing.T) {
+	repoPath, remote, _ := newTestRepo(t)
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	publisher := newTestPublisher(t, server, repoPath, remote, t.TempDir())
+
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Add feature", Description: "Adds a feature", Priority: 2, Branch: "agent-1/feat-1"}
+	if _, err := publisher.Publish(t.Context(), tk); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if len(fake.statuses) != 0 {
+		t.Errorf("No commit status should be posted without a CI result, got %v", fake.statuses)
+	}
+}
+
+func TestNew_MissingToken(t *testing.T) {
+	t.Setenv("TEST_GITHUB_TOKEN", "")
+	_, err := New(Config{Enabled: true, Repo: "acme/widgets", TokenEnv: "TEST_GITHUB_TOKEN"}, "", nil, "")
+	if err == nil || !strings.Contains(err.Error(), "$TEST_GITHUB_TOKEN") {
+		t.Errorf("Expected an error naming the token variable, got %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{Enabled: true, Repo: "acme/widgets", Remote: "origin", Base: "main", TokenEnv: "GITHUB_TOKEN", StatusContext: "ci"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Valid config rejected: %v", err)
+	}
+	for _, repo := range []string{"", "widgets", "/widgets", "acme/", "acme/widgets/extra"} {
+		config := valid
+		config.Repo = repo
+		if err := config.Validate(); err == nil {
+			t.Errorf("Repo %q should be rejected", repo)
+		}
+	}
+	if err := (Config{Repo: "nonsense"}).Validate(); err != nil {
+		t.Errorf("Disabled config should not be validated: %v", err)
+	}
+}
+
+func TestBody(t *testing.T) {
+	tk := &ticket.Ticket{
+		ID:          "feat-1",
+		Title:       "Add feature",
+		Description: "Adds a feature",
+		Priority:    2,
+		Tags:        []string{"api"},
+		Summary:     "Added the feature",
+		Done:        []ticket.DoneCheck{{Name: "smoke", Run: "make smoke"}},
+	}
+	body, err := Body(tk)
+	if err != nil {
+		t.Fatalf("Body failed: %v", err)
+	}
+	for _, want := range []string{"Adds a feature", "## Summary\n\nAdded the feature", "- [ ] smoke: `make smoke`", "priority 2, tagged api", "```yaml\nid: feat-1"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Body should contain %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// Ticket status values used to color graph nodes
+const (
+	StatusQueued    = "queued"
+	StatusInFlight  = "in_flight"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusUnknown   = "unknown"
+)
+
+// statusColors maps a ticket status to its fill color
+var statusColors = map[string]string{
+	StatusQueued:    "#87ceeb",
+	StatusInFlight:  "#ffd700",
+	StatusCompleted: "#90ee90",
+	StatusFailed:    "#fa8072",
+	StatusUnknown:   "#d3d3d3",
+}
+
+// Node is a ticket together with its current status
+type Node struct {
+	Ticket *ticket.Ticket
+	Status string
+}
+
+// Graph holds the dependency and lock relationships between tickets
+type Graph struct {
+	nodes []Node
+	index map[string]int
+}
+
+// New creates a graph from the given nodes
+// Duplicate ticket IDs keep the first occurrence
+func New(nodes []Node) *Graph {
+	g := &Graph{index: make(map[string]int)}
+	for _, n := range nodes {
+		if n.Ticket == nil {
+			continue
+		}
+		if _, exists := g.index[n.Ticket.ID]; exists {
+			continue
+		}
+		g.index[n.Ticket.ID] = len(g.nodes)
+		g.nodes = append(g.nodes, n)
+	}
+	return g
+}
+
+// Nodes returns the ticket nodes in the graph
+func (g *Graph) Nodes() []Node {
+	return g.nodes
+}
+
+// missingDependencies returns dependency IDs that are not part of the graph
+func (g *Graph) missingDependencies() []string {
+	seen := make(map[string]bool)
+	var missing []string
+	for _, n := range g.nodes {
+		for _, dep := range n.Ticket.Dependencies {
+			if _, ok := g.index[dep]; ok || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			missing = append(missing, dep)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// locks returns all lock names referenced by tickets in the graph
+func (g *Graph) locks() []string {
+	seen := make(map[string]bool)
+	var locks []string
+	for _, n := range g.nodes {
+		for _, lock := range n.Ticket.Locks {
+			if seen[lock] {
+				continue
+			}
+			seen[lock] = true
+			locks = append(locks, lock)
+		}
+	}
+	sort.Strings(locks)
+	return locks
+}
+
+// DOT renders the graph in Graphviz DOT format
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph backlog {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [style=filled, fontname=\"Helvetica\"];\n")
+
+	for _, n := range g.nodes {
+		// Real newlines, which %q escapes once into the \n DOT breaks lines on
+		label := fmt.Sprintf("%s\n%s\n[P%d %s]", n.Ticket.ID, n.Ticket.Title, n.Ticket.Priority, n.Status)
+		fmt.Fprintf(&b, "  %q [shape=box, label=%q, fillcolor=%q];\n",
+			n.Ticket.ID, label, colorFor(n.Status))
+	}
+
+	for _, dep := range g.missingDependencies() {
+		fmt.Fprintf(&b, "  %q [shape=box, style=\"filled,dashed\", fillcolor=%q];\n",
+			dep, statusColors[StatusUnknown])
+	}
+
+	for _, lock := range g.locks() {
+		fmt.Fprintf(&b, "  %q [shape=ellipse, label=%q, fillcolor=\"white\"];\n",
+			lockID(lock), "🔒 "+lock)
+	}
+
+	// Dependencies point from the prerequisite to the dependent ticket
+	for _, n := range g.nodes {
+		for _, dep := range n.Ticket.Dependencies {
+			fmt.Fprintf(&b, "  %q -> %q;\n", dep, n.Ticket.ID)
+		}
+	}
+
+	for _, n := range g.nodes {
+		for _, lock := range n.Ticket.Locks {
+			fmt.Fprintf(&b, "  %q -> %q [style=dashed, arrowhead=none];\n", n.Ticket.ID, lockID(lock))
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	ids := make(map[string]string)
+	mermaidID := func(key string) string {
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[key] = id
+		return id
+	}
+
+	for _, n := range g.nodes {
+		label := fmt.Sprintf("%s<br/>%s<br/>P%d %s", n.Ticket.ID, n.Ticket.Title, n.Ticket.Priority, n.Status)
+		fmt.Fprintf(&b, "  %s[\"%s\"]:::%s\n", mermaidID(n.Ticket.ID), mermaidEscape(label), classFor(n.Status))
+	}
+
+	for _, dep := range g.missingDependencies() {
+		fmt.Fprintf(&b, "  %s[\"%s\"]:::%s\n", mermaidID(dep), mermaidEscape(dep), StatusUnknown)
+	}
+
+	for _, lock := range g.locks() {
+		fmt.Fprintf(&b, "  %s((\"🔒 %s\"))\n", mermaidID(lockID(lock)), mermaidEscape(lock))
+	}
+
+	for _, n := range g.nodes {
+		for _, dep := range n.Ticket.Dependencies {
+			fmt.Fprintf(&b, "  %s --> %s\n", mermaidID(dep), mermaidID(n.Ticket.ID))
+		}
+	}
+
+	for _, n := range g.nodes {
+		for _, lock := range n.Ticket.Locks {
+			fmt.Fprintf(&b, "  %s -.- %s\n", mermaidID(n.Ticket.ID), mermaidID(lockID(lock)))
+		}
+	}
+
+	for _, status := range []string{StatusQueued, StatusInFlight, StatusCompleted, StatusFailed, StatusUnknown} {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", status, statusColors[status])
+	}
+
+	return b.String()
+}
+
+// colorFor returns the fill color for a status
+func colorFor(status string) string {
+	if color, ok := statusColors[status]; ok {
+		return color
+	}
+	return statusColors[StatusUnknown]
+}
+
+// classFor returns the Mermaid class name for a status
+func classFor(status string) string {
+	if _, ok := statusColors[status]; ok {
+		return status
+	}
+	return StatusUnknown
+}
+
+// lockID returns the node identifier used for a lock
+func lockID(lock string) string {
+	return "lock:" + lock
+}
+
+// mermaidEscape makes a label safe to embed in a quoted Mermaid node
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func testNodes() []Node {
+	return []Node{
+		{
+			Ticket: &ticket.Ticket{
+				ID:       "feat-auth",
+				Title:    "User auth",
+				Priority: 1,
+				Locks:    []string{"user-profile"},
+			},
+			Status: StatusCompleted,
+		},
+		{
+			Ticket: &ticket.Ticket{
+				ID:           "feat-avatar",
+				Title:        "Avatar support",
+				Priority:     2,
+				Locks:        []string{"user-profile"},
+				Dependencies: []string{"feat-auth", "feat-storage"},
+			},
+			Status: StatusQueued,
+		},
+	}
+}
+
+func TestDOT(t *testing.T) {
+	out := New(testNodes()).DOT()
+
+	if !strings.HasPrefix(out, "digraph backlog {") {
+		t.Errorf("Expected DOT output to start with digraph, got: %s", out)
+	}
+
+	expected := []string{
+		`"feat-auth" [shape=box, label="feat-auth\nUser auth\n[P1 completed]"`,
+		`"feat-auth" -> "feat-avatar";`,
+		`"feat-storage" -> "feat-avatar";`,
+		`"feat-avatar" -> "lock:user-profile" [style=dashed, arrowhead=none];`,
+		`fillcolor="` + statusColors[StatusCompleted] + `"`,
+		`fillcolor="` + statusColors[StatusQueued] + `"`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// Shared locks should only be declared once
+	if count := strings.Count(out, `"lock:user-profile" [shape=ellipse`); count != 1 {
+		t.Errorf("Expected lock node to be declared once, got %d", count)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	out := New(testNodes()).Mermaid()
+
+	if !strings.HasPrefix(out, "flowchart LR") {
+		t.Errorf("Expected Mermaid output to start with flowchart, got: %s", out)
+	}
+
+	expected := []string{
+		":::completed",
+		":::queued",
+		"n0 --> n1",
+		"-.-",
+		"classDef in_flight",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected Mermaid output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestNewSkipsDuplicates(t *testing.T) {
+	nodes := testNodes()
+	nodes = append(nodes, Node{Ticket: nodes[0].Ticket, Status: StatusFailed}, Node{})
+
+	g := New(nodes)
+	if len(g.Nodes()) != 2 {
+		t.Fatalf("Expected 2 nodes, got %d", len(g.Nodes()))
+	}
+	if g.Nodes()[0].Status != StatusCompleted {
+		t.Errorf("Expected first occurrence to be kept, got status %s", g.Nodes()[0].Status)
+	}
+}
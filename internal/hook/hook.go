@@ -0,0 +1,163 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// maxReasonLength caps how much hook output is kept as a rejection reason
+const maxReasonLength = 1000
+
+// Decision is the outcome of validating a ticket
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Config holds validation hook settings
+// Exactly one of URL or Command should be set
+type Config struct {
+	URL     string        // Receives the ticket as a JSON POST and answers with a Decision
+	Command string        // Run via sh -c with the ticket JSON on stdin; non-zero exit rejects
+	Timeout time.Duration // Defaults to 10 seconds
+}
+
+// Validator sends tickets to an external policy check before they are enqueued
+type Validator struct {
+	url     string
+	command string
+	timeout time.Duration
+	client  *http.Client
+	runner  command.Runner
+}
+
+// New creates a validator, or returns nil when no hook is configured
+func New(config Config) *Validator {
+	if config.URL == "" && config.Command == "" {
+		return nil
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &Validator{
+		url:     config.URL,
+		command: config.Command,
+		timeout: timeout,
+		client:  &http.Client{},
+	}
+}
+
+// Validate asks the hook whether the ticket may be enqueued
+// An error means the hook itself failed, not that the ticket was rejected
+func (v *Validator) Validate(t *ticket.Ticket) (*Decision, error) {
+	if v == nil {
+		return &Decision{Allow: true}, nil
+	}
+
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
+	defer cancel()
+
+	if v.url != "" {
+		return v.validateHTTP(ctx, payload)
+	}
+	return v.validateCommand(ctx, t, payload)
+}
+
+// validateHTTP posts the ticket to the configured endpoint
+func (v *Validator) validateHTTP(ctx context.Context, payload []byte) (*Decision, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("validation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validation response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("validation endpoint returned %s: %s", resp.Status, truncate(string(body)))
+	}
+
+	var decision Decision
+	if err := json.Unmarshal(body, &decision); err != nil {
+		return nil, fmt.Errorf("failed to parse validation response: %w", err)
+	}
+	decision.Reason = truncate(decision.Reason)
+
+	return &decision, nil
+}
+
+// SetRunner sets what runs the validation command; nil uses command.Default
+func (v *Validator) SetRunner(runner command.Runner) {
+	v.runner = runner
+}
+
+// validateCommand runs the configured command with the ticket on stdin
+// Exit status 0 allows the ticket; any other exit status rejects it with
+// the command's output as the reason
+func (v *Validator) validateCommand(ctx context.Context, t *ticket.Ticket, payload []byte) (*Decision, error) {
+	cmd := command.Context(ctx, "sh", "-c", v.command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(), "TICKET_ID="+t.ID)
+
+	output, err := command.Or(v.runner).CombinedOutput(cmd)
+	if err == nil {
+		return &Decision{Allow: true}, nil
+	}
+
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("validation command timed out after %v", v.timeout)
+	}
+
+	code := command.ExitCode(err)
+	if code < 0 {
+		return nil, fmt.Errorf("failed to run validation command: %w", err)
+	}
+
+	// The shell reports missing or non-executable commands as 127/126
+	if code == 126 || code == 127 {
+		return nil, fmt.Errorf("validation command could not run: %s", truncate(string(output)))
+	}
+
+	reason := truncate(string(output))
+	if reason == "" {
+		reason = fmt.Sprintf("validation command exited with status %d", code)
+	}
+
+	return &Decision{Allow: false, Reason: reason}, nil
+}
+
+// truncate trims whitespace and caps the length of a reason
+func truncate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxReasonLength {
+		s = s[:maxReasonLength] + "..."
+	}
+	return s
+}
@@ -0,0 +1,132 @@
+package hook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+func testTicket() *ticket.Ticket {
+	return &ticket.Ticket{
+		ID:          "feat-hook",
+		Title:       "Hooked feature",
+		Description: "Delete production database",
+		Priority:    1,
+	}
+}
+
+func TestNewWithoutHook(t *testing.T) {
+	v := New(Config{})
+	if v != nil {
+		t.Fatal("Expected nil validator when no hook is configured")
+	}
+
+	decision, err := v.Validate(testTicket())
+	if err != nil || !decision.Allow {
+		t.Errorf("Expected nil validator to allow, got %+v, %v", decision, err)
+	}
+}
+
+func TestValidateHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tk ticket.Ticket
+		if err := json.NewDecoder(r.Body).Decode(&tk); err != nil {
+			http.Error(w, "bad ticket", http.StatusBadRequest)
+			return
+		}
+
+		decision := Decision{Allow: true}
+		if strings.Contains(tk.Description, "production") {
+			decision = Decision{Allow: false, Reason: "touches production"}
+		}
+		json.NewEncoder(w).Encode(decision)
+	}))
+	defer server.Close()
+
+	v := New(Config{URL: server.URL})
+
+	decision, err := v.Validate(testTicket())
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if decision.Allow || decision.Reason != "touches production" {
+		t.Errorf("Expected rejection with reason, got %+v", decision)
+	}
+
+	safe := testTicket()
+	safe.Description = "Add a button"
+	decision, err = v.Validate(safe)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !decision.Allow {
+		t.Errorf("Expected ticket to be allowed, got %+v", decision)
+	}
+}
+
+func TestValidateHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if _, err := New(Config{URL: server.URL}).Validate(testTicket()); err == nil {
+		t.Error("Expected error for non-2xx response")
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	allow := New(Config{Command: `grep -q '"id":"feat-hook"' && test "$TICKET_ID" = feat-hook`})
+	decision, err := allow.Validate(testTicket())
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !decision.Allow {
+		t.Errorf("Expected command to allow ticket, got %+v", decision)
+	}
+
+	reject := New(Config{Command: "echo 'needs security review'; exit 1"})
+	decision, err = reject.Validate(testTicket())
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if decision.Allow || decision.Reason != "needs security review" {
+		t.Errorf("Expected rejection with reason, got %+v", decision)
+	}
+}
+
+func TestValidateCommandUsesRunner(t *testing.T) {
+	recorder := command.NewRecorder()
+	recorder.Stub("sh -c policy-check", "needs security review\n", &command.ExitStatus{Code: 1})
+	validator := New(Config{Command: "policy-check"})
+	validator.SetRunner(recorder)
+
+	decision, err := validator.Validate(testTicket())
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if decision.Allow || decision.Reason != "needs security review" {
+		t.Errorf("Expected the runner's rejection, got %+v", decision)
+	}
+	if calls := recorder.Calls(); len(calls) != 1 {
+		t.Errorf("Expected one call through the runner, got %v", calls)
+	}
+}
+
+func TestValidateCommandErrors(t *testing.T) {
+	missing := New(Config{Command: "/nonexistent/policy-check"})
+	if _, err := missing.Validate(testTicket()); err == nil {
+		t.Error("Expected error for missing command")
+	}
+
+	slow := New(Config{Command: "sleep 5", Timeout: 100 * time.Millisecond})
+	if _, err := slow.Validate(testTicket()); err == nil {
+		t.Error("Expected error for timed out command")
+	}
+}
@@ -0,0 +1,181 @@
+// Package i18n looks up user-facing CLI and TUI messages in a catalog for
+// the selected locale. Catalogs are YAML files in locales/ mapping message
+// keys to fmt templates; a translation is added by dropping in a new file,
+// and any key it lacks falls back to English.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is the catalog every other falls back to
+const DefaultLocale = "en"
+
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// Catalog holds the messages of one locale
+type Catalog struct {
+	locale   string
+	messages map[string]string
+	fallback map[string]string
+}
+
+// Load returns the catalog for locale, e.g. "de" or "pt_BR"
+func Load(locale string) (*Catalog, error) {
+	return load(locales, locale)
+}
+
+func load(fsys fs.FS, locale string) (*Catalog, error) {
+	fallback, err := readCatalog(fsys, DefaultLocale)
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{locale: DefaultLocale, messages: fallback, fallback: fallback}
+	if locale == DefaultLocale {
+		return c, nil
+	}
+	messages, err := readCatalog(fsys, locale)
+	if err != nil {
+		return nil, err
+	}
+	c.locale, c.messages = locale, messages
+	return c, nil
+}
+
+// readCatalog parses locales/<locale>.yaml
+func readCatalog(fsys fs.FS, locale string) (map[string]string, error) {
+	data, err := fs.ReadFile(fsys, path.Join("locales", locale+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("no message catalog for locale %q", locale)
+	}
+	var messages map[string]string
+	if err := yaml.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("invalid message catalog for locale %q: %w", locale, err)
+	}
+	return messages, nil
+}
+
+// Locale returns the catalog's locale
+func (c *Catalog) Locale() string {
+	return c.locale
+}
+
+// T formats the message for key with args, falling back to English and
+// then to the key itself
+func (c *Catalog) T(key string, args ...any) string {
+	message, ok := c.messages[key]
+	if !ok {
+		if message, ok = c.fallback[key]; !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Available lists the locales with a catalog
+func Available() []string {
+	return available(locales)
+}
+
+func available(fsys fs.FS) []string {
+	entries, _ := fs.ReadDir(fsys, "locales")
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".yaml"); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Detect picks the locale to use: the configured one, else the first of
+// ORCHESTRATOR_LOCALE, LC_ALL, LC_MESSAGES and LANG that is set, else
+// DefaultLocale when none of those has a catalog
+func Detect(configured string) string {
+	return detect(locales, configured, os.Getenv)
+}
+
+func detect(fsys fs.FS, configured string, getenv func(string) string) string {
+	value := configured
+	for _, name := range []string{"ORCHESTRATOR_LOCALE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value != "" {
+			break
+		}
+		value = getenv(name)
+	}
+	if locale, ok := match(fsys, value); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// Match finds the catalog for a locale value such as de_DE.UTF-8: a de_DE
+// catalog if there is one, otherwise a de one
+func Match(value string) (string, bool) {
+	return match(locales, value)
+}
+
+func match(fsys fs.FS, value string) (string, bool) {
+	value, _, _ = strings.Cut(value, ".") // Drop the encoding
+	value, _, _ = strings.Cut(value, "@") // And the modifier
+	value = strings.ReplaceAll(value, "-", "_")
+	if value == "" {
+		return "", false
+	}
+	language, _, _ := strings.Cut(value, "_")
+	known := available(fsys)
+	for _, candidate := range []string{value, language} {
+		for _, name := range known {
+			if strings.EqualFold(name, candidate) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+var (
+	mu      sync.RWMutex
+	current *Catalog
+)
+
+func init() {
+	current, _ = Load(Detect(""))
+	if current == nil {
+		current = &Catalog{locale: DefaultLocale}
+	}
+}
+
+// Set makes the catalog for locale the one T uses
+func Set(locale string) error {
+	c, err := Load(locale)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+	return nil
+}
+
+// T formats the message for key in the current locale
+func T(key string, args ...any) string {
+	mu.RLock()
+	c := current
+	mu.RUnlock()
+	return c.T(key, args...)
+}
@@ -0,0 +1,98 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+var testLocales = fstest.MapFS{
+	"locales/en.yaml":    {Data: []byte("greeting: \"Hello, %s\"\nfarewell: Bye\n")},
+	"locales/de.yaml":    {Data: []byte("greeting: \"Hallo, %s\"\n")},
+	"locales/pt_BR.yaml": {Data: []byte("greeting: \"Olá, %s\"\n")},
+}
+
+func TestCatalogFallsBack(t *testing.T) {
+	c, err := load(testLocales, "de")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if got := c.T("greeting", "Ada"); got != "Hallo, Ada" {
+		t.Errorf("Expected the German message, got %q", got)
+	}
+	if got := c.T("farewell"); got != "Bye" {
+		t.Errorf("Expected the English message for a missing key, got %q", got)
+	}
+	if got := c.T("nowhere"); got != "nowhere" {
+		t.Errorf("Expected the key for an unknown message, got %q", got)
+	}
+
+	if _, err := load(testLocales, "fr"); err == nil {
+		t.Error("Expected an error for a locale without a catalog")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		env        map[string]string
+		want       string
+	}{
+		{"default", "", nil, "en"},
+		{"configured", "de", map[string]string{"LANG": "pt_BR.UTF-8"}, "de"},
+		{"orchestrator env first", "", map[string]string{"ORCHESTRATOR_LOCALE": "de", "LANG": "pt_BR.UTF-8"}, "de"},
+		{"lang with encoding", "", map[string]string{"LANG": "pt_BR.UTF-8"}, "pt_BR"},
+		{"language only", "", map[string]string{"LC_ALL": "de_AT.UTF-8@euro"}, "de"},
+		{"bcp 47 tag", "pt-br", nil, "pt_BR"},
+		{"no catalog", "", map[string]string{"LANG": "fr_FR.UTF-8"}, "en"},
+		{"posix", "", map[string]string{"LANG": "C"}, "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(name string) string { return tt.env[name] }
+			if got := detect(testLocales, tt.configured, getenv); got != tt.want {
+				t.Errorf("detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	if got := available(testLocales); !slices.Equal(got, []string{"de", "en", "pt_BR"}) {
+		t.Errorf("available() = %v", got)
+	}
+	if !slices.Contains(Available(), DefaultLocale) {
+		t.Errorf("Expected an embedded %s catalog, got %v", DefaultLocale, Available())
+	}
+}
+
+// verbs matches the fmt verbs in a message
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// TestCatalogsMatchEnglish checks every shipped translation only has keys
+// English has, with the same verbs in the same order
+func TestCatalogsMatchEnglish(t *testing.T) {
+	english, err := readCatalog(locales, DefaultLocale)
+	if err != nil {
+		t.Fatalf("English catalog: %v", err)
+	}
+	for _, locale := range Available() {
+		messages, err := readCatalog(locales, locale)
+		if err != nil {
+			t.Errorf("%s catalog: %v", locale, err)
+			continue
+		}
+		for key, message := range messages {
+			source, ok := english[key]
+			if !ok {
+				t.Errorf("%s: key %q is not in the English catalog", locale, key)
+				continue
+			}
+			if got, want := verbs.FindAllString(message, -1), verbs.FindAllString(source, -1); !slices.Equal(got, want) {
+				t.Errorf("%s: %q has verbs %v, English has %v", locale, key, got, want)
+			}
+		}
+	}
+}
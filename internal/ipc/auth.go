@@ -0,0 +1,154 @@
+package ipc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TokenEnv is the variable clients read their auth token from
+const TokenEnv = "ORCHESTRATOR_IPC_TOKEN"
+
+// authCommand is handled by the server itself to authenticate a connection
+const authCommand = "auth"
+
+// Role grants access to events and commands; each role includes the ones below it
+type Role string
+
+const (
+	RoleNone     Role = "none"     // No access; the connection must authenticate first
+	RoleViewer   Role = "viewer"   // Stream events and read status
+	RoleOperator Role = "operator" // Enqueue, cancel and re-run work
+	RoleAdmin    Role = "admin"    // Scale, pause and approve
+)
+
+// level orders roles; unknown roles have no access
+func (r Role) level() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Allows reports whether r grants the access of required
+func (r Role) Allows(required Role) bool {
+	return r.level() >= required.level()
+}
+
+// validRole reports whether r is one of the defined roles
+func validRole(r Role) bool {
+	return r == RoleNone || r.level() > 0
+}
+
+// TokenConfig binds an auth token to a role
+// The token itself is read from an environment variable to keep it out of config
+type TokenConfig struct {
+	Name     string `mapstructure:"name"`      // Identifies the token holder in logs
+	TokenEnv string `mapstructure:"token_env"` // Variable holding the token
+	Role     Role   `mapstructure:"role"`
+}
+
+// AuthConfig enables role-based access to the IPC socket
+// With no tokens configured every connection is an admin
+type AuthConfig struct {
+	Tokens      []TokenConfig `mapstructure:"tokens"`
+	DefaultRole Role          `mapstructure:"default_role"` // Role of connections that have not authenticated; defaults to viewer
+}
+
+// Validate checks token entries and roles
+func (c AuthConfig) Validate() error {
+	if c.DefaultRole != "" && !validRole(c.DefaultRole) {
+		return fmt.Errorf("unknown default_role %q (expected none, viewer, operator or admin)", c.DefaultRole)
+	}
+	names := make(map[string]bool)
+	for i, token := range c.Tokens {
+		if token.Name == "" || token.TokenEnv == "" {
+			return fmt.Errorf("tokens[%d]: name and token_env are required", i)
+		}
+		if names[token.Name] {
+			return fmt.Errorf("tokens[%d]: duplicate name %q", i, token.Name)
+		}
+		names[token.Name] = true
+		if token.Role.level() == 0 {
+			return fmt.Errorf("tokens[%d]: unknown role %q (expected viewer, operator or admin)", i, token.Role)
+		}
+	}
+	return nil
+}
+
+// identity is a resolved auth token
+type identity struct {
+	name  string
+	token string
+	role  Role
+}
+
+// Authenticator maps tokens presented by clients to roles
+type Authenticator struct {
+	identities  []identity
+	defaultRole Role
+}
+
+// NewAuthenticator resolves the configured tokens from the environment
+// It returns nil when no tokens are configured, leaving the socket open
+func NewAuthenticator(config AuthConfig) (*Authenticator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if len(config.Tokens) == 0 {
+		return nil, nil
+	}
+
+	auth := &Authenticator{defaultRole: config.DefaultRole}
+	if auth.defaultRole == "" {
+		auth.defaultRole = RoleViewer
+	}
+	for _, token := range config.Tokens {
+		value := os.Getenv(token.TokenEnv)
+		if value == "" {
+			return nil, fmt.Errorf("token %s: %s is not set", token.Name, token.TokenEnv)
+		}
+		auth.identities = append(auth.identities, identity{name: token.Name, token: value, role: token.Role})
+	}
+	return auth, nil
+}
+
+// DefaultRole returns the role of connections that have not authenticated
+func (a *Authenticator) DefaultRole() Role {
+	if a == nil {
+		return RoleAdmin
+	}
+	return a.defaultRole
+}
+
+// Authenticate returns the name and role bound to token
+func (a *Authenticator) Authenticate(token string) (string, Role, error) {
+	if a == nil {
+		return "", RoleAdmin, nil
+	}
+	for _, id := range a.identities {
+		if subtle.ConstantTimeCompare([]byte(id.token), []byte(token)) == 1 {
+			return id.name, id.role, nil
+		}
+	}
+	return "", RoleNone, errors.New("invalid token")
+}
+
+// Authenticate presents a token to the server, raising this connection's role
+func (c *Client) Authenticate(ctx context.Context, token string) error {
+	response, err := c.SendCommand(ctx, authCommand, map[string]string{"token": token})
+	if err != nil {
+		return err
+	}
+	if !response.OK {
+		return errors.New(response.Error)
+	}
+	return nil
+}
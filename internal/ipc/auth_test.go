@@ -0,0 +1,352 @@
+package ipc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, required Role
+		want           bool
+	}{
+		{RoleAdmin, RoleOperator, true},
+		{RoleOperator, RoleOperator, true},
+		{RoleViewer, RoleOperator, false},
+		{RoleViewer, RoleViewer, true},
+		{RoleNone, RoleViewer, false},
+		{Role("root"), RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Allows(tt.required); got != tt.want {
+			t.Errorf("%s.Allows(%s) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestAuthConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AuthConfig
+		wantErr bool
+	}{
+		{"empty", AuthConfig{}, false},
+		{"valid", AuthConfig{Tokens: []TokenConfig{{Name: "ci", TokenEnv: "CI_TOKEN", Role: RoleOperator}}, DefaultRole: RoleNone}, false},
+		{"unknown role", AuthConfig{Tokens: []TokenConfig{{Name: "ci", TokenEnv: "CI_TOKEN", Role: "root"}}}, true},
+		{"token without env", AuthConfig{Tokens: []TokenConfig{{Name: "ci", Role: RoleViewer}}}, true},
+		{"duplicate name", AuthConfig{Tokens: []TokenConfig{
+			{Name: "ci", TokenEnv: "A", Role: RoleViewer},
+			{Name: "ci", TokenEnv: "B", Role: RoleAdmin},
+		}}, true},
+		{"unknown default role", AuthConfig{DefaultRole: "guest"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAuthenticatorRequiresTokens(t *testing.T) {
+	if auth, err := NewAuthenticator(AuthConfig{}); err != nil || auth != nil {
+		t.Errorf("Expected no authenticator without tokens, got %v (err %v)", auth, err)
+	}
+
+	t.Setenv("MISSING_TOKEN", "")
+	_, err := NewAuthenticator(AuthConfig{Tokens: []TokenConfig{{Name: "ci", TokenEnv: "MISSING_TOKEN", Role: RoleViewer}}})
+	if err == nil {
+		t.Error("Expected error for an unset token variable, got nil")
+	}
+}
+
+func TestIPCCommandRoles(t *testing.T) {
+	t.Setenv("TEST_OPERATOR_TOKEN", "op-secret")
+	t.Setenv("TEST_ADMIN_TOKEN", "admin-secret")
+
+	auth, err := NewAuthenticator(AuthConfig{Tokens: []TokenConfig{
+		{Name: "deploy-bot", TokenEnv: "TEST_OPERATOR_TOKEN", Role: RoleOperator},
+		{Name: "alice", TokenEnv: "TEST_ADMIN_TOKEN", Role: RoleAdmin},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	server := NewServer(socketPath)
+	server.SetAuthenticator(auth)
+	server.HandleCommand("cancel", RoleOperator, func(caller Caller, args map[string]string) (string, error) {
+		return "cancelled", nil
+	})
+	server.HandleCommand("pause", RoleAdmin, func(caller Caller, args map[string]string) (string, error) {
+		return "paused", nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	client := NewClient(socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	expect := func(name string, ok bool) {
+		t.Helper()
+		response, err := client.SendCommand(ctx, name, nil)
+		if err != nil {
+			t.Fatalf("SendCommand failed: %v", err)
+		}
+		if response.OK != ok {
+			t.Errorf("%s: expected ok=%v, got %+v", name, ok, response)
+		}
+		if !ok && !strings.Contains(response.Error, "permission denied") {
+			t.Errorf("%s: expected permission error, got %q", name, response.Error)
+		}
+	}
+
+	// Unauthenticated connections are viewers
+	expect("cancel", false)
+
+	if err := client.Authenticate(ctx, "wrong"); err == nil {
+		t.Error("Expected invalid token to be rejected")
+	}
+	expect("cancel", false)
+
+	if err := client.Authenticate(ctx, "op-secret"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	expect("cancel", true)
+	expect("pause", false)
+
+	if err := client.Authenticate(ctx, "admin-secret"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	expect("cancel", true)
+	expect("pause", true)
+}
+
+func TestIPCEventsRequireViewer(t *testing.T) {
+	t.Setenv("TEST_VIEWER_TOKEN", "view-secret")
+
+	auth, err := NewAuthenticator(AuthConfig{
+		Tokens:      []TokenConfig{{Name: "dashboard", TokenEnv: "TEST_VIEWER_TOKEN", Role: RoleViewer}},
+		DefaultRole: RoleNone,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	server := NewServer(socketPath)
+	server.SetAuthenticator(auth)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	client := NewClient(socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	server.PublishQueueUpdated(1, nil)
+
+	select {
+	case event := <-client.Events():
+		t.Fatalf("Unauthenticated client received %s event", event.Type)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Authenticate(ctx, "view-secret"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	server.PublishQueueUpdated(2, nil)
+	select {
+	case event := <-client.Events():
+		if event.Type != EventTypeQueueUpdated {
+			t.Errorf("Expected %s event, got %s", EventTypeQueueUpdated, event.Type)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timeout waiting for event after authenticating")
+	}
+}
+
+func TestIPCCommandAttribution(t *testing.T) {
+	t.Setenv("TEST_OPERATOR_TOKEN", "op-secret")
+	auth, err := NewAuthenticator(AuthConfig{Tokens: []TokenConfig{
+		{Name: "deploy-bot", TokenEnv: "TEST_OPERATOR_TOKEN", Role: RoleOperator},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	server := NewServer(socketPath)
+	server.SetAuthenticator(auth)
+
+	records := make(chan CommandRecord, 10)
+	server.SetCommandRecorder(func(record CommandRecord) {
+		records <- record
+	})
+
+	var handlerCaller Caller
+	server.HandleCommand("cancel", RoleOperator, func(caller Caller, args map[string]string) (string, error) {
+		handlerCaller = caller
+		return "cancelled " + args["ticket"], nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	client := NewClient(socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := client.Authenticate(ctx, "op-secret"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	authRecord := <-records
+	if authRecord.Name != authCommand || authRecord.Caller.Token != "deploy-bot" {
+		t.Errorf("Unexpected auth record %+v", authRecord)
+	}
+	if authRecord.Args["token"] == "op-secret" {
+		t.Error("Expected the auth token to be redacted")
+	}
+
+	response, err := client.SendCommand(ctx, "cancel", map[string]string{"ticket": "feat-1"})
+	if err != nil || !response.OK {
+		t.Fatalf("cancel failed: %+v (err %v)", response, err)
+	}
+
+	record := <-records
+	if record.Name != "cancel" || record.Caller.Token != "deploy-bot" || !record.Response.OK {
+		t.Errorf("Unexpected cancel record %+v", record)
+	}
+	if handlerCaller != record.Caller {
+		t.Errorf("Expected handler to see caller %+v, got %+v", record.Caller, handlerCaller)
+	}
+	if runtime.GOOS == "linux" && (record.Caller.PID != os.Getpid() || record.Caller.UID != os.Getuid()) {
+		t.Errorf("Expected peer credentials of this process, got %+v", record.Caller)
+	}
+
+	// Control commands are announced to every client with their caller
+	select {
+	case event := <-client.Events():
+		if event.Type != EventTypeControlCommand {
+			t.Fatalf("Expected %s event, got %s", EventTypeControlCommand, event.Type)
+		}
+		data := event.Data.(map[string]interface{})
+		caller := data["caller"].(map[string]interface{})
+		if data["command"] != "cancel" || caller["token"] != "deploy-bot" {
+			t.Errorf("Unexpected control command event %v", data)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timeout waiting for control command event")
+	}
+}
+
+func TestCallerString(t *testing.T) {
+	tests := []struct {
+		caller Caller
+		want   string
+	}{
+		{Caller{}, "unknown"},
+		{Caller{Token: "deploy-bot"}, "deploy-bot"},
+		{Caller{User: "alice", UID: 1000, PID: 42}, "alice, pid 42"},
+		{Caller{UID: 1000, PID: 42}, "uid 1000, pid 42"},
+		{Caller{Token: "deploy-bot", User: "alice", UID: 1000, PID: 42}, "deploy-bot (alice, pid 42)"},
+		{Caller{Token: "gpu-box", Addr: "10.0.0.7:51234"}, "gpu-box (10.0.0.7:51234)"},
+	}
+	for _, tt := range tests {
+		if got := tt.caller.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.caller, got, tt.want)
+		}
+	}
+}
+
+func TestIPCTCPRequiresToken(t *testing.T) {
+	server := NewServer(filepath.Join(t.TempDir(), "test.sock"))
+	if err := server.StartTCP("127.0.0.1:0"); err == nil {
+		t.Fatal("Expected TCP listener to require auth tokens")
+	}
+
+	t.Setenv("TEST_WORKER_TOKEN", "worker-secret")
+	auth, err := NewAuthenticator(AuthConfig{
+		Tokens:      []TokenConfig{{Name: "gpu-box", TokenEnv: "TEST_WORKER_TOKEN", Role: RoleOperator}},
+		DefaultRole: RoleAdmin,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	server.SetAuthenticator(auth)
+
+	records := make(chan CommandRecord, 10)
+	server.SetCommandRecorder(func(record CommandRecord) {
+		records <- record
+	})
+	server.HandleQuietCommand("heartbeat", RoleOperator, func(caller Caller, args map[string]string) (string, error) {
+		return "ok", nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+	if err := server.StartTCP("127.0.0.1:0"); err != nil {
+		t.Fatalf("StartTCP failed: %v", err)
+	}
+
+	client := NewTCPClient(server.TCPAddress())
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// The default role only applies to the local socket
+	response, err := client.SendCommand(ctx, "heartbeat", nil)
+	if err != nil || response.OK {
+		t.Fatalf("Expected unauthenticated TCP client to be refused, got %+v (err %v)", response, err)
+	}
+	if record := <-records; record.Caller.Addr == "" || record.Response.OK {
+		t.Errorf("Expected refused command to be recorded with the remote address, got %+v", record)
+	}
+
+	if err := client.Authenticate(ctx, "worker-secret"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	<-records
+
+	response, err = client.SendCommand(ctx, "heartbeat", nil)
+	if err != nil || !response.OK {
+		t.Fatalf("heartbeat failed: %+v (err %v)", response, err)
+	}
+	select {
+	case record := <-records:
+		t.Errorf("Expected quiet command not to be recorded, got %+v", record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
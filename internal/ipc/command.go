@@ -0,0 +1,367 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+// maxCommandSize bounds a single command line sent by a client
+const maxCommandSize = 64 * 1024
+
+// Command is a request sent by a client to the daemon
+type Command struct {
+	ID   string            `json:"id"`
+	Name string            `json:"name"`
+	Args map[string]string `json:"args,omitempty"`
+}
+
+// CommandResponse is the daemon's reply to a Command
+// It is delivered as an event of type EventTypeCommandResponse
+type CommandResponse struct {
+	ID      string `json:"id"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Caller identifies who issued a command
+type Caller struct {
+	Token string `json:"token,omitempty"` // Name of the token the connection authenticated with
+	User  string `json:"user,omitempty"`  // OS user of the connecting process
+	UID   int    `json:"uid"`
+	PID   int    `json:"pid,omitempty"`  // Zero when peer credentials are unavailable
+	Addr  string `json:"addr,omitempty"` // Remote address of TCP clients
+}
+
+// String describes the caller for logs and events
+func (c Caller) String() string {
+	var peer string
+	if c.PID > 0 {
+		user := c.User
+		if user == "" {
+			user = "uid " + strconv.Itoa(c.UID)
+		}
+		peer = fmt.Sprintf("%s, pid %d", user, c.PID)
+	} else if c.Addr != "" {
+		peer = c.Addr
+	}
+
+	switch {
+	case c.Token != "" && peer != "":
+		return fmt.Sprintf("%s (%s)", c.Token, peer)
+	case c.Token != "":
+		return c.Token
+	case peer != "":
+		return peer
+	}
+	return "unknown"
+}
+
+// CommandRecord describes a command the server dispatched, for auditing
+type CommandRecord struct {
+	Time     time.Time
+	Name     string
+	Args     map[string]string // Secrets such as auth tokens are redacted
+	Caller   Caller
+	Response CommandResponse
+}
+
+// CommandHandler executes a command on behalf of caller and returns a message
+// for the client
+type CommandHandler func(caller Caller, args map[string]string) (string, error)
+
+// registeredHandler is a command handler and the role required to run it
+type registeredHandler struct {
+	role    Role
+	handler CommandHandler
+	quiet   bool // Neither recorded nor announced
+}
+
+// session is the state of a client connection
+type session struct {
+	caller Caller
+	role   Role
+	frames *frameWriter // Set once the client negotiates deflate framing; guarded by writeMux
+}
+
+// newSession captures the peer credentials of a new connection
+func newSession(conn net.Conn, role Role) *session {
+	client := &session{role: role}
+	if _, isTCP := conn.(*net.TCPConn); isTCP {
+		client.caller.Addr = conn.RemoteAddr().String()
+	}
+	if uid, pid, ok := peerCredentials(conn); ok {
+		client.caller.UID = uid
+		client.caller.PID = pid
+		if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+			client.caller.User = u.Username
+		}
+	}
+	return client
+}
+
+// HandleCommand registers the handler for a command name
+// Only connections with at least the given role may run it
+func (s *Server) HandleCommand(name string, role Role, handler CommandHandler) {
+	s.handlersMux.Lock()
+	defer s.handlersMux.Unlock()
+	s.handlers[name] = registeredHandler{role: role, handler: handler}
+}
+
+// HandleQuietCommand registers a handler like HandleCommand, but successful
+// calls are neither recorded nor announced; it suits frequent traffic such
+// as remote worker heartbeats and log lines
+func (s *Server) HandleQuietCommand(name string, role Role, handler CommandHandler) {
+	s.handlersMux.Lock()
+	defer s.handlersMux.Unlock()
+	s.handlers[name] = registeredHandler{role: role, handler: handler, quiet: true}
+}
+
+// SetAuthenticator enables role-based access; call it before Start
+func (s *Server) SetAuthenticator(auth *Authenticator) {
+	s.auth = auth
+}
+
+// AddEventObserver adds a function called with every published event, in the
+// publishing goroutine; call it before Start
+func (s *Server) AddEventObserver(observer func(Event)) {
+	s.observers = append(s.observers, observer)
+}
+
+// SetDisconnector sets a function asked before each event is sent to a
+// client; when it returns true the client is dropped instead, so chaos
+// testing can exercise reconnects. Call it before Start
+func (s *Server) SetDisconnector(disconnect func() bool) {
+	s.disconnect = disconnect
+}
+
+// SetCommandRecorder sets a function called with every dispatched command,
+// including failed and unauthorized ones
+func (s *Server) SetCommandRecorder(recorder func(CommandRecord)) {
+	s.recorder = recorder
+}
+
+// authenticate raises a connection's role to the one bound to its token
+func (s *Server) authenticate(conn net.Conn, token string) (string, error) {
+	name, role, err := s.auth.Authenticate(token)
+	if err != nil {
+		return "", err
+	}
+
+	s.clientsMux.Lock()
+	if client, ok := s.clients[conn]; ok {
+		if s.auth == nil {
+			// Without tokens there is no role to raise to; WebSocket
+			// clients keep the role of the bridge's shared token
+			name, role = client.caller.Token, client.role
+		}
+		client.caller.Token = name
+		client.role = role
+	}
+	s.clientsMux.Unlock()
+
+	if name == "" {
+		return fmt.Sprintf("authenticated as %s", role), nil
+	}
+	return fmt.Sprintf("authenticated as %s (%s)", name, role), nil
+}
+
+// clientSession returns a copy of a connection's session
+func (s *Server) clientSession(conn net.Conn) session {
+	s.clientsMux.RLock()
+	defer s.clientsMux.RUnlock()
+	if client, ok := s.clients[conn]; ok {
+		return *client
+	}
+	return session{role: RoleNone}
+}
+
+// dispatchCommand runs a command line received from a client and replies to it
+func (s *Server) dispatchCommand(conn net.Conn, line []byte) {
+	var cmd Command
+	if err := json.Unmarshal(line, &cmd); err != nil {
+		log.Printf("Ignoring malformed IPC command: %v", err)
+		return
+	}
+
+	if cmd.Name == framingCommand {
+		s.negotiateFraming(conn, cmd)
+		return
+	}
+
+	client := s.clientSession(conn)
+	var response CommandResponse
+	if cmd.Name == authCommand {
+		response = CommandResponse{ID: cmd.ID}
+		if message, err := s.authenticate(conn, cmd.Args["token"]); err != nil {
+			response.Error = err.Error()
+		} else {
+			response.OK = true
+			response.Message = message
+		}
+		// Record who the connection became, not who it was
+		s.recordCommand(cmd, s.clientSession(conn).caller, response)
+	} else {
+		response = s.runCommand(client.caller, client.role, cmd)
+	}
+
+	if err := s.writeEvent(conn, EventTypeCommandResponse, response); err != nil {
+		log.Printf("Failed to send command response: %v", err)
+	}
+}
+
+// Execute runs a registered command on behalf of caller holding role, with
+// the same checks, records and announcements as one sent over a connection,
+// so other transports such as the HTTP API share the daemon's commands
+func (s *Server) Execute(caller Caller, role Role, name string, args map[string]string) CommandResponse {
+	return s.runCommand(caller, role, Command{Name: name, Args: args})
+}
+
+// runCommand checks the caller's role, runs the command's handler, and
+// records and announces the call
+func (s *Server) runCommand(caller Caller, role Role, cmd Command) CommandResponse {
+	s.handlersMux.RLock()
+	registered, ok := s.handlers[cmd.Name]
+	s.handlersMux.RUnlock()
+
+	response := CommandResponse{ID: cmd.ID}
+	if !ok {
+		response.Error = fmt.Sprintf("unknown command %q", cmd.Name)
+	} else if !role.Allows(registered.role) {
+		response.Error = fmt.Sprintf("permission denied: %s requires the %s role", cmd.Name, registered.role)
+	} else if message, err := registered.handler(caller, cmd.Args); err != nil {
+		response.Error = err.Error()
+	} else {
+		response.OK = true
+		response.Message = message
+	}
+
+	if registered.quiet && response.OK {
+		return response
+	}
+	s.recordCommand(cmd, caller, response)
+
+	// Control commands are announced so every client can attribute them
+	if ok && registered.role.Allows(RoleOperator) {
+		s.PublishEvent(EventTypeControlCommand, ControlCommandEvent{
+			Command: cmd.Name,
+			Args:    redactArgs(cmd.Args),
+			Caller:  caller,
+			OK:      response.OK,
+			Error:   response.Error,
+		})
+	}
+	return response
+}
+
+// recordCommand logs a dispatched command and passes it to the recorder
+func (s *Server) recordCommand(cmd Command, caller Caller, response CommandResponse) {
+	log.Printf("IPC command %s from %s: ok=%v %s%s", cmd.Name, caller, response.OK, response.Message, response.Error)
+
+	if s.recorder != nil {
+		s.recorder(CommandRecord{
+			Time:     time.Now(),
+			Name:     cmd.Name,
+			Args:     redactArgs(cmd.Args),
+			Caller:   caller,
+			Response: response,
+		})
+	}
+}
+
+// redactArgs copies command arguments, hiding secrets
+func redactArgs(args map[string]string) map[string]string {
+	if len(args) == 0 {
+		return nil
+	}
+	redacted := make(map[string]string, len(args))
+	for k, v := range args {
+		if k == "token" {
+			v = "[redacted]"
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// SendCommand sends a command to the daemon and waits for its response
+func (c *Client) SendCommand(ctx context.Context, name string, args map[string]string) (*CommandResponse, error) {
+	return c.sendCommand(ctx, name, args, "")
+}
+
+// sendCommand sends a command, noting a framing request so the event reader
+// switches framing when it sees the response
+func (c *Client) sendCommand(ctx context.Context, name string, args map[string]string, framing string) (*CommandResponse, error) {
+	c.pendingMux.Lock()
+	c.nextID++
+	id := strconv.Itoa(c.nextID)
+	reply := make(chan CommandResponse, 1)
+	c.pending[id] = reply
+	if framing != "" {
+		c.framingID, c.framing = id, framing
+	}
+	c.pendingMux.Unlock()
+
+	defer func() {
+		c.pendingMux.Lock()
+		delete(c.pending, id)
+		c.pendingMux.Unlock()
+	}()
+
+	data, err := json.Marshal(Command{ID: id, Name: name, Args: args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal command: %w", err)
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	select {
+	case response := <-reply:
+		return &response, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no response to %s: %w", name, ctx.Err())
+	case <-c.ctx.Done():
+		return nil, fmt.Errorf("connection closed before response to %s", name)
+	}
+}
+
+// deliverResponse routes a command response event to the waiting caller
+// It returns the framing the server switched to, if the response accepts a
+// framing request
+func (c *Client) deliverResponse(event Event) string {
+	// Event data arrives as a generic map; round-trip it into the struct
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return ""
+	}
+	var response CommandResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		log.Printf("Failed to decode command response: %v", err)
+		return ""
+	}
+
+	c.pendingMux.Lock()
+	reply, ok := c.pending[response.ID]
+	var framing string
+	if response.OK && response.ID == c.framingID {
+		framing = c.framing
+	}
+	c.pendingMux.Unlock()
+
+	if ok {
+		select {
+		case reply <- response:
+		default:
+		}
+	}
+	return framing
+}
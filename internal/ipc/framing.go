@@ -0,0 +1,189 @@
+package ipc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// How the server frames events sent to a client
+const (
+	// FramingJSON sends one JSON event per line; every connection starts with it
+	FramingJSON = "json"
+	// FramingDeflate sends each event as a length-prefixed frame holding the
+	// next flushed block of a per-connection deflate stream, so an event
+	// compresses against the ones before it: repeated field names, ticket IDs
+	// and titles shrink to back-references
+	FramingDeflate = "deflate"
+)
+
+// framingCommand is handled by the server itself to switch a connection's
+// framing; the response is the last event sent in the old framing
+const framingCommand = "framing"
+
+// maxFrameSize bounds a single compressed frame
+const maxFrameSize = 16 << 20
+
+// ValidateFraming checks a framing name; "" means JSON
+func ValidateFraming(framing string) error {
+	switch framing {
+	case "", FramingJSON, FramingDeflate:
+		return nil
+	}
+	return fmt.Errorf("unknown framing %q (expected %s or %s)", framing, FramingJSON, FramingDeflate)
+}
+
+// frameWriter compresses a connection's events into frames
+type frameWriter struct {
+	buf bytes.Buffer
+	zw  *flate.Writer
+}
+
+func newFrameWriter() *frameWriter {
+	fw := &frameWriter{}
+	fw.zw, _ = flate.NewWriter(&fw.buf, flate.BestSpeed) // Only fails for an invalid level
+	return fw
+}
+
+// frame returns the frame for an encoded event; it is only valid until the
+// next call
+func (fw *frameWriter) frame(event []byte) ([]byte, error) {
+	fw.buf.Reset()
+	fw.buf.Write(make([]byte, 4)) // Length, filled in below
+	if _, err := fw.zw.Write(event); err != nil {
+		return nil, err
+	}
+	if err := fw.zw.Flush(); err != nil {
+		return nil, err
+	}
+	frame := fw.buf.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	return frame, nil
+}
+
+// frameReader strips the length prefixes from a stream of frames, yielding
+// the deflate stream they carry
+type frameReader struct {
+	r         *bufio.Reader
+	remaining int
+}
+
+func (f *frameReader) Read(p []byte) (int, error) {
+	for f.remaining == 0 {
+		var header [4]byte
+		if _, err := io.ReadFull(f.r, header[:]); err != nil {
+			return 0, err
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxFrameSize {
+			return 0, fmt.Errorf("frame of %d bytes exceeds the %d byte limit", size, maxFrameSize)
+		}
+		f.remaining = int(size)
+	}
+	if len(p) > f.remaining {
+		p = p[:f.remaining]
+	}
+	n, err := f.r.Read(p)
+	f.remaining -= n
+	return n, err
+}
+
+// eventDecoder reads the next event from a connection
+type eventDecoder func(event *Event) error
+
+// lineDecoder reads JSON events, one per line
+func lineDecoder(r *bufio.Reader) eventDecoder {
+	return func(event *Event) error {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(line, event)
+	}
+}
+
+// deflateDecoder reads events from deflate frames
+func deflateDecoder(r *bufio.Reader) eventDecoder {
+	decoder := json.NewDecoder(flate.NewReader(&frameReader{r: r}))
+	return func(event *Event) error {
+		return decoder.Decode(event)
+	}
+}
+
+// encodeFor frames an encoded event for a client; call it holding writeMux
+func (c *session) encodeFor(event []byte) ([]byte, error) {
+	if c == nil || c.frames == nil {
+		return event, nil
+	}
+	return c.frames.frame(event)
+}
+
+// negotiateFraming answers a framing command, switching the connection's
+// framing right after the response is written so the client can follow
+func (s *Server) negotiateFraming(conn net.Conn, cmd Command) {
+	format := cmd.Args["format"]
+
+	s.clientsMux.RLock()
+	defer s.clientsMux.RUnlock()
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+
+	client := s.clients[conn]
+	if client == nil {
+		return
+	}
+	response := CommandResponse{ID: cmd.ID}
+	if err := ValidateFraming(format); err != nil {
+		response.Error = err.Error()
+	} else if client.frames != nil {
+		response.Error = "framing already negotiated"
+	} else {
+		response.OK = true
+		response.Message = "framing " + format
+	}
+
+	eventJSON, err := json.Marshal(Event{Type: EventTypeCommandResponse, Timestamp: time.Now().UTC(), Data: response})
+	if err != nil {
+		log.Printf("Failed to marshal framing response: %v", err)
+		return
+	}
+	data, err := client.encodeFor(append(eventJSON, '\n'))
+	if err == nil {
+		_, err = conn.Write(data)
+	}
+	if err != nil {
+		log.Printf("Failed to send framing response: %v", err)
+		return
+	}
+	if response.OK && format == FramingDeflate {
+		client.frames = newFrameWriter()
+	}
+}
+
+// NegotiateFraming asks the server to frame events with format from now on
+// Servers predating framing reply with an error and keep sending JSON lines
+func (c *Client) NegotiateFraming(ctx context.Context, format string) error {
+	if err := ValidateFraming(format); err != nil {
+		return err
+	}
+	if format == "" || format == FramingJSON {
+		return nil
+	}
+	response, err := c.sendCommand(ctx, framingCommand, map[string]string{"format": format}, format)
+	if err != nil {
+		return err
+	}
+	if !response.OK {
+		return errors.New(response.Error)
+	}
+	return nil
+}
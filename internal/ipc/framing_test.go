@@ -0,0 +1,103 @@
+package ipc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestDeflateFraming(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	server := NewServer(socketPath)
+	server.HandleQuietCommand("echo", RoleViewer, func(caller Caller, args map[string]string) (string, error) {
+		return args["text"], nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	client := NewClient(socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.NegotiateFraming(ctx, FramingDeflate); err != nil {
+		t.Fatalf("NegotiateFraming failed: %v", err)
+	}
+	if err := client.NegotiateFraming(ctx, FramingDeflate); err == nil {
+		t.Error("Expected error negotiating framing twice")
+	}
+
+	// Events and command responses both arrive framed
+	for i := 0; i < 20; i++ {
+		server.PublishTicketEnqueued(&ticket.Ticket{ID: fmt.Sprintf("feat-%d", i), Title: "Framed ticket"})
+	}
+	for i := 0; i < 20; i++ {
+		select {
+		case event := <-client.Events():
+			data, _ := event.Data.(map[string]interface{})
+			tk, _ := data["ticket"].(map[string]interface{})
+			if event.Type != EventTypeTicketEnqueued || tk["id"] != fmt.Sprintf("feat-%d", i) {
+				t.Fatalf("Unexpected event %d: %+v", i, event)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Timeout waiting for event %d", i)
+		}
+	}
+	response, err := client.SendCommand(ctx, "echo", map[string]string{"text": "hello"})
+	if err != nil || !response.OK || response.Message != "hello" {
+		t.Errorf("Expected an echo over framed events, got %+v (err %v)", response, err)
+	}
+
+	if err := client.NegotiateFraming(ctx, "msgpack"); err == nil {
+		t.Error("Expected error for an unknown framing")
+	}
+}
+
+func TestFrameWriterCompressesAcrossEvents(t *testing.T) {
+	fw := newFrameWriter()
+	var stream bytes.Buffer
+	var jsonSize int
+	for i := 0; i < 50; i++ {
+		event, _ := json.Marshal(Event{Type: EventTypeTicketPhase, Data: TicketPhaseEvent{
+			Ticket:   &ticket.Ticket{ID: "feat-login", Title: "Add login page", Description: strings.Repeat("Build the form. ", 10)},
+			WorkerID: 1,
+			Phase:    "agent",
+			Message:  fmt.Sprintf("step %d", i),
+		}})
+		event = append(event, '\n')
+		jsonSize += len(event)
+		frame, err := fw.frame(event)
+		if err != nil {
+			t.Fatalf("frame failed: %v", err)
+		}
+		stream.Write(frame)
+	}
+	if stream.Len()*4 > jsonSize {
+		t.Errorf("Expected repeated events to compress at least 4x, got %d bytes from %d", stream.Len(), jsonSize)
+	}
+
+	decode := deflateDecoder(bufio.NewReader(&stream))
+	for i := 0; i < 50; i++ {
+		var event Event
+		if err := decode(&event); err != nil {
+			t.Fatalf("Failed to decode event %d: %v", i, err)
+		}
+		data, _ := event.Data.(map[string]interface{})
+		if data["message"] != fmt.Sprintf("step %d", i) {
+			t.Fatalf("Unexpected event %d: %+v", i, data)
+		}
+	}
+}
@@ -1,17 +1,21 @@
 package ipc
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/brettsmith212/amp-orchestrator/internal/policy"
 	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
 )
 
@@ -19,11 +23,59 @@ import (
 type EventType string
 
 const (
-	EventTypeQueueUpdated   EventType = "queue_updated"
-	EventTypeTicketEnqueued EventType = "ticket_enqueued"
-	EventTypeTicketStarted  EventType = "ticket_started"
-	EventTypeTicketComplete EventType = "ticket_complete"
-	EventTypeWorkerStatus   EventType = "worker_status"
+	EventTypeQueueUpdated          EventType = "queue_updated"
+	EventTypeTicketEnqueued        EventType = "ticket_enqueued"
+	EventTypeTicketStarted         EventType = "ticket_started"
+	EventTypeTicketComplete        EventType = "ticket_complete"
+	EventTypeTicketPhase           EventType = "ticket_phase"
+	EventTypeTicketFailed          EventType = "ticket_failed"
+	EventTypeTicketPreempted       EventType = "ticket_preempted"
+	EventTypeTicketRetrying        EventType = "ticket_retrying"
+	EventTypeTicketCancelled       EventType = "ticket_cancelled"
+	EventTypeWorkerStatus          EventType = "worker_status"
+	EventTypeAgentAuthError        EventType = "agent_auth_error"
+	EventTypeTicketRejected        EventType = "ticket_rejected"
+	EventTypePolicyViolation       EventType = "policy_violation"
+	EventTypeCommandResponse       EventType = "command_response"
+	EventTypeResourceLimitExceeded EventType = "resource_limit_exceeded"
+	EventTypeDiskSpace             EventType = "disk_space"
+	EventTypeControlCommand        EventType = "control_command"
+	EventTypeCIFlaky               EventType = "ci_flaky"
+	EventTypeCISkipped             EventType = "ci_skipped"
+	EventTypeRuleTriggered         EventType = "rule_triggered"
+	EventTypeTicketVerified        EventType = "ticket_verified"
+	EventTypeMainHealth            EventType = "main_health"
+	EventTypeDispatchBlocked       EventType = "dispatch_blocked"
+	EventTypeDispatchResumed       EventType = "dispatch_resumed"
+	EventTypeQuotaExceeded         EventType = "quota_exceeded"
+	EventTypeMergeConflict         EventType = "merge_conflict"
+	EventTypePullRequest           EventType = "pull_request"
+	EventTypeRegressionTest        EventType = "regression_test"
+	EventTypeGuardedFiles          EventType = "guarded_files"
+	EventTypeCIFullTier            EventType = "ci_full_tier"
+	EventTypeCIRunning             EventType = "ci_running"
+	EventTypeLockAcquired          EventType = "lock_acquired"
+	EventTypeLockReleased          EventType = "lock_released"
+	EventTypeLockWaiting           EventType = "lock_waiting"
+)
+
+// ErrorCode classifies why a ticket failed so automation can branch on it
+type ErrorCode string
+
+const (
+	ErrorCodeAgentFailed      ErrorCode = "agent_failed" // The agent errored or produced nothing to commit
+	ErrorCodeCIFailed         ErrorCode = "ci_failed"
+	ErrorCodePushFailed       ErrorCode = "push_failed"
+	ErrorCodeTimeout          ErrorCode = "timeout"            // Gave up waiting, e.g. for CI results
+	ErrorCodeConflict         ErrorCode = "conflict"           // The ticket's branch changed underneath the worker
+	ErrorCodeAuth             ErrorCode = "auth"               // The agent lost its credentials
+	ErrorCodeNotFixed         ErrorCode = "not_fixed"          // A bug's reproduce command still failed after the agent
+	ErrorCodeNoRegressionTest ErrorCode = "no_regression_test" // A bug fix changed no test files
+	ErrorCodeGuardedFiles     ErrorCode = "guarded_files"      // The agent's changes included oversized files, binaries or blocked paths
+	ErrorCodeLicenseViolation ErrorCode = "license_violation"  // The agent's changes carried a disallowed license or copied code
+	ErrorCodeVulnerable       ErrorCode = "vulnerable"         // CI found critical dependency vulnerabilities main doesn't have
+	ErrorCodePrecheckFailed   ErrorCode = "precheck_failed"    // The agent's changes failed the quick CI run in the worktree
+	ErrorCodePushVetoed       ErrorCode = "push_vetoed"        // The pre-push policy hook refused the agent's changes
 )
 
 // Event represents a message sent over the IPC bus
@@ -44,6 +96,16 @@ type TicketEvent struct {
 	Ticket   *ticket.Ticket `json:"ticket"`
 	WorkerID int            `json:"worker_id,omitempty"`
 	Message  string         `json:"message,omitempty"`
+	Code     ErrorCode      `json:"code,omitempty"` // For ticket_failed events
+}
+
+// TicketPhaseEvent reports a worker moving a ticket into a new phase
+// (agent, ci or artifacts)
+type TicketPhaseEvent struct {
+	Ticket   *ticket.Ticket `json:"ticket"`
+	WorkerID int            `json:"worker_id"`
+	Phase    string         `json:"phase"`
+	Message  string         `json:"message,omitempty"`
 }
 
 // WorkerStatusEvent represents worker status updates
@@ -52,16 +114,215 @@ type WorkerStatusEvent struct {
 	Status        string         `json:"status"` // "idle", "working", "error"
 	CurrentTicket *ticket.Ticket `json:"current_ticket,omitempty"`
 	Message       string         `json:"message,omitempty"`
+	Stats         *WorkerStats   `json:"stats,omitempty"`
+}
+
+// WorkerStats are a worker's running totals since it started
+type WorkerStats struct {
+	WorkerID         int           `json:"worker_id"`
+	Status           string        `json:"status,omitempty"`     // idle, working or error
+	Ticket           string        `json:"ticket,omitempty"`     // ID of the current ticket
+	LastError        string        `json:"last_error,omitempty"` // Of the last failed ticket
+	TicketsCompleted int           `json:"tickets_completed"`
+	TicketsFailed    int           `json:"tickets_failed"`
+	AverageDuration  time.Duration `json:"average_duration"` // Of completed tickets
+	Phase            string        `json:"phase,omitempty"`  // Of the current ticket
+	Uptime           time.Duration `json:"uptime"`
+	CIStep           string        `json:"ci_step,omitempty"`    // Of the CI run being waited on
+	CIElapsed        time.Duration `json:"ci_elapsed,omitempty"` // Since that run was dispatched
+	Paused           bool          `json:"paused,omitempty"`     // Takes no new tickets until resumed
+}
+
+// AgentAuthErrorEvent reports that the agent CLI lost its credentials
+// Severity is always "critical": the worker pool is paused until resumed
+type AgentAuthErrorEvent struct {
+	WorkerID int            `json:"worker_id"`
+	Ticket   *ticket.Ticket `json:"ticket,omitempty"`
+	Severity string         `json:"severity"`
+	Message  string         `json:"message"`
+}
+
+// ResourceLimitEvent reports a ticket killed, or a worker idled, for
+// exceeding a resource limit; Ticket is nil for disk quota checks
+type ResourceLimitEvent struct {
+	WorkerID int            `json:"worker_id"`
+	Ticket   *ticket.Ticket `json:"ticket,omitempty"`
+	Message  string         `json:"message"`
+}
+
+// DiskSpaceEvent reports a filesystem crossing the free space threshold
+// Severity is "warning" while space is low and "info" once it recovers
+type DiskSpaceEvent struct {
+	Path      string `json:"path"`
+	FreeMB    uint64 `json:"free_mb"`
+	MinFreeMB uint64 `json:"min_free_mb"`
+	Low       bool   `json:"low"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+}
+
+// ControlCommandEvent attributes a control command, such as a cancel or a
+// CI re-run, to the client that issued it
+type ControlCommandEvent struct {
+	Command string            `json:"command"`
+	Args    map[string]string `json:"args,omitempty"`
+	Caller  Caller            `json:"caller"`
+	OK      bool              `json:"ok"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// CIFlakyEvent reports a test package that failed and then passed on retry
+type CIFlakyEvent struct {
+	WorkerID int            `json:"worker_id"`
+	Ticket   *ticket.Ticket `json:"ticket,omitempty"`
+	Package  string         `json:"package"`
+	Message  string         `json:"message"`
+}
+
+// RuleTriggeredEvent reports an event reaction rule firing
+type RuleTriggeredEvent struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// VerificationEvent reports a merged ticket's definition of done checks
+// Action is what was done about a failure: revert_ticket, rollback or none
+type VerificationEvent struct {
+	TicketID string `json:"ticket_id"`
+	Commit   string `json:"commit"` // Main branch commit the checks ran on
+	Passed   bool   `json:"passed"`
+	Check    string `json:"check,omitempty"` // The check that failed
+	Action   string `json:"action,omitempty"`
+	Message  string `json:"message"`
+}
+
+// MainHealth is CI's verdict on the main branch, sent with main_health
+// events when main turns red or green and included in status reports
+type MainHealth struct {
+	Branch    string    `json:"branch"`
+	Commit    string    `json:"commit"` // Main's tip
+	Status    string    `json:"status"` // The tip's CI status, or PENDING until it has one
+	Red       bool      `json:"red"`    // The latest finished CI run on main failed
+	Since     time.Time `json:"since"`  // When main last turned red or green
+	Held      bool      `json:"held"`   // Dispatch is held while main is red
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// DispatchEvent reports the scheduler holding or resuming new ticket dispatch
+// because of CI on main
+type DispatchEvent struct {
+	Reason  string `json:"reason"`
+	Branch  string `json:"branch"`
+	Commit  string `json:"commit"` // The failing commit, or the one that went green
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// QuotaExceededEvent reports a ticket held back by its tag's quota: kept in
+// the backlog for max_queued, or in the queue for the other limits
+type QuotaExceededEvent struct {
+	Ticket  *ticket.Ticket `json:"ticket"`
+	Tag     string         `json:"tag"`
+	Limit   string         `json:"limit"` // max_queued, max_in_flight or max_daily_invocations
+	Max     int            `json:"max"`
+	Current int            `json:"current"`
+	Message string         `json:"message"`
+}
+
+// LockEvent reports a ticket acquiring or releasing its locks, or held in
+// the queue by locks another ticket holds
+type LockEvent struct {
+	Ticket  *ticket.Ticket `json:"ticket"`
+	Locks   []string       `json:"locks"`
+	HeldBy  string         `json:"held_by,omitempty"` // The conflicting ticket, for lock_waiting
+	Message string         `json:"message"`
+}
+
+// RegressionTestEvent reports whether a bug fix adds or changes a test
+type RegressionTestEvent struct {
+	WorkerID int            `json:"worker_id"`
+	Ticket   *ticket.Ticket `json:"ticket"`
+	Passed   bool           `json:"passed"`
+	Message  string         `json:"message"`
+}
+
+// GuardedFilesEvent reports oversized files, binaries or blocked paths in
+// the agent's changes, and whether the ticket failed for them
+type GuardedFilesEvent struct {
+	WorkerID int            `json:"worker_id"`
+	Ticket   *ticket.Ticket `json:"ticket"`
+	Rejected bool           `json:"rejected"`
+	Message  string         `json:"message"`
+}
+
+// CIRunningEvent reports the step a ticket's CI run is executing while the
+// worker waits for its result
+type CIRunningEvent struct {
+	WorkerID int            `json:"worker_id"`
+	Ticket   *ticket.Ticket `json:"ticket"`
+	Step     string         `json:"step"`
+	Elapsed  time.Duration  `json:"elapsed"`
+	Message  string         `json:"message"`
+}
+
+// CIFullTierEvent reports the full CI suite's verdict on a completed
+// ticket whose agent loop was gated by the quick tier; Passed gates the merge
+type CIFullTierEvent struct {
+	Ticket  *ticket.Ticket `json:"ticket"`
+	Commit  string         `json:"commit"`
+	Status  string         `json:"status"`
+	Passed  bool           `json:"passed"`
+	Message string         `json:"message"`
+}
+
+// MergeConflictEvent reports a completed ticket's branch that no longer
+// merges cleanly into main, and the ticket enqueued to resolve it
+type MergeConflictEvent struct {
+	TicketID string   `json:"ticket_id"`
+	Branch   string   `json:"branch"`
+	Commit   string   `json:"commit"`
+	Files    []string `json:"files"`
+	FollowUp string   `json:"follow_up,omitempty"` // ID of the resolution ticket; "" if none was enqueued
+	Message  string   `json:"message"`
+}
+
+// PullRequestEvent reports a completed ticket's branch pushed to GitHub
+// with a pull request open for it
+type PullRequestEvent struct {
+	TicketID string `json:"ticket_id"`
+	Branch   string `json:"branch"`
+	Commit   string `json:"commit"`
+	Number   int    `json:"number"`
+	URL      string `json:"url"`
+	Created  bool   `json:"created"` // False when a pull request for the branch was already open
+	Message  string `json:"message"`
+}
+
+// PolicyViolationEvent reports a ticket rejected by the policy rules
+type PolicyViolationEvent struct {
+	Ticket     *ticket.Ticket     `json:"ticket"`
+	Violations []policy.Violation `json:"violations"`
 }
 
 // Server represents the IPC server that publishes events
 type Server struct {
-	socketPath string
-	listener   net.Listener
-	clients    map[net.Conn]bool
-	clientsMux sync.RWMutex
-	ctx        context.Context
-	cancel     context.CancelFunc
+	socketPath  string
+	socketPerms SocketPermissions
+	listener    net.Listener
+	tcpListener net.Listener // Optional; remote workers connect here
+	wsServer    *http.Server // Optional; bridges the protocol over WebSockets
+	wsListener  net.Listener
+	clients     map[net.Conn]*session
+	clientsMux  sync.RWMutex
+	writeMux    sync.Mutex // Keeps event lines from interleaving on a connection
+	handlers    map[string]registeredHandler
+	handlersMux sync.RWMutex
+	auth        *Authenticator // Nil leaves every connection an admin
+	recorder    func(CommandRecord)
+	observers   []func(Event) // See every published event
+	disconnect  func() bool   // Optional; drops a client in place of sending it an event
+	ctx         context.Context
+	cancel      context.CancelFunc
 }
 
 // NewServer creates a new IPC server
@@ -79,7 +340,8 @@ func NewServer(socketPath string) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
 		socketPath: socketPath,
-		clients:    make(map[net.Conn]bool),
+		clients:    make(map[net.Conn]*session),
+		handlers:   make(map[string]registeredHandler),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -93,11 +355,11 @@ func (s *Server) Start() error {
 	}
 
 	// Create directory for socket if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0755); err != nil {
+	if err := s.prepareSocketDir(); err != nil {
 		return fmt.Errorf("failed to create socket directory: %w", err)
 	}
 
-	listener, err := net.Listen("unix", s.socketPath)
+	listener, err := s.listenUnix()
 	if err != nil {
 		return fmt.Errorf("failed to listen on unix socket: %w", err)
 	}
@@ -106,11 +368,40 @@ func (s *Server) Start() error {
 	log.Printf("IPC server listening on %s", s.socketPath)
 
 	// Accept connections in a goroutine
-	go s.acceptConnections()
+	go s.acceptConnections(listener)
 
 	return nil
 }
 
+// StartTCP additionally accepts connections on a TCP address
+// TCP connections have no access until they authenticate, so it requires
+// an authenticator
+func (s *Server) StartTCP(address string) error {
+	if s.auth == nil {
+		return fmt.Errorf("refusing to listen on %s without auth tokens", address)
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	s.tcpListener = listener
+	log.Printf("IPC server listening on tcp %s", listener.Addr())
+
+	go s.acceptConnections(listener)
+
+	return nil
+}
+
+// TCPAddress returns the address of the TCP listener, if any
+func (s *Server) TCPAddress() string {
+	if s.tcpListener == nil {
+		return ""
+	}
+	return s.tcpListener.Addr().String()
+}
+
 // Stop shuts down the IPC server
 func (s *Server) Stop() error {
 	s.cancel()
@@ -122,10 +413,16 @@ func (s *Server) Stop() error {
 	}
 	s.clientsMux.Unlock()
 
-	// Close listener
+	// Close listeners
 	if s.listener != nil {
 		s.listener.Close()
 	}
+	if s.tcpListener != nil {
+		s.tcpListener.Close()
+	}
+	if s.wsServer != nil {
+		s.wsServer.Close()
+	}
 
 	// Remove socket file
 	return os.Remove(s.socketPath)
@@ -135,9 +432,12 @@ func (s *Server) Stop() error {
 func (s *Server) PublishEvent(eventType EventType, data interface{}) {
 	event := Event{
 		Type:      eventType,
-		Timestamp: time.Now(),
+		Timestamp: time.Now().UTC(),
 		Data:      data,
 	}
+	for _, observer := range s.observers {
+		observer(event)
+	}
 
 	eventJSON, err := json.Marshal(event)
 	if err != nil {
@@ -150,10 +450,23 @@ func (s *Server) PublishEvent(eventType EventType, data interface{}) {
 
 	s.clientsMux.RLock()
 	defer s.clientsMux.RUnlock()
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
 
-	// Send to all connected clients
-	for conn := range s.clients {
-		_, err := conn.Write(eventJSON)
+	// Send to all clients allowed to see events
+	for conn, client := range s.clients {
+		if !client.role.Allows(RoleViewer) {
+			continue
+		}
+		if s.disconnect != nil && s.disconnect() {
+			log.Printf("Dropping IPC client %s", conn.RemoteAddr())
+			go s.removeClient(conn)
+			continue
+		}
+		data, err := client.encodeFor(eventJSON)
+		if err == nil {
+			_, err = conn.Write(data)
+		}
 		if err != nil {
 			log.Printf("Failed to write to client: %v", err)
 			// Remove client on write error
@@ -162,6 +475,29 @@ func (s *Server) PublishEvent(eventType EventType, data interface{}) {
 	}
 }
 
+// writeEvent sends an event to a single client
+func (s *Server) writeEvent(conn net.Conn, eventType EventType, data interface{}) error {
+	eventJSON, err := json.Marshal(Event{
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	s.clientsMux.RLock()
+	defer s.clientsMux.RUnlock()
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+	framed, err := s.clients[conn].encodeFor(append(eventJSON, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to frame event: %w", err)
+	}
+	_, err = conn.Write(framed)
+	return err
+}
+
 // Helper methods for common events
 func (s *Server) PublishQueueUpdated(queueLength int, nextTicket *ticket.Ticket) {
 	s.PublishEvent(EventTypeQueueUpdated, QueueEvent{
@@ -177,6 +513,20 @@ func (s *Server) PublishTicketEnqueued(t *ticket.Ticket) {
 	})
 }
 
+func (s *Server) PublishTicketRejected(t *ticket.Ticket, reason string) {
+	s.PublishEvent(EventTypeTicketRejected, TicketEvent{
+		Ticket:  t,
+		Message: reason,
+	})
+}
+
+func (s *Server) PublishPolicyViolation(t *ticket.Ticket, violations []policy.Violation) {
+	s.PublishEvent(EventTypePolicyViolation, PolicyViolationEvent{
+		Ticket:     t,
+		Violations: violations,
+	})
+}
+
 func (s *Server) PublishTicketStarted(t *ticket.Ticket, workerID int) {
 	s.PublishEvent(EventTypeTicketStarted, TicketEvent{
 		Ticket:   t,
@@ -185,31 +535,240 @@ func (s *Server) PublishTicketStarted(t *ticket.Ticket, workerID int) {
 	})
 }
 
+// PublishTicketPhase publishes a ticket entering a phase
+func (s *Server) PublishTicketPhase(workerID int, t *ticket.Ticket, phase string) {
+	s.PublishEvent(EventTypeTicketPhase, TicketPhaseEvent{
+		Ticket:   t,
+		WorkerID: workerID,
+		Phase:    phase,
+		Message:  fmt.Sprintf("Worker %d entered %s phase of ticket %s", workerID, phase, t.ID),
+	})
+}
+
+// PublishTicketFailed publishes a ticket a worker gave up on
+func (s *Server) PublishTicketFailed(t *ticket.Ticket, workerID int, code ErrorCode, reason string) {
+	s.PublishEvent(EventTypeTicketFailed, TicketEvent{
+		Ticket:   t,
+		WorkerID: workerID,
+		Message:  reason,
+		Code:     code,
+	})
+}
+
+// PublishTicketPreempted publishes a ticket checkpointed and requeued for an
+// urgent one
+func (s *Server) PublishTicketPreempted(t *ticket.Ticket, workerID int, message string) {
+	s.PublishEvent(EventTypeTicketPreempted, TicketEvent{
+		Ticket:   t,
+		WorkerID: workerID,
+		Message:  message,
+	})
+}
+
+// PublishTicketRetrying publishes a failed ticket requeued to run again
+func (s *Server) PublishTicketRetrying(t *ticket.Ticket, workerID int, message string) {
+	s.PublishEvent(EventTypeTicketRetrying, TicketEvent{
+		Ticket:   t,
+		WorkerID: workerID,
+		Message:  message,
+	})
+}
+
+// PublishTicketCancelled publishes a ticket an operator removed from the
+// queue (workerID 0) or stopped on a worker
+func (s *Server) PublishTicketCancelled(t *ticket.Ticket, workerID int, message string) {
+	s.PublishEvent(EventTypeTicketCancelled, TicketEvent{
+		Ticket:   t,
+		WorkerID: workerID,
+		Message:  message,
+	})
+}
+
 func (s *Server) PublishTicketComplete(t *ticket.Ticket, workerID int) {
+	message := fmt.Sprintf("Worker %d completed ticket %s", workerID, t.ID)
+	if t.Summary != "" {
+		message += ": " + t.Summary
+	}
+	if n := len(t.ArtifactURLs); n > 0 {
+		message += fmt.Sprintf(" (%d artifacts published)", n)
+	}
+
 	s.PublishEvent(EventTypeTicketComplete, TicketEvent{
 		Ticket:   t,
 		WorkerID: workerID,
-		Message:  fmt.Sprintf("Worker %d completed ticket %s", workerID, t.ID),
+		Message:  message,
 	})
 }
 
 func (s *Server) PublishWorkerStatus(workerID int, status string, currentTicket *ticket.Ticket, message string) {
+	s.PublishWorkerStats(workerID, status, currentTicket, message, nil)
+}
+
+// PublishWorkerStats publishes a worker status event carrying the worker's
+// running totals
+func (s *Server) PublishWorkerStats(workerID int, status string, currentTicket *ticket.Ticket, message string, stats *WorkerStats) {
 	s.PublishEvent(EventTypeWorkerStatus, WorkerStatusEvent{
 		WorkerID:      workerID,
 		Status:        status,
 		CurrentTicket: currentTicket,
 		Message:       message,
+		Stats:         stats,
+	})
+}
+
+func (s *Server) PublishAgentAuthError(workerID int, t *ticket.Ticket, message string) {
+	s.PublishEvent(EventTypeAgentAuthError, AgentAuthErrorEvent{
+		WorkerID: workerID,
+		Ticket:   t,
+		Severity: "critical",
+		Message:  message,
+	})
+}
+
+// PublishResourceLimitExceeded publishes a resource limit event
+func (s *Server) PublishResourceLimitExceeded(workerID int, t *ticket.Ticket, message string) {
+	s.PublishEvent(EventTypeResourceLimitExceeded, ResourceLimitEvent{
+		WorkerID: workerID,
+		Ticket:   t,
+		Message:  message,
+	})
+}
+
+// PublishCIFlaky publishes a test package that only passed on retry
+func (s *Server) PublishCIFlaky(workerID int, t *ticket.Ticket, pkg string) {
+	s.PublishEvent(EventTypeCIFlaky, CIFlakyEvent{
+		WorkerID: workerID,
+		Ticket:   t,
+		Package:  pkg,
+		Message:  fmt.Sprintf("%s passed on retry", pkg),
+	})
+}
+
+// PublishCISkipped publishes a ticket whose CI run was skipped and why
+func (s *Server) PublishCISkipped(workerID int, t *ticket.Ticket, reason string) {
+	s.PublishEvent(EventTypeCISkipped, TicketEvent{
+		Ticket:   t,
+		WorkerID: workerID,
+		Message:  reason,
+	})
+}
+
+// PublishRegressionTest publishes the outcome of a bug fix's regression test check
+func (s *Server) PublishRegressionTest(workerID int, t *ticket.Ticket, passed bool, message string) {
+	s.PublishEvent(EventTypeRegressionTest, RegressionTestEvent{
+		WorkerID: workerID,
+		Ticket:   t,
+		Passed:   passed,
+		Message:  message,
+	})
+}
+
+// PublishGuardedFiles publishes the files caught by the file guardrails
+func (s *Server) PublishGuardedFiles(workerID int, t *ticket.Ticket, rejected bool, message string) {
+	s.PublishEvent(EventTypeGuardedFiles, GuardedFilesEvent{
+		WorkerID: workerID,
+		Ticket:   t,
+		Rejected: rejected,
+		Message:  message,
+	})
+}
+
+// PublishCIRunning publishes the progress of a ticket's CI run
+func (s *Server) PublishCIRunning(workerID int, t *ticket.Ticket, step string, elapsed time.Duration, message string) {
+	s.PublishEvent(EventTypeCIRunning, CIRunningEvent{
+		WorkerID: workerID,
+		Ticket:   t,
+		Step:     step,
+		Elapsed:  elapsed,
+		Message:  message,
+	})
+}
+
+// PublishCIFullTier publishes the full CI tier's result for a completed ticket
+func (s *Server) PublishCIFullTier(event CIFullTierEvent) {
+	s.PublishEvent(EventTypeCIFullTier, event)
+}
+
+// PublishRuleTriggered publishes a rule's notification
+func (s *Server) PublishRuleTriggered(rule, message string) {
+	s.PublishEvent(EventTypeRuleTriggered, RuleTriggeredEvent{Rule: rule, Message: message})
+}
+
+// PublishTicketVerified publishes the outcome of a merged ticket's checks
+func (s *Server) PublishTicketVerified(event VerificationEvent) {
+	s.PublishEvent(EventTypeTicketVerified, event)
+}
+
+// PublishMainHealth publishes main turning red or green
+func (s *Server) PublishMainHealth(health MainHealth) {
+	s.PublishEvent(EventTypeMainHealth, health)
+}
+
+// PublishMergeConflict publishes a completed ticket's merge conflicts with main
+func (s *Server) PublishMergeConflict(event MergeConflictEvent) {
+	s.PublishEvent(EventTypeMergeConflict, event)
+}
+
+// PublishPullRequest publishes a pull request opened or updated for a
+// completed ticket
+func (s *Server) PublishPullRequest(event PullRequestEvent) {
+	s.PublishEvent(EventTypePullRequest, event)
+}
+
+// PublishLockAcquired publishes a ticket taking its locks as it starts
+func (s *Server) PublishLockAcquired(event LockEvent) {
+	s.PublishEvent(EventTypeLockAcquired, event)
+}
+
+// PublishLockReleased publishes a finished ticket giving up its locks
+func (s *Server) PublishLockReleased(event LockEvent) {
+	s.PublishEvent(EventTypeLockReleased, event)
+}
+
+// PublishLockWaiting publishes a ticket held in the queue by another's locks
+func (s *Server) PublishLockWaiting(event LockEvent) {
+	s.PublishEvent(EventTypeLockWaiting, event)
+}
+
+// PublishQuotaExceeded publishes a ticket held back by a quota
+func (s *Server) PublishQuotaExceeded(event QuotaExceededEvent) {
+	s.PublishEvent(EventTypeQuotaExceeded, event)
+}
+
+// PublishDispatchBlocked publishes the scheduler holding new tickets
+func (s *Server) PublishDispatchBlocked(event DispatchEvent) {
+	s.PublishEvent(EventTypeDispatchBlocked, event)
+}
+
+// PublishDispatchResumed publishes the scheduler handing out tickets again
+func (s *Server) PublishDispatchResumed(event DispatchEvent) {
+	s.PublishEvent(EventTypeDispatchResumed, event)
+}
+
+// PublishDiskSpace publishes a disk space threshold crossing
+func (s *Server) PublishDiskSpace(path string, freeMB, minFreeMB uint64, low bool, message string) {
+	severity := "info"
+	if low {
+		severity = "warning"
+	}
+	s.PublishEvent(EventTypeDiskSpace, DiskSpaceEvent{
+		Path:      path,
+		FreeMB:    freeMB,
+		MinFreeMB: minFreeMB,
+		Low:       low,
+		Severity:  severity,
+		Message:   message,
 	})
 }
 
 // acceptConnections handles incoming client connections
-func (s *Server) acceptConnections() {
+func (s *Server) acceptConnections(listener net.Listener) {
 	for {
 		select {
 		case <-s.ctx.Done():
 			return
 		default:
-			conn, err := s.listener.Accept()
+			conn, err := listener.Accept()
 			if err != nil {
 				if s.ctx.Err() != nil {
 					// Server is shutting down
@@ -226,8 +785,19 @@ func (s *Server) acceptConnections() {
 
 // addClient adds a new client connection
 func (s *Server) addClient(conn net.Conn) {
+	role := s.auth.DefaultRole()
+	if _, isTCP := conn.(*net.TCPConn); isTCP {
+		// Network clients must always present a token
+		role = RoleNone
+	}
+
+	s.addSession(conn, newSession(conn, role))
+}
+
+// addSession registers a connection with its session and serves it
+func (s *Server) addSession(conn net.Conn, client *session) {
 	s.clientsMux.Lock()
-	s.clients[conn] = true
+	s.clients[conn] = client
 	s.clientsMux.Unlock()
 
 	log.Printf("New IPC client connected: %s", conn.RemoteAddr())
@@ -250,7 +820,10 @@ func (s *Server) removeClient(conn net.Conn) {
 func (s *Server) handleClient(conn net.Conn) {
 	defer s.removeClient(conn)
 
-	// Set read timeout to detect dead connections
+	// Clients send newline-delimited commands; blank lines are keepalives
+	reader := bufio.NewReader(conn)
+	var pending []byte
+
 	for {
 		select {
 		case <-s.ctx.Done():
@@ -259,26 +832,42 @@ func (s *Server) handleClient(conn net.Conn) {
 			// Set a read deadline to periodically check if context is cancelled
 			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
 
-			// Try to read from connection (clients might send keepalive)
-			buf := make([]byte, 1024)
-			_, err := conn.Read(buf)
+			chunk, err := reader.ReadBytes('\n')
+			pending = append(pending, chunk...)
+			if len(pending) > maxCommandSize {
+				log.Printf("IPC command from %s too large, disconnecting", conn.RemoteAddr())
+				return
+			}
 			if err != nil {
 				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					// Timeout is expected, continue
+					// Timeout is expected, keep any partial line and continue
 					continue
 				}
 				// Connection closed or other error
 				return
 			}
+
+			line := bytes.TrimSpace(pending)
+			pending = nil
+			if len(line) > 0 {
+				s.dispatchCommand(conn, line)
+			}
 		}
 	}
 }
 
 // Client represents an IPC client that receives events
 type Client struct {
-	socketPath string
+	network    string
+	socketPath string // Socket path, host:port for TCP clients or a ws:// URL
+	token      string // Shared token presented in the WebSocket handshake
 	conn       net.Conn
 	events     chan Event
+	pending    map[string]chan CommandResponse // Commands awaiting a response, by ID
+	pendingMux sync.Mutex
+	nextID     int
+	framingID  string // Command asking for framing; the reader switches on its response
+	framing    string
 	ctx        context.Context
 	cancel     context.CancelFunc
 	closeOnce  sync.Once
@@ -296,10 +885,22 @@ func NewClient(socketPath string) *Client {
 		}
 	}
 
+	return newClient("unix", socketPath)
+}
+
+// NewTCPClient creates a client for a daemon's TCP listener
+func NewTCPClient(address string) *Client {
+	return newClient("tcp", address)
+}
+
+// newClient creates a client for a network address
+func newClient(network, address string) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Client{
-		socketPath: socketPath,
+		network:    network,
+		socketPath: address,
 		events:     make(chan Event, 100), // Buffer events
+		pending:    make(map[string]chan CommandResponse),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -307,9 +908,14 @@ func NewClient(socketPath string) *Client {
 
 // Connect establishes connection to the IPC server
 func (c *Client) Connect() error {
-	conn, err := net.Dial("unix", c.socketPath)
-	if err != nil {
-		return fmt.Errorf("failed to connect to unix socket: %w", err)
+	var conn net.Conn
+	var err error
+	if c.network == networkWebSocket {
+		if conn, err = dialWebSocket(c.socketPath, c.token); err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", c.socketPath, err)
+		}
+	} else if conn, err = net.Dial(c.network, c.socketPath); err != nil {
+		return fmt.Errorf("failed to connect to %s socket: %w", c.network, err)
 	}
 
 	c.conn = conn
@@ -344,7 +950,8 @@ func (c *Client) Close() error {
 func (c *Client) readEvents() {
 	defer c.Close()
 
-	decoder := json.NewDecoder(c.conn)
+	reader := bufio.NewReader(c.conn)
+	decode := lineDecoder(reader)
 
 	for {
 		select {
@@ -352,11 +959,19 @@ func (c *Client) readEvents() {
 			return
 		default:
 			var event Event
-			if err := decoder.Decode(&event); err != nil {
+			if err := decode(&event); err != nil {
 				log.Printf("Failed to decode event: %v", err)
 				return
 			}
 
+			if event.Type == EventTypeCommandResponse {
+				// Everything after an accepted framing request is framed
+				if c.deliverResponse(event) == FramingDeflate {
+					decode = deflateDecoder(reader)
+				}
+				continue
+			}
+
 			select {
 			case c.events <- event:
 			case <-c.ctx.Done():
@@ -2,6 +2,7 @@ package ipc
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -79,6 +80,25 @@ func TestIPCServer(t *testing.T) {
 	}
 }
 
+func TestEventObserver(t *testing.T) {
+	server := NewServer(filepath.Join(t.TempDir(), "test.sock"))
+
+	var observed []Event
+	server.AddEventObserver(func(event Event) {
+		observed = append(observed, event)
+	})
+
+	// Observers see events even without connected clients
+	server.PublishCIFlaky(2, &ticket.Ticket{ID: "feat-1"}, "example.com/app/auth")
+	if len(observed) != 1 || observed[0].Type != EventTypeCIFlaky {
+		t.Fatalf("Expected the ci_flaky event, got %+v", observed)
+	}
+	data, ok := observed[0].Data.(CIFlakyEvent)
+	if !ok || data.Package != "example.com/app/auth" || data.WorkerID != 2 {
+		t.Errorf("Unexpected event data %+v", observed[0].Data)
+	}
+}
+
 func TestIPCQueueEvent(t *testing.T) {
 	// Create temporary directory for socket
 	tmpDir, err := os.MkdirTemp("", "ipc-test")
@@ -212,6 +232,21 @@ func TestIPCWorkerStatusEvent(t *testing.T) {
 	case <-ctx.Done():
 		t.Fatal("Timeout waiting for event")
 	}
+
+	// Running totals ride along when given
+	server.PublishWorkerStats(1, "idle", nil, "Completed ticket", &WorkerStats{WorkerID: 1, TicketsCompleted: 2, Phase: "ci"})
+
+	select {
+	case event := <-client.Events():
+		workerEvent, _ := event.Data.(map[string]interface{})
+		stats, ok := workerEvent["stats"].(map[string]interface{})
+		if !ok || stats["tickets_completed"] != float64(2) || stats["phase"] != "ci" {
+			t.Errorf("Expected stats in the event, got %v", workerEvent["stats"])
+		}
+
+	case <-ctx.Done():
+		t.Fatal("Timeout waiting for event")
+	}
 }
 
 func TestIPCMultipleClients(t *testing.T) {
@@ -276,4 +311,89 @@ func TestIPCMultipleClients(t *testing.T) {
 	case <-ctx.Done():
 		t.Fatal("Timeout waiting for event on client2")
 	}
-}
\ No newline at end of file
+}
+func TestIPCCommand(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+
+	server := NewServer(socketPath)
+	server.HandleCommand("echo", RoleViewer, func(caller Caller, args map[string]string) (string, error) {
+		if args["text"] == "" {
+			return "", errors.New("nothing to echo")
+		}
+		return args["text"], nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	client := NewClient(socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	response, err := client.SendCommand(ctx, "echo", map[string]string{"text": "hello"})
+	if err != nil {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	if !response.OK || response.Message != "hello" {
+		t.Errorf("Expected ok response with message hello, got %+v", response)
+	}
+
+	response, err = client.SendCommand(ctx, "echo", nil)
+	if err != nil {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	if response.OK || response.Error != "nothing to echo" {
+		t.Errorf("Expected handler error, got %+v", response)
+	}
+
+	response, err = client.SendCommand(ctx, "missing", nil)
+	if err != nil {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	if response.OK {
+		t.Errorf("Expected unknown command to fail, got %+v", response)
+	}
+
+	// Command responses must not leak into the event stream
+	select {
+	case event := <-client.Events():
+		t.Errorf("Unexpected event %s", event.Type)
+	default:
+	}
+}
+
+func TestDisconnectorDropsClients(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+
+	server := NewServer(socketPath)
+	server.SetDisconnector(func() bool { return true })
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	client := NewClient(socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	server.PublishQueueUpdated(1, nil)
+
+	select {
+	case event, ok := <-client.Events():
+		if ok {
+			t.Errorf("Expected the client to be dropped, got %s", event.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the client to be dropped")
+	}
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package ipc
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredentials returns the uid and pid of the process on the other end
+// of a unix socket, using SO_PEERCRED
+func peerCredentials(conn net.Conn) (uid, pid int, ok bool) {
+	unixConn, isUnix := conn.(*net.UnixConn)
+	if !isUnix {
+		return 0, 0, false
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, 0, false
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil || credErr != nil {
+		return 0, 0, false
+	}
+	return int(cred.Uid), int(cred.Pid), true
+}
@@ -0,0 +1,154 @@
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// socketTempPrefix names the private directories sockets are bound in
+// before they are moved into place
+const socketTempPrefix = ".socket-"
+
+// SocketPermissions restrict who may connect to the Unix socket
+// Empty fields leave the socket as the umask creates it
+type SocketPermissions struct {
+	Mode    string `mapstructure:"mode"`     // Octal file mode of the socket, e.g. 0660
+	Group   string `mapstructure:"group"`    // Group name or ID given the socket and, with dir_mode, its directory
+	DirMode string `mapstructure:"dir_mode"` // Octal mode the socket's directory is created with and kept at, e.g. 0750
+}
+
+// Validate checks the modes and that the group exists
+func (p SocketPermissions) Validate() error {
+	if _, err := parseMode(p.Mode); err != nil {
+		return fmt.Errorf("mode: %w", err)
+	}
+	if _, err := parseMode(p.DirMode); err != nil {
+		return fmt.Errorf("dir_mode: %w", err)
+	}
+	if _, err := lookupGroup(p.Group); err != nil {
+		return fmt.Errorf("group: %w", err)
+	}
+	return nil
+}
+
+// SetSocketPermissions sets the socket's mode, group and directory; call it
+// before Start
+func (s *Server) SetSocketPermissions(p SocketPermissions) {
+	s.socketPerms = p
+}
+
+// prepareSocketDir creates the socket's directory, applying dir_mode and the
+// group to it when dir_mode is set; without it an existing directory such as
+// the home directory is left alone. A directory given dir_mode must be the
+// socket's own, so an existing one may hold nothing but the socket.
+func (s *Server) prepareSocketDir() error {
+	dir := filepath.Dir(s.socketPath)
+	dirMode, _ := parseMode(s.socketPerms.DirMode)
+	if dirMode == 0 {
+		return os.MkdirAll(dir, 0755)
+	}
+
+	entries, err := os.ReadDir(dir)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(dir, dirMode); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		for _, entry := range entries {
+			if entry.Name() != filepath.Base(s.socketPath) && !strings.HasPrefix(entry.Name(), socketTempPrefix) {
+				return fmt.Errorf("dir_mode needs a directory of the socket's own, but %s also holds %s", dir, entry.Name())
+			}
+		}
+	}
+	if err := os.Chmod(dir, dirMode); err != nil {
+		return err
+	}
+	return chgrp(dir, s.socketPerms.Group)
+}
+
+// listenUnix creates the listening socket; with a mode or group it is bound
+// in a private directory and only moved into place once they are set, so it
+// is never reachable with the umask's permissions
+func (s *Server) listenUnix() (net.Listener, error) {
+	if s.socketPerms.Mode == "" && s.socketPerms.Group == "" {
+		return net.Listen("unix", s.socketPath)
+	}
+
+	private, err := os.MkdirTemp(filepath.Dir(s.socketPath), socketTempPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(private)
+
+	boundPath := filepath.Join(private, filepath.Base(s.socketPath))
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: boundPath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// Stop removes the socket from where it ends up
+	listener.SetUnlinkOnClose(false)
+	if err := s.applySocketPermissions(boundPath); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	if err := os.Rename(boundPath, s.socketPath); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// applySocketPermissions sets the mode and group of the socket at path
+func (s *Server) applySocketPermissions(path string) error {
+	mode, _ := parseMode(s.socketPerms.Mode)
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+	}
+	return chgrp(path, s.socketPerms.Group)
+}
+
+// parseMode reads an octal permission mode; "" is 0
+func parseMode(value string) (os.FileMode, error) {
+	if value == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return 0, fmt.Errorf("invalid mode %q (expected octal permissions such as 0660)", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// lookupGroup resolves a group name or numeric ID; "" is -1, leaving the
+// group unchanged
+func lookupGroup(group string) (int, error) {
+	if group == "" {
+		return -1, nil
+	}
+	if gid, err := strconv.Atoi(group); err == nil && gid >= 0 {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+func chgrp(path, group string) error {
+	gid, err := lookupGroup(group)
+	if err != nil || gid < 0 {
+		return err
+	}
+	return os.Chown(path, -1, gid)
+}
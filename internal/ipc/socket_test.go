@@ -0,0 +1,83 @@
+package ipc
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestSocketPermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run", "orchestrator")
+	socketPath := filepath.Join(dir, "test.sock")
+	gid := strconv.Itoa(os.Getgid())
+
+	server := NewServer(socketPath)
+	server.SetSocketPermissions(SocketPermissions{Mode: "0660", Group: gid, DirMode: "0750"})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Failed to stat socket: %v", err)
+	}
+	if info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket mode 0660, got %o", info.Mode().Perm())
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && strconv.Itoa(int(stat.Gid)) != gid {
+		t.Errorf("Expected socket group %s, got %d", gid, stat.Gid)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0750 {
+		t.Errorf("Expected directory mode 0750, got %v (err %v)", info.Mode().Perm(), err)
+	}
+
+	client := NewClient(socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect through the restricted socket: %v", err)
+	}
+	client.Close()
+}
+
+func TestSocketPermissionsRefuseSharedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	server := NewServer(filepath.Join(dir, "test.sock"))
+	server.SetSocketPermissions(SocketPermissions{DirMode: "0750"})
+	if err := server.Start(); err == nil {
+		server.Stop()
+		t.Fatal("Expected dir_mode to be refused for a directory holding other files")
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() == 0750 {
+		t.Errorf("Expected the shared directory to be left alone, got %v (err %v)", info.Mode().Perm(), err)
+	}
+}
+
+func TestSocketPermissions_Validate(t *testing.T) {
+	valid := []SocketPermissions{
+		{},
+		{Mode: "0660", DirMode: "750", Group: strconv.Itoa(os.Getgid())},
+	}
+	for _, p := range valid {
+		if err := p.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", p, err)
+		}
+	}
+
+	invalid := []SocketPermissions{
+		{Mode: "rw-rw----"},
+		{Mode: "0"},
+		{DirMode: "1777"},
+		{Group: "no-such-group-for-orchestrator"},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", p)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package ipc
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/websocket"
+)
+
+// WebSocketPath is where the bridge accepts connections
+const WebSocketPath = "/ipc"
+
+// networkWebSocket marks clients that connect through the bridge
+const networkWebSocket = "websocket"
+
+// webSocketCaller names connections that presented the shared token
+const webSocketCaller = "websocket"
+
+// maxEventSize bounds a single event read by WebSocket clients
+const maxEventSize = 16 * 1024 * 1024
+
+// WebSocketConfig bridges the IPC protocol over WebSockets, so TUIs and
+// scripts on other machines can stream events and send commands. The same
+// shared token configures both ends: the daemon listens on ListenAddr and
+// the CLI and TUI connect to URL instead of the socket. Without TLSCert and
+// TLSKey the bridge speaks plain ws://, which only belongs on loopback or
+// behind a TLS-terminating proxy.
+type WebSocketConfig struct {
+	ListenAddr string `mapstructure:"listen_addr"` // Daemon side, e.g. 127.0.0.1:7421; empty disables the bridge
+	URL        string `mapstructure:"url"`         // Client side, e.g. wss://build-01:7421/ipc; empty uses socket_path
+	TokenEnv   string `mapstructure:"token_env"`   // Variable holding the shared token
+	Role       Role   `mapstructure:"role"`        // Role the shared token grants; defaults to viewer
+	TLSCert    string `mapstructure:"tls_cert"`    // Daemon side: PEM certificate to serve wss:// with
+	TLSKey     string `mapstructure:"tls_key"`     // Daemon side: PEM key for TLSCert
+}
+
+// TLS reports whether the daemon serves the bridge over TLS
+func (c WebSocketConfig) TLS() bool {
+	return c.TLSCert != ""
+}
+
+// Validate checks the token variable, role and URL
+func (c WebSocketConfig) Validate() error {
+	if c.ListenAddr == "" && c.URL == "" {
+		return nil
+	}
+	if c.TokenEnv == "" {
+		return errors.New("token_env is required")
+	}
+	if c.Role != "" && c.Role.level() == 0 {
+		return fmt.Errorf("unknown role %q (expected viewer, operator or admin)", c.Role)
+	}
+	if c.URL != "" && !strings.HasPrefix(c.URL, "ws://") && !strings.HasPrefix(c.URL, "wss://") {
+		return fmt.Errorf("url %q must start with ws:// or wss://", c.URL)
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("tls_cert and tls_key must be set together")
+	}
+	return nil
+}
+
+// StartWebSocket additionally accepts connections as WebSockets at
+// WebSocketPath on the configured address. Each WebSocket message carries
+// what a socket line would: commands from the client, events and command
+// responses from the server. Clients present the shared token as an
+// Authorization: Bearer header, never in the URL where proxies log it, and
+// get the configured role; with ipc.auth they can authenticate to raise it
+// like any other client.
+func (s *Server) StartWebSocket(config WebSocketConfig) error {
+	token := os.Getenv(config.TokenEnv)
+	if token == "" {
+		return fmt.Errorf("refusing to listen on %s: %s is not set", config.ListenAddr, config.TokenEnv)
+	}
+	role := config.Role
+	if role == "" {
+		role = RoleViewer
+	}
+
+	var certificate tls.Certificate
+	scheme := "ws"
+	if config.TLS() {
+		var err error
+		if certificate, err = tls.LoadX509KeyPair(config.TLSCert, config.TLSKey); err != nil {
+			return fmt.Errorf("failed to load the bridge's TLS certificate: %w", err)
+		}
+		scheme = "wss"
+	}
+
+	listener, err := net.Listen("tcp", config.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", config.ListenAddr, err)
+	}
+	if config.TLS() {
+		listener = tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+WebSocketPath, func(w http.ResponseWriter, r *http.Request) {
+		s.acceptWebSocket(w, r, token, role)
+	})
+	s.wsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	s.wsListener = listener
+	log.Printf("IPC server listening on %s://%s%s", scheme, listener.Addr(), WebSocketPath)
+
+	go func() {
+		if err := s.wsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("IPC WebSocket bridge stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// WebSocketAddress returns the address of the WebSocket listener, if any
+func (s *Server) WebSocketAddress() string {
+	if s.wsListener == nil {
+		return ""
+	}
+	return s.wsListener.Addr().String()
+}
+
+// acceptWebSocket checks the shared token and serves the upgraded
+// connection like a socket client
+func (s *Server) acceptWebSocket(w http.ResponseWriter, r *http.Request, token string, role Role) {
+	header := r.Header.Get("Authorization")
+	presented, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ws, err := websocket.Upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ws.SetReadLimit(maxCommandSize)
+
+	s.addSession(websocket.NewStream(ws), &session{
+		caller: Caller{Token: webSocketCaller, Addr: r.RemoteAddr},
+		role:   role,
+	})
+}
+
+// NewWebSocketClient creates a client for a daemon's WebSocket bridge,
+// presenting the shared token when it connects
+func NewWebSocketClient(url, token string) *Client {
+	c := newClient(networkWebSocket, url)
+	c.token = token
+	return c
+}
+
+// dialWebSocket connects to the bridge and returns the connection as a stream
+func dialWebSocket(url, token string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ws, err := websocket.Dial(ctx, url, http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		return nil, err
+	}
+	ws.SetReadLimit(maxEventSize)
+	return websocket.NewStream(ws), nil
+}
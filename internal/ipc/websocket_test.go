@@ -0,0 +1,193 @@
+package ipc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// startWebSocketServer serves the bridge on a random port with the shared
+// token ws-secret and an operator command
+func startWebSocketServer(t *testing.T, role Role) (*Server, string) {
+	t.Helper()
+	t.Setenv("TEST_WS_TOKEN", "ws-secret")
+
+	server := NewServer(filepath.Join(t.TempDir(), "test.sock"))
+	server.HandleCommand("cancel", RoleOperator, func(caller Caller, args map[string]string) (string, error) {
+		return "cancelled for " + caller.String(), nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+
+	if err := server.StartWebSocket(WebSocketConfig{ListenAddr: "127.0.0.1:0", TokenEnv: "TEST_WS_TOKEN", Role: role}); err != nil {
+		t.Fatalf("StartWebSocket failed: %v", err)
+	}
+	return server, "ws://" + server.WebSocketAddress() + WebSocketPath
+}
+
+func TestWebSocketBridgeStreamsEvents(t *testing.T) {
+	server, url := startWebSocketServer(t, "")
+
+	client := NewWebSocketClient(url, "ws-secret")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// A round trip guarantees the server has registered the client
+	response, err := client.SendCommand(ctx, "cancel", nil)
+	if err != nil || response.OK || !strings.Contains(response.Error, "permission denied") {
+		t.Fatalf("Expected the default viewer role to be refused, got %+v (err %v)", response, err)
+	}
+
+	// Without ipc.auth tokens, authenticating can't raise the shared token's role
+	if err := client.Authenticate(ctx, "anything"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if response, err := client.SendCommand(ctx, "cancel", nil); err != nil || response.OK {
+		t.Fatalf("Expected the viewer role to stick, got %+v (err %v)", response, err)
+	}
+
+	// Commands are announced as control events before the ticket arrives
+	server.PublishTicketEnqueued(&ticket.Ticket{ID: "feat-1", Title: "Remote"})
+	for {
+		select {
+		case event := <-client.Events():
+			if event.Type == EventTypeTicketEnqueued {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for ticket_enqueued over the bridge")
+		}
+	}
+}
+
+func TestWebSocketBridgeRoleAndToken(t *testing.T) {
+	_, url := startWebSocketServer(t, RoleOperator)
+
+	if err := NewWebSocketClient(url, "wrong").Connect(); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected a wrong token to be refused, got %v", err)
+	}
+
+	client := NewWebSocketClient(url, "ws-secret")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	response, err := client.SendCommand(ctx, "cancel", nil)
+	if err != nil || !response.OK || !strings.HasPrefix(response.Message, "cancelled for websocket (127.0.0.1:") {
+		t.Fatalf("Expected the operator command attributed to the bridge, got %+v (err %v)", response, err)
+	}
+}
+
+func TestWebSocketBridgeRefusesTokenInURL(t *testing.T) {
+	_, url := startWebSocketServer(t, RoleOperator)
+
+	resp, err := http.Get(strings.Replace(url, "ws://", "http://", 1) + "?token=ws-secret")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a token in the URL to be refused, got %s", resp.Status)
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and
+// its key, returning their paths and the certificate
+func writeTestCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "orchestrator"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "bridge.crt"), filepath.Join(dir, "bridge.key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certPath, keyPath, cert
+}
+
+func TestWebSocketBridgeServesTLS(t *testing.T) {
+	t.Setenv("TEST_WS_TOKEN", "ws-secret")
+	certPath, keyPath, cert := writeTestCertificate(t)
+
+	server := NewServer(filepath.Join(t.TempDir(), "test.sock"))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+	config := WebSocketConfig{ListenAddr: "127.0.0.1:0", TokenEnv: "TEST_WS_TOKEN", TLSCert: certPath, TLSKey: keyPath}
+	if err := server.StartWebSocket(config); err != nil {
+		t.Fatalf("StartWebSocket failed: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get("https://" + server.WebSocketAddress() + WebSocketPath)
+	if err != nil {
+		t.Fatalf("Expected the bridge to speak TLS, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a request without a token to be refused, got %s", resp.Status)
+	}
+}
+
+func TestWebSocketConfigValidate(t *testing.T) {
+	for _, config := range []WebSocketConfig{
+		{ListenAddr: ":7421"},
+		{ListenAddr: ":7421", TokenEnv: "WS_TOKEN", Role: "root"},
+		{URL: "http://build-01:7421/ipc", TokenEnv: "WS_TOKEN"},
+		{ListenAddr: ":7421", TokenEnv: "WS_TOKEN", TLSCert: "bridge.crt"},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Config %+v should be rejected", config)
+		}
+	}
+	if err := (WebSocketConfig{URL: "wss://build-01/ipc", TokenEnv: "WS_TOKEN"}).Validate(); err != nil {
+		t.Errorf("Expected a wss URL to be valid, got %v", err)
+	}
+}
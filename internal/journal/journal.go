@@ -0,0 +1,184 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+)
+
+// tailChunk is how much of a file is read at a time when looking for the
+// end of its last complete line
+const tailChunk = 64 * 1024
+
+// Writer appends values to a JSON lines file, one line per value
+// With an encrypting cipher each line is sealed and base64 encoded
+type Writer struct {
+	path   string
+	cipher *encryption.Cipher
+	mu     sync.Mutex
+}
+
+// NewWriter returns a writer appending to path; the file is created on the
+// first append
+func NewWriter(path string, cipher *encryption.Cipher) *Writer {
+	return &Writer{path: path, cipher: cipher}
+}
+
+// Append writes v to the end of the file
+func (w *Writer) Append(v interface{}) error {
+	line, err := Encode(v, w.cipher)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	f, _, err := OpenAppend(w.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(w.path), err)
+	}
+	return nil
+}
+
+// Encode marshals v into a line, sealed if cipher encrypts, ending in a
+// newline
+func Encode(v interface{}, cipher *encryption.Cipher) ([]byte, error) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+	if cipher.Encrypts() {
+		sealed, err := cipher.Encrypt(line)
+		if err != nil {
+			return nil, err
+		}
+		line = []byte(base64.StdEncoding.EncodeToString(sealed))
+	}
+	return append(line, '\n'), nil
+}
+
+// OpenAppend opens path for appending, creating it if needed, and returns
+// its size. A last line left unterminated by a crash mid-write is cut off
+// first, so the next line isn't glued onto it.
+func OpenAppend(path string) (*os.File, int64, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+
+	size, err := completeLength(f, info.Size())
+	if err == nil && size != info.Size() {
+		err = f.Truncate(size)
+	}
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("failed to repair %s: %w", filepath.Base(path), err)
+	}
+	return f, size, nil
+}
+
+// completeLength returns the length of f up to the end of its last
+// newline-terminated line
+func completeLength(f *os.File, size int64) (int64, error) {
+	buf := make([]byte, tailChunk)
+	for end := size; end > 0; {
+		start := max(end-tailChunk, 0)
+		chunk := buf[:end-start]
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// Read calls decode with the JSON of each line of path in order, decrypting
+// sealed lines with cipher. A missing file has no lines. A line only counts
+// once its newline is written, so a last line a crash cut short is skipped;
+// any other line that can't be read is an error naming it.
+func Read(path string, cipher *encryption.Cipher, decode func(data []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for lineNum := 1; ; lineNum++ {
+		raw, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+
+		if line := bytes.TrimSpace(raw); len(line) > 0 {
+			if err := decodeLine(line, cipher, decode); err != nil {
+				return fmt.Errorf("%s line %d: %w", filepath.Base(path), lineNum, err)
+			}
+		}
+	}
+}
+
+// Lines returns the complete, non-empty lines of path as they are stored,
+// sealed or not, for copying a journal elsewhere; a missing file has none
+func Lines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	var lines []string
+	complete := data[:bytes.LastIndexByte(data, '\n')+1]
+	for _, line := range strings.Split(string(complete), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// decodeLine unseals a line if it isn't plain JSON and hands it to decode
+func decodeLine(line []byte, cipher *encryption.Cipher, decode func(data []byte) error) error {
+	if line[0] != '{' {
+		sealed, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil {
+			return err
+		}
+		if line, err = cipher.Decrypt(sealed); err != nil {
+			return err
+		}
+	}
+	return decode(line)
+}
@@ -0,0 +1,102 @@
+package journal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+)
+
+type entry struct {
+	N int `json:"n"`
+}
+
+func readAll(t *testing.T, path string, cipher *encryption.Cipher) ([]int, error) {
+	t.Helper()
+	var ns []int
+	err := Read(path, cipher, func(data []byte) error {
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		ns = append(ns, e.N)
+		return nil
+	})
+	return ns, err
+}
+
+func TestReadMissingFile(t *testing.T) {
+	ns, err := readAll(t, filepath.Join(t.TempDir(), "missing.jsonl"), nil)
+	if err != nil || len(ns) != 0 {
+		t.Fatalf("Expected no entries, got %v (err %v)", ns, err)
+	}
+}
+
+func TestTornLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.jsonl")
+	w := NewWriter(path, nil)
+	for n := 1; n <= 2; n++ {
+		if err := w.Append(entry{N: n}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// A crash cut the third line short
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	f.WriteString(`{"n":`)
+	f.Close()
+
+	ns, err := readAll(t, path, nil)
+	if err != nil || len(ns) != 2 {
+		t.Fatalf("Expected the torn line to be skipped, got %v (err %v)", ns, err)
+	}
+
+	// The next append replaces the torn line instead of joining it
+	if err := w.Append(entry{N: 3}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	ns, err = readAll(t, path, nil)
+	if err != nil || len(ns) != 3 || ns[2] != 3 {
+		t.Fatalf("Expected entries 1 to 3, got %v (err %v)", ns, err)
+	}
+}
+
+func TestCorruptLineIsAnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.jsonl")
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n{\"n\":\n{\"n\":3}\n"), 0600); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+	if _, err := readAll(t, path, nil); err == nil || !strings.Contains(err.Error(), "test.jsonl line 2") {
+		t.Errorf("Expected an error naming line 2, got %v", err)
+	}
+}
+
+func TestEncryptedLines(t *testing.T) {
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "test.jsonl")
+	if err := NewWriter(path, cipher).Append(entry{N: 7}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if strings.Contains(string(data), `"n"`) {
+		t.Errorf("Expected the line to be sealed, got %q", data)
+	}
+
+	ns, err := readAll(t, path, cipher)
+	if err != nil || len(ns) != 1 || ns[0] != 7 {
+		t.Fatalf("Expected entry 7, got %v (err %v)", ns, err)
+	}
+}
@@ -0,0 +1,338 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// Execution backends accepted in agents.backend
+const (
+	BackendLocal      = "local"      // Agents run as processes of the daemon's workers
+	BackendKubernetes = "kubernetes" // Each ticket runs as a Kubernetes Job
+)
+
+// Environment variables the Job's container receives
+const (
+	EnvTicket   = "ORCHESTRATOR_TICKET"    // The ticket as JSON
+	EnvTicketID = "ORCHESTRATOR_TICKET_ID" // The ticket's ID
+	EnvPrompt   = "ORCHESTRATOR_PROMPT"    // The rendered agent prompt
+	EnvRepoURL  = "ORCHESTRATOR_REPO_URL"  // Repository to clone and push to
+	EnvBranch   = "ORCHESTRATOR_BRANCH"    // Branch the work must be pushed to
+)
+
+// managedByLabel marks the Jobs the orchestrator created
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// ErrJobFailed indicates the Job ran but did not succeed
+var ErrJobFailed = errors.New("kubernetes job failed")
+
+// Config selects the cluster and shapes the Jobs agents run in
+type Config struct {
+	Image          string            `mapstructure:"image"`           // Runs the agent; see examples/kube-job-entrypoint.sh
+	RepoURL        string            `mapstructure:"repo_url"`        // repository.path as reachable from pods
+	Namespace      string            `mapstructure:"namespace"`       // Defaults to kubectl's current namespace
+	Context        string            `mapstructure:"context"`         // kubeconfig context; defaults to the current one
+	CPU            string            `mapstructure:"cpu"`             // Requested and limited, e.g. "2"
+	Memory         string            `mapstructure:"memory"`          // Requested and limited, e.g. "4Gi"
+	ServiceAccount string            `mapstructure:"service_account"` // Optional
+	SecretName     string            `mapstructure:"secret_name"`     // Optional secret exposed as env vars, e.g. the agent's API key
+	NodeSelector   map[string]string `mapstructure:"node_selector"`   // Optional, e.g. to pick GPU nodes
+	TTLSeconds     int               `mapstructure:"ttl_seconds"`     // Finished Jobs are deleted after this; 0 keeps them
+	PollSeconds    int               `mapstructure:"poll_seconds"`    // Seconds between Job status checks; defaults to 10
+	Kubectl        string            `mapstructure:"kubectl"`         // Defaults to kubectl on PATH
+}
+
+// Validate checks the settings required to launch Jobs
+func (c Config) Validate() error {
+	if c.Image == "" || c.RepoURL == "" {
+		return errors.New("image and repo_url are required")
+	}
+	if c.TTLSeconds < 0 || c.PollSeconds < 0 {
+		return errors.New("ttl_seconds and poll_seconds cannot be negative")
+	}
+	return nil
+}
+
+// Job describes one ticket to run
+type Job struct {
+	Ticket   *ticket.Ticket
+	Branch   string
+	Prompt   string
+	WorkerID int
+}
+
+// Result is what a finished Job left behind
+type Result struct {
+	Name string
+	Logs []byte // Output of the Job's pods
+}
+
+// Runner launches Jobs with kubectl and waits for them
+type Runner struct {
+	config       Config
+	timeout      time.Duration // The Job's activeDeadlineSeconds; 0 means no limit
+	pollInterval time.Duration
+	kubectl      func(ctx context.Context, stdin []byte, args ...string) ([]byte, error)
+	runner       command.Runner
+	now          func() time.Time
+}
+
+// NewRunner creates a runner for the configured cluster; Jobs running longer
+// than timeout are stopped by Kubernetes
+func NewRunner(config Config, timeout time.Duration) (*Runner, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &Runner{
+		config:       config,
+		timeout:      timeout,
+		pollInterval: 10 * time.Second,
+		now:          time.Now,
+	}
+	if config.PollSeconds > 0 {
+		r.pollInterval = time.Duration(config.PollSeconds) * time.Second
+	}
+	r.kubectl = r.runKubectl
+	return r, nil
+}
+
+// Run launches a Job for a ticket and waits for it to finish
+// The Job is deleted if ctx is done first; the logs are returned either way
+func (r *Runner) Run(ctx context.Context, job Job) (Result, error) {
+	name := jobName(job.Ticket.ID, r.now())
+	result := Result{Name: name}
+
+	manifest, err := r.manifest(name, job)
+	if err != nil {
+		return result, err
+	}
+	if _, err := r.kubectl(ctx, manifest, "create", "-f", "-"); err != nil {
+		return result, fmt.Errorf("failed to create job %s: %w", name, err)
+	}
+	log.Printf("Launched kubernetes job %s for ticket %s", name, job.Ticket.ID)
+
+	succeeded, waitErr := r.wait(ctx, name)
+
+	// Collect logs even from failed Jobs; they explain the failure
+	logCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	result.Logs, err = r.kubectl(logCtx, nil, "logs", "job/"+name, "--all-containers")
+	if err != nil {
+		log.Printf("Failed to collect logs of job %s: %v", name, err)
+	}
+
+	if waitErr != nil {
+		if _, err := r.kubectl(logCtx, nil, "delete", "job", name, "--ignore-not-found", "--wait=false"); err != nil {
+			log.Printf("Failed to delete job %s: %v", name, err)
+		}
+		return result, waitErr
+	}
+	if !succeeded {
+		return result, fmt.Errorf("%w: %s", ErrJobFailed, name)
+	}
+	return result, nil
+}
+
+// wait polls a Job until it completes or fails
+func (r *Runner) wait(ctx context.Context, name string) (bool, error) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+
+		output, err := r.kubectl(ctx, nil, "get", "job", name, "-o", "json")
+		if err != nil {
+			log.Printf("Failed to check job %s: %v", name, err)
+			continue
+		}
+
+		var status jobStatus
+		if err := json.Unmarshal(output, &status); err != nil {
+			return false, fmt.Errorf("invalid status for job %s: %w", name, err)
+		}
+		for _, condition := range status.Status.Conditions {
+			if condition.Status != "True" {
+				continue
+			}
+			switch condition.Type {
+			case "Complete":
+				return true, nil
+			case "Failed":
+				log.Printf("Kubernetes job %s failed: %s %s", name, condition.Reason, condition.Message)
+				return false, nil
+			}
+		}
+	}
+}
+
+// jobStatus is the part of a Job object the runner reads
+type jobStatus struct {
+	Status struct {
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// manifest renders the Job object for kubectl create
+func (r *Runner) manifest(name string, job Job) ([]byte, error) {
+	ticketJSON, err := json.Marshal(job.Ticket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	container := map[string]interface{}{
+		"name":  "agent",
+		"image": r.config.Image,
+		"env": []map[string]string{
+			{"name": EnvTicket, "value": string(ticketJSON)},
+			{"name": EnvTicketID, "value": job.Ticket.ID},
+			{"name": EnvPrompt, "value": job.Prompt},
+			{"name": EnvRepoURL, "value": r.config.RepoURL},
+			{"name": EnvBranch, "value": job.Branch},
+		},
+	}
+	if r.config.SecretName != "" {
+		container["envFrom"] = []map[string]interface{}{
+			{"secretRef": map[string]string{"name": r.config.SecretName}},
+		}
+	}
+	resources := map[string]string{}
+	if r.config.CPU != "" {
+		resources["cpu"] = r.config.CPU
+	}
+	if r.config.Memory != "" {
+		resources["memory"] = r.config.Memory
+	}
+	if len(resources) > 0 {
+		container["resources"] = map[string]interface{}{"requests": resources, "limits": resources}
+	}
+
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+		"containers":    []interface{}{container},
+	}
+	if r.config.ServiceAccount != "" {
+		podSpec["serviceAccountName"] = r.config.ServiceAccount
+	}
+	if len(r.config.NodeSelector) > 0 {
+		podSpec["nodeSelector"] = r.config.NodeSelector
+	}
+
+	labels := map[string]string{
+		managedByLabel:        "amp-orchestrator",
+		"orchestrator/ticket": labelValue(job.Ticket.ID),
+		"orchestrator/worker": strconv.Itoa(job.WorkerID),
+	}
+
+	// Agents don't get retried by Kubernetes; the orchestrator decides
+	spec := map[string]interface{}{
+		"backoffLimit": 0,
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": labels},
+			"spec":     podSpec,
+		},
+	}
+	if r.timeout > 0 {
+		spec["activeDeadlineSeconds"] = int64(r.timeout / time.Second)
+	}
+	if r.config.TTLSeconds > 0 {
+		spec["ttlSecondsAfterFinished"] = r.config.TTLSeconds
+	}
+
+	metadata := map[string]interface{}{"name": name, "labels": labels}
+	if r.config.Namespace != "" {
+		metadata["namespace"] = r.config.Namespace
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   metadata,
+		"spec":       spec,
+	})
+}
+
+// SetRunner sets what runs kubectl; nil uses command.Default
+func (r *Runner) SetRunner(runner command.Runner) {
+	r.runner = runner
+}
+
+// runKubectl runs kubectl against the configured context and namespace
+func (r *Runner) runKubectl(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	kubectl := r.config.Kubectl
+	if kubectl == "" {
+		kubectl = "kubectl"
+	}
+
+	var global []string
+	if r.config.Context != "" {
+		global = append(global, "--context", r.config.Context)
+	}
+	if r.config.Namespace != "" {
+		global = append(global, "--namespace", r.config.Namespace)
+	}
+
+	cmd := command.Context(ctx, kubectl, append(global, args...)...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := command.Or(r.runner).Output(cmd)
+	if err != nil {
+		return output, fmt.Errorf("kubectl %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// invalidNameChars are not allowed in Kubernetes object names
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// jobName derives a unique DNS-1123 name for a ticket's Job
+func jobName(ticketID string, now time.Time) string {
+	suffix := strconv.FormatInt(now.Unix(), 36)
+	base := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(ticketID), "-"), "-")
+
+	// Names are limited to 63 characters
+	if max := 63 - len("amp--") - len(suffix); len(base) > max {
+		base = strings.TrimRight(base[:max], "-")
+	}
+	if base == "" {
+		return "amp-" + suffix
+	}
+	return "amp-" + base + "-" + suffix
+}
+
+// invalidLabelChars are not allowed in label values
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// labelValue makes a ticket ID usable as a label value
+func labelValue(value string) string {
+	value = invalidLabelChars.ReplaceAllString(value, "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "-._")
+}
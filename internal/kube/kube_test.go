@@ -0,0 +1,220 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// fakeCluster answers kubectl calls with a Job that finishes after a few polls
+type fakeCluster struct {
+	mu        sync.Mutex
+	calls     [][]string
+	manifest  []byte
+	condition string // Complete or Failed
+	polls     int
+}
+
+func (f *fakeCluster) kubectl(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, args)
+
+	switch args[0] {
+	case "create":
+		f.manifest = stdin
+		return []byte("job.batch/created"), nil
+	case "get":
+		f.polls++
+		if f.polls < 2 || f.condition == "" {
+			return []byte(`{"status":{"active":1}}`), nil
+		}
+		return []byte(`{"status":{"conditions":[{"type":"` + f.condition + `","status":"True"}]}}`), nil
+	case "logs":
+		return []byte("cloning repository\nSUMMARY: Added the login page\n"), nil
+	case "delete":
+		return nil, nil
+	}
+	return nil, errors.New("unexpected kubectl call")
+}
+
+func (f *fakeCluster) called(verb string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, call := range f.calls {
+		if call[0] == verb {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestRunner(t *testing.T, cluster *fakeCluster) *Runner {
+	t.Helper()
+	runner, err := NewRunner(Config{
+		Image:        "registry.example.com/amp-agent:latest",
+		RepoURL:      "git://orchestrator.default.svc/repo.git",
+		Namespace:    "agents",
+		CPU:          "2",
+		Memory:       "4Gi",
+		SecretName:   "amp-credentials",
+		NodeSelector: map[string]string{"gpu": "true"},
+		TTLSeconds:   600,
+	}, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	runner.pollInterval = 10 * time.Millisecond
+	runner.kubectl = cluster.kubectl
+	return runner
+}
+
+func TestRunnerLaunchesJob(t *testing.T) {
+	cluster := &fakeCluster{condition: "Complete"}
+	runner := newTestRunner(t, cluster)
+
+	job := Job{
+		Ticket:   &ticket.Ticket{ID: "feat_Login.Page", Title: "Login page", Priority: 1},
+		Branch:   "agent-2/feat_Login.Page",
+		Prompt:   "Implement the login page",
+		WorkerID: 2,
+	}
+	result, err := runner.Run(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(string(result.Logs), "SUMMARY: Added the login page") {
+		t.Errorf("Expected job logs, got %q", result.Logs)
+	}
+	if !strings.HasPrefix(result.Name, "amp-feat-login-page-") {
+		t.Errorf("Unexpected job name %q", result.Name)
+	}
+
+	var manifest struct {
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			BackoffLimit          int `json:"backoffLimit"`
+			ActiveDeadlineSeconds int `json:"activeDeadlineSeconds"`
+			TTLSecondsAfterFinish int `json:"ttlSecondsAfterFinished"`
+			Template              struct {
+				Spec struct {
+					RestartPolicy string            `json:"restartPolicy"`
+					NodeSelector  map[string]string `json:"nodeSelector"`
+					Containers    []struct {
+						Image string `json:"image"`
+						Env   []struct {
+							Name  string `json:"name"`
+							Value string `json:"value"`
+						} `json:"env"`
+						EnvFrom []struct {
+							SecretRef struct {
+								Name string `json:"name"`
+							} `json:"secretRef"`
+						} `json:"envFrom"`
+						Resources struct {
+							Limits map[string]string `json:"limits"`
+						} `json:"resources"`
+					} `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(cluster.manifest, &manifest); err != nil {
+		t.Fatalf("Invalid manifest: %v", err)
+	}
+	if manifest.Metadata.Name != result.Name || manifest.Metadata.Namespace != "agents" {
+		t.Errorf("Unexpected metadata %+v", manifest.Metadata)
+	}
+	if manifest.Metadata.Labels["orchestrator/ticket"] != "feat_Login.Page" {
+		t.Errorf("Unexpected labels %v", manifest.Metadata.Labels)
+	}
+	spec := manifest.Spec
+	if spec.BackoffLimit != 0 || spec.ActiveDeadlineSeconds != 1800 || spec.TTLSecondsAfterFinish != 600 {
+		t.Errorf("Unexpected job spec %+v", spec)
+	}
+	pod := spec.Template.Spec
+	if pod.RestartPolicy != "Never" || pod.NodeSelector["gpu"] != "true" || len(pod.Containers) != 1 {
+		t.Fatalf("Unexpected pod spec %+v", pod)
+	}
+	container := pod.Containers[0]
+	if container.Image != "registry.example.com/amp-agent:latest" || container.Resources.Limits["memory"] != "4Gi" {
+		t.Errorf("Unexpected container %+v", container)
+	}
+	if len(container.EnvFrom) != 1 || container.EnvFrom[0].SecretRef.Name != "amp-credentials" {
+		t.Errorf("Expected the credentials secret, got %+v", container.EnvFrom)
+	}
+
+	env := make(map[string]string)
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+	if env[EnvBranch] != job.Branch || env[EnvPrompt] != job.Prompt || env[EnvRepoURL] != "git://orchestrator.default.svc/repo.git" {
+		t.Errorf("Unexpected environment %v", env)
+	}
+	var injected ticket.Ticket
+	if err := json.Unmarshal([]byte(env[EnvTicket]), &injected); err != nil || injected.ID != job.Ticket.ID {
+		t.Errorf("Expected the ticket payload, got %q (err %v)", env[EnvTicket], err)
+	}
+}
+
+func TestRunnerReportsFailedJob(t *testing.T) {
+	cluster := &fakeCluster{condition: "Failed"}
+	runner := newTestRunner(t, cluster)
+
+	result, err := runner.Run(context.Background(), Job{Ticket: &ticket.Ticket{ID: "feat-1"}, Branch: "agent-1/feat-1"})
+	if !errors.Is(err, ErrJobFailed) {
+		t.Fatalf("Expected ErrJobFailed, got %v", err)
+	}
+	if len(result.Logs) == 0 {
+		t.Error("Expected logs from the failed job")
+	}
+}
+
+func TestRunnerDeletesJobOnCancel(t *testing.T) {
+	cluster := &fakeCluster{} // Never finishes
+	runner := newTestRunner(t, cluster)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := runner.Run(ctx, Job{Ticket: &ticket.Ticket{ID: "feat-1"}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+	if !cluster.called("delete") {
+		t.Error("Expected the abandoned job to be deleted")
+	}
+}
+
+func TestJobName(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	if got := jobName("Feat/Login Page", now); got != "amp-feat-login-page-s44we8" {
+		t.Errorf("Unexpected name %q", got)
+	}
+	if got := jobName(strings.Repeat("x", 100), now); len(got) > 63 {
+		t.Errorf("Expected name within 63 characters, got %d", len(got))
+	}
+	if got := jobName("___", now); got != "amp-s44we8" {
+		t.Errorf("Unexpected name %q", got)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{}).Validate(); err == nil {
+		t.Error("Expected error without image and repo_url")
+	}
+	if err := (Config{Image: "agent", RepoURL: "git://repo", TTLSeconds: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative ttl_seconds")
+	}
+	if err := (Config{Image: "agent", RepoURL: "git://repo"}).Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cpuSlack allows for rusage reporting slightly less CPU time than the
+// kernel counted when it enforced RLIMIT_CPU
+const cpuSlack = 100 * time.Millisecond
+
+// ErrExceeded indicates a process was killed, or a ticket refused, for
+// exceeding a resource limit
+var ErrExceeded = errors.New("resource limit exceeded")
+
+// Limits caps the resources of the agent and CI processes a worker starts
+// Zero values mean unlimited
+type Limits struct {
+	Nice         int `mapstructure:"nice"`          // Scheduling niceness, 1-19
+	MemoryMB     int `mapstructure:"memory_mb"`     // Virtual memory per process (ulimit -v)
+	CPUSeconds   int `mapstructure:"cpu_seconds"`   // CPU time per process (ulimit -t)
+	MaxProcesses int `mapstructure:"max_processes"` // Processes for the user (ulimit -u)
+	DiskQuotaMB  int `mapstructure:"disk_quota_mb"` // Worker directory size checked before each ticket
+}
+
+// Validate checks that limits are in range
+func (l Limits) Validate() error {
+	if l.Nice < 0 || l.Nice > 19 {
+		return fmt.Errorf("nice must be between 0 and 19, got %d", l.Nice)
+	}
+	if l.MemoryMB < 0 || l.CPUSeconds < 0 || l.MaxProcesses < 0 || l.DiskQuotaMB < 0 {
+		return errors.New("limits cannot be negative")
+	}
+	return nil
+}
+
+// processLimited reports whether any per-process limit is set
+func (l Limits) processLimited() bool {
+	return l.Nice > 0 || l.MemoryMB > 0 || l.CPUSeconds > 0 || l.MaxProcesses > 0
+}
+
+// Apply rewrites an unstarted command to run under ulimit and nice
+// Commands are left untouched when no process limit is set
+func (l Limits) Apply(cmd *exec.Cmd) {
+	if !l.processLimited() || cmd.Err != nil {
+		return
+	}
+
+	// bash rather than sh: dash's ulimit has no -u
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		return
+	}
+
+	var script []string
+	if l.MemoryMB > 0 {
+		script = append(script, fmt.Sprintf("ulimit -v %d", l.MemoryMB*1024))
+	}
+	if l.CPUSeconds > 0 {
+		script = append(script, fmt.Sprintf("ulimit -t %d", l.CPUSeconds))
+	}
+	if l.MaxProcesses > 0 {
+		script = append(script, fmt.Sprintf("ulimit -u %d", l.MaxProcesses))
+	}
+	run := `exec "$0" "$@"`
+	if l.Nice > 0 {
+		run = fmt.Sprintf(`exec nice -n %d "$0" "$@"`, l.Nice)
+	}
+	script = append(script, run)
+
+	// The original program and arguments become $0 and $@
+	args := append([]string{"bash", "-c", strings.Join(script, " && "), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = bash
+	cmd.Args = args
+}
+
+// Check inspects a failed command and returns an error wrapping ErrExceeded
+// when the failure looks like one of the limits was hit, or nil otherwise.
+// A command whose ctx is done was cancelled or timed out, whatever killed it.
+func (l Limits) Check(ctx context.Context, err error, output []byte) error {
+	if err == nil || ctx.Err() != nil {
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if l.CPUSeconds > 0 && errors.As(err, &exitErr) && l.cpuKilled(exitErr) {
+		return fmt.Errorf("%w: killed after %d CPU seconds", ErrExceeded, l.CPUSeconds)
+	}
+
+	// ENOMEM's message; a program merely printing "out of memory" is not
+	// proof the limit was hit
+	text := strings.ToLower(string(output))
+	if l.MemoryMB > 0 && strings.Contains(text, "cannot allocate memory") {
+		return fmt.Errorf("%w: ran out of memory under the %d MB limit", ErrExceeded, l.MemoryMB)
+	}
+	if l.MaxProcesses > 0 && (strings.Contains(text, "resource temporarily unavailable") || strings.Contains(text, "fork: retry")) {
+		return fmt.Errorf("%w: could not start processes under the %d process limit", ErrExceeded, l.MaxProcesses)
+	}
+
+	return nil
+}
+
+// cpuKilled reports whether the process died of RLIMIT_CPU: SIGXCPU at the
+// soft limit, or SIGKILL at the hard limit once its CPU time reached it
+func (l Limits) cpuKilled(exitErr *exec.ExitError) bool {
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return false
+	}
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		return true
+	case syscall.SIGKILL:
+		return exitErr.UserTime()+exitErr.SystemTime() >= time.Duration(l.CPUSeconds)*time.Second-cpuSlack
+	}
+	return false
+}
+
+// CheckDisk returns an error wrapping ErrExceeded when dir uses more than
+// the disk quota
+func (l Limits) CheckDisk(dir string) error {
+	if l.DiskQuotaMB <= 0 {
+		return nil
+	}
+
+	size, err := DirSize(dir)
+	if err != nil {
+		return fmt.Errorf("failed to measure %s: %w", dir, err)
+	}
+
+	if usedMB := size / (1024 * 1024); usedMB > int64(l.DiskQuotaMB) {
+		return fmt.Errorf("%w: %s uses %d MB of its %d MB quota", ErrExceeded, dir, usedMB, l.DiskQuotaMB)
+	}
+	return nil
+}
+
+// DirSize returns the total size of regular files under dir
+// A missing directory has size zero
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
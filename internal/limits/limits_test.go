@@ -0,0 +1,134 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if err := (Limits{}).Validate(); err != nil {
+		t.Errorf("Expected zero limits to be valid, got %v", err)
+	}
+	if err := (Limits{Nice: 20}).Validate(); err == nil {
+		t.Error("Expected error for nice above 19, got nil")
+	}
+	if err := (Limits{MemoryMB: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative memory limit, got nil")
+	}
+}
+
+func TestApply(t *testing.T) {
+	// No limits leaves the command alone
+	cmd := exec.Command("echo", "hi")
+	path := cmd.Path
+	Limits{DiskQuotaMB: 10}.Apply(cmd)
+	if cmd.Path != path || len(cmd.Args) != 2 {
+		t.Errorf("Expected command untouched, got %s %v", cmd.Path, cmd.Args)
+	}
+
+	cmd = exec.Command("bash", "-c", `echo "$(ulimit -v) $(ulimit -t) $(nice) $1"`, "_", "arg with space")
+	Limits{Nice: 5, MemoryMB: 512, CPUSeconds: 30}.Apply(cmd)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Limited command failed: %v: %s", err, output)
+	}
+	if got, want := strings.TrimSpace(string(output)), "524288 30 5 arg with space"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCheck_CPUTime(t *testing.T) {
+	limits := Limits{CPUSeconds: 1}
+
+	cmd := exec.Command("bash", "-c", "while :; do :; done")
+	limits.Apply(cmd)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("Expected busy loop to be killed")
+	}
+
+	if checked := limits.Check(context.Background(), err, output); !errors.Is(checked, ErrExceeded) {
+		t.Errorf("Expected ErrExceeded, got %v", checked)
+	}
+}
+
+func TestCheck_KilledWithoutUsingCPU(t *testing.T) {
+	limits := Limits{CPUSeconds: 30}
+
+	// A timeout's SIGKILL long before the CPU limit is not the limit
+	cmd := exec.Command("bash", "-c", "kill -KILL $$")
+	limits.Apply(cmd)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("Expected the command to be killed")
+	}
+	if checked := limits.Check(context.Background(), err, output); checked != nil {
+		t.Errorf("Expected nil for a SIGKILL under the CPU limit, got %v", checked)
+	}
+
+	// Nor is anything that happens once the command's context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if checked := (Limits{MemoryMB: 256}).Check(ctx, errors.New("exit status 1"), []byte("cannot allocate memory")); checked != nil {
+		t.Errorf("Expected nil for a cancelled command, got %v", checked)
+	}
+}
+
+func TestCheck_Output(t *testing.T) {
+	failure := errors.New("exit status 1")
+	ctx := context.Background()
+
+	if err := (Limits{MemoryMB: 256}).Check(ctx, failure, []byte("bash: fork: Cannot allocate memory")); !errors.Is(err, ErrExceeded) {
+		t.Errorf("Expected ErrExceeded for ENOMEM, got %v", err)
+	}
+
+	// A program reporting its own out of memory condition is an ordinary failure
+	if err := (Limits{MemoryMB: 256}).Check(ctx, failure, []byte("test: cache out of memory, evicting")); err != nil {
+		t.Errorf("Expected nil for output merely mentioning memory, got %v", err)
+	}
+
+	// Without a memory limit ENOMEM is an ordinary failure
+	if err := (Limits{}).Check(ctx, failure, []byte("cannot allocate memory")); err != nil {
+		t.Errorf("Expected nil without a memory limit, got %v", err)
+	}
+
+	if err := (Limits{MaxProcesses: 10}).Check(ctx, failure, []byte("bash: fork: retry: Resource temporarily unavailable")); !errors.Is(err, ErrExceeded) {
+		t.Errorf("Expected ErrExceeded for process limit, got %v", err)
+	}
+
+	if err := (Limits{MemoryMB: 256}).Check(ctx, nil, []byte("cannot allocate memory")); err != nil {
+		t.Errorf("Expected nil for a successful command, got %v", err)
+	}
+}
+
+func TestCheckDisk(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	data := make([]byte, 2*1024*1024)
+	if err := os.WriteFile(filepath.Join(dir, "sub", "big"), data, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	size, err := DirSize(dir)
+	if err != nil || size != int64(len(data)) {
+		t.Errorf("Expected size %d, got %d (err %v)", len(data), size, err)
+	}
+
+	if err := (Limits{DiskQuotaMB: 1}).CheckDisk(dir); !errors.Is(err, ErrExceeded) {
+		t.Errorf("Expected ErrExceeded over quota, got %v", err)
+	}
+	if err := (Limits{DiskQuotaMB: 10}).CheckDisk(dir); err != nil {
+		t.Errorf("Expected nil under quota, got %v", err)
+	}
+	if err := (Limits{DiskQuotaMB: 1}).CheckDisk(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("Expected nil for missing directory, got %v", err)
+	}
+}
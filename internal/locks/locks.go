@@ -0,0 +1,166 @@
+// Package locks keeps tickets whose lock sets intersect from being worked on
+// at the same time
+package locks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// Event kinds a Manager publishes
+const (
+	Acquired = "acquired"
+	Released = "released"
+	Waiting  = "waiting"
+)
+
+// Event describes a ticket taking or giving up its locks, or held back by
+// locks another ticket holds
+type Event struct {
+	Kind   string // One of the Event kind constants
+	Ticket *ticket.Ticket
+	Locks  []string // The ticket's locks, or those in conflict when Waiting
+	HeldBy string   // The conflicting ticket when Waiting
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case Waiting:
+		return fmt.Sprintf("ticket %s waiting for %s held by %s", e.Ticket.ID, strings.Join(e.Locks, ", "), e.HeldBy)
+	case Acquired:
+		return fmt.Sprintf("ticket %s acquired %s", e.Ticket.ID, strings.Join(e.Locks, ", "))
+	default:
+		return fmt.Sprintf("ticket %s released %s", e.Ticket.ID, strings.Join(e.Locks, ", "))
+	}
+}
+
+// Manager tracks the locks held by tickets being worked on and holds back
+// tickets needing any of them at dispatch; it implements queue.Gate
+// A nil Manager holds nothing back
+type Manager struct {
+	publisher func(Event)
+
+	mu       sync.Mutex
+	holders  map[string]string   // Lock to the ID of the ticket holding it
+	held     map[string][]string // Ticket ID to the locks it holds
+	reported map[string]string   // Ticket ID to the holder it was last reported waiting on
+}
+
+// New creates a manager with no locks held
+func New() *Manager {
+	return &Manager{
+		holders:  make(map[string]string),
+		held:     make(map[string][]string),
+		reported: make(map[string]string),
+	}
+}
+
+// SetPublisher sets the function told when locks are acquired and released,
+// and the first time a ticket waits on each holder
+func (m *Manager) SetPublisher(publisher func(Event)) {
+	if m != nil {
+		m.publisher = publisher
+	}
+}
+
+// Allows reports whether none of the ticket's locks are held by another
+// ticket; tickets it holds back stay queued until the holder is released
+func (m *Manager) Allows(t *ticket.Ticket) bool {
+	if m == nil || len(t.Locks) == 0 {
+		return true
+	}
+
+	m.mu.Lock()
+	var conflicts []string
+	holder := ""
+	for _, lock := range normalize(t.Locks) {
+		if id, ok := m.holders[lock]; ok && id != t.ID {
+			if holder == "" {
+				holder = id
+			}
+			if id == holder {
+				conflicts = append(conflicts, lock)
+			}
+		}
+	}
+	first := holder != "" && m.reported[t.ID] != holder
+	if holder != "" {
+		m.reported[t.ID] = holder
+	}
+	m.mu.Unlock()
+
+	if holder == "" {
+		return true
+	}
+	if first {
+		m.publish(Event{Kind: Waiting, Ticket: t, Locks: conflicts, HeldBy: holder})
+	}
+	return false
+}
+
+// Start takes the locks of a ticket Allows accepted
+func (m *Manager) Start(t *ticket.Ticket) {
+	if m == nil || len(t.Locks) == 0 {
+		return
+	}
+
+	locks := normalize(t.Locks)
+	m.mu.Lock()
+	for _, lock := range locks {
+		m.holders[lock] = t.ID
+	}
+	m.held[t.ID] = locks
+	delete(m.reported, t.ID)
+	m.mu.Unlock()
+
+	m.publish(Event{Kind: Acquired, Ticket: t, Locks: locks})
+}
+
+// Release gives up a ticket's locks, whether it completed, failed or went
+// back on the queue; releasing a ticket twice is harmless
+func (m *Manager) Release(t *ticket.Ticket) {
+	if m == nil || t == nil {
+		return
+	}
+
+	m.mu.Lock()
+	locks, ok := m.held[t.ID]
+	for _, lock := range locks {
+		if m.holders[lock] == t.ID {
+			delete(m.holders, lock)
+		}
+	}
+	delete(m.held, t.ID)
+	delete(m.reported, t.ID)
+	m.mu.Unlock()
+
+	if ok {
+		m.publish(Event{Kind: Released, Ticket: t, Locks: locks})
+	}
+}
+
+func (m *Manager) publish(event Event) {
+	if m.publisher != nil {
+		m.publisher(event)
+	}
+}
+
+// normalize lowercases and sorts lock names, dropping blanks and duplicates
+func normalize(locks []string) []string {
+	seen := make(map[string]bool, len(locks))
+	result := make([]string, 0, len(locks))
+	for _, lock := range locks {
+		lock = strings.ToLower(strings.TrimSpace(lock))
+		if lock == "" || seen[lock] {
+			continue
+		}
+		seen[lock] = true
+		result = append(result, lock)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,71 @@
+package locks
+
+import (
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestManager(t *testing.T) {
+	m := New()
+	var events []Event
+	m.SetPublisher(func(e Event) { events = append(events, e) })
+
+	schema := &ticket.Ticket{ID: "migrate", Locks: []string{"DB-Schema", "api"}}
+	other := &ticket.Ticket{ID: "add-column", Locks: []string{"db-schema"}}
+	free := &ticket.Ticket{ID: "docs", Locks: []string{"readme"}}
+
+	if !m.Allows(schema) {
+		t.Fatal("Expected a ticket to be allowed while nothing is locked")
+	}
+	m.Start(schema)
+
+	if m.Allows(other) {
+		t.Error("Expected a ticket sharing a lock to be held back")
+	}
+	if m.Allows(other) {
+		t.Error("Expected the ticket to stay held back")
+	}
+	if !m.Allows(free) {
+		t.Error("Expected a ticket with disjoint locks to be allowed")
+	}
+	if !m.Allows(&ticket.Ticket{ID: "unlocked"}) {
+		t.Error("Expected a ticket without locks to be allowed")
+	}
+
+	m.Release(schema)
+	m.Release(schema)
+	if !m.Allows(other) {
+		t.Error("Expected the ticket to be allowed once the lock was released")
+	}
+
+	want := []struct {
+		kind, ticket string
+		locks        int
+	}{
+		{Acquired, "migrate", 2},
+		{Waiting, "add-column", 1},
+		{Released, "migrate", 2},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %v", len(want), events)
+	}
+	for i, w := range want {
+		if events[i].Kind != w.kind || events[i].Ticket.ID != w.ticket || len(events[i].Locks) != w.locks {
+			t.Errorf("Event %d: expected %s %s with %d locks, got %v", i, w.kind, w.ticket, w.locks, events[i])
+		}
+	}
+	if events[1].HeldBy != "migrate" || events[1].Locks[0] != "db-schema" {
+		t.Errorf("Expected add-column waiting on db-schema held by migrate, got %v", events[1])
+	}
+}
+
+func TestNilManagerHoldsNothingBack(t *testing.T) {
+	var m *Manager
+	tk := &ticket.Ticket{ID: "migrate", Locks: []string{"db"}}
+	m.Start(tk)
+	if !m.Allows(tk) {
+		t.Error("Expected a nil manager to allow every ticket")
+	}
+	m.Release(tk)
+}
@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// fieldChecks report whether a ticket field is set, keyed by YAML name
+var fieldChecks = map[string]func(*ticket.Ticket) bool{
+	"description":       func(t *ticket.Ticket) bool { return t.Description != "" },
+	"estimate_min":      func(t *ticket.Ticket) bool { return t.EstimateMin > 0 },
+	"locks":             func(t *ticket.Ticket) bool { return len(t.Locks) > 0 },
+	"dependencies":      func(t *ticket.Ticket) bool { return len(t.Dependencies) > 0 },
+	"tags":              func(t *ticket.Ticket) bool { return len(t.Tags) > 0 },
+	"context_group":     func(t *ticket.Ticket) bool { return t.ContextGroup != "" },
+	"type":              func(t *ticket.Ticket) bool { return t.Type != "" },
+	"reproduce":         func(t *ticket.Ticket) bool { return t.Reproduce != "" },
+	"requires_approval": func(t *ticket.Ticket) bool { return t.RequiresApproval },
+}
+
+// Condition selects the tickets a rule applies to
+// Empty fields match every ticket
+type Condition struct {
+	Priority int      `mapstructure:"priority" yaml:"priority,omitempty"`
+	Tags     []string `mapstructure:"tags" yaml:"tags,omitempty"` // Matches tickets with any of these tags
+}
+
+// Rule requires fields to be set on tickets matching its condition
+type Rule struct {
+	Name    string    `mapstructure:"name" yaml:"name"`
+	When    Condition `mapstructure:"when" yaml:"when,omitempty"`
+	Require []string  `mapstructure:"require" yaml:"require"`
+	Message string    `mapstructure:"message" yaml:"message,omitempty"` // Optional explanation shown on violation
+}
+
+// Policy is a set of rules checked whenever a ticket is loaded
+type Policy struct {
+	Rules        []Rule   `mapstructure:"rules" yaml:"rules,omitempty"`
+	AllowedLocks []string `mapstructure:"allowed_locks" yaml:"allowed_locks,omitempty"` // Empty allows any lock
+}
+
+// Violation describes a single broken rule
+type Violation struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Violations is returned by Check when a ticket breaks the policy
+type Violations []Violation
+
+// Error joins the violations into one line
+func (v Violations) Error() string {
+	parts := make([]string, len(v))
+	for i, violation := range v {
+		parts[i] = fmt.Sprintf("%s: %s", violation.Rule, violation.Message)
+	}
+	return "policy violation: " + strings.Join(parts, "; ")
+}
+
+// Validate checks that every rule refers to a known field
+func (p *Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("policy rule %d has no name", i+1)
+		}
+		if len(rule.Require) == 0 {
+			return fmt.Errorf("policy rule %s requires no fields", rule.Name)
+		}
+		for _, field := range rule.Require {
+			if _, ok := fieldChecks[field]; !ok {
+				return fmt.Errorf("policy rule %s requires unknown field %q (known: %s)",
+					rule.Name, field, strings.Join(knownFields(), ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Check evaluates the policy against a ticket
+// It returns nil when the ticket complies, otherwise Violations
+func (p *Policy) Check(t *ticket.Ticket) error {
+	if p == nil {
+		return nil
+	}
+
+	var violations Violations
+
+	for _, rule := range p.Rules {
+		if !rule.When.matches(t) {
+			continue
+		}
+		for _, field := range rule.Require {
+			check, ok := fieldChecks[field]
+			if !ok || check(t) {
+				continue
+			}
+
+			message := rule.Message
+			if message == "" {
+				message = fmt.Sprintf("%s is required", field)
+			}
+			violations = append(violations, Violation{Rule: rule.Name, Field: field, Message: message})
+		}
+	}
+
+	if len(p.AllowedLocks) > 0 {
+		allowed := make(map[string]bool, len(p.AllowedLocks))
+		for _, lock := range p.AllowedLocks {
+			allowed[lock] = true
+		}
+		for _, lock := range t.Locks {
+			if !allowed[lock] {
+				violations = append(violations, Violation{
+					Rule:    "allowed_locks",
+					Field:   "locks",
+					Message: fmt.Sprintf("lock %q is not in the allowlist", lock),
+				})
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return violations
+}
+
+// matches reports whether the condition selects the ticket
+func (c Condition) matches(t *ticket.Ticket) bool {
+	if c.Priority != 0 && t.Priority != c.Priority {
+		return false
+	}
+
+	if len(c.Tags) == 0 {
+		return true
+	}
+	for _, want := range c.Tags {
+		for _, tag := range t.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// knownFields lists the fields rules may require
+func knownFields() []string {
+	fields := make([]string, 0, len(fieldChecks))
+	for field := range fieldChecks {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
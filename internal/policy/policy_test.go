@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func testPolicy() *Policy {
+	return &Policy{
+		Rules: []Rule{
+			{Name: "p1-needs-estimate", When: Condition{Priority: 1}, Require: []string{"estimate_min"}},
+			{
+				Name:    "prod-deploy-approval",
+				When:    Condition{Tags: []string{"prod-deploy"}},
+				Require: []string{"requires_approval"},
+				Message: "prod deploys need approval",
+			},
+		},
+		AllowedLocks: []string{"database", "user-auth"},
+	}
+}
+
+func TestCheckCompliantTicket(t *testing.T) {
+	tk := &ticket.Ticket{
+		ID:               "ok",
+		Priority:         1,
+		EstimateMin:      30,
+		Tags:             []string{"prod-deploy"},
+		RequiresApproval: true,
+		Locks:            []string{"database"},
+	}
+
+	if err := testPolicy().Check(tk); err != nil {
+		t.Errorf("Expected ticket to comply, got %v", err)
+	}
+}
+
+func TestCheckViolations(t *testing.T) {
+	tk := &ticket.Ticket{
+		ID:       "bad",
+		Priority: 1,
+		Tags:     []string{"prod-deploy"},
+		Locks:    []string{"billing"},
+	}
+
+	err := testPolicy().Check(tk)
+	var violations Violations
+	if !errors.As(err, &violations) {
+		t.Fatalf("Expected Violations, got %v", err)
+	}
+
+	if len(violations) != 3 {
+		t.Fatalf("Expected 3 violations, got %d: %v", len(violations), violations)
+	}
+
+	want := []Violation{
+		{Rule: "p1-needs-estimate", Field: "estimate_min", Message: "estimate_min is required"},
+		{Rule: "prod-deploy-approval", Field: "requires_approval", Message: "prod deploys need approval"},
+		{Rule: "allowed_locks", Field: "locks", Message: `lock "billing" is not in the allowlist`},
+	}
+	for i, v := range violations {
+		if v != want[i] {
+			t.Errorf("Violation %d: expected %+v, got %+v", i, want[i], v)
+		}
+	}
+
+	if !strings.Contains(err.Error(), "p1-needs-estimate: estimate_min is required") {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
+
+func TestConditionOnlyAppliesToMatchingTickets(t *testing.T) {
+	tk := &ticket.Ticket{ID: "p3", Priority: 3}
+	if err := testPolicy().Check(tk); err != nil {
+		t.Errorf("Expected rules not to apply to priority 3 ticket, got %v", err)
+	}
+
+	var nilPolicy *Policy
+	if err := nilPolicy.Check(tk); err != nil {
+		t.Errorf("Expected nil policy to allow everything, got %v", err)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := testPolicy().Validate(); err != nil {
+		t.Errorf("Expected valid policy, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"missing name", Rule{Require: []string{"estimate_min"}}},
+		{"no fields", Rule{Name: "empty"}},
+		{"unknown field", Rule{Name: "typo", Require: []string{"estimate"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Policy{Rules: []Rule{tt.rule}}
+			if err := p.Validate(); err == nil {
+				t.Error("Expected validation error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// RetryBackoff is a gate holding requeued tickets back until their
+// RetryAfter time has passed
+func RetryBackoff() Gate {
+	return retryBackoff{now: time.Now}
+}
+
+type retryBackoff struct {
+	now func() time.Time
+}
+
+func (b retryBackoff) Allows(t *ticket.Ticket) bool {
+	return !b.now().Before(t.RetryAfter)
+}
+
+func (b retryBackoff) Start(t *ticket.Ticket) {}
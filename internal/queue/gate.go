@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// Gate holds tickets back at dispatch, e.g. under a per-team quota
+type Gate interface {
+	Allows(t *ticket.Ticket) bool // Whether t may start now
+	Start(t *ticket.Ticket)       // Counts t as started once it is popped
+}
+
+// Gates combines gates: a ticket may start only if every gate allows it,
+// and is counted as started by each
+func Gates(gates ...Gate) Gate {
+	return multiGate(gates)
+}
+
+type multiGate []Gate
+
+func (g multiGate) Allows(t *ticket.Ticket) bool {
+	for _, gate := range g {
+		if !gate.Allows(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g multiGate) Start(t *ticket.Ticket) {
+	for _, gate := range g {
+		gate.Start(t)
+	}
+}
+
+// SetGate sets the gate Pop and PopFor consult; tickets it holds back are
+// passed over for less urgent ones
+func (q *Queue) SetGate(g Gate) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.gate = g
+}
+
+// popGated removes and returns the most urgent ticket the gate allows, if
+// slots has room for it; the caller holds mu
+func (q *Queue) popGated(slots *Slots) *ticket.Ticket {
+	candidates := make(ticketHeap, len(*q.heap))
+	copy(candidates, *q.heap)
+	sort.Sort(candidates)
+
+	for _, t := range candidates {
+		if !q.gate.Allows(t) {
+			continue
+		}
+		if slots != nil && !slots.acquire(t.Priority) {
+			return nil
+		}
+		for i, queued := range *q.heap {
+			if queued == t {
+				heap.Remove(q.heap, i)
+				break
+			}
+		}
+		q.gate.Start(t)
+		return t
+	}
+	return nil
+}
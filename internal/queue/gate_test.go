@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// blockGate holds back the tickets it names and records those started
+type blockGate struct {
+	blocked map[string]bool
+	started []string
+}
+
+func (g *blockGate) Allows(t *ticket.Ticket) bool { return !g.blocked[t.ID] }
+func (g *blockGate) Start(t *ticket.Ticket)       { g.started = append(g.started, t.ID) }
+
+func TestPopSkipsGatedTickets(t *testing.T) {
+	q := New()
+	now := time.Now()
+	q.Push(&ticket.Ticket{ID: "urgent", Priority: 1, CreatedAt: now})
+	q.Push(&ticket.Ticket{ID: "older", Priority: 3, CreatedAt: now.Add(-time.Hour)})
+	q.Push(&ticket.Ticket{ID: "newer", Priority: 3, CreatedAt: now})
+
+	gate := &blockGate{blocked: map[string]bool{"urgent": true}}
+	q.SetGate(gate)
+
+	if got := q.Pop(); got == nil || got.ID != "older" {
+		t.Fatalf("Expected the most urgent allowed ticket, got %v", got)
+	}
+	if got := q.PopFor(NewSlots(2, nil)); got == nil || got.ID != "newer" {
+		t.Fatalf("Expected the next allowed ticket, got %v", got)
+	}
+	if got := q.Pop(); got != nil {
+		t.Fatalf("Expected nothing while the remaining ticket is held back, got %s", got.ID)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Expected the held back ticket to stay queued, got %d tickets", q.Len())
+	}
+	if len(gate.started) != 2 || gate.started[0] != "older" || gate.started[1] != "newer" {
+		t.Errorf("Expected the gate to be told of both started tickets, got %v", gate.started)
+	}
+}
+
+func TestPopForGatedRespectsReservations(t *testing.T) {
+	q := New()
+	q.Push(&ticket.Ticket{ID: "urgent", Priority: 1, CreatedAt: time.Now()})
+	q.Push(&ticket.Ticket{ID: "low", Priority: 4, CreatedAt: time.Now()})
+	q.SetGate(&blockGate{blocked: map[string]bool{"urgent": true}})
+
+	// Two workers, one of them reserved for priority 1 and the other busy
+	slots := NewSlots(2, []Reservation{{Priority: 1, Workers: 1}})
+	slots.busy = 1
+	if got := q.PopFor(slots); got != nil {
+		t.Fatalf("Expected the reserved slot to be held, got %s", got.ID)
+	}
+	if q.Len() != 2 {
+		t.Errorf("Expected both tickets to stay queued, got %d", q.Len())
+	}
+}
+
+func TestGatesRequireEveryGate(t *testing.T) {
+	q := New()
+	now := time.Now()
+	q.Push(&ticket.Ticket{ID: "first", Priority: 2, CreatedAt: now.Add(-time.Hour)})
+	q.Push(&ticket.Ticket{ID: "second", Priority: 2, CreatedAt: now})
+	q.Push(&ticket.Ticket{ID: "third", Priority: 2, CreatedAt: now.Add(time.Hour)})
+
+	quota := &blockGate{blocked: map[string]bool{"first": true}}
+	locks := &blockGate{blocked: map[string]bool{"second": true}}
+	q.SetGate(Gates(quota, locks))
+
+	if got := q.Pop(); got == nil || got.ID != "third" {
+		t.Fatalf("Expected the only ticket both gates allow, got %v", got)
+	}
+	if len(quota.started) != 1 || len(locks.started) != 1 {
+		t.Errorf("Expected each gate to count the ticket started, got %v and %v", quota.started, locks.started)
+	}
+}
+
+func TestRetryBackoffHoldsTicketsUntilRetryAfter(t *testing.T) {
+	now := time.Now()
+	gate := retryBackoff{now: func() time.Time { return now }}
+
+	if !gate.Allows(&ticket.Ticket{ID: "fresh"}) {
+		t.Error("Expected a ticket never retried to be allowed")
+	}
+	if gate.Allows(&ticket.Ticket{ID: "waiting", RetryAfter: now.Add(time.Minute)}) {
+		t.Error("Expected a ticket to be held back until its retry time")
+	}
+	if !gate.Allows(&ticket.Ticket{ID: "due", RetryAfter: now.Add(-time.Second)}) {
+		t.Error("Expected a ticket to be allowed once its retry time has passed")
+	}
+}
@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// Orders a listing can be sorted in
+const (
+	SortAge      = "age"      // Oldest first
+	SortPriority = "priority" // Most urgent first
+	SortEstimate = "estimate" // Shortest first; tickets without an estimate last
+)
+
+// Listing is a queued or in-flight ticket as shown by orchestrator list
+type Listing struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Priority     int       `json:"priority"`
+	EnqueuedAt   time.Time `json:"enqueued_at"`
+	EstimateMin  int       `json:"estimate_min,omitempty"`
+	Locks        []string  `json:"locks,omitempty"`
+	Dependencies []string  `json:"dependencies,omitempty"`
+	WorkerID     int       `json:"worker_id,omitempty"` // 0 while queued
+	Phase        string    `json:"phase,omitempty"`
+	RetryAfter   time.Time `json:"retry_after,omitempty"` // Set while a ticket requeued for retry waits out its backoff
+}
+
+// NewListing describes a ticket, dating it from when it was enqueued
+func NewListing(t *ticket.Ticket) Listing {
+	enqueuedAt := t.CreatedAt
+	if t.Provenance != nil && !t.Provenance.EnqueuedAt.IsZero() {
+		enqueuedAt = t.Provenance.EnqueuedAt
+	}
+	return Listing{
+		ID:           t.ID,
+		Title:        t.Title,
+		Priority:     t.Priority,
+		EnqueuedAt:   enqueuedAt,
+		EstimateMin:  t.EstimateMin,
+		Locks:        t.Locks,
+		Dependencies: t.Dependencies,
+		RetryAfter:   t.RetryAfter,
+	}
+}
+
+// SortListings orders listings by age, priority or estimate, breaking ties
+// by priority, then age, then ID
+func SortListings(listings []Listing, by string) error {
+	var less func(a, b Listing) (bool, bool)
+	switch by {
+	case SortAge:
+		less = func(a, b Listing) (bool, bool) {
+			return a.EnqueuedAt.Before(b.EnqueuedAt), a.EnqueuedAt.Equal(b.EnqueuedAt)
+		}
+	case SortPriority:
+		less = func(a, b Listing) (bool, bool) { return a.Priority < b.Priority, a.Priority == b.Priority }
+	case SortEstimate:
+		less = func(a, b Listing) (bool, bool) {
+			if (a.EstimateMin == 0) != (b.EstimateMin == 0) {
+				return b.EstimateMin == 0, false
+			}
+			return a.EstimateMin < b.EstimateMin, a.EstimateMin == b.EstimateMin
+		}
+	default:
+		return fmt.Errorf("unknown sort %q (expected %s, %s or %s)", by, SortAge, SortPriority, SortEstimate)
+	}
+
+	sort.SliceStable(listings, func(i, j int) bool {
+		a, b := listings[i], listings[j]
+		if before, tied := less(a, b); !tied {
+			return before
+		}
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if !a.EnqueuedAt.Equal(b.EnqueuedAt) {
+			return a.EnqueuedAt.Before(b.EnqueuedAt)
+		}
+		return a.ID < b.ID
+	})
+	return nil
+}
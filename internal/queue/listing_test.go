@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestNewListing(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	enqueued := created.Add(time.Hour)
+	tk := &ticket.Ticket{ID: "feat-a", Title: "A", Priority: 2, EstimateMin: 30, Locks: []string{"db"}, CreatedAt: created}
+
+	if l := NewListing(tk); !l.EnqueuedAt.Equal(created) || l.EstimateMin != 30 || l.Locks[0] != "db" {
+		t.Errorf("Unexpected listing %+v", l)
+	}
+	tk.Provenance = &ticket.Provenance{EnqueuedAt: enqueued}
+	if l := NewListing(tk); !l.EnqueuedAt.Equal(enqueued) {
+		t.Errorf("Expected the provenance's enqueue time, got %v", l.EnqueuedAt)
+	}
+}
+
+func TestSortListings(t *testing.T) {
+	now := time.Now()
+	listings := func() []Listing {
+		return []Listing{
+			{ID: "a", Priority: 3, EnqueuedAt: now.Add(-3 * time.Hour), EstimateMin: 60},
+			{ID: "b", Priority: 1, EnqueuedAt: now.Add(-time.Hour)},
+			{ID: "c", Priority: 2, EnqueuedAt: now.Add(-2 * time.Hour), EstimateMin: 15},
+			{ID: "d", Priority: 1, EnqueuedAt: now.Add(-4 * time.Hour), EstimateMin: 60},
+		}
+	}
+
+	tests := []struct {
+		by   string
+		want string
+	}{
+		{SortAge, "dacb"},
+		{SortPriority, "dbca"},
+		{SortEstimate, "cdab"},
+	}
+	for _, tt := range tests {
+		l := listings()
+		if err := SortListings(l, tt.by); err != nil {
+			t.Fatalf("SortListings(%s) failed: %v", tt.by, err)
+		}
+		var got strings.Builder
+		for _, item := range l {
+			got.WriteString(item.ID)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Sort by %s: expected %s, got %s", tt.by, tt.want, got.String())
+		}
+	}
+
+	if err := SortListings(listings(), "size"); err == nil {
+		t.Error("Expected error for an unknown sort")
+	}
+}
@@ -11,6 +11,7 @@ import (
 // Queue represents a thread-safe priority queue for tickets
 type Queue struct {
 	heap *ticketHeap
+	gate Gate // Optional; holds tickets back at dispatch
 	mu   sync.RWMutex
 }
 
@@ -39,6 +40,9 @@ func (q *Queue) Pop() *ticket.Ticket {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	
+	if q.gate != nil {
+		return q.popGated(nil)
+	}
 	if q.heap.Len() == 0 {
 		return nil
 	}
@@ -81,6 +85,12 @@ func (q *Queue) List() []*ticket.Ticket {
 // Remove removes a ticket with the given ID from the queue
 // Returns true if the ticket was found and removed
 func (q *Queue) Remove(ticketID string) bool {
+	return q.Take(ticketID) != nil
+}
+
+// Take removes the ticket with the given ID from the queue and returns it
+// Returns nil if no such ticket is queued
+func (q *Queue) Take(ticketID string) *ticket.Ticket {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	
@@ -89,11 +99,11 @@ func (q *Queue) Remove(ticketID string) bool {
 		if t.ID == ticketID {
 			// Remove the item at index i
 			heap.Remove(q.heap, i)
-			return true
+			return t
 		}
 	}
 	
-	return false
+	return nil
 }
 
 // Clear removes all tickets from the queue
@@ -0,0 +1,119 @@
+package queue
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// Reservation keeps worker slots free for urgent tickets
+type Reservation struct {
+	Priority int `mapstructure:"priority"` // Tickets at this priority or more urgent may use the slots
+	Workers  int `mapstructure:"workers"`
+}
+
+// ValidateReservations checks reservations against the number of workers,
+// which must include at least one slot any ticket can use
+func ValidateReservations(reservations []Reservation, workers int) error {
+	reserved := 0
+	for _, r := range reservations {
+		if r.Priority < 1 || r.Priority > 4 {
+			return fmt.Errorf("reservation priority %d must be between 1 and 4", r.Priority)
+		}
+		if r.Workers < 1 {
+			return fmt.Errorf("reservation for priority %d must reserve at least one worker", r.Priority)
+		}
+		reserved += r.Workers
+	}
+	if len(reservations) > 0 && reserved >= workers {
+		return errors.New("reservations must leave at least one worker for tickets of any priority")
+	}
+	return nil
+}
+
+// Slots tracks how many of a pool's workers are busy, holding reserved
+// slots back from less urgent tickets
+// A nil Slots never holds anything back
+type Slots struct {
+	size         int
+	busy         int
+	reservations []Reservation
+	mu           sync.Mutex
+}
+
+// NewSlots creates slots for a pool of size workers
+func NewSlots(size int, reservations []Reservation) *Slots {
+	return &Slots{size: size, reservations: reservations}
+}
+
+// SetSize changes how many workers the pool has
+func (s *Slots) SetSize(size int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.size = size
+}
+
+// Busy returns how many slots are taken
+func (s *Slots) Busy() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.busy
+}
+
+// Release frees a slot taken by PopFor
+func (s *Slots) Release() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy > 0 {
+		s.busy--
+	}
+}
+
+// acquire takes a slot if one is free for the priority once the slots
+// reserved for more urgent tickets are set aside
+func (s *Slots) acquire(priority int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	need := 1
+	for _, r := range s.reservations {
+		if r.Priority < priority {
+			need += r.Workers
+		}
+	}
+	if s.size-s.busy < need {
+		return false
+	}
+	s.busy++
+	return true
+}
+
+// PopFor removes and returns the highest priority ticket if slots has room
+// for it, taking a slot that the caller must Release when done
+// Less urgent tickets need even more room, so nil means nothing can run yet
+func (q *Queue) PopFor(slots *Slots) *ticket.Ticket {
+	if slots == nil {
+		return q.Pop()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.gate != nil {
+		return q.popGated(slots)
+	}
+	next := q.heap.peek()
+	if next == nil || !slots.acquire(next.Priority) {
+		return nil
+	}
+	return heap.Pop(q.heap).(*ticket.Ticket)
+}
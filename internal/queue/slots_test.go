@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestPopForHoldsReservedSlots(t *testing.T) {
+	q := New()
+	for i, priority := range []int{4, 4, 4} {
+		q.Push(&ticket.Ticket{ID: "low-" + string(rune('a'+i)), Priority: priority, CreatedAt: time.Now()})
+	}
+
+	// Three workers, one kept for priority 1 and one for priority 2 or better
+	slots := NewSlots(3, []Reservation{{Priority: 1, Workers: 1}, {Priority: 2, Workers: 1}})
+
+	if got := q.PopFor(slots); got == nil || got.Priority != 4 {
+		t.Fatalf("Expected the first low priority ticket to run, got %v", got)
+	}
+	if got := q.PopFor(slots); got != nil {
+		t.Fatalf("Expected the remaining slots to be held for urgent tickets, got %s", got.ID)
+	}
+
+	q.Push(&ticket.Ticket{ID: "p2", Priority: 2, CreatedAt: time.Now()})
+	if got := q.PopFor(slots); got == nil || got.ID != "p2" {
+		t.Fatalf("Expected the priority 2 ticket to use its reserved slot, got %v", got)
+	}
+
+	q.Push(&ticket.Ticket{ID: "p2-again", Priority: 2, CreatedAt: time.Now()})
+	if got := q.PopFor(slots); got != nil {
+		t.Fatalf("Expected the last slot to be held for priority 1, got %s", got.ID)
+	}
+	q.Remove("p2-again")
+
+	q.Push(&ticket.Ticket{ID: "p1", Priority: 1, CreatedAt: time.Now()})
+	if got := q.PopFor(slots); got == nil || got.ID != "p1" {
+		t.Fatalf("Expected the priority 1 ticket to run, got %v", got)
+	}
+	if slots.Busy() != 3 || q.Len() != 2 {
+		t.Errorf("Expected 3 busy slots and 2 queued tickets, got %d and %d", slots.Busy(), q.Len())
+	}
+
+	// Freeing the urgent slots doesn't let low priority work into them
+	slots.Release()
+	slots.Release()
+	if got := q.PopFor(slots); got != nil {
+		t.Errorf("Expected low priority tickets to wait, got %s", got.ID)
+	}
+	slots.Release()
+	if got := q.PopFor(slots); got == nil {
+		t.Error("Expected a low priority ticket to run once the pool is idle")
+	}
+}
+
+func TestPopForWithoutSlots(t *testing.T) {
+	q := New()
+	q.Push(&ticket.Ticket{ID: "feat-1", Priority: 5, CreatedAt: time.Now()})
+	if got := q.PopFor(nil); got == nil || got.ID != "feat-1" {
+		t.Errorf("Expected nil slots to behave like Pop, got %v", got)
+	}
+}
+
+func TestValidateReservations(t *testing.T) {
+	tests := []struct {
+		reservations []Reservation
+		workers      int
+		valid        bool
+	}{
+		{nil, 1, true},
+		{[]Reservation{{Priority: 1, Workers: 1}}, 2, true},
+		{[]Reservation{{Priority: 1, Workers: 1}, {Priority: 2, Workers: 1}}, 2, false},
+		{[]Reservation{{Priority: 5, Workers: 1}}, 3, false},
+		{[]Reservation{{Priority: 1, Workers: 0}}, 3, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateReservations(tt.reservations, tt.workers); (err == nil) != tt.valid {
+			t.Errorf("ValidateReservations(%v, %d) = %v, want valid %v", tt.reservations, tt.workers, err, tt.valid)
+		}
+	}
+}
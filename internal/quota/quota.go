@@ -0,0 +1,244 @@
+// Package quota caps how much of a shared orchestrator the tickets of one
+// tag, such as a team's, may take
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// ErrExceeded is wrapped by errors for tickets held back by a quota
+var ErrExceeded = errors.New("quota exceeded")
+
+// Limits a quota can set
+const (
+	LimitQueued      = "max_queued"
+	LimitInFlight    = "max_in_flight"
+	LimitInvocations = "max_daily_invocations"
+)
+
+// Quota limits the tickets carrying Tag; zero leaves a limit off
+type Quota struct {
+	Tag                 string `mapstructure:"tag"`                   // e.g. team-payments; matched case-insensitively
+	MaxQueued           int    `mapstructure:"max_queued"`            // Tickets waiting in the queue; more stay in the backlog
+	MaxInFlight         int    `mapstructure:"max_in_flight"`         // Tickets being worked on at once
+	MaxDailyInvocations int    `mapstructure:"max_daily_invocations"` // Agent runs started per UTC day
+}
+
+// Validate checks each quota names a tag once and sets no negative limit
+func Validate(quotas []Quota) error {
+	seen := make(map[string]bool)
+	for _, q := range quotas {
+		tag := strings.ToLower(q.Tag)
+		if tag == "" {
+			return errors.New("quota tag is required")
+		}
+		if seen[tag] {
+			return fmt.Errorf("duplicate quota for tag %q", q.Tag)
+		}
+		seen[tag] = true
+		if q.MaxQueued < 0 || q.MaxInFlight < 0 || q.MaxDailyInvocations < 0 {
+			return fmt.Errorf("quota for tag %q has a negative limit", q.Tag)
+		}
+	}
+	return nil
+}
+
+// Exceeded describes a ticket held back by a quota
+type Exceeded struct {
+	Ticket  *ticket.Ticket
+	Tag     string
+	Limit   string // One of the Limit constants
+	Max     int
+	Current int
+}
+
+func (e Exceeded) Error() string {
+	return fmt.Sprintf("%v: tag %s is at %s %d", ErrExceeded, e.Tag, e.Limit, e.Max)
+}
+
+func (e Exceeded) Unwrap() error {
+	return ErrExceeded
+}
+
+// Enforcer applies quotas when tickets are enqueued and dispatched
+// A nil Enforcer holds nothing back
+type Enforcer struct {
+	quotas    []Quota
+	publisher func(Exceeded)
+	now       func() time.Time
+
+	mu          sync.Mutex
+	inFlight    map[string]map[string]bool // Tag to the IDs of its tickets being worked on
+	day         string                     // UTC date invocations are counted for
+	invocations map[string]int             // Tag to agent runs started on day
+	reported    map[string]bool            // Ticket ID and limit already published
+}
+
+// New returns an enforcer for quotas, or nil when there are none
+func New(quotas []Quota) *Enforcer {
+	if len(quotas) == 0 {
+		return nil
+	}
+	return &Enforcer{
+		quotas:      quotas,
+		now:         time.Now,
+		inFlight:    make(map[string]map[string]bool),
+		invocations: make(map[string]int),
+		reported:    make(map[string]bool),
+	}
+}
+
+// SetPublisher sets the function told the first time each ticket is held
+// back by each limit
+func (e *Enforcer) SetPublisher(publisher func(Exceeded)) {
+	if e != nil {
+		e.publisher = publisher
+	}
+}
+
+// Admit checks a ticket about to be enqueued against max_queued, given the
+// tickets already waiting; an error leaves it in the backlog for later
+func (e *Enforcer) Admit(t *ticket.Ticket, queued []*ticket.Ticket) error {
+	if e == nil {
+		return nil
+	}
+	for _, q := range e.matching(t) {
+		if q.MaxQueued == 0 {
+			continue
+		}
+		count := 0
+		for _, other := range queued {
+			if hasTag(other, q.Tag) {
+				count++
+			}
+		}
+		if count >= q.MaxQueued {
+			return e.exceeded(Exceeded{Ticket: t, Tag: q.Tag, Limit: LimitQueued, Max: q.MaxQueued, Current: count})
+		}
+	}
+	e.forget(t, LimitQueued)
+	return nil
+}
+
+// Allows reports whether a ticket may start under max_in_flight and
+// max_daily_invocations; it implements queue.Gate
+func (e *Enforcer) Allows(t *ticket.Ticket) bool {
+	if e == nil {
+		return true
+	}
+	e.mu.Lock()
+	e.rollover()
+	var held *Exceeded
+	for _, q := range e.matching(t) {
+		tag := strings.ToLower(q.Tag)
+		if n := len(e.inFlight[tag]); q.MaxInFlight > 0 && n >= q.MaxInFlight {
+			held = &Exceeded{Ticket: t, Tag: q.Tag, Limit: LimitInFlight, Max: q.MaxInFlight, Current: n}
+			break
+		}
+		if n := e.invocations[tag]; q.MaxDailyInvocations > 0 && n >= q.MaxDailyInvocations {
+			held = &Exceeded{Ticket: t, Tag: q.Tag, Limit: LimitInvocations, Max: q.MaxDailyInvocations, Current: n}
+			break
+		}
+	}
+	e.mu.Unlock()
+
+	if held != nil {
+		e.exceeded(*held)
+		return false
+	}
+	return true
+}
+
+// Start counts a ticket Allows accepted as in flight and as one of the day's
+// agent invocations; it implements queue.Gate
+func (e *Enforcer) Start(t *ticket.Ticket) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.rollover()
+	for _, q := range e.matching(t) {
+		tag := strings.ToLower(q.Tag)
+		if e.inFlight[tag] == nil {
+			e.inFlight[tag] = make(map[string]bool)
+		}
+		e.inFlight[tag][t.ID] = true
+		e.invocations[tag]++
+	}
+	e.mu.Unlock()
+	e.forget(t, LimitInFlight, LimitInvocations)
+}
+
+// Finish stops counting a ticket as in flight, whether it completed, failed
+// or went back on the queue; finishing a ticket twice is harmless
+func (e *Enforcer) Finish(t *ticket.Ticket) {
+	if e == nil || t == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, ids := range e.inFlight {
+		delete(ids, t.ID)
+	}
+	for _, limit := range []string{LimitQueued, LimitInFlight, LimitInvocations} {
+		delete(e.reported, t.ID+"\x00"+limit)
+	}
+}
+
+// matching returns the quotas for the ticket's tags
+func (e *Enforcer) matching(t *ticket.Ticket) []Quota {
+	var quotas []Quota
+	for _, q := range e.quotas {
+		if hasTag(t, q.Tag) {
+			quotas = append(quotas, q)
+		}
+	}
+	return quotas
+}
+
+// rollover starts a new day's invocation counts; the caller holds mu
+func (e *Enforcer) rollover() {
+	if day := e.now().UTC().Format("2006-01-02"); day != e.day {
+		e.day = day
+		e.invocations = make(map[string]int)
+	}
+}
+
+// exceeded publishes a ticket held back unless it was already reported for
+// the same limit, and returns it as an error
+func (e *Enforcer) exceeded(x Exceeded) error {
+	key := x.Ticket.ID + "\x00" + x.Limit
+	e.mu.Lock()
+	first := !e.reported[key]
+	e.reported[key] = true
+	e.mu.Unlock()
+	if first && e.publisher != nil {
+		e.publisher(x)
+	}
+	return x
+}
+
+// forget clears what was reported for a ticket that got past limits, so it
+// is reported again should it be held back later
+func (e *Enforcer) forget(t *ticket.Ticket, limits ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, limit := range limits {
+		delete(e.reported, t.ID+"\x00"+limit)
+	}
+}
+
+func hasTag(t *ticket.Ticket, tag string) bool {
+	for _, candidate := range t.Tags {
+		if strings.EqualFold(candidate, tag) {
+			return true
+		}
+	}
+	return false
+}
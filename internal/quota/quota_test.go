@@ -0,0 +1,112 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func tagged(id string, tags ...string) *ticket.Ticket {
+	return &ticket.Ticket{ID: id, Tags: tags}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate([]Quota{{Tag: "team-a", MaxQueued: 2}, {Tag: "team-b"}}); err != nil {
+		t.Errorf("Expected valid quotas, got %v", err)
+	}
+	for _, quotas := range [][]Quota{
+		{{MaxQueued: 1}},
+		{{Tag: "team-a"}, {Tag: "Team-A"}},
+		{{Tag: "team-a", MaxInFlight: -1}},
+	} {
+		if err := Validate(quotas); err == nil {
+			t.Errorf("Expected %+v to be rejected", quotas)
+		}
+	}
+}
+
+func TestNilEnforcer(t *testing.T) {
+	var e *Enforcer
+	if New(nil) != nil {
+		t.Error("Expected no enforcer without quotas")
+	}
+	tk := tagged("a", "team-a")
+	if err := e.Admit(tk, nil); err != nil || !e.Allows(tk) {
+		t.Error("Expected a nil enforcer to hold nothing back")
+	}
+	e.Start(tk)
+	e.Finish(tk)
+}
+
+func TestAdmitMaxQueued(t *testing.T) {
+	e := New([]Quota{{Tag: "team-a", MaxQueued: 2}})
+	var events []Exceeded
+	e.SetPublisher(func(x Exceeded) { events = append(events, x) })
+
+	queued := []*ticket.Ticket{tagged("q1", "Team-A"), tagged("q2", "team-b")}
+	if err := e.Admit(tagged("new", "team-a"), queued); err != nil {
+		t.Fatalf("Expected room for a second team-a ticket, got %v", err)
+	}
+
+	queued = append(queued, tagged("q3", "team-a"))
+	err := e.Admit(tagged("new", "team-a"), queued)
+	if !errors.Is(err, ErrExceeded) {
+		t.Fatalf("Expected ErrExceeded, got %v", err)
+	}
+	e.Admit(tagged("new", "team-a"), queued)
+	if len(events) != 1 || events[0].Limit != LimitQueued || events[0].Current != 2 {
+		t.Fatalf("Expected one max_queued event, got %+v", events)
+	}
+	if err := e.Admit(tagged("other", "team-b"), queued); err != nil {
+		t.Errorf("Expected untagged teams to be admitted, got %v", err)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	e := New([]Quota{{Tag: "team-a", MaxInFlight: 1}})
+	var events []Exceeded
+	e.SetPublisher(func(x Exceeded) { events = append(events, x) })
+
+	first, second := tagged("a1", "team-a"), tagged("a2", "team-a")
+	if !e.Allows(first) {
+		t.Fatal("Expected the first ticket to be allowed")
+	}
+	e.Start(first)
+	if e.Allows(second) || e.Allows(second) {
+		t.Fatal("Expected the second ticket to wait for the first")
+	}
+	if len(events) != 1 || events[0].Limit != LimitInFlight || events[0].Ticket != second {
+		t.Fatalf("Expected one max_in_flight event, got %+v", events)
+	}
+
+	e.Finish(first)
+	e.Finish(first)
+	if !e.Allows(second) {
+		t.Error("Expected the second ticket once the first finished")
+	}
+}
+
+func TestMaxDailyInvocations(t *testing.T) {
+	e := New([]Quota{{Tag: "team-a", MaxDailyInvocations: 2}})
+	now := time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	for _, id := range []string{"a1", "a2"} {
+		tk := tagged(id, "team-a")
+		if !e.Allows(tk) {
+			t.Fatalf("Expected %s to be allowed", id)
+		}
+		e.Start(tk)
+		e.Finish(tk)
+	}
+	if e.Allows(tagged("a3", "team-a")) {
+		t.Fatal("Expected the day's invocations to be used up")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if !e.Allows(tagged("a3", "team-a")) {
+		t.Error("Expected invocations to reset on a new UTC day")
+	}
+}
@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter bounds how often and how many agent processes may run
+// A nil Limiter allows everything
+type Limiter struct {
+	perWindow int
+	window    time.Duration
+	sem       chan struct{}
+	starts    []time.Time
+	mu        sync.Mutex
+}
+
+// New creates a limiter allowing perHour invocations per rolling hour and at
+// most concurrent simultaneous invocations. Zero disables either limit.
+func New(perHour, concurrent int) *Limiter {
+	l := &Limiter{
+		perWindow: perHour,
+		window:    time.Hour,
+	}
+	if concurrent > 0 {
+		l.sem = make(chan struct{}, concurrent)
+	}
+	return l
+}
+
+// Acquire blocks until an invocation is allowed or ctx is done, taking the
+// rate token before the slot so waiting for one never holds the other.
+// The returned release function must be called when the invocation finishes.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if err := l.Reserve(ctx); err != nil {
+		return nil, err
+	}
+	return l.Slot(ctx)
+}
+
+// Reserve blocks until the rolling window allows another invocation, or ctx
+// is done, and records it
+func (l *Limiter) Reserve(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		wait := l.reserve()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Slot blocks until fewer than the concurrent limit of invocations are
+// running, or ctx is done; the returned release function frees the slot
+func (l *Limiter) Slot(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.sem != nil {
+				<-l.sem
+			}
+		})
+	}, nil
+}
+
+// reserve records an invocation if the window allows it, otherwise it
+// returns how long to wait before trying again
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perWindow <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+	kept := l.starts[:0]
+	for _, start := range l.starts {
+		if start.After(cutoff) {
+			kept = append(kept, start)
+		}
+	}
+	l.starts = kept
+
+	if len(l.starts) < l.perWindow {
+		l.starts = append(l.starts, now)
+		return 0
+	}
+
+	return l.starts[0].Add(l.window).Sub(now)
+}
+
+// rateLimitMarkers are substrings amp prints when the service throttles us;
+// a bare 429 is not one, as it turns up in line numbers, IDs and test output
+var rateLimitMarkers = []string{
+	"rate limit",
+	"rate-limit",
+	"ratelimit",
+	"too many requests",
+	"status 429",
+	"status code 429",
+	"quota exceeded",
+}
+
+// IsRateLimited reports whether agent output indicates a rate-limit error
+func IsRateLimited(output string) bool {
+	lower := strings.ToLower(output)
+	for _, marker := range rateLimitMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns an exponential delay for the given retry attempt with up to
+// one base interval of random jitter so workers don't retry in lockstep
+func Backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	if attempt > 10 {
+		attempt = 10
+	}
+	delay := base << uint(attempt)
+	return delay + time.Duration(rand.Int63n(int64(base)))
+}
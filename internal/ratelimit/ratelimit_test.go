@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterPerWindow(t *testing.T) {
+	l := New(2, 0)
+	l.window = 200 * time.Millisecond
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			t.Fatalf("Acquire %d failed: %v", i, err)
+		}
+		release()
+	}
+
+	start := time.Now()
+	release, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Third acquire failed: %v", err)
+	}
+	release()
+
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Errorf("Expected third acquire to wait for the window, waited %v", waited)
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := New(0, 1)
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); err == nil {
+		t.Fatal("Expected second acquire to block until context timeout")
+	}
+
+	release()
+	release() // Releasing twice must not free an extra slot
+
+	release2, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	}
+	defer release2()
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	if _, err := l.Acquire(ctx2); err == nil {
+		t.Error("Expected double release not to grant an extra slot")
+	}
+}
+
+func TestLimiterWaitsForTokenWithoutHoldingSlot(t *testing.T) {
+	l := New(1, 1)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	release()
+
+	// The window is spent; a caller waiting for the next token must leave
+	// the slot free
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.Acquire(ctx)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	slotCtx, slotCancel := context.WithTimeout(context.Background(), time.Second)
+	defer slotCancel()
+	releaseSlot, err := l.Slot(slotCtx)
+	if err != nil {
+		t.Fatalf("Expected the slot to be free while waiting for a token, got %v", err)
+	}
+	releaseSlot()
+
+	cancel()
+	if err := <-done; err == nil {
+		t.Error("Expected the waiting Acquire to be cancelled")
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Nil limiter should allow everything, got %v", err)
+	}
+	release()
+}
+
+func TestIsRateLimited(t *testing.T) {
+	if !IsRateLimited("Error: 429 Too Many Requests") {
+		t.Error("Expected 429 output to be detected as rate limited")
+	}
+	if !IsRateLimited("You have hit the Rate Limit, try later") {
+		t.Error("Expected rate limit output to be detected")
+	}
+	if !IsRateLimited("request failed with status code 429") {
+		t.Error("Expected a 429 status to be detected as rate limited")
+	}
+	if IsRateLimited("compilation failed: undefined: foo") {
+		t.Error("Expected ordinary failure not to be detected as rate limited")
+	}
+	if IsRateLimited("main.go:429: undefined: foo") {
+		t.Error("Expected a bare 429 not to be detected as rate limited")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt := 0; attempt < 4; attempt++ {
+		delay := Backoff(base, attempt)
+		min := base << uint(attempt)
+		if delay < min || delay >= min+base {
+			t.Errorf("Attempt %d: expected delay in [%v, %v), got %v", attempt, min, min+base, delay)
+		}
+	}
+
+	if Backoff(0, 3) != 0 {
+		t.Error("Expected zero base to disable backoff")
+	}
+}
@@ -0,0 +1,339 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// Log streaming bounds: lines are sent in chunks well under the IPC command
+// size limit, and dropped once too much is waiting
+const (
+	logChunkSize  = 16 * 1024
+	maxPendingLog = 256 * 1024
+)
+
+// AgentConfig holds settings for a remote worker process
+type AgentConfig struct {
+	Address      string        // Daemon's remote.listen_address
+	Token        string        // IPC token with at least the operator role
+	Name         string        // Unique per worker process
+	WorkDir      string        // Holds the repository clone, worktrees and CI status
+	PollInterval time.Duration // How often an idle worker asks for work
+	Heartbeat    time.Duration // How often a busy worker reports in; keep it well under the daemon's lease
+
+	// Template for the worker running each ticket; ID, RepoPath and WorkDir
+	// are filled in, and CIStatusDir defaults to <WorkDir>/ci-status
+	Worker worker.Config
+}
+
+// Agent connects to a daemon, claims tickets and runs them with a local
+// worker, pushing the resulting branches back over git
+type Agent struct {
+	config AgentConfig
+	client *ipc.Client
+	repo   *gitutils.GitRepo // Bare clone of the daemon's repository
+
+	logMu      sync.Mutex
+	logTicket  string // Ticket whose log lines are being streamed
+	pendingLog []byte
+	dropped    bool
+}
+
+// NewAgent creates a remote worker agent
+func NewAgent(config AgentConfig) *Agent {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 10 * time.Second
+	}
+	if config.Heartbeat <= 0 {
+		config.Heartbeat = 30 * time.Second
+	}
+	if config.Worker.CIStatusDir == "" {
+		config.Worker.CIStatusDir = filepath.Join(config.WorkDir, "ci-status")
+	}
+	return &Agent{config: config}
+}
+
+// Run connects to the daemon and processes tickets until ctx is done or the
+// connection is lost
+func (a *Agent) Run(ctx context.Context) error {
+	if err := a.connect(ctx); err != nil {
+		return err
+	}
+	defer a.client.Close()
+
+	go a.streamLogs(ctx)
+
+	log.Printf("Remote worker %s connected to %s", a.config.Name, a.config.Address)
+	for {
+		ran, err := a.RunOnce(ctx)
+		if err != nil {
+			return err
+		}
+		if ran {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(a.config.PollInterval):
+		}
+	}
+}
+
+// LogWriter returns a writer whose output is streamed to the daemon while a
+// ticket is running, e.g. as part of the log package's output
+func (a *Agent) LogWriter() io.Writer {
+	return logWriter{a}
+}
+
+// connect dials the daemon and authenticates
+func (a *Agent) connect(ctx context.Context) error {
+	client := ipc.NewTCPClient(a.config.Address)
+	if err := client.Connect(); err != nil {
+		return err
+	}
+
+	authCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := client.Authenticate(authCtx, a.config.Token); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to authenticate with %s: %w", a.config.Address, err)
+	}
+
+	// Workers don't watch the event stream; drain it so it never backs up
+	go func() {
+		for range client.Events() {
+		}
+	}()
+
+	a.client = client
+	return nil
+}
+
+// RunOnce claims and runs a single ticket, reporting whether there was one
+// An error means the daemon could not be reached
+func (a *Agent) RunOnce(ctx context.Context) (bool, error) {
+	response, err := a.send(ctx, CommandClaim, map[string]string{"worker": a.config.Name})
+	if err != nil {
+		return false, err
+	}
+	if response.Message == "" {
+		return false, nil
+	}
+
+	var assignment Assignment
+	if err := json.Unmarshal([]byte(response.Message), &assignment); err != nil {
+		return false, fmt.Errorf("invalid assignment: %w", err)
+	}
+	t := assignment.Ticket
+	log.Printf("Remote worker %s picked up ticket %s: %s", a.config.Name, t.ID, t.Title)
+
+	if err := a.syncRepo(ctx, assignment.RepoURL); err != nil {
+		log.Printf("Remote worker %s failed to fetch %s: %v", a.config.Name, assignment.RepoURL, err)
+		return false, a.complete(ctx, t, map[string]string{"requeue": "true", "error": err.Error()})
+	}
+
+	a.startLog(t.ID)
+	result := a.runTicket(ctx, assignment)
+
+	args := map[string]string{
+		"ok":      strconv.FormatBool(result.Err == nil),
+		"branch":  result.Branch,
+		"commit":  result.Commit,
+		"summary": t.Summary,
+	}
+	if result.Err != nil {
+		args["error"] = result.Err.Error()
+		args["code"] = string(worker.FailureCode(result.Err))
+	}
+	if errors.Is(result.Err, worker.ErrAgentAuth) {
+		args["requeue"] = "true"
+	}
+
+	log.Printf("Remote worker %s finished ticket %s (ok=%s)", a.config.Name, t.ID, args["ok"])
+	a.flushLogs(ctx)
+	a.startLog("")
+
+	return true, a.complete(ctx, t, args)
+}
+
+// runTicket runs a ticket with a local worker and pushes its branch upstream
+func (a *Agent) runTicket(ctx context.Context, assignment Assignment) worker.RunResult {
+	t := assignment.Ticket
+
+	config := a.config.Worker
+	config.ID = assignment.WorkerID
+	config.RepoPath = a.repo.Path
+	config.WorkDir = filepath.Join(a.config.WorkDir, "work")
+	w := worker.New(config, queue.New())
+	w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+		if eventType == "started" || eventType == "limit_exceeded" {
+			a.reportStatus(ctx, t, "working", message)
+		}
+	})
+
+	// Keep the daemon from assuming this worker died during long agent runs
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(a.config.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				a.reportStatus(ctx, t, "", "")
+			}
+		}
+	}()
+
+	result := w.Run(ctx, t)
+	if !result.Implemented || result.Commit == "" {
+		return result
+	}
+
+	if err := a.repo.WithContext(ctx).PushBranch(assignment.RepoURL, result.Branch); err != nil {
+		log.Printf("Remote worker %s failed to push %s: %v", a.config.Name, result.Branch, err)
+		if result.Err == nil {
+			result.Err = err
+		}
+	}
+	return result
+}
+
+// syncRepo clones the daemon's repository on first use and fetches it after
+func (a *Agent) syncRepo(ctx context.Context, url string) error {
+	path := filepath.Join(a.config.WorkDir, "repo.git")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		repo, err := gitutils.CloneBare(ctx, url, path)
+		if err != nil {
+			return err
+		}
+		a.repo = repo
+		return nil
+	}
+
+	a.repo = gitutils.NewRepo(path)
+	return a.repo.WithContext(ctx).FetchBranches(url)
+}
+
+// complete reports a ticket's outcome to the daemon
+func (a *Agent) complete(ctx context.Context, t *ticket.Ticket, args map[string]string) error {
+	args["worker"] = a.config.Name
+	args["ticket"] = t.ID
+	_, err := a.send(ctx, CommandComplete, args)
+	return err
+}
+
+// reportStatus sends a progress update; an empty status is only a heartbeat
+func (a *Agent) reportStatus(ctx context.Context, t *ticket.Ticket, status, message string) {
+	if t == nil {
+		return
+	}
+	args := map[string]string{"worker": a.config.Name, "ticket": t.ID, "status": status, "message": message}
+	if _, err := a.send(ctx, CommandStatus, args); err != nil {
+		log.Printf("Remote worker %s failed to report status: %v", a.config.Name, err)
+	}
+}
+
+// send issues a command and turns error responses into errors
+func (a *Agent) send(ctx context.Context, name string, args map[string]string) (*ipc.CommandResponse, error) {
+	sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	response, err := a.client.SendCommand(sendCtx, name, args)
+	if err != nil {
+		return nil, err
+	}
+	if !response.OK {
+		return nil, fmt.Errorf("%s: %s", name, response.Error)
+	}
+	return response, nil
+}
+
+// startLog begins streaming log output for a ticket; "" stops streaming
+func (a *Agent) startLog(ticketID string) {
+	a.logMu.Lock()
+	defer a.logMu.Unlock()
+	a.logTicket = ticketID
+	a.pendingLog = nil
+	a.dropped = false
+}
+
+// streamLogs sends buffered log output once a second until ctx is done
+func (a *Agent) streamLogs(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.flushLogs(ctx)
+		}
+	}
+}
+
+// flushLogs sends the buffered log output for the current ticket
+// Failures are not logged, since that would feed back into the stream
+func (a *Agent) flushLogs(ctx context.Context) {
+	a.logMu.Lock()
+	ticketID, pending := a.logTicket, a.pendingLog
+	a.pendingLog = nil
+	a.logMu.Unlock()
+
+	for ticketID != "" && len(pending) > 0 {
+		n := len(pending)
+		if n > logChunkSize {
+			n = logChunkSize
+		}
+		args := map[string]string{"worker": a.config.Name, "ticket": ticketID, "lines": string(pending[:n])}
+		if _, err := a.send(ctx, CommandLog, args); err != nil {
+			return
+		}
+		pending = pending[n:]
+	}
+}
+
+// logWriter buffers output for the ticket being streamed
+type logWriter struct {
+	agent *Agent
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	a := w.agent
+	a.logMu.Lock()
+	defer a.logMu.Unlock()
+
+	if a.logTicket == "" {
+		return len(p), nil
+	}
+	if len(a.pendingLog)+len(p) > maxPendingLog {
+		if !a.dropped {
+			a.pendingLog = append(a.pendingLog, "[log lines dropped]\n"...)
+			a.dropped = true
+		}
+		return len(p), nil
+	}
+	a.dropped = false
+	a.pendingLog = append(a.pendingLog, p...)
+	return len(p), nil
+}
@@ -0,0 +1,309 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/claim"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/locks"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/quota"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// IPC commands remote workers send to the daemon
+const (
+	CommandClaim    = "worker_claim"    // Take the next ticket from the queue
+	CommandStatus   = "worker_status"   // Report progress; doubles as a heartbeat
+	CommandLog      = "worker_log"      // Append log lines for the current ticket
+	CommandComplete = "worker_complete" // Report the outcome of the current ticket
+)
+
+// Assignment is the reply to a claim: the ticket to run and where to fetch
+// and push the repository
+type Assignment struct {
+	WorkerID int            `json:"worker_id"`
+	RepoURL  string         `json:"repo_url"`
+	Ticket   *ticket.Ticket `json:"ticket"`
+}
+
+// Publisher receives the events a remote worker's progress produces
+// *ipc.Server implements it
+type Publisher interface {
+	PublishTicketStarted(t *ticket.Ticket, workerID int)
+	PublishTicketComplete(t *ticket.Ticket, workerID int)
+	PublishTicketFailed(t *ticket.Ticket, workerID int, code ipc.ErrorCode, reason string)
+	PublishWorkerStatus(workerID int, status string, currentTicket *ticket.Ticket, message string)
+}
+
+// Config holds coordinator settings
+type Config struct {
+	RepoURL       string        // Git URL remote workers clone and push branches to
+	FirstWorkerID int           // Remote workers are numbered from here, after the local ones
+	Lease         time.Duration // Tickets of workers silent for longer are requeued
+	LogDir        string        // Streamed logs are appended to <LogDir>/<ticket-id>.log
+	Claims        *claim.Claimer
+	Quotas        *quota.Enforcer // Optional; told when a remote worker's ticket is no longer in flight
+	Locks         *locks.Manager  // Optional; releases a remote worker's ticket's locks likewise
+}
+
+// Coordinator hands queued tickets to remote workers connected over IPC and
+// tracks them until they report back
+type Coordinator struct {
+	config    Config
+	queue     *queue.Queue
+	publisher Publisher
+	now       func() time.Time
+
+	mu      sync.Mutex
+	workers map[string]*remoteWorker // By worker name
+	nextID  int
+}
+
+// remoteWorker is a connected worker process and the ticket it holds
+type remoteWorker struct {
+	id       int
+	caller   ipc.Caller
+	ticket   *ticket.Ticket
+	lastSeen time.Time
+}
+
+// NewCoordinator creates a coordinator serving tickets from q
+func NewCoordinator(config Config, q *queue.Queue, publisher Publisher) *Coordinator {
+	return &Coordinator{
+		config:    config,
+		queue:     q,
+		publisher: publisher,
+		now:       time.Now,
+		workers:   make(map[string]*remoteWorker),
+		nextID:    config.FirstWorkerID,
+	}
+}
+
+// Register adds the remote worker commands to an IPC server
+// Workers authenticate with a token holding at least the operator role
+func (c *Coordinator) Register(server *ipc.Server) {
+	server.HandleCommand(CommandClaim, ipc.RoleOperator, c.claim)
+	server.HandleCommand(CommandComplete, ipc.RoleOperator, c.complete)
+	server.HandleQuietCommand(CommandStatus, ipc.RoleOperator, c.status)
+	server.HandleQuietCommand(CommandLog, ipc.RoleOperator, c.appendLog)
+}
+
+// Run requeues the tickets of workers that stop reporting until ctx is done
+func (c *Coordinator) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.reap()
+		}
+	}
+}
+
+// claim pops the next ticket for a worker; the reply is empty when the queue is
+func (c *Coordinator) claim(caller ipc.Caller, args map[string]string) (string, error) {
+	name := args["worker"]
+	if name == "" {
+		return "", errors.New("worker name is required")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.worker(name, caller)
+	if w.ticket != nil {
+		// The worker restarted and forgot its ticket; let someone else have it
+		log.Printf("Remote worker %s claimed again while holding %s, requeueing it", name, w.ticket.ID)
+		c.config.Quotas.Finish(w.ticket)
+		c.config.Locks.Release(w.ticket)
+		c.queue.Push(w.ticket)
+		w.ticket = nil
+	}
+
+	t := c.queue.Pop()
+	if t == nil {
+		return "", nil
+	}
+	w.ticket = t
+
+	data, err := json.Marshal(Assignment{WorkerID: w.id, RepoURL: c.config.RepoURL, Ticket: t})
+	if err != nil {
+		c.config.Quotas.Finish(t)
+		c.config.Locks.Release(t)
+		c.queue.Push(t)
+		w.ticket = nil
+		return "", fmt.Errorf("failed to marshal assignment: %w", err)
+	}
+
+	log.Printf("Remote worker %s (%d) picked up ticket: %s", name, w.id, t.ID)
+	if c.publisher != nil {
+		c.publisher.PublishTicketStarted(t, w.id)
+		c.publisher.PublishWorkerStatus(w.id, "working", t, fmt.Sprintf("Started processing ticket %s on %s", t.ID, name))
+	}
+	return string(data), nil
+}
+
+// status records a progress report from a worker
+func (c *Coordinator) status(caller ipc.Caller, args map[string]string) (string, error) {
+	c.mu.Lock()
+	w, err := c.holder(args["worker"], args["ticket"], caller)
+	c.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	if c.publisher != nil && args["status"] != "" {
+		var current *ticket.Ticket
+		if args["status"] == "working" {
+			current = w.ticket
+		}
+		c.publisher.PublishWorkerStatus(w.id, args["status"], current, args["message"])
+	}
+	return "ok", nil
+}
+
+// appendLog writes streamed log lines to the ticket's log file
+func (c *Coordinator) appendLog(caller ipc.Caller, args map[string]string) (string, error) {
+	c.mu.Lock()
+	w, err := c.holder(args["worker"], args["ticket"], caller)
+	c.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	if c.config.LogDir == "" || args["lines"] == "" {
+		return "ok", nil
+	}
+	if err := os.MkdirAll(c.config.LogDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(c.config.LogDir, w.ticket.ID+".log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s", args["lines"]); err != nil {
+		return "", fmt.Errorf("failed to write log: %w", err)
+	}
+	return "ok", nil
+}
+
+// complete records the outcome of a worker's ticket
+// Tickets the worker could not attempt, e.g. because its agent is logged
+// out, are requeued with requeue=true
+func (c *Coordinator) complete(caller ipc.Caller, args map[string]string) (string, error) {
+	c.mu.Lock()
+	w, err := c.holder(args["worker"], args["ticket"], caller)
+	if err != nil {
+		c.mu.Unlock()
+		return "", err
+	}
+	t := w.ticket
+	w.ticket = nil
+	c.mu.Unlock()
+	c.config.Quotas.Finish(t)
+	c.config.Locks.Release(t)
+
+	ok, _ := strconv.ParseBool(args["ok"])
+	requeue, _ := strconv.ParseBool(args["requeue"])
+
+	switch {
+	case requeue:
+		c.queue.Push(t)
+		log.Printf("Remote worker %s requeued ticket %s: %s", args["worker"], t.ID, args["error"])
+		if c.publisher != nil {
+			c.publisher.PublishWorkerStatus(w.id, "error", nil, args["error"])
+		}
+		return "requeued " + t.ID, nil
+
+	case ok:
+		t.Summary = args["summary"]
+		log.Printf("Remote worker %s completed ticket %s on %s (%s)", args["worker"], t.ID, args["branch"], args["commit"])
+		if c.publisher != nil {
+			c.publisher.PublishTicketComplete(t, w.id)
+			c.publisher.PublishWorkerStatus(w.id, "idle", nil, fmt.Sprintf("Completed ticket %s", t.ID))
+		}
+
+	default:
+		log.Printf("Remote worker %s failed ticket %s: %s", args["worker"], t.ID, args["error"])
+		t.FailureCode = args["code"]
+		if c.publisher != nil {
+			c.publisher.PublishTicketFailed(t, w.id, ipc.ErrorCode(t.FailureCode), args["error"])
+			c.publisher.PublishWorkerStatus(w.id, "idle", nil, fmt.Sprintf("Failed ticket %s: %s", t.ID, args["error"]))
+		}
+	}
+
+	if err := c.config.Claims.Complete(t.ID); err != nil {
+		log.Printf("Failed to complete claim on %s: %v", t.ID, err)
+	}
+	return "recorded " + t.ID, nil
+}
+
+// reap requeues tickets held by workers that stopped reporting
+func (c *Coordinator) reap() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for name, w := range c.workers {
+		if now.Sub(w.lastSeen) <= c.config.Lease {
+			continue
+		}
+		if w.ticket != nil {
+			log.Printf("Remote worker %s went silent, requeueing ticket %s", name, w.ticket.ID)
+			c.config.Quotas.Finish(w.ticket)
+			c.config.Locks.Release(w.ticket)
+			c.queue.Push(w.ticket)
+		}
+		if c.publisher != nil {
+			c.publisher.PublishWorkerStatus(w.id, "error", nil, fmt.Sprintf("Remote worker %s disconnected", name))
+		}
+		delete(c.workers, name)
+	}
+}
+
+// worker returns the record for a worker name, registering new workers
+// Callers hold c.mu
+func (c *Coordinator) worker(name string, caller ipc.Caller) *remoteWorker {
+	w, ok := c.workers[name]
+	if !ok {
+		w = &remoteWorker{id: c.nextID}
+		c.nextID++
+		c.workers[name] = w
+		log.Printf("Remote worker %s connected as worker %d from %s", name, w.id, caller)
+	}
+	w.caller = caller
+	w.lastSeen = c.now()
+	return w
+}
+
+// holder returns the worker holding ticketID, refreshing its heartbeat
+// Callers hold c.mu
+func (c *Coordinator) holder(name, ticketID string, caller ipc.Caller) (*remoteWorker, error) {
+	w, ok := c.workers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown worker %q", name)
+	}
+	w.caller = caller
+	w.lastSeen = c.now()
+
+	if w.ticket == nil || w.ticket.ID != ticketID {
+		return nil, fmt.Errorf("worker %s does not hold ticket %s", name, ticketID)
+	}
+	return w, nil
+}
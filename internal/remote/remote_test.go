@@ -0,0 +1,271 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/worker"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// recordingPublisher remembers the events a coordinator publishes
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (p *recordingPublisher) add(event string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+func (p *recordingPublisher) PublishTicketStarted(t *ticket.Ticket, workerID int) {
+	p.add(fmt.Sprintf("started %s %d", t.ID, workerID))
+}
+
+func (p *recordingPublisher) PublishTicketComplete(t *ticket.Ticket, workerID int) {
+	p.add(fmt.Sprintf("complete %s %d", t.ID, workerID))
+}
+
+func (p *recordingPublisher) PublishTicketFailed(t *ticket.Ticket, workerID int, code ipc.ErrorCode, reason string) {
+	p.add(fmt.Sprintf("failed %s %d %s", t.ID, workerID, code))
+}
+
+func (p *recordingPublisher) PublishWorkerStatus(workerID int, status string, currentTicket *ticket.Ticket, message string) {
+	p.add(fmt.Sprintf("status %d %s", workerID, status))
+}
+
+func (p *recordingPublisher) has(event string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func newTicket(id string) *ticket.Ticket {
+	return &ticket.Ticket{ID: id, Title: "Test " + id, Description: "Remote worker test", Priority: 1, CreatedAt: time.Now()}
+}
+
+func TestCoordinatorAssignsAndCompletes(t *testing.T) {
+	q := queue.New()
+	q.Push(newTicket("feat-1"))
+	publisher := &recordingPublisher{}
+	logDir := t.TempDir()
+	c := NewCoordinator(Config{RepoURL: "git://build/repo.git", FirstWorkerID: 4, Lease: time.Minute, LogDir: logDir}, q, publisher)
+
+	caller := ipc.Caller{Token: "gpu-box"}
+	message, err := c.claim(caller, map[string]string{"worker": "gpu-1"})
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	var assignment Assignment
+	if err := json.Unmarshal([]byte(message), &assignment); err != nil {
+		t.Fatalf("Invalid assignment %q: %v", message, err)
+	}
+	if assignment.WorkerID != 4 || assignment.RepoURL != "git://build/repo.git" || assignment.Ticket.ID != "feat-1" {
+		t.Errorf("Unexpected assignment %+v", assignment)
+	}
+	if !publisher.has("started feat-1 4") {
+		t.Errorf("Expected ticket started event, got %v", publisher.events)
+	}
+
+	// Nothing left to hand out
+	if message, err := c.claim(caller, map[string]string{"worker": "gpu-2"}); err != nil || message != "" {
+		t.Errorf("Expected empty claim, got %q (err %v)", message, err)
+	}
+
+	if _, err := c.appendLog(caller, map[string]string{"worker": "gpu-1", "ticket": "feat-1", "lines": "running agent\n"}); err != nil {
+		t.Fatalf("appendLog failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(logDir, "feat-1.log")); string(data) != "running agent\n" {
+		t.Errorf("Unexpected streamed log %q", data)
+	}
+
+	// Only the holder may report on a ticket
+	if _, err := c.complete(caller, map[string]string{"worker": "gpu-2", "ticket": "feat-1", "ok": "true"}); err == nil {
+		t.Error("Expected another worker's report to be refused")
+	}
+	if _, err := c.complete(caller, map[string]string{"worker": "gpu-1", "ticket": "feat-1", "ok": "true", "summary": "Done remotely"}); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	if !publisher.has("complete feat-1 4") {
+		t.Errorf("Expected ticket complete event, got %v", publisher.events)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Expected completed ticket not to be requeued, queue has %d", q.Len())
+	}
+
+	// Failures keep the worker's error code
+	q.Push(newTicket("feat-2"))
+	if _, err := c.claim(caller, map[string]string{"worker": "gpu-1"}); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if _, err := c.complete(caller, map[string]string{"worker": "gpu-1", "ticket": "feat-2", "ok": "false", "error": "CI failed: tests failed", "code": "ci_failed"}); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	if !publisher.has("failed feat-2 4 ci_failed") {
+		t.Errorf("Expected ticket failed event with its code, got %v", publisher.events)
+	}
+}
+
+func TestCoordinatorRequeues(t *testing.T) {
+	q := queue.New()
+	q.Push(newTicket("feat-1"))
+	now := time.Now()
+	c := NewCoordinator(Config{FirstWorkerID: 2, Lease: time.Minute}, q, nil)
+	c.now = func() time.Time { return now }
+
+	// A worker whose agent is logged out hands its ticket back
+	if _, err := c.claim(ipc.Caller{}, map[string]string{"worker": "gpu-1"}); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if _, err := c.complete(ipc.Caller{}, map[string]string{"worker": "gpu-1", "ticket": "feat-1", "requeue": "true", "error": "agent authentication failed"}); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Expected ticket to be requeued, queue has %d", q.Len())
+	}
+
+	// A worker that goes silent loses its ticket after the lease
+	if _, err := c.claim(ipc.Caller{}, map[string]string{"worker": "gpu-2"}); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	now = now.Add(30 * time.Second)
+	c.reap()
+	if q.Len() != 0 {
+		t.Fatal("Expected ticket to stay with a worker within its lease")
+	}
+
+	now = now.Add(2 * time.Minute)
+	c.reap()
+	if q.Len() != 1 || q.Peek().ID != "feat-1" {
+		t.Fatalf("Expected silent worker's ticket to be requeued, queue has %d", q.Len())
+	}
+	if _, err := c.complete(ipc.Caller{}, map[string]string{"worker": "gpu-2", "ticket": "feat-1", "ok": "true"}); err == nil {
+		t.Error("Expected a reaped worker's late report to be refused")
+	}
+}
+
+func TestAgentRunsTicketRemotely(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// The daemon's repository, reached by the agent through a git URL
+	repoPath := filepath.Join(tmpDir, "repo.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	t.Setenv("TEST_WORKER_TOKEN", "worker-secret")
+	auth, err := ipc.NewAuthenticator(ipc.AuthConfig{Tokens: []ipc.TokenConfig{
+		{Name: "gpu-box", TokenEnv: "TEST_WORKER_TOKEN", Role: ipc.RoleOperator},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	server := ipc.NewServer(filepath.Join(tmpDir, "daemon.sock"))
+	server.SetAuthenticator(auth)
+
+	q := queue.New()
+	q.Push(newTicket("feat-remote"))
+	publisher := &recordingPublisher{}
+	logDir := filepath.Join(tmpDir, "remote-logs")
+	coordinator := NewCoordinator(Config{RepoURL: repoPath, FirstWorkerID: 3, Lease: time.Minute, LogDir: logDir}, q, publisher)
+	coordinator.Register(server)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+	if err := server.StartTCP("127.0.0.1:0"); err != nil {
+		t.Fatalf("StartTCP failed: %v", err)
+	}
+
+	agent := NewAgent(AgentConfig{
+		Address: server.TCPAddress(),
+		Token:   "worker-secret",
+		Name:    "gpu-1",
+		WorkDir: filepath.Join(tmpDir, "remote"),
+		Worker:  worker.Config{SkipCI: true, SkipAmp: true},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := agent.connect(ctx); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer agent.client.Close()
+
+	// The agent's log output is streamed while it runs the ticket
+	log.SetOutput(io.MultiWriter(os.Stderr, agent.LogWriter()))
+	defer log.SetOutput(os.Stderr)
+
+	ran, err := agent.RunOnce(ctx)
+	if err != nil || !ran {
+		t.Fatalf("RunOnce returned %v (err %v)", ran, err)
+	}
+
+	// The branch was pushed back to the daemon's repository
+	commit, err := gitutils.NewRepo(repoPath).GetBranchCommit("agent-3/feat-remote")
+	if err != nil {
+		t.Fatalf("Expected the branch to be pushed: %v", err)
+	}
+	if commit == "" {
+		t.Error("Expected a commit on the pushed branch")
+	}
+	if !publisher.has("started feat-remote 3") || !publisher.has("complete feat-remote 3") {
+		t.Errorf("Expected start and completion events, got %v", publisher.events)
+	}
+
+	if ran, err := agent.RunOnce(ctx); err != nil || ran {
+		t.Errorf("Expected no more work, got %v (err %v)", ran, err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(logDir, "feat-remote.log")); !strings.Contains(string(data), "Worker 3 processing ticket feat-remote") {
+		t.Errorf("Expected streamed log lines, got %q", data)
+	}
+}
+
+func TestAgentRejectsBadToken(t *testing.T) {
+	t.Setenv("TEST_WORKER_TOKEN", "worker-secret")
+	auth, err := ipc.NewAuthenticator(ipc.AuthConfig{Tokens: []ipc.TokenConfig{
+		{Name: "gpu-box", TokenEnv: "TEST_WORKER_TOKEN", Role: ipc.RoleOperator},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	server := ipc.NewServer(filepath.Join(t.TempDir(), "daemon.sock"))
+	server.SetAuthenticator(auth)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+	if err := server.StartTCP("127.0.0.1:0"); err != nil {
+		t.Fatalf("StartTCP failed: %v", err)
+	}
+
+	agent := NewAgent(AgentConfig{Address: server.TCPAddress(), Token: "wrong", Name: "gpu-1", WorkDir: t.TempDir()})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := agent.Run(ctx); err == nil || !strings.Contains(err.Error(), "invalid token") {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+}
@@ -0,0 +1,358 @@
+// Package report writes a Markdown record of each completed ticket: what
+// was asked, what the agent did, what changed, how CI went and how long it
+// all took
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// maxPending bounds completed tickets waiting for their report
+const maxPending = 256
+
+// BranchPlaceholder is replaced by the branch name in Config.BranchURL
+const BranchPlaceholder = "{branch}"
+
+// Config controls completion reports
+type Config struct {
+	Enabled    bool     `mapstructure:"enabled"`
+	Path       string   `mapstructure:"path"`        // Directory reports are written to as <ticket-id>.md
+	BranchURL  string   `mapstructure:"branch_url"`  // Optional link for branches, e.g. https://github.com/acme/widgets/tree/{branch}
+	NotifyURLs []string `mapstructure:"notify_urls"` // Webhooks each report is posted to as JSON
+}
+
+// Validate checks the report settings
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Path == "" {
+		return errors.New("path is required")
+	}
+	if c.BranchURL != "" && !strings.Contains(c.BranchURL, BranchPlaceholder) {
+		return fmt.Errorf("branch_url must contain %s", BranchPlaceholder)
+	}
+	for _, notifyURL := range c.NotifyURLs {
+		u, err := url.Parse(notifyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notify URL %q must be an http or https URL", notifyURL)
+		}
+	}
+	return nil
+}
+
+// Details is everything a report is rendered from
+type Details struct {
+	Ticket      *ticket.Ticket
+	WorkerID    int
+	CompletedAt time.Time
+	Commit      string
+	DiffStat    string       // git diff --stat of the branch against main
+	CI          []*ci.Status // The ticket's CI runs, oldest first
+	Timeline    timeline.Timeline
+	BranchURL   string // Empty when branches have no link
+	Redacted    bool   // Leaves out the ticket description and agent summary
+}
+
+// completion is a ticket_complete event waiting for its report
+type completion struct {
+	ticket      *ticket.Ticket
+	workerID    int
+	completedAt time.Time
+}
+
+// Writer writes a report for every completed ticket
+type Writer struct {
+	config       Config
+	repoPath     string
+	environments environment.Environments
+	ciStatus     *ci.StatusReader
+	timeline     func(ticketID string) (timeline.Timeline, error) // Optional
+	cipher       *encryption.Cipher                               // Optional
+	client       *http.Client
+	pending      chan completion
+}
+
+// New creates a writer reading branches from the repository at repoPath, or
+// their environment's repository, CI results from ciStatusDir and timings
+// from timelineFor when it is not nil
+func New(config Config, repoPath string, environments environment.Environments, ciStatusDir string, timelineFor func(ticketID string) (timeline.Timeline, error)) *Writer {
+	return &Writer{
+		config:       config,
+		repoPath:     repoPath,
+		environments: environments,
+		ciStatus:     ci.NewStatusReader(ciStatusDir),
+		timeline:     timelineFor,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		pending:      make(chan completion, maxPending),
+	}
+}
+
+// SetCipher sets the cipher reports are sealed with; while it encrypts,
+// webhooks get reports without the ticket description and agent summary
+func (w *Writer) SetCipher(c *encryption.Cipher) {
+	w.cipher = c
+}
+
+// Record queues a completed ticket for Run to report on, without blocking
+// the event's publisher; when the queue is full the report is skipped
+func (w *Writer) Record(event ipc.Event) {
+	data, ok := event.Data.(ipc.TicketEvent)
+	if !ok || event.Type != ipc.EventTypeTicketComplete || data.Ticket == nil {
+		return
+	}
+	select {
+	case w.pending <- completion{ticket: data.Ticket, workerID: data.WorkerID, completedAt: event.Timestamp}:
+	default:
+		log.Printf("Too many completed tickets waiting; no report for %s", data.Ticket.ID)
+	}
+}
+
+// Run writes reports for recorded tickets until ctx is done
+func (w *Writer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c := <-w.pending:
+			path, err := w.Write(ctx, c.ticket, c.workerID, c.completedAt)
+			if err != nil {
+				log.Printf("Failed to write report for %s: %v", c.ticket.ID, err)
+				continue
+			}
+			log.Printf("Wrote report for %s to %s", c.ticket.ID, path)
+		}
+	}
+}
+
+// Write gathers a completed ticket's details, writes its report and posts
+// it to the notify URLs, returning the report's path
+func (w *Writer) Write(ctx context.Context, t *ticket.Ticket, workerID int, completedAt time.Time) (string, error) {
+	details := w.gather(ctx, t, workerID, completedAt)
+	markdown := Render(details)
+
+	if err := os.MkdirAll(w.config.Path, 0700); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	path := filepath.Join(w.config.Path, t.ID+".md")
+	if err := w.cipher.WriteFile(path, []byte(markdown), 0600); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if w.cipher.Encrypts() {
+		details.Redacted = true
+		markdown = Render(details)
+	}
+
+	for _, notifyURL := range w.config.NotifyURLs {
+		if err := w.notify(ctx, notifyURL, t, path, markdown); err != nil {
+			log.Printf("Failed to post report for %s to %s: %v", t.ID, notifyURL, err)
+		}
+	}
+	return path, nil
+}
+
+// gather collects what is known about a completed ticket; anything that
+// cannot be found is left out of the report rather than failing it
+func (w *Writer) gather(ctx context.Context, t *ticket.Ticket, workerID int, completedAt time.Time) Details {
+	details := Details{Ticket: t, WorkerID: workerID, CompletedAt: completedAt}
+
+	if t.Branch != "" {
+		repoPath := w.repoPath
+		if settings, err := w.environments.Lookup(t.Environment); err == nil && settings.Repository != "" {
+			repoPath = settings.Repository
+		}
+		repo := gitutils.NewRepo(repoPath).WithContext(ctx)
+		if commit, err := repo.GetBranchCommit(t.Branch); err == nil {
+			details.Commit = commit
+		}
+		if stat, err := repo.GetDiffStatText(t.Branch); err == nil {
+			details.DiffStat = stat
+		} else {
+			log.Printf("Failed to get diffstat for %s: %v", t.ID, err)
+		}
+		if w.config.BranchURL != "" {
+			details.BranchURL = strings.ReplaceAll(w.config.BranchURL, BranchPlaceholder, t.Branch)
+		}
+	}
+
+	if statuses, err := w.ciStatus.GetByTicket(t.ID); err == nil {
+		details.CI = statuses
+	}
+	if w.timeline != nil {
+		if tl, err := w.timeline(t.ID); err == nil {
+			details.Timeline = tl
+		} else {
+			log.Printf("Failed to load timeline for %s: %v", t.ID, err)
+		}
+	}
+	return details
+}
+
+// notify posts a report to a webhook; text carries the Markdown so chat
+// webhooks such as Slack's can show it as is
+func (w *Writer) notify(ctx context.Context, notifyURL string, t *ticket.Ticket, path, markdown string) error {
+	body, err := json.Marshal(map[string]string{
+		"text":      markdown,
+		"ticket_id": t.ID,
+		"title":     t.Title,
+		"path":      path,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notifyURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Render writes a ticket's report as Markdown
+func Render(d Details) string {
+	t := d.Ticket
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s (%s)\n\n", t.Title, t.ID)
+
+	kind := t.Type
+	if kind == "" {
+		kind = ticket.TypeFeature
+	}
+	b.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Type | %s |\n", kind)
+	fmt.Fprintf(&b, "| Priority | P%d |\n", t.Priority)
+	if len(t.Tags) > 0 {
+		fmt.Fprintf(&b, "| Tags | %s |\n", strings.Join(t.Tags, ", "))
+	}
+	if d.WorkerID > 0 {
+		fmt.Fprintf(&b, "| Agent | %d |\n", d.WorkerID)
+	}
+	if t.Attempt > 1 {
+		fmt.Fprintf(&b, "| Attempt | %d |\n", t.Attempt)
+	}
+	if t.Branch != "" {
+		fmt.Fprintf(&b, "| Branch | %s |\n", branchLink(t.Branch, d.BranchURL))
+	}
+	if d.Commit != "" {
+		fmt.Fprintf(&b, "| Commit | `%.8s` |\n", d.Commit)
+	}
+	if !d.CompletedAt.IsZero() {
+		fmt.Fprintf(&b, "| Completed | %s |\n", timefmt.Timestamp(d.CompletedAt))
+	}
+
+	if d.Redacted {
+		b.WriteString("\n## Ticket\n\nLeft out because encryption is enabled\n")
+	} else {
+		fmt.Fprintf(&b, "\n## Ticket\n\n%s\n", strings.TrimSpace(t.Description))
+	}
+	if t.Summary != "" && !d.Redacted {
+		fmt.Fprintf(&b, "\n## Agent summary\n\n%s\n", strings.TrimSpace(t.Summary))
+	}
+	if d.DiffStat != "" {
+		fmt.Fprintf(&b, "\n## Changes\n\n```\n%s\n```\n", d.DiffStat)
+	}
+
+	b.WriteString("\n## CI\n\n")
+	switch {
+	case len(d.CI) > 0:
+		for _, status := range d.CI {
+			b.WriteString("- " + describeCI(status) + "\n")
+		}
+	case t.SkipCI:
+		b.WriteString("Skipped for this ticket\n")
+	default:
+		b.WriteString("No CI results recorded\n")
+	}
+
+	if steps := phases(d.Timeline); len(steps) > 0 {
+		b.WriteString("\n## Timings\n\n| Phase | Started | Took |\n|---|---|---|\n")
+		for _, step := range steps {
+			took := ""
+			if step.Duration() > 0 {
+				took = timeline.FormatDuration(step.Duration())
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", step.Name, timefmt.Timestamp(step.Start), took)
+		}
+		fmt.Fprintf(&b, "\nTotal: %s\n", timeline.FormatDuration(d.Timeline.Duration()))
+	}
+
+	if t.Branch != "" || len(t.ArtifactURLs) > 0 || len(t.LogURLs) > 0 {
+		b.WriteString("\n## Links\n\n")
+		if t.Branch != "" {
+			fmt.Fprintf(&b, "- Branch: %s\n", branchLink(t.Branch, d.BranchURL))
+		}
+		for _, artifact := range t.ArtifactURLs {
+			fmt.Fprintf(&b, "- Artifact: %s\n", artifact)
+		}
+		for _, logURL := range t.LogURLs {
+			fmt.Fprintf(&b, "- Agent log: %s\n", logURL)
+		}
+	}
+	return b.String()
+}
+
+// branchLink renders a branch name, linked when there is a URL for it
+func branchLink(branch, branchURL string) string {
+	if branchURL == "" {
+		return "`" + branch + "`"
+	}
+	return fmt.Sprintf("[`%s`](%s)", branch, branchURL)
+}
+
+// describeCI summarises one CI run on a line
+func describeCI(status *ci.Status) string {
+	line := fmt.Sprintf("**%s** at `%.8s`, %s", status.Status, status.Commit, timefmt.Timestamp(status.Timestamp))
+	if status.Profile != "" {
+		line += ", " + status.Profile + " profile"
+	}
+	if status.Tier != "" {
+		line += ", " + status.Tier + " tier"
+	}
+	if len(status.Flaky) > 0 {
+		line += "; flaky: " + strings.Join(status.Flaky, ", ")
+	}
+	for _, cell := range status.Cells {
+		line += fmt.Sprintf("; %s %s", cell.Name, cell.Status)
+	}
+	return line
+}
+
+// phases returns the timeline's phases, leaving out markers
+func phases(tl timeline.Timeline) []timeline.Step {
+	var steps []timeline.Step
+	for _, step := range tl.Steps {
+		if !step.Marker {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
@@ -0,0 +1,212 @@
+package report
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// newTestRepo returns a bare repository with a commit adding feature.txt on
+// agent-1/feat-1
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "repo.git")
+	clone := filepath.Join(tmpDir, "clone")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	for _, args := range [][]string{
+		{"clone", "-q", repoPath, clone},
+		{"-C", clone, "checkout", "-q", "-b", "agent-1/feat-1"},
+	} {
+		git(t, tmpDir, args...)
+	}
+	if err := os.WriteFile(filepath.Join(clone, "feature.txt"), []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatalf("Failed to write feature.txt: %v", err)
+	}
+	git(t, clone, "add", "feature.txt")
+	git(t, clone, "commit", "-q", "-m", "Add feature")
+	git(t, clone, "push", "-q", "origin", "agent-1/feat-1")
+	return repoPath
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+func TestWriterWritesAndPostsReport(t *testing.T) {
+	repoPath := newTestRepo(t)
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	var posted map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer server.Close()
+
+	config := Config{
+		Enabled:    true,
+		Path:       filepath.Join(t.TempDir(), "reports"),
+		BranchURL:  "https://github.com/acme/widgets/tree/{branch}",
+		NotifyURLs: []string{server.URL},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Config should be valid: %v", err)
+	}
+	timelineFor := func(ticketID string) (timeline.Timeline, error) {
+		return timeline.Build(ticketID, []timeline.Entry{
+			{Time: start, Ticket: ticketID, Event: string(ipc.EventTypeTicketStarted)},
+			{Time: start.Add(3 * time.Minute), Ticket: ticketID, Event: string(ipc.EventTypeTicketComplete)},
+		}, nil, nil), nil
+	}
+	writer := New(config, repoPath, nil, t.TempDir(), timelineFor)
+
+	tk := &ticket.Ticket{
+		ID:          "feat-1",
+		Title:       "Add feature",
+		Description: "Adds a feature",
+		Priority:    2,
+		Summary:     "Added feature.txt",
+		Branch:      "agent-1/feat-1",
+		LogURLs:     []string{"s3://logs/feat-1/agent-1.log"},
+	}
+	path, err := writer.Write(t.Context(), tk, 1, start.Add(3*time.Minute))
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if path != filepath.Join(config.Path, "feat-1.md") {
+		t.Errorf("Unexpected report path %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	report := string(data)
+	for _, want := range []string{
+		"# Add feature (feat-1)",
+		"| Priority | P2 |",
+		"## Agent summary\n\nAdded feature.txt",
+		"feature.txt | 2 ++",
+		"No CI results recorded",
+		"| started |",
+		"Total: 3m 0s",
+		"[`agent-1/feat-1`](https://github.com/acme/widgets/tree/agent-1/feat-1)",
+		"- Agent log: s3://logs/feat-1/agent-1.log",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Report should contain %q:\n%s", want, report)
+		}
+	}
+	if posted["ticket_id"] != "feat-1" || posted["text"] != report || posted["path"] != path {
+		t.Errorf("Unexpected webhook payload %v", posted)
+	}
+}
+
+func TestWriterSealsReportsWhenEncrypting(t *testing.T) {
+	repoPath := newTestRepo(t)
+
+	var posted map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer server.Close()
+
+	cipher, err := encryption.NewCipher([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	config := Config{Enabled: true, Path: filepath.Join(t.TempDir(), "reports"), NotifyURLs: []string{server.URL}}
+	writer := New(config, repoPath, nil, t.TempDir(), nil)
+	writer.SetCipher(cipher)
+
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Add feature", Description: "Secret plans", Summary: "Added secret.txt", Branch: "agent-1/feat-1"}
+	path, err := writer.Write(t.Context(), tk, 1, time.Now())
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat report: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the report to be private, got %v", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	if !encryption.IsEncrypted(data) {
+		t.Fatal("Expected the report to be sealed")
+	}
+	report, err := cipher.Decrypt(data)
+	if err != nil {
+		t.Fatalf("Failed to decrypt report: %v", err)
+	}
+	if !strings.Contains(string(report), "Secret plans") || !strings.Contains(string(report), "Added secret.txt") {
+		t.Errorf("Expected the sealed report to keep the ticket:\n%s", report)
+	}
+	if strings.Contains(posted["text"], "Secret plans") || strings.Contains(posted["text"], "Added secret.txt") {
+		t.Errorf("Expected the webhook to get no description or summary:\n%s", posted["text"])
+	}
+	if !strings.Contains(posted["text"], "# Add feature (feat-1)") {
+		t.Errorf("Expected the webhook to still get the report:\n%s", posted["text"])
+	}
+}
+
+func TestRenderCI(t *testing.T) {
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Add feature", Priority: 3}
+	report := Render(Details{Ticket: tk, CI: []*ci.Status{
+		{Commit: "0123456789abcdef", Status: "FAIL"},
+		{Commit: "fedcba9876543210", Status: "FLAKY", Profile: "api", Flaky: []string{"./internal/api"}},
+	}})
+	for _, want := range []string{"**FAIL** at `01234567`", "**FLAKY** at `fedcba98`", "api profile; flaky: ./internal/api"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Report should contain %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "## Links") {
+		t.Errorf("A ticket without a branch, artifacts or logs should have no links:\n%s", report)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, config := range []Config{
+		{Enabled: true},
+		{Enabled: true, Path: "reports", BranchURL: "https://github.com/acme/widgets"},
+		{Enabled: true, Path: "reports", NotifyURLs: []string{"hooks.example.com"}},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Config %+v should be rejected", config)
+		}
+	}
+	if err := (Config{}).Validate(); err != nil {
+		t.Errorf("Disabled config should not be validated: %v", err)
+	}
+}
@@ -0,0 +1,512 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// actionTimeout bounds each command and notification a rule runs
+const actionTimeout = time.Minute
+
+// Rule reacts to matching events on the daemon's event bus
+// Templates in the actions see the event's data, e.g. {{.ticket.id}}
+type Rule struct {
+	Name          string            `mapstructure:"name"`
+	Event         string            `mapstructure:"event"`          // Event type, e.g. ci_flaky
+	Match         map[string]string `mapstructure:"match"`          // Field path to regexp, e.g. ticket.id: "^feat-"
+	Per           string            `mapstructure:"per"`            // Field path counted separately per value, e.g. package
+	Count         int               `mapstructure:"count"`          // Fire on every count-th match; defaults to 1
+	WindowMinutes int               `mapstructure:"window_minutes"` // Only count matches this recent; 0 counts all
+	Command       string            `mapstructure:"command"`        // Run via sh -c with the event JSON on stdin; fields are passed as variables
+	Enqueue       *TicketTemplate   `mapstructure:"enqueue"`        // Ticket written to the backlog
+	Notify        *Notification     `mapstructure:"notify"`         // Message published as a rule_triggered event
+}
+
+// TicketTemplate describes the ticket a rule enqueues
+type TicketTemplate struct {
+	ID          string   `mapstructure:"id"`
+	Title       string   `mapstructure:"title"`
+	Description string   `mapstructure:"description"`
+	Priority    int      `mapstructure:"priority"`
+	Tags        []string `mapstructure:"tags"`
+}
+
+// Notification describes the message a rule sends
+type Notification struct {
+	Message string `mapstructure:"message"`
+	URL     string `mapstructure:"url"` // Optional webhook receiving {"rule", "message", "event"} as a JSON POST
+}
+
+// Validate checks a set of rules
+func Validate(rules []Rule) error {
+	_, err := compile(rules)
+	return err
+}
+
+// compiledRule is a rule with its patterns and templates parsed
+type compiledRule struct {
+	Rule
+	match     map[string]*regexp.Regexp
+	templates map[string]*template.Template
+}
+
+func compile(rules []Rule) ([]*compiledRule, error) {
+	compiled := make([]*compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		c, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		c.Name = name
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	if rule.Event == "" {
+		return nil, errors.New("event is required")
+	}
+	if rule.Command == "" && rule.Enqueue == nil && rule.Notify == nil {
+		return nil, errors.New("at least one of command, enqueue or notify is required")
+	}
+	if rule.Count < 0 || rule.WindowMinutes < 0 {
+		return nil, errors.New("count and window_minutes cannot be negative")
+	}
+
+	c := &compiledRule{
+		Rule:      rule,
+		match:     make(map[string]*regexp.Regexp),
+		templates: make(map[string]*template.Template),
+	}
+	for field, pattern := range rule.Match {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match for %s: %w", field, err)
+		}
+		c.match[field] = re
+	}
+
+	texts := map[string]string{"command": rule.Command}
+	if t := rule.Enqueue; t != nil {
+		if t.ID == "" || t.Title == "" {
+			return nil, errors.New("enqueue needs an id and a title")
+		}
+		if t.Priority < 0 || t.Priority > 5 {
+			return nil, errors.New("enqueue priority must be between 0 and 5")
+		}
+		texts["id"], texts["title"], texts["description"] = t.ID, t.Title, t.Description
+	}
+	if n := rule.Notify; n != nil {
+		if n.Message == "" {
+			return nil, errors.New("notify needs a message")
+		}
+		texts["message"] = n.Message
+	}
+	for name, text := range texts {
+		if text == "" {
+			continue
+		}
+		tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", name, err)
+		}
+		if name == "command" {
+			// A defined template's output would reach the shell unquoted
+			if len(tmpl.Templates()) > 1 {
+				return nil, errors.New("invalid command template: define and block are not allowed")
+			}
+			if err := passAsVariables(tmpl.Tree.Root); err != nil {
+				return nil, fmt.Errorf("invalid command template: %w", err)
+			}
+		}
+		c.templates[name] = tmpl
+	}
+	return c, nil
+}
+
+// funcs are available in rule templates
+var funcs = template.FuncMap{
+	"slug": slug,
+	"arg":  fmt.Sprint, // Replaced per run in command templates, see renderCommand
+}
+
+// argPrefix names the variables holding the fields of a command template
+const argPrefix = "RULE_ARG_"
+
+// passAsVariables pipes every value a command template prints through arg,
+// so event fields such as ticket titles never reach the shell as code;
+// {{template}} is refused, since what it prints can't be piped
+func passAsVariables(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := passAsVariables(child); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		// Assignments like {{$x := .package}} print nothing
+		if len(n.Pipe.Decl) == 0 {
+			arg := &parse.IdentifierNode{NodeType: parse.NodeIdentifier, Ident: "arg"}
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Args: []parse.Node{arg}})
+		}
+	case *parse.TemplateNode:
+		return fmt.Errorf("{{template %q}} is not allowed", n.Name)
+	case *parse.IfNode:
+		return passBranches(n.List, n.ElseList)
+	case *parse.RangeNode:
+		return passBranches(n.List, n.ElseList)
+	case *parse.WithNode:
+		return passBranches(n.List, n.ElseList)
+	}
+	return nil
+}
+
+// passBranches applies passAsVariables to both branches of a control node
+func passBranches(list, elseList *parse.ListNode) error {
+	if err := passAsVariables(list); err != nil {
+		return err
+	}
+	return passAsVariables(elseList)
+}
+
+// renderCommand renders a command template, replacing each printed value
+// with a quoted reference to a RULE_ARG_<n> variable that holds it, and
+// returns the command and those variables
+func renderCommand(tmpl *template.Template, fields map[string]interface{}) (string, []string, error) {
+	var env []string
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return "", nil, err
+	}
+	clone.Funcs(template.FuncMap{"arg": func(value interface{}) string {
+		name := fmt.Sprintf("%s%d", argPrefix, len(env))
+		if value == nil {
+			value = ""
+		}
+		env = append(env, name+"="+fmt.Sprint(value))
+		return `"$` + name + `"`
+	}})
+	command, err := render(clone, fields)
+	return command, env, err
+}
+
+// invalidSlugChars are replaced by slug
+var invalidSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug makes a value usable in ticket IDs and file names
+func slug(value string) string {
+	return strings.Trim(invalidSlugChars.ReplaceAllString(strings.ToLower(value), "-"), "-")
+}
+
+// Engine evaluates rules against events and runs their actions
+type Engine struct {
+	rules      []*compiledRule
+	backlogDir string
+	notify     func(rule, message string)
+	client     *http.Client
+	runner     command.Runner
+	now        func() time.Time
+
+	mu   sync.Mutex
+	hits map[string][]time.Time // Match times by rule and per value
+	wg   sync.WaitGroup         // Running actions
+}
+
+// New creates an engine; enqueued tickets are written to backlogDir
+func New(rules []Rule, backlogDir string) (*Engine, error) {
+	compiled, err := compile(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{
+		rules:      compiled,
+		backlogDir: backlogDir,
+		client:     &http.Client{Timeout: actionTimeout},
+		now:        time.Now,
+		hits:       make(map[string][]time.Time),
+	}, nil
+}
+
+// SetNotifier sets the function that publishes notify messages
+func (e *Engine) SetNotifier(notify func(rule, message string)) {
+	e.notify = notify
+}
+
+// Handle evaluates an event; actions of the rules it fires run in the
+// background so the publisher isn't held up
+func (e *Engine) Handle(eventType string, data interface{}) {
+	var fields map[string]interface{}
+	payload, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(payload, &fields)
+	}
+	if err != nil {
+		log.Printf("Rules: failed to decode %s event: %v", eventType, err)
+		return
+	}
+
+	for _, rule := range e.rules {
+		if rule.Event != eventType || !rule.matches(fields) || !e.record(rule, fields) {
+			continue
+		}
+
+		log.Printf("Rule %s fired on %s event", rule.Name, eventType)
+		e.wg.Add(1)
+		go func(rule *compiledRule) {
+			defer e.wg.Done()
+			e.fire(rule, eventType, payload, fields)
+		}(rule)
+	}
+}
+
+// Wait blocks until running actions finish
+func (e *Engine) Wait() {
+	e.wg.Wait()
+}
+
+// matches reports whether an event's fields satisfy every match pattern
+func (r *compiledRule) matches(fields map[string]interface{}) bool {
+	for field, re := range r.match {
+		if !re.MatchString(lookup(fields, field)) {
+			return false
+		}
+	}
+	return true
+}
+
+// record counts a match and reports whether the rule should fire
+func (e *Engine) record(rule *compiledRule, fields map[string]interface{}) bool {
+	count := rule.Count
+	if count <= 1 {
+		return true
+	}
+
+	key := rule.Name
+	if rule.Per != "" {
+		key += "\x00" + lookup(fields, rule.Per)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	hits := e.hits[key]
+	if rule.WindowMinutes > 0 {
+		cutoff := now.Add(-time.Duration(rule.WindowMinutes) * time.Minute)
+		kept := hits[:0]
+		for _, hit := range hits {
+			if hit.After(cutoff) {
+				kept = append(kept, hit)
+			}
+		}
+		hits = kept
+	}
+	hits = append(hits, now)
+
+	if len(hits) < count {
+		e.hits[key] = hits
+		return false
+	}
+	delete(e.hits, key)
+	return true
+}
+
+// fire runs a rule's actions; failures are logged
+func (e *Engine) fire(rule *compiledRule, eventType string, payload []byte, fields map[string]interface{}) {
+	if rule.Command != "" {
+		if err := e.runCommand(rule, payload, fields); err != nil {
+			log.Printf("Rule %s: command failed: %v", rule.Name, err)
+		}
+	}
+	if rule.Enqueue != nil {
+		if err := e.enqueue(rule, fields); err != nil {
+			log.Printf("Rule %s: failed to enqueue ticket: %v", rule.Name, err)
+		}
+	}
+	if rule.Notify != nil {
+		if err := e.sendNotification(rule, eventType, payload, fields); err != nil {
+			log.Printf("Rule %s: notification failed: %v", rule.Name, err)
+		}
+	}
+}
+
+// SetRunner sets what runs the rules' commands; nil uses command.Default
+func (e *Engine) SetRunner(runner command.Runner) {
+	e.runner = runner
+}
+
+func (e *Engine) runCommand(rule *compiledRule, payload []byte, fields map[string]interface{}) error {
+	script, env, err := renderCommand(rule.templates["command"], fields)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+
+	cmd := command.Context(ctx, "sh", "-c", script)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(payload)
+	if output, err := command.Or(e.runner).CombinedOutput(cmd); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// enqueue writes the rule's ticket to the backlog, where the watcher picks it
+// up like any other; a ticket file that already exists is left alone
+func (e *Engine) enqueue(rule *compiledRule, fields map[string]interface{}) error {
+	spec := rule.Enqueue
+	t := &ticket.Ticket{
+		Priority:  spec.Priority,
+		Tags:      spec.Tags,
+		CreatedAt: e.now(),
+		UpdatedAt: e.now(),
+	}
+	if t.Priority == 0 {
+		t.Priority = 3
+	}
+
+	var err error
+	if t.ID, err = render(rule.templates["id"], fields); err != nil {
+		return err
+	}
+	if t.Title, err = render(rule.templates["title"], fields); err != nil {
+		return err
+	}
+	if t.Description, err = render(rule.templates["description"], fields); err != nil {
+		return err
+	}
+	if t.Description == "" {
+		t.Description = t.Title
+	}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	data, err := t.ToYAML()
+	if err != nil {
+		return fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+	data, _, err = ticket.Stamp(data, ticket.Provenance{
+		EnqueuedBy: "rule:" + rule.Name,
+		Source:     "rules",
+		EnqueuedAt: e.now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(e.backlogDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backlog directory: %w", err)
+	}
+
+	path := filepath.Join(e.backlogDir, "rule-"+slug(t.ID)+".yaml")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		log.Printf("Rule %s: ticket %s is already in the backlog", rule.Name, t.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Rule %s enqueued ticket %s", rule.Name, t.ID)
+	return nil
+}
+
+func (e *Engine) sendNotification(rule *compiledRule, eventType string, payload []byte, fields map[string]interface{}) error {
+	message, err := render(rule.templates["message"], fields)
+	if err != nil {
+		return err
+	}
+	if e.notify != nil {
+		e.notify(rule.Name, message)
+	}
+	if rule.Notify.URL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"rule":    rule.Name,
+		"message": message,
+		"event":   map[string]interface{}{"type": eventType, "data": json.RawMessage(payload)},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(rule.Notify.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// render executes a template against an event's fields
+func render(tmpl *template.Template, fields map[string]interface{}) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, fields); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// lookup resolves a dotted field path, e.g. ticket.id, to a string
+func lookup(fields map[string]interface{}, path string) string {
+	var value interface{} = fields
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// flakyEvent mirrors the daemon's ci_flaky event data
+type flakyEvent struct {
+	WorkerID int            `json:"worker_id"`
+	Ticket   *ticket.Ticket `json:"ticket,omitempty"`
+	Package  string         `json:"package"`
+}
+
+func TestEnqueuesTicketAfterRepeatedMatches(t *testing.T) {
+	backlog := t.TempDir()
+	engine, err := New([]Rule{{
+		Name:          "fix-flaky",
+		Event:         "ci_flaky",
+		Per:           "package",
+		Count:         2,
+		WindowMinutes: 60,
+		Enqueue: &TicketTemplate{
+			ID:          "fix-flaky-{{slug .package}}",
+			Title:       "Fix flaky tests in {{.package}}",
+			Description: "{{.package}} passed only on retry twice, last while working on {{.ticket.id}}",
+			Priority:    2,
+		},
+	}}, backlog)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	now := time.Now()
+	engine.now = func() time.Time { return now }
+
+	engine.Handle("ci_flaky", flakyEvent{WorkerID: 1, Ticket: &ticket.Ticket{ID: "feat-1"}, Package: "example.com/app/auth"})
+	engine.Handle("ci_flaky", flakyEvent{WorkerID: 1, Ticket: &ticket.Ticket{ID: "feat-1"}, Package: "example.com/app/db"})
+	engine.Handle("ticket_complete", flakyEvent{Package: "example.com/app/auth"})
+	engine.Wait()
+	if entries, _ := os.ReadDir(backlog); len(entries) != 0 {
+		t.Fatalf("Expected no ticket after one match per package, got %d", len(entries))
+	}
+
+	engine.Handle("ci_flaky", flakyEvent{WorkerID: 2, Ticket: &ticket.Ticket{ID: "feat-2"}, Package: "example.com/app/auth"})
+	engine.Wait()
+
+	enqueued, err := ticket.Load(filepath.Join(backlog, "rule-fix-flaky-example-com-app-auth.yaml"))
+	if err != nil {
+		t.Fatalf("Expected an enqueued ticket: %v", err)
+	}
+	if enqueued.ID != "fix-flaky-example-com-app-auth" || enqueued.Priority != 2 {
+		t.Errorf("Unexpected ticket %+v", enqueued)
+	}
+	if enqueued.Description != "example.com/app/auth passed only on retry twice, last while working on feat-2" {
+		t.Errorf("Unexpected description %q", enqueued.Description)
+	}
+
+	// The count starts over after firing, and matches outside the window expire
+	engine.Handle("ci_flaky", flakyEvent{Package: "example.com/app/auth"})
+	now = now.Add(2 * time.Hour)
+	engine.Handle("ci_flaky", flakyEvent{Package: "example.com/app/db"})
+	engine.Handle("ci_flaky", flakyEvent{Package: "example.com/app/auth"})
+	engine.Wait()
+	if entries, _ := os.ReadDir(backlog); len(entries) != 1 {
+		t.Errorf("Expected expired matches not to count, backlog has %d tickets", len(entries))
+	}
+}
+
+func TestMatchCommandAndNotify(t *testing.T) {
+	var webhook map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &webhook)
+	}))
+	defer server.Close()
+
+	out := filepath.Join(t.TempDir(), "event.json")
+	engine, err := New([]Rule{{
+		Name:    "release",
+		Event:   "ticket_complete",
+		Match:   map[string]string{"ticket.id": "^release-", "worker_id": "^[12]$"},
+		Command: "cat > " + out,
+		Notify:  &Notification{Message: "{{.ticket.id}} is done", URL: server.URL},
+	}}, t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	var notified []string
+	engine.SetNotifier(func(rule, message string) {
+		notified = append(notified, rule+": "+message)
+	})
+
+	engine.Handle("ticket_complete", flakyEvent{WorkerID: 3, Ticket: &ticket.Ticket{ID: "release-1"}})
+	engine.Handle("ticket_complete", flakyEvent{WorkerID: 1, Ticket: &ticket.Ticket{ID: "feat-1"}})
+	engine.Wait()
+	if len(notified) != 0 {
+		t.Fatalf("Expected no match, got %v", notified)
+	}
+
+	engine.Handle("ticket_complete", flakyEvent{WorkerID: 2, Ticket: &ticket.Ticket{ID: "release-1"}})
+	engine.Wait()
+
+	if len(notified) != 1 || notified[0] != "release: release-1 is done" {
+		t.Errorf("Unexpected notifications %v", notified)
+	}
+	if webhook["message"] != "release-1 is done" || webhook["rule"] != "release" {
+		t.Errorf("Unexpected webhook payload %v", webhook)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || !strings.Contains(string(data), `"release-1"`) {
+		t.Errorf("Expected the event JSON on the command's stdin, got %q (err %v)", data, err)
+	}
+}
+
+func TestCommandFieldsAreNotShellCode(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "title.txt")
+	engine, err := New([]Rule{{
+		Name:    "announce",
+		Event:   "ticket_complete",
+		Command: "printf '%s|%s' {{.ticket.title}} \"{{.ticket.id}}\" > " + out,
+	}}, t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	title := "$(touch " + filepath.Join(dir, "pwned") + ") `id`; echo"
+	engine.Handle("ticket_complete", flakyEvent{Ticket: &ticket.Ticket{ID: "feat-1", Title: title}})
+	engine.Wait()
+
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); !os.IsNotExist(err) {
+		t.Fatal("Expected the ticket title not to run as a command")
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != title+"|feat-1" {
+		t.Errorf("Expected the fields passed verbatim, got %q (err %v)", data, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"missing event", Rule{Command: "true"}},
+		{"no action", Rule{Event: "ci_flaky"}},
+		{"bad pattern", Rule{Event: "ci_flaky", Command: "true", Match: map[string]string{"package": "("}}},
+		{"bad template", Rule{Event: "ci_flaky", Notify: &Notification{Message: "{{.package"}}},
+		{"ticket without title", Rule{Event: "ci_flaky", Enqueue: &TicketTemplate{ID: "fix"}}},
+		{"negative count", Rule{Event: "ci_flaky", Command: "true", Count: -1}},
+		{"priority out of range", Rule{Event: "ci_flaky", Enqueue: &TicketTemplate{ID: "fix", Title: "Fix", Priority: 6}}},
+		{"defined command template", Rule{Event: "ci_flaky", Command: `{{define "raw"}}{{.package}}{{end}}echo {{template "raw" .}}`}},
+		{"command block", Rule{Event: "ci_flaky", Command: `echo {{block "raw" .}}{{.package}}{{end}}`}},
+		{"command template call", Rule{Event: "ci_flaky", Command: `{{if .package}}echo {{template "missing" .}}{{end}}`}},
+	}
+	for _, tt := range tests {
+		if err := Validate([]Rule{tt.rule}); err == nil {
+			t.Errorf("%s: expected error, got nil", tt.name)
+		}
+	}
+
+	if err := Validate([]Rule{{Event: "ci_flaky", Command: "true"}}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// Priority 0 takes the default
+	if err := Validate([]Rule{{Event: "ci_flaky", Enqueue: &TicketTemplate{ID: "fix", Title: "Fix"}}}); err != nil {
+		t.Errorf("Expected enqueue priority 0 to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package scratch
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EnvVar names the variable that gives agents and CI a ticket's scratch directory
+const EnvVar = "ORCHESTRATOR_SCRATCH_DIR"
+
+// Dir returns a ticket's scratch directory under the work directory
+// It sits outside every worktree, so nothing written there is committed
+func Dir(workDir, ticketID string) string {
+	return filepath.Join(workDir, "scratch", ticketID)
+}
+
+// Create makes a ticket's scratch directory and returns its absolute path
+func Create(workDir, ticketID string) (string, error) {
+	dir, err := filepath.Abs(Dir(workDir, ticketID))
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute scratch path: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	return dir, nil
+}
+
+// Prune removes scratch directories with nothing modified within maxAge
+// It returns the number of directories removed
+func Prune(workDir string, maxAge time.Duration) (int, error) {
+	root := filepath.Join(workDir, "scratch")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read scratch directory: %w", err)
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		modified, err := lastModified(dir)
+		if err != nil || modified.After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, fmt.Errorf("failed to remove scratch directory %s: %w", entry.Name(), err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// lastModified returns the newest modification time within dir
+func lastModified(dir string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
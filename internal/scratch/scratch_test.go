@@ -0,0 +1,82 @@
+package scratch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreate(t *testing.T) {
+	workDir := t.TempDir()
+
+	dir, err := Create(workDir, "feat-1")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !filepath.IsAbs(dir) {
+		t.Errorf("Expected absolute path, got %s", dir)
+	}
+	if dir != Dir(workDir, "feat-1") {
+		t.Errorf("Expected %s, got %s", Dir(workDir, "feat-1"), dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("Expected scratch directory to exist")
+	}
+
+	// Creating again keeps existing contents
+	if err := os.WriteFile(filepath.Join(dir, "out.bin"), []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write scratch file: %v", err)
+	}
+	if _, err := Create(workDir, "feat-1"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.bin")); err != nil {
+		t.Errorf("Expected scratch file to survive, got %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	workDir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+
+	for _, id := range []string{"stale", "fresh", "touched"} {
+		dir, err := Create(workDir, id)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		file := filepath.Join(dir, "artifact")
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to write scratch file: %v", err)
+		}
+		if id != "fresh" {
+			os.Chtimes(file, old, old)
+			os.Chtimes(dir, old, old)
+		}
+	}
+
+	// A recently written file keeps its directory alive
+	if err := os.WriteFile(filepath.Join(Dir(workDir, "touched"), "new"), []byte("y"), 0644); err != nil {
+		t.Fatalf("Failed to write scratch file: %v", err)
+	}
+
+	removed, err := Prune(workDir, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 directory pruned, got %d", removed)
+	}
+
+	for id, want := range map[string]bool{"stale": false, "fresh": true, "touched": true} {
+		_, err := os.Stat(Dir(workDir, id))
+		if got := err == nil; got != want {
+			t.Errorf("scratch %s exists = %v, want %v", id, got, want)
+		}
+	}
+
+	// A missing scratch root is not an error
+	if removed, err := Prune(t.TempDir(), time.Hour); err != nil || removed != 0 {
+		t.Errorf("Expected nothing pruned, got %d (err %v)", removed, err)
+	}
+}
@@ -0,0 +1,359 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/backlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/deadletter"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/throughput"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// Where a ticket was found
+const (
+	SourceQueue      = "queue"       // backlog directory
+	SourceProcessed  = "processed"   // backlog/processed
+	SourceArchive    = "archive"     // backlog/archive
+	SourceRejected   = "rejected"    // backlog/rejected
+	SourceDeadLetter = "dead-letter" // Dead-letter journal in the state directory
+	SourceHistory    = "history"     // Completions recorded in the metrics directory
+)
+
+// A ticket's status, worked out from everywhere it was found
+const (
+	StatusQueued    = "queued"
+	StatusProcessed = "processed" // Picked up, with no recorded outcome
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusRejected  = "rejected"
+)
+
+// Paths are the stores a search reads
+type Paths struct {
+	BacklogPath string
+	StateDir    string // Dead-letter journal; "" skips it
+	MetricsDir  string // Completion history; "" skips it
+}
+
+// Result is everything known about one ticket
+type Result struct {
+	Ticket  *ticket.Ticket // Only the ID is known for a ticket seen in history alone
+	Status  string
+	Sources []string
+	Time    time.Time // Latest activity: enqueued, processed, completed or failed
+	Message string    // Why the ticket failed, from the dead-letter journal
+}
+
+// Query selects tickets; empty fields match everything
+type Query struct {
+	Text   string    // Case-insensitive; matched against ID, title, description, tags and status
+	Status string    // One of the statuses above
+	Tag    string    // Case-insensitive
+	Since  time.Time // Latest activity at or after this time
+}
+
+// entry collects what each store says about a ticket
+type entry struct {
+	result      Result
+	queued      bool
+	rejected    bool
+	completedAt time.Time
+	failedAt    time.Time
+}
+
+// Collect reads every store into one result per ticket, most recent first
+// Files that cannot be parsed or decrypted are skipped
+func Collect(paths Paths, cipher *encryption.Cipher) ([]Result, error) {
+	entries := make(map[string]*entry)
+	get := func(id string, t *ticket.Ticket, source string, at time.Time) *entry {
+		e := entries[id]
+		if e == nil {
+			e = &entry{result: Result{Ticket: t}}
+			entries[id] = e
+		}
+		if t != nil && (e.result.Ticket == nil || e.result.Ticket.Title == "") {
+			e.result.Ticket = t
+		}
+		if !containsString(e.result.Sources, source) {
+			e.result.Sources = append(e.result.Sources, source)
+		}
+		if at.After(e.result.Time) {
+			e.result.Time = at
+		}
+		return e
+	}
+
+	for _, dir := range []struct{ path, source string }{
+		{paths.BacklogPath, SourceQueue},
+		{filepath.Join(paths.BacklogPath, "processed"), SourceProcessed},
+		{filepath.Join(paths.BacklogPath, "rejected"), SourceRejected},
+	} {
+		files, err := readDir(dir.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			t := parse(f.data, cipher)
+			if t == nil {
+				continue
+			}
+			e := get(t.ID, t, dir.source, f.modified)
+			e.queued = e.queued || dir.source == SourceQueue
+			e.rejected = e.rejected || dir.source == SourceRejected
+		}
+	}
+
+	archived, err := backlog.ReadArchived(paths.BacklogPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range archived {
+		if t := parse(f.Data, cipher); t != nil {
+			get(t.ID, t, SourceArchive, f.Processed)
+		}
+	}
+
+	if paths.StateDir != "" {
+		failures, err := deadletter.Load(paths.StateDir, cipher)
+		if err != nil {
+			return nil, err
+		}
+		for _, failure := range failures {
+			if failure.Ticket == nil {
+				continue
+			}
+			e := get(failure.Ticket.ID, failure.Ticket, SourceDeadLetter, failure.Time)
+			if failure.Time.After(e.failedAt) {
+				e.failedAt = failure.Time
+				e.result.Message = failure.Message
+			}
+		}
+	}
+
+	if paths.MetricsDir != "" {
+		completions, err := throughput.Load(paths.MetricsDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range completions {
+			e := get(c.TicketID, nil, SourceHistory, c.Time)
+			if c.Time.After(e.completedAt) {
+				e.completedAt = c.Time
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(entries))
+	for id, e := range entries {
+		if e.result.Ticket == nil {
+			e.result.Ticket = &ticket.Ticket{ID: id}
+		}
+		e.result.Status = e.status()
+		results = append(results, e.result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].Time.Equal(results[j].Time) {
+			return results[i].Time.After(results[j].Time)
+		}
+		return results[i].Ticket.ID < results[j].Ticket.ID
+	})
+	return results, nil
+}
+
+// status picks the ticket's current status: a file waiting in the backlog
+// wins, then the latest recorded outcome
+func (e *entry) status() string {
+	switch {
+	case e.queued:
+		return StatusQueued
+	case !e.completedAt.IsZero() && !e.completedAt.Before(e.failedAt):
+		return StatusCompleted
+	case !e.failedAt.IsZero():
+		return StatusFailed
+	case e.rejected:
+		return StatusRejected
+	default:
+		return StatusProcessed
+	}
+}
+
+// Filter returns the results the query matches, in the same order
+func Filter(results []Result, q Query) []Result {
+	var matched []Result
+	for _, r := range results {
+		if q.Matches(r) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// Matches reports whether a result satisfies every part of the query
+func (q Query) Matches(r Result) bool {
+	if q.Status != "" && !strings.EqualFold(q.Status, r.Status) {
+		return false
+	}
+	if q.Tag != "" && !containsFold(r.Ticket.Tags, q.Tag) {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if q.Text == "" {
+		return true
+	}
+
+	text := strings.ToLower(q.Text)
+	fields := append([]string{r.Ticket.ID, r.Ticket.Title, r.Ticket.Description, r.Status}, r.Ticket.Tags...)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseArgs reads a query from command line arguments: words are joined into
+// the search text, and --status, --tag and --since take a value, given as
+// the next argument or after =
+func ParseArgs(args []string, now time.Time) (Query, error) {
+	var q Query
+	var words []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			words = append(words, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !hasValue {
+			if i+1 >= len(args) {
+				return q, fmt.Errorf("--%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "status":
+			if !isStatus(value) {
+				return q, fmt.Errorf("unknown status %q (expected %s)", value, strings.Join(statuses, ", "))
+			}
+			q.Status = strings.ToLower(value)
+		case "tag":
+			q.Tag = value
+		case "since":
+			since, err := ParseSince(value, now)
+			if err != nil {
+				return q, err
+			}
+			q.Since = since
+		default:
+			return q, fmt.Errorf("unknown flag --%s", name)
+		}
+	}
+	q.Text = strings.Join(words, " ")
+	return q, nil
+}
+
+// ParseSince reads a time as an age such as 7d, 12h or 90m before now, or as
+// a YYYY-MM-DD date
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (e.g. 7d, 12h or 2024-01-31)", value)
+}
+
+// statuses lists the statuses a query may filter on
+var statuses = []string{StatusQueued, StatusProcessed, StatusCompleted, StatusFailed, StatusRejected}
+
+func isStatus(value string) bool {
+	return containsFold(statuses, value)
+}
+
+// ticketFile is a ticket file and when it was last written
+type ticketFile struct {
+	data     []byte
+	modified time.Time
+}
+
+// readDir reads the YAML files in a directory; a missing directory is empty
+func readDir(dir string) ([]ticketFile, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var files []ticketFile
+	for _, d := range dirEntries {
+		ext := strings.ToLower(filepath.Ext(d.Name()))
+		if d.IsDir() || (ext != ".yaml" && ext != ".yml") || d.Name() == ticket.DefaultsFileName {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, d.Name()))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue // Picked up while we were listing
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", d.Name(), err)
+		}
+		files = append(files, ticketFile{data: data, modified: info.ModTime()})
+	}
+	return files, nil
+}
+
+// parse decrypts and parses a ticket file, returning nil if it can't be read
+func parse(data []byte, cipher *encryption.Cipher) *ticket.Ticket {
+	plaintext, err := cipher.Decrypt(data)
+	if err != nil {
+		return nil
+	}
+	t, err := ticket.LoadFromBytes(plaintext)
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/backlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/deadletter"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/throughput"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func writeTicket(t *testing.T, dir string, tk *ticket.Ticket, modified time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if tk.Description == "" {
+		tk.Description = tk.Title
+	}
+	data, err := tk.ToYAML()
+	if err != nil {
+		t.Fatalf("Failed to marshal %s: %v", tk.ID, err)
+	}
+	path := filepath.Join(dir, tk.ID+".yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, modified, modified)
+}
+
+func TestCollectAndFilter(t *testing.T) {
+	backlogPath, stateDir, metricsDir := t.TempDir(), t.TempDir(), t.TempDir()
+	now := time.Now().Truncate(time.Second)
+	day := 24 * time.Hour
+
+	writeTicket(t, backlogPath, &ticket.Ticket{ID: "feat-queued", Title: "Add search", Priority: 2, Tags: []string{"cli"}}, now.Add(-time.Hour))
+	processed := filepath.Join(backlogPath, "processed")
+	writeTicket(t, processed, &ticket.Ticket{ID: "feat-done", Title: "Add login", Priority: 2, Tags: []string{"backend"}}, now.Add(-2*day))
+	writeTicket(t, processed, &ticket.Ticket{ID: "feat-broken", Title: "Add billing", Priority: 1, Tags: []string{"Backend"}}, now.Add(-3*day))
+	writeTicket(t, processed, &ticket.Ticket{ID: "feat-old", Title: "Old work", Description: "Touches the payment gateway", Priority: 3}, now.Add(-60*day))
+	writeTicket(t, filepath.Join(backlogPath, "rejected"), &ticket.Ticket{ID: "feat-bad", Title: "Rejected", Priority: 3}, now.Add(-day))
+
+	// feat-old is archived, keeping its modification time
+	if archived, err := backlog.PruneProcessed(backlogPath, backlog.Retention{MaxAgeDays: 30}, now); err != nil || archived != 1 {
+		t.Fatalf("Expected feat-old archived, got %d (err %v)", archived, err)
+	}
+
+	journal := deadletter.Open(stateDir, nil)
+	broken := &ticket.Ticket{ID: "feat-broken", Title: "Add billing", Priority: 1, Tags: []string{"Backend"}}
+	if err := journal.Append(deadletter.Entry{Time: now.Add(-3 * day), Ticket: broken, Code: ipc.ErrorCodeCIFailed, Message: "CI failed"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []throughput.Completion{
+		{Time: now.Add(-2 * day), TicketID: "feat-done", WorkerID: 1},
+		{Time: now.Add(-90 * day), TicketID: "feat-gone", WorkerID: 1},
+	} {
+		if err := throughput.Append(metricsDir, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Collect(Paths{BacklogPath: backlogPath, StateDir: stateDir, MetricsDir: metricsDir}, nil)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, r := range results {
+		statuses[r.Ticket.ID] = r.Status
+	}
+	expected := map[string]string{
+		"feat-queued": StatusQueued,
+		"feat-done":   StatusCompleted,
+		"feat-broken": StatusFailed,
+		"feat-old":    StatusProcessed,
+		"feat-bad":    StatusRejected,
+		"feat-gone":   StatusCompleted,
+	}
+	for id, status := range expected {
+		if statuses[id] != status {
+			t.Errorf("Expected %s to be %s, got %q", id, status, statuses[id])
+		}
+	}
+	if results[0].Ticket.ID != "feat-queued" || results[len(results)-1].Ticket.ID != "feat-gone" {
+		t.Errorf("Expected most recent first, got %s ... %s", results[0].Ticket.ID, results[len(results)-1].Ticket.ID)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"title", Query{Text: "add"}, []string{"feat-queued", "feat-done", "feat-broken"}},
+		{"description in archive", Query{Text: "PAYMENT"}, []string{"feat-old"}},
+		{"status word", Query{Text: "rejected"}, []string{"feat-bad"}},
+		{"status filter", Query{Status: StatusFailed}, []string{"feat-broken"}},
+		{"tag filter", Query{Tag: "backend"}, []string{"feat-done", "feat-broken"}},
+		{"since", Query{Since: now.Add(-7 * day)}, []string{"feat-queued", "feat-bad", "feat-done", "feat-broken"}},
+		{"combined", Query{Text: "add", Tag: "backend", Since: now.Add(-7 * day), Status: StatusCompleted}, []string{"feat-done"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range Filter(results, tt.query) {
+			got = append(got, r.Ticket.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+				break
+			}
+		}
+	}
+
+	for _, r := range results {
+		if r.Ticket.ID == "feat-broken" && r.Message != "CI failed" {
+			t.Errorf("Expected the dead-letter message, got %q", r.Message)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	q, err := ParseArgs([]string{"login", "--status", "FAILED", "page", "--tag=backend", "--since", "7d"}, now)
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+	if q.Text != "login page" || q.Status != StatusFailed || q.Tag != "backend" || !q.Since.Equal(now.Add(-7*24*time.Hour)) {
+		t.Errorf("Unexpected query %+v", q)
+	}
+
+	if q, err := ParseArgs([]string{"--since", "2024-03-01"}, now); err != nil || !q.Since.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a date, got %v (err %v)", q.Since, err)
+	}
+	if q, err := ParseArgs([]string{"--since=90m"}, now); err != nil || !q.Since.Equal(now.Add(-90*time.Minute)) {
+		t.Errorf("Expected a duration, got %v (err %v)", q.Since, err)
+	}
+
+	for _, args := range [][]string{
+		{"--status", "lost"},
+		{"--since", "last week"},
+		{"--tag"},
+		{"--owner", "me"},
+	} {
+		if _, err := ParseArgs(args, now); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnsigned is returned for a ticket without a signature when signatures are required
+	ErrUnsigned = errors.New("ticket is not signed")
+	// ErrUnknownKey is returned for a signature by a key that is not configured
+	ErrUnknownKey = errors.New("ticket signed by unknown key")
+	// ErrBadSignature is returned when a ticket changed after it was signed
+	ErrBadSignature = errors.New("ticket signature does not match")
+)
+
+// Config lists the public keys trusted to sign tickets
+type Config struct {
+	RequireSignedTickets bool              `mapstructure:"require_signed_tickets"` // Reject unsigned tickets
+	PublicKeys           map[string]string `mapstructure:"public_keys"`            // Key name to base64 ed25519 public key
+}
+
+// Validate checks that every public key decodes and that signatures can be
+// verified when they are required
+func (c Config) Validate() error {
+	if c.RequireSignedTickets && len(c.PublicKeys) == 0 {
+		return errors.New("require_signed_tickets needs at least one public key")
+	}
+	for name, key := range c.PublicKeys {
+		if _, err := decodePublicKey(key); err != nil {
+			return fmt.Errorf("public key %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Verifier checks ticket signatures against the configured public keys
+type Verifier struct {
+	keys    map[string]ed25519.PublicKey
+	require bool
+}
+
+// NewVerifier returns a verifier for config, or nil when no keys are
+// configured; a nil verifier accepts every ticket
+func NewVerifier(config Config) (*Verifier, error) {
+	if len(config.PublicKeys) == 0 && !config.RequireSignedTickets {
+		return nil, nil
+	}
+	v := &Verifier{keys: make(map[string]ed25519.PublicKey), require: config.RequireSignedTickets}
+	for name, key := range config.PublicKeys {
+		pub, err := decodePublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("public key %s: %w", name, err)
+		}
+		// Config keys are case-insensitive
+		v.keys[strings.ToLower(name)] = pub
+	}
+	return v, nil
+}
+
+// Verify checks a ticket's signature
+// Unsigned tickets pass unless signatures are required
+func (v *Verifier) Verify(t *ticket.Ticket) error {
+	if v == nil {
+		return nil
+	}
+	if t.Signature == nil {
+		if v.require {
+			return ErrUnsigned
+		}
+		return nil
+	}
+
+	pub, ok := v.keys[strings.ToLower(t.Signature.Key)]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownKey, t.Signature.Key)
+	}
+	sig, err := base64.StdEncoding.DecodeString(t.Signature.Value)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	canonical, err := t.Canonical()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, canonical, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Sign sets a ticket's signature, made with the named private key
+func Sign(t *ticket.Ticket, keyName string, key ed25519.PrivateKey) error {
+	canonical, err := t.Canonical()
+	if err != nil {
+		return err
+	}
+	t.Signature = &ticket.Signature{
+		Key:   keyName,
+		Value: base64.StdEncoding.EncodeToString(ed25519.Sign(key, canonical)),
+	}
+	return nil
+}
+
+// SignFile signs the ticket in data and returns it rewritten as YAML with
+// its signature; comments are not kept
+func SignFile(data []byte, keyName string, key ed25519.PrivateKey) ([]byte, error) {
+	var t ticket.Ticket
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	// A provenance block belongs to an earlier enqueue
+	t.Provenance = nil
+	if err := Sign(&t, keyName, key); err != nil {
+		return nil, err
+	}
+	return t.ToYAML()
+}
+
+// GenerateKey returns a new key pair, base64 encoded; the private key is
+// stored as its seed
+func GenerateKey() (publicKey, privateKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv.Seed()), nil
+}
+
+// LoadPrivateKey reads a base64 private key written by GenerateKey
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key %s: %w", path, err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("invalid signing key %s: expected %d bytes, got %d", path, ed25519.SeedSize, len(raw))
+}
+
+// decodePublicKey parses a base64 ed25519 public key
+func decodePublicKey(key string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
@@ -0,0 +1,100 @@
+package signing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	publicKey, privateKey, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "pipeline.key")
+	if err := os.WriteFile(keyPath, []byte(privateKey+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	key, err := LoadPrivateKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+
+	data := []byte("# A comment\nid: \"feat-1\"\ntitle: \"Feature\"\ndescription: \"Do it\"\npriority: 2\n")
+	signed, err := SignFile(data, "pipeline", key)
+	if err != nil {
+		t.Fatalf("SignFile failed: %v", err)
+	}
+
+	// Keys are matched case-insensitively, as viper lowercases map keys
+	verifier, err := NewVerifier(Config{RequireSignedTickets: true, PublicKeys: map[string]string{"Pipeline": publicKey}})
+	if err != nil {
+		t.Fatalf("NewVerifier failed: %v", err)
+	}
+
+	// Timestamps and provenance are filled in after signing
+	stamped, _, err := ticket.Stamp(signed, ticket.Provenance{EnqueuedBy: "cli:alice"})
+	if err != nil {
+		t.Fatalf("Stamp failed: %v", err)
+	}
+	loaded, err := ticket.LoadFromBytes(stamped)
+	if err != nil {
+		t.Fatalf("Failed to load signed ticket: %v", err)
+	}
+	if err := verifier.Verify(loaded); err != nil {
+		t.Errorf("Expected signed ticket to verify, got %v", err)
+	}
+
+	tampered, err := ticket.LoadFromBytes([]byte(strings.Replace(string(signed), "priority: 2", "priority: 1", 1)))
+	if err != nil {
+		t.Fatalf("Failed to load tampered ticket: %v", err)
+	}
+	if err := verifier.Verify(tampered); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature for a tampered ticket, got %v", err)
+	}
+
+	unsigned := &ticket.Ticket{ID: "feat-2", Title: "Unsigned", Description: "x", Priority: 2}
+	if err := verifier.Verify(unsigned); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned, got %v", err)
+	}
+
+	loaded.Signature.Key = "someone-else"
+	if err := verifier.Verify(loaded); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestOptionalSignatures(t *testing.T) {
+	verifier, err := NewVerifier(Config{})
+	if err != nil || verifier != nil {
+		t.Fatalf("Expected no verifier without keys, got %v (err %v)", verifier, err)
+	}
+	if err := verifier.Verify(&ticket.Ticket{ID: "feat-1"}); err != nil {
+		t.Errorf("Expected a nil verifier to accept tickets, got %v", err)
+	}
+
+	publicKey, _, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	verifier, err = NewVerifier(Config{PublicKeys: map[string]string{"pipeline": publicKey}})
+	if err != nil {
+		t.Fatalf("NewVerifier failed: %v", err)
+	}
+	if err := verifier.Verify(&ticket.Ticket{ID: "feat-1"}); err != nil {
+		t.Errorf("Expected unsigned tickets to pass when signatures are optional, got %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{RequireSignedTickets: true}).Validate(); err == nil {
+		t.Error("Expected error for required signatures without keys")
+	}
+	if err := (Config{PublicKeys: map[string]string{"bad": "not-a-key"}}).Validate(); err == nil {
+		t.Error("Expected error for an invalid public key")
+	}
+}
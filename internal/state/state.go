@@ -0,0 +1,117 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// versionFile is the name of the file recording the on-disk format version
+const versionFile = "FORMAT_VERSION"
+
+// ErrNewerFormat is returned when the state directory was written by a newer binary
+var ErrNewerFormat = errors.New("state directory uses a newer format than this binary supports")
+
+// Migration upgrades the state directory from Version-1 to Version
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(dir string) error
+}
+
+// migrations lists every format change in order
+// Append new entries here whenever persistent state changes shape
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "initial versioned layout",
+		Apply:       func(dir string) error { return nil },
+	},
+}
+
+// CurrentVersion returns the format version written by this binary
+func CurrentVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Dir is an opened, up-to-date state directory
+type Dir struct {
+	Path    string
+	Version int
+}
+
+// Open prepares the state directory, running any pending migrations
+// It refuses to touch directories written by a newer orchestrator
+func Open(path string) (*Dir, error) {
+	return open(path, migrations)
+}
+
+func open(path string, migrations []Migration) (*Dir, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	version, err := ReadVersion(path)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := migrations[len(migrations)-1].Version
+	if version > latest {
+		return nil, fmt.Errorf("%w: found version %d, supported up to %d", ErrNewerFormat, version, latest)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+
+		log.Printf("Migrating state in %s to format version %d (%s)", path, m.Version, m.Description)
+		if err := m.Apply(path); err != nil {
+			return nil, fmt.Errorf("migration to version %d failed: %w", m.Version, err)
+		}
+
+		// Record progress after every step so an interrupted upgrade resumes cleanly
+		if err := writeVersion(path, m.Version); err != nil {
+			return nil, err
+		}
+		version = m.Version
+	}
+
+	return &Dir{Path: path, Version: version}, nil
+}
+
+// ReadVersion returns the format version of a state directory
+// A directory without a version file is treated as version 0
+func ReadVersion(path string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(path, versionFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read state version: %w", err)
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid state version %q: %w", strings.TrimSpace(string(data)), err)
+	}
+
+	return version, nil
+}
+
+// writeVersion atomically records the format version
+func writeVersion(path string, version int) error {
+	tmpPath := filepath.Join(path, versionFile+".tmp")
+	if err := os.WriteFile(tmpPath, []byte(strconv.Itoa(version)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write state version: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(path, versionFile)); err != nil {
+		return fmt.Errorf("failed to write state version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenInitializesVersion(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+
+	d, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if d.Version != CurrentVersion() {
+		t.Errorf("Expected version %d, got %d", CurrentVersion(), d.Version)
+	}
+
+	version, err := ReadVersion(dir)
+	if err != nil {
+		t.Fatalf("ReadVersion failed: %v", err)
+	}
+	if version != CurrentVersion() {
+		t.Errorf("Expected persisted version %d, got %d", CurrentVersion(), version)
+	}
+}
+
+func TestOpenRunsPendingMigrations(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeVersion(dir, 1); err != nil {
+		t.Fatalf("Failed to write version: %v", err)
+	}
+
+	var applied []int
+	testMigrations := []Migration{
+		{Version: 1, Apply: func(string) error { applied = append(applied, 1); return nil }},
+		{Version: 2, Apply: func(string) error { applied = append(applied, 2); return nil }},
+		{Version: 3, Apply: func(string) error { applied = append(applied, 3); return nil }},
+	}
+
+	d, err := open(dir, testMigrations)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	if d.Version != 3 {
+		t.Errorf("Expected version 3, got %d", d.Version)
+	}
+	if len(applied) != 2 || applied[0] != 2 || applied[1] != 3 {
+		t.Errorf("Expected migrations [2 3] to run, got %v", applied)
+	}
+}
+
+func TestOpenStopsOnFailedMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	testMigrations := []Migration{
+		{Version: 1, Apply: func(string) error { return nil }},
+		{Version: 2, Apply: func(string) error { return errors.New("boom") }},
+	}
+
+	if _, err := open(dir, testMigrations); err == nil {
+		t.Fatal("Expected migration failure, got nil")
+	}
+
+	// Progress up to the last successful migration is kept
+	version, err := ReadVersion(dir)
+	if err != nil {
+		t.Fatalf("ReadVersion failed: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Expected version 1 after failed migration, got %d", version)
+	}
+}
+
+func TestOpenRejectsNewerFormat(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, versionFile), []byte("999\n"), 0644); err != nil {
+		t.Fatalf("Failed to write version: %v", err)
+	}
+
+	_, err := Open(dir)
+	if !errors.Is(err, ErrNewerFormat) {
+		t.Errorf("Expected ErrNewerFormat, got %v", err)
+	}
+}
@@ -0,0 +1,433 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// S3Config selects an S3-compatible bucket, e.g. AWS S3, MinIO or R2
+type S3Config struct {
+	Endpoint     string `mapstructure:"endpoint"` // e.g. https://s3.us-east-1.amazonaws.com
+	Bucket       string `mapstructure:"bucket"`
+	Region       string `mapstructure:"region"`         // Defaults to us-east-1
+	Prefix       string `mapstructure:"prefix"`         // Optional key prefix
+	AccessKeyEnv string `mapstructure:"access_key_env"` // Defaults to AWS_ACCESS_KEY_ID
+	SecretKeyEnv string `mapstructure:"secret_key_env"` // Defaults to AWS_SECRET_ACCESS_KEY
+}
+
+// S3Store uploads files with path-style requests signed with AWS
+// Signature Version 4
+type S3Store struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	prefix    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewS3Store creates a store for the bucket, reading credentials from the
+// configured environment variables
+func NewS3Store(config S3Config) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	accessKeyEnv := config.AccessKeyEnv
+	if accessKeyEnv == "" {
+		accessKeyEnv = "AWS_ACCESS_KEY_ID"
+	}
+	secretKeyEnv := config.SecretKeyEnv
+	if secretKeyEnv == "" {
+		secretKeyEnv = "AWS_SECRET_ACCESS_KEY"
+	}
+
+	accessKey, secretKey := os.Getenv(accessKeyEnv), os.Getenv(secretKeyEnv)
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("S3 credentials not set: export %s and %s", accessKeyEnv, secretKeyEnv)
+	}
+
+	return &S3Store{
+		endpoint:  endpoint,
+		bucket:    config.Bucket,
+		region:    region,
+		prefix:    strings.Trim(config.Prefix, "/"),
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 10 * time.Minute},
+		now:       time.Now,
+	}, nil
+}
+
+// Put uploads the file to <bucket>/<prefix>/<key> and returns its URL
+func (s *S3Store) Put(ctx context.Context, key, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	objectURL := s.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), file)
+	if err != nil {
+		return "", fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	req.ContentLength = info.Size()
+	s.sign(req, unsignedPayload)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("S3 upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("S3 upload returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return objectURL.String(), nil
+}
+
+// unsignedPayload lets uploads stream without hashing the body first
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// ErrNotFound is returned by GetObject for keys that don't exist
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object; keys are relative to the store prefix
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// objectURL returns the URL of <bucket>/<prefix>/<key>
+func (s *S3Store) objectURL(key string) *url.URL {
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	objectURL := *s.endpoint
+	objectURL.Path = s.endpoint.Path + "/" + s.bucket + "/" + key
+	return &objectURL
+}
+
+// do signs and sends a request, returning the response if it has one of the
+// accepted status codes
+func (s *S3Store) do(req *http.Request, payloadHash string, accepted ...int) (*http.Response, error) {
+	s.sign(req, payloadHash)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range accepted {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// GetObject downloads <bucket>/<prefix>/<key>
+func (s *S3Store) GetObject(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	resp, err := s.do(req, hexSHA256(""), http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("S3 download of %s failed: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("S3 download of %s failed: %w", key, err)
+	}
+	return data, nil
+}
+
+// PutObject uploads data to <bucket>/<prefix>/<key>
+func (s *S3Store) PutObject(ctx context.Context, key string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	resp, err := s.do(req, hexSHA256(string(data)), http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("S3 upload of %s failed: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteObject removes <bucket>/<prefix>/<key>; missing keys are not an error
+func (s *S3Store) DeleteObject(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	resp, err := s.do(req, hexSHA256(""), http.StatusOK, http.StatusNoContent)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("S3 delete of %s failed: %w", key, err)
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// listBucketResult is the ListObjectsV2 response body
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListObjects returns every object whose key starts with prefix, following
+// ListObjectsV2 continuation tokens
+func (s *S3Store) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	fullPrefix := prefix
+	if s.prefix != "" {
+		fullPrefix = s.prefix + "/" + prefix
+	}
+
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {fullPrefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		bucketURL := *s.endpoint
+		bucketURL.Path = s.endpoint.Path + "/" + s.bucket
+		bucketURL.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, bucketURL.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 request: %w", err)
+		}
+		resp, err := s.do(req, hexSHA256(""), http.StatusOK)
+		if err != nil {
+			return nil, fmt.Errorf("S3 list of %s failed: %w", prefix, err)
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse S3 listing: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			key := c.Key
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, s.prefix+"/")
+			}
+			objects = append(objects, ObjectInfo{Key: key, Size: c.Size, LastModified: c.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// lifecycleRulePrefix starts the IDs of the rules ApplyLifecycle sets
+const lifecycleRulePrefix = "orchestrator-"
+
+// ApplyLifecycle sets rules expiring objects under each prefix, relative to
+// the store prefix. The bucket's configuration is read first and merged by
+// rule ID, so rules set by anyone else are kept
+func (s *S3Store) ApplyLifecycle(ctx context.Context, rules []LifecycleRule) error {
+	existing, err := s.getLifecycle(ctx)
+	if err != nil {
+		return err
+	}
+
+	var managed []lifecycleRule
+	ids := make(map[string]bool)
+	for _, rule := range rules {
+		prefix := rule.Prefix
+		if s.prefix != "" {
+			prefix = s.prefix + "/" + prefix
+		}
+		id := lifecycleRulePrefix + strings.Trim(prefix, "/")
+		ids[id] = true
+		managed = append(managed, lifecycleRule{
+			Inner: fmt.Sprintf("<ID>%s</ID><Filter><Prefix>%s</Prefix></Filter><Status>Enabled</Status><Expiration><Days>%d</Days></Expiration>", xmlEscape(id), xmlEscape(prefix), rule.Days),
+		})
+	}
+
+	config := lifecycleConfiguration{Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	for _, rule := range existing.Rules {
+		if !ids[rule.id()] {
+			config.Rules = append(config.Rules, rule)
+		}
+	}
+	config.Rules = append(config.Rules, managed...)
+
+	body, err := xml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lifecycle configuration: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.lifecycleURL().String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	sum := md5.Sum(body)
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := s.do(req, hexSHA256(string(body)), http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("S3 lifecycle update failed: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// getLifecycle reads the bucket's lifecycle configuration, which is empty
+// when the bucket has none
+func (s *S3Store) getLifecycle(ctx context.Context) (*lifecycleConfiguration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.lifecycleURL().String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	resp, err := s.do(req, hexSHA256(""), http.StatusOK)
+	if errors.Is(err, ErrNotFound) {
+		// NoSuchLifecycleConfiguration
+		return &lifecycleConfiguration{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("S3 lifecycle read failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var config lifecycleConfiguration
+	if err := xml.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse lifecycle configuration: %w", err)
+	}
+	return &config, nil
+}
+
+// lifecycleURL returns the URL of the bucket's lifecycle subresource
+func (s *S3Store) lifecycleURL() *url.URL {
+	bucketURL := *s.endpoint
+	bucketURL.Path = s.endpoint.Path + "/" + s.bucket
+	bucketURL.RawQuery = "lifecycle="
+	return &bucketURL
+}
+
+// lifecycleConfiguration is the body of the bucket lifecycle requests
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Xmlns   string          `xml:"xmlns,attr,omitempty"`
+	Rules   []lifecycleRule `xml:"Rule"`
+}
+
+// lifecycleRule keeps a rule's XML as is, so rules set by others survive
+// being read and written back
+type lifecycleRule struct {
+	Inner string `xml:",innerxml"`
+}
+
+// id returns the rule's ID
+func (r lifecycleRule) id() string {
+	var rule struct {
+		ID string `xml:"ID"`
+	}
+	xml.Unmarshal([]byte("<Rule>"+r.Inner+"</Rule>"), &rule)
+	return rule.ID
+}
+
+// xmlEscape escapes text for an XML element
+func xmlEscape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// sign adds SigV4 headers to a request whose body has the given SHA-256 hex
+// digest, or unsignedPayload
+func (s *S3Store) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestS3StorePut(t *testing.T) {
+	var gotPath, gotBody, gotAuth, gotDate string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Expected PUT, got %s", r.Method)
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotBody = r.URL.Path, string(body)
+		gotAuth, gotDate = r.Header.Get("Authorization"), r.Header.Get("x-amz-date")
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_S3_KEY", "AKIDEXAMPLE")
+	t.Setenv("TEST_S3_SECRET", "secret")
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:     server.URL,
+		Bucket:       "builds",
+		Region:       "eu-west-1",
+		Prefix:       "/orchestrator/",
+		AccessKeyEnv: "TEST_S3_KEY",
+		SecretKeyEnv: "TEST_S3_SECRET",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store failed: %v", err)
+	}
+	store.now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	file := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(file, []byte("binary"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	url, err := store.Put(context.Background(), "feat-1/dist/app.bin", file)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if gotPath != "/builds/orchestrator/feat-1/dist/app.bin" {
+		t.Errorf("Unexpected object path %s", gotPath)
+	}
+	if url != server.URL+gotPath {
+		t.Errorf("Expected URL %s, got %s", server.URL+gotPath, url)
+	}
+	if gotBody != "binary" {
+		t.Errorf("Expected uploaded body, got %q", gotBody)
+	}
+	if gotDate != "20250102T030405Z" {
+		t.Errorf("Unexpected x-amz-date %s", gotDate)
+	}
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20250102/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(gotAuth, wantPrefix) || len(gotAuth) != len(wantPrefix)+64 {
+		t.Errorf("Unexpected Authorization header %q", gotAuth)
+	}
+}
+
+func TestS3StoreErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<Error>AccessDenied</Error>", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	if _, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "builds"}); err == nil {
+		t.Error("Expected error without credentials, got nil")
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	store, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "builds"})
+	if err != nil {
+		t.Fatalf("NewS3Store failed: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "app.bin")
+	os.WriteFile(file, []byte("binary"), 0644)
+	if _, err := store.Put(context.Background(), "feat-1/app.bin", file); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Expected AccessDenied error, got %v", err)
+	}
+}
+
+func TestS3StoreApplyLifecycle(t *testing.T) {
+	// The bucket already has a rule of its own and an outdated one of ours
+	existing := `<LifecycleConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+		`<Rule><ID>team-backups</ID><Filter><Prefix>backups/</Prefix></Filter><Status>Enabled</Status><Transition><Days>30</Days><StorageClass>GLACIER</StorageClass></Transition></Rule>` +
+		`<Rule><ID>orchestrator-orch/logs</ID><Filter><Prefix>orch/logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>30</Days></Expiration></Rule>` +
+		`</LifecycleConfiguration>`
+	var gotQuery, gotMD5, gotBody, gotHash string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(existing))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotQuery, gotBody = r.URL.RawQuery, string(body)
+		gotMD5, gotHash = r.Header.Get("Content-MD5"), r.Header.Get("x-amz-content-sha256")
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	store, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "builds", Prefix: "orch"})
+	if err != nil {
+		t.Fatalf("NewS3Store failed: %v", err)
+	}
+
+	rules := Lifecycle{LogsDays: 7, ArtifactsDays: 90}.Rules()
+	if err := store.ApplyLifecycle(context.Background(), rules); err != nil {
+		t.Fatalf("ApplyLifecycle failed: %v", err)
+	}
+
+	if gotQuery != "lifecycle=" {
+		t.Errorf("Expected lifecycle query, got %q", gotQuery)
+	}
+	if gotMD5 == "" || gotHash == unsignedPayload {
+		t.Errorf("Expected Content-MD5 and a signed payload hash, got %q and %q", gotMD5, gotHash)
+	}
+	for _, want := range []string{
+		"<ID>team-backups</ID><Filter><Prefix>backups/</Prefix></Filter><Status>Enabled</Status><Transition><Days>30</Days><StorageClass>GLACIER</StorageClass></Transition>",
+		"<Filter><Prefix>orch/logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>7</Days></Expiration>",
+		"<Filter><Prefix>orch/artifacts/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>90</Days></Expiration>",
+	} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("Expected lifecycle body to contain %s, got %s", want, gotBody)
+		}
+	}
+	if strings.Contains(gotBody, "<Days>30</Days></Expiration>") || strings.Count(gotBody, "<Rule>") != 3 {
+		t.Errorf("Expected the outdated logs rule to be replaced, got %s", gotBody)
+	}
+	if strings.Contains(gotBody, "ci/") {
+		t.Errorf("Expected no rule for CI outputs without a retention, got %s", gotBody)
+	}
+}
+
+func TestS3StoreObjects(t *testing.T) {
+	// A fake bucket that lists one key per page to exercise continuation
+	objects := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("Unsigned %s %s", r.Method, r.URL)
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/builds/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			var keys []string
+			for k := range objects {
+				if strings.HasPrefix(k, r.URL.Query().Get("prefix")) && k > r.URL.Query().Get("continuation-token") {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			fmt.Fprint(w, "<ListBucketResult>")
+			if len(keys) > 0 {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>2025-01-02T03:04:05.000Z</LastModified></Contents>", keys[0], len(objects[keys[0]]))
+			}
+			if len(keys) > 1 {
+				fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>", keys[0])
+			}
+			fmt.Fprint(w, "</ListBucketResult>")
+		case r.Method == http.MethodGet:
+			data, ok := objects[key]
+			if !ok {
+				http.Error(w, "<Error>NoSuchKey</Error>", http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, data)
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[key] = string(body)
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	store, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "builds", Prefix: "orch"})
+	if err != nil {
+		t.Fatalf("NewS3Store failed: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"ci-status/aaa.json", "ci-status/bbb.json", "logs/feat-1.log"} {
+		if err := store.PutObject(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("PutObject failed: %v", err)
+		}
+	}
+	if data, err := store.GetObject(ctx, "ci-status/aaa.json"); err != nil || string(data) != "ci-status/aaa.json" {
+		t.Errorf("Unexpected object %q (err %v)", data, err)
+	}
+	if _, err := store.GetObject(ctx, "ci-status/zzz.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	listed, err := store.ListObjects(ctx, "ci-status/")
+	if err != nil {
+		t.Fatalf("ListObjects failed: %v", err)
+	}
+	if len(listed) != 2 || listed[0].Key != "ci-status/aaa.json" || listed[1].Size != int64(len("ci-status/bbb.json")) || listed[0].LastModified.IsZero() {
+		t.Errorf("Expected both statuses relative to the store prefix, got %+v", listed)
+	}
+
+	if err := store.DeleteObject(ctx, "ci-status/aaa.json"); err != nil {
+		t.Fatalf("DeleteObject failed: %v", err)
+	}
+	if _, ok := objects["orch/ci-status/aaa.json"]; ok {
+		t.Error("Expected the object deleted")
+	}
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Store backend names accepted in Config
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Config selects a store: a local directory or an S3-compatible bucket
+type Config struct {
+	Backend string   `mapstructure:"backend"` // local or s3; defaults to local
+	Dir     string   `mapstructure:"dir"`     // Local backend root directory
+	S3      S3Config `mapstructure:"s3"`
+}
+
+// Validate checks the settings required by the chosen backend
+func (c Config) Validate() error {
+	switch c.Backend {
+	case "", BackendLocal:
+	case BackendS3:
+		if c.S3.Endpoint == "" || c.S3.Bucket == "" {
+			return errors.New("s3.endpoint and s3.bucket are required for the s3 backend")
+		}
+	default:
+		return fmt.Errorf("unknown backend %q (expected local or s3)", c.Backend)
+	}
+	return nil
+}
+
+// Store saves files under slash-separated keys and returns a URL for each
+type Store interface {
+	Put(ctx context.Context, key, path string) (string, error)
+}
+
+// NewStore creates the configured store
+func NewStore(config Config) (Store, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Backend == BackendS3 {
+		return NewS3Store(config.S3)
+	}
+	if config.Dir == "" {
+		return nil, errors.New("dir is required for the local backend")
+	}
+	return &LocalStore{Dir: config.Dir}, nil
+}
+
+// LocalStore copies files into a directory tree
+type LocalStore struct {
+	Dir string
+}
+
+// Put copies the file to <Dir>/<key> and returns its file:// URL
+func (s *LocalStore) Put(ctx context.Context, key, path string) (string, error) {
+	dest, err := filepath.Abs(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute store path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	// Copy to a temporary file first so readers never see a partial file
+	tmp := dest + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return "", fmt.Errorf("failed to create stored file: %w", err)
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to copy file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write stored file: %w", err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return "", fmt.Errorf("failed to write stored file: %w", err)
+	}
+
+	return "file://" + filepath.ToSlash(dest), nil
+}
+
+// PutBytes stores data under key through a temporary file
+func PutBytes(ctx context.Context, store Store, key string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp("", "orchestrator-upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write upload file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write upload file: %w", err)
+	}
+
+	return store.Put(ctx, key, tmp.Name())
+}
+
+// Key prefixes for the kinds of objects the orchestrator stores
+const (
+	LogsPrefix      = "logs/"
+	CIPrefix        = "ci/"
+	ArtifactsPrefix = "artifacts/"
+)
+
+// LifecycleRule expires objects under a key prefix after a number of days
+type LifecycleRule struct {
+	Prefix string
+	Days   int
+}
+
+// Lifecycle sets how long each kind of object is kept; 0 keeps it forever
+type Lifecycle struct {
+	LogsDays      int `mapstructure:"logs_days"`
+	CIDays        int `mapstructure:"ci_days"`
+	ArtifactsDays int `mapstructure:"artifacts_days"`
+}
+
+// Validate rejects negative retention
+func (l Lifecycle) Validate() error {
+	if l.LogsDays < 0 || l.CIDays < 0 || l.ArtifactsDays < 0 {
+		return errors.New("lifecycle days cannot be negative")
+	}
+	return nil
+}
+
+// Rules returns an expiration rule for every kind with a retention set
+func (l Lifecycle) Rules() []LifecycleRule {
+	var rules []LifecycleRule
+	for _, rule := range []LifecycleRule{
+		{Prefix: LogsPrefix, Days: l.LogsDays},
+		{Prefix: CIPrefix, Days: l.CIDays},
+		{Prefix: ArtifactsPrefix, Days: l.ArtifactsDays},
+	} {
+		if rule.Days > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorePut(t *testing.T) {
+	storeDir := t.TempDir()
+	src := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(src, []byte("binary"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	store, err := NewStore(Config{Backend: BackendLocal, Dir: storeDir})
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+
+	url, err := store.Put(context.Background(), "feat-1/dist/app.bin", src)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	dest := filepath.Join(storeDir, "feat-1", "dist", "app.bin")
+	if url != "file://"+filepath.ToSlash(dest) {
+		t.Errorf("Expected URL for %s, got %s", dest, url)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != "binary" {
+		t.Errorf("Expected copied file, got %q (err %v)", data, err)
+	}
+
+	if _, err := NewStore(Config{Backend: BackendLocal}); err == nil {
+		t.Error("Expected error for local store without dir, got nil")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := []Config{
+		{},
+		{Dir: "./artifacts"},
+		{Backend: BackendS3, S3: S3Config{Endpoint: "https://s3.example.com", Bucket: "builds"}},
+	}
+	for _, config := range valid {
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", config, err)
+		}
+	}
+
+	invalid := []Config{
+		{Backend: BackendS3, S3: S3Config{Endpoint: "https://s3.example.com"}},
+		{Backend: "ftp", Dir: "./artifacts"},
+	}
+	for _, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for %+v, got nil", config)
+		}
+	}
+}
+
+func TestLifecycleRules(t *testing.T) {
+	rules := Lifecycle{LogsDays: 7, CIDays: 30}.Rules()
+	if len(rules) != 2 || rules[0] != (LifecycleRule{Prefix: LogsPrefix, Days: 7}) || rules[1] != (LifecycleRule{Prefix: CIPrefix, Days: 30}) {
+		t.Errorf("Unexpected rules %+v", rules)
+	}
+
+	if err := (Lifecycle{CIDays: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative retention, got nil")
+	}
+}
+
+func TestPutBytes(t *testing.T) {
+	storeDir := t.TempDir()
+	store := &LocalStore{Dir: storeDir}
+
+	if _, err := PutBytes(context.Background(), store, "logs/feat-1/agent.log", []byte("output")); err != nil {
+		t.Fatalf("PutBytes failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(storeDir, "logs", "feat-1", "agent.log")); err != nil || string(data) != "output" {
+		t.Errorf("Expected stored log, got %q (err %v)", data, err)
+	}
+}
@@ -0,0 +1,457 @@
+// Package summary writes a weekly summary of the orchestrator's work to the
+// metrics directory: tickets completed and failed, failures by error code,
+// the flakiest test packages, agent and CI time with their estimated cost,
+// and how throughput has trended over the preceding weeks
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/throughput"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+)
+
+// Output formats
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Bounds on what a summary lists
+const (
+	topFlaky       = 5  // Flakiest packages listed
+	failureTickets = 5  // Tickets named per error code
+	maxBar         = 40 // Widest trend bar
+)
+
+// checkInterval is how often the scheduler looks for a finished week
+const checkInterval = time.Hour
+
+// Config controls weekly summaries
+type Config struct {
+	Enabled          bool    `mapstructure:"enabled"`             // Write each finished week's summary from the daemon
+	Format           string  `mapstructure:"format"`              // markdown or html
+	TrendWeeks       int     `mapstructure:"trend_weeks"`         // Weeks of throughput in the trend, this one included
+	AgentCostPerHour float64 `mapstructure:"agent_cost_per_hour"` // Estimated cost of an hour of agent time; zero leaves it out
+	CICostPerHour    float64 `mapstructure:"ci_cost_per_hour"`    // Estimated cost of an hour of CI time; zero leaves it out
+}
+
+// Validate checks the summary settings
+func (c Config) Validate() error {
+	if c.Format != FormatMarkdown && c.Format != FormatHTML {
+		return fmt.Errorf("format must be %s or %s, got %q", FormatMarkdown, FormatHTML, c.Format)
+	}
+	if c.TrendWeeks < 1 {
+		return errors.New("trend_weeks must be at least 1")
+	}
+	if c.AgentCostPerHour < 0 || c.CICostPerHour < 0 {
+		return errors.New("costs per hour cannot be negative")
+	}
+	return nil
+}
+
+// Sources are where a summary's data is read from
+type Sources struct {
+	MetricsDir string             // Throughput and flaky test CSVs; summaries are written here too
+	StateDir   string             // Timeline journal with failures and phases
+	Cipher     *encryption.Cipher // Decrypts the journal
+}
+
+// FailureCount is how often tickets failed with an error code
+type FailureCount struct {
+	Code    string
+	Count   int
+	Tickets []string // Up to failureTickets, in first-failed order
+}
+
+// WeekCount is the tickets completed and failed in a week
+type WeekCount struct {
+	Start     time.Time
+	Completed int
+	Failed    int
+}
+
+// Summary is a week of work
+type Summary struct {
+	Start       time.Time // Monday 00:00 in the configured timezone
+	End         time.Time // The next Monday
+	Completed   int
+	Failed      int
+	AvgDuration time.Duration // Of completions with a known start
+	Failures    []FailureCount
+	Flaky       []ci.FlakyStat
+	AgentTime   time.Duration
+	CITime      time.Duration
+	Cost        float64 // Estimated from the configured rates
+	Trend       []WeekCount
+	Generated   time.Time
+}
+
+// WeekStart returns the start of the week t is in: Monday 00:00 in loc
+func WeekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// Build summarises the week starting at weekStart, which WeekStart returns
+func Build(config Config, sources Sources, weekStart time.Time) (*Summary, error) {
+	completions, err := throughput.Load(sources.MetricsDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := timeline.Load(sources.StateDir, sources.Cipher, "")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Summary{Start: weekStart, End: weekStart.AddDate(0, 0, 7), Generated: time.Now()}
+	if s.Flaky, err = ci.LoadFlakyStatsBetween(sources.MetricsDir, s.Start, s.End); err != nil {
+		return nil, err
+	}
+	if len(s.Flaky) > topFlaky {
+		s.Flaky = s.Flaky[:topFlaky]
+	}
+
+	// Throughput for this week and the ones before it
+	weeks := max(config.TrendWeeks, 1)
+	for i := weeks - 1; i >= 0; i-- {
+		s.Trend = append(s.Trend, WeekCount{Start: weekStart.AddDate(0, 0, -7*i)})
+	}
+	week := func(t time.Time) int {
+		if !t.Before(s.End) {
+			return -1
+		}
+		for i := len(s.Trend) - 1; i >= 0; i-- {
+			if !t.Before(s.Trend[i].Start) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var timed int
+	var total time.Duration
+	for _, c := range completions {
+		if i := week(c.Time); i >= 0 {
+			s.Trend[i].Completed++
+		}
+		if c.Time.Before(s.Start) || !c.Time.Before(s.End) {
+			continue
+		}
+		s.Completed++
+		if c.Duration > 0 {
+			timed++
+			total += c.Duration
+		}
+	}
+	if timed > 0 {
+		s.AvgDuration = total / time.Duration(timed)
+	}
+
+	failures := make(map[string]*FailureCount)
+	byTicket := make(map[string][]timeline.Entry)
+	for _, entry := range entries {
+		byTicket[entry.Ticket] = append(byTicket[entry.Ticket], entry)
+		if entry.Event != string(ipc.EventTypeTicketFailed) {
+			continue
+		}
+		if i := week(entry.Time); i >= 0 {
+			s.Trend[i].Failed++
+		}
+		if entry.Time.Before(s.Start) || !entry.Time.Before(s.End) {
+			continue
+		}
+		s.Failed++
+		code := entry.Code
+		if code == "" {
+			code = "unknown"
+		}
+		failure, ok := failures[code]
+		if !ok {
+			failure = &FailureCount{Code: code}
+			failures[code] = failure
+		}
+		failure.Count++
+		if len(failure.Tickets) < failureTickets && !contains(failure.Tickets, entry.Ticket) {
+			failure.Tickets = append(failure.Tickets, entry.Ticket)
+		}
+	}
+	for _, failure := range failures {
+		s.Failures = append(s.Failures, *failure)
+	}
+	sort.Slice(s.Failures, func(i, j int) bool {
+		if s.Failures[i].Count != s.Failures[j].Count {
+			return s.Failures[i].Count > s.Failures[j].Count
+		}
+		return s.Failures[i].Code < s.Failures[j].Code
+	})
+
+	// Agent and CI time are the phases of that name that started this week
+	for id, ticketEntries := range byTicket {
+		for _, step := range timeline.Build(id, ticketEntries, nil, nil).Steps {
+			if step.Start.Before(s.Start) || !step.Start.Before(s.End) {
+				continue
+			}
+			switch step.Name {
+			case "agent":
+				s.AgentTime += step.Duration()
+			case "ci":
+				s.CITime += step.Duration()
+			}
+		}
+	}
+	s.Cost = s.AgentTime.Hours()*config.AgentCostPerHour + s.CITime.Hours()*config.CICostPerHour
+	return s, nil
+}
+
+// FileName names a week's summary after its ISO week, e.g. weekly-2025-W23.md
+func FileName(weekStart time.Time, format string) string {
+	year, week := weekStart.ISOWeek()
+	ext := ".md"
+	if format == FormatHTML {
+		ext = ".html"
+	}
+	return fmt.Sprintf("weekly-%d-W%02d%s", year, week, ext)
+}
+
+// Write builds the summary of the week starting at weekStart and writes it to
+// the metrics directory, returning its path
+func Write(config Config, sources Sources, weekStart time.Time) (string, error) {
+	s, err := Build(config, sources, weekStart)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(sources.MetricsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+	path := filepath.Join(sources.MetricsDir, FileName(weekStart, config.Format))
+	if err := os.WriteFile(path, []byte(Render(s, config, config.Format)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write weekly summary: %w", err)
+	}
+	return path, nil
+}
+
+// Scheduler writes each week's summary once the week is over
+type Scheduler struct {
+	config  Config
+	sources Sources
+}
+
+// NewScheduler returns a scheduler writing summaries from sources
+func NewScheduler(config Config, sources Sources) *Scheduler {
+	return &Scheduler{config: config, sources: sources}
+}
+
+// Run writes the last finished week's summary, if it has not been written,
+// now and every hour until ctx is done; a daemon that was down over the
+// weekend catches up when it starts
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		s.check(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check writes the summary of the week before now's, unless it exists
+func (s *Scheduler) check(now time.Time) {
+	lastWeek := WeekStart(now, timefmt.Location()).AddDate(0, 0, -7)
+	path := filepath.Join(s.sources.MetricsDir, FileName(lastWeek, s.config.Format))
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	if path, err := Write(s.config, s.sources, lastWeek); err != nil {
+		log.Printf("Failed to write weekly summary: %v", err)
+	} else {
+		log.Printf("Wrote weekly summary to %s", path)
+	}
+}
+
+// section is a heading with paragraphs and an optional table, rendered as
+// Markdown or HTML
+type section struct {
+	heading string
+	lines   []string
+	header  []string
+	rows    [][]string
+}
+
+// Render formats a summary as Markdown or HTML
+func Render(s *Summary, config Config, format string) string {
+	last := s.End.AddDate(0, 0, -1)
+	title := fmt.Sprintf("Weekly summary: %s to %s", s.Start.Format("Mon 2 Jan"), last.Format("Mon 2 Jan 2006"))
+
+	tickets := section{heading: "Tickets", lines: []string{fmt.Sprintf("%d completed, %d failed", s.Completed, s.Failed)}}
+	if attempts := s.Completed + s.Failed; attempts > 0 {
+		tickets.lines[0] += fmt.Sprintf(" (%.0f%% success)", 100*float64(s.Completed)/float64(attempts))
+	}
+	if len(s.Trend) > 1 {
+		previous := s.Trend[len(s.Trend)-2].Completed
+		tickets.lines = append(tickets.lines, fmt.Sprintf("Previous week: %d completed (%s)", previous, change(s.Completed, previous)))
+	}
+	if s.AvgDuration > 0 {
+		tickets.lines = append(tickets.lines, "Average time per ticket: "+timeline.FormatDuration(s.AvgDuration))
+	}
+
+	failures := section{heading: "Failures by error code"}
+	if len(s.Failures) == 0 {
+		failures.lines = []string{"No tickets failed"}
+	} else {
+		failures.header = []string{"Code", "Failures", "Tickets"}
+		for _, f := range s.Failures {
+			failures.rows = append(failures.rows, []string{f.Code, fmt.Sprint(f.Count), strings.Join(f.Tickets, ", ")})
+		}
+	}
+
+	flaky := section{heading: "Top flaky tests"}
+	if len(s.Flaky) == 0 {
+		flaky.lines = []string{"No flaky test packages"}
+	} else {
+		flaky.header = []string{"Package", "Flakes", "Tickets"}
+		for _, f := range s.Flaky {
+			flaky.rows = append(flaky.rows, []string{f.Package, fmt.Sprint(f.Count), strings.Join(f.Tickets, ", ")})
+		}
+	}
+
+	cost := section{heading: "Cost", header: []string{"", "Time", "Estimated cost"}}
+	costRow := func(name string, d time.Duration, rate float64) []string {
+		estimate := "-"
+		if rate > 0 {
+			estimate = fmt.Sprintf("%.2f", d.Hours()*rate)
+		}
+		return []string{name, timeline.FormatDuration(d), estimate}
+	}
+	cost.rows = [][]string{
+		costRow("Agent", s.AgentTime, config.AgentCostPerHour),
+		costRow("CI", s.CITime, config.CICostPerHour),
+	}
+	if config.AgentCostPerHour > 0 || config.CICostPerHour > 0 {
+		cost.rows = append(cost.rows, []string{"Total", timeline.FormatDuration(s.AgentTime + s.CITime), fmt.Sprintf("%.2f", s.Cost)})
+		if s.Completed > 0 {
+			cost.lines = []string{fmt.Sprintf("%.2f per completed ticket", s.Cost/float64(s.Completed))}
+		}
+	} else {
+		cost.lines = []string{"Set metrics.summary.agent_cost_per_hour and ci_cost_per_hour to estimate costs"}
+	}
+
+	trend := section{heading: "Throughput trend", header: []string{"Week of", "Completed", "Failed", ""}}
+	most := 0
+	for _, w := range s.Trend {
+		most = max(most, w.Completed)
+	}
+	for _, w := range s.Trend {
+		bar := ""
+		if most > 0 {
+			bar = strings.Repeat("#", w.Completed*maxBar/most)
+		}
+		trend.rows = append(trend.rows, []string{w.Start.Format("2006-01-02"), fmt.Sprint(w.Completed), fmt.Sprint(w.Failed), bar})
+	}
+
+	sections := []section{tickets, failures, flaky, cost, trend}
+	footer := "Generated " + timefmt.Timestamp(s.Generated)
+	if format == FormatHTML {
+		return renderHTML(title, sections, footer)
+	}
+	return renderMarkdown(title, sections, footer)
+}
+
+// change describes the difference from previous, e.g. +3 or -20%
+func change(current, previous int) string {
+	if previous == 0 {
+		return fmt.Sprintf("%+d", current)
+	}
+	return fmt.Sprintf("%+.0f%%", 100*float64(current-previous)/float64(previous))
+}
+
+func renderMarkdown(title string, sections []section, footer string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+	cell := func(s string) string {
+		if s == "" {
+			return " "
+		}
+		return strings.ReplaceAll(s, "|", "\\|")
+	}
+	for _, sec := range sections {
+		fmt.Fprintf(&b, "\n## %s\n\n", sec.heading)
+		for _, line := range sec.lines {
+			fmt.Fprintf(&b, "%s\n\n", line)
+		}
+		if len(sec.header) == 0 {
+			continue
+		}
+		row := func(cells []string) {
+			for i := range cells {
+				cells[i] = cell(cells[i])
+			}
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+		}
+		row(append([]string{}, sec.header...))
+		b.WriteString("|" + strings.Repeat(" --- |", len(sec.header)) + "\n")
+		for _, r := range sec.rows {
+			row(append([]string{}, r...))
+		}
+	}
+	fmt.Fprintf(&b, "\n_%s_\n", footer)
+	return b.String()
+}
+
+func renderHTML(title string, sections []section, footer string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", html.EscapeString(title))
+	b.WriteString("<style>body{font-family:sans-serif;max-width:60em;margin:2em auto}table{border-collapse:collapse}th,td{border:1px solid #ccc;padding:.3em .6em;text-align:left}</style>\n")
+	fmt.Fprintf(&b, "</head>\n<body>\n<h1>%s</h1>\n", html.EscapeString(title))
+	for _, sec := range sections {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(sec.heading))
+		for _, line := range sec.lines {
+			fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(line))
+		}
+		if len(sec.header) == 0 {
+			continue
+		}
+		b.WriteString("<table>\n<tr>")
+		for _, h := range sec.header {
+			fmt.Fprintf(&b, "<th>%s</th>", html.EscapeString(h))
+		}
+		b.WriteString("</tr>\n")
+		for _, r := range sec.rows {
+			b.WriteString("<tr>")
+			for _, c := range r {
+				fmt.Fprintf(&b, "<td>%s</td>", html.EscapeString(c))
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</table>\n")
+	}
+	fmt.Fprintf(&b, "<p><em>%s</em></p>\n</body>\n</html>\n", html.EscapeString(footer))
+	return b.String()
+}
+
+// contains reports whether s is in list
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package summary
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/throughput"
+	"github.com/brettsmith212/amp-orchestrator/internal/timeline"
+)
+
+// week is Monday 2 June 2025 in UTC
+var week = time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+// newTestSources records a week of work: three completions, two of them this
+// week, a CI failure and an auth failure, a flaky package and one ticket
+// with an hour with the agent and half an hour in CI
+func newTestSources(t *testing.T) Sources {
+	t.Helper()
+	sources := Sources{MetricsDir: t.TempDir(), StateDir: t.TempDir()}
+
+	for _, c := range []throughput.Completion{
+		{Time: week.Add(-3 * 24 * time.Hour), TicketID: "feat-0", WorkerID: 1},
+		{Time: week.Add(26 * time.Hour), TicketID: "feat-1", WorkerID: 1, Duration: 90 * time.Minute},
+		{Time: week.Add(50 * time.Hour), TicketID: "feat-2", WorkerID: 2, Duration: 30 * time.Minute},
+	} {
+		if err := throughput.Append(sources.MetricsDir, c); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if err := ci.RecordFlaky(sources.MetricsDir, &ci.Status{TicketID: "feat-1", Timestamp: week.Add(25 * time.Hour), Flaky: []string{"./internal/api"}}); err != nil {
+		t.Fatalf("RecordFlaky failed: %v", err)
+	}
+
+	journal := timeline.Open(sources.StateDir, nil)
+	start := week.Add(24 * time.Hour)
+	for _, entry := range []timeline.Entry{
+		{Time: start, Ticket: "feat-1", Event: string(ipc.EventTypeTicketStarted)},
+		{Time: start, Ticket: "feat-1", Event: string(ipc.EventTypeTicketPhase), Phase: "agent"},
+		{Time: start.Add(time.Hour), Ticket: "feat-1", Event: string(ipc.EventTypeTicketPhase), Phase: "ci"},
+		{Time: start.Add(90 * time.Minute), Ticket: "feat-1", Event: string(ipc.EventTypeTicketComplete)},
+		{Time: start.Add(3 * time.Hour), Ticket: "bug-1", Event: string(ipc.EventTypeTicketFailed), Code: string(ipc.ErrorCodeCIFailed)},
+		{Time: start.Add(4 * time.Hour), Ticket: "bug-2", Event: string(ipc.EventTypeTicketFailed), Code: string(ipc.ErrorCodeAuth)},
+		{Time: start.Add(5 * time.Hour), Ticket: "bug-1", Event: string(ipc.EventTypeTicketFailed), Code: string(ipc.ErrorCodeCIFailed)},
+		{Time: week.Add(-time.Hour), Ticket: "old-1", Event: string(ipc.EventTypeTicketFailed), Code: string(ipc.ErrorCodeTimeout)},
+	} {
+		if err := journal.Append(entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	return sources
+}
+
+func TestBuild(t *testing.T) {
+	config := Config{Format: FormatMarkdown, TrendWeeks: 2, AgentCostPerHour: 10, CICostPerHour: 2}
+	s, err := Build(config, newTestSources(t), week)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if s.Completed != 2 || s.Failed != 3 || s.AvgDuration != time.Hour {
+		t.Errorf("Expected 2 completed, 3 failed and an hour per ticket, got %+v", s)
+	}
+	if len(s.Failures) != 2 || s.Failures[0].Code != "ci_failed" || s.Failures[0].Count != 2 || len(s.Failures[0].Tickets) != 1 {
+		t.Errorf("Expected ci_failed twice for bug-1 first, got %+v", s.Failures)
+	}
+	if len(s.Flaky) != 1 || s.Flaky[0].Package != "./internal/api" {
+		t.Errorf("Expected the flaky package, got %+v", s.Flaky)
+	}
+	if s.AgentTime != time.Hour || s.CITime != 30*time.Minute || s.Cost != 11 {
+		t.Errorf("Expected an agent hour, 30m of CI and a cost of 11, got %s, %s and %.2f", s.AgentTime, s.CITime, s.Cost)
+	}
+	if len(s.Trend) != 2 || s.Trend[0].Completed != 1 || s.Trend[0].Failed != 1 || s.Trend[1].Completed != 2 {
+		t.Errorf("Unexpected trend %+v", s.Trend)
+	}
+}
+
+func TestWriteMarkdownAndHTML(t *testing.T) {
+	sources := newTestSources(t)
+	config := Config{Format: FormatMarkdown, TrendWeeks: 4}
+
+	path, err := Write(config, sources, week)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if filepath.Base(path) != "weekly-2025-W23.md" {
+		t.Errorf("Unexpected summary path %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	text := string(data)
+	for _, want := range []string{
+		"# Weekly summary: Mon 2 Jun to Sun 8 Jun 2025",
+		"2 completed, 3 failed (40% success)",
+		"Previous week: 1 completed (+100%)",
+		"| ci_failed | 2 | bug-1 |",
+		"| ./internal/api | 1 | feat-1 |",
+		"| Agent | 1h 0m | - |",
+		"Set metrics.summary.agent_cost_per_hour",
+		"| 2025-06-02 | 2 | 3 | " + strings.Repeat("#", maxBar) + " |",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Summary should contain %q:\n%s", want, text)
+		}
+	}
+
+	config.Format = FormatHTML
+	path, err = Write(config, sources, week)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if filepath.Ext(path) != ".html" || !strings.Contains(string(data), "<td>ci_failed</td><td>2</td>") {
+		t.Errorf("Expected an HTML summary at %s:\n%s", path, data)
+	}
+}
+
+func TestSchedulerWritesLastWeekOnce(t *testing.T) {
+	sources := newTestSources(t)
+	s := NewScheduler(Config{Format: FormatMarkdown, TrendWeeks: 1}, sources)
+
+	s.check(week.AddDate(0, 0, 9))
+	path := filepath.Join(sources.MetricsDir, "weekly-2025-W23.md")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected the finished week's summary: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("kept"), 0644); err != nil {
+		t.Fatalf("Failed to overwrite summary: %v", err)
+	}
+	s.check(week.AddDate(0, 0, 10))
+	if data, _ := os.ReadFile(path); string(data) != "kept" {
+		t.Errorf("A written summary should not be rewritten, got %q", data)
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	for _, day := range []time.Time{week, week.Add(3 * 24 * time.Hour), week.Add(7*24*time.Hour - time.Second)} {
+		if got := WeekStart(day, time.UTC); !got.Equal(week) {
+			t.Errorf("WeekStart(%s) = %s, want %s", day, got, week)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, config := range []Config{
+		{Format: "pdf", TrendWeeks: 8},
+		{Format: FormatMarkdown},
+		{Format: FormatHTML, TrendWeeks: 8, CICostPerHour: -1},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Config %+v should be rejected", config)
+		}
+	}
+}
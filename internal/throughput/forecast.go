@@ -0,0 +1,103 @@
+package throughput
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/backlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+)
+
+// minSpan keeps a burst of completions just after the first one from
+// forecasting an absurd rate
+const minSpan = time.Hour
+
+// Forecast estimates when the backlog clears at recent throughput
+type Forecast struct {
+	Backlog   int           `json:"backlog"`   // Tickets queued or in progress
+	Completed int           `json:"completed"` // Tickets completed within the window
+	Window    time.Duration `json:"window"`    // Period the rate is measured over
+	PerDay    float64       `json:"per_day"`   // Tickets completed per day
+	ClearsIn  time.Duration `json:"clears_in"` // Zero when the backlog is empty or nothing was completed
+}
+
+// Compute forecasts the backlog from the completions within window of now
+// A history shorter than the window is measured from its first completion
+func Compute(completions []Completion, backlog int, now time.Time, window time.Duration) Forecast {
+	f := Forecast{Backlog: backlog, Window: window}
+
+	since := now.Add(-window)
+	var first time.Time
+	var earlier bool // History reaches back before the window
+	for _, c := range completions {
+		if c.Time.Before(since) {
+			earlier = true
+			continue
+		}
+		if c.Time.After(now) {
+			continue
+		}
+		if first.IsZero() || c.Time.Before(first) {
+			first = c.Time
+		}
+		f.Completed++
+	}
+	if f.Completed == 0 {
+		return f
+	}
+
+	if !earlier {
+		span := now.Sub(first)
+		if span < minSpan {
+			span = minSpan
+		}
+		if span < f.Window {
+			f.Window = span
+		}
+	}
+
+	f.PerDay = float64(f.Completed) / f.Window.Hours() * 24
+	if backlog > 0 {
+		f.ClearsIn = time.Duration(float64(backlog) / f.PerDay * float64(24*time.Hour))
+	}
+	return f
+}
+
+// String summarises the forecast in a sentence
+func (f Forecast) String() string {
+	switch {
+	case f.Backlog == 0:
+		return "backlog is empty"
+	case f.Completed == 0:
+		return fmt.Sprintf("backlog of %d; no tickets completed recently to forecast from", f.Backlog)
+	}
+	return fmt.Sprintf("at current throughput (%.1f tickets/day), backlog of %d clears in %s", f.PerDay, f.Backlog, FormatETA(f.ClearsIn))
+}
+
+// FormatETA renders a rough duration such as ~3 days, ~5 hours or ~20 minutes
+func FormatETA(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("~%d days", int(math.Round(d.Hours()/24)))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("~%d hours", int(math.Round(d.Hours())))
+	case d >= 2*time.Minute:
+		return fmt.Sprintf("~%d minutes", int(math.Round(d.Minutes())))
+	}
+	return "~1 minute"
+}
+
+// Report is the daemon's answer to the status command
+type Report struct {
+	Queued     int                     `json:"queued"`
+	InProgress int                     `json:"in_progress"`
+	Forecast   Forecast                `json:"forecast"`
+	Workers    []ipc.WorkerStats       `json:"workers,omitempty"`   // Filled in by the daemon
+	Backlog    *backlog.ProcessedStats `json:"backlog,omitempty"`   // Filled in by the daemon
+	Main       *ipc.MainHealth         `json:"main,omitempty"`      // Filled in by the daemon when tracking CI on main
+	Queue      []queue.Listing         `json:"queue,omitempty"`     // Filled in by the daemon, most urgent first
+	Recent     []Completion            `json:"recent,omitempty"`    // Latest completions, newest first
+	Residency  []ResidencyStats        `json:"residency,omitempty"` // Queue waits per priority, most urgent first
+}
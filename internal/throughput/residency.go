@@ -0,0 +1,208 @@
+package throughput
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// ResidencyFile is the CSV in the metrics directory that records how long
+// every dispatched ticket waited in the queue
+const ResidencyFile = "residency.csv"
+
+// residencyHeader is the first row of ResidencyFile
+var residencyHeader = []string{"started_at", "ticket_id", "priority", "wait_ms"}
+
+// maxResidencies bounds the waits a recorder without a metrics directory
+// keeps in memory
+const maxResidencies = 10000
+
+// Residency is how long a ticket waited in the queue before a worker
+// started it
+type Residency struct {
+	Time     time.Time     `json:"time"` // When the ticket was started
+	TicketID string        `json:"ticket_id"`
+	Priority int           `json:"priority"` // At dispatch
+	Wait     time.Duration `json:"wait"`
+}
+
+// ResidencyStats summarises the waits of one priority class
+type ResidencyStats struct {
+	Priority int           `json:"priority"`
+	Count    int           `json:"count"`
+	P50      time.Duration `json:"p50"`
+	P90      time.Duration `json:"p90"`
+	P99      time.Duration `json:"p99"`
+	Max      time.Duration `json:"max"`
+}
+
+// AppendResidency adds a wait to the residency CSV
+func AppendResidency(metricsDir string, r Residency) error {
+	if err := os.MkdirAll(metricsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+
+	path := filepath.Join(metricsDir, ResidencyFile)
+	_, statErr := os.Stat(path)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open residency metrics: %w", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if os.IsNotExist(statErr) {
+		w.Write(residencyHeader)
+	}
+	w.Write([]string{
+		timefmt.Machine(r.Time),
+		r.TicketID,
+		strconv.Itoa(r.Priority),
+		strconv.FormatInt(r.Wait.Milliseconds(), 10),
+	})
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write residency metrics: %w", err)
+	}
+	return nil
+}
+
+// LoadResidency reads every recorded wait from the residency CSV, oldest
+// first; a missing file means no ticket has been dispatched yet
+func LoadResidency(metricsDir string) ([]Residency, error) {
+	file, err := os.Open(filepath.Join(metricsDir, ResidencyFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open residency metrics: %w", err)
+	}
+	defer file.Close()
+
+	var residencies []Residency
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = len(residencyHeader)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read residency metrics: %w", err)
+		}
+		if record[0] == residencyHeader[0] {
+			continue
+		}
+
+		startedAt, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time %q: %w", record[0], err)
+		}
+		priority, _ := strconv.Atoi(record[2])
+		millis, _ := strconv.ParseInt(record[3], 10, 64)
+		residencies = append(residencies, Residency{
+			Time:     startedAt,
+			TicketID: record[1],
+			Priority: priority,
+			Wait:     time.Duration(millis) * time.Millisecond,
+		})
+	}
+
+	sort.SliceStable(residencies, func(i, j int) bool {
+		return residencies[i].Time.Before(residencies[j].Time)
+	})
+	return residencies, nil
+}
+
+// SummarizeResidency computes wait percentiles per priority, most urgent
+// first, over the tickets started within window of now
+func SummarizeResidency(residencies []Residency, now time.Time, window time.Duration) []ResidencyStats {
+	since := now.Add(-window)
+	waits := make(map[int][]time.Duration)
+	for _, r := range residencies {
+		if r.Time.Before(since) || r.Time.After(now) {
+			continue
+		}
+		waits[r.Priority] = append(waits[r.Priority], r.Wait)
+	}
+
+	stats := make([]ResidencyStats, 0, len(waits))
+	for priority, durations := range waits {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		stats = append(stats, ResidencyStats{
+			Priority: priority,
+			Count:    len(durations),
+			P50:      percentile(durations, 50),
+			P90:      percentile(durations, 90),
+			P99:      percentile(durations, 99),
+			Max:      durations[len(durations)-1],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Priority < stats[j].Priority })
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// queuedSince is when a ticket last became eligible for dispatch: when it
+// was enqueued or requeued, or once a retry's backoff passed
+func queuedSince(t *ticket.Ticket, requeued time.Time) time.Time {
+	since := requeued
+	if since.IsZero() {
+		since = queue.NewListing(t).EnqueuedAt
+	}
+	if t.RetryAfter.After(since) {
+		since = t.RetryAfter
+	}
+	return since
+}
+
+// recordResidency notes how long a started ticket waited; the caller holds
+// r.mu
+func (r *Recorder) recordResidency(t *ticket.Ticket, startedAt time.Time) error {
+	since := queuedSince(t, r.queued[t.ID])
+	delete(r.queued, t.ID)
+	if since.IsZero() || startedAt.Before(since) {
+		return nil
+	}
+
+	residency := Residency{Time: startedAt, TicketID: t.ID, Priority: t.Priority, Wait: startedAt.Sub(since)}
+	if r.metricsDir != "" {
+		return AppendResidency(r.metricsDir, residency)
+	}
+	r.residencies = append(r.residencies, residency)
+	if len(r.residencies) > maxResidencies {
+		r.residencies = r.residencies[len(r.residencies)-maxResidencies:]
+	}
+	return nil
+}
+
+// residency returns the waits recorded so far, oldest first
+func (r *Recorder) residency() ([]Residency, error) {
+	if r.metricsDir != "" {
+		return LoadResidency(r.metricsDir)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	residencies := make([]Residency, len(r.residencies))
+	copy(residencies, r.residencies)
+	return residencies, nil
+}
@@ -0,0 +1,106 @@
+package throughput
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func priorityEvent(eventType ipc.EventType, t *ticket.Ticket, at time.Time) ipc.Event {
+	return ipc.Event{Type: eventType, Timestamp: at, Data: ipc.TicketEvent{Ticket: t, WorkerID: 1}}
+}
+
+func TestRecorderRecordsResidency(t *testing.T) {
+	metricsDir := t.TempDir()
+	r := NewRecorder(metricsDir)
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	urgent := &ticket.Ticket{ID: "fix-1", Priority: 1}
+	routine := &ticket.Ticket{ID: "feat-1", Priority: 3}
+	retried := &ticket.Ticket{ID: "feat-2", Priority: 3}
+	dated := &ticket.Ticket{ID: "feat-3", Priority: 3, CreatedAt: start}
+
+	events := []ipc.Event{
+		priorityEvent(ipc.EventTypeTicketEnqueued, routine, start),
+		priorityEvent(ipc.EventTypeTicketEnqueued, urgent, start.Add(time.Minute)),
+		priorityEvent(ipc.EventTypeTicketStarted, urgent, start.Add(90*time.Second)),
+		priorityEvent(ipc.EventTypeTicketStarted, routine, start.Add(10*time.Minute)),
+		// Requeued for retry: the wait runs from when the backoff ends
+		priorityEvent(ipc.EventTypeTicketRetrying, retried, start),
+		// Enqueued before the recorder saw it: dated from the ticket
+		priorityEvent(ipc.EventTypeTicketStarted, dated, start.Add(4*time.Minute)),
+	}
+	retried.RetryAfter = start.Add(5 * time.Minute)
+	events = append(events, priorityEvent(ipc.EventTypeTicketStarted, retried, start.Add(7*time.Minute)))
+	for _, event := range events {
+		if err := r.Record(event); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	residencies, err := LoadResidency(metricsDir)
+	if err != nil {
+		t.Fatalf("LoadResidency failed: %v", err)
+	}
+	want := map[string]time.Duration{"fix-1": 30 * time.Second, "feat-1": 10 * time.Minute, "feat-2": 2 * time.Minute, "feat-3": 4 * time.Minute}
+	if len(residencies) != len(want) {
+		t.Fatalf("Expected %d waits, got %+v", len(want), residencies)
+	}
+	for _, residency := range residencies {
+		if residency.Wait != want[residency.TicketID] {
+			t.Errorf("%s waited %v, expected %v", residency.TicketID, residency.Wait, want[residency.TicketID])
+		}
+	}
+	if residencies[0].TicketID != "fix-1" || residencies[0].Priority != 1 {
+		t.Errorf("Expected fix-1 at P1 first, got %+v", residencies[0])
+	}
+}
+
+func TestRecorderKeepsResidencyInMemory(t *testing.T) {
+	r := NewRecorder("")
+	now := time.Now()
+	tk := &ticket.Ticket{ID: "feat-1", Priority: 2}
+	r.Record(priorityEvent(ipc.EventTypeTicketEnqueued, tk, now.Add(-time.Minute)))
+	r.Record(priorityEvent(ipc.EventTypeTicketStarted, tk, now))
+
+	report, err := r.Report(0, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(report.Residency) != 1 || report.Residency[0].Priority != 2 || report.Residency[0].Max != time.Minute {
+		t.Errorf("Unexpected residency %+v", report.Residency)
+	}
+}
+
+func TestSummarizeResidency(t *testing.T) {
+	now := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	var residencies []Residency
+	for i := 1; i <= 100; i++ {
+		residencies = append(residencies, Residency{Time: now.Add(-time.Hour), TicketID: fmt.Sprintf("feat-%d", i), Priority: 3, Wait: time.Duration(i) * time.Second})
+	}
+	residencies = append(residencies,
+		Residency{Time: now.Add(-time.Hour), TicketID: "fix-1", Priority: 1, Wait: 5 * time.Second},
+		Residency{Time: now.Add(-30 * 24 * time.Hour), TicketID: "fix-old", Priority: 1, Wait: time.Hour}, // Outside the window
+	)
+
+	stats := SummarizeResidency(residencies, now, 7*24*time.Hour)
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 priorities, got %+v", stats)
+	}
+	if p1 := stats[0]; p1.Priority != 1 || p1.Count != 1 || p1.P50 != 5*time.Second || p1.Max != 5*time.Second {
+		t.Errorf("Unexpected P1 stats %+v", p1)
+	}
+	p3 := stats[1]
+	if p3.Priority != 3 || p3.Count != 100 || p3.P50 != 50*time.Second || p3.P90 != 90*time.Second || p3.P99 != 99*time.Second || p3.Max != 100*time.Second {
+		t.Errorf("Unexpected P3 stats %+v", p3)
+	}
+}
+
+func TestLoadResidencyMissingFile(t *testing.T) {
+	residencies, err := LoadResidency(t.TempDir())
+	if err != nil || residencies != nil {
+		t.Errorf("Expected no residency and no error, got %v, %v", residencies, err)
+	}
+}
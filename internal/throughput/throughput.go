@@ -0,0 +1,210 @@
+package throughput
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// MetricsFile is the CSV in the metrics directory that records every
+// completed ticket
+const MetricsFile = "throughput.csv"
+
+// header is the first row of MetricsFile
+var header = []string{"completed_at", "ticket_id", "worker_id", "duration_seconds"}
+
+// Completion is a ticket finished by a worker
+type Completion struct {
+	Time     time.Time     `json:"time"`
+	TicketID string        `json:"ticket_id"`
+	WorkerID int           `json:"worker_id"`
+	Duration time.Duration `json:"duration"` // From start to completion; zero when the start wasn't seen
+}
+
+// recentCompletions is how many of the latest completions a Report lists
+const recentCompletions = 5
+
+// Append adds a completion to the metrics CSV
+func Append(metricsDir string, c Completion) error {
+	if err := os.MkdirAll(metricsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+
+	path := filepath.Join(metricsDir, MetricsFile)
+	_, statErr := os.Stat(path)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open throughput metrics: %w", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if os.IsNotExist(statErr) {
+		w.Write(header)
+	}
+	w.Write([]string{
+		timefmt.Machine(c.Time),
+		c.TicketID,
+		strconv.Itoa(c.WorkerID),
+		strconv.Itoa(int(c.Duration.Seconds())),
+	})
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write throughput metrics: %w", err)
+	}
+	return nil
+}
+
+// Load reads every completion from the metrics CSV, oldest first
+// A missing file means nothing has been completed yet
+func Load(metricsDir string) ([]Completion, error) {
+	file, err := os.Open(filepath.Join(metricsDir, MetricsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open throughput metrics: %w", err)
+	}
+	defer file.Close()
+
+	var completions []Completion
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = len(header)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read throughput metrics: %w", err)
+		}
+		if record[0] == header[0] {
+			continue
+		}
+
+		completedAt, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid completion time %q: %w", record[0], err)
+		}
+		workerID, _ := strconv.Atoi(record[2])
+		seconds, _ := strconv.Atoi(record[3])
+		completions = append(completions, Completion{
+			Time:     completedAt,
+			TicketID: record[1],
+			WorkerID: workerID,
+			Duration: time.Duration(seconds) * time.Second,
+		})
+	}
+
+	sort.SliceStable(completions, func(i, j int) bool {
+		return completions[i].Time.Before(completions[j].Time)
+	})
+	return completions, nil
+}
+
+// Recorder appends a completion for every ticket_complete event, timing it
+// from the ticket's ticket_started event, and how long each started ticket
+// waited in the queue; with no metrics directory it only tracks which
+// tickets are in progress and keeps the waits in memory
+type Recorder struct {
+	metricsDir  string
+	mu          sync.Mutex
+	started     map[string]time.Time
+	queued      map[string]time.Time // When tickets were last enqueued or requeued
+	residencies []Residency          // Without a metrics directory
+}
+
+// NewRecorder returns a recorder writing to metricsDir
+func NewRecorder(metricsDir string) *Recorder {
+	return &Recorder{metricsDir: metricsDir, started: make(map[string]time.Time), queued: make(map[string]time.Time)}
+}
+
+// Record handles an IPC event, ignoring those that don't queue, start or
+// complete a ticket
+func (r *Recorder) Record(event ipc.Event) error {
+	data, ok := event.Data.(ipc.TicketEvent)
+	if !ok || data.Ticket == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch event.Type {
+	case ipc.EventTypeTicketEnqueued:
+		r.queued[data.Ticket.ID] = event.Timestamp
+	case ipc.EventTypeTicketStarted:
+		r.started[data.Ticket.ID] = event.Timestamp
+		return r.recordResidency(data.Ticket, event.Timestamp)
+	case ipc.EventTypeTicketPreempted, ipc.EventTypeTicketRetrying:
+		delete(r.started, data.Ticket.ID)
+		r.queued[data.Ticket.ID] = event.Timestamp
+	case ipc.EventTypeTicketFailed, ipc.EventTypeTicketCancelled:
+		delete(r.started, data.Ticket.ID)
+		delete(r.queued, data.Ticket.ID)
+	case ipc.EventTypeTicketComplete:
+		completion := Completion{Time: event.Timestamp, TicketID: data.Ticket.ID, WorkerID: data.WorkerID}
+		if started, ok := r.started[data.Ticket.ID]; ok {
+			completion.Duration = event.Timestamp.Sub(started)
+			delete(r.started, data.Ticket.ID)
+		}
+		if r.metricsDir == "" {
+			return nil
+		}
+		return Append(r.metricsDir, completion)
+	}
+	return nil
+}
+
+// InProgress returns how many started tickets have yet to complete or fail
+func (r *Recorder) InProgress() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.started)
+}
+
+// Report forecasts the queued and in-progress tickets from the completions
+// recorded within window, listing the latest completions and the queue
+// residency of each priority over the same window
+func (r *Recorder) Report(queued int, window time.Duration) (Report, error) {
+	var completions []Completion
+	if r.metricsDir != "" {
+		var err error
+		if completions, err = Load(r.metricsDir); err != nil {
+			return Report{}, err
+		}
+	}
+	residencies, err := r.residency()
+	if err != nil {
+		return Report{}, err
+	}
+
+	now := time.Now()
+	report := Report{Queued: queued, InProgress: r.InProgress()}
+	report.Forecast = Compute(completions, report.Queued+report.InProgress, now, window)
+	report.Recent = latest(completions, recentCompletions)
+	report.Residency = SummarizeResidency(residencies, now, window)
+	return report, nil
+}
+
+// latest returns up to n completions, newest first
+func latest(completions []Completion, n int) []Completion {
+	sorted := make([]Completion, len(completions))
+	copy(sorted, completions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.After(sorted[j].Time) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
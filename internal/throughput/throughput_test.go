@@ -0,0 +1,133 @@
+package throughput
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func ticketEvent(eventType ipc.EventType, id string, at time.Time) ipc.Event {
+	return ipc.Event{Type: eventType, Timestamp: at, Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: id}, WorkerID: 2}}
+}
+
+func TestRecorderAppendsCompletions(t *testing.T) {
+	metricsDir := t.TempDir()
+	r := NewRecorder(metricsDir)
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	events := []ipc.Event{
+		ticketEvent(ipc.EventTypeTicketStarted, "feat-1", start),
+		ticketEvent(ipc.EventTypeTicketStarted, "feat-2", start),
+		ticketEvent(ipc.EventTypeTicketStarted, "feat-3", start),
+		ticketEvent(ipc.EventTypeTicketFailed, "feat-3", start.Add(time.Minute)),
+		ticketEvent(ipc.EventTypeTicketComplete, "feat-1", start.Add(90*time.Second)),
+		{Type: ipc.EventTypeDiskSpace, Timestamp: start, Data: ipc.DiskSpaceEvent{Path: "/work"}},
+	}
+	for _, event := range events {
+		if err := r.Record(event); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	if r.InProgress() != 1 {
+		t.Errorf("Expected feat-2 to be in progress, got %d tickets", r.InProgress())
+	}
+
+	completions, err := Load(metricsDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(completions) != 1 {
+		t.Fatalf("Expected 1 completion, got %+v", completions)
+	}
+	c := completions[0]
+	if c.TicketID != "feat-1" || c.WorkerID != 2 || c.Duration != 90*time.Second || !c.Time.Equal(start.Add(90*time.Second)) {
+		t.Errorf("Unexpected completion %+v", c)
+	}
+}
+
+func TestReportListsRecentCompletions(t *testing.T) {
+	metricsDir := t.TempDir()
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < recentCompletions+2; i++ {
+		c := Completion{Time: start.Add(time.Duration(i) * time.Hour), TicketID: fmt.Sprintf("feat-%d", i), WorkerID: 1}
+		if err := Append(metricsDir, c); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	report, err := NewRecorder(metricsDir).Report(0, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(report.Recent) != recentCompletions {
+		t.Fatalf("Expected %d recent completions, got %d", recentCompletions, len(report.Recent))
+	}
+	if newest := report.Recent[0].TicketID; newest != fmt.Sprintf("feat-%d", recentCompletions+1) {
+		t.Errorf("Expected the newest completion first, got %s", newest)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	completions, err := Load(t.TempDir())
+	if err != nil || completions != nil {
+		t.Errorf("Expected no completions, got %v (err %v)", completions, err)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	daysAgo := func(days float64) Completion {
+		return Completion{Time: now.Add(-time.Duration(days * float64(24*time.Hour)))}
+	}
+
+	// 14 tickets in the last week is 2 a day; older history is ignored
+	var completions []Completion
+	for i := 0; i < 14; i++ {
+		completions = append(completions, daysAgo(float64(i)/2))
+	}
+	completions = append(completions, daysAgo(30), daysAgo(31))
+
+	f := Compute(completions, 6, now, week)
+	if f.Completed != 14 || f.PerDay != 2 || f.Window != week {
+		t.Errorf("Unexpected forecast %+v", f)
+	}
+	if f.ClearsIn != 3*24*time.Hour {
+		t.Errorf("Expected the backlog to clear in 3 days, got %v", f.ClearsIn)
+	}
+	if got := f.String(); got != "at current throughput (2.0 tickets/day), backlog of 6 clears in ~3 days" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+
+	// A daemon with two days of history is measured over those two days
+	f = Compute([]Completion{daysAgo(2), daysAgo(1), daysAgo(0.5), daysAgo(0)}, 4, now, week)
+	if f.Window != 2*24*time.Hour || f.PerDay != 2 {
+		t.Errorf("Expected the window to shrink to the history, got %+v", f)
+	}
+
+	if got := Compute(completions, 0, now, week).String(); got != "backlog is empty" {
+		t.Errorf("Unexpected summary for an empty backlog %q", got)
+	}
+	f = Compute(completions[14:], 3, now, week)
+	if f.ClearsIn != 0 || f.String() != "backlog of 3; no tickets completed recently to forecast from" {
+		t.Errorf("Expected no forecast without recent completions, got %+v", f)
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	tests := map[time.Duration]string{
+		30 * time.Second: "~1 minute",
+		20 * time.Minute: "~20 minutes",
+		5 * time.Hour:    "~5 hours",
+		70 * time.Hour:   "~3 days",
+	}
+	for d, want := range tests {
+		if got := FormatETA(d); got != want {
+			t.Errorf("FormatETA(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ticket
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultsFileName is the file in the backlog directory whose fields are
+// merged into every ticket loaded from the backlog
+const DefaultsFileName = "_defaults.yaml"
+
+// Defaults are ticket fields shared by a whole backlog, e.g. one generated
+// by a script
+// Lists are added to each ticket's own; other fields only fill in a ticket
+// that leaves them empty
+type Defaults struct {
+	Tags        []string `yaml:"tags,omitempty"`
+	Locks       []string `yaml:"locks,omitempty"`
+	EstimateMin int      `yaml:"estimate_min,omitempty"`
+	Environment string   `yaml:"environment,omitempty"`
+}
+
+// LoadDefaults reads a defaults file
+// A missing file yields nil defaults, which change nothing
+func LoadDefaults(path string) (*Defaults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ticket defaults: %w", err)
+	}
+	if err := CheckSize(int64(len(data))); err != nil {
+		return nil, fmt.Errorf("failed to read ticket defaults: %w", err)
+	}
+
+	// Unknown fields are refused, so a typo doesn't silently default nothing
+	var d Defaults
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&d); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse ticket defaults %s: %w", path, err)
+	}
+
+	limits := Ticket{Tags: d.Tags, Locks: d.Locks, Environment: d.Environment}
+	if err := limits.checkLimits(); err != nil {
+		return nil, fmt.Errorf("invalid ticket defaults %s: %w", path, err)
+	}
+	if d.EstimateMin < 0 {
+		return nil, fmt.Errorf("invalid ticket defaults %s: estimate_min cannot be negative", path)
+	}
+	return &d, nil
+}
+
+// Apply merges the defaults into t
+// The ticket as written is kept for its signature, which covers only the file
+func (d *Defaults) Apply(t *Ticket) {
+	if d == nil {
+		return
+	}
+	t.keepWritten()
+	t.Tags = mergeList(d.Tags, t.Tags)
+	t.Locks = mergeList(d.Locks, t.Locks)
+	if t.EstimateMin == 0 {
+		t.EstimateMin = d.EstimateMin
+	}
+	if t.Environment == "" {
+		t.Environment = d.Environment
+	}
+}
+
+// keepWritten saves the ticket as its file had it, before the first change
+// made while loading it
+func (t *Ticket) keepWritten() {
+	if t.written == nil {
+		written := *t
+		t.written = &written
+	}
+}
+
+// mergeList returns the defaults followed by the ticket's own entries, without
+// duplicates, in a new slice
+func mergeList(defaults, own []string) []string {
+	if len(defaults) == 0 {
+		return own
+	}
+	merged := make([]string, 0, len(defaults)+len(own))
+	seen := make(map[string]bool, len(defaults)+len(own))
+	for _, list := range [][]string{defaults, own} {
+		for _, entry := range list {
+			if !seen[entry] {
+				seen[entry] = true
+				merged = append(merged, entry)
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,65 @@
+package ticket
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	dir := t.TempDir()
+
+	missing, err := LoadDefaults(filepath.Join(dir, DefaultsFileName))
+	if err != nil || missing != nil {
+		t.Fatalf("Expected no defaults without a file, got %v, %v", missing, err)
+	}
+
+	path := filepath.Join(dir, DefaultsFileName)
+	if err := os.WriteFile(path, []byte("tags: [a]\nestimate_min: 15\n"), 0644); err != nil {
+		t.Fatalf("Failed to write defaults: %v", err)
+	}
+	defaults, err := LoadDefaults(path)
+	if err != nil {
+		t.Fatalf("LoadDefaults failed: %v", err)
+	}
+	if !reflect.DeepEqual(defaults.Tags, []string{"a"}) || defaults.EstimateMin != 15 {
+		t.Errorf("Unexpected defaults: %+v", defaults)
+	}
+
+	if err := os.WriteFile(path, []byte("priorty: 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write defaults: %v", err)
+	}
+	if _, err := LoadDefaults(path); err == nil {
+		t.Error("Expected an unknown field to be rejected")
+	}
+}
+
+func TestDefaultsApply(t *testing.T) {
+	defaults := &Defaults{Tags: []string{"gen", "api"}, Locks: []string{"db"}, EstimateMin: 30, Environment: "staging"}
+	tk := &Ticket{ID: "feat-1", Title: "T", Description: "D", Priority: 2, Tags: []string{"api", "ui"}, Environment: "prod"}
+	before, err := tk.Canonical()
+	if err != nil {
+		t.Fatalf("Canonical failed: %v", err)
+	}
+
+	defaults.Apply(tk)
+	if !reflect.DeepEqual(tk.Tags, []string{"gen", "api", "ui"}) || !reflect.DeepEqual(tk.Locks, []string{"db"}) {
+		t.Errorf("Expected lists to be merged, got tags %v and locks %v", tk.Tags, tk.Locks)
+	}
+	if tk.EstimateMin != 30 || tk.Environment != "prod" {
+		t.Errorf("Expected only empty fields to be filled, got %d and %q", tk.EstimateMin, tk.Environment)
+	}
+
+	// The signature still covers the ticket as written
+	after, err := tk.Canonical()
+	if err != nil {
+		t.Fatalf("Canonical failed: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("Expected defaults to be left out of the canonical form:\n%s\nvs\n%s", before, after)
+	}
+
+	var none *Defaults
+	none.Apply(tk)
+}
@@ -0,0 +1,143 @@
+package ticket
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Anyone who can write to the backlog directory can hand the daemon a ticket,
+// so ticket files are parsed defensively and held to these limits
+const (
+	MaxFileSize    = 1 << 20  // Bytes in a ticket file
+	MaxDepth       = 16       // Nesting of YAML mappings and sequences
+	MaxNodes       = 4096     // YAML nodes in a ticket file
+	MaxFieldLength = 1024     // Bytes in a single-line field such as id or title
+	MaxDescription = 64 << 10 // Bytes in the description and summary
+	MaxListLength  = 256      // Entries in a list field such as locks or tags
+)
+
+// ErrTooLarge is returned for ticket files beyond the ingestion limits
+var ErrTooLarge = errors.New("ticket exceeds size limits")
+
+// CheckSize rejects a ticket file of size bytes before it is read
+func CheckSize(size int64) error {
+	if size > MaxFileSize {
+		return fmt.Errorf("%w: file is %d bytes, limit is %d", ErrTooLarge, size, MaxFileSize)
+	}
+	return nil
+}
+
+// decode parses a ticket file into t
+// The document is first parsed into a node tree, which is checked for depth
+// and size before being decoded. Aliases count as the nodes they expand to,
+// so anchors shared between fields work but a document can't expand
+// exponentially
+func decode(data []byte, t *Ticket) (err error) {
+	if err := CheckSize(int64(len(data))); err != nil {
+		return err
+	}
+
+	// The parser is not trusted to fail cleanly on every input
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed ticket YAML: %v", r)
+		}
+	}()
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	nodes := 0
+	if err := checkNode(&doc, 0, &nodes); err != nil {
+		return err
+	}
+	if err := doc.Decode(t); err != nil {
+		return err
+	}
+	return t.checkLimits()
+}
+
+// checkNode walks the node tree, counting nodes into count and following
+// aliases to count what they expand to
+func checkNode(n *yaml.Node, depth int, count *int) error {
+	if depth > MaxDepth {
+		return fmt.Errorf("%w: nested deeper than %d levels", ErrTooLarge, MaxDepth)
+	}
+	*count++
+	if *count > MaxNodes {
+		return fmt.Errorf("%w: more than %d YAML nodes", ErrTooLarge, MaxNodes)
+	}
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		return checkNode(n.Alias, depth+1, count)
+	}
+	for _, child := range n.Content {
+		if err := checkNode(child, depth+1, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLimits rejects fields too large for any real ticket
+func (t *Ticket) checkLimits() error {
+	fields := map[string]string{
+		"id":            t.ID,
+		"title":         t.Title,
+		"type":          t.Type,
+		"reproduce":     t.Reproduce,
+		"context_group": t.ContextGroup,
+		"environment":   t.Environment,
+		"checkpoint":    t.Checkpoint,
+		"branch":        t.Branch,
+		"retry_branch":  t.RetryBranch,
+		"failure_code":  t.FailureCode,
+	}
+	for name, value := range fields {
+		if len(value) > MaxFieldLength {
+			return fmt.Errorf("%w: %s is %d bytes, limit is %d", ErrTooLarge, name, len(value), MaxFieldLength)
+		}
+	}
+	for name, value := range map[string]string{"description": t.Description, "summary": t.Summary} {
+		if len(value) > MaxDescription {
+			return fmt.Errorf("%w: %s is %d bytes, limit is %d", ErrTooLarge, name, len(value), MaxDescription)
+		}
+	}
+
+	lists := map[string][]string{
+		"locks":         t.Locks,
+		"dependencies":  t.Dependencies,
+		"tags":          t.Tags,
+		"artifacts":     t.Artifacts,
+		"artifact_urls": t.ArtifactURLs,
+	}
+	if len(t.Done) > MaxListLength {
+		return fmt.Errorf("%w: done has %d checks, limit is %d", ErrTooLarge, len(t.Done), MaxListLength)
+	}
+	for _, check := range t.Done {
+		if len(check.Name) > MaxFieldLength || len(check.Run) > MaxFieldLength {
+			return fmt.Errorf("%w: done check %q is over %d bytes", ErrTooLarge, check.Name, MaxFieldLength)
+		}
+	}
+
+	if r := t.Resolves; r != nil {
+		if len(r.Ticket) > MaxFieldLength || len(r.Branch) > MaxFieldLength || len(r.Commit) > MaxFieldLength {
+			return fmt.Errorf("%w: resolves is over %d bytes", ErrTooLarge, MaxFieldLength)
+		}
+		lists["resolves.files"] = r.Files
+	}
+
+	for name, list := range lists {
+		if len(list) > MaxListLength {
+			return fmt.Errorf("%w: %s has %d entries, limit is %d", ErrTooLarge, name, len(list), MaxListLength)
+		}
+		for _, entry := range list {
+			if len(entry) > MaxFieldLength {
+				return fmt.Errorf("%w: entry in %s is %d bytes, limit is %d", ErrTooLarge, name, len(entry), MaxFieldLength)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package ticket
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const limitsBase = "id: feat-1\ntitle: Limits\ndescription: Test ingestion limits\npriority: 2\n"
+
+func TestLoadFromBytesLimits(t *testing.T) {
+	deep := limitsBase + "locks: " + strings.Repeat("[", MaxDepth+1) + strings.Repeat("]", MaxDepth+1) + "\n"
+	tags := limitsBase + "tags:\n" + strings.Repeat("  - tag\n", MaxListLength+1)
+
+	tests := []struct {
+		name   string
+		yaml   string
+		tooBig bool
+	}{
+		{"huge document", limitsBase + "summary: " + strings.Repeat("a", MaxFileSize) + "\n", true},
+		{"deep nesting", deep, true},
+		{"long title", strings.Replace(limitsBase, "Limits", strings.Repeat("t", MaxFieldLength+1), 1), true},
+		{"long description", limitsBase + "summary: " + strings.Repeat("s", MaxDescription+1) + "\n", true},
+		{"long list", tags, true},
+		{"long list entry", limitsBase + "locks: [" + strings.Repeat("l", MaxFieldLength+1) + "]\n", true},
+		{"alias expansion", aliasBomb(), true},
+		{"path ID", strings.Replace(limitsBase, "feat-1", "../../x", 1), false},
+		{"dot ID", strings.Replace(limitsBase, "feat-1", ".hidden", 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFromBytes([]byte(tt.yaml))
+			if err == nil {
+				t.Fatal("Expected the ticket to be rejected")
+			}
+			if tt.tooBig && !errors.Is(err, ErrTooLarge) {
+				t.Errorf("Expected ErrTooLarge, got %v", err)
+			}
+		})
+	}
+
+	if _, err := LoadFromBytes([]byte(limitsBase + "tags:\n" + strings.Repeat("  - tag\n", MaxListLength))); err != nil {
+		t.Errorf("Expected a ticket at the limits to load, got %v", err)
+	}
+
+	// Anchors are fine as long as their expansion stays small
+	shared := "id: feat-1\ntitle: &t Shared title\ndescription: *t\npriority: 2\ntags: &tags [api, auth]\nlocks: *tags\n"
+	if loaded, err := LoadFromBytes([]byte(shared)); err != nil || loaded.Description != "Shared title" || len(loaded.Locks) != 2 {
+		t.Errorf("Expected anchors to be expanded, got %+v (err %v)", loaded, err)
+	}
+}
+
+// aliasBomb nests aliases so the tags expand to 10^6 entries
+func aliasBomb() string {
+	doc := limitsBase + "x0: &x0 [a, a, a, a, a, a, a, a, a, a]\n"
+	for i := 1; i < 6; i++ {
+		doc += fmt.Sprintf("x%d: &x%d [*x%d, *x%d, *x%d, *x%d, *x%d, *x%d, *x%d, *x%d, *x%d, *x%d]\n", i, i, i-1, i-1, i-1, i-1, i-1, i-1, i-1, i-1, i-1, i-1)
+	}
+	return doc + "tags: *x5\n"
+}
+
+func TestLoadRejectsOversizedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "huge.yaml")
+	if err := os.WriteFile(path, []byte(limitsBase+"summary: "+strings.Repeat("a", MaxFileSize)+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	if _, err := Load(path); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+}
+
+func FuzzLoadFromBytes(f *testing.F) {
+	f.Add([]byte(limitsBase))
+	f.Add([]byte(limitsBase + "locks: [a, b]\ndependencies: [feat-0]\ntags: [x]\n"))
+	f.Add([]byte(limitsBase + "provenance:\n  enqueued_by: cli\n  checksum: sha256:00\n"))
+	f.Add([]byte("id: &a [*a]\n"))
+	f.Add([]byte(strings.Repeat("- ", 100) + "x"))
+	f.Add([]byte("{{{{{{{{"))
+	f.Add([]byte(fmt.Sprintf("id: %q\n", strings.Repeat("\x00", 10))))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ticket, err := LoadFromBytes(data)
+		if err != nil {
+			return
+		}
+		if err := ticket.Validate(); err != nil {
+			t.Errorf("Loaded ticket fails validation: %v", err)
+		}
+		if err := ticket.checkLimits(); err != nil {
+			t.Errorf("Loaded ticket exceeds limits: %v", err)
+		}
+		if _, err := ticket.ToYAML(); err != nil {
+			t.Errorf("Loaded ticket cannot be marshalled: %v", err)
+		}
+	})
+}
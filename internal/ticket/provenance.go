@@ -0,0 +1,88 @@
+package ticket
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrChecksumMismatch is returned when a ticket file no longer matches the
+// checksum recorded when it was enqueued
+var ErrChecksumMismatch = errors.New("ticket checksum mismatch")
+
+// Provenance records who enqueued a ticket and what it looked like then
+// It is kept as a provenance block at the end of the ticket file
+type Provenance struct {
+	EnqueuedBy string    `yaml:"enqueued_by" json:"enqueued_by"` // e.g. cli:alice, rule:<name> or watcher
+	Source     string    `yaml:"source" json:"source"`           // File the ticket was enqueued from, or the rule that wrote it
+	Checksum   string    `yaml:"checksum" json:"checksum"`       // sha256 of the ticket file without its provenance block
+	EnqueuedAt time.Time `yaml:"enqueued_at" json:"enqueued_at"`
+}
+
+// provenanceKey starts the provenance block
+var provenanceKey = []byte("provenance:")
+
+// SplitProvenance separates a ticket file into its body and the provenance
+// block at its end, which is nil when there is none
+func SplitProvenance(data []byte) (body, block []byte) {
+	start := -1
+	if bytes.HasPrefix(data, provenanceKey) {
+		start = 0
+	} else if i := bytes.LastIndex(data, append([]byte("\n"), provenanceKey...)); i >= 0 {
+		start = i + 1
+	}
+	if start < 0 {
+		return data, nil
+	}
+
+	// The block only counts if nothing but its own indented lines follow
+	for _, line := range bytes.Split(data[start:], []byte("\n"))[1:] {
+		if len(line) > 0 && line[0] != ' ' && line[0] != '\t' {
+			return data, nil
+		}
+	}
+	return data[:start], data[start:]
+}
+
+// Checksum returns the sha256 of a ticket file, ignoring its provenance block
+func Checksum(data []byte) string {
+	body, _ := SplitProvenance(data)
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Stamp returns the ticket file with p as its provenance block, replacing
+// any block already there; the checksum is taken from the file's body
+func Stamp(data []byte, p Provenance) ([]byte, *Provenance, error) {
+	body, _ := SplitProvenance(data)
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		body = append(append([]byte(nil), body...), '\n')
+	}
+	p.Checksum = Checksum(body)
+
+	block, err := yaml.Marshal(struct {
+		Provenance Provenance `yaml:"provenance"`
+	}{p})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal provenance: %w", err)
+	}
+	return append(append([]byte(nil), body...), block...), &p, nil
+}
+
+// VerifyProvenance checks that data, the file t was loaded from, still
+// matches the checksum recorded when it was enqueued
+// Tickets without provenance pass
+func (t *Ticket) VerifyProvenance(data []byte) error {
+	if t.Provenance == nil || t.Provenance.Checksum == "" {
+		return nil
+	}
+	if sum := Checksum(data); sum != t.Provenance.Checksum {
+		return fmt.Errorf("%w: enqueued as %s, file is %s", ErrChecksumMismatch, t.Provenance.Checksum, sum)
+	}
+	return nil
+}
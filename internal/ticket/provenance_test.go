@@ -0,0 +1,55 @@
+package ticket
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStampAndVerifyProvenance(t *testing.T) {
+	body := "id: \"feat-1\"\ntitle: \"Feature\"\ndescription: \"Do it\"\npriority: 2"
+
+	stamped, p, err := Stamp([]byte(body), Provenance{EnqueuedBy: "cli:alice", Source: "/tmp/feat-1.yaml"})
+	if err != nil {
+		t.Fatalf("Stamp failed: %v", err)
+	}
+	if p.Checksum != Checksum([]byte(body+"\n")) || !strings.HasPrefix(p.Checksum, "sha256:") {
+		t.Errorf("Unexpected checksum %s", p.Checksum)
+	}
+
+	loaded, err := LoadFromBytes(stamped)
+	if err != nil {
+		t.Fatalf("Failed to load stamped ticket: %v", err)
+	}
+	if loaded.Provenance == nil || loaded.Provenance.EnqueuedBy != "cli:alice" || loaded.Provenance.Checksum != p.Checksum {
+		t.Fatalf("Unexpected provenance %+v", loaded.Provenance)
+	}
+	if err := loaded.VerifyProvenance(stamped); err != nil {
+		t.Errorf("Expected stamped ticket to verify, got %v", err)
+	}
+
+	// Restamping replaces the block rather than adding a second one
+	restamped, _, err := Stamp(stamped, Provenance{EnqueuedBy: "watcher"})
+	if err != nil {
+		t.Fatalf("Stamp failed: %v", err)
+	}
+	if strings.Count(string(restamped), "provenance:") != 1 {
+		t.Errorf("Expected one provenance block, got:\n%s", restamped)
+	}
+
+	tampered := []byte(strings.Replace(string(stamped), "priority: 2", "priority: 1", 1))
+	if err := loaded.VerifyProvenance(tampered); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch for an edited ticket, got %v", err)
+	}
+
+	if err := (&Ticket{}).VerifyProvenance([]byte(body)); err != nil {
+		t.Errorf("Expected tickets without provenance to pass, got %v", err)
+	}
+}
+
+func TestSplitProvenanceIgnoresEarlierKey(t *testing.T) {
+	data := []byte("provenance:\n  source: x\nid: \"feat-1\"\n")
+	if body, block := SplitProvenance(data); block != nil || string(body) != string(data) {
+		t.Errorf("Expected a provenance key followed by other keys not to split, got %q / %q", body, block)
+	}
+}
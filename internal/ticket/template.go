@@ -0,0 +1,58 @@
+package ticket
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Templating holds the variables ticket descriptions may refer to, e.g.
+// {{ .ProjectName }}, {{ .Date }} or {{ .Vars.team }}
+type Templating struct {
+	ProjectName string            `mapstructure:"project_name"` // Defaults to the repository's name
+	Vars        map[string]string `mapstructure:"vars"`         // Keys are lowercased by the config loader
+}
+
+// TemplateData is what a ticket description is rendered with
+type TemplateData struct {
+	ProjectName string
+	Date        string // Day the ticket was loaded, as YYYY-MM-DD
+	Vars        map[string]string
+}
+
+// Expand renders the ticket's description as a Go template
+// Descriptions without placeholders are left alone; an unknown variable is
+// an error rather than an empty string. The ticket as written is kept for
+// its signature
+func (c Templating) Expand(t *Ticket, now time.Time) error {
+	if !strings.Contains(t.Description, "{{") {
+		return nil
+	}
+
+	tmpl, err := template.New(t.ID).Option("missingkey=error").Parse(t.Description)
+	if err != nil {
+		return fmt.Errorf("invalid description template: %w", err)
+	}
+	vars := c.Vars
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, TemplateData{ProjectName: c.ProjectName, Date: now.Format("2006-01-02"), Vars: vars}); err != nil {
+		return fmt.Errorf("failed to render description: %w", err)
+	}
+	if b.Len() > MaxDescription {
+		return fmt.Errorf("%w: rendered description is %d bytes, limit is %d", ErrTooLarge, b.Len(), MaxDescription)
+	}
+
+	t.keepWritten()
+	t.Description = b.String()
+	return nil
+}
+
+// EscapeTemplate quotes text placed in a generated description, e.g. command
+// output, so that Expand leaves it as it is
+func EscapeTemplate(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
@@ -0,0 +1,55 @@
+package ticket
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplatingExpand(t *testing.T) {
+	templating := Templating{ProjectName: "shop", Vars: map[string]string{"team": "payments"}}
+	now := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+
+	tk := &Ticket{ID: "feat-1", Title: "T", Priority: 2, Description: "Add refunds to {{ .ProjectName }} for {{ .Vars.team }} ({{ .Date }})"}
+	before, _ := tk.Canonical()
+	if err := templating.Expand(tk, now); err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if want := "Add refunds to shop for payments (2025-03-14)"; tk.Description != want {
+		t.Errorf("Expected %q, got %q", want, tk.Description)
+	}
+	if after, _ := tk.Canonical(); string(after) != string(before) {
+		t.Error("Expected the signature to cover the description as written")
+	}
+
+	plain := &Ticket{ID: "feat-2", Description: "No placeholders"}
+	if err := templating.Expand(plain, now); err != nil || plain.written != nil {
+		t.Errorf("Expected a plain description to be left alone, got %v", err)
+	}
+}
+
+func TestTemplatingExpandErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+	}{
+		{"unknown variable", "For {{ .Vars.owner }}"},
+		{"unknown field", "For {{ .Project }}"},
+		{"bad syntax", "For {{ .ProjectName"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tk := &Ticket{ID: "feat-1", Description: tt.description}
+			if err := (Templating{}).Expand(tk, time.Now()); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	huge := &Ticket{ID: "feat-1", Description: "{{ .ProjectName }}{{ .ProjectName }}"}
+	big := Templating{ProjectName: strings.Repeat("p", MaxDescription)}
+	if err := big.Expand(huge, time.Now()); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected an oversized description to be rejected, got %v", err)
+	}
+}
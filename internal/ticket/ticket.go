@@ -4,34 +4,78 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Ticket types
+const (
+	TypeFeature  = "feature"
+	TypeBug      = "bug"
+	TypeRefactor = "refactor"
+	TypeChore    = "chore"
+)
+
+// validID matches ticket IDs, which name files and directories such as
+// scratch space, logs and reports: letters, digits, dots, dashes and
+// underscores, not starting with a dot
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
 // Ticket represents a feature request or task to be completed by an agent
 type Ticket struct {
 	ID          string    `yaml:"id" json:"id"`
 	Title       string    `yaml:"title" json:"title"`
 	Description string    `yaml:"description" json:"description"`
 	Priority    int       `yaml:"priority" json:"priority"`
+	Type        string    `yaml:"type,omitempty" json:"type,omitempty"` // feature, bug, refactor or chore; "" is a feature
+	Reproduce   string    `yaml:"reproduce,omitempty" json:"reproduce,omitempty"` // For bugs: shell command that fails until the bug is fixed
 	Locks       []string  `yaml:"locks,omitempty" json:"locks,omitempty"`
 	Dependencies []string `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
 	EstimateMin int       `yaml:"estimate_min,omitempty" json:"estimate_min,omitempty"`
 	Tags        []string  `yaml:"tags,omitempty" json:"tags,omitempty"`
+	ContextGroup string   `yaml:"context_group,omitempty" json:"context_group,omitempty"`
+	Environment string    `yaml:"environment,omitempty" json:"environment,omitempty"` // e.g. staging or prod; picks per-environment settings
+	Summary     string    `yaml:"summary,omitempty" json:"summary,omitempty"`
+	RequiresApproval bool `yaml:"requires_approval,omitempty" json:"requires_approval,omitempty"`
+	SkipCI      bool      `yaml:"skip_ci,omitempty" json:"skip_ci,omitempty"` // Go straight from the agent to completion
+	Artifacts   []string  `yaml:"artifacts,omitempty" json:"artifacts,omitempty"` // Worktree globs published after CI passes
+	Done        []DoneCheck `yaml:"done,omitempty" json:"done,omitempty"` // Checks run on main once the ticket is merged
+	Resolves    *Resolution `yaml:"resolves,omitempty" json:"resolves,omitempty"` // Set on tickets generated to resolve another ticket's merge conflicts
+	ArtifactURLs []string `yaml:"artifact_urls,omitempty" json:"artifact_urls,omitempty"` // Set once artifacts are published
+	LogURLs     []string  `yaml:"log_urls,omitempty" json:"log_urls,omitempty"` // Set once the agent's output is uploaded
+	// Set by workers while the ticket runs; never read from or written to
+	// ticket files, so a backlog file can't pick the branch a worker uses
+	Checkpoint  string    `yaml:"-" json:"checkpoint,omitempty"` // Branch holding work saved when the ticket was preempted
+	Branch      string    `yaml:"-" json:"branch,omitempty"` // Branch the latest attempt ran on
+	Attempt     int       `yaml:"-" json:"attempt,omitempty"` // Number of times a worker has started the ticket
+	RetryBranch string    `yaml:"-" json:"retry_branch,omitempty"` // How a retry treated the earlier branch: reset or attempt
+	FailureCode string    `yaml:"-" json:"failure_code,omitempty"` // Set when a worker gives up on the ticket, e.g. ci_failed
+	PushVeto    string    `yaml:"-" json:"push_veto,omitempty"` // Why the pre-push hook refused the latest attempt; shown to the agent on retry
+	RetryAfter  time.Time `yaml:"-" json:"retry_after,omitempty"` // Set when a failed ticket is requeued; it waits until then
 	CreatedAt   time.Time `yaml:"created_at,omitempty" json:"created_at,omitempty"`
 	UpdatedAt   time.Time `yaml:"updated_at,omitempty" json:"updated_at,omitempty"`
+	Signature   *Signature `yaml:"signature,omitempty" json:"signature,omitempty"` // Set by orchestrator sign for trusted pipelines
+	Provenance  *Provenance `yaml:"provenance,omitempty" json:"provenance,omitempty"` // Kept last, as the file's final block
+
+	written     *Ticket    // The ticket as its file had it, before backlog defaults and templating
 }
 
 // Load loads a ticket from a YAML file
 func Load(filepath string) (*Ticket, error) {
+	if info, err := os.Stat(filepath); err == nil {
+		if err := CheckSize(info.Size()); err != nil {
+			return nil, fmt.Errorf("failed to read ticket file %s: %w", filepath, err)
+		}
+	}
 	data, err := os.ReadFile(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read ticket file %s: %w", filepath, err)
 	}
 
 	var ticket Ticket
-	if err := yaml.Unmarshal(data, &ticket); err != nil {
+	if err := decode(data, &ticket); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML in %s: %w", filepath, err)
 	}
 
@@ -52,10 +96,11 @@ func Load(filepath string) (*Ticket, error) {
 	return &ticket, nil
 }
 
-// LoadFromBytes loads a ticket from YAML bytes
+// LoadFromBytes loads a ticket from YAML bytes, which are treated as
+// untrusted and held to the ingestion limits
 func LoadFromBytes(data []byte) (*Ticket, error) {
 	var ticket Ticket
-	if err := yaml.Unmarshal(data, &ticket); err != nil {
+	if err := decode(data, &ticket); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
@@ -81,6 +126,9 @@ func (t *Ticket) Validate() error {
 	if t.ID == "" {
 		return errors.New("ticket ID is required")
 	}
+	if !validID.MatchString(t.ID) {
+		return fmt.Errorf("ticket ID %q may only contain letters, digits, '.', '-' and '_', and cannot start with '.'", t.ID)
+	}
 	
 	if t.Title == "" {
 		return errors.New("ticket title is required")
@@ -93,10 +141,66 @@ func (t *Ticket) Validate() error {
 	if t.Priority < 1 || t.Priority > 5 {
 		return errors.New("ticket priority must be between 1 and 5")
 	}
+
+	switch t.Type {
+	case "", TypeFeature, TypeBug, TypeRefactor, TypeChore:
+	default:
+		return fmt.Errorf("unknown ticket type %q (expected feature, bug, refactor or chore)", t.Type)
+	}
+	if t.Reproduce != "" && t.Type != TypeBug {
+		return errors.New("reproduce is only supported on tickets of type bug")
+	}
+
+	for i, check := range t.Done {
+		if check.Name == "" || check.Run == "" {
+			return fmt.Errorf("done check %d needs a name and a run command", i+1)
+		}
+	}
+
+	if t.Resolves != nil && (t.Resolves.Ticket == "" || t.Resolves.Branch == "") {
+		return errors.New("resolves needs the ticket and branch whose conflicts to resolve")
+	}
 	
 	return nil
 }
 
+// DoneCheck is a step of a ticket's definition of done, run on the main
+// branch after the ticket is merged, e.g. building main or a smoke test
+type DoneCheck struct {
+	Name string `yaml:"name" json:"name"`
+	Run  string `yaml:"run" json:"run"` // Shell command, run from the repository root
+}
+
+// Resolution names the ticket whose branch a conflict resolution ticket
+// merges with main
+type Resolution struct {
+	Ticket string   `yaml:"ticket" json:"ticket"`
+	Branch string   `yaml:"branch" json:"branch"`
+	Commit string   `yaml:"commit,omitempty" json:"commit,omitempty"` // Branch tip the conflicts were found at
+	Files  []string `yaml:"files,omitempty" json:"files,omitempty"`   // Files git could not merge
+}
+
+// Signature is an ed25519 signature over a ticket's canonical YAML
+type Signature struct {
+	Key   string `yaml:"key" json:"key"`     // Name of the public key that verifies it
+	Value string `yaml:"value" json:"value"` // Base64 signature
+}
+
+// Canonical returns the YAML a ticket's signature covers: the ticket without
+// its signature, provenance and timestamps, which are filled in after signing,
+// and without backlog defaults or rendered descriptions
+func (t *Ticket) Canonical() ([]byte, error) {
+	c := *t
+	if t.written != nil {
+		c = *t.written
+	}
+	c.Signature = nil
+	c.Provenance = nil
+	c.CreatedAt = time.Time{}
+	c.UpdatedAt = time.Time{}
+	return yaml.Marshal(&c)
+}
+
 // ToYAML returns the ticket as YAML bytes
 func (t *Ticket) ToYAML() ([]byte, error) {
 	return yaml.Marshal(t)
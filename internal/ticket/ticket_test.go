@@ -3,6 +3,7 @@ package ticket
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -158,6 +159,37 @@ priority: 1`
 	}
 }
 
+func TestLoadIgnoresRuntimeFields(t *testing.T) {
+	data := `id: "test-123"
+title: "Test ticket"
+description: "Runs on main"
+priority: 1
+checkpoint: main
+branch: main
+attempt: 7
+retry_branch: attempt
+failure_code: ci_failed
+push_veto: "no"
+retry_after: 2030-01-01T00:00:00Z`
+
+	tk, err := LoadFromBytes([]byte(data))
+	if err != nil {
+		t.Fatalf("Failed to load ticket: %v", err)
+	}
+	if tk.Checkpoint != "" || tk.Branch != "" || tk.Attempt != 0 || tk.RetryBranch != "" || tk.FailureCode != "" || tk.PushVeto != "" || !tk.RetryAfter.IsZero() {
+		t.Errorf("Expected runtime fields to be ignored in ticket files, got %+v", tk)
+	}
+
+	tk.Checkpoint, tk.Attempt = "agent-1/test-123", 2
+	out, err := tk.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	if strings.Contains(string(out), "checkpoint") || strings.Contains(string(out), "attempt") {
+		t.Errorf("Expected runtime fields to stay out of YAML, got:\n%s", out)
+	}
+}
+
 func TestTicketValidate(t *testing.T) {
 	// Test valid ticket
 	validTicket := &Ticket{
@@ -183,6 +215,30 @@ func TestTicketValidate(t *testing.T) {
 	if err := validTicket.Validate(); err != nil {
 		t.Errorf("Priority 5 should be valid, got: %v", err)
 	}
+
+	validTicket.Reproduce = "go test ./parser"
+	if err := validTicket.Validate(); err == nil {
+		t.Error("Expected reproduce on a feature to fail validation")
+	}
+	validTicket.Type = TypeBug
+	if err := validTicket.Validate(); err != nil {
+		t.Errorf("Expected a bug with a reproduce command to be valid, got: %v", err)
+	}
+	validTicket.Type = "epic"
+	validTicket.Reproduce = ""
+	if err := validTicket.Validate(); err == nil {
+		t.Error("Expected an unknown type to fail validation")
+	}
+	validTicket.Type = ""
+
+	validTicket.Resolves = &Resolution{Ticket: "feat-1"}
+	if err := validTicket.Validate(); err == nil {
+		t.Error("Expected resolves without a branch to fail validation")
+	}
+	validTicket.Resolves.Branch = "agent-1/feat-1"
+	if err := validTicket.Validate(); err != nil {
+		t.Errorf("Expected resolves with a ticket and branch to be valid, got: %v", err)
+	}
 }
 
 func TestToYAML(t *testing.T) {
@@ -0,0 +1,231 @@
+// Package ticketlog keeps a log file per ticket run, under
+// <path>/<ticket-id>/, of the commands the worker ran (git, the agent, CI)
+// with their output, so one ticket's story can be read apart from the
+// daemon's interleaved output
+package ticketlog
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// Config controls per-ticket log files
+type Config struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"` // Directory holding a <ticket-id>/ directory per ticket
+}
+
+// Validate checks the ticket log settings
+func (c Config) Validate() error {
+	if c.Enabled && c.Path == "" {
+		return errors.New("path is required")
+	}
+	return nil
+}
+
+// Log is one run's log file; a nil Log discards everything
+type Log struct {
+	mu     sync.Mutex
+	file   *os.File
+	cipher *encryption.Cipher
+}
+
+// Open creates the log file for a worker's run of a ticket, named after the
+// time it started; lines are encrypted one by one when cipher encrypts
+func Open(root, ticketID string, workerID int, cipher *encryption.Cipher) (*Log, error) {
+	if ticketID == "" || ticketID != filepath.Base(ticketID) || strings.HasPrefix(ticketID, ".") {
+		return nil, fmt.Errorf("ticket ID %q cannot be used as a directory name", ticketID)
+	}
+	dir := filepath.Join(root, ticketID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create ticket log directory: %w", err)
+	}
+	name := fmt.Sprintf("%s-agent-%d.log", time.Now().UTC().Format("20060102T150405Z"), workerID)
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ticket log: %w", err)
+	}
+	return &Log{file: file, cipher: cipher}, nil
+}
+
+// Printf writes a line from source, e.g. worker or ci
+func (l *Log) Printf(source, format string, args ...interface{}) {
+	l.Output(source, []byte(fmt.Sprintf(format, args...)))
+}
+
+// Output writes every line of output from source
+func (l *Log) Output(source string, output []byte) {
+	if l == nil {
+		return
+	}
+	text := strings.TrimRight(string(output), "\n")
+	if text == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	stamp := timefmt.Machine(time.Now())
+	for _, line := range strings.Split(text, "\n") {
+		entry := []byte(fmt.Sprintf("%s [%s] %s", stamp, source, strings.TrimRight(line, "\r")))
+		if l.cipher.Encrypts() {
+			sealed, err := l.cipher.Encrypt(entry)
+			if err != nil {
+				continue
+			}
+			entry = []byte(base64.StdEncoding.EncodeToString(sealed))
+		}
+		l.file.Write(append(entry, '\n'))
+	}
+}
+
+// Close closes the log file; later writes are discarded
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Runner logs every command it runs, with its output and how it exited, to
+// the log of the ticket being worked on, if any
+type Runner struct {
+	inner command.Runner
+	mu    sync.Mutex
+	log   *Log
+}
+
+// NewRunner returns a runner running commands with inner
+func NewRunner(inner command.Runner) *Runner {
+	return &Runner{inner: command.Or(inner)}
+}
+
+// SetLog makes l the log commands are written to; nil stops logging
+func (r *Runner) SetLog(l *Log) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = l
+}
+
+func (r *Runner) current() *Log {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.log
+}
+
+func (r *Runner) Run(cmd *exec.Cmd) error {
+	l := r.current()
+	source := logCommand(l, cmd)
+	err := r.inner.Run(cmd)
+	logExit(l, source, err)
+	return err
+}
+
+func (r *Runner) Output(cmd *exec.Cmd) ([]byte, error) {
+	l := r.current()
+	source := logCommand(l, cmd)
+	output, err := r.inner.Output(cmd)
+	l.Output(source, output)
+	logExit(l, source, err)
+	return output, err
+}
+
+func (r *Runner) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	l := r.current()
+	source := logCommand(l, cmd)
+	output, err := r.inner.CombinedOutput(cmd)
+	l.Output(source, output)
+	logExit(l, source, err)
+	return output, err
+}
+
+// logCommand writes the command line and returns the source its output is
+// logged under: the program's name, e.g. git or amp
+func logCommand(l *Log, cmd *exec.Cmd) string {
+	source := filepath.Base(cmd.Path)
+	args := append([]string{source}, cmd.Args[1:]...)
+	l.Printf(source, "$ %s", strings.Join(args, " "))
+	return source
+}
+
+// logExit notes a command that failed
+func logExit(l *Log, source string, err error) {
+	if err != nil {
+		l.Printf(source, "failed: %v", err)
+	}
+}
+
+// Files returns a ticket's log files, oldest run first
+func Files(root, ticketID string) ([]string, error) {
+	if ticketID == "" || ticketID != filepath.Base(ticketID) {
+		return nil, fmt.Errorf("invalid ticket ID %q", ticketID)
+	}
+	matches, err := filepath.Glob(filepath.Join(root, ticketID, "*.log"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches) // Names start with the run's start time
+	return matches, nil
+}
+
+// Decode returns a log line as written, decrypting it if it was encrypted
+func Decode(line string, cipher *encryption.Cipher) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || !encryption.IsEncrypted(sealed) {
+		return line, nil
+	}
+	plain, err := cipher.Decrypt(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// Copy writes the lines of a log file read from r to w, decrypted, and
+// returns how many bytes of r it consumed; a trailing partial line is left
+// for the next call, so a growing file can be followed
+func Copy(w io.Writer, r io.Reader, cipher *encryption.Cipher) (int64, error) {
+	reader := bufio.NewReader(r)
+	var consumed int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return consumed, nil
+		}
+		if err != nil {
+			return consumed, err
+		}
+		consumed += int64(len(line))
+		text, err := Decode(strings.TrimRight(line, "\n"), cipher)
+		if err != nil {
+			return consumed, err
+		}
+		if _, err := fmt.Fprintln(w, text); err != nil {
+			return consumed, err
+		}
+	}
+}
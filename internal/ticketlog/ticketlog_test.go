@@ -0,0 +1,126 @@
+package ticketlog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// readLog returns the decoded contents of a ticket's only log file
+func readLog(t *testing.T, root, ticketID string, cipher *encryption.Cipher) string {
+	t.Helper()
+	files, err := Files(root, ticketID)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one log file, got %v (%v)", files, err)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	if _, err := Copy(&out, f, cipher); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	return out.String()
+}
+
+func TestRunnerLogsCommands(t *testing.T) {
+	root := t.TempDir()
+	recorder := command.NewRecorder()
+	recorder.Stub("git status", " M main.go\n", nil)
+	recorder.Stub("git push", "rejected\n", &command.ExitStatus{Code: 1})
+	runner := NewRunner(recorder)
+
+	// Commands run while no ticket's log is set are not logged anywhere
+	runner.CombinedOutput(exec.Command("git", "fetch"))
+
+	l, err := Open(root, "feat-1", 2, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	runner.SetLog(l)
+	l.Printf("worker", "Processing %s", "feat-1")
+	runner.Output(exec.Command("git", "status", "--porcelain"))
+	runner.CombinedOutput(exec.Command("git", "push", "origin", "agent-2/feat-1"))
+	runner.SetLog(nil)
+	l.Close()
+	l.Printf("worker", "written after close")
+
+	text := readLog(t, root, "feat-1", nil)
+	for _, want := range []string{
+		"[worker] Processing feat-1",
+		"[git] $ git status --porcelain",
+		"[git]  M main.go",
+		"[git] $ git push origin agent-2/feat-1",
+		"[git] rejected",
+		"[git] failed: exit status 1",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Log should contain %q:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"git fetch", "after close"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("Log should not contain %q:\n%s", unwanted, text)
+		}
+	}
+	if len(recorder.Calls()) != 3 {
+		t.Errorf("Every command should still run, got %v", recorder.Calls())
+	}
+}
+
+func TestLogEncryptsLines(t *testing.T) {
+	root := t.TempDir()
+	cipher, err := encryption.NewCipher(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+	l, err := Open(root, "feat-1", 1, cipher)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	l.Output("amp", []byte("first secret\nsecond secret\n"))
+	l.Close()
+
+	files, _ := Files(root, "feat-1")
+	raw, _ := os.ReadFile(files[0])
+	if bytes.Contains(raw, []byte("secret")) {
+		t.Errorf("Log lines should be encrypted:\n%s", raw)
+	}
+	text := readLog(t, root, "feat-1", cipher)
+	if !strings.Contains(text, "[amp] first secret\n") || !strings.Contains(text, "[amp] second secret\n") {
+		t.Errorf("Decrypted log should have both lines:\n%s", text)
+	}
+
+	f, _ := os.Open(files[0])
+	defer f.Close()
+	if _, err := Copy(&bytes.Buffer{}, f, nil); !errors.Is(err, encryption.ErrNoKey) {
+		t.Errorf("Expected ErrNoKey reading without the key, got %v", err)
+	}
+}
+
+func TestCopyLeavesPartialLine(t *testing.T) {
+	var out bytes.Buffer
+	consumed, err := Copy(&out, strings.NewReader("one\ntwo\nthr"), nil)
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if out.String() != "one\ntwo\n" || consumed != 8 {
+		t.Errorf("Expected two lines and 8 bytes, got %q and %d", out.String(), consumed)
+	}
+}
+
+func TestOpenRejectsUnsafeTicketIDs(t *testing.T) {
+	for _, id := range []string{"", "../escape", "a/b", ".hidden"} {
+		if _, err := Open(t.TempDir(), id, 1, nil); err == nil {
+			t.Errorf("Ticket ID %q should be rejected", id)
+		}
+	}
+}
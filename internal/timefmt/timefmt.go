@@ -0,0 +1,158 @@
+// Package timefmt formats timestamps for people the same way across the CLI,
+// TUI and logs, in a configured timezone and layout. Machine outputs (events,
+// CI status files, CSV metrics) use Machine: RFC3339 in UTC.
+package timefmt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for timestamps and times of day
+const (
+	DefaultFormat      = time.DateTime // 2006-01-02 15:04:05
+	DefaultClockFormat = time.TimeOnly // 15:04:05
+)
+
+// namedFormats are the layouts Format and ClockFormat may name instead of
+// giving a Go layout
+var namedFormats = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123Z,
+	"datetime": time.DateTime,
+	"time":     time.TimeOnly,
+	"kitchen":  time.Kitchen,
+}
+
+// Config sets the timezone and layouts timestamps are shown in
+type Config struct {
+	Timezone    string `mapstructure:"timezone"`     // IANA name such as Europe/Berlin, or UTC; empty or Local uses the machine's
+	Format      string `mapstructure:"format"`       // Go layout or rfc3339, rfc1123, datetime; empty uses DefaultFormat
+	ClockFormat string `mapstructure:"clock_format"` // Times of day in the TUI and timelines; empty uses DefaultClockFormat
+}
+
+// Validate checks the timezone and layouts
+func (c Config) Validate() error {
+	_, err := c.resolve()
+	return err
+}
+
+// formatter is a resolved Config
+type formatter struct {
+	location *time.Location
+	format   string
+	clock    string
+}
+
+// resolve loads the timezone and expands named layouts
+func (c Config) resolve() (formatter, error) {
+	f := formatter{location: time.Local, format: DefaultFormat, clock: DefaultClockFormat}
+	if c.Timezone != "" && c.Timezone != "Local" {
+		location, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return f, fmt.Errorf("unknown timezone %q: %w", c.Timezone, err)
+		}
+		f.location = location
+	}
+	var err error
+	if f.format, err = layout(c.Format, DefaultFormat); err != nil {
+		return f, fmt.Errorf("format: %w", err)
+	}
+	if f.clock, err = layout(c.ClockFormat, DefaultClockFormat); err != nil {
+		return f, fmt.Errorf("clock_format: %w", err)
+	}
+	return f, nil
+}
+
+// layout expands a named layout, checking that a Go layout has at least
+// one time element in it
+func layout(value, fallback string) (string, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if named, ok := namedFormats[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+	// A layout with no time elements in it formats as itself
+	sample := time.Date(2001, time.November, 12, 13, 14, 15, 0, time.UTC)
+	if sample.Format(value) == value {
+		return "", fmt.Errorf("%q is not a Go time layout or one of rfc3339, rfc1123, datetime, time, kitchen", value)
+	}
+	return value, nil
+}
+
+var (
+	mu      sync.RWMutex
+	current = formatter{location: time.Local, format: DefaultFormat, clock: DefaultClockFormat}
+)
+
+// Set makes c the configuration used by the formatting functions
+func Set(c Config) error {
+	f, err := c.resolve()
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current = f
+	return nil
+}
+
+func get() formatter {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Timestamp formats a full date and time for people
+func Timestamp(t time.Time) string {
+	f := get()
+	return t.In(f.location).Format(f.format)
+}
+
+// Clock formats a time of day for people
+func Clock(t time.Time) string {
+	f := get()
+	return t.In(f.location).Format(f.clock)
+}
+
+// Location returns the configured timezone
+func Location() *time.Location {
+	return get().location
+}
+
+// Machine formats t for files and other programs: RFC3339 in UTC
+func Machine(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// LogWriter prefixes every line written to w with the current Timestamp;
+// use it with log.SetFlags(0) so log lines follow the configuration
+func LogWriter(w io.Writer) io.Writer {
+	return &logWriter{out: w, now: time.Now}
+}
+
+type logWriter struct {
+	out io.Writer
+	now func() time.Time
+}
+
+func (l *logWriter) Write(p []byte) (int, error) {
+	var b bytes.Buffer
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		b.WriteString(Timestamp(l.now()))
+		b.WriteByte(' ')
+		b.Write(line)
+	}
+	if _, err := l.out.Write(b.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
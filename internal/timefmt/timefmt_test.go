@@ -0,0 +1,74 @@
+package timefmt
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFormatting(t *testing.T) {
+	t.Cleanup(func() { Set(Config{}) })
+
+	moment := time.Date(2024, time.March, 5, 14, 30, 15, 0, time.UTC)
+	if err := Set(Config{Timezone: "Asia/Tokyo"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got := Timestamp(moment); got != "2024-03-05 23:30:15" {
+		t.Errorf("Timestamp = %q", got)
+	}
+	if got := Clock(moment); got != "23:30:15" {
+		t.Errorf("Clock = %q", got)
+	}
+	if got := Machine(moment.In(time.FixedZone("X", 3600))); got != "2024-03-05T14:30:15Z" {
+		t.Errorf("Expected machine output in UTC, got %q", got)
+	}
+
+	if err := Set(Config{Timezone: "UTC", Format: "RFC3339", ClockFormat: "15:04"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got := Timestamp(moment); got != "2024-03-05T14:30:15Z" {
+		t.Errorf("Expected a named layout, got %q", got)
+	}
+	if got := Clock(moment); got != "14:30" {
+		t.Errorf("Expected a Go layout, got %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"defaults", Config{}, false},
+		{"local", Config{Timezone: "Local"}, false},
+		{"iana zone", Config{Timezone: "Europe/Berlin", Format: "02 Jan 2006 15:04"}, false},
+		{"unknown zone", Config{Timezone: "Mars/Olympus"}, true},
+		{"not a layout", Config{Format: "yyyy-mm-dd"}, true},
+		{"bad clock layout", Config{ClockFormat: "hh:mm"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	t.Cleanup(func() { Set(Config{}) })
+	if err := Set(Config{Timezone: "UTC"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	w := &logWriter{out: &out, now: func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }}
+	n, err := w.Write([]byte("first\nsecond\n"))
+	if err != nil || n != len("first\nsecond\n") {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if got, want := out.String(), "2024-01-02 03:04:05 first\n2024-01-02 03:04:05 second\n"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package timeline
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/timefmt"
+)
+
+// Render draws a timeline as a Gantt-style chart with bars width characters
+// wide; markers are drawn as * on their own rows
+func Render(w io.Writer, t Timeline, width int) {
+	if width < 10 {
+		width = 10
+	}
+	fmt.Fprintf(w, "Ticket %s: %s (%s - %s)\n", t.Ticket, FormatDuration(t.Duration()),
+		timefmt.Timestamp(t.Start), timefmt.Clock(t.End))
+
+	total := t.Duration()
+	column := func(at time.Time) int {
+		if total <= 0 {
+			return 0
+		}
+		col := int(float64(at.Sub(t.Start)) / float64(total) * float64(width-1))
+		if col < 0 {
+			col = 0
+		}
+		if col > width-1 {
+			col = width - 1
+		}
+		return col
+	}
+
+	for _, step := range t.Steps {
+		bar := []byte(strings.Repeat(" ", width))
+		start := column(step.Start)
+		switch {
+		case step.Marker:
+			bar[start] = '*'
+		case step.End.IsZero():
+			bar[start] = '|'
+		default:
+			for i := start; i <= column(step.End) && i < width; i++ {
+				bar[i] = '#'
+			}
+		}
+
+		took := ""
+		if d := step.Duration(); d > 0 {
+			took = FormatDuration(d)
+		}
+		// Phase messages only restate the phase; the worker is what matters
+		detail := step.Detail
+		if step.WorkerID > 0 && !step.Marker {
+			detail = fmt.Sprintf("worker %d", step.WorkerID)
+			if step.Name == "failed" {
+				detail += ": " + step.Detail
+			}
+		}
+		fmt.Fprintf(w, "%-12s %s [%s] %8s  %s\n", step.Name, timefmt.Clock(step.Start), bar, took, detail)
+	}
+}
+
+// FormatDuration renders a duration as e.g. 1h 5m, 3m 20s or 45s
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
@@ -0,0 +1,227 @@
+package timeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/audit"
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/journal"
+)
+
+// FileName is the journal's name within the state directory
+const FileName = "timeline.jsonl"
+
+// Entry records one event in a ticket's life
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Ticket   string    `json:"ticket"`
+	Event    string    `json:"event"`           // IPC event type
+	Phase    string    `json:"phase,omitempty"` // For ticket_phase events
+	Code     string    `json:"code,omitempty"`  // For ticket_failed events
+	WorkerID int       `json:"worker_id,omitempty"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// Journal appends ticket events to a JSON lines file
+// With an encrypting cipher each line is sealed and base64 encoded
+type Journal struct {
+	file *journal.Writer
+}
+
+// Open returns the journal in stateDir
+func Open(stateDir string, cipher *encryption.Cipher) *Journal {
+	return &Journal{file: journal.NewWriter(filepath.Join(stateDir, FileName), cipher)}
+}
+
+// Record appends an IPC event if it concerns a ticket
+func (j *Journal) Record(event ipc.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", event.Type, err)
+	}
+	var subject struct {
+		Ticket *struct {
+			ID string `json:"id"`
+		} `json:"ticket"`
+		Phase    string `json:"phase"`
+		Code     string `json:"code"`
+		WorkerID int    `json:"worker_id"`
+		Message  string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &subject); err != nil || subject.Ticket == nil || subject.Ticket.ID == "" {
+		return nil
+	}
+
+	return j.Append(Entry{
+		Time:     event.Timestamp.UTC(),
+		Ticket:   subject.Ticket.ID,
+		Event:    string(event.Type),
+		Phase:    subject.Phase,
+		Code:     subject.Code,
+		WorkerID: subject.WorkerID,
+		Message:  subject.Message,
+	})
+}
+
+// Append writes an entry to the end of the journal
+func (j *Journal) Append(entry Entry) error {
+	return j.file.Append(entry)
+}
+
+// Load reads the entries for a ticket from the journal in stateDir, oldest
+// first; an empty ticketID returns every entry
+func Load(stateDir string, cipher *encryption.Cipher, ticketID string) ([]Entry, error) {
+	var entries []Entry
+	err := journal.Read(filepath.Join(stateDir, FileName), cipher, func(data []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		if ticketID == "" || entry.Ticket == ticketID {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Step is a phase of a ticket's life, or a marker for something that
+// happened at one point in time, such as a command or a CI result
+type Step struct {
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end,omitempty"` // Start of the next phase; zero for markers and the last phase
+	WorkerID int       `json:"worker_id,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+	Marker   bool      `json:"marker,omitempty"`
+}
+
+// Duration returns how long a phase lasted
+func (s Step) Duration() time.Duration {
+	if s.End.IsZero() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// Timeline is a ticket's phases and markers in time order
+type Timeline struct {
+	Ticket string    `json:"ticket"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Steps  []Step    `json:"steps"`
+}
+
+// Duration returns the time from the first to the last step
+func (t Timeline) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// phases maps the events that move a ticket along to phase names
+var phases = map[string]string{
+	string(ipc.EventTypeTicketEnqueued):  "enqueued",
+	string(ipc.EventTypeTicketStarted):   "started",
+	string(ipc.EventTypeTicketComplete):  "completed",
+	string(ipc.EventTypeTicketFailed):    "failed",
+	string(ipc.EventTypeTicketPreempted): "preempted",
+	string(ipc.EventTypeTicketRejected):  "rejected",
+	string(ipc.EventTypePolicyViolation): "rejected",
+}
+
+// Build assembles a ticket's timeline from its journal entries, the audit
+// journal's commands naming it and its CI results
+func Build(ticketID string, entries []Entry, commands []audit.Entry, statuses []*ci.Status) Timeline {
+	var steps []Step
+	for _, entry := range entries {
+		if entry.Ticket != ticketID {
+			continue
+		}
+		step := Step{Start: entry.Time, WorkerID: entry.WorkerID, Detail: entry.Message}
+		if entry.Event == string(ipc.EventTypeTicketPhase) {
+			step.Name = entry.Phase
+		} else if name, ok := phases[entry.Event]; ok {
+			step.Name = name
+		} else {
+			step.Name = entry.Event
+			step.Marker = true
+		}
+		steps = append(steps, step)
+	}
+
+	for _, command := range commands {
+		if command.Args["ticket"] != ticketID && command.Args["target"] != ticketID {
+			continue
+		}
+		if command.Caller == audit.SystemCaller {
+			// Recorded from an event the ticket's journal already has
+			continue
+		}
+		detail := "by " + command.Caller.String()
+		if !command.OK {
+			detail += ": " + command.Error
+		}
+		steps = append(steps, Step{Name: command.Command, Start: command.Time, Detail: detail, Marker: true})
+	}
+
+	for _, status := range statuses {
+		if status.TicketID != ticketID {
+			continue
+		}
+		steps = append(steps, Step{
+			Name:   "ci_result",
+			Start:  status.Timestamp,
+			Detail: fmt.Sprintf("%s %s@%.8s", status.Status, status.Branch(), status.Commit),
+			Marker: true,
+		})
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Start.Before(steps[j].Start)
+	})
+
+	// Each phase lasts until the next one starts
+	var last *Step
+	for i := range steps {
+		if steps[i].Marker {
+			continue
+		}
+		if last != nil {
+			last.End = steps[i].Start
+		}
+		last = &steps[i]
+	}
+
+	timeline := Timeline{Ticket: ticketID, Steps: steps}
+	if len(steps) > 0 {
+		timeline.Start = steps[0].Start
+		timeline.End = steps[len(steps)-1].Start
+	}
+	return timeline
+}
+
+// Assemble builds a ticket's timeline from the journals in stateDir and the
+// CI results in statusDir
+func Assemble(stateDir string, cipher *encryption.Cipher, statusDir, ticketID string) (Timeline, error) {
+	entries, err := Load(stateDir, cipher, ticketID)
+	if err != nil {
+		return Timeline{}, err
+	}
+	commands, err := audit.Load(stateDir, cipher)
+	if err != nil {
+		return Timeline{}, err
+	}
+	statuses, err := ci.NewStatusReader(statusDir).GetByTicket(ticketID)
+	if err != nil {
+		return Timeline{}, err
+	}
+	return Build(ticketID, entries, commands, statuses), nil
+}
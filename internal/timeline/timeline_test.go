@@ -0,0 +1,138 @@
+package timeline
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/audit"
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestJournalRecordsTicketEvents(t *testing.T) {
+	stateDir := t.TempDir()
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	journal := Open(stateDir, cipher)
+
+	now := time.Now()
+	events := []ipc.Event{
+		{Type: ipc.EventTypeTicketStarted, Timestamp: now, Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-1"}, WorkerID: 2}},
+		{Type: ipc.EventTypeTicketPhase, Timestamp: now, Data: ipc.TicketPhaseEvent{Ticket: &ticket.Ticket{ID: "feat-1"}, WorkerID: 2, Phase: "agent"}},
+		{Type: ipc.EventTypeTicketStarted, Timestamp: now, Data: ipc.TicketEvent{Ticket: &ticket.Ticket{ID: "feat-2"}, WorkerID: 1}},
+		{Type: ipc.EventTypeDiskSpace, Timestamp: now, Data: ipc.DiskSpaceEvent{Path: "/work"}},
+	}
+	for _, event := range events {
+		if err := journal.Record(event); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(stateDir, FileName))
+	if strings.Contains(string(data), "feat-1") {
+		t.Error("Expected the journal to be encrypted")
+	}
+
+	entries, err := Load(stateDir, cipher, "feat-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Phase != "agent" || entries[1].WorkerID != 2 {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+	if all, _ := Load(stateDir, cipher, ""); len(all) != 3 {
+		t.Errorf("Expected 3 ticket events, got %d", len(all))
+	}
+}
+
+func TestBuild(t *testing.T) {
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	entries := []Entry{
+		{Time: at(0), Ticket: "feat-1", Event: "ticket_enqueued"},
+		{Time: at(2), Ticket: "feat-1", Event: "ticket_started", WorkerID: 3},
+		{Time: at(3), Ticket: "feat-1", Event: "ticket_phase", Phase: "agent", WorkerID: 3},
+		{Time: at(13), Ticket: "feat-1", Event: "ticket_phase", Phase: "ci", WorkerID: 3},
+		{Time: at(15), Ticket: "feat-1", Event: "ci_flaky", Message: "example.com/app passed on retry"},
+		{Time: at(18), Ticket: "feat-1", Event: "ticket_complete", WorkerID: 3},
+		{Time: at(5), Ticket: "feat-2", Event: "ticket_enqueued"},
+	}
+	commands := []audit.Entry{
+		{Time: at(16), Command: "ci_rerun", Args: map[string]string{"target": "feat-1"}, Caller: ipc.Caller{Token: "alice"}, OK: true},
+		{Time: at(17), Command: "ci_rerun", Args: map[string]string{"target": "feat-2"}, OK: true},
+	}
+	statuses := []*ci.Status{
+		{Ref: "refs/heads/agent-3/feat-1", Commit: "0123456789abcdef", TicketID: "feat-1", Status: "PASS", Timestamp: at(17)},
+	}
+
+	tl := Build("feat-1", entries, commands, statuses)
+	if tl.Duration() != 18*time.Minute {
+		t.Errorf("Expected 18m total, got %v", tl.Duration())
+	}
+
+	var names []string
+	for _, step := range tl.Steps {
+		names = append(names, step.Name)
+	}
+	if got := strings.Join(names, " "); got != "enqueued started agent ci ci_flaky ci_rerun ci_result completed" {
+		t.Fatalf("Unexpected steps %q", got)
+	}
+
+	// Phases last until the next phase; markers don't cut them short
+	durations := map[string]time.Duration{}
+	for _, step := range tl.Steps {
+		durations[step.Name] = step.Duration()
+	}
+	if durations["enqueued"] != 2*time.Minute || durations["agent"] != 10*time.Minute || durations["ci"] != 5*time.Minute {
+		t.Errorf("Unexpected durations %v", durations)
+	}
+	if durations["completed"] != 0 || durations["ci_flaky"] != 0 {
+		t.Errorf("Expected markers and the last phase to have no duration, got %v", durations)
+	}
+	if tl.Steps[5].Detail != "by alice" || tl.Steps[6].Detail != "PASS agent-3/feat-1@01234567" {
+		t.Errorf("Unexpected marker details %q, %q", tl.Steps[5].Detail, tl.Steps[6].Detail)
+	}
+
+	var buf bytes.Buffer
+	Render(&buf, tl, 20)
+	out := buf.String()
+	if !strings.Contains(out, "Ticket feat-1: 18m 0s") {
+		t.Errorf("Expected a header with the total, got:\n%s", out)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 9 {
+		t.Fatalf("Expected a header and 8 rows, got:\n%s", out)
+	}
+	if !strings.Contains(lines[3], "[   ##########") || !strings.Contains(lines[3], "10m 0s  worker 3") {
+		t.Errorf("Unexpected agent row %q", lines[3])
+	}
+	if !strings.Contains(lines[5], "*") || !strings.HasSuffix(lines[5], "example.com/app passed on retry") {
+		t.Errorf("Unexpected marker row %q", lines[5])
+	}
+	if !strings.Contains(lines[8], "|]") {
+		t.Errorf("Expected the last phase at the end of the chart, got %q", lines[8])
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		45 * time.Second:        "45s",
+		200 * time.Second:       "3m 20s",
+		65 * time.Minute:        "1h 5m",
+		1500 * time.Millisecond: "2s",
+	}
+	for d, want := range tests {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// mainStateFile records how far CI results on main have been read, in the
+// state directory
+const mainStateFile = "verify_main.json"
+
+// mainState is where the main CI watch left off
+type mainState struct {
+	CheckedThrough time.Time `json:"checked_through"` // Timestamp of the last status read
+	Broken         bool      `json:"broken"`          // Whether main's last CI run failed
+}
+
+// WatchMainCI makes the verifier also read CI results for main from the
+// status directory, enqueueing a revert ticket for the merge that broke it
+// Only statuses recorded after the first call are considered
+func (v *Verifier) WatchMainCI(statusDir string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.statuses = ci.NewStatusReader(statusDir)
+	v.mainStatePath = filepath.Join(filepath.Dir(v.statePath), mainStateFile)
+	data, err := os.ReadFile(v.mainStatePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read main CI state: %w", err)
+		}
+		v.main = mainState{CheckedThrough: time.Now()}
+		return v.saveMainState()
+	}
+	if err := json.Unmarshal(data, &v.main); err != nil {
+		return fmt.Errorf("failed to parse main CI state: %w", err)
+	}
+	return nil
+}
+
+// CheckMainCI reads the CI results for main recorded since the last call
+// Each time main goes from passing to failing, the latest merge at the
+// failing commit is blamed; failures while main is already broken are not
+func (v *Verifier) CheckMainCI() []ipc.VerificationEvent {
+	results := v.readMainCI()
+	if v.onResult != nil {
+		for _, result := range results {
+			v.onResult(result)
+		}
+	}
+	return results
+}
+
+// readMainCI blames the new failures on main and saves where it left off
+func (v *Verifier) readMainCI() []ipc.VerificationEvent {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.statuses == nil {
+		return nil
+	}
+
+	repo := gitutils.NewRepo(v.repoPath)
+	mainBranch, err := repo.MainBranch()
+	if err != nil {
+		log.Printf("Failed to find the main branch: %v", err)
+		return nil
+	}
+	statuses, err := v.statuses.ListSince(v.main.CheckedThrough)
+	if err != nil {
+		log.Printf("Failed to read CI statuses: %v", err)
+		return nil
+	}
+
+	var results []ipc.VerificationEvent
+	for _, status := range statuses {
+		if !status.Timestamp.After(v.main.CheckedThrough) {
+			continue
+		}
+		v.main.CheckedThrough = status.Timestamp
+		if status.Branch() != mainBranch {
+			continue
+		}
+		if status.Passed() {
+			v.main.Broken = false
+			continue
+		}
+		if v.main.Broken {
+			continue
+		}
+		v.main.Broken = true
+		results = append(results, v.blame(repo, status))
+	}
+	if err := v.saveMainState(); err != nil {
+		log.Printf("Failed to save main CI state: %v", err)
+	}
+	return results
+}
+
+// blame finds the merge behind a failed CI run on main and enqueues a
+// revert ticket for it; the caller holds mu
+func (v *Verifier) blame(repo *gitutils.GitRepo, status *ci.Status) ipc.VerificationEvent {
+	result := ipc.VerificationEvent{Commit: status.Commit, Check: "ci", Action: "none"}
+
+	merge, err := repo.LastMerge(status.Commit)
+	var parents []string
+	if err == nil && merge != "" {
+		parents, err = repo.Parents(merge)
+	}
+	if err != nil || len(parents) < 2 {
+		result.Message = fmt.Sprintf("CI failed on main at %s, but no merge to blame was found", shortHash(status.Commit))
+		if err != nil {
+			result.Message += ": " + err.Error()
+		}
+		log.Print(result.Message)
+		return result
+	}
+
+	// The merged branch tip was CI'd as the ticket's branch
+	b := breakage{
+		Commit:      parents[1],
+		MergeCommit: merge,
+		Failure:     fmt.Sprintf("CI then failed on main at %s", status.Commit),
+		Output:      []byte(status.Output),
+	}
+	if branchStatus, err := v.statuses.GetStatus(parents[1]); err == nil {
+		b.TicketID = branchStatus.TicketID
+		b.Branch = branchStatus.Branch()
+		b.Title = "CI failed on main"
+	} else {
+		b.Branch = "an unknown branch"
+	}
+
+	result.TicketID = b.TicketID
+	result.Message = fmt.Sprintf("CI failed on main at %s after merge %s", shortHash(status.Commit), shortHash(merge))
+	log.Print(result.Message)
+	if err := v.enqueueRevert(b); err != nil {
+		log.Printf("Failed to enqueue revert ticket for merge %s: %v", shortHash(merge), err)
+		return result
+	}
+	result.Action = OnFailureRevertTicket
+	return result
+}
+
+// saveMainState writes where the main CI watch left off; the caller holds mu
+func (v *Verifier) saveMainState() error {
+	data, err := json.MarshalIndent(v.main, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.mainStatePath), 0755); err != nil {
+		return err
+	}
+	tmp := v.mainStatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.mainStatePath)
+}
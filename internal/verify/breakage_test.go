@@ -0,0 +1,94 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestCheckMainCIBlamesBreakingMerge(t *testing.T) {
+	r := newTestRepo(t)
+	stateDir, backlogDir, statusDir := t.TempDir(), t.TempDir(), t.TempDir()
+	v := newVerifier(t, r, OnFailureRevertTicket, stateDir, backlogDir)
+	if err := v.WatchMainCI(statusDir); err != nil {
+		t.Fatalf("WatchMainCI failed: %v", err)
+	}
+
+	now := time.Now()
+	write := func(ref, commit, ticketID, result string, at time.Duration) {
+		t.Helper()
+		status := &ci.Status{Ref: ref, Commit: commit, TicketID: ticketID, Status: result, Output: "FAIL: TestThing", Timestamp: now.Add(at)}
+		if err := ci.WriteStatus(statusDir, status); err != nil {
+			t.Fatalf("Failed to write status: %v", err)
+		}
+	}
+	mainRef := "refs/heads/" + r.mainBranch
+
+	r.branch(t, "agent-1/feat-bad", "bad.txt")
+	tip := git(t, r.clone, "rev-parse", "HEAD")
+	write("refs/heads/agent-1/feat-bad", tip, "feat-bad", "PASS", time.Second)
+	r.merge(t, "agent-1/feat-bad")
+	merge := git(t, r.clone, "rev-parse", "HEAD")
+	write(mainRef, merge, "", "FAIL", 2*time.Second)
+
+	results := v.CheckMainCI()
+	if len(results) != 1 || results[0].TicketID != "feat-bad" || results[0].Action != OnFailureRevertTicket {
+		t.Fatalf("Expected feat-bad to be blamed, got %+v", results)
+	}
+	revert, err := ticket.Load(filepath.Join(backlogDir, "revert-feat-bad.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load revert ticket: %v", err)
+	}
+	if revert.Priority != 1 || !strings.Contains(revert.Description, merge) || !strings.Contains(revert.Description, "FAIL: TestThing") {
+		t.Errorf("Expected an urgent ticket naming merge %s, got %+v", merge, revert)
+	}
+
+	// Main staying broken blames nothing new, even after a restart
+	git(t, r.clone, "commit", "-q", "--allow-empty", "-m", "Still broken")
+	git(t, r.clone, "push", "-q", "origin", r.mainBranch)
+	write(mainRef, git(t, r.clone, "rev-parse", "HEAD"), "", "FAIL", 3*time.Second)
+	v = newVerifier(t, r, OnFailureRevertTicket, stateDir, backlogDir)
+	if err := v.WatchMainCI(statusDir); err != nil {
+		t.Fatalf("WatchMainCI failed: %v", err)
+	}
+	if results := v.CheckMainCI(); len(results) != 0 {
+		t.Fatalf("Expected no blame while main stays broken, got %+v", results)
+	}
+
+	// Once main passes again, the next breaking merge is blamed; an unknown
+	// branch is named after the merge commit
+	write(mainRef, strings.Repeat("a", 40), "", "PASS", 4*time.Second)
+	r.branch(t, "feature/other", "other.txt")
+	r.merge(t, "feature/other")
+	other := git(t, r.clone, "rev-parse", "HEAD")
+	write(mainRef, other, "", "FAIL", 5*time.Second)
+	results = v.CheckMainCI()
+	if len(results) != 1 || results[0].TicketID != "" {
+		t.Fatalf("Expected the other merge to be blamed, got %+v", results)
+	}
+	if _, err := os.Stat(filepath.Join(backlogDir, "revert-"+other[:8]+".yaml")); err != nil {
+		t.Errorf("Expected a revert ticket named after the merge: %v", err)
+	}
+}
+
+func TestWatchMainCIIgnoresEarlierStatuses(t *testing.T) {
+	r := newTestRepo(t)
+	statusDir := t.TempDir()
+	status := &ci.Status{Ref: "refs/heads/" + r.mainBranch, Commit: strings.Repeat("b", 40), Status: "FAIL", Timestamp: time.Now().Add(-time.Hour)}
+	if err := ci.WriteStatus(statusDir, status); err != nil {
+		t.Fatalf("Failed to write status: %v", err)
+	}
+
+	v := newVerifier(t, r, OnFailureRevertTicket, t.TempDir(), t.TempDir())
+	if err := v.WatchMainCI(statusDir); err != nil {
+		t.Fatalf("WatchMainCI failed: %v", err)
+	}
+	if results := v.CheckMainCI(); len(results) != 0 {
+		t.Errorf("Expected failures from before the watch started to be ignored, got %+v", results)
+	}
+}
@@ -0,0 +1,465 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// What the verifier does when a merged ticket fails its definition of done
+const (
+	OnFailureRevertTicket = "revert_ticket" // Enqueue an urgent ticket to fix or revert the change
+	OnFailureRollback     = "rollback"      // Revert the merge on main, falling back to a revert ticket
+)
+
+// pendingFile holds the tickets waiting to be merged, in the state directory
+const pendingFile = "verify.json"
+
+// maxOutput is how much of a failed check's output goes into a revert ticket
+const maxOutput = 8 << 10
+
+// Config controls post-merge verification
+type Config struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	IntervalSeconds int    `mapstructure:"interval_seconds"` // How often main is checked for merged tickets
+	Timeout         int    `mapstructure:"timeout"`          // Seconds each check may take; 0 = no limit
+	OnFailure       string `mapstructure:"on_failure"`       // revert_ticket or rollback
+	MainCI          bool   `mapstructure:"main_ci"`          // Enqueue a revert ticket for the merge that breaks CI on main
+}
+
+// Validate checks the verification settings
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.IntervalSeconds < 1 {
+		return errors.New("interval_seconds must be at least 1")
+	}
+	if c.Timeout < 0 {
+		return errors.New("timeout cannot be negative")
+	}
+	if c.OnFailure != OnFailureRevertTicket && c.OnFailure != OnFailureRollback {
+		return fmt.Errorf("unknown on_failure %q (expected %s or %s)", c.OnFailure, OnFailureRevertTicket, OnFailureRollback)
+	}
+	return nil
+}
+
+// Pending is a completed ticket whose checks run once it is merged
+type Pending struct {
+	TicketID    string             `json:"ticket_id"`
+	Title       string             `json:"title"`
+	RepoPath    string             `json:"repo_path"`
+	Branch      string             `json:"branch"`
+	Commit      string             `json:"commit"` // Branch tip when the ticket completed
+	Checks      []ticket.DoneCheck `json:"checks"`
+	CompletedAt time.Time          `json:"completed_at"`
+}
+
+// Verifier runs merged tickets' definition of done checks on main
+type Verifier struct {
+	config       Config
+	repoPath     string
+	environments environment.Environments
+	workDir      string // Detached worktrees for checks are created here
+	backlogDir   string // Revert tickets are written here
+	statePath    string
+	onResult     func(ipc.VerificationEvent) // Optional
+	runner       command.Runner              // Runs the checks; nil uses command.Default
+
+	mu      sync.Mutex
+	pending map[string]Pending
+
+	// Set by WatchMainCI
+	statuses      *ci.StatusReader
+	mainStatePath string
+	main          mainState
+}
+
+// New creates a verifier, loading the tickets still waiting to be merged
+// from the state directory
+func New(config Config, repoPath string, environments environment.Environments, workDir, backlogDir, stateDir string) (*Verifier, error) {
+	v := &Verifier{
+		config:       config,
+		repoPath:     repoPath,
+		environments: environments,
+		workDir:      workDir,
+		backlogDir:   backlogDir,
+		statePath:    filepath.Join(stateDir, pendingFile),
+		pending:      make(map[string]Pending),
+	}
+
+	data, err := os.ReadFile(v.statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return v, nil
+		}
+		return nil, fmt.Errorf("failed to read pending verifications: %w", err)
+	}
+	var pending []Pending
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("failed to parse pending verifications: %w", err)
+	}
+	for _, p := range pending {
+		v.pending[p.TicketID] = p
+	}
+	return v, nil
+}
+
+// SetResultHandler sets a function called with the outcome of each verification
+func (v *Verifier) SetResultHandler(handler func(ipc.VerificationEvent)) {
+	v.onResult = handler
+}
+
+// Record remembers the branch commit of a completed ticket that declares
+// done checks, so they can run once that commit reaches main
+func (v *Verifier) Record(event ipc.Event) error {
+	data, ok := event.Data.(ipc.TicketEvent)
+	if !ok || event.Type != ipc.EventTypeTicketComplete || data.Ticket == nil || len(data.Ticket.Done) == 0 {
+		return nil
+	}
+	t := data.Ticket
+
+	repoPath := v.repoPath
+	if settings, err := v.environments.Lookup(t.Environment); err == nil && settings.Repository != "" {
+		repoPath = settings.Repository
+	}
+	commit, err := gitutils.NewRepo(repoPath).GetBranchCommit(t.Branch)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s for verification: %w", t.Branch, err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pending[t.ID] = Pending{
+		TicketID:    t.ID,
+		Title:       t.Title,
+		RepoPath:    repoPath,
+		Branch:      t.Branch,
+		Commit:      commit,
+		Checks:      t.Done,
+		CompletedAt: event.Timestamp,
+	}
+	log.Printf("Ticket %s will be verified once %s is merged", t.ID, shortHash(commit))
+	return v.save()
+}
+
+// Pending returns the tickets waiting to be merged, oldest first
+func (v *Verifier) Pending() []Pending {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	pending := make([]Pending, 0, len(v.pending))
+	for _, p := range v.pending {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CompletedAt.Before(pending[j].CompletedAt) })
+	return pending
+}
+
+// Run checks for merged tickets, and for CI failures on main if watched,
+// every interval until ctx is done
+func (v *Verifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(v.config.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.Check(ctx)
+			v.CheckMainCI()
+		}
+	}
+}
+
+// Check verifies every pending ticket that has reached main and returns the
+// outcomes; tickets not merged yet stay pending
+func (v *Verifier) Check(ctx context.Context) []ipc.VerificationEvent {
+	var results []ipc.VerificationEvent
+	for _, p := range v.Pending() {
+		merged, err := gitutils.NewRepo(p.RepoPath).IsMerged(p.Commit)
+		if err != nil {
+			log.Printf("Failed to check whether %s is merged: %v", p.TicketID, err)
+			continue
+		}
+		if !merged || ctx.Err() != nil {
+			continue
+		}
+
+		result, err := v.verify(ctx, p)
+		if ctx.Err() != nil {
+			return results // Interrupted checks are retried on the next start
+		}
+		if err != nil {
+			log.Printf("Failed to verify %s, retrying later: %v", p.TicketID, err)
+			continue
+		}
+		results = append(results, result)
+
+		v.mu.Lock()
+		delete(v.pending, p.TicketID)
+		if err := v.save(); err != nil {
+			log.Printf("Failed to save pending verifications: %v", err)
+		}
+		v.mu.Unlock()
+
+		if v.onResult != nil {
+			v.onResult(result)
+		}
+	}
+	return results
+}
+
+// verify runs a merged ticket's checks on main and handles a failure
+// An error means the checks could not be run at all
+func (v *Verifier) verify(ctx context.Context, p Pending) (ipc.VerificationEvent, error) {
+	result := ipc.VerificationEvent{TicketID: p.TicketID}
+	repo := gitutils.NewRepo(p.RepoPath)
+	repo.Runner = v.runner
+
+	worktreePath := filepath.Join(v.workDir, "verify", p.TicketID)
+	os.RemoveAll(worktreePath)
+	if err := repo.AddDetachedWorktree(worktreePath); err != nil {
+		return result, fmt.Errorf("failed to check out main: %w", err)
+	}
+	defer func() {
+		if err := repo.RemoveWorktree(worktreePath); err != nil {
+			log.Printf("Failed to remove verification worktree for %s: %v", p.TicketID, err)
+		}
+	}()
+
+	commit, err := v.headCommit(ctx, worktreePath)
+	if err != nil {
+		return result, err
+	}
+	result.Commit = commit
+	for _, check := range p.Checks {
+		output, err := v.runCheck(ctx, worktreePath, check)
+		if err == nil {
+			continue
+		}
+		result.Check = check.Name
+		result.Message = fmt.Sprintf("Check %s failed on main: %v", check.Name, err)
+		log.Printf("Ticket %s: %s", p.TicketID, result.Message)
+		if ctx.Err() == nil {
+			result.Action = v.handleFailure(repo, worktreePath, p, check, output)
+		}
+		return result, nil
+	}
+
+	result.Passed = true
+	result.Message = fmt.Sprintf("%d checks passed on main", len(p.Checks))
+	log.Printf("Ticket %s verified: %s", p.TicketID, result.Message)
+	return result, nil
+}
+
+// SetRunner sets what runs the checks and git in the verification worktree
+func (v *Verifier) SetRunner(runner command.Runner) {
+	v.runner = runner
+}
+
+// runCheck runs one check from the worktree's root
+func (v *Verifier) runCheck(ctx context.Context, dir string, check ticket.DoneCheck) ([]byte, error) {
+	if v.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(v.config.Timeout)*time.Second)
+		defer cancel()
+	}
+	cmd := command.Context(ctx, "sh", "-c", check.Run)
+	cmd.Dir = dir
+	return command.Or(v.runner).CombinedOutput(cmd)
+}
+
+// handleFailure rolls the merge back or enqueues a revert ticket, returning
+// the action taken
+func (v *Verifier) handleFailure(repo *gitutils.GitRepo, worktreePath string, p Pending, check ticket.DoneCheck, output []byte) string {
+	merge, err := repo.MergedBy(p.Commit)
+	if err != nil {
+		log.Printf("Failed to find the merge of %s: %v", p.TicketID, err)
+	}
+
+	if v.config.OnFailure == OnFailureRollback && err == nil {
+		target := merge
+		if target == "" {
+			target = p.Commit
+		}
+		var reverted string
+		if reverted, err = repo.RevertOnMain(worktreePath, target); err == nil {
+			log.Printf("Rolled back %s on main with %s", p.TicketID, shortHash(reverted))
+			return OnFailureRollback
+		}
+		log.Printf("Failed to roll back %s, enqueueing a revert ticket instead: %v", p.TicketID, err)
+	}
+
+	b := breakage{
+		TicketID:    p.TicketID,
+		Title:       p.Title,
+		Branch:      p.Branch,
+		Commit:      p.Commit,
+		MergeCommit: merge,
+		Failure:     fmt.Sprintf("its definition of done check %q (`%s`) failed", check.Name, check.Run),
+		Output:      output,
+		Checks:      p.Checks,
+	}
+	if err := v.enqueueRevert(b); err != nil {
+		log.Printf("Failed to enqueue revert ticket for %s: %v", p.TicketID, err)
+		return "none"
+	}
+	return OnFailureRevertTicket
+}
+
+// breakage is a merged change that broke main
+type breakage struct {
+	TicketID    string // Ticket that made the change, if known
+	Title       string
+	Branch      string
+	Commit      string // Ticket's branch tip
+	MergeCommit string // Merge that brought the change into main; "" if none
+	Failure     string // What failed, completing "... merged into main, but"
+	Output      []byte
+	Checks      []ticket.DoneCheck // Carried over to the revert ticket
+}
+
+// revertID names a breakage's revert ticket after the ticket, or after the
+// merge commit if the ticket is unknown
+func (b breakage) revertID() string {
+	if b.TicketID != "" {
+		return "revert-" + b.TicketID
+	}
+	return "revert-" + shortHash(b.MergeCommit)
+}
+
+// enqueueRevert writes an urgent ticket to fix or revert the change
+func (v *Verifier) enqueueRevert(b breakage) error {
+	output := b.Output
+	if len(output) > maxOutput {
+		output = output[len(output)-maxOutput:]
+	}
+
+	change := "Ticket " + b.TicketID
+	if b.TicketID == "" {
+		change = "A change"
+	}
+	if b.Commit != "" {
+		change += fmt.Sprintf(" (commit %s from %s)", b.Commit, b.Branch)
+	}
+	merged := "was merged into main"
+	revert := "revert the change"
+	if b.MergeCommit != "" {
+		merged = "was merged into main by " + b.MergeCommit
+		revert = fmt.Sprintf("revert the merge with `git revert -m 1 %s`", b.MergeCommit)
+	}
+
+	title := fmt.Sprintf("Fix or revert %s: %s", b.TicketID, b.Title)
+	if b.TicketID == "" {
+		title = "Fix or revert merge " + shortHash(b.MergeCommit)
+	}
+	source := b.TicketID
+	if source == "" {
+		source = b.MergeCommit
+	}
+
+	now := time.Now()
+	t := &ticket.Ticket{
+		ID:    b.revertID(),
+		Title: title,
+		Description: fmt.Sprintf("%s %s, but %s:\n\n%s\n\nFix main, or %s.",
+			change, merged, ticket.EscapeTemplate(b.Failure), ticket.EscapeTemplate(string(output)), revert),
+		Priority:  1,
+		Tags:      []string{"verify"},
+		Done:      b.Checks,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	data, err := t.ToYAML()
+	if err != nil {
+		return fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+	data, _, err = ticket.Stamp(data, ticket.Provenance{
+		EnqueuedBy: "verifier",
+		Source:     source,
+		EnqueuedAt: now.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(v.backlogDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backlog directory: %w", err)
+	}
+
+	path := filepath.Join(v.backlogDir, t.ID+".yaml")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		log.Printf("Revert ticket %s is already in the backlog", t.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Enqueued revert ticket %s", t.ID)
+	return nil
+}
+
+// save writes the pending tickets; the caller holds mu
+func (v *Verifier) save() error {
+	pending := make([]Pending, 0, len(v.pending))
+	for _, p := range v.pending {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].TicketID < pending[j].TicketID })
+
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.statePath), 0755); err != nil {
+		return err
+	}
+	tmp := v.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.statePath)
+}
+
+// headCommit returns the commit checked out in a worktree
+func (v *Verifier) headCommit(ctx context.Context, dir string) (string, error) {
+	cmd := command.Context(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	output, err := command.Or(v.runner).Output(cmd)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// shortHash abbreviates a commit hash for names and messages
+func shortHash(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
@@ -0,0 +1,199 @@
+package verify
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// testRepo is a bare repository with a clone to make branches and merges in
+type testRepo struct {
+	path       string
+	clone      string
+	mainBranch string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	tmpDir := t.TempDir()
+	r := &testRepo{path: filepath.Join(tmpDir, "repo.git"), clone: filepath.Join(tmpDir, "clone")}
+	if err := gitutils.InitBareRepo(r.path); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(r.path).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	git(t, tmpDir, "clone", r.path, r.clone)
+	r.mainBranch = git(t, r.clone, "rev-parse", "--abbrev-ref", "HEAD")
+	return r
+}
+
+// branch commits file on a new branch and pushes it
+func (r *testRepo) branch(t *testing.T, name, file string) {
+	t.Helper()
+	git(t, r.clone, "checkout", "-q", "-b", name, r.mainBranch)
+	if err := os.WriteFile(filepath.Join(r.clone, file), []byte(name+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", file, err)
+	}
+	git(t, r.clone, "add", file)
+	git(t, r.clone, "commit", "-q", "-m", "Add "+file)
+	git(t, r.clone, "push", "-q", "origin", name)
+}
+
+// merge merges a branch into main with a merge commit and pushes main
+func (r *testRepo) merge(t *testing.T, name string) {
+	t.Helper()
+	git(t, r.clone, "checkout", "-q", r.mainBranch)
+	git(t, r.clone, "pull", "-q", "origin", r.mainBranch)
+	git(t, r.clone, "merge", "-q", "--no-ff", "-m", "Merge "+name, name)
+	git(t, r.clone, "push", "-q", "origin", r.mainBranch)
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func completed(tk *ticket.Ticket) ipc.Event {
+	return ipc.Event{Type: ipc.EventTypeTicketComplete, Timestamp: time.Now(), Data: ipc.TicketEvent{Ticket: tk, WorkerID: 1}}
+}
+
+func newVerifier(t *testing.T, r *testRepo, onFailure string, stateDir, backlogDir string) *Verifier {
+	t.Helper()
+	config := Config{Enabled: true, IntervalSeconds: 1, Timeout: 30, OnFailure: onFailure}
+	v, err := New(config, r.path, environment.Environments{}, t.TempDir(), backlogDir, stateDir)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	return v
+}
+
+func TestVerifierWaitsForMergeThenPasses(t *testing.T) {
+	r := newTestRepo(t)
+	stateDir := t.TempDir()
+	v := newVerifier(t, r, OnFailureRevertTicket, stateDir, t.TempDir())
+
+	r.branch(t, "agent-1/feat-ok", "ok.txt")
+	tk := &ticket.Ticket{ID: "feat-ok", Title: "OK", Branch: "agent-1/feat-ok", Done: []ticket.DoneCheck{{Name: "file", Run: "test -f ok.txt"}}}
+	if err := v.Record(completed(tk)); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := v.Record(completed(&ticket.Ticket{ID: "feat-none", Branch: "agent-1/feat-ok"})); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	if results := v.Check(context.Background()); len(results) != 0 {
+		t.Fatalf("Expected nothing to be verified before the merge, got %+v", results)
+	}
+
+	// Pending tickets survive a restart
+	v = newVerifier(t, r, OnFailureRevertTicket, stateDir, t.TempDir())
+	if pending := v.Pending(); len(pending) != 1 || pending[0].TicketID != "feat-ok" {
+		t.Fatalf("Expected feat-ok to be pending, got %+v", pending)
+	}
+
+	r.merge(t, "agent-1/feat-ok")
+	results := v.Check(context.Background())
+	if len(results) != 1 || !results[0].Passed {
+		t.Fatalf("Expected feat-ok to pass, got %+v", results)
+	}
+	if len(v.Pending()) != 0 {
+		t.Error("Expected nothing to be pending after verification")
+	}
+}
+
+func TestVerifierEnqueuesRevertTicket(t *testing.T) {
+	r := newTestRepo(t)
+	backlogDir := t.TempDir()
+	v := newVerifier(t, r, OnFailureRevertTicket, t.TempDir(), backlogDir)
+
+	var published []ipc.VerificationEvent
+	v.SetResultHandler(func(event ipc.VerificationEvent) { published = append(published, event) })
+
+	r.branch(t, "agent-1/feat-bad", "bad.txt")
+	tk := &ticket.Ticket{ID: "feat-bad", Title: "Bad", Branch: "agent-1/feat-bad", Done: []ticket.DoneCheck{{Name: "smoke", Run: "echo '{{ broken }}'; test ! -f bad.txt"}}}
+	if err := v.Record(completed(tk)); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	r.merge(t, "agent-1/feat-bad")
+
+	v.Check(context.Background())
+	if len(published) != 1 || published[0].Passed || published[0].Check != "smoke" || published[0].Action != OnFailureRevertTicket {
+		t.Fatalf("Expected a failed smoke check with a revert ticket, got %+v", published)
+	}
+
+	revert, err := ticket.Load(filepath.Join(backlogDir, "revert-feat-bad.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load revert ticket: %v", err)
+	}
+	if revert.Priority != 1 || len(revert.Done) != 1 || revert.Provenance == nil || revert.Provenance.EnqueuedBy != "verifier" {
+		t.Errorf("Unexpected revert ticket: %+v", revert)
+	}
+	if err := (ticket.Templating{}).Expand(revert, time.Now()); err != nil || !strings.Contains(revert.Description, "{{ broken }}") {
+		t.Errorf("Expected check output to survive templating, got %v:\n%s", err, revert.Description)
+	}
+}
+
+func TestVerifierRollsBackMerge(t *testing.T) {
+	r := newTestRepo(t)
+	backlogDir := t.TempDir()
+	v := newVerifier(t, r, OnFailureRollback, t.TempDir(), backlogDir)
+
+	r.branch(t, "agent-1/feat-bad", "bad.txt")
+	tk := &ticket.Ticket{ID: "feat-bad", Title: "Bad", Branch: "agent-1/feat-bad", Done: []ticket.DoneCheck{{Name: "build", Run: "test ! -f bad.txt"}}}
+	if err := v.Record(completed(tk)); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	r.merge(t, "agent-1/feat-bad")
+
+	results := v.Check(context.Background())
+	if len(results) != 1 || results[0].Action != OnFailureRollback {
+		t.Fatalf("Expected the merge to be rolled back, got %+v", results)
+	}
+
+	git(t, r.clone, "pull", "-q", "origin", r.mainBranch)
+	if _, err := os.Stat(filepath.Join(r.clone, "bad.txt")); !os.IsNotExist(err) {
+		t.Error("Expected bad.txt to be gone from main")
+	}
+	if subject := git(t, r.clone, "log", "-1", "--format=%s"); !strings.HasPrefix(subject, "Revert") {
+		t.Errorf("Expected main to end in a revert, got %q", subject)
+	}
+	if _, err := os.Stat(filepath.Join(backlogDir, "revert-feat-bad.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected no revert ticket after a rollback")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{}).Validate(); err != nil {
+		t.Errorf("Expected disabled config to be valid, got %v", err)
+	}
+	if err := (Config{Enabled: true, IntervalSeconds: 60, OnFailure: OnFailureRollback}).Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+	if err := (Config{Enabled: true, IntervalSeconds: 0, OnFailure: OnFailureRollback}).Validate(); err == nil {
+		t.Error("Expected error for a zero interval")
+	}
+	if err := (Config{Enabled: true, IntervalSeconds: 60, OnFailure: "ignore"}).Validate(); err == nil {
+		t.Error("Expected error for an unknown on_failure")
+	}
+}
@@ -0,0 +1,89 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `# Editor and temp files
+.#*
+*.tmp.yaml
+drafts/
+/scratch.yaml
+archive/**/*.yml
+!keep.tmp.yaml
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, IgnoreFileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+
+	m, err := LoadIgnore(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadIgnore failed: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{".#feature.yaml", true},
+		{"feature.tmp.yaml", true},
+		{"keep.tmp.yaml", false},
+		{"drafts/idea.yaml", true},
+		{"drafts", false}, // Directory-only rule does not match a file named drafts
+		{"scratch.yaml", true},
+		{"nested/scratch.yaml", false},
+		{"archive/2024/q1/old.yml", true},
+		{"archive/old.yml", true},
+		{"feature.yaml", false},
+	}
+
+	for _, tt := range tests {
+		if got := m.Match(tt.path, false); got != tt.ignored {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+
+	if !m.Match("drafts", true) {
+		t.Error("Expected drafts directory to be ignored")
+	}
+}
+
+func TestLoadIgnoreMissingFile(t *testing.T) {
+	m, err := LoadIgnore(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadIgnore failed: %v", err)
+	}
+	if m.Match("feature.yaml", false) {
+		t.Error("Expected nothing to be ignored without an ignore file")
+	}
+
+	var nilMatcher *IgnoreMatcher
+	if nilMatcher.Match("feature.yaml", false) {
+		t.Error("Expected nil matcher to ignore nothing")
+	}
+}
+
+func TestNewIgnoreMatcher(t *testing.T) {
+	m := NewIgnoreMatcher([]string{"docs/", "*.md", "LICENSE"})
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{"README.md", true},
+		{"docs/guide/setup.txt", true},
+		{"LICENSE", true},
+		{"internal/worker/worker.go", false},
+		{"docs.go", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, false); got != tt.ignored {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+}
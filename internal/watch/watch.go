@@ -2,31 +2,59 @@ package watch
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
 	"github.com/brettsmith212/amp-orchestrator/internal/queue"
 	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
 )
 
+// pendingFiles is how many ticket files can wait for processing; files
+// beyond it are picked up by a later scan
+const pendingFiles = 256
+
+// ErrValidatorUnavailable marks a validator error that says nothing about
+// the ticket, such as a hook that could not be reached; the ticket stays in
+// the backlog and is validated again on the next scan
+var ErrValidatorUnavailable = errors.New("validator unavailable")
+
 // Watcher monitors a directory for new ticket files and enqueues them
 type Watcher struct {
-	backlogPath string
-	queue       *queue.Queue
-	tickerInterval time.Duration
-	fsWatcher   *fsnotify.Watcher
-	eventPublisher func(*ticket.Ticket) // Optional event publisher
+	backlogPath        string
+	queue              *queue.Queue
+	tickerInterval     time.Duration
+	fsWatcher          *fsnotify.Watcher
+	ignore             *IgnoreMatcher
+	defaultsPath       string                             // Ticket defaults merged into every ticket
+	defaults           *ticket.Defaults
+	templating         ticket.Templating                  // Variables for description placeholders
+	eventPublisher     func(*ticket.Ticket)               // Optional event publisher
+	validator          func(*ticket.Ticket) error         // Optional check before enqueue; an error rejects the ticket
+	rejectionPublisher func(*ticket.Ticket, error)        // Optional publisher for rejected tickets
+	cipher             *encryption.Cipher                 // Optional; encrypts tickets as they are archived
+	claimer            func(*ticket.Ticket) (bool, error) // Optional; false leaves the ticket to another daemon
+	admitter           func(*ticket.Ticket) error         // Optional; an error leaves the ticket in the backlog for a later scan
+	pending            chan string                        // Ticket files waiting to be processed off the watch loop
+	queued             map[string]bool                    // Files in pending, so each waits there once
+	validated          map[string][sha256.Size]byte       // Hash of each backlog file the validator passed; used only by processPending
+	mu                 sync.Mutex                         // Guards queued and defaults
 }
 
 // Config holds watcher configuration
 type Config struct {
 	BacklogPath    string
 	TickerInterval time.Duration
+	DefaultsPath   string // Defaults to _defaults.yaml in the backlog
+	Templating     ticket.Templating
 }
 
 // New creates a new backlog watcher
@@ -36,11 +64,21 @@ func New(config Config, q *queue.Queue) (*Watcher, error) {
 		return nil, fmt.Errorf("failed to create file watcher: %w", err)
 	}
 
+	defaultsPath := config.DefaultsPath
+	if defaultsPath == "" {
+		defaultsPath = filepath.Join(config.BacklogPath, ticket.DefaultsFileName)
+	}
+
 	return &Watcher{
 		backlogPath:    config.BacklogPath,
 		queue:          q,
 		tickerInterval: config.TickerInterval,
 		fsWatcher:      fsWatcher,
+		defaultsPath:   defaultsPath,
+		templating:     config.Templating,
+		pending:        make(chan string, pendingFiles),
+		queued:         make(map[string]bool),
+		validated:      make(map[string][sha256.Size]byte),
 	}, nil
 }
 
@@ -58,7 +96,13 @@ func (w *Watcher) Start(ctx context.Context) error {
 	ticker := time.NewTicker(w.tickerInterval)
 	defer ticker.Stop()
 
-	// Initial scan of existing files
+	// Tickets are loaded, validated and enqueued in the background, so a
+	// slow validation hook never holds up file events or scans
+	processorDone := make(chan struct{})
+	go w.processPending(ctx, processorDone)
+	defer func() { <-processorDone }()
+
+	// Initial scan of existing files (also loads the ignore file)
 	if err := w.scanDirectory(); err != nil {
 		log.Printf("Error during initial scan: %v", err)
 	}
@@ -94,15 +138,28 @@ func (w *Watcher) Start(ctx context.Context) error {
 func (w *Watcher) handleFileEvent(event fsnotify.Event) {
 	// Only process write and create events for YAML files
 	if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create {
-		if w.isTicketFile(event.Name) {
+		if filepath.Base(event.Name) == IgnoreFileName {
+			w.reloadIgnore()
+			return
+		}
+		if filepath.Clean(event.Name) == filepath.Clean(w.defaultsPath) {
+			w.reloadDefaults()
+			return
+		}
+
+		if w.isTicketFile(event.Name) && !w.isIgnored(event.Name) {
 			log.Printf("File event: %s %s", event.Op, event.Name)
-			w.processTicketFile(event.Name)
+			w.queueTicketFile(event.Name)
 		}
 	}
 }
 
 // scanDirectory scans the backlog directory for ticket files
 func (w *Watcher) scanDirectory() error {
+	// Pick up edits to the ignore and defaults files that fsnotify may have missed
+	w.reloadIgnore()
+	w.reloadDefaults()
+
 	pattern := filepath.Join(w.backlogPath, "*.yaml")
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
@@ -110,7 +167,10 @@ func (w *Watcher) scanDirectory() error {
 	}
 
 	for _, file := range matches {
-		w.processTicketFile(file)
+		if w.isIgnored(file) || !w.isTicketFile(file) {
+			continue
+		}
+		w.queueTicketFile(file)
 	}
 
 	// Also check for .yml files
@@ -121,42 +181,183 @@ func (w *Watcher) scanDirectory() error {
 	}
 
 	for _, file := range matches {
-		w.processTicketFile(file)
+		if w.isIgnored(file) || !w.isTicketFile(file) {
+			continue
+		}
+		w.queueTicketFile(file)
 	}
 
 	return nil
 }
 
+// queueTicketFile hands a ticket file to processPending unless it is
+// already waiting there
+func (w *Watcher) queueTicketFile(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.queued[path] {
+		return
+	}
+	select {
+	case w.pending <- path:
+		w.queued[path] = true
+	default:
+		log.Printf("Too many ticket files waiting; %s is left for the next scan", path)
+	}
+}
+
+// processPending processes queued ticket files one at a time until ctx is
+// done, then closes done
+func (w *Watcher) processPending(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case path := <-w.pending:
+			w.processTicketFile(path)
+			w.mu.Lock()
+			delete(w.queued, path)
+			w.mu.Unlock()
+		}
+	}
+}
+
 // processTicketFile attempts to load and enqueue a ticket file
 func (w *Watcher) processTicketFile(filepath string) {
 	log.Printf("Processing ticket file: %s", filepath)
 
-	ticket, err := ticket.Load(filepath)
+	// Oversized files are refused before they are read into memory
+	if info, err := os.Stat(filepath); err == nil {
+		if err := ticket.CheckSize(info.Size()); err != nil {
+			log.Printf("Failed to load ticket from %s: %v", filepath, err)
+			return
+		}
+	}
+
+	data, err := w.cipher.ReadFile(filepath)
 	if err != nil {
 		log.Printf("Failed to load ticket from %s: %v", filepath, err)
 		return
 	}
+	t, err := ticket.LoadFromBytes(data)
+	if err != nil {
+		log.Printf("Failed to load ticket from %s: %v", filepath, err)
+		return
+	}
+	w.mu.Lock()
+	w.defaults.Apply(t)
+	w.mu.Unlock()
 
 	// Check if ticket is already in queue to avoid duplicates
-	if w.isTicketInQueue(ticket.ID) {
-		log.Printf("Ticket %s is already in queue, skipping", ticket.ID)
+	if w.isTicketInQueue(t.ID) {
+		log.Printf("Ticket %s is already in queue, skipping", t.ID)
 		return
 	}
 
-	w.queue.Push(ticket)
-	log.Printf("Enqueued ticket %s: %s", ticket.ID, ticket.Title)
+	// A ticket stamped when it was enqueued must not have changed since
+	if err := t.VerifyProvenance(data); err != nil {
+		w.rejectTicket(filepath, t, err)
+		return
+	}
+
+	// Placeholders in the description are filled in once, as it is enqueued
+	if err := w.templating.Expand(t, time.Now()); err != nil {
+		w.rejectTicket(filepath, t, err)
+		return
+	}
+
+	// A ticket left in the backlog after passing, e.g. waiting for approval,
+	// is only sent to the validator again once its file changes
+	if sum := sha256.Sum256(data); w.validator != nil && w.validated[filepath] != sum {
+		if err := w.validator(t); errors.Is(err, ErrValidatorUnavailable) {
+			log.Printf("Ticket %s stays in the backlog until it can be validated: %v", t.ID, err)
+			return
+		} else if err != nil {
+			w.rejectTicket(filepath, t, err)
+			return
+		}
+		w.validated[filepath] = sum
+	}
+
+	if w.admitter != nil {
+		if err := w.admitter(t); err != nil {
+			log.Printf("Ticket %s stays in the backlog: %v", t.ID, err)
+			return
+		}
+	}
+
+	if w.claimer != nil {
+		claimed, err := w.claimer(t)
+		if err != nil {
+			log.Printf("Failed to claim ticket %s: %v", t.ID, err)
+			return
+		}
+		if !claimed {
+			log.Printf("Ticket %s is claimed by another daemon, skipping", t.ID)
+			return
+		}
+	}
+
+	// Tickets dropped straight into the backlog are stamped by the watcher
+	var stamped []byte
+	if t.Provenance == nil {
+		stamped, t.Provenance, err = ticket.Stamp(data, ticket.Provenance{
+			EnqueuedBy: "watcher",
+			Source:     filepath,
+			EnqueuedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			log.Printf("Failed to record provenance of %s: %v", t.ID, err)
+		}
+	}
+
+	w.queue.Push(t)
+	delete(w.validated, filepath)
+	log.Printf("Enqueued ticket %s: %s", t.ID, t.Title)
 
 	// Publish event if publisher is set
 	if w.eventPublisher != nil {
-		w.eventPublisher(ticket)
+		w.eventPublisher(t)
 	}
 
 	// Move the file to a processed directory to avoid re-processing
-	if err := w.moveToProcessed(filepath); err != nil {
+	if err := w.moveToProcessed(filepath, stamped); err != nil {
 		log.Printf("Failed to move processed file %s: %v", filepath, err)
 	}
 }
 
+// reloadIgnore re-reads the ignore file, keeping the previous rules on error
+func (w *Watcher) reloadIgnore() {
+	ignore, err := LoadIgnore(w.backlogPath)
+	if err != nil {
+		log.Printf("Failed to load %s: %v", IgnoreFileName, err)
+		return
+	}
+	w.ignore = ignore
+}
+
+// reloadDefaults re-reads the ticket defaults, keeping the previous ones on error
+func (w *Watcher) reloadDefaults() {
+	defaults, err := ticket.LoadDefaults(w.defaultsPath)
+	if err != nil {
+		log.Printf("Failed to load %s: %v", w.defaultsPath, err)
+		return
+	}
+	w.mu.Lock()
+	w.defaults = defaults
+	w.mu.Unlock()
+}
+
+// isIgnored checks if a backlog file matches the ignore file
+func (w *Watcher) isIgnored(path string) bool {
+	rel, err := filepath.Rel(w.backlogPath, path)
+	if err != nil {
+		return false
+	}
+	return w.ignore.Match(rel, false)
+}
+
 // isTicketInQueue checks if a ticket with the given ID is already in the queue
 func (w *Watcher) isTicketInQueue(ticketID string) bool {
 	tickets := w.queue.List()
@@ -170,6 +371,9 @@ func (w *Watcher) isTicketInQueue(ticketID string) bool {
 
 // isTicketFile checks if the file is a YAML ticket file
 func (w *Watcher) isTicketFile(filename string) bool {
+	if filepath.Base(filename) == ticket.DefaultsFileName {
+		return false
+	}
 	ext := strings.ToLower(filepath.Ext(filename))
 	return ext == ".yaml" || ext == ".yml"
 }
@@ -189,8 +393,74 @@ func (w *Watcher) SetEventPublisher(publisher func(*ticket.Ticket)) {
 	w.eventPublisher = publisher
 }
 
+// SetValidator sets a check run on each ticket before it is enqueued
+// Tickets for which it returns an error are archived as rejected, unless the
+// error wraps ErrValidatorUnavailable
+func (w *Watcher) SetValidator(validator func(*ticket.Ticket) error) {
+	w.validator = validator
+}
+
+// SetRejectionPublisher sets the publisher called with each rejected ticket
+// and the validator's error
+func (w *Watcher) SetRejectionPublisher(publisher func(*ticket.Ticket, error)) {
+	w.rejectionPublisher = publisher
+}
+
+// SetClaimer sets a check run before a valid ticket is enqueued; tickets it
+// returns false for are left in place for the daemon that owns them
+func (w *Watcher) SetClaimer(claimer func(*ticket.Ticket) (bool, error)) {
+	w.claimer = claimer
+}
+
+// SetAdmitter sets a check run on each valid ticket before it is claimed and
+// enqueued, e.g. a queue quota; tickets it refuses are retried on later scans
+func (w *Watcher) SetAdmitter(admitter func(*ticket.Ticket) error) {
+	w.admitter = admitter
+}
+
+// SetCipher sets the cipher used to read encrypted tickets and to encrypt
+// tickets as they are moved to the processed directory
+func (w *Watcher) SetCipher(c *encryption.Cipher) {
+	w.cipher = c
+}
+
+// rejectTicket archives a rejected ticket file and reports the reason
+func (w *Watcher) rejectTicket(filePath string, t *ticket.Ticket, reason error) {
+	log.Printf("Rejected ticket %s: %v", t.ID, reason)
+
+	if err := w.moveToRejected(filePath, reason.Error()); err != nil {
+		log.Printf("Failed to archive rejected file %s: %v", filePath, err)
+	}
+
+	if w.rejectionPublisher != nil {
+		w.rejectionPublisher(t, reason)
+	}
+}
+
+// moveToRejected moves a rejected ticket file to a rejected subdirectory
+// and writes the reason next to it
+func (w *Watcher) moveToRejected(filePath, reason string) error {
+	rejectedDir := filepath.Join(w.backlogPath, "rejected")
+	if err := os.MkdirAll(rejectedDir, 0755); err != nil {
+		return fmt.Errorf("failed to create rejected directory: %w", err)
+	}
+
+	destPath := filepath.Join(rejectedDir, filepath.Base(filePath))
+	if err := os.WriteFile(destPath+".reason", []byte(reason+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write rejection reason: %w", err)
+	}
+
+	if err := os.Rename(filePath, destPath); err != nil {
+		return fmt.Errorf("failed to move file to rejected directory: %w", err)
+	}
+
+	log.Printf("Moved rejected ticket file to %s", destPath)
+	return nil
+}
+
 // moveToProcessed moves a processed ticket file to a processed subdirectory
-func (w *Watcher) moveToProcessed(filePath string) error {
+// When stamped is set it is written in place of the file's contents
+func (w *Watcher) moveToProcessed(filePath string, stamped []byte) error {
 	// Create processed directory if it doesn't exist
 	processedDir := filepath.Join(w.backlogPath, "processed")
 	if err := os.MkdirAll(processedDir, 0755); err != nil {
@@ -202,10 +472,53 @@ func (w *Watcher) moveToProcessed(filePath string) error {
 	
 	// Move file to processed directory
 	destPath := filepath.Join(processedDir, filename)
+	if stamped != nil {
+		perm := os.FileMode(0644)
+		if w.cipher.Encrypts() {
+			perm = 0600
+		}
+		if err := w.cipher.WriteFile(destPath, stamped, perm); err != nil {
+			return fmt.Errorf("failed to write processed ticket: %w", err)
+		}
+		if err := os.Remove(filePath); err != nil {
+			return fmt.Errorf("failed to remove original ticket file: %w", err)
+		}
+		log.Printf("Moved processed ticket file to %s", destPath)
+		return nil
+	}
+	if w.cipher.Encrypts() {
+		if err := w.archiveEncrypted(filePath, destPath); err != nil {
+			return err
+		}
+		log.Printf("Moved processed ticket file to %s", destPath)
+		return nil
+	}
 	if err := os.Rename(filePath, destPath); err != nil {
 		return fmt.Errorf("failed to move file to processed directory: %w", err)
 	}
+	// Processed retention goes by modification time, which a rename keeps
+	now := time.Now()
+	os.Chtimes(destPath, now, now)
 
 	log.Printf("Moved processed ticket file to %s", destPath)
 	return nil
-}
\ No newline at end of file
+}
+
+// archiveEncrypted writes an encrypted copy of a ticket file to destPath and
+// removes the original
+func (w *Watcher) archiveEncrypted(filePath, destPath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read ticket file: %w", err)
+	}
+	if encryption.IsEncrypted(data) {
+		return os.Rename(filePath, destPath)
+	}
+	if err := w.cipher.WriteFile(destPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write encrypted ticket: %w", err)
+	}
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("failed to remove original ticket file: %w", err)
+	}
+	return nil
+}
@@ -2,12 +2,18 @@ package watch
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
 	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
 )
 
 func TestWatcherFileEvent(t *testing.T) {
@@ -312,4 +318,534 @@ func TestWatcherNonYAMLFiles(t *testing.T) {
 	if q.Len() != 0 {
 		t.Errorf("Expected queue to be empty for non-YAML files, got %d items", q.Len())
 	}
-}
\ No newline at end of file
+}
+
+func TestWatcherRespectsIgnoreFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, IgnoreFileName), []byte(".#*\n*.draft.yaml\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 50 * time.Millisecond,
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			t.Logf("Watcher error: %v", err)
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	ticketYAML := `id: "ignored-001"
+title: "Ignored ticket"
+description: "A ticket the watcher should not enqueue"
+priority: 1`
+	for _, name := range []string{".#feature.yaml", "idea.draft.yaml"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(ticketYAML), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// The same ticket under a name the ignore file doesn't match is enqueued,
+	// so the fixture is valid and only the ignore rules keep the others out
+	controlYAML := strings.Replace(ticketYAML, "ignored-001", "control-001", 1)
+	if err := os.WriteFile(filepath.Join(tmpDir, "feature.yaml"), []byte(controlYAML), 0644); err != nil {
+		t.Fatalf("Failed to write feature.yaml: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if q.Len() != 1 {
+		t.Fatalf("Expected only the control ticket to be enqueued, got %d items", q.Len())
+	}
+	if tk := q.List()[0]; tk.ID != "control-001" {
+		t.Errorf("Expected control-001 to be enqueued, got %s", tk.ID)
+	}
+
+	// Ignored files stay where they are instead of moving to processed
+	if _, err := os.Stat(filepath.Join(tmpDir, "idea.draft.yaml")); err != nil {
+		t.Errorf("Expected ignored file to remain in backlog: %v", err)
+	}
+}
+
+func TestWatcherRejectsInvalidTickets(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 50 * time.Millisecond,
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	watcher.SetValidator(func(tk *ticket.Ticket) error {
+		if tk.ID == "rejected-001" {
+			return errors.New("needs security review")
+		}
+		return nil
+	})
+
+	rejected := make(chan string, 1)
+	watcher.SetRejectionPublisher(func(tk *ticket.Ticket, reason error) {
+		rejected <- tk.ID + ": " + reason.Error()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			t.Logf("Watcher error: %v", err)
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	ticketYAML := `id: "rejected-001"
+title: "Risky ticket"
+description: "A ticket the watcher should not enqueue"
+priority: 1`
+	if err := os.WriteFile(filepath.Join(tmpDir, "risky.yaml"), []byte(ticketYAML), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	select {
+	case msg := <-rejected:
+		if msg != "rejected-001: needs security review" {
+			t.Errorf("Unexpected rejection: %s", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for rejection")
+	}
+
+	if q.Len() != 0 {
+		t.Errorf("Expected rejected ticket not to be enqueued, got %d items", q.Len())
+	}
+
+	reason, err := os.ReadFile(filepath.Join(tmpDir, "rejected", "risky.yaml.reason"))
+	if err != nil {
+		t.Fatalf("Expected rejection reason to be archived: %v", err)
+	}
+	if string(reason) != "needs security review\n" {
+		t.Errorf("Unexpected archived reason: %q", reason)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "rejected", "risky.yaml")); err != nil {
+		t.Errorf("Expected ticket file to be archived: %v", err)
+	}
+}
+
+func TestWatcherKeepsTicketsWhileValidatorUnavailable(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 50 * time.Millisecond,
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	var calls atomic.Int32
+	watcher.SetValidator(func(tk *ticket.Ticket) error {
+		if calls.Add(1) == 1 {
+			return fmt.Errorf("%w: connection refused", ErrValidatorUnavailable)
+		}
+		return nil
+	})
+	watcher.SetRejectionPublisher(func(tk *ticket.Ticket, reason error) {
+		t.Errorf("Expected %s not to be rejected, got %v", tk.ID, reason)
+	})
+
+	ticketYAML := `id: "later-001"
+title: "Ticket validated later"
+description: "A ticket whose hook is down at first"
+priority: 1`
+	if err := os.WriteFile(filepath.Join(tmpDir, "later.yaml"), []byte(ticketYAML), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Start(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Expected the ticket to be enqueued once the validator answered, got %d items", q.Len())
+	}
+	if calls.Load() < 2 {
+		t.Errorf("Expected the ticket to be validated again, got %d calls", calls.Load())
+	}
+}
+
+func TestWatcherValidatesHeldTicketsOnce(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 20 * time.Millisecond,
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	var validations, admissions atomic.Int32
+	watcher.SetValidator(func(tk *ticket.Ticket) error {
+		validations.Add(1)
+		return nil
+	})
+	watcher.SetAdmitter(func(tk *ticket.Ticket) error {
+		if admissions.Add(1) < 4 {
+			return errors.New("waiting for approval")
+		}
+		return nil
+	})
+
+	ticketYAML := `id: "held-001"
+title: "Held ticket"
+description: "A ticket kept in the backlog for a few scans"
+priority: 1`
+	if err := os.WriteFile(filepath.Join(tmpDir, "held.yaml"), []byte(ticketYAML), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Start(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Expected the ticket to be enqueued once admitted, got %d items", q.Len())
+	}
+	if n := validations.Load(); n != 1 {
+		t.Errorf("Expected the unchanged ticket to be validated once across %d scans, got %d", admissions.Load(), n)
+	}
+}
+
+func TestWatcherEncryptsProcessedTickets(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 50 * time.Millisecond,
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+	watcher.SetCipher(cipher)
+
+	ticketYAML := `id: "secret-001"
+title: "Confidential ticket"
+description: "Should never be archived in plaintext"
+priority: 1`
+	if err := os.WriteFile(filepath.Join(tmpDir, "secret.yaml"), []byte(ticketYAML), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			t.Logf("Watcher error: %v", err)
+		}
+	}()
+
+	archived := filepath.Join(tmpDir, "processed", "secret.yaml")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(archived); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for ticket to be archived")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if q.Len() != 1 {
+		t.Fatalf("Expected 1 ticket in queue, got %d", q.Len())
+	}
+
+	data, err := os.ReadFile(archived)
+	if err != nil {
+		t.Fatalf("Failed to read archived ticket: %v", err)
+	}
+	if !encryption.IsEncrypted(data) {
+		t.Error("Expected archived ticket to be encrypted")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "secret.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected original ticket file to be removed")
+	}
+
+	tk, err := cipher.LoadTicket(archived)
+	if err != nil {
+		t.Fatalf("Failed to load archived ticket: %v", err)
+	}
+	if tk.ID != "secret-001" {
+		t.Errorf("Expected ticket secret-001, got %s", tk.ID)
+	}
+}
+
+func TestWatcherSkipsTicketsClaimedElsewhere(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 50 * time.Millisecond,
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	claims := make(chan string, 10)
+	watcher.SetClaimer(func(tk *ticket.Ticket) (bool, error) {
+		claims <- tk.ID
+		return tk.ID != "theirs-001", nil
+	})
+
+	for name, id := range map[string]string{"mine.yaml": "mine-001", "theirs.yaml": "theirs-001"} {
+		ticketYAML := "id: \"" + id + "\"\ntitle: \"Ticket\"\ndescription: \"Claimed by one daemon\"\npriority: 1\n"
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(ticketYAML), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			t.Logf("Watcher error: %v", err)
+		}
+	}()
+
+	// Wait for the ticket owned elsewhere to be offered at least twice
+	seen := 0
+	timeout := time.After(2 * time.Second)
+	for seen < 2 {
+		select {
+		case id := <-claims:
+			if id == "theirs-001" {
+				seen++
+			}
+		case <-timeout:
+			t.Fatal("Timeout waiting for claims")
+		}
+	}
+	cancel()
+
+	if q.Len() != 1 || q.List()[0].ID != "mine-001" {
+		t.Errorf("Expected only mine-001 in queue, got %v", q.List())
+	}
+	// The other daemon's ticket is left for it to archive
+	if _, err := os.Stat(filepath.Join(tmpDir, "theirs.yaml")); err != nil {
+		t.Errorf("Expected unclaimed ticket to remain in backlog: %v", err)
+	}
+}
+
+func TestWatcherRecordsProvenance(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 50 * time.Millisecond,
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	rejected := make(chan error, 1)
+	watcher.SetRejectionPublisher(func(tk *ticket.Ticket, reason error) {
+		rejected <- reason
+	})
+
+	// A stamped ticket edited after it was enqueued
+	stamped, _, err := ticket.Stamp([]byte("id: \"tampered-001\"\ntitle: \"Tampered\"\ndescription: \"Edited after enqueue\"\npriority: 2\n"),
+		ticket.Provenance{EnqueuedBy: "cli:alice", Source: "tampered.yaml"})
+	if err != nil {
+		t.Fatalf("Stamp failed: %v", err)
+	}
+	tampered := []byte(strings.Replace(string(stamped), "priority: 2", "priority: 1", 1))
+	if err := os.WriteFile(filepath.Join(tmpDir, "tampered.yaml"), tampered, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	plain := "id: \"plain-001\"\ntitle: \"Plain\"\ndescription: \"Dropped into the backlog\"\npriority: 2\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "plain.yaml"), []byte(plain), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Start(ctx)
+
+	select {
+	case reason := <-rejected:
+		if !errors.Is(reason, ticket.ErrChecksumMismatch) {
+			t.Errorf("Expected a checksum mismatch, got %v", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the tampered ticket to be rejected")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Expected only the plain ticket enqueued, got %d", q.Len())
+	}
+	enqueued := q.Peek()
+	if enqueued.Provenance == nil || enqueued.Provenance.EnqueuedBy != "watcher" || enqueued.Provenance.Checksum != ticket.Checksum([]byte(plain)) {
+		t.Fatalf("Unexpected provenance %+v", enqueued.Provenance)
+	}
+
+	// The processed copy carries the provenance block and still verifies
+	var processed *ticket.Ticket
+	for time.Now().Before(deadline) {
+		if processed, err = ticket.Load(filepath.Join(tmpDir, "processed", "plain.yaml")); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Failed to load processed ticket: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(tmpDir, "processed", "plain.yaml"))
+	if processed.Provenance == nil || processed.VerifyProvenance(data) != nil {
+		t.Errorf("Expected a verifiable provenance block in the processed ticket, got %s", data)
+	}
+}
+
+func TestWatcherAppliesTicketDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	defaults := "tags: [\"generated\"]\nlocks: [\"schema\"]\nestimate_min: 30\nenvironment: staging\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ticket.DefaultsFileName), []byte(defaults), 0644); err != nil {
+		t.Fatalf("Failed to write defaults file: %v", err)
+	}
+	plain := "id: \"gen-001\"\ntitle: \"Generated\"\ndescription: \"From a script\"\npriority: 2\ntags: [\"api\"]\nestimate_min: 90\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "gen-001.yaml"), []byte(plain), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 50 * time.Millisecond,
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Start(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Expected only the ticket to be enqueued, got %d", q.Len())
+	}
+
+	got := q.List()[0]
+	if strings.Join(got.Tags, ",") != "generated,api" || strings.Join(got.Locks, ",") != "schema" {
+		t.Errorf("Expected default tags and locks to be merged, got tags %v and locks %v", got.Tags, got.Locks)
+	}
+	if got.EstimateMin != 90 || got.Environment != "staging" {
+		t.Errorf("Expected the ticket's estimate and the default environment, got %d and %q", got.EstimateMin, got.Environment)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ticket.DefaultsFileName)); err != nil {
+		t.Errorf("Expected the defaults file to stay in the backlog: %v", err)
+	}
+}
+
+func TestWatcherExpandsDescriptionTemplates(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	q := queue.New()
+	watcher, err := New(Config{
+		BacklogPath:    tmpDir,
+		TickerInterval: 50 * time.Millisecond,
+		Templating:     ticket.Templating{ProjectName: "shop", Vars: map[string]string{"team": "payments"}},
+	}, q)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	rejected := make(chan error, 1)
+	watcher.SetRejectionPublisher(func(tk *ticket.Ticket, reason error) {
+		rejected <- reason
+	})
+
+	good := "id: \"tmpl-001\"\ntitle: \"Templated\"\ndescription: \"Refunds for {{ .ProjectName }} by {{ .Vars.team }}\"\npriority: 2\n"
+	bad := "id: \"tmpl-002\"\ntitle: \"Unknown\"\ndescription: \"Owned by {{ .Vars.owner }}\"\npriority: 2\n"
+	for name, content := range map[string]string{"good.yaml": good, "bad.yaml": bad} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Start(ctx)
+
+	select {
+	case reason := <-rejected:
+		if !strings.Contains(reason.Error(), "owner") {
+			t.Errorf("Expected the unknown variable to be reported, got %v", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the ticket with an unknown variable to be rejected")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Expected one ticket to be enqueued, got %d", q.Len())
+	}
+	if got := q.List()[0].Description; got != "Refunds for shop by payments" {
+		t.Errorf("Expected the description to be rendered, got %q", got)
+	}
+}
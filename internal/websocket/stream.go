@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Stream presents a WebSocket connection as a net.Conn byte stream, so
+// line-based protocols run over it unchanged: each Write is sent as one
+// message, text when it is valid UTF-8, and Read returns the payloads of
+// received messages in order. Pings are answered, and a close frame ends
+// the stream with io.EOF.
+type Stream struct {
+	ws       *Conn
+	messages chan []byte
+	done     chan struct{} // Closed once reading stops
+	closed   chan struct{} // Closed by Close
+	err      error         // Why reading stopped; set before done is closed
+	pending  []byte
+
+	deadlineMu sync.Mutex
+	deadline   time.Time
+	closeOnce  sync.Once
+}
+
+// NewStream starts reading messages from ws
+func NewStream(ws *Conn) *Stream {
+	s := &Stream{
+		ws:       ws,
+		messages: make(chan []byte),
+		done:     make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	go s.readMessages()
+	return s
+}
+
+// readMessages hands data frames to Read until the connection ends
+func (s *Stream) readMessages() {
+	defer close(s.done)
+	for {
+		opcode, payload, err := s.ws.ReadFrame()
+		if err != nil {
+			s.err = err
+			return
+		}
+		switch opcode {
+		case OpText, OpBinary, OpContinuation:
+			select {
+			case s.messages <- payload:
+			case <-s.closed:
+				s.err = net.ErrClosed
+				return
+			}
+		case OpPing:
+			s.ws.WriteFrame(OpPong, payload)
+		case OpClose:
+			s.ws.WriteFrame(OpClose, nil)
+			s.err = io.EOF
+			return
+		}
+	}
+}
+
+// Read returns received message bytes, waiting for the next message until
+// the read deadline
+func (s *Stream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		s.deadlineMu.Lock()
+		deadline := s.deadline
+		s.deadlineMu.Unlock()
+
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			wait := time.Until(deadline)
+			if wait <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+			timer := time.NewTimer(wait)
+			timeout = timer.C
+			defer timer.Stop()
+		}
+
+		select {
+		case message := <-s.messages:
+			s.pending = message
+		case <-s.done:
+			return 0, s.err
+		case <-timeout:
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Write sends p as a single message
+func (s *Stream) Write(p []byte) (int, error) {
+	opcode := byte(OpText)
+	if !utf8.Valid(p) {
+		opcode = OpBinary
+	}
+	if err := s.ws.WriteFrame(opcode, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close sends a close frame and closes the connection
+func (s *Stream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.ws.WriteFrame(OpClose, nil)
+		err = s.ws.Close()
+	})
+	return err
+}
+
+func (s *Stream) LocalAddr() net.Addr {
+	return s.ws.conn.LocalAddr()
+}
+
+func (s *Stream) RemoteAddr() net.Addr {
+	return s.ws.conn.RemoteAddr()
+}
+
+// SetDeadline sets the read deadline; each write has its own timeout
+func (s *Stream) SetDeadline(t time.Time) error {
+	return s.SetReadDeadline(t)
+}
+
+func (s *Stream) SetReadDeadline(t time.Time) error {
+	s.deadlineMu.Lock()
+	defer s.deadlineMu.Unlock()
+	s.deadline = t
+	return nil
+}
+
+// SetWriteDeadline is a no-op; each frame is written with its own timeout
+func (s *Stream) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer upgrades requests carrying the right header and echoes
+// messages back through a Stream
+func echoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		ws, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stream := NewStream(ws)
+		go func() {
+			defer stream.Close()
+			reader := bufio.NewReader(stream)
+			for {
+				line, err := reader.ReadBytes('\n')
+				if err != nil {
+					return
+				}
+				stream.Write(append([]byte("echo "), line...))
+			}
+		}()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDialAndStream(t *testing.T) {
+	server := echoServer(t)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/"
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := Dial(ctx, url, nil); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("Expected the handshake to be refused without a token, got %v", err)
+	}
+
+	ws, err := Dial(ctx, url, http.Header{"Authorization": {"Bearer secret"}})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	stream := NewStream(ws)
+	defer stream.Close()
+
+	// Pings are answered without surfacing as data
+	if err := ws.WriteFrame(OpPing, []byte("hi")); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if _, err := stream.Write([]byte("one\ntwo\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	reader := bufio.NewReader(stream)
+	stream.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for _, want := range []string{"echo one\n", "echo two\n"} {
+		line, err := reader.ReadString('\n')
+		if err != nil || line != want {
+			t.Fatalf("Expected %q, got %q (err %v)", want, line, err)
+		}
+	}
+
+	stream.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := reader.ReadByte(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected the read deadline to expire, got %v", err)
+	}
+}
+
+func TestDialRejectsOtherSchemes(t *testing.T) {
+	if _, err := Dial(context.Background(), "http://localhost/ipc", nil); err == nil {
+		t.Error("Expected an http URL to be rejected")
+	}
+}
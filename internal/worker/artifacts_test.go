@@ -0,0 +1,186 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/artifacts"
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestWorkerPublishesArtifacts(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	storeDir := filepath.Join(tmpDir, "artifacts")
+	metricsDir := filepath.Join(tmpDir, "metrics")
+	config := Config{
+		ID:            1,
+		RepoPath:      repoPath,
+		WorkDir:       filepath.Join(tmpDir, "work"),
+		CIStatusDir:   filepath.Join(tmpDir, "ci-status"),
+		MetricsDir:    metricsDir,
+		SkipCI:        true,
+		AgentCommand:  "sh",
+		AgentArgs:     []string{"-c", "mkdir -p dist && echo binary > dist/app.bin && echo package main > main.go"},
+		ArtifactStore: &storage.LocalStore{Dir: storeDir},
+	}
+	w := New(config, queue.New())
+
+	testTicket := &ticket.Ticket{
+		ID:        "feat-build",
+		Title:     "Builds a binary",
+		Priority:  1,
+		Artifacts: []string{"dist/*"},
+		CreatedAt: time.Now(),
+	}
+
+	if err := w.processTicket(testTicket); err != nil {
+		t.Fatalf("processTicket failed: %v", err)
+	}
+
+	dest := filepath.Join(storeDir, "feat-build", "dist", "app.bin")
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("Expected published artifact at %s: %v", dest, err)
+	}
+	if len(testTicket.ArtifactURLs) != 1 || testTicket.ArtifactURLs[0] != "file://"+filepath.ToSlash(dest) {
+		t.Errorf("Expected the ticket to link the artifact, got %v", testTicket.ArtifactURLs)
+	}
+
+	records, err := artifacts.LoadHistory(metricsDir, "feat-build")
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(records) != 1 || records[0].Commit == "" || len(records[0].Artifacts) != 1 {
+		t.Errorf("Expected one history record with the commit, got %+v", records)
+	}
+}
+
+func TestWorkerUploadsAgentLog(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	objectDir := filepath.Join(tmpDir, "objects")
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", "echo agent says hello && echo package main > main.go"},
+		ObjectStore:  &storage.LocalStore{Dir: objectDir},
+	}
+	w := New(config, queue.New())
+
+	testTicket := &ticket.Ticket{ID: "feat-log", Title: "Logs output", Priority: 1, CreatedAt: time.Now()}
+	if err := w.processTicket(testTicket); err != nil {
+		t.Fatalf("processTicket failed: %v", err)
+	}
+
+	logs, err := filepath.Glob(filepath.Join(objectDir, "logs", "feat-log", "*-agent-1.log"))
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("Expected one uploaded agent log, got %v (err %v)", logs, err)
+	}
+	if data, _ := os.ReadFile(logs[0]); string(data) != "agent says hello\n" {
+		t.Errorf("Unexpected agent log %q", data)
+	}
+	if len(testTicket.LogURLs) != 1 {
+		t.Errorf("Expected the log's URL on the ticket, got %v", testTicket.LogURLs)
+	}
+}
+
+func TestWorkerEncryptsAgentLog(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	objectDir := filepath.Join(tmpDir, "objects")
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", "echo secret output && echo package main > main.go"},
+		ObjectStore:  &storage.LocalStore{Dir: objectDir},
+		Cipher:       cipher,
+	}
+	w := New(config, queue.New())
+
+	testTicket := &ticket.Ticket{ID: "feat-secret", Title: "Encrypts logs", Priority: 1, CreatedAt: time.Now()}
+	if err := w.processTicket(testTicket); err != nil {
+		t.Fatalf("processTicket failed: %v", err)
+	}
+
+	logs, err := filepath.Glob(filepath.Join(objectDir, "logs", "feat-secret", "*-agent-1.log"))
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("Expected one uploaded agent log, got %v (err %v)", logs, err)
+	}
+	data, _ := os.ReadFile(logs[0])
+	if !encryption.IsEncrypted(data) {
+		t.Fatalf("Expected agent log to be encrypted, got %q", data)
+	}
+	if plaintext, err := cipher.Decrypt(data); err != nil || string(plaintext) != "secret output\n" {
+		t.Errorf("Unexpected decrypted agent log %q (err %v)", plaintext, err)
+	}
+}
+
+func TestUploadCIOutputTrimsLocalStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	statusDir := filepath.Join(tmpDir, "ci-status")
+	objectDir := filepath.Join(tmpDir, "objects")
+	w := New(Config{ID: 1, RepoPath: tmpDir, WorkDir: tmpDir, CIStatusDir: statusDir, ObjectStore: &storage.LocalStore{Dir: objectDir}}, queue.New())
+
+	status := &ci.Status{Ref: "refs/heads/agent-1/feat-1", Commit: "abc123", Status: "FAIL", Output: "--- FAIL: TestLogin"}
+	if err := ci.WriteStatus(statusDir, status); err != nil {
+		t.Fatalf("WriteStatus failed: %v", err)
+	}
+	w.uploadCIOutput(&ticket.Ticket{ID: "feat-1"}, "abc123")
+
+	uploaded, err := os.ReadFile(filepath.Join(objectDir, "ci", "feat-1", "abc123.json"))
+	if err != nil || !strings.Contains(string(uploaded), "--- FAIL: TestLogin") {
+		t.Fatalf("Expected the full status in object storage, got %q (err %v)", uploaded, err)
+	}
+	local, err := ci.NewStatusReader(statusDir).GetStatus("abc123")
+	if err != nil {
+		t.Fatalf("Expected the local status to be kept: %v", err)
+	}
+	if local.Status != "FAIL" || strings.Contains(local.Output, "TestLogin") || !strings.HasPrefix(local.Output, "Output uploaded to ") {
+		t.Errorf("Expected the local output replaced by a pointer, got %+v", local)
+	}
+}
@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// DefaultAttributionDir is where provenance files go on the ticket's branch
+// when no directory is configured
+const DefaultAttributionDir = ".orchestrator/provenance"
+
+// AttributionConfig commits a provenance file describing how the agent
+// generated the ticket's changes, for compliance tooling
+type AttributionConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Dir     string `mapstructure:"dir"` // Relative to the repository root; each ticket writes <ticket-id>.json
+}
+
+// Validate checks the attribution settings
+func (c AttributionConfig) Validate() error {
+	if !c.Enabled || c.Dir == "" {
+		return nil
+	}
+	if !filepath.IsLocal(c.Dir) {
+		return fmt.Errorf("dir %q must be a relative path inside the repository", c.Dir)
+	}
+	return nil
+}
+
+// Attribution is the provenance file committed with an agent's changes
+type Attribution struct {
+	TicketID        string             `json:"ticket_id"`
+	Title           string             `json:"title"`
+	Type            string             `json:"type,omitempty"`
+	Attempt         int                `json:"attempt,omitempty"`
+	WorkerID        int                `json:"worker_id"`
+	Agent           AgentAttribution   `json:"agent"`
+	PromptSHA256    string             `json:"prompt_sha256"`
+	Enqueued        *ticket.Provenance `json:"enqueued,omitempty"` // How the ticket reached the backlog, when known
+	CreatedAt       time.Time          `json:"created_at,omitempty"`
+	AgentStartedAt  time.Time          `json:"agent_started_at"`
+	AgentFinishedAt time.Time          `json:"agent_finished_at"`
+	GeneratedAt     time.Time          `json:"generated_at"`
+}
+
+// AgentAttribution identifies the agent that generated the changes
+type AgentAttribution struct {
+	Command string   `json:"command"`
+	Version string   `json:"version,omitempty"` // First line of --version; empty when it could not be run
+	Args    []string `json:"args,omitempty"`
+}
+
+// AttributionPath returns where a ticket's provenance file goes under dir;
+// one file per ticket keeps ticket branches from conflicting over it
+func AttributionPath(dir, ticketID string) string {
+	if dir == "" {
+		dir = DefaultAttributionDir
+	}
+	return filepath.Join(dir, ticketID+".json")
+}
+
+// writeAttribution writes and stages the ticket's provenance file, so it is
+// committed with the agent's changes
+func (w *Worker) writeAttribution(t *ticket.Ticket, prompt string, agentStarted, agentFinished time.Time) error {
+	if !w.attribution.Enabled {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(prompt))
+	record := Attribution{
+		TicketID: t.ID,
+		Title:    t.Title,
+		Type:     t.Type,
+		Attempt:  t.Attempt,
+		WorkerID: w.ID,
+		Agent: AgentAttribution{
+			Command: w.agentCommand,
+			Version: w.agentVersion(),
+			Args:    w.agentArgs,
+		},
+		PromptSHA256:    hex.EncodeToString(sum[:]),
+		Enqueued:        t.Provenance,
+		CreatedAt:       t.CreatedAt.UTC(),
+		AgentStartedAt:  agentStarted.UTC(),
+		AgentFinishedAt: agentFinished.UTC(),
+		GeneratedAt:     time.Now().UTC(),
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal provenance: %w", err)
+	}
+
+	path := AttributionPath(w.attribution.Dir, t.ID)
+	fullPath := filepath.Join(w.worktreePath, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create provenance directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+
+	cmd := command.Context(w.ctx, "git", "add", "--", path)
+	cmd.Dir = w.worktreePath
+	if output, err := w.runner.CombinedOutput(cmd); err != nil {
+		return fmt.Errorf("failed to stage provenance: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// agentVersion returns the first line of the agent's --version, asking the
+// agent once per worker; agents running in pods are not asked
+func (w *Worker) agentVersion() string {
+	w.agentVersionOnce.Do(func() {
+		if w.jobs != nil {
+			return
+		}
+		cmd := command.Context(w.ctx, w.agentCommand, "--version")
+		cmd.Env = append(os.Environ(), w.env...)
+		output, err := w.runner.Output(cmd)
+		if err != nil {
+			log.Printf("Worker %d could not get the %s version for provenance: %v", w.ID, w.agentCommand, err)
+			return
+		}
+		version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+		w.agentVersionText = strings.TrimSpace(version)
+	})
+	return w.agentVersionText
+}
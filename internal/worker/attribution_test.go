@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestWriteAttribution(t *testing.T) {
+	agent := filepath.Join(t.TempDir(), "agent")
+	if err := os.WriteFile(agent, []byte("#!/bin/sh\necho 'agent 1.2.3'\necho 'built today'\n"), 0755); err != nil {
+		t.Fatalf("Failed to write agent: %v", err)
+	}
+	w := New(Config{
+		ID:           2,
+		RepoPath:     t.TempDir(),
+		WorkDir:      t.TempDir(),
+		AgentCommand: agent,
+		AgentArgs:    []string{"--model", "fast"},
+		Attribution:  AttributionConfig{Enabled: true},
+	}, queue.New())
+	w.worktreePath = t.TempDir()
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = w.worktreePath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, output)
+	}
+
+	enqueued := &ticket.Provenance{EnqueuedBy: "cli:alice", Source: "feat-1.yaml", EnqueuedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Feature", Attempt: 2, Provenance: enqueued}
+	started := time.Date(2026, 1, 2, 4, 0, 0, 0, time.UTC)
+	finished := started.Add(3 * time.Minute)
+	if err := w.writeAttribution(tk, "Implement the feature", started, finished); err != nil {
+		t.Fatalf("writeAttribution failed: %v", err)
+	}
+
+	path := filepath.Join(".orchestrator", "provenance", "feat-1.json")
+	data, err := os.ReadFile(filepath.Join(w.worktreePath, path))
+	if err != nil {
+		t.Fatalf("Expected the provenance file: %v", err)
+	}
+	var record Attribution
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("Expected JSON, got %s: %v", data, err)
+	}
+
+	sum := sha256.Sum256([]byte("Implement the feature"))
+	if record.TicketID != "feat-1" || record.Attempt != 2 || record.WorkerID != 2 {
+		t.Errorf("Unexpected ticket fields %+v", record)
+	}
+	if record.PromptSHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected the prompt's hash, got %s", record.PromptSHA256)
+	}
+	if record.Agent.Command != agent || record.Agent.Version != "agent 1.2.3" || strings.Join(record.Agent.Args, " ") != "--model fast" {
+		t.Errorf("Unexpected agent %+v", record.Agent)
+	}
+	if !record.AgentStartedAt.Equal(started) || !record.AgentFinishedAt.Equal(finished) || record.GeneratedAt.IsZero() {
+		t.Errorf("Unexpected timestamps %+v", record)
+	}
+	if record.Enqueued == nil || record.Enqueued.EnqueuedBy != "cli:alice" {
+		t.Errorf("Expected the ticket's provenance, got %+v", record.Enqueued)
+	}
+
+	cmd = exec.Command("git", "diff", "--cached", "--name-only")
+	cmd.Dir = w.worktreePath
+	staged, err := cmd.Output()
+	if err != nil || strings.TrimSpace(string(staged)) != filepath.ToSlash(path) {
+		t.Errorf("Expected the provenance file staged, got %q (%v)", staged, err)
+	}
+}
+
+func TestWriteAttributionDisabled(t *testing.T) {
+	w := New(Config{ID: 1, RepoPath: t.TempDir(), WorkDir: t.TempDir()}, queue.New())
+	w.worktreePath = t.TempDir()
+	if err := w.writeAttribution(&ticket.Ticket{ID: "feat-1"}, "prompt", time.Now(), time.Now()); err != nil {
+		t.Fatalf("Expected nothing to do, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(w.worktreePath, ".orchestrator")); !os.IsNotExist(err) {
+		t.Errorf("Expected no provenance file, got %v", err)
+	}
+}
+
+func TestAttributionConfigValidate(t *testing.T) {
+	for _, dir := range []string{"", "meta/provenance"} {
+		if err := (AttributionConfig{Enabled: true, Dir: dir}).Validate(); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", dir, err)
+		}
+	}
+	for _, dir := range []string{"/tmp/provenance", "../provenance"} {
+		if err := (AttributionConfig{Enabled: true, Dir: dir}).Validate(); err == nil {
+			t.Errorf("Expected %q to be rejected", dir)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+)
+
+// ErrAgentAuth is returned when the agent CLI cannot run because it is not
+// logged in or its credentials have expired
+var ErrAgentAuth = errors.New("agent authentication failed")
+
+// authErrorLines match the lines amp prints when credentials are missing or
+// invalid: an "Error:" line naming the problem, or its advice to log in.
+// They are anchored to the start of a line so that the agent's own output,
+// say code handling 401 responses, is not mistaken for them.
+var authErrorLines = []*regexp.Regexp{
+	regexp.MustCompile(`(?im)^\s*error:.*\b(?:not logged in|unauthori[sz]ed|unauthenticated|invalid api key|api key is invalid|(?:credentials|token|session) (?:has |have )?expired)\b`),
+	regexp.MustCompile("(?im)^\\s*(?:please )?run `?amp login`?"),
+}
+
+// isAuthError reports whether the output of a failed agent run shows it
+// could not authenticate; only call it once the run has failed
+func isAuthError(output string) bool {
+	for _, line := range authErrorLines {
+		if line.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// PauseGate stops a pool of workers from picking up new tickets
+// A nil PauseGate is never paused
+type PauseGate struct {
+	paused bool
+	reason string
+	mu     sync.RWMutex
+}
+
+// NewPauseGate creates an open gate
+func NewPauseGate() *PauseGate {
+	return &PauseGate{}
+}
+
+// Pause closes the gate and reports whether it was previously open
+func (g *PauseGate) Pause(reason string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.paused {
+		return false
+	}
+	g.paused = true
+	g.reason = reason
+	return true
+}
+
+// Resume opens the gate again
+func (g *PauseGate) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.paused = false
+	g.reason = ""
+}
+
+// Paused reports whether the gate is closed and why
+func (g *PauseGate) Paused() (bool, string) {
+	if g == nil {
+		return false, ""
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.paused, g.reason
+}
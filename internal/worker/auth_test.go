@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestIsAuthError(t *testing.T) {
+	for _, output := range []string{
+		"Error: Not logged in. Run `amp login` first.",
+		"Thinking...\nError: 401 Unauthorized",
+		"error: your session has expired",
+		"Run amp login to continue",
+	} {
+		if !isAuthError(output) {
+			t.Errorf("Expected %q to be detected as an auth error", output)
+		}
+	}
+	for _, output := range []string{
+		"compilation failed: undefined: foo",
+		"Added a handler returning 401 Unauthorized for expired sessions",
+		"--- FAIL: TestLogin\n    login_test.go:12: expected unauthorized, got ok",
+		"The README now says to run amp login first",
+	} {
+		if isAuthError(output) {
+			t.Errorf("Expected %q not to be detected as an auth error", output)
+		}
+	}
+}
+
+func TestPauseGate(t *testing.T) {
+	var nilGate *PauseGate
+	if paused, _ := nilGate.Paused(); paused {
+		t.Error("Expected nil gate to never be paused")
+	}
+
+	g := NewPauseGate()
+	if !g.Pause("logged out") {
+		t.Error("Expected first pause to close the gate")
+	}
+	if g.Pause("again") {
+		t.Error("Expected second pause to report the gate was already closed")
+	}
+	if paused, reason := g.Paused(); !paused || reason != "logged out" {
+		t.Errorf("Expected gate paused with first reason, got %v %q", paused, reason)
+	}
+
+	g.Resume()
+	if paused, _ := g.Paused(); paused {
+		t.Error("Expected gate to be open after resume")
+	}
+}
+
+func TestWorkerAuthErrorRequeuesAndPauses(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := gitutils.NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	q := queue.New()
+	gate := NewPauseGate()
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		Pause:        gate,
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", "echo 'Error: not logged in, run amp login'; exit 1"},
+	}
+	w := New(config, q)
+
+	var published []string
+	w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+		published = append(published, eventType)
+	})
+
+	testTicket := &ticket.Ticket{
+		ID:        "feat-auth",
+		Title:     "Needs credentials",
+		Priority:  1,
+		CreatedAt: time.Now(),
+	}
+
+	err := w.processTicket(testTicket)
+	if !errors.Is(err, ErrAgentAuth) {
+		t.Fatalf("Expected ErrAgentAuth, got %v", err)
+	}
+
+	if q.Len() != 1 || q.Peek().ID != "feat-auth" {
+		t.Errorf("Expected ticket to be requeued, queue has %d tickets", q.Len())
+	}
+
+	if paused, _ := gate.Paused(); !paused {
+		t.Error("Expected worker pool to be paused")
+	}
+
+	authEvents := 0
+	for _, e := range published {
+		if e == "auth_error" {
+			authEvents++
+		}
+	}
+	if authEvents != 1 {
+		t.Errorf("Expected one auth_error event, got %v", published)
+	}
+	if slices.Contains(published, "failed") {
+		t.Errorf("Expected no failed event for a requeued ticket, got %v", published)
+	}
+	if testTicket.FailureCode != "" {
+		t.Errorf("Expected no failure code on a requeued ticket, got %q", testTicket.FailureCode)
+	}
+
+	if w.GetStatus().CurrentTicket != nil {
+		t.Error("Expected worker to be idle after auth failure")
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"errors"
+	"log"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// ErrCancelled is returned when an operator cancelled the ticket while a
+// worker was running it
+var ErrCancelled = errors.New("cancelled by an operator")
+
+// beginTicket makes t the ticket Cancel can stop
+func (w *Worker) beginTicket(t *ticket.Ticket) {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	w.runningTicket = t.ID
+	w.cancelled = make(chan struct{})
+	w.cancelReason = ""
+}
+
+// endTicket ends the window in which Cancel can stop the ticket
+func (w *Worker) endTicket() {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	w.runningTicket = ""
+}
+
+// Cancel stops the ticket if this worker is running it: the agent is killed
+// straight away, and at its next step the worker removes the worktree and
+// drops the ticket. It reports false when the worker is not running it.
+func (w *Worker) Cancel(ticketID, reason string) bool {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	if w.runningTicket == "" || w.runningTicket != ticketID {
+		return false
+	}
+	select {
+	case <-w.cancelled:
+		return false
+	default:
+	}
+	w.cancelReason = reason
+	close(w.cancelled)
+	if w.cancelAgent != nil {
+		w.cancelAgent()
+	}
+	return true
+}
+
+// cancelRequested is closed once the running ticket is cancelled
+func (w *Worker) cancelRequested() <-chan struct{} {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	return w.cancelled
+}
+
+// isCancelled reports whether the running ticket was cancelled
+func (w *Worker) isCancelled() bool {
+	select {
+	case <-w.cancelRequested():
+		return true
+	default:
+		return false
+	}
+}
+
+// abandonCancelled cleans up after a cancelled ticket and returns ErrCancelled
+func (w *Worker) abandonCancelled(t *ticket.Ticket) error {
+	w.agentMu.Lock()
+	reason := w.cancelReason
+	w.agentMu.Unlock()
+
+	log.Printf("Worker %d dropped ticket %s: %s", w.ID, t.ID, reason)
+	w.cleanup()
+	if w.eventPublisher != nil {
+		w.eventPublisher("cancelled", w.ID, t, reason)
+	}
+	return ErrCancelled
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestCancelStopsRunningAgent(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	q := queue.New()
+	w := New(Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", "exec sleep 30"},
+		Retry:        RetryConfig{MaxAttempts: 3, BackoffSeconds: 1, MaxBackoffSeconds: 1, On: DefaultRetryCodes},
+	}, q)
+
+	var mu sync.Mutex
+	var published []string
+	w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, eventType+": "+message)
+	})
+
+	slow := &ticket.Ticket{ID: "feat-slow", Title: "Slow", Priority: 2, CreatedAt: time.Now(), Attempt: 1}
+	done := make(chan error, 1)
+	go func() { done <- w.processTicket(slow) }()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if running, _ := w.agentRun(); running != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the agent to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if w.Cancel("feat-other", "cancelled by test") {
+		t.Error("Expected Cancel to ignore a ticket the worker is not running")
+	}
+	if !w.Cancel("feat-slow", "cancelled by test") {
+		t.Fatal("Expected Cancel to stop the running ticket")
+	}
+	if w.Cancel("feat-slow", "cancelled again") {
+		t.Error("Expected a second Cancel to report false")
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCancelled) {
+			t.Fatalf("Expected ErrCancelled, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the cancelled ticket to stop")
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "work", "agent-1", "feat-slow")); !os.IsNotExist(err) {
+		t.Errorf("Expected the worktree to be removed, got %v", err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Expected a cancelled ticket not to be retried, got %d queued", q.Len())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if n := len(published); n == 0 || published[n-1] != "cancelled: cancelled by test" {
+		t.Errorf("Expected a cancelled event last, got %v", published)
+	}
+	if slices.ContainsFunc(published, func(e string) bool { return strings.HasPrefix(e, "failed:") }) {
+		t.Errorf("Expected no failed event, got %v", published)
+	}
+	if w.GetStatus().FailedTicketCount != 0 {
+		t.Error("Expected a cancelled ticket not to count as a failure")
+	}
+}
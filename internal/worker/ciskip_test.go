@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// countingBackend counts CI runs, each of which passes
+type countingBackend struct {
+	statusDir string
+	runs      int
+}
+
+func (b *countingBackend) Run(ctx context.Context, run ci.Run) error {
+	b.runs++
+	return ci.WriteStatus(b.statusDir, &ci.Status{Ref: "refs/heads/" + run.Branch, Commit: run.Commit, Status: "PASS"})
+}
+
+func TestWorkerSkipsCIForDocsOnlyChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		ticket ticket.Ticket
+		reason string
+	}{
+		{"docs only", "echo guide > README.md", ticket.Ticket{ID: "docs-1"}, "only documentation paths changed"},
+		{"skip_ci flag", "echo code > main.go", ticket.Ticket{ID: "feat-1", SkipCI: true}, "ticket sets skip_ci"},
+		{"profile", "echo code > main.go", ticket.Ticket{ID: "feat-2", Tags: []string{"docs"}}, "profile docs skips CI"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			repoPath := filepath.Join(tmpDir, "test.git")
+			if err := gitutils.InitBareRepo(repoPath); err != nil {
+				t.Fatalf("Failed to init bare repo: %v", err)
+			}
+			repo := gitutils.NewRepo(repoPath)
+			if err := repo.CreateInitialCommit(); err != nil {
+				t.Fatalf("Failed to create initial commit: %v", err)
+			}
+
+			statusDir := filepath.Join(tmpDir, "ci-status")
+			backend := &countingBackend{statusDir: statusDir}
+			config := Config{
+				ID:           1,
+				RepoPath:     repoPath,
+				WorkDir:      filepath.Join(tmpDir, "work"),
+				CIStatusDir:  statusDir,
+				CIBackend:    backend,
+				CIProfiles:   []ci.Profile{{Name: "docs", Tags: []string{"docs"}, Skip: true}},
+				DocsPaths:    []string{"*.md", "docs/"},
+				AgentCommand: "sh",
+				AgentArgs:    []string{"-c", tt.script},
+			}
+			w := New(config, queue.New())
+
+			var skipped []string
+			w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+				if eventType == "ci_skipped" {
+					skipped = append(skipped, message)
+				}
+			})
+
+			tk := tt.ticket
+			tk.Title = "Update"
+			tk.CreatedAt = time.Now()
+			if err := w.processTicket(&tk); err != nil {
+				t.Fatalf("processTicket failed: %v", err)
+			}
+
+			if backend.runs != 0 {
+				t.Errorf("Expected CI not to run, got %d runs", backend.runs)
+			}
+			if len(skipped) != 1 || skipped[0] != tt.reason {
+				t.Errorf("Expected one ci_skipped event %q, got %v", tt.reason, skipped)
+			}
+
+			status, err := ci.NewStatusReader(statusDir).GetLatestForBranch(w.branchName(&tk))
+			if err != nil || status.Status != "SKIPPED" {
+				t.Errorf("Expected a SKIPPED status, got %+v (%v)", status, err)
+			}
+		})
+	}
+}
+
+func TestWorkerRunsCIWhenCodeChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := gitutils.NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	statusDir := filepath.Join(tmpDir, "ci-status")
+	backend := &countingBackend{statusDir: statusDir}
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  statusDir,
+		CIBackend:    backend,
+		DocsPaths:    []string{"*.md"},
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", "echo guide > README.md; echo code > main.go"},
+	}
+	w := New(config, queue.New())
+
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Mixed change", CreatedAt: time.Now()}
+	if err := w.processTicket(tk); err != nil {
+		t.Fatalf("processTicket failed: %v", err)
+	}
+	if backend.runs != 1 {
+		t.Errorf("Expected CI to run for a change touching code, got %d runs", backend.runs)
+	}
+}
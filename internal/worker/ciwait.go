@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// CI results are polled from minCIPoll, doubling while nothing changes up to
+// maxCIPoll; a new step or the backend finishing polls again right away
+const (
+	minCIPoll          = 200 * time.Millisecond
+	maxCIPoll          = 5 * time.Second
+	ciProgressInterval = 15 * time.Second // Between ci_running events while the step stays the same
+	ciResultGrace      = 30 * time.Second // For the status to show up once the backend returns
+)
+
+// ciStepDispatched is reported until the backend records a step of its own
+const ciStepDispatched = "dispatched"
+
+// waitForCI waits for the commit's CI status while the dispatched run is in
+// progress, publishing ci_running events with the step it is executing
+func (w *Worker) waitForCI(t *ticket.Ticket, commitHash, branchName string, dispatched <-chan error) error {
+	log.Printf("Worker %d waiting for CI to complete for branch %s (commit %s)", w.ID, branchName, commitHash[:8])
+
+	started := time.Now()
+	step := ciStepDispatched
+	w.setCIProgress(step, started)
+	defer w.setCIProgress("", time.Time{})
+	w.publishCIRunning(t, step, started)
+	published := time.Now()
+
+	interval := minCIPoll
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	var deadline <-chan time.Time // Set once the backend returns
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+
+		case <-w.cancelRequested():
+			return ErrCancelled
+
+		case <-deadline:
+			return fmt.Errorf("%w waiting for CI results after %v", ErrTimeout, ciResultGrace)
+
+		case err := <-dispatched:
+			dispatched = nil
+			if err != nil {
+				log.Printf("Worker %d failed to trigger CI for %s: %v", w.ID, t.ID, err)
+				w.reportLimitExceeded(t, err)
+				return fmt.Errorf("failed to trigger CI: %v", err)
+			}
+			log.Printf("Worker %d: CI finished running for %s", w.ID, branchName)
+			deadline = time.After(ciResultGrace)
+			interval = minCIPoll
+			timer.Reset(0)
+
+		case <-timer.C:
+			// Look up the branch's latest status; an older commit's result doesn't count
+			status, err := w.ciStatusReader.GetLatestForBranch(branchName)
+			if err == nil && status.Commit == commitHash {
+				return w.ciResult(t, branchName, status)
+			}
+
+			interval = nextCIPoll(interval)
+			if current := ci.ReadProgress(w.ciStatusDir, commitHash); current != "" && current != step {
+				step = current
+				interval = minCIPoll
+				w.setCIProgress(step, started)
+				w.publishCIRunning(t, step, started)
+				published = time.Now()
+			} else if time.Since(published) >= ciProgressInterval {
+				w.publishCIRunning(t, step, started)
+				published = time.Now()
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// ciResult records a flaky pass and turns the commit's status into the
+// ticket's verdict
+func (w *Worker) ciResult(t *ticket.Ticket, branchName string, status *ci.Status) error {
+	w.logCIResult(status)
+	if status.Status == "FLAKY" {
+		// Pre-existing flakiness isn't the agent's fault; record it and move on
+		log.Printf("Worker %d: CI passed on retry for %s, flaky: %s", w.ID, branchName, strings.Join(status.Flaky, ", "))
+		if w.eventPublisher != nil {
+			for _, pkg := range status.Flaky {
+				w.eventPublisher("ci_flaky", w.ID, t, pkg)
+			}
+		}
+		if w.metricsDir != "" {
+			if err := ci.RecordFlaky(w.metricsDir, status); err != nil {
+				log.Printf("Worker %d failed to record flaky tests: %v", w.ID, err)
+			}
+		}
+	}
+
+	if status.Passed() {
+		log.Printf("Worker %d: CI passed for %s", w.ID, branchName)
+		return nil
+	}
+	return fmt.Errorf("CI failed for %s: %s", branchName, status.Output)
+}
+
+// nextCIPoll doubles a polling interval, up to maxCIPoll
+func nextCIPoll(interval time.Duration) time.Duration {
+	if interval *= 2; interval > maxCIPoll {
+		return maxCIPoll
+	}
+	return interval
+}
+
+// setCIProgress records the CI step reported in WorkerStatus; an empty step
+// clears it
+func (w *Worker) setCIProgress(step string, started time.Time) {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+	w.ciStep = step
+	w.ciStarted = started
+}
+
+// publishCIRunning announces the step the running CI job is executing
+func (w *Worker) publishCIRunning(t *ticket.Ticket, step string, started time.Time) {
+	if w.eventPublisher == nil {
+		return
+	}
+	elapsed := time.Since(started).Round(time.Second)
+	w.eventPublisher("ci_running", w.ID, t, fmt.Sprintf("%s (%s elapsed)", step, elapsed))
+}
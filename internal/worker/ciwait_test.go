@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// steppingBackend reports a few steps before passing, or fails to run
+type steppingBackend struct {
+	statusDir string
+	steps     []string
+	err       error
+}
+
+func (b *steppingBackend) Run(ctx context.Context, run ci.Run) error {
+	for _, step := range b.steps {
+		if err := ci.WriteProgress(b.statusDir, run.Commit, step); err != nil {
+			return err
+		}
+		time.Sleep(5 * minCIPoll)
+	}
+	if b.err != nil {
+		return b.err
+	}
+	return ci.WriteStatus(b.statusDir, &ci.Status{Ref: "refs/heads/" + run.Branch, Commit: run.Commit, Status: "PASS"})
+}
+
+func TestWorkerPublishesCIProgress(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{"passes", nil, false},
+		{"fails to run", errors.New("runner unavailable"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			repoPath := filepath.Join(tmpDir, "test.git")
+			if err := gitutils.InitBareRepo(repoPath); err != nil {
+				t.Fatalf("Failed to init bare repo: %v", err)
+			}
+			repo := gitutils.NewRepo(repoPath)
+			if err := repo.CreateInitialCommit(); err != nil {
+				t.Fatalf("Failed to create initial commit: %v", err)
+			}
+
+			statusDir := filepath.Join(tmpDir, "ci-status")
+			config := Config{
+				ID:           1,
+				RepoPath:     repoPath,
+				WorkDir:      filepath.Join(tmpDir, "work"),
+				CIStatusDir:  statusDir,
+				CIBackend:    &steppingBackend{statusDir: statusDir, steps: []string{"cloning", "go test"}, err: tt.err},
+				AgentCommand: "sh",
+				AgentArgs:    []string{"-c", "echo code > main.go"},
+			}
+			w := New(config, queue.New())
+
+			var mu sync.Mutex
+			var running []string
+			w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+				if eventType == "ci_running" {
+					mu.Lock()
+					running = append(running, message)
+					mu.Unlock()
+				}
+			})
+
+			tk := &ticket.Ticket{ID: "feat-1", Title: "Add code", CreatedAt: time.Now()}
+			err := w.processTicket(tk)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCIFailed) || !strings.Contains(err.Error(), "runner unavailable") {
+					t.Fatalf("Expected the backend's error as a CI failure, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("processTicket failed: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			want := []string{ciStepDispatched, "cloning", "go test"}
+			if len(running) != len(want) {
+				t.Fatalf("Expected ci_running events for %v, got %v", want, running)
+			}
+			for i, step := range want {
+				if !strings.HasPrefix(running[i], step+" (") || !strings.HasSuffix(running[i], " elapsed)") {
+					t.Errorf("Expected event %d to report %s with the elapsed time, got %q", i, step, running[i])
+				}
+			}
+			if status := w.GetStatus(); status.CIStep != "" || status.CIElapsed != 0 {
+				t.Errorf("Expected the CI step cleared once CI finished, got %q after %v", status.CIStep, status.CIElapsed)
+			}
+		})
+	}
+}
+
+func TestNextCIPoll(t *testing.T) {
+	interval := minCIPoll
+	for i := 0; i < 10; i++ {
+		next := nextCIPoll(interval)
+		if next < interval || next > maxCIPoll {
+			t.Fatalf("Expected %v to back off up to %v, got %v", interval, maxCIPoll, next)
+		}
+		interval = next
+	}
+	if interval != maxCIPoll {
+		t.Errorf("Expected polling to settle at %v, got %v", maxCIPoll, interval)
+	}
+}
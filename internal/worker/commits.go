@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// How a worker splits the agent's work into commits
+const (
+	CommitSingle      = "single"    // One commit for the whole ticket
+	CommitByDirectory = "directory" // One commit per top-level directory
+	CommitPlan        = "plan"      // The agent groups the changes into logical commits
+)
+
+// planPrefix starts each commit in the agent's commit plan
+const planPrefix = "COMMIT:"
+
+// CommitConfig controls how the agent's work is committed
+type CommitConfig struct {
+	Split      string `mapstructure:"split"`       // single, directory or plan
+	MaxCommits int    `mapstructure:"max_commits"` // Later groups are folded into the last commit; 0 means no limit
+}
+
+// Validate checks the commit settings
+func (c CommitConfig) Validate() error {
+	switch c.Split {
+	case "", CommitSingle, CommitByDirectory, CommitPlan:
+	default:
+		return fmt.Errorf("unknown split %q (expected %s, %s or %s)", c.Split, CommitSingle, CommitByDirectory, CommitPlan)
+	}
+	if c.MaxCommits < 0 {
+		return fmt.Errorf("max_commits cannot be negative")
+	}
+	return nil
+}
+
+// commitGroup is files committed together and the commit's message
+type commitGroup struct {
+	Message string
+	Files   []string
+}
+
+// splitCommits commits the staged changes in groups, all but the last, and
+// returns the message for the last group, which is left staged for
+// commitAllChanges to commit and push along with the rest
+func (w *Worker) splitCommits(t *ticket.Ticket, message string, args []string) (string, error) {
+	if w.commits.Split == "" || w.commits.Split == CommitSingle {
+		return message, nil
+	}
+	files, err := w.stagedFiles()
+	if err != nil {
+		return "", err
+	}
+
+	var groups []commitGroup
+	switch w.commits.Split {
+	case CommitByDirectory:
+		groups = groupByDirectory(files, message)
+	case CommitPlan:
+		groups, err = w.planCommits(t, files, args)
+		if err != nil {
+			log.Printf("Worker %d failed to plan commits for %s, committing in one: %v", w.ID, t.ID, err)
+			return message, nil
+		}
+	}
+	groups = foldGroups(groups, w.commits.MaxCommits)
+	if len(groups) < 2 {
+		return message, nil
+	}
+
+	// The last commit carries the ticket's message, so the branch tip
+	// describes the whole ticket
+	last := message
+	if w.commits.Split == CommitPlan {
+		last = groups[len(groups)-1].Message + "\n\n" + message
+	}
+
+	for _, group := range groups[:len(groups)-1] {
+		cmd := command.Context(w.ctx, "git", append([]string{"commit", "-q", "-m", group.Message, "--"}, group.Files...)...)
+		cmd.Dir = w.worktreePath
+		if output, err := w.runner.CombinedOutput(cmd); err != nil {
+			log.Printf("Worker %d git commit error: %s", w.ID, string(output))
+			return "", fmt.Errorf("git commit failed: %w", err)
+		}
+	}
+	log.Printf("Worker %d split %s into %d commits (%s)", w.ID, t.ID, len(groups), w.commits.Split)
+	return last, nil
+}
+
+// groupByDirectory groups files by their top-level directory, files at the
+// top level last, adding the directory to the subject of message
+func groupByDirectory(files []string, message string) []commitGroup {
+	subject, body, _ := strings.Cut(message, "\n")
+	byDir := make(map[string][]string)
+	var dirs []string
+	for _, file := range files {
+		dir, _, nested := strings.Cut(file, "/")
+		if !nested {
+			dir = ""
+		}
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], file)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if (dirs[i] == "") != (dirs[j] == "") {
+			return dirs[j] == ""
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	groups := make([]commitGroup, len(dirs))
+	for i, dir := range dirs {
+		where := dir + "/"
+		if dir == "" {
+			where = "top-level files"
+		}
+		groups[i] = commitGroup{Message: fmt.Sprintf("%s (%s)\n%s", subject, where, body), Files: byDir[dir]}
+	}
+	return groups
+}
+
+// planCommits asks the agent to group the staged files into logical commits
+func (w *Worker) planCommits(t *ticket.Ticket, files []string, args []string) ([]commitGroup, error) {
+	cmd := command.Context(w.ctx, "git", "diff", "--cached", "--stat")
+	cmd.Dir = w.worktreePath
+	stat, err := w.runner.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize staged changes: %w", err)
+	}
+
+	output, err := w.runAgent(args, commitPlanPrompt(t, files, string(stat)))
+	if err != nil {
+		return nil, fmt.Errorf("agent failed: %w", err)
+	}
+	return parseCommitPlan(string(output), files), nil
+}
+
+// parseCommitPlan reads the agent's commit plan: a "COMMIT: <message>" line
+// before the files of each commit. Files the plan leaves out go with the last
+// commit; files it names twice stay with the first commit naming them
+func parseCommitPlan(output string, files []string) []commitGroup {
+	staged := make(map[string]bool, len(files))
+	for _, file := range files {
+		staged[file] = true
+	}
+
+	var groups []commitGroup
+	planned := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if subject, ok := strings.CutPrefix(line, planPrefix); ok {
+			if subject = strings.TrimSpace(subject); subject != "" {
+				groups = append(groups, commitGroup{Message: subject})
+			}
+			continue
+		}
+		file := path.Clean(strings.TrimPrefix(line, "- "))
+		if len(groups) == 0 || !staged[file] || planned[file] {
+			continue
+		}
+		planned[file] = true
+		groups[len(groups)-1].Files = append(groups[len(groups)-1].Files, file)
+	}
+
+	// Commits without files are dropped
+	kept := groups[:0]
+	for _, group := range groups {
+		if len(group.Files) > 0 {
+			kept = append(kept, group)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	last := &kept[len(kept)-1]
+	for _, file := range files {
+		if !planned[file] {
+			last.Files = append(last.Files, file)
+		}
+	}
+	return kept
+}
+
+// foldGroups folds the files of groups beyond max into the last one allowed
+func foldGroups(groups []commitGroup, max int) []commitGroup {
+	if max <= 0 || len(groups) <= max {
+		return groups
+	}
+	folded := append([]commitGroup(nil), groups[:max]...)
+	last := &folded[max-1]
+	last.Files = append([]string(nil), last.Files...)
+	for _, group := range groups[max:] {
+		last.Files = append(last.Files, group.Files...)
+	}
+	return folded
+}
+
+// commitPlanPrompt asks the agent to group its changes into logical commits
+func commitPlanPrompt(t *ticket.Ticket, files []string, stat string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `You are an AI coding agent that just finished ticket %s: %s
+
+The changes are staged in the current directory:
+%s
+Group these files into a few logical commits that a reviewer can read one at a time, in the order they should be applied:
+`, t.ID, t.Title, stat)
+	for _, file := range files {
+		fmt.Fprintf(&b, "- %s\n", file)
+	}
+	fmt.Fprintf(&b, `
+Do not change, stage or commit anything. Reply only with the plan: for each commit, a line starting with "%s" followed by a one-line commit message, then the files of that commit one per line.`, planPrefix)
+	return b.String()
+}
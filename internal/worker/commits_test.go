@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestGroupByDirectory(t *testing.T) {
+	files := []string{"README.md", "internal/parser/parser.go", "cmd/app/main.go", "internal/lexer/lexer.go", "go.mod"}
+	groups := groupByDirectory(files, "Implement parser\n\nBody")
+
+	want := []commitGroup{
+		{Message: "Implement parser (cmd/)\n\nBody", Files: []string{"cmd/app/main.go"}},
+		{Message: "Implement parser (internal/)\n\nBody", Files: []string{"internal/parser/parser.go", "internal/lexer/lexer.go"}},
+		{Message: "Implement parser (top-level files)\n\nBody", Files: []string{"README.md", "go.mod"}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("Expected %+v, got %+v", want, groups)
+	}
+}
+
+func TestParseCommitPlan(t *testing.T) {
+	files := []string{"lexer.go", "lexer_test.go", "parser.go", "README.md"}
+	output := `Here is the plan:
+COMMIT: Add lexer
+lexer.go
+- lexer_test.go
+COMMIT: Nothing to see
+missing.go
+COMMIT: Parse tokens
+parser.go
+lexer.go
+
+SUMMARY: done`
+
+	groups := parseCommitPlan(output, files)
+	want := []commitGroup{
+		{Message: "Add lexer", Files: []string{"lexer.go", "lexer_test.go"}},
+		{Message: "Parse tokens", Files: []string{"parser.go", "README.md"}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("Expected %+v, got %+v", want, groups)
+	}
+
+	if groups := parseCommitPlan("I could not plan this", files); groups != nil {
+		t.Errorf("Expected no groups without a plan, got %+v", groups)
+	}
+}
+
+func TestFoldGroups(t *testing.T) {
+	groups := []commitGroup{{"a", []string{"a.go"}}, {"b", []string{"b.go"}}, {"c", []string{"c.go"}}}
+	folded := foldGroups(groups, 2)
+	if len(folded) != 2 || folded[1].Message != "b" || !reflect.DeepEqual(folded[1].Files, []string{"b.go", "c.go"}) {
+		t.Errorf("Unexpected folded groups %+v", folded)
+	}
+	if len(groups[1].Files) != 1 {
+		t.Error("Expected the original groups to be left alone")
+	}
+	if got := foldGroups(groups, 0); len(got) != 3 {
+		t.Errorf("Expected no limit for 0, got %+v", got)
+	}
+}
+
+func TestSplitCommitsByDirectory(t *testing.T) {
+	w := New(Config{ID: 1, RepoPath: t.TempDir(), WorkDir: t.TempDir(), Commits: CommitConfig{Split: CommitByDirectory}}, queue.New())
+	w.worktreePath = t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = w.worktreePath
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return string(output)
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "Initial commit")
+	for _, file := range []string{"README.md", "cmd/main.go", "pkg/util.go"} {
+		os.MkdirAll(filepath.Join(w.worktreePath, filepath.Dir(file)), 0755)
+		if err := os.WriteFile(filepath.Join(w.worktreePath, file), []byte(file+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	git("add", ".")
+
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Feature"}
+	message, err := w.splitCommits(tk, "Implement Feature\n\nDetails", nil)
+	if err != nil {
+		t.Fatalf("splitCommits failed: %v", err)
+	}
+	if message != "Implement Feature\n\nDetails" {
+		t.Errorf("Expected the ticket's message for the last commit, got %q", message)
+	}
+
+	subjects := strings.Split(strings.TrimSpace(git("log", "--format=%s")), "\n")
+	want := []string{"Implement Feature (pkg/)", "Implement Feature (cmd/)", "Initial commit"}
+	if !reflect.DeepEqual(subjects, want) {
+		t.Errorf("Expected commits %v, got %v", want, subjects)
+	}
+	if staged := strings.TrimSpace(git("diff", "--cached", "--name-only")); staged != "README.md" {
+		t.Errorf("Expected the top-level files left staged, got %q", staged)
+	}
+}
+
+func TestCommitConfigValidate(t *testing.T) {
+	if err := (CommitConfig{Split: CommitPlan, MaxCommits: 5}).Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+	if err := (CommitConfig{Split: "file"}).Validate(); err == nil {
+		t.Error("Expected an unknown split to be rejected")
+	}
+	if err := (CommitConfig{MaxCommits: -1}).Validate(); err == nil {
+		t.Error("Expected negative max_commits to be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestWorkerUsesEnvironmentSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	repos := map[string]*gitutils.GitRepo{}
+	for _, name := range []string{"default", "staging"} {
+		path := filepath.Join(tmpDir, name+".git")
+		if err := gitutils.InitBareRepo(path); err != nil {
+			t.Fatalf("Failed to init bare repo: %v", err)
+		}
+		repos[name] = gitutils.NewRepo(path)
+		if err := repos[name].CreateInitialCommit(); err != nil {
+			t.Fatalf("Failed to create initial commit: %v", err)
+		}
+	}
+
+	profiles := []ci.Profile{{Name: "frontend", Tags: []string{"ui"}}, {Name: "release", Tags: []string{"release"}}}
+	w := New(Config{
+		ID:           1,
+		RepoPath:     repos["default"].Path,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		CIProfiles:   profiles,
+		SkipCI:       true,
+		SkipAmp:      true,
+		Environments: environment.Environments{"staging": {Repository: repos["staging"].Path, CIProfile: "release"}},
+	}, queue.New())
+
+	staged := &ticket.Ticket{ID: "feat-staged", Title: "Staged", Priority: 2, Tags: []string{"ui"}, Environment: "staging", CreatedAt: time.Now()}
+	if err := w.processTicket(staged); err != nil {
+		t.Fatalf("Failed to process staging ticket: %v", err)
+	}
+	if profile := w.ciProfile(staged); profile == nil || profile.Name != "release" {
+		t.Errorf("Expected the environment's CI profile to win over tags, got %+v", profile)
+	}
+
+	plain := &ticket.Ticket{ID: "feat-plain", Title: "Plain", Priority: 2, Tags: []string{"ui"}, CreatedAt: time.Now()}
+	if err := w.processTicket(plain); err != nil {
+		t.Fatalf("Failed to process ticket: %v", err)
+	}
+	w.cleanup()
+	if profile := w.ciProfile(plain); profile == nil || profile.Name != "frontend" {
+		t.Errorf("Expected tags to select the CI profile, got %+v", profile)
+	}
+
+	for repo, branch := range map[string]string{"staging": "agent-1/feat-staged", "default": "agent-1/feat-plain"} {
+		if exists, _ := repos[repo].BranchExists(branch); !exists {
+			t.Errorf("Expected %s in the %s repository", branch, repo)
+		}
+	}
+	if exists, _ := repos["default"].BranchExists("agent-1/feat-staged"); exists {
+		t.Error("Expected the staging ticket's branch to stay out of the default repository")
+	}
+
+	unknown := &ticket.Ticket{ID: "feat-qa", Title: "QA", Priority: 2, Environment: "qa", CreatedAt: time.Now()}
+	if err := w.processTicket(unknown); err == nil {
+		t.Error("Expected a ticket for an unknown environment to fail")
+	}
+}
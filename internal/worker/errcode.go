@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brettsmith212/amp-orchestrator/internal"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+)
+
+// ErrConflict indicates the ticket's branch changed underneath the worker,
+// e.g. a push rejected as non-fast-forward
+var ErrConflict = errors.New("conflict")
+
+// ErrTimeout indicates the worker gave up waiting, e.g. for CI results
+var ErrTimeout = errors.New("timed out")
+
+// FailureCode classifies why a ticket failed
+func FailureCode(err error) ipc.ErrorCode {
+	var gitErr *internal.GitError
+	switch {
+	case errors.Is(err, ErrAgentAuth):
+		return ipc.ErrorCodeAuth
+	case errors.Is(err, ErrConflict), errors.Is(err, internal.ErrBranchExists), errors.Is(err, internal.ErrWorktreeExists):
+		return ipc.ErrorCodeConflict
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ipc.ErrorCodeTimeout
+	case errors.Is(err, internal.ErrPushFailed), errors.As(err, &gitErr) && gitErr.Operation == "push":
+		return ipc.ErrorCodePushFailed
+	case errors.Is(err, ErrNotFixed):
+		return ipc.ErrorCodeNotFixed
+	case errors.Is(err, ErrNoRegressionTest):
+		return ipc.ErrorCodeNoRegressionTest
+	case errors.Is(err, ErrGuardedFiles):
+		return ipc.ErrorCodeGuardedFiles
+	case errors.Is(err, ErrLicenseViolation):
+		return ipc.ErrorCodeLicenseViolation
+	case errors.Is(err, ErrPrecheckFailed):
+		return ipc.ErrorCodePrecheckFailed
+	case errors.Is(err, ErrPushVetoed):
+		return ipc.ErrorCodePushVetoed
+	case errors.Is(err, ErrVulnerable):
+		return ipc.ErrorCodeVulnerable
+	case errors.Is(err, ErrCIFailed):
+		return ipc.ErrorCodeCIFailed
+	}
+	return ipc.ErrorCodeAgentFailed
+}
+
+// retryableCodes are the failure codes a retry policy may name; auth
+// failures requeue the ticket and pause the pool instead
+var retryableCodes = []ipc.ErrorCode{
+	ipc.ErrorCodeAgentFailed,
+	ipc.ErrorCodeCIFailed,
+	ipc.ErrorCodePushFailed,
+	ipc.ErrorCodeTimeout,
+	ipc.ErrorCodeConflict,
+	ipc.ErrorCodeNotFixed,
+	ipc.ErrorCodeNoRegressionTest,
+	ipc.ErrorCodeGuardedFiles,
+	ipc.ErrorCodeLicenseViolation,
+	ipc.ErrorCodeVulnerable,
+	ipc.ErrorCodePrecheckFailed,
+	ipc.ErrorCodePushVetoed,
+}
+
+// retryable reports whether a retry policy may name code
+func retryable(code ipc.ErrorCode) bool {
+	for _, c := range retryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
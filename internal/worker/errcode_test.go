@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal"
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+)
+
+func TestFailureCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ipc.ErrorCode
+	}{
+		{errors.New("amp CLI failed: exit status 1"), ipc.ErrorCodeAgentFailed},
+		{fmt.Errorf("%w: tests failed", ErrCIFailed), ipc.ErrorCodeCIFailed},
+		{fmt.Errorf("%w: %w waiting for CI results", ErrCIFailed, ErrTimeout), ipc.ErrorCodeTimeout},
+		{fmt.Errorf("amp CLI failed: %w", context.DeadlineExceeded), ipc.ErrorCodeTimeout},
+		{fmt.Errorf("failed to commit changes: %w", internal.NewGitError("push", "/work", errors.New("exit status 1"))), ipc.ErrorCodePushFailed},
+		{internal.NewGitError("push", "/work", fmt.Errorf("%w: agent-1/feat-1 was updated by someone else", ErrConflict)), ipc.ErrorCodeConflict},
+		{fmt.Errorf("failed to create worktree: %w", internal.NewGitError("add-worktree", "/work", internal.ErrWorktreeExists)), ipc.ErrorCodeConflict},
+		{fmt.Errorf("%w: logged out", ErrAgentAuth), ipc.ErrorCodeAuth},
+		{fmt.Errorf("%w: \"go test ./...\" still exits with 1", ErrNotFixed), ipc.ErrorCodeNotFixed},
+		{fmt.Errorf("%w: the changes include logo.png (binary)", ErrGuardedFiles), ipc.ErrorCodeGuardedFiles},
+		{fmt.Errorf("%w:\nsort.go:3: stackoverflow.com/questions/12345", ErrLicenseViolation), ipc.ErrorCodeLicenseViolation},
+		{fmt.Errorf("%w: \"go vet ./...\" failed:\nmain.go:3: undefined: x", ErrPrecheckFailed), ipc.ErrorCodePrecheckFailed},
+		{fmt.Errorf("%w:\ngo.mod changes are not whitelisted", ErrPushVetoed), ipc.ErrorCodePushVetoed},
+		{fmt.Errorf("%w: %w: govulncheck GO-2024-2687 in golang.org/x/net (critical)", ErrCIFailed, ErrVulnerable), ipc.ErrorCodeVulnerable},
+	}
+	for _, tt := range tests {
+		if got := FailureCode(tt.err); got != tt.want {
+			t.Errorf("FailureCode(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
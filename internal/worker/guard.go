@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/watch"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// What a worker does when the agent's changes include guarded files
+const (
+	GuardReject = "reject" // Fail the ticket without committing
+	GuardWarn   = "warn"   // Commit anyway and report the files
+)
+
+// DefaultBlockedPaths are the paths kept out of agent commits by default:
+// installed dependencies and build caches
+var DefaultBlockedPaths = []string{
+	"node_modules/",
+	"bower_components/",
+	"vendor/",
+	".venv/",
+	"venv/",
+	"__pycache__/",
+	"*.pyc",
+}
+
+// ErrGuardedFiles indicates the agent's changes include files the guardrails reject
+var ErrGuardedFiles = errors.New("guarded files")
+
+// FileGuardConfig catches oversized files, binaries and blocked paths in the
+// agent's changes before they are committed
+type FileGuardConfig struct {
+	Enabled   bool     `mapstructure:"enabled"`
+	MaxFileKB int      `mapstructure:"max_file_kb"` // Largest file the agent may add or change; 0 disables
+	Binaries  bool     `mapstructure:"binaries"`    // Whether binary files are guarded
+	Blocklist []string `mapstructure:"blocklist"`   // gitignore-style paths the agent may not add or change
+	Action    string   `mapstructure:"action"`      // reject or warn
+}
+
+// Validate checks the file guard settings
+func (c FileGuardConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Action != GuardReject && c.Action != GuardWarn {
+		return fmt.Errorf("unknown action %q (expected %s or %s)", c.Action, GuardReject, GuardWarn)
+	}
+	if c.MaxFileKB < 0 {
+		return errors.New("max_file_kb cannot be negative")
+	}
+	return nil
+}
+
+// guardedFile is a staged file the guardrails caught and why
+type guardedFile struct {
+	Path   string
+	Reason string
+}
+
+func (g guardedFile) String() string {
+	return g.Path + " (" + g.Reason + ")"
+}
+
+// guardFiles checks the staged changes against the file guardrails, failing
+// the ticket or only reporting the files depending on the configured action
+func (w *Worker) guardFiles(t *ticket.Ticket) error {
+	if !w.fileGuard.Enabled {
+		return nil
+	}
+	guarded, err := w.findGuardedFiles()
+	if err != nil {
+		return err
+	}
+	if len(guarded) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(guarded))
+	for i, file := range guarded {
+		descriptions[i] = file.String()
+	}
+	message := "the changes include " + strings.Join(descriptions, ", ")
+	log.Printf("Worker %d file guard for %s (%s): %s", w.ID, t.ID, w.fileGuard.Action, message)
+	if w.eventPublisher != nil {
+		if w.fileGuard.Action == GuardReject {
+			w.eventPublisher("files_rejected", w.ID, t, message)
+		} else {
+			w.eventPublisher("files_warned", w.ID, t, message)
+		}
+	}
+	if w.fileGuard.Action == GuardReject {
+		return fmt.Errorf("%w: %s", ErrGuardedFiles, message)
+	}
+	return nil
+}
+
+// findGuardedFiles lists the staged files, other than deletions, that are
+// blocked, binary or too large
+func (w *Worker) findGuardedFiles() ([]guardedFile, error) {
+	cmd := command.Context(w.ctx, "git", "diff", "--cached", "--numstat", "--no-renames", "--diff-filter=d", "-z")
+	cmd.Dir = w.worktreePath
+	output, err := w.runner.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+
+	blocked := watch.NewIgnoreMatcher(w.fileGuard.Blocklist)
+	maxSize := int64(w.fileGuard.MaxFileKB) << 10
+	var guarded []guardedFile
+	for _, record := range strings.Split(string(output), "\x00") {
+		// Each record is "<added>\t<deleted>\t<path>", with "-" counts for binaries
+		fields := strings.SplitN(record, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		path := fields[2]
+
+		if blocked.Match(path, false) {
+			guarded = append(guarded, guardedFile{Path: path, Reason: "blocked path"})
+			continue
+		}
+		if w.fileGuard.Binaries && fields[0] == "-" && fields[1] == "-" {
+			guarded = append(guarded, guardedFile{Path: path, Reason: "binary"})
+			continue
+		}
+		if maxSize > 0 {
+			info, err := os.Lstat(filepath.Join(w.worktreePath, path))
+			if err != nil {
+				return nil, fmt.Errorf("failed to check size of %s: %w", path, err)
+			}
+			if info.Size() > maxSize {
+				guarded = append(guarded, guardedFile{Path: path, Reason: fmt.Sprintf("%d KB, over %d KB", (info.Size()+1023)>>10, w.fileGuard.MaxFileKB)})
+			}
+		}
+	}
+	return guarded, nil
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// newGuardWorker returns a worker whose worktree has a small source file, a
+// large file, a binary and a dependency staged, and a deleted binary
+func newGuardWorker(t *testing.T, config FileGuardConfig) (*Worker, *[]string) {
+	t.Helper()
+	w := newWorktreeWorker(t, Config{FileGuard: config})
+	worktreeWrite(t, w, "old.bin", "\x00\x01\x02")
+	worktreeGit(t, w, "add", ".")
+	worktreeGit(t, w, "commit", "-q", "-m", "Initial commit")
+	worktreeGit(t, w, "rm", "-q", "old.bin")
+	worktreeWrite(t, w, "main.go", "package main\n")
+	worktreeWrite(t, w, "data.txt", strings.Repeat("row\n", 1<<10))
+	worktreeWrite(t, w, "logo.png", "\x89PNG\x00\x00\x00")
+	worktreeWrite(t, w, "web/node_modules/left-pad/index.js", "module.exports = 1\n")
+	worktreeGit(t, w, "add", "-A")
+
+	var events []string
+	w.SetEventPublisher(func(eventType string, workerID int, tk *ticket.Ticket, message string) {
+		events = append(events, eventType+": "+message)
+	})
+	return w, &events
+}
+
+func TestGuardFilesRejects(t *testing.T) {
+	config := FileGuardConfig{Enabled: true, MaxFileKB: 2, Binaries: true, Blocklist: DefaultBlockedPaths, Action: GuardReject}
+	w, events := newGuardWorker(t, config)
+
+	err := w.guardFiles(&ticket.Ticket{ID: "feat-1"})
+	if !errors.Is(err, ErrGuardedFiles) {
+		t.Fatalf("Expected ErrGuardedFiles, got %v", err)
+	}
+	if len(*events) != 1 || !strings.HasPrefix((*events)[0], "files_rejected: ") {
+		t.Fatalf("Expected one files_rejected event, got %v", *events)
+	}
+	for _, want := range []string{"data.txt (4 KB, over 2 KB)", "logo.png (binary)", "web/node_modules/left-pad/index.js (blocked path)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+	for _, unwanted := range []string{"main.go", "old.bin"} {
+		if strings.Contains(err.Error(), unwanted) {
+			t.Errorf("Expected %s to pass the guard, got %v", unwanted, err)
+		}
+	}
+}
+
+func TestGuardFilesWarns(t *testing.T) {
+	config := FileGuardConfig{Enabled: true, Binaries: true, Action: GuardWarn}
+	w, events := newGuardWorker(t, config)
+
+	if err := w.guardFiles(&ticket.Ticket{ID: "feat-1"}); err != nil {
+		t.Fatalf("Expected a warning only, got %v", err)
+	}
+	if len(*events) != 1 || (*events)[0] != "files_warned: the changes include logo.png (binary)" {
+		t.Errorf("Expected a warning for the binary only, got %v", *events)
+	}
+}
+
+func TestGuardFilesDisabled(t *testing.T) {
+	w, events := newGuardWorker(t, FileGuardConfig{Binaries: true, Action: GuardReject})
+	if err := w.guardFiles(&ticket.Ticket{ID: "feat-1"}); err != nil || len(*events) != 0 {
+		t.Errorf("Expected a disabled guard to pass everything, got %v and %v", err, *events)
+	}
+}
+
+func TestFileGuardConfigValidate(t *testing.T) {
+	if err := (FileGuardConfig{}).Validate(); err != nil {
+		t.Errorf("Expected a disabled guard to be valid, got %v", err)
+	}
+	if err := (FileGuardConfig{Enabled: true, Action: "skip"}).Validate(); err == nil {
+		t.Error("Expected an unknown action to be rejected")
+	}
+	if err := (FileGuardConfig{Enabled: true, Action: GuardWarn, MaxFileKB: -1}).Validate(); err == nil {
+		t.Error("Expected negative max_file_kb to be rejected")
+	}
+}
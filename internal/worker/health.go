@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// recordFailure notes a failed ticket; it returns true when the failure
+// puts the worker into the error state
+func (w *Worker) recordFailure(t *ticket.Ticket, err error) bool {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+
+	w.phase = ""
+	w.failedTickets++
+	w.failedInARow++
+	w.lastError = err.Error()
+	w.lastErrorAt = time.Now()
+	w.lastErrorOn = t.ID
+
+	if w.maxFailures > 0 && w.failedInARow >= w.maxFailures && !w.failing {
+		w.failing = true
+		return true
+	}
+	return false
+}
+
+// recordSuccess adds a completed ticket to the totals and resets the run of
+// failed tickets
+func (w *Worker) recordSuccess(duration time.Duration) {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+	w.phase = ""
+	w.completed++
+	w.completedTime += duration
+	w.failedInARow = 0
+}
+
+// setPhase records the phase of the current ticket for WorkerStatus
+func (w *Worker) setPhase(phase string) {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+	w.phase = phase
+}
+
+// Failing reports whether the worker stopped taking tickets after too many
+// failed in a row
+func (w *Worker) Failing() bool {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+	return w.failing
+}
+
+// Restart clears the error state and has the worker re-run its
+// prerequisite checks before taking tickets again
+func (w *Worker) Restart() {
+	w.healthMu.Lock()
+	w.failing = false
+	w.failedInARow = 0
+	w.healthMu.Unlock()
+
+	select {
+	case w.restart <- struct{}{}:
+	default:
+	}
+}
+
+// Pause stops the worker taking tickets, for maintenance or to debug its
+// agent; a ticket in progress is finished first. It reports whether the
+// worker was running before
+func (w *Worker) Pause(reason string) bool {
+	return w.held.Pause(reason)
+}
+
+// Resume lets a paused worker take tickets again and reports whether it
+// was paused
+func (w *Worker) Resume() bool {
+	paused, _ := w.held.Paused()
+	w.held.Resume()
+	return paused
+}
+
+// recordError updates the failure counts after a ticket returned err; it
+// returns true when the worker stops taking tickets as a result
+func (w *Worker) recordError(t *ticket.Ticket, err error) bool {
+	if errors.Is(err, ErrPreempted) || errors.Is(err, ErrAgentAuth) || errors.Is(err, ErrCancelled) {
+		// None says anything about this worker's health
+		w.setPhase("")
+		return false
+	}
+	if !w.recordFailure(t, err) {
+		return false
+	}
+	log.Printf("Worker %d stopped taking tickets after %d failures in a row", w.ID, w.maxFailures)
+	return true
+}
+
+// errorMessage describes why a worker stopped taking tickets
+func (w *Worker) errorMessage(t *ticket.Ticket, err error) string {
+	return fmt.Sprintf("%d tickets failed in a row; last on %s: %v", w.maxFailures, t.ID, err)
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestWorkerErrorState(t *testing.T) {
+	w := New(Config{ID: 1, MaxFailures: 2}, queue.New())
+
+	w.recordError(&ticket.Ticket{ID: "a"}, fmt.Errorf("agent: %w", ErrCIFailed))
+	w.recordSuccess(time.Minute)
+	w.recordError(&ticket.Ticket{ID: "c"}, ErrPreempted)
+	w.recordError(&ticket.Ticket{ID: "d"}, errors.New("push rejected"))
+	if w.Failing() {
+		t.Fatal("Expected a success to reset the run of failures")
+	}
+
+	if !w.recordError(&ticket.Ticket{ID: "e"}, errors.New("worktree locked")) || !w.Failing() {
+		t.Fatal("Expected two failures in a row to stop the worker")
+	}
+	if w.recordError(&ticket.Ticket{ID: "f"}, errors.New("worktree locked")) {
+		t.Error("Expected only the failure that stops the worker to report it")
+	}
+
+	status := w.GetStatus()
+	if status.Status != "error" || status.FailedTicketCount != 4 || status.LastError != "worktree locked" || status.LastErrorTicket != "f" || status.LastErrorAt == nil {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	w.Restart()
+	if w.Failing() {
+		t.Error("Expected restart to clear the error state")
+	}
+	if status := w.GetStatus(); status.Status != "idle" || status.FailedTicketCount != 4 {
+		t.Errorf("Expected an idle worker keeping its failure history, got %+v", status)
+	}
+	select {
+	case <-w.restart:
+	default:
+		t.Error("Expected restart to signal the worker loop")
+	}
+}
+
+func TestWorkerErrorStateDisabled(t *testing.T) {
+	w := New(Config{ID: 1}, queue.New())
+	for i := 0; i < 10; i++ {
+		w.recordError(&ticket.Ticket{ID: "a"}, errors.New("boom"))
+	}
+	if w.Failing() {
+		t.Error("Expected max failures of 0 to never stop the worker")
+	}
+}
+
+func TestWorkerRunningTotals(t *testing.T) {
+	w := New(Config{ID: 1}, queue.New())
+	if status := w.GetStatus(); status.TicketsCompleted != 0 || status.AverageDuration != 0 || status.Uptime != 0 {
+		t.Errorf("Expected empty totals before starting, got %+v", status)
+	}
+
+	w.startedAt = time.Now().Add(-time.Hour)
+	w.publishPhase(&ticket.Ticket{ID: "a"}, "ci")
+	if status := w.GetStatus(); status.Phase != "ci" || status.Uptime < time.Hour {
+		t.Errorf("Expected the current phase and uptime, got %+v", status)
+	}
+
+	w.recordSuccess(2 * time.Minute)
+	w.recordSuccess(4 * time.Minute)
+	w.recordError(&ticket.Ticket{ID: "c"}, errors.New("boom"))
+	status := w.GetStatus()
+	if status.TicketsCompleted != 2 || status.AverageDuration != 3*time.Minute || status.FailedTicketCount != 1 || status.Phase != "" {
+		t.Errorf("Unexpected totals %+v", status)
+	}
+}
+
+func TestWorkerPauseResume(t *testing.T) {
+	w := New(Config{ID: 1}, queue.New())
+
+	if !w.Pause("disk replacement") {
+		t.Fatal("Expected the first pause to take effect")
+	}
+	if w.Pause("again") {
+		t.Error("Expected pausing a paused worker to report no change")
+	}
+	if status := w.GetStatus(); !status.Paused || status.PausedReason != "disk replacement" || status.Status != "idle" {
+		t.Errorf("Expected an idle worker paused for disk replacement, got %+v", status)
+	}
+
+	if !w.Resume() {
+		t.Fatal("Expected resume to report the worker was paused")
+	}
+	if w.Resume() {
+		t.Error("Expected resuming a running worker to report no change")
+	}
+	if status := w.GetStatus(); status.Paused || status.PausedReason != "" {
+		t.Errorf("Expected the pause cleared, got %+v", status)
+	}
+}
@@ -0,0 +1,199 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// Chores idle workers can run between tickets
+const (
+	ChorePrefetch       = "prefetch"        // Download upstream branches without touching local ones
+	ChoreGC             = "gc"              // Garbage-collect the repository
+	ChoreWarmCache      = "warm_cache"      // Build the main branch to fill the Go build cache
+	ChorePruneWorktrees = "prune_worktrees" // Drop metadata of worktrees deleted behind git's back
+)
+
+// HousekeepingConfig selects the chores idle workers run
+type HousekeepingConfig struct {
+	Chores          []string `mapstructure:"chores"`
+	IntervalMinutes int      `mapstructure:"interval_minutes"` // Each chore runs at most this often across the pool
+	Upstream        string   `mapstructure:"upstream"`         // Remote name or URL for prefetch
+}
+
+// Validate checks the chores are known and have what they need
+func (c HousekeepingConfig) Validate() error {
+	seen := make(map[string]bool, len(c.Chores))
+	for _, chore := range c.Chores {
+		switch chore {
+		case ChorePrefetch:
+			if c.Upstream == "" {
+				return errors.New("the prefetch chore needs an upstream")
+			}
+		case ChoreGC, ChoreWarmCache, ChorePruneWorktrees:
+		default:
+			return fmt.Errorf("unknown chore %q (expected prefetch, gc, warm_cache or prune_worktrees)", chore)
+		}
+		if seen[chore] {
+			return fmt.Errorf("chore %s is listed twice", chore)
+		}
+		seen[chore] = true
+	}
+	if len(c.Chores) > 0 && c.IntervalMinutes < 1 {
+		return errors.New("interval_minutes must be at least 1")
+	}
+	return nil
+}
+
+// Housekeeper hands chores to idle workers so each runs on one worker at a
+// time and at most once per interval
+type Housekeeper struct {
+	config   HousekeepingConfig
+	interval time.Duration
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+	running map[string]bool
+}
+
+// NewHousekeeper returns a Housekeeper shared by the pool, or nil when no
+// chores are configured
+func NewHousekeeper(config HousekeepingConfig) *Housekeeper {
+	if len(config.Chores) == 0 {
+		return nil
+	}
+	return &Housekeeper{
+		config:   config,
+		interval: time.Duration(config.IntervalMinutes) * time.Minute,
+		lastRun:  make(map[string]time.Time),
+		running:  make(map[string]bool),
+	}
+}
+
+// claim returns a chore that is due and marks it running, or "" if none is
+func (h *Housekeeper) claim() string {
+	if h == nil {
+		return ""
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, chore := range h.config.Chores {
+		if h.running[chore] || time.Since(h.lastRun[chore]) < h.interval {
+			continue
+		}
+		h.running[chore] = true
+		return chore
+	}
+	return ""
+}
+
+// release returns a claimed chore; one that was interrupted is due again
+// straight away
+func (h *Housekeeper) release(chore string, interrupted bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.running, chore)
+	if !interrupted {
+		h.lastRun[chore] = time.Now()
+	}
+}
+
+// startChore runs a due chore in the background while the worker is idle;
+// stopChore interrupts it when a ticket arrives
+func (w *Worker) startChore(ctx context.Context) {
+	if w.choreDone != nil {
+		select {
+		case <-w.choreDone:
+			w.choreDone = nil
+		default:
+			return
+		}
+	}
+
+	chore := w.housekeeper.claim()
+	if chore == "" {
+		return
+	}
+
+	choreCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	w.cancelChore, w.choreDone = cancel, done
+
+	go func() {
+		defer close(done)
+		defer cancel()
+
+		start := time.Now()
+		err := w.runChore(choreCtx, chore)
+		interrupted := choreCtx.Err() != nil
+		w.housekeeper.release(chore, interrupted)
+
+		switch {
+		case interrupted:
+			log.Printf("Worker %d interrupted chore %s", w.ID, chore)
+		case err != nil:
+			log.Printf("Worker %d chore %s failed: %v", w.ID, chore, err)
+		default:
+			log.Printf("Worker %d finished chore %s in %s", w.ID, chore, time.Since(start).Round(time.Millisecond))
+		}
+	}()
+}
+
+// stopChore interrupts the running chore, if any, and waits for it to exit
+func (w *Worker) stopChore() {
+	if w.choreDone == nil {
+		return
+	}
+	w.cancelChore()
+	<-w.choreDone
+	w.choreDone = nil
+}
+
+// runChore performs one chore, stopping early if ctx is cancelled
+func (w *Worker) runChore(ctx context.Context, chore string) error {
+	switch chore {
+	case ChorePrefetch:
+		return w.defaultRepo.Prefetch(ctx, w.housekeeper.config.Upstream)
+	case ChoreGC:
+		return w.defaultRepo.Maintain(ctx)
+	case ChorePruneWorktrees:
+		return w.defaultRepo.PruneWorktrees(ctx)
+	case ChoreWarmCache:
+		return w.warmBuildCache(ctx)
+	}
+	return fmt.Errorf("unknown chore %q", chore)
+}
+
+// warmBuildCache builds the main branch in a scratch worktree so the first
+// ticket after an idle spell finds the Go build cache filled
+func (w *Worker) warmBuildCache(ctx context.Context) error {
+	scratchPath := filepath.Join(w.workDir, fmt.Sprintf("agent-%d", w.ID), "housekeeping")
+	os.RemoveAll(scratchPath)
+	repo := w.defaultRepo.WithContext(ctx)
+	if err := repo.AddDetachedWorktree(scratchPath); err != nil {
+		return err
+	}
+	defer repo.WithContext(context.WithoutCancel(ctx)).RemoveWorktree(scratchPath)
+
+	if _, err := os.Stat(filepath.Join(scratchPath, "go.mod")); os.IsNotExist(err) {
+		return nil
+	}
+
+	cmd := command.Context(ctx, "go", "build", "./...")
+	cmd.Dir = scratchPath
+	cmd.Env = append(os.Environ(), w.env...)
+	if output, err := w.runner.CombinedOutput(cmd); err != nil {
+		return fmt.Errorf("go build failed: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
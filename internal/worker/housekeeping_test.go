@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+)
+
+func TestHousekeepingConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config HousekeepingConfig
+		valid  bool
+	}{
+		{"none", HousekeepingConfig{}, true},
+		{"all", HousekeepingConfig{Chores: []string{ChorePrefetch, ChoreGC, ChoreWarmCache, ChorePruneWorktrees}, IntervalMinutes: 60, Upstream: "origin"}, true},
+		{"unknown chore", HousekeepingConfig{Chores: []string{"vacuum"}, IntervalMinutes: 60}, false},
+		{"duplicate chore", HousekeepingConfig{Chores: []string{ChoreGC, ChoreGC}, IntervalMinutes: 60}, false},
+		{"prefetch without upstream", HousekeepingConfig{Chores: []string{ChorePrefetch}, IntervalMinutes: 60}, false},
+		{"no interval", HousekeepingConfig{Chores: []string{ChoreGC}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestHousekeeperClaim(t *testing.T) {
+	if NewHousekeeper(HousekeepingConfig{}) != nil {
+		t.Error("Expected no housekeeper without chores")
+	}
+
+	h := NewHousekeeper(HousekeepingConfig{Chores: []string{ChoreGC, ChorePruneWorktrees}, IntervalMinutes: 60})
+	if chore := h.claim(); chore != ChoreGC {
+		t.Fatalf("Expected gc first, got %q", chore)
+	}
+	// Another worker gets the next chore rather than the running one
+	if chore := h.claim(); chore != ChorePruneWorktrees {
+		t.Fatalf("Expected prune_worktrees while gc runs, got %q", chore)
+	}
+	if chore := h.claim(); chore != "" {
+		t.Fatalf("Expected nothing left to claim, got %q", chore)
+	}
+
+	h.release(ChoreGC, false)
+	h.release(ChorePruneWorktrees, true)
+	if chore := h.claim(); chore != ChorePruneWorktrees {
+		t.Errorf("Expected the interrupted chore to be due again, got %q", chore)
+	}
+	if chore := h.claim(); chore != "" {
+		t.Errorf("Expected finished gc to wait for its interval, got %q", chore)
+	}
+}
+
+func TestChoreStopsForTicket(t *testing.T) {
+	tmpDir, repoPath := newWarmupTestRepo(t)
+
+	// Give the main branch a go.mod so warm_cache builds it
+	clonePath := filepath.Join(tmpDir, "clone")
+	git := func(args ...string) {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	git("clone", "--quiet", repoPath, clonePath)
+	if err := os.WriteFile(filepath.Join(clonePath, "go.mod"), []byte("module example.com/test\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	git("-C", clonePath, "add", "go.mod")
+	git("-C", clonePath, "commit", "--quiet", "-m", "Add go.mod")
+	git("-C", clonePath, "push", "--quiet", "origin", "HEAD")
+
+	// A go that never finishes stands in for a long build
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "go"), []byte("#!/bin/sh\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake go: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	housekeeper := NewHousekeeper(HousekeepingConfig{Chores: []string{ChoreWarmCache}, IntervalMinutes: 60})
+	w := New(Config{ID: 1, RepoPath: repoPath, WorkDir: filepath.Join(tmpDir, "work"), Housekeeper: housekeeper}, queue.New())
+
+	w.startChore(context.Background())
+	if w.choreDone == nil {
+		t.Fatal("Expected a chore to start")
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	w.stopChore()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the chore to stop promptly, took %s", elapsed)
+	}
+	if chore := housekeeper.claim(); chore != ChoreWarmCache {
+		t.Errorf("Expected the interrupted chore to be due again, got %q", chore)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "work", "agent-1", "housekeeping")); !os.IsNotExist(err) {
+		t.Errorf("Expected the scratch worktree to be removed, got %v", err)
+	}
+}
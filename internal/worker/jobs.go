@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/kube"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// JobRunner runs a ticket's agent outside this process, e.g. as a Kubernetes
+// Job; the job clones the repository and pushes its work to the ticket's branch
+type JobRunner interface {
+	Run(ctx context.Context, job kube.Job) (kube.Result, error)
+}
+
+// runJob runs the agent for a ticket through the job runner and checks that
+// it pushed a new commit to the branch
+func (w *Worker) runJob(t *ticket.Ticket, branchName string) error {
+	prompt, err := w.renderPrompt(t)
+	if err != nil {
+		return err
+	}
+
+	release, err := w.acquireAgentSlot()
+	if err != nil {
+		return fmt.Errorf("waiting for agent rate limit: %w", err)
+	}
+	defer release()
+
+	// The branch may not exist yet
+	before, _ := w.repo.GetBranchCommit(branchName)
+
+	log.Printf("Worker %d running ticket %s as a job", w.ID, t.ID)
+	result, err := w.jobs.Run(w.agentContext(), kube.Job{Ticket: t, Branch: branchName, Prompt: prompt, WorkerID: w.ID})
+	w.logAgentOutput(result.Logs)
+	w.uploadAgentLog(t, result.Logs)
+	if err != nil {
+		log.Printf("Worker %d job %s output: %s", w.ID, result.Name, string(result.Logs))
+		if isAuthError(string(result.Logs)) {
+			return fmt.Errorf("%w: %v", ErrAgentAuth, err)
+		}
+		return fmt.Errorf("agent job failed: %w", err)
+	}
+
+	after, err := w.repo.GetBranchCommit(branchName)
+	if err != nil || after == before {
+		return fmt.Errorf("job %s did not push a commit to %s", result.Name, branchName)
+	}
+
+	t.Summary = extractSummary(string(result.Logs))
+	log.Printf("Worker %d job %s pushed %s to %s", w.ID, result.Name, after, branchName)
+	return nil
+}
@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/kube"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// fakeJobRunner stands in for a cluster: it commits to the ticket's branch
+// the way the job's container would push to it
+type fakeJobRunner struct {
+	repo *gitutils.GitRepo
+	dir  string
+	logs string
+	err  error
+	jobs []kube.Job
+}
+
+func (f *fakeJobRunner) Run(ctx context.Context, job kube.Job) (kube.Result, error) {
+	f.jobs = append(f.jobs, job)
+	result := kube.Result{Name: "amp-" + job.Ticket.ID, Logs: []byte(f.logs)}
+	if f.err != nil {
+		return result, f.err
+	}
+
+	worktree := filepath.Join(f.dir, job.Ticket.ID)
+	if _, err := f.repo.AddWorktree(worktree, job.Branch); err != nil {
+		return result, err
+	}
+	defer f.repo.RemoveWorktree(worktree)
+	if err := os.WriteFile(filepath.Join(worktree, "main.go"), []byte("package main\n"), 0644); err != nil {
+		return result, err
+	}
+	_, err := f.repo.CommitFile(worktree, "main.go", "Implement "+job.Ticket.Title)
+	return result, err
+}
+
+func newJobTestWorker(t *testing.T, jobs *fakeJobRunner) (*Worker, *queue.Queue) {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := gitutils.NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	jobs.repo = repo
+	jobs.dir = filepath.Join(tmpDir, "pod")
+
+	q := queue.New()
+	w := New(Config{
+		ID:          2,
+		RepoPath:    repoPath,
+		WorkDir:     filepath.Join(tmpDir, "work"),
+		CIStatusDir: filepath.Join(tmpDir, "ci-status"),
+		SkipCI:      true,
+		Jobs:        jobs,
+	}, q)
+	return w, q
+}
+
+func TestWorkerRunsTicketAsJob(t *testing.T) {
+	jobs := &fakeJobRunner{logs: "cloning\nSUMMARY: Added main.go from the cluster\n"}
+	w, _ := newJobTestWorker(t, jobs)
+
+	testTicket := &ticket.Ticket{ID: "feat-job", Title: "Job feature", Priority: 1, CreatedAt: time.Now()}
+	result := w.Run(context.Background(), testTicket)
+	if result.Err != nil {
+		t.Fatalf("Run failed: %v", result.Err)
+	}
+
+	if len(jobs.jobs) != 1 || jobs.jobs[0].Branch != "agent-2/feat-job" || jobs.jobs[0].Prompt == "" {
+		t.Errorf("Unexpected jobs %+v", jobs.jobs)
+	}
+	if testTicket.Summary != "Added main.go from the cluster" {
+		t.Errorf("Expected summary from the job logs, got %q", testTicket.Summary)
+	}
+	if result.Commit == "" || !result.Implemented {
+		t.Errorf("Expected the pushed branch to be picked up, got %+v", result)
+	}
+}
+
+func TestWorkerJobFailures(t *testing.T) {
+	// A job that succeeds without pushing anything did no work
+	w, _ := newJobTestWorker(t, &fakeJobRunner{})
+	w.jobs = lazyJobRunner{}
+	if err := w.processTicket(&ticket.Ticket{ID: "feat-lazy", Title: "Lazy", Priority: 1}); err == nil {
+		t.Error("Expected an error when the job pushed nothing")
+	}
+
+	// Auth failures inside the pod requeue the ticket like local ones
+	jobs := &fakeJobRunner{err: kube.ErrJobFailed, logs: "Error: not logged in, run amp login"}
+	w, q := newJobTestWorker(t, jobs)
+	err := w.processTicket(&ticket.Ticket{ID: "feat-auth", Title: "Auth", Priority: 1})
+	if !errors.Is(err, ErrAgentAuth) {
+		t.Fatalf("Expected ErrAgentAuth, got %v", err)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Expected the ticket to be requeued, queue has %d", q.Len())
+	}
+}
+
+// lazyJobRunner reports success without touching the repository
+type lazyJobRunner struct{}
+
+func (lazyJobRunner) Run(ctx context.Context, job kube.Job) (kube.Result, error) {
+	return kube.Result{Name: "amp-lazy"}, nil
+}
@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// ErrLicenseViolation indicates the agent's changes carry a disallowed
+// license or code copied from elsewhere
+var ErrLicenseViolation = errors.New("license violation")
+
+// licenseScanTimeout bounds a license scan command
+const licenseScanTimeout = 10 * time.Minute
+
+// maxViolations is how many heuristic findings go into a report
+const maxViolations = 20
+
+// DefaultLicensePatterns are the added lines flagged when no patterns are
+// configured: copyleft license headers and links to copied answers
+var DefaultLicensePatterns = []string{
+	`SPDX-License-Identifier:\s*(A|L)?GPL`,
+	`GNU (Affero |Lesser )?General Public License`,
+	`Server Side Public License`,
+	`stackoverflow\.com/(questions|q|a)/\d+`,
+	`(?i)copied from\s+https?://`,
+}
+
+// LicenseScanConfig checks the lines the agent adds for disallowed licenses
+// and copied code before they are committed
+type LicenseScanConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Command  string   `mapstructure:"command"`  // Shell command run instead of the patterns, given the changed files on stdin; failing means violations
+	Patterns []string `mapstructure:"patterns"` // Regular expressions matched against added lines; empty uses DefaultLicensePatterns
+}
+
+// Validate checks the license scan settings
+func (c LicenseScanConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	_, err := c.compile()
+	return err
+}
+
+// compile returns the configured patterns, or the defaults
+func (c LicenseScanConfig) compile() ([]*regexp.Regexp, error) {
+	patterns := c.Patterns
+	if len(patterns) == 0 {
+		patterns = DefaultLicensePatterns
+	}
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled[i] = re
+	}
+	return compiled, nil
+}
+
+// scanLicenses fails the ticket with a report when the staged changes carry
+// a disallowed license or copied code, found by the configured command or
+// the patterns
+func (w *Worker) scanLicenses(t *ticket.Ticket) error {
+	if !w.licenseScan.Enabled {
+		return nil
+	}
+	var report string
+	var err error
+	if w.licenseScan.Command != "" {
+		report, err = w.runLicenseCommand()
+	} else {
+		report, err = w.matchLicensePatterns()
+	}
+	if err != nil {
+		return fmt.Errorf("license scan failed: %w", err)
+	}
+	if report == "" {
+		return nil
+	}
+	log.Printf("Worker %d license scan found violations in %s:\n%s", w.ID, t.ID, report)
+	return fmt.Errorf("%w:\n%s", ErrLicenseViolation, report)
+}
+
+// runLicenseCommand runs the scan command in the worktree with the added and
+// modified files on stdin, returning its output if it fails
+func (w *Worker) runLicenseCommand() (string, error) {
+	files, err := w.stagedFiles("--no-renames", "--diff-filter=AM")
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(w.ctx, licenseScanTimeout)
+	defer cancel()
+	cmd := command.Context(ctx, "sh", "-c", w.licenseScan.Command)
+	cmd.Dir = w.worktreePath
+	cmd.Env = append(os.Environ(), w.env...)
+	cmd.Stdin = strings.NewReader(strings.Join(files, "\n") + "\n")
+	output, err := w.runner.CombinedOutput(cmd)
+	if err == nil {
+		return "", nil
+	}
+	if w.ctx.Err() != nil {
+		return "", w.ctx.Err()
+	}
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("%q killed after %s", w.licenseScan.Command, licenseScanTimeout)
+	}
+	report := strings.TrimSpace(tail(string(output), maxReproduceOutput))
+	if report == "" {
+		report = fmt.Sprintf("%q exited with %d", w.licenseScan.Command, command.ExitCode(err))
+	}
+	return report, nil
+}
+
+// matchLicensePatterns matches the lines added by the staged changes against
+// the patterns, returning a line per finding
+func (w *Worker) matchLicensePatterns() (string, error) {
+	patterns, err := w.licenseScan.compile()
+	if err != nil {
+		return "", err
+	}
+	cmd := command.Context(w.ctx, "git", "-c", "core.quotePath=false", "diff", "--cached", "--unified=0", "--no-color", "--no-renames", "--diff-filter=AM")
+	cmd.Dir = w.worktreePath
+	output, err := w.runner.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to read staged changes: %w", err)
+	}
+
+	var findings []string
+	var file string
+	line := 0
+	header := false // Between a "diff --git" line and its first hunk
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			header = true
+		case header && strings.HasPrefix(text, "+++ "):
+			file = strings.TrimPrefix(strings.TrimRight(text[len("+++ "):], "\t"), "b/")
+		case strings.HasPrefix(text, "@@ "):
+			header = false
+			// "@@ -a,b +c,d @@": added lines are numbered from c
+			if _, plus, ok := strings.Cut(text, " +"); ok {
+				fmt.Sscanf(plus, "%d", &line)
+			}
+		case !header && strings.HasPrefix(text, "+"):
+			for _, re := range patterns {
+				if match := re.FindString(text[1:]); match != "" {
+					findings = append(findings, fmt.Sprintf("%s:%d: %s", file, line, strings.TrimSpace(match)))
+					break
+				}
+			}
+			line++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read staged changes: %w", err)
+	}
+
+	if len(findings) > maxViolations {
+		findings = append(findings[:maxViolations], fmt.Sprintf("... and %d more", len(findings)-maxViolations))
+	}
+	return strings.Join(findings, "\n"), nil
+}
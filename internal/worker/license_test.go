@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// newLicenseWorker returns a worker whose worktree has a committed GPL
+// header in legacy.c and the given files staged on top
+func newLicenseWorker(t *testing.T, config LicenseScanConfig, files map[string]string) *Worker {
+	t.Helper()
+	w := newWorktreeWorker(t, Config{LicenseScan: config})
+	worktreeWrite(t, w, "legacy.c", "/* SPDX-License-Identifier: GPL-2.0 */\nint main(void) { return 0; }\n")
+	worktreeGit(t, w, "add", ".")
+	worktreeGit(t, w, "commit", "-q", "-m", "Initial commit")
+	for name, content := range files {
+		worktreeWrite(t, w, name, content)
+	}
+	worktreeGit(t, w, "add", "-A")
+	return w
+}
+
+func TestScanLicensesPatterns(t *testing.T) {
+	w := newLicenseWorker(t, LicenseScanConfig{Enabled: true}, map[string]string{
+		"legacy.c": "/* SPDX-License-Identifier: GPL-2.0 */\nint main(void) { return 1; }\n",
+		"sort.go":  "package sort\n\n// Adapted from https://stackoverflow.com/questions/12345/quick-sort\nfunc Sort() {}\n",
+		"util.go":  "package util\n",
+	})
+
+	err := w.scanLicenses(&ticket.Ticket{ID: "feat-1"})
+	if !errors.Is(err, ErrLicenseViolation) {
+		t.Fatalf("Expected ErrLicenseViolation, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "sort.go:3: stackoverflow.com/questions/12345") {
+		t.Errorf("Expected the copied code's file and line in %v", err)
+	}
+	// The GPL header was there before the agent
+	if strings.Contains(err.Error(), "legacy.c") {
+		t.Errorf("Expected unchanged lines to be ignored, got %v", err)
+	}
+}
+
+func TestScanLicensesClean(t *testing.T) {
+	w := newLicenseWorker(t, LicenseScanConfig{Enabled: true}, map[string]string{"util.go": "package util\n"})
+	if err := w.scanLicenses(&ticket.Ticket{ID: "feat-1"}); err != nil {
+		t.Errorf("Expected no violations, got %v", err)
+	}
+}
+
+func TestScanLicensesCommand(t *testing.T) {
+	files := map[string]string{"a.go": "package a\n", "b.go": "package b\n"}
+
+	w := newLicenseWorker(t, LicenseScanConfig{Enabled: true, Command: `grep -q '^b.go$' && echo "b.go: MIT-incompatible"; exit 3`}, files)
+	err := w.scanLicenses(&ticket.Ticket{ID: "feat-1"})
+	if !errors.Is(err, ErrLicenseViolation) || !strings.Contains(err.Error(), "b.go: MIT-incompatible") {
+		t.Fatalf("Expected the command's report, got %v", err)
+	}
+
+	w = newLicenseWorker(t, LicenseScanConfig{Enabled: true, Command: "cat >/dev/null"}, files)
+	if err := w.scanLicenses(&ticket.Ticket{ID: "feat-1"}); err != nil {
+		t.Errorf("Expected a passing command to pass, got %v", err)
+	}
+}
+
+func TestLicenseScanConfigValidate(t *testing.T) {
+	if err := (LicenseScanConfig{Enabled: true}).Validate(); err != nil {
+		t.Errorf("Expected the default patterns to compile, got %v", err)
+	}
+	if err := (LicenseScanConfig{Enabled: true, Patterns: []string{"GPL("}}).Validate(); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/limits"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestWorkerKillsTicketOverCPULimit(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		Limits:       limits.Limits{CPUSeconds: 1},
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", "while :; do :; done"},
+	}
+	w := New(config, queue.New())
+
+	var published []string
+	w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+		published = append(published, eventType)
+	})
+
+	testTicket := &ticket.Ticket{
+		ID:        "feat-spin",
+		Title:     "Spins forever",
+		Priority:  1,
+		CreatedAt: time.Now(),
+	}
+
+	err := w.processTicket(testTicket)
+	if !errors.Is(err, limits.ErrExceeded) {
+		t.Fatalf("Expected limits.ErrExceeded, got %v", err)
+	}
+
+	// The limit is reported, then the ticket's failure
+	n := len(published)
+	if n < 2 || published[n-2] != "limit_exceeded" || published[n-1] != "failed" {
+		t.Errorf("Expected limit_exceeded and failed events, got %v", published)
+	}
+	if testTicket.FailureCode != string(ipc.ErrorCodeAgentFailed) {
+		t.Errorf("Expected the ticket to carry its failure code, got %q", testTicket.FailureCode)
+	}
+}
+
+func TestWorkerDiskQuota(t *testing.T) {
+	workerDir := t.TempDir()
+
+	w := New(Config{ID: 1, Limits: limits.Limits{DiskQuotaMB: 1}}, queue.New())
+
+	var events []string
+	w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+		events = append(events, eventType)
+	})
+
+	if !w.withinDiskQuota(workerDir) {
+		t.Fatal("Expected empty directory to be within quota")
+	}
+
+	big := filepath.Join(workerDir, "big")
+	if err := os.WriteFile(big, make([]byte, 2*1024*1024), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// Over quota is reported once, however often it is checked
+	if w.withinDiskQuota(workerDir) || w.withinDiskQuota(workerDir) {
+		t.Fatal("Expected directory to be over quota")
+	}
+	if len(events) != 1 || events[0] != "limit_exceeded" {
+		t.Errorf("Expected one limit_exceeded event, got %v", events)
+	}
+
+	if err := os.Remove(big); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if !w.withinDiskQuota(workerDir) {
+		t.Error("Expected directory to be back within quota")
+	}
+}
@@ -0,0 +1,22 @@
+package worker
+
+import (
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+)
+
+// HoldWhileRed closes gate while CI on main is red and opens it again once
+// main is green. It reports whether this call closed or opened the gate
+func HoldWhileRed(gate *PauseGate, health ipc.MainHealth) (blocked, resumed bool) {
+	if health.Red {
+		commit := health.Commit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		return gate.Pause("CI on " + health.Branch + " is failing at " + commit), false
+	}
+	if paused, _ := gate.Paused(); paused {
+		gate.Resume()
+		return false, true
+	}
+	return false, false
+}
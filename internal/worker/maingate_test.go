@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+)
+
+func TestHoldWhileRed(t *testing.T) {
+	gate := NewPauseGate()
+	red := ipc.MainHealth{Branch: "main", Commit: "0123456789abcdef", Status: "FAIL", Red: true}
+
+	if blocked, resumed := HoldWhileRed(gate, red); !blocked || resumed {
+		t.Fatalf("Expected red main to block dispatch, got blocked=%v resumed=%v", blocked, resumed)
+	}
+	if paused, reason := gate.Paused(); !paused || !strings.Contains(reason, "01234567") {
+		t.Errorf("Expected the gate closed naming the failing commit, got %v %q", paused, reason)
+	}
+	if blocked, _ := HoldWhileRed(gate, red); blocked {
+		t.Error("Expected no new block while main stays red")
+	}
+
+	// Pending tips leave main as red as its last finished run
+	pending := ipc.MainHealth{Branch: "main", Commit: "fedcba9876543210", Status: "PENDING", Red: true}
+	if blocked, resumed := HoldWhileRed(gate, pending); blocked || resumed {
+		t.Error("Expected dispatch to stay held while the new tip is pending")
+	}
+
+	green := ipc.MainHealth{Branch: "main", Commit: "fedcba9876543210", Status: "PASS"}
+	if _, resumed := HoldWhileRed(gate, green); !resumed {
+		t.Fatal("Expected green main to resume dispatch")
+	}
+	if paused, _ := gate.Paused(); paused {
+		t.Error("Expected the gate to be open")
+	}
+	if blocked, resumed := HoldWhileRed(gate, green); blocked || resumed {
+		t.Error("Expected nothing to change while main stays green")
+	}
+}
@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// ErrPrecheckFailed indicates the agent's changes failed the dry run of CI
+// in the worktree, so nothing was committed or pushed
+var ErrPrecheckFailed = errors.New("precheck failed")
+
+// DefaultPrecheckCommand is the quick CI run when no command is configured
+const DefaultPrecheckCommand = "go vet ./... && go test -short ./..."
+
+// DefaultPrecheckTimeout bounds a precheck run when no timeout is configured
+const DefaultPrecheckTimeout = 10 * time.Minute
+
+// PrecheckConfig runs a quick CI command in the worktree before the agent's
+// changes are committed, handing failures back to the agent
+type PrecheckConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	Command        string `mapstructure:"command"`         // Shell command run in the worktree; empty uses DefaultPrecheckCommand
+	Retries        int    `mapstructure:"retries"`         // Times the agent is asked to fix a failing run before the ticket fails
+	TimeoutSeconds int    `mapstructure:"timeout_seconds"` // Bounds each run; 0 uses DefaultPrecheckTimeout
+}
+
+// Validate checks the precheck settings
+func (c PrecheckConfig) Validate() error {
+	if c.Retries < 0 || c.TimeoutSeconds < 0 {
+		return errors.New("retries and timeout_seconds cannot be negative")
+	}
+	return nil
+}
+
+// command returns the configured command, or the default
+func (c PrecheckConfig) command() string {
+	if c.Command == "" {
+		return DefaultPrecheckCommand
+	}
+	return c.Command
+}
+
+// timeout returns the configured timeout, or the default
+func (c PrecheckConfig) timeout() time.Duration {
+	if c.TimeoutSeconds == 0 {
+		return DefaultPrecheckTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// precheck runs the quick CI command on the staged changes, asking the agent
+// to fix a failing run as often as configured; tickets that skip CI skip it too
+func (w *Worker) precheck(t *ticket.Ticket, args []string) error {
+	if !w.precheckConfig.Enabled || t.SkipCI {
+		return nil
+	}
+	cmdline := w.precheckConfig.command()
+
+	for attempt := 0; ; attempt++ {
+		w.publishPhase(t, "precheck")
+		output, err := w.runPrecheck()
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			log.Printf("Worker %d precheck passed for %s", w.ID, t.ID)
+			return nil
+		}
+
+		log.Printf("Worker %d precheck failed for %s:\n%s", w.ID, t.ID, output)
+		if attempt >= w.precheckConfig.Retries {
+			return fmt.Errorf("%w: %q failed:\n%s", ErrPrecheckFailed, cmdline, output)
+		}
+		log.Printf("Worker %d asking the agent to fix the precheck for %s (%d of %d)", w.ID, t.ID, attempt+1, w.precheckConfig.Retries)
+
+		w.publishPhase(t, "agent")
+		agentOutput, err := w.runAgent(args, precheckPrompt(t, cmdline, output))
+		w.uploadAgentLog(t, agentOutput)
+		if err != nil {
+			log.Printf("Worker %d amp CLI error output: %s", w.ID, string(agentOutput))
+			return fmt.Errorf("amp CLI failed fixing the precheck: %w", err)
+		}
+		if err := w.addAllChanges(); err != nil {
+			return fmt.Errorf("failed to add precheck fixes: %w", err)
+		}
+	}
+}
+
+// runPrecheck runs the precheck command in the worktree, returning the end
+// of its output if it fails
+func (w *Worker) runPrecheck() (string, error) {
+	timeout := w.precheckConfig.timeout()
+	ctx, cancel := context.WithTimeout(w.ctx, timeout)
+	defer cancel()
+
+	cmd := command.Context(ctx, "sh", "-c", w.precheckConfig.command())
+	cmd.Dir = w.worktreePath
+	cmd.Env = append(os.Environ(), w.env...)
+	output, err := w.runner.CombinedOutput(cmd)
+	if err == nil {
+		return "", nil
+	}
+	if w.ctx.Err() != nil {
+		return "", w.ctx.Err()
+	}
+
+	report := strings.TrimSpace(tail(string(output), maxReproduceOutput))
+	if ctx.Err() != nil {
+		report += fmt.Sprintf("\n(killed after %s)", timeout)
+	}
+	if report == "" {
+		report = fmt.Sprintf("exited with %d", command.ExitCode(err))
+	}
+	return report, nil
+}
+
+// precheckPrompt asks the agent to fix the failures of the quick CI run
+func precheckPrompt(t *ticket.Ticket, cmdline, output string) string {
+	return fmt.Sprintf(`You are an AI coding agent working on ticket %s: %s
+
+Description: %s
+
+Your changes are in the current directory, but running `+"`%s`"+` fails:
+`+"```"+`
+%s
+`+"```"+`
+Fix the failures without dropping the work for the ticket.
+
+Do not explain what you're doing, just fix the code.`, t.ID, t.Title, t.Description, cmdline, output)
+}
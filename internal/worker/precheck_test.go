@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// newPrecheckWorker returns a worker whose worktree has main.go staged and
+// whose agent is sh
+func newPrecheckWorker(t *testing.T, config PrecheckConfig) *Worker {
+	t.Helper()
+	w := newWorktreeWorker(t, Config{AgentCommand: "sh", Precheck: config})
+	worktreeWrite(t, w, "main.go", "package main\n")
+	worktreeGit(t, w, "add", ".")
+	return w
+}
+
+func TestPrecheckRetriesAgent(t *testing.T) {
+	config := PrecheckConfig{Enabled: true, Command: "test -f fixed.go || { echo 'undefined: fixed'; exit 1; }", Retries: 1}
+	w := newPrecheckWorker(t, config)
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Add fixed", Description: "Adds fixed"}
+
+	if err := w.precheck(tk, []string{"-c", "echo 'package main' > fixed.go"}); err != nil {
+		t.Fatalf("Expected the agent's fix to pass the precheck, got %v", err)
+	}
+	files, err := w.stagedFiles()
+	if err != nil {
+		t.Fatalf("stagedFiles failed: %v", err)
+	}
+	if strings.Join(files, ",") != "fixed.go,main.go" {
+		t.Errorf("Expected the agent's fix to be staged, got %v", files)
+	}
+}
+
+func TestPrecheckFails(t *testing.T) {
+	config := PrecheckConfig{Enabled: true, Command: "echo 'undefined: fixed'; exit 1"}
+	w := newPrecheckWorker(t, config)
+	tk := &ticket.Ticket{ID: "feat-1", Title: "Add fixed"}
+
+	err := w.precheck(tk, []string{"-c", "echo 'package main' > fixed.go"})
+	if !errors.Is(err, ErrPrecheckFailed) {
+		t.Fatalf("Expected ErrPrecheckFailed, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "undefined: fixed") {
+		t.Errorf("Expected the command's output in the error, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(w.worktreePath, "fixed.go")); !os.IsNotExist(statErr) {
+		t.Error("Expected the agent not to be asked without retries")
+	}
+
+	// Tickets that skip CI skip the precheck
+	if err := w.precheck(&ticket.Ticket{ID: "docs-1", SkipCI: true}, nil); err != nil {
+		t.Errorf("Expected skip_ci tickets to pass unchecked, got %v", err)
+	}
+}
+
+func TestPrecheckConfigValidate(t *testing.T) {
+	if err := (PrecheckConfig{Enabled: true}).Validate(); err != nil {
+		t.Errorf("Expected defaults to be valid, got %v", err)
+	}
+	if err := (PrecheckConfig{Retries: -1}).Validate(); err == nil {
+		t.Error("Expected negative retries to be rejected")
+	}
+	if (PrecheckConfig{}).command() != DefaultPrecheckCommand {
+		t.Error("Expected the default command without one configured")
+	}
+}
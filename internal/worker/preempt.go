@@ -0,0 +1,198 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// ErrPreempted is returned when a ticket was checkpointed and requeued to
+// make way for an urgent one
+var ErrPreempted = errors.New("preempted by an urgent ticket")
+
+// startAgent begins the agent phase, during which the ticket can be preempted
+func (w *Worker) startAgent(t *ticket.Ticket) {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	w.agentCtx, w.cancelAgent = context.WithCancel(w.ctx)
+	w.agentTicket = t
+	w.agentStarted = time.Now()
+	w.preempted = false
+	select {
+	case <-w.cancelled:
+		// Cancelled before the agent got going
+		w.cancelAgent()
+	default:
+	}
+}
+
+// stopAgent ends the agent phase and reports whether it was preempted
+func (w *Worker) stopAgent() bool {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	if w.cancelAgent != nil {
+		w.cancelAgent()
+	}
+	w.agentCtx, w.cancelAgent, w.agentTicket = nil, nil, nil
+	return w.preempted
+}
+
+// agentContext returns the context agents run under
+func (w *Worker) agentContext() context.Context {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	if w.agentCtx != nil {
+		return w.agentCtx
+	}
+	return w.ctx
+}
+
+// agentRun returns the ticket whose agent is running and when it started
+func (w *Worker) agentRun() (*ticket.Ticket, time.Time) {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	return w.agentTicket, w.agentStarted
+}
+
+// Preempt stops the running agent; the worker then checkpoints its work and
+// requeues the ticket. It reports false when no agent is running.
+func (w *Worker) Preempt() bool {
+	w.agentMu.Lock()
+	defer w.agentMu.Unlock()
+	if w.cancelAgent == nil || w.preempted {
+		return false
+	}
+	w.preempted = true
+	w.cancelAgent()
+	return true
+}
+
+// requeuePreempted commits whatever the agent left in the worktree to the
+// ticket's branch and puts the ticket back on the queue to resume from it
+func (w *Worker) requeuePreempted(t *ticket.Ticket, branchName string) error {
+	if w.worktreePath != "" {
+		if err := w.addAllChanges(); err == nil {
+			message := fmt.Sprintf("Checkpoint %s\n\nPreempted by an urgent ticket; work resumes from here", t.Title)
+			if commitHash, err := w.commitAllChanges(message); err == nil {
+				log.Printf("Worker %d checkpointed %s at %s", w.ID, t.ID, commitHash)
+			}
+		}
+	}
+	w.cleanup()
+
+	t.Checkpoint = branchName
+	w.queue.Push(t)
+	log.Printf("Worker %d requeued preempted ticket %s", w.ID, t.ID)
+
+	if w.eventPublisher != nil {
+		w.eventPublisher("preempted", w.ID, t, fmt.Sprintf("Preempted; checkpointed to %s and requeued", branchName))
+	}
+	return ErrPreempted
+}
+
+// Preemptor frees a worker for an urgent ticket when every worker is busy
+// with low priority work
+type Preemptor struct {
+	queue          *queue.Queue
+	workers        []*Worker
+	urgentPriority int             // Tickets at this priority or more urgent may preempt
+	victimPriority int             // Only tickets at this priority or less urgent are preempted
+	served         map[string]bool // Urgent tickets a worker was already freed for
+}
+
+// NewPreemptor creates a preemptor for a pool of workers
+func NewPreemptor(q *queue.Queue, workers []*Worker, urgentPriority, victimPriority int) *Preemptor {
+	return &Preemptor{
+		queue:          q,
+		workers:        workers,
+		urgentPriority: urgentPriority,
+		victimPriority: victimPriority,
+		served:         make(map[string]bool),
+	}
+}
+
+// Run checks for urgent tickets every interval until ctx is cancelled
+func (p *Preemptor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Check()
+		}
+	}
+}
+
+// Check preempts the least urgent, most recently started agent when an
+// urgent ticket waits and every active worker is busy with low priority
+// work; it returns the preempted ticket's ID, or "" if nothing was preempted
+func (p *Preemptor) Check() string {
+	queued := make(map[string]bool)
+	for _, t := range p.queue.List() {
+		queued[t.ID] = true
+	}
+	for id := range p.served {
+		if !queued[id] {
+			delete(p.served, id)
+		}
+	}
+
+	next := p.queue.Peek()
+	if next == nil || next.Priority > p.urgentPriority || p.served[next.ID] {
+		return ""
+	}
+
+	var victim *Worker
+	var victimTicket *ticket.Ticket
+	var victimStarted time.Time
+	for _, w := range p.workers {
+		if paused, _ := w.pause.Paused(); paused {
+			return ""
+		}
+		if paused, _ := w.lowDisk.Paused(); paused {
+			return ""
+		}
+		if paused, _ := w.mainRed.Paused(); paused {
+			return ""
+		}
+		if paused, _ := w.standby.Paused(); paused {
+			continue
+		}
+		if paused, _ := w.held.Paused(); paused {
+			// It won't take the urgent ticket, so freeing it gains nothing
+			continue
+		}
+
+		current := w.currentTask
+		if current == nil || current.Priority < p.victimPriority {
+			// A free worker will take the urgent ticket, or a busy one is
+			// on work too important to interrupt
+			return ""
+		}
+
+		t, started := w.agentRun()
+		if t == nil {
+			continue
+		}
+		if victim == nil || t.Priority > victimTicket.Priority ||
+			(t.Priority == victimTicket.Priority && started.After(victimStarted)) {
+			victim, victimTicket, victimStarted = w, t, started
+		}
+	}
+
+	if victim == nil || !victim.Preempt() {
+		return ""
+	}
+	p.served[next.ID] = true
+	log.Printf("Preempting %s (priority %d) on worker %d for urgent ticket %s",
+		victimTicket.ID, victimTicket.Priority, victim.ID, next.ID)
+	return victimTicket.ID
+}
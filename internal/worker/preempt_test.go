@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestPreemptorCheckpointsAndRequeues(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := gitutils.NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	q := queue.New()
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", "echo wip > wip.txt; exec sleep 30"},
+	}
+	w := New(config, q)
+
+	var published []string
+	w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+		published = append(published, eventType)
+	})
+
+	low := &ticket.Ticket{ID: "feat-low", Title: "Nice to have", Priority: 4, CreatedAt: time.Now()}
+	done := make(chan error, 1)
+	go func() { done <- w.processTicket(low) }()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if running, _ := w.agentRun(); running != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the agent to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Give the agent time to write its work
+	time.Sleep(200 * time.Millisecond)
+
+	p := NewPreemptor(q, []*Worker{w}, 1, 4)
+	if id := p.Check(); id != "" {
+		t.Fatalf("Expected nothing to preempt without an urgent ticket, got %s", id)
+	}
+
+	q.Push(&ticket.Ticket{ID: "fix-urgent", Title: "Outage", Priority: 1, CreatedAt: time.Now()})
+	if id := p.Check(); id != "feat-low" {
+		t.Fatalf("Expected feat-low to be preempted, got %q", id)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrPreempted) {
+			t.Fatalf("Expected ErrPreempted, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the preempted ticket to stop")
+	}
+
+	if p.Check() != "" {
+		t.Error("Expected an idle worker not to be preempted")
+	}
+
+	if q.Len() != 2 {
+		t.Fatalf("Expected both tickets queued, got %d", q.Len())
+	}
+	q.Remove("fix-urgent")
+	requeued := q.Peek()
+	if requeued.ID != "feat-low" || requeued.Checkpoint != "agent-1/feat-low" {
+		t.Errorf("Expected feat-low requeued with its checkpoint branch, got %+v", requeued)
+	}
+
+	// The agent's work was committed on top of the initial commit
+	if count, err := repo.GetCommitCount(requeued.Checkpoint); err != nil || count != 2 {
+		t.Errorf("Expected a checkpoint commit on %s, got %d commits (%v)", requeued.Checkpoint, count, err)
+	}
+
+	if n := len(published); n == 0 || published[n-1] != "preempted" {
+		t.Errorf("Expected a preempted event and no failure, got %v", published)
+	}
+	if w.GetStatus().CurrentTicket != nil {
+		t.Error("Expected the worker to be idle after preemption")
+	}
+}
+
+func TestPreemptorSparesUrgentWork(t *testing.T) {
+	q := queue.New()
+	q.Push(&ticket.Ticket{ID: "fix-urgent", Priority: 1, CreatedAt: time.Now()})
+
+	busy := New(Config{ID: 1}, q)
+	busy.currentTask = &ticket.Ticket{ID: "feat-2", Priority: 2}
+	busy.startAgent(busy.currentTask)
+	defer busy.stopAgent()
+
+	if id := NewPreemptor(q, []*Worker{busy}, 1, 4).Check(); id != "" {
+		t.Errorf("Expected priority 2 work not to be preempted, got %s", id)
+	}
+
+	idle := New(Config{ID: 2}, q)
+	busy.currentTask.Priority = 5
+	if id := NewPreemptor(q, []*Worker{busy, idle}, 1, 4).Check(); id != "" {
+		t.Errorf("Expected an idle worker to take the urgent ticket instead, got %s", id)
+	}
+}
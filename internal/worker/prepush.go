@@ -0,0 +1,150 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/kube"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// ErrPushVetoed indicates the pre-push policy hook refused the agent's
+// changes, so nothing was committed or pushed
+var ErrPushVetoed = errors.New("push vetoed")
+
+// envWorktree gives the pre-push hook the path of the worktree it polices
+const envWorktree = "ORCHESTRATOR_WORKTREE"
+
+// DefaultPrePushTimeout bounds a pre-push hook run when no timeout is configured
+const DefaultPrePushTimeout = 2 * time.Minute
+
+// PrePushConfig runs a policy command before the agent's changes are
+// committed and pushed, e.g. to require that go.mod changes only add
+// whitelisted modules. The command gets the branch's diff against main on
+// stdin and the ticket in ORCHESTRATOR_TICKET; exiting non-zero vetoes the
+// push with its output as the reason, which the agent sees on a retry. It
+// runs from the daemon's working directory so the agent cannot rewrite it.
+type PrePushConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	Command        string `mapstructure:"command"`         // Shell command run from the daemon's working directory
+	TimeoutSeconds int    `mapstructure:"timeout_seconds"` // Bounds each run; 0 uses DefaultPrePushTimeout
+}
+
+// Validate checks the pre-push hook settings
+func (c PrePushConfig) Validate() error {
+	if c.TimeoutSeconds < 0 {
+		return errors.New("timeout_seconds cannot be negative")
+	}
+	if c.Enabled && c.Command == "" {
+		return errors.New("command is required")
+	}
+	return nil
+}
+
+// timeout returns the configured timeout, or the default
+func (c PrePushConfig) timeout() time.Duration {
+	if c.TimeoutSeconds == 0 {
+		return DefaultPrePushTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// checkPrePush asks the policy hook whether the staged changes may be
+// pushed, failing the ticket with the veto reason and keeping it on the
+// ticket for the agent's next attempt
+func (w *Worker) checkPrePush(t *ticket.Ticket) error {
+	if !w.prePush.Enabled {
+		return nil
+	}
+	w.publishPhase(t, "pre_push")
+	reason, err := w.runPrePush(t)
+	if err != nil {
+		return fmt.Errorf("pre-push hook failed: %w", err)
+	}
+	if reason == "" {
+		t.PushVeto = ""
+		return nil
+	}
+
+	t.PushVeto = reason
+	log.Printf("Worker %d pre-push hook vetoed %s:\n%s", w.ID, t.ID, reason)
+	return fmt.Errorf("%w:\n%s", ErrPushVetoed, reason)
+}
+
+// runPrePush runs the hook with the diff on stdin, returning the end of its
+// output if it vetoes the push. It deliberately runs outside the worktree:
+// a relative command there would be whatever the agent left at that path.
+func (w *Worker) runPrePush(t *ticket.Ticket) (string, error) {
+	diff, err := w.branchDiff()
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	timeout := w.prePush.timeout()
+	ctx, cancel := context.WithTimeout(w.ctx, timeout)
+	defer cancel()
+	cmd := command.Context(ctx, "sh", "-c", w.prePush.Command)
+	cmd.Env = append(os.Environ(), w.env...)
+	cmd.Env = append(cmd.Env,
+		kube.EnvTicket+"="+string(payload),
+		kube.EnvTicketID+"="+t.ID,
+		kube.EnvBranch+"="+t.Branch,
+		envWorktree+"="+w.worktreePath,
+	)
+	cmd.Stdin = strings.NewReader(diff)
+	output, err := w.runner.CombinedOutput(cmd)
+	if err == nil {
+		return "", nil
+	}
+	if w.ctx.Err() != nil {
+		return "", w.ctx.Err()
+	}
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("%q killed after %s", w.prePush.Command, timeout)
+	}
+	reason := strings.TrimSpace(tail(string(output), maxReproduceOutput))
+	if reason == "" {
+		reason = fmt.Sprintf("%q exited with %d", w.prePush.Command, command.ExitCode(err))
+	}
+	return reason, nil
+}
+
+// branchDiff returns everything the branch would push: the staged changes
+// and any commits already on the branch, against where it left main
+func (w *Worker) branchDiff() (string, error) {
+	base := "HEAD"
+	if mainBranch, err := w.repo.MainBranch(); err == nil {
+		cmd := command.Context(w.ctx, "git", "merge-base", mainBranch, "HEAD")
+		cmd.Dir = w.worktreePath
+		if output, err := w.runner.Output(cmd); err == nil {
+			base = strings.TrimSpace(string(output))
+		}
+	}
+
+	cmd := command.Context(w.ctx, "git", "-c", "core.quotePath=false", "diff", "--cached", "--no-color", base)
+	cmd.Dir = w.worktreePath
+	output, err := w.runner.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the changes: %w", err)
+	}
+	return string(output), nil
+}
+
+// vetoSection tells the agent why the previous attempt's push was refused
+func vetoSection(veto string) string {
+	if veto == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\nA previous attempt at this ticket was refused by the repository's pre-push policy:\n```\n%s\n```\nMake sure your changes satisfy the policy this time.", veto)
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestPrePushVetoFedBackOnRetry(t *testing.T) {
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	// The agent only leaves go.mod alone once its prompt mentions the veto
+	agent := `cat > prompt.txt; echo 'package main' > main.go
+grep -q "pre-push policy" prompt.txt || echo 'require example.com/leftpad v1.0.0' > go.mod`
+	// The policy refuses go.mod changes, sees the ticket and runs outside
+	// the worktree the agent controls
+	hook := `if [ "$PWD" = "$ORCHESTRATOR_WORKTREE" ] || [ ! -f "$ORCHESTRATOR_WORKTREE/main.go" ]; then echo "ran in the wrong place"; exit 1; fi
+if grep -q '^+++ b/go.mod'; then echo "go.mod changes are not whitelisted for $ORCHESTRATOR_TICKET_ID"; exit 1; fi
+echo "$ORCHESTRATOR_TICKET" | grep -q '"title":"Add padding"'`
+
+	q := queue.New()
+	w := New(Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", agent},
+		PrePush:      PrePushConfig{Enabled: true, Command: hook},
+		Retry:        RetryConfig{MaxAttempts: 2, On: []string{string(ipc.ErrorCodePushVetoed)}},
+	}, q)
+
+	tk := &ticket.Ticket{ID: "feat-pad", Title: "Add padding", Description: "Pads strings", Priority: 1, CreatedAt: time.Now()}
+	err := w.processTicket(tk)
+	w.cleanup()
+	if !errors.Is(err, ErrRetrying) || !errors.Is(err, ErrPushVetoed) {
+		t.Fatalf("Expected the vetoed push to be retried, got %v", err)
+	}
+	if tk.PushVeto != "go.mod changes are not whitelisted for feat-pad" {
+		t.Fatalf("Expected the veto reason on the ticket, got %q", tk.PushVeto)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Expected the ticket to be requeued, queue has %d", q.Len())
+	}
+
+	if err := w.processTicket(tk); err != nil {
+		t.Fatalf("Expected the retry to pass the policy, got %v", err)
+	}
+	defer w.cleanup()
+	prompt, err := os.ReadFile(filepath.Join(w.worktreePath, "prompt.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read the retry's prompt: %v", err)
+	}
+	if !strings.Contains(string(prompt), "go.mod changes are not whitelisted for feat-pad") {
+		t.Errorf("Expected the veto reason in the retry's prompt, got:\n%s", prompt)
+	}
+	if tk.PushVeto != "" {
+		t.Errorf("Expected the veto to be cleared once the policy passed, got %q", tk.PushVeto)
+	}
+}
+
+func TestPrePushConfigValidate(t *testing.T) {
+	if err := (PrePushConfig{Enabled: true, Command: "scripts/policy.sh"}).Validate(); err != nil {
+		t.Errorf("Expected a command to be valid, got %v", err)
+	}
+	if err := (PrePushConfig{Enabled: true}).Validate(); err == nil {
+		t.Error("Expected an enabled hook without a command to be rejected")
+	}
+	if err := (PrePushConfig{TimeoutSeconds: -1}).Validate(); err == nil {
+		t.Error("Expected a negative timeout to be rejected")
+	}
+	if (PrePushConfig{}).timeout() != DefaultPrePushTimeout {
+		t.Error("Expected the default timeout without one configured")
+	}
+}
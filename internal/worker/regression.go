@@ -0,0 +1,159 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/watch"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// What a worker does when a bug fix changes no test files
+const (
+	RegressionRetry = "retry" // Ask the agent to add a regression test, then fail if it still hasn't
+	RegressionFail  = "fail"  // Fail the ticket straight away
+)
+
+// DefaultTestPatterns are the paths counted as tests when none are configured
+var DefaultTestPatterns = []string{
+	"*_test.go",
+	"test_*.py",
+	"*_test.py",
+	"*.test.js",
+	"*.test.ts",
+	"*.spec.js",
+	"*.spec.ts",
+	"test/",
+	"tests/",
+	"__tests__/",
+	"spec/",
+}
+
+// ErrNoRegressionTest indicates a bug fix that changes no test files
+var ErrNoRegressionTest = errors.New("no regression test")
+
+// RegressionTestConfig requires the fixes for bug tickets to add or change a test
+type RegressionTestConfig struct {
+	Enabled   bool     `mapstructure:"enabled"`
+	Patterns  []string `mapstructure:"patterns"`   // gitignore-style paths that count as tests; empty uses DefaultTestPatterns
+	OnMissing string   `mapstructure:"on_missing"` // retry or fail
+	Retries   int      `mapstructure:"retries"`    // Times the agent is asked for a test before the ticket fails
+}
+
+// Validate checks the regression test settings
+func (c RegressionTestConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.OnMissing != RegressionRetry && c.OnMissing != RegressionFail {
+		return fmt.Errorf("unknown on_missing %q (expected %s or %s)", c.OnMissing, RegressionRetry, RegressionFail)
+	}
+	if c.Retries < 0 {
+		return errors.New("retries cannot be negative")
+	}
+	return nil
+}
+
+// testMatcher returns the matcher for the configured test paths
+func (c RegressionTestConfig) testMatcher() *watch.IgnoreMatcher {
+	if len(c.Patterns) == 0 {
+		return watch.NewIgnoreMatcher(DefaultTestPatterns)
+	}
+	return watch.NewIgnoreMatcher(c.Patterns)
+}
+
+// requireRegressionTest checks a bug fix's staged changes include a test,
+// asking the agent for one as often as configured, and records each check
+// so it is kept in the audit journal
+func (w *Worker) requireRegressionTest(t *ticket.Ticket, args []string) error {
+	if !w.regression.Enabled || t.Type != ticket.TypeBug {
+		return nil
+	}
+	tests := w.regression.testMatcher()
+
+	for attempt := 0; ; attempt++ {
+		files, err := w.stagedFiles()
+		if err != nil {
+			return err
+		}
+		var changed []string
+		for _, file := range files {
+			if tests.Match(file, false) {
+				changed = append(changed, file)
+			}
+		}
+		if len(changed) > 0 {
+			w.recordRegressionCheck(t, true, "regression test in "+strings.Join(changed, ", "))
+			return nil
+		}
+
+		if w.regression.OnMissing != RegressionRetry || attempt >= w.regression.Retries {
+			w.recordRegressionCheck(t, false, "the fix changes no test files")
+			return fmt.Errorf("%w: the fix changes no test files", ErrNoRegressionTest)
+		}
+		w.recordRegressionCheck(t, false, fmt.Sprintf("the fix changes no test files; asking the agent for a regression test (%d of %d)", attempt+1, w.regression.Retries))
+
+		output, err := w.runAgent(args, regressionPrompt(t, files))
+		w.uploadAgentLog(t, output)
+		if err != nil {
+			log.Printf("Worker %d amp CLI error output: %s", w.ID, string(output))
+			return fmt.Errorf("amp CLI failed adding a regression test: %w", err)
+		}
+		if err := w.addAllChanges(); err != nil {
+			return fmt.Errorf("failed to add regression test: %w", err)
+		}
+	}
+}
+
+// stagedFiles lists the files staged in the worktree, narrowed by any extra
+// git diff arguments such as --diff-filter
+func (w *Worker) stagedFiles(args ...string) ([]string, error) {
+	cmd := command.Context(w.ctx, "git", append([]string{"diff", "--cached", "--name-only", "-z"}, args...)...)
+	cmd.Dir = w.worktreePath
+	output, err := w.runner.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+	var files []string
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// recordRegressionCheck logs and announces the outcome of a regression test check
+func (w *Worker) recordRegressionCheck(t *ticket.Ticket, passed bool, message string) {
+	log.Printf("Worker %d regression test check for %s: %s", w.ID, t.ID, message)
+	if w.eventPublisher == nil {
+		return
+	}
+	if passed {
+		w.eventPublisher("regression_test_passed", w.ID, t, message)
+	} else {
+		w.eventPublisher("regression_test_missing", w.ID, t, message)
+	}
+}
+
+// regressionPrompt asks the agent to add a test for the bug it fixed
+func regressionPrompt(t *ticket.Ticket, files []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `You are an AI coding agent working on bug %s: %s
+
+Description: %s
+
+The bug has been fixed in the current directory, changing:
+`, t.ID, t.Title, t.Description)
+	for _, file := range files {
+		fmt.Fprintf(&b, "- %s\n", file)
+	}
+	b.WriteString(`
+but no test was added or changed. Add a regression test that fails without the fix and passes with it, next to the project's existing tests. Do not change the fix itself.
+
+Do not explain what you're doing, just add the test.`)
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// newRegressionWorker returns a worker whose worktree has parser.go staged
+// and whose agent is sh
+func newRegressionWorker(t *testing.T, config RegressionTestConfig) (*Worker, *[]string) {
+	t.Helper()
+	w := newWorktreeWorker(t, Config{AgentCommand: "sh", Regression: config})
+	worktreeWrite(t, w, "parser.go", "package parser\n")
+	worktreeGit(t, w, "add", ".")
+
+	var events []string
+	w.SetEventPublisher(func(eventType string, workerID int, tk *ticket.Ticket, message string) {
+		events = append(events, eventType)
+	})
+	return w, &events
+}
+
+func TestRegressionTestRetriesAgent(t *testing.T) {
+	config := RegressionTestConfig{Enabled: true, OnMissing: RegressionRetry, Retries: 1}
+	w, events := newRegressionWorker(t, config)
+	bug := &ticket.Ticket{ID: "bug-1", Title: "Parser crash", Description: "Crashes on empty input", Type: ticket.TypeBug}
+
+	if err := w.requireRegressionTest(bug, []string{"-c", "echo 'package parser' > parser_test.go"}); err != nil {
+		t.Fatalf("Expected the agent's test to satisfy the check, got %v", err)
+	}
+	if len(*events) != 2 || (*events)[0] != "regression_test_missing" || (*events)[1] != "regression_test_passed" {
+		t.Errorf("Expected a missing then a passed check, got %v", *events)
+	}
+}
+
+func TestRegressionTestFails(t *testing.T) {
+	config := RegressionTestConfig{Enabled: true, OnMissing: RegressionFail}
+	w, events := newRegressionWorker(t, config)
+	bug := &ticket.Ticket{ID: "bug-1", Title: "Parser crash", Description: "Crashes on empty input", Type: ticket.TypeBug}
+
+	err := w.requireRegressionTest(bug, []string{"-c", "echo 'package parser' > parser_test.go"})
+	if !errors.Is(err, ErrNoRegressionTest) {
+		t.Fatalf("Expected ErrNoRegressionTest, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(w.worktreePath, "parser_test.go")); !os.IsNotExist(statErr) {
+		t.Error("Expected the agent not to be asked again with on_missing: fail")
+	}
+	if len(*events) != 1 || (*events)[0] != "regression_test_missing" {
+		t.Errorf("Expected one missing check, got %v", *events)
+	}
+
+	// Features are not checked
+	if err := w.requireRegressionTest(&ticket.Ticket{ID: "feat-1"}, nil); err != nil {
+		t.Errorf("Expected features to pass unchecked, got %v", err)
+	}
+}
+
+func TestRegressionTestConfigValidate(t *testing.T) {
+	if err := (RegressionTestConfig{}).Validate(); err != nil {
+		t.Errorf("Expected a disabled check to be valid, got %v", err)
+	}
+	if err := (RegressionTestConfig{Enabled: true, OnMissing: "ignore"}).Validate(); err == nil {
+		t.Error("Expected an unknown on_missing to be rejected")
+	}
+	if err := (RegressionTestConfig{Enabled: true, OnMissing: RegressionRetry, Retries: -1}).Validate(); err == nil {
+		t.Error("Expected negative retries to be rejected")
+	}
+}
+
+func TestDefaultTestPatterns(t *testing.T) {
+	tests := RegressionTestConfig{}.testMatcher()
+	for _, file := range []string{"parser/parser_test.go", "tests/test_parser.py", "web/src/__tests__/app.tsx", "app.spec.ts"} {
+		if !tests.Match(file, false) {
+			t.Errorf("Expected %s to count as a test", file)
+		}
+	}
+	for _, file := range []string{"parser/parser.go", "contest.go", "README.md"} {
+		if tests.Match(file, false) {
+			t.Errorf("Expected %s not to count as a test", file)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+// ErrNotFixed indicates a bug ticket's reproduce command still fails after
+// the agent's changes
+var ErrNotFixed = errors.New("bug not fixed")
+
+// reproduceTimeout bounds each run of a reproduce command
+const reproduceTimeout = 10 * time.Minute
+
+// maxReproduceOutput is how much of a reproduce command's output, from the
+// end, goes into the prompt and errors
+const maxReproduceOutput = 8 << 10
+
+// reproduction is the outcome of running a bug ticket's reproduce command
+type reproduction struct {
+	Command  string
+	ExitCode int
+	Output   string
+}
+
+// Failed reports whether the command reproduced the bug
+func (r reproduction) Failed() bool {
+	return r.ExitCode != 0
+}
+
+// reproduce runs a bug ticket's reproduce command in the worktree; a command
+// that fails is a result, not an error
+func (w *Worker) reproduce(t *ticket.Ticket) (reproduction, error) {
+	ctx, cancel := context.WithTimeout(w.ctx, reproduceTimeout)
+	defer cancel()
+
+	cmd := command.Context(ctx, "sh", "-c", t.Reproduce)
+	cmd.Dir = w.worktreePath
+	cmd.Env = append(os.Environ(), w.env...)
+	output, err := w.runner.CombinedOutput(cmd)
+
+	result := reproduction{Command: t.Reproduce, Output: tail(string(output), maxReproduceOutput)}
+	if err != nil {
+		if w.ctx.Err() != nil {
+			return result, w.ctx.Err()
+		}
+		result.ExitCode = command.ExitCode(err)
+		if ctx.Err() != nil {
+			result.Output += fmt.Sprintf("\n(killed after %s)", reproduceTimeout)
+		}
+	}
+	return result, nil
+}
+
+// reproduceBefore runs a bug ticket's reproduce command before the agent, so
+// the failure can go into the prompt
+func (w *Worker) reproduceBefore(t *ticket.Ticket) (*reproduction, error) {
+	if t.Type != ticket.TypeBug || t.Reproduce == "" {
+		return nil, nil
+	}
+	before, err := w.reproduce(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run reproduce command: %w", err)
+	}
+	if before.Failed() {
+		log.Printf("Worker %d reproduced bug %s (exit %d)", w.ID, t.ID, before.ExitCode)
+	} else {
+		log.Printf("Worker %d could not reproduce bug %s: %q succeeds before the fix", w.ID, t.ID, t.Reproduce)
+	}
+	return &before, nil
+}
+
+// verifyFix runs a bug ticket's reproduce command again after the agent, and
+// fails the ticket before CI if it still fails
+func (w *Worker) verifyFix(t *ticket.Ticket) error {
+	if t.Type != ticket.TypeBug || t.Reproduce == "" {
+		return nil
+	}
+	after, err := w.reproduce(t)
+	if err != nil {
+		return fmt.Errorf("failed to run reproduce command: %w", err)
+	}
+	if after.Failed() {
+		log.Printf("Worker %d reproduce output for %s after the fix:\n%s", w.ID, t.ID, after.Output)
+		return fmt.Errorf("%w: %q still exits with %d", ErrNotFixed, t.Reproduce, after.ExitCode)
+	}
+	log.Printf("Worker %d verified the fix for bug %s", w.ID, t.ID)
+	return nil
+}
+
+// reproductionSection tells the agent how the bug shows itself
+func reproductionSection(r *reproduction) string {
+	if r == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nBug reproduction:\n")
+	if r.Failed() {
+		fmt.Fprintf(&b, "Running `%s` in the current directory exits with %d and prints:\n", r.Command, r.ExitCode)
+	} else {
+		fmt.Fprintf(&b, "Running `%s` in the current directory succeeds before any change, so the bug may not be reproduced yet. It printed:\n", r.Command)
+	}
+	fmt.Fprintf(&b, "```\n%s\n```\n", strings.TrimRight(r.Output, "\n"))
+	b.WriteString("Fix the bug so that this command succeeds; it is run again after your changes to verify the fix.")
+	return b.String()
+}
+
+// tail returns the last max bytes of s
+func tail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[len(s)-max:]
+}
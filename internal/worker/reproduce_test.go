@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestReproduceBugBeforeAndAfterFix(t *testing.T) {
+	w := New(Config{ID: 1, RepoPath: t.TempDir(), WorkDir: t.TempDir()}, queue.New())
+	w.bindContext(context.Background())
+	w.worktreePath = t.TempDir()
+
+	bug := &ticket.Ticket{ID: "bug-1", Type: ticket.TypeBug, Reproduce: "echo broken; test -f fixed.txt"}
+	before, err := w.reproduceBefore(bug)
+	if err != nil {
+		t.Fatalf("reproduceBefore failed: %v", err)
+	}
+	if before == nil || !before.Failed() || before.ExitCode != 1 || !strings.Contains(before.Output, "broken") {
+		t.Fatalf("Expected the bug to reproduce with its output, got %+v", before)
+	}
+	section := reproductionSection(before)
+	if !strings.Contains(section, "exits with 1") || !strings.Contains(section, "broken") {
+		t.Errorf("Expected the failure in the prompt, got %q", section)
+	}
+
+	if err := w.verifyFix(bug); !errors.Is(err, ErrNotFixed) {
+		t.Fatalf("Expected ErrNotFixed before the fix, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(w.worktreePath, "fixed.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write fix: %v", err)
+	}
+	if err := w.verifyFix(bug); err != nil {
+		t.Errorf("Expected the fix to verify, got %v", err)
+	}
+
+	feature := &ticket.Ticket{ID: "feat-1"}
+	if before, err := w.reproduceBefore(feature); before != nil || err != nil {
+		t.Errorf("Expected nothing run for a feature, got %+v (err %v)", before, err)
+	}
+	if reproductionSection(nil) != "" {
+		t.Error("Expected no prompt section without a reproduction")
+	}
+}
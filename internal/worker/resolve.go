@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// createResolvePrompt asks the agent to merge the branch a conflict
+// resolution ticket names into its worktree, which starts from main, and to
+// resolve the conflicts; the worker commits the merge as it commits any work
+func (w *Worker) createResolvePrompt(t *ticket.Ticket) string {
+	r := t.Resolves
+	var b strings.Builder
+	fmt.Fprintf(&b, `You are an AI coding agent resolving merge conflicts for ticket %s: %s
+
+Branch %s holds the work of ticket %s, but it no longer merges cleanly into main. The current directory is a checkout of main.
+
+`, t.ID, t.Title, r.Branch, r.Ticket)
+
+	if len(r.Files) > 0 {
+		b.WriteString("Git could not merge these files:\n")
+		for _, file := range r.Files {
+			fmt.Fprintf(&b, "- %s\n", file)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, `Conflict report:
+%s
+
+1. Run "git merge --no-commit %s" to bring the branch in
+2. Resolve every conflict, keeping what both main and the branch set out to do; do not simply take one side
+3. Remove all conflict markers and make sure the code builds and its tests pass
+4. Stage your resolution with "git add", but do not commit; the merge is committed for you
+
+Do not explain what you're doing, just resolve the conflicts.
+
+When you are finished, end your reply with a single line starting with "SUMMARY:" followed by one or two sentences describing how you resolved the conflicts.`, t.Description, r.Branch)
+
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+func TestRenderPromptForConflictResolution(t *testing.T) {
+	w := &Worker{ID: 1, promptTemplate: template.Must(template.New("prompt").Parse("Custom {{ .ID }}"))}
+
+	tk := &ticket.Ticket{ID: "feat-a", Title: "Feature A", Description: "Implement A", Priority: 3}
+	if prompt, err := w.renderPrompt(tk); err != nil || prompt != "Custom feat-a" {
+		t.Fatalf("Expected the configured template, got %q (err %v)", prompt, err)
+	}
+
+	tk = &ticket.Ticket{
+		ID:          "resolve-feat-a",
+		Title:       "Resolve merge conflicts: Feature A",
+		Description: "CONFLICT (content): Merge conflict in shared.txt",
+		Priority:    3,
+		Resolves:    &ticket.Resolution{Ticket: "feat-a", Branch: "agent-1/feat-a", Files: []string{"shared.txt"}},
+	}
+	prompt, err := w.renderPrompt(tk)
+	if err != nil {
+		t.Fatalf("renderPrompt failed: %v", err)
+	}
+	for _, want := range []string{"git merge --no-commit agent-1/feat-a", "- shared.txt", "CONFLICT (content)", "ticket feat-a"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected the resolution prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+}
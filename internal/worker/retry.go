@@ -0,0 +1,157 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// How a retried ticket treats the branch an earlier attempt left behind
+const (
+	RetryReset   = "reset"   // Point the branch back at main, dropping the old commits
+	RetryAttempt = "attempt" // Start an attempt-suffixed branch, keeping the old one for comparison
+)
+
+// ValidRetryBranch reports whether mode is a known retry branch mode
+func ValidRetryBranch(mode string) bool {
+	return mode == RetryReset || mode == RetryAttempt
+}
+
+// ErrRetrying marks a failed ticket that was requeued to run again
+var ErrRetrying = errors.New("requeued for retry")
+
+// DefaultRetryCodes are the failures retried when retry.on is empty
+var DefaultRetryCodes = []string{
+	string(ipc.ErrorCodeAgentFailed),
+	string(ipc.ErrorCodeCIFailed),
+	string(ipc.ErrorCodePushFailed),
+	string(ipc.ErrorCodePushVetoed),
+	string(ipc.ErrorCodeTimeout),
+}
+
+// RetryConfig requeues failed tickets with exponential backoff; the
+// ticket_failed event is published once the attempts run out
+type RetryConfig struct {
+	MaxAttempts       int      `mapstructure:"max_attempts"`        // Runs per ticket, the first included; 1 never retries
+	BackoffSeconds    int      `mapstructure:"backoff_seconds"`     // Before the first retry, doubling for each one after
+	MaxBackoffSeconds int      `mapstructure:"max_backoff_seconds"` // Caps the doubling; 0 leaves it uncapped
+	On                []string `mapstructure:"on"`                  // Failure codes retried; empty uses DefaultRetryCodes
+}
+
+// Validate checks the retry settings
+func (c RetryConfig) Validate() error {
+	if c.MaxAttempts < 0 || c.BackoffSeconds < 0 || c.MaxBackoffSeconds < 0 {
+		return errors.New("max_attempts, backoff_seconds and max_backoff_seconds cannot be negative")
+	}
+	for _, code := range c.On {
+		if !retryable(ipc.ErrorCode(code)) {
+			return fmt.Errorf("failure code %q in on is unknown or cannot be retried", code)
+		}
+	}
+	return nil
+}
+
+// retries reports whether a failure with code is retried
+func (c RetryConfig) retries(code ipc.ErrorCode) bool {
+	codes := c.On
+	if len(codes) == 0 {
+		codes = DefaultRetryCodes
+	}
+	for _, retried := range codes {
+		if ipc.ErrorCode(retried) == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before a ticket's next run after failed runs
+func (c RetryConfig) backoff(failed int) time.Duration {
+	delay := time.Duration(c.BackoffSeconds) * time.Second
+	limit := time.Duration(c.MaxBackoffSeconds) * time.Second
+	for i := 1; i < failed && (limit == 0 || delay < limit); i++ {
+		delay *= 2
+	}
+	if limit > 0 && delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// requeueForRetry puts a failed ticket back on the queue, held back until
+// its backoff has passed, if the policy has attempts left for the failure
+func (w *Worker) requeueForRetry(t *ticket.Ticket, code ipc.ErrorCode) (time.Duration, bool) {
+	// A ticket that failed before a run started (Attempt 0) would never use one up
+	if t.Attempt == 0 || t.Attempt >= w.retry.MaxAttempts || !w.retry.retries(code) {
+		return 0, false
+	}
+	delay := w.retry.backoff(t.Attempt)
+	t.RetryAfter = time.Now().Add(delay)
+	t.FailureCode = ""
+	w.queue.Push(t)
+	log.Printf("Worker %d requeued %s after %s (attempt %d of %d), retrying in %v", w.ID, t.ID, code, t.Attempt, w.retry.MaxAttempts, delay)
+	return delay, true
+}
+
+// prepareBranch picks the branch for this attempt at a ticket and records
+// the attempt on it. A branch left by an earlier attempt is reset or
+// replaced according to the retry mode; a preempted ticket always resumes
+// on its checkpoint branch
+func (w *Worker) prepareBranch(t *ticket.Ticket, worktreePath string) (string, error) {
+	branch := w.branchName(t)
+	if t.Checkpoint != "" {
+		t.Branch = branch
+		return branch, nil
+	}
+	t.Attempt++
+
+	// A crash can leave the worktree behind with the branch checked out
+	if _, err := os.Stat(worktreePath); err == nil {
+		log.Printf("Worker %d removing stale worktree %s", w.ID, worktreePath)
+		if err := w.repo.RemoveWorktree(worktreePath); err != nil {
+			os.RemoveAll(worktreePath)
+			if err := w.repo.PruneWorktrees(w.ctx); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	exists, err := w.repo.BranchExists(branch)
+	if err != nil || !exists {
+		t.Branch = branch
+		return branch, err
+	}
+	if t.Attempt < 2 {
+		// The earlier attempt ran before the ticket was reloaded
+		t.Attempt = 2
+	}
+
+	switch w.retryBranch {
+	case RetryAttempt:
+		for {
+			candidate := gitutils.AttemptBranch(branch, t.Attempt)
+			if exists, err := w.repo.BranchExists(candidate); err != nil {
+				return "", err
+			} else if !exists {
+				branch = candidate
+				break
+			}
+			t.Attempt++
+		}
+	default:
+		if err := w.repo.ResetBranch(branch); err != nil {
+			return "", fmt.Errorf("failed to reset %s for retry: %w", branch, err)
+		}
+	}
+
+	t.RetryBranch = w.retryBranch
+	t.Branch = branch
+	log.Printf("Worker %d retrying %s (attempt %d, %s) on %s", w.ID, t.ID, t.Attempt, w.retryBranch, branch)
+	return branch, nil
+}
@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ipc"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestRetryBranchModes(t *testing.T) {
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+
+	tests := []struct {
+		mode   string
+		branch string
+	}{
+		{RetryReset, "agent-1/feat-retry"},
+		{RetryAttempt, "agent-1/feat-retry-attempt-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			repoPath := filepath.Join(tmpDir, "test.git")
+			if err := gitutils.InitBareRepo(repoPath); err != nil {
+				t.Fatalf("Failed to init bare repo: %v", err)
+			}
+			repo := gitutils.NewRepo(repoPath)
+			if err := repo.CreateInitialCommit(); err != nil {
+				t.Fatalf("Failed to create initial commit: %v", err)
+			}
+
+			mainCommit, err := repo.ResolveRef("HEAD")
+			if err != nil {
+				t.Fatalf("Failed to resolve main: %v", err)
+			}
+
+			w := New(Config{
+				ID:          1,
+				RepoPath:    repoPath,
+				WorkDir:     filepath.Join(tmpDir, "work"),
+				CIStatusDir: filepath.Join(tmpDir, "ci-status"),
+				SkipCI:      true,
+				SkipAmp:     true,
+				RetryBranch: tt.mode,
+			}, queue.New())
+
+			tk := &ticket.Ticket{ID: "feat-retry", Title: "Retry me", Priority: 1, CreatedAt: time.Now()}
+			if err := w.processTicket(tk); err != nil {
+				t.Fatalf("First attempt failed: %v", err)
+			}
+			w.cleanup()
+			if tk.Attempt != 1 || tk.Branch != "agent-1/feat-retry" || tk.RetryBranch != "" {
+				t.Fatalf("Unexpected first attempt: attempt %d, branch %q, mode %q", tk.Attempt, tk.Branch, tk.RetryBranch)
+			}
+			firstCommit, _ := repo.GetBranchCommit("agent-1/feat-retry")
+
+			if err := w.processTicket(tk); err != nil {
+				t.Fatalf("Retry failed: %v", err)
+			}
+			w.cleanup()
+			if tk.Attempt != 2 || tk.Branch != tt.branch || tk.RetryBranch != tt.mode {
+				t.Errorf("Unexpected retry: attempt %d, branch %q, mode %q", tk.Attempt, tk.Branch, tk.RetryBranch)
+			}
+
+			// The retry starts from main rather than on top of the first attempt
+			if parent, _ := repo.ResolveRef(tt.branch + "~1"); parent != mainCommit {
+				t.Errorf("Expected the retry to start from main %s, got %s", mainCommit, parent)
+			}
+			if kept, _ := repo.GetBranchCommit("agent-1/feat-retry"); tt.mode == RetryAttempt && kept != firstCommit {
+				t.Errorf("Expected the first attempt's branch to be kept at %s, got %s", firstCommit, kept)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	c := RetryConfig{BackoffSeconds: 30, MaxBackoffSeconds: 100}
+	for failed, want := range map[int]time.Duration{1: 30 * time.Second, 2: 60 * time.Second, 3: 100 * time.Second, 10: 100 * time.Second} {
+		if got := c.backoff(failed); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", failed, got, want)
+		}
+	}
+	if got := (RetryConfig{BackoffSeconds: 1}).backoff(4); got != 8*time.Second {
+		t.Errorf("Expected uncapped backoff to keep doubling, got %v", got)
+	}
+}
+
+func TestRequeueForRetry(t *testing.T) {
+	q := queue.New()
+	w := New(Config{ID: 1, Retry: RetryConfig{MaxAttempts: 2, BackoffSeconds: 60}}, q)
+
+	tk := &ticket.Ticket{ID: "flaky", Attempt: 1, FailureCode: "ci_failed"}
+	delay, ok := w.requeueForRetry(tk, ipc.ErrorCodeCIFailed)
+	if !ok || delay != time.Minute {
+		t.Fatalf("Expected the first failure to be retried after a minute, got %v, %v", delay, ok)
+	}
+	if q.Len() != 1 || tk.FailureCode != "" || time.Until(tk.RetryAfter) < 59*time.Second {
+		t.Errorf("Expected the ticket requeued for a minute's time, got queue length %d, code %q, retry after %v", q.Len(), tk.FailureCode, tk.RetryAfter)
+	}
+
+	tk.Attempt = 2
+	if _, ok := w.requeueForRetry(tk, ipc.ErrorCodeCIFailed); ok {
+		t.Error("Expected no retry once the attempts run out")
+	}
+	if _, ok := w.requeueForRetry(&ticket.Ticket{ID: "guarded", Attempt: 1}, ipc.ErrorCodeGuardedFiles); ok {
+		t.Error("Expected failures outside the default codes not to be retried")
+	}
+	if _, ok := w.requeueForRetry(&ticket.Ticket{ID: "never-ran"}, ipc.ErrorCodeAgentFailed); ok {
+		t.Error("Expected a ticket that never started a run not to be retried")
+	}
+	if q.Len() != 1 {
+		t.Errorf("Expected only the first retry on the queue, got %d tickets", q.Len())
+	}
+}
+
+func TestRetryConfigValidate(t *testing.T) {
+	if err := (RetryConfig{MaxAttempts: 3, On: []string{"ci_failed", "precheck_failed"}}).Validate(); err != nil {
+		t.Errorf("Expected a valid policy, got %v", err)
+	}
+	if err := (RetryConfig{On: []string{"auth"}}).Validate(); err == nil || !strings.Contains(err.Error(), "auth") {
+		t.Errorf("Expected auth failures to be rejected, got %v", err)
+	}
+	if err := (RetryConfig{BackoffSeconds: -1}).Validate(); err == nil {
+		t.Error("Expected a negative backoff to be rejected")
+	}
+}
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+)
+
+func TestWorkerUsesRunner(t *testing.T) {
+	runner := command.NewRecorder()
+	runner.Stub("amp threads new", "T-abc123\n", nil)
+	w := New(Config{ID: 1, RepoPath: "/repos/app.git", WorkDir: t.TempDir(), Runner: runner}, queue.New())
+	w.worktreePath = "/work/agent-1"
+
+	threadID, err := w.newAmpThread()
+	if err != nil || threadID != "T-abc123" {
+		t.Fatalf("Expected the stubbed thread, got %q (err %v)", threadID, err)
+	}
+
+	// The worker's repository runs git through the same runner
+	if exists, err := w.repo.BranchExists("main"); err != nil || !exists {
+		t.Fatalf("Expected the recorder to report main, got %v (err %v)", exists, err)
+	}
+
+	calls := runner.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 calls, got %v", calls)
+	}
+	if calls[0].String() != "amp threads new" || calls[0].Dir != "/work/agent-1" {
+		t.Errorf("Unexpected thread call %+v", calls[0])
+	}
+	if !strings.HasPrefix(calls[1].String(), "git --git-dir /repos/app.git show-ref") {
+		t.Errorf("Unexpected git call %q", calls[1])
+	}
+}
+
+func TestCleanupKillsLeftoverProcesses(t *testing.T) {
+	w := New(Config{ID: 1, RepoPath: t.TempDir(), WorkDir: t.TempDir()}, queue.New())
+	w.currentTask = &ticket.Ticket{ID: "feat-server"}
+
+	// An agent that starts a server in the background and exits
+	if err := w.runner.Run(exec.Command("sh", "-c", "(sleep 30) >/dev/null 2>&1 &")); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if orphaned := w.procs.Orphaned(); len(orphaned) != 1 {
+		t.Fatalf("Expected the background server to be tracked, got %v", orphaned)
+	}
+
+	w.cleanup()
+	if orphaned := w.procs.Orphaned(); len(orphaned) != 0 {
+		t.Errorf("Expected cleanup to kill the leftover server, got %v", orphaned)
+	}
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/scratch"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestWorkerScratchDirIsNotCommitted(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	workDir := filepath.Join(tmpDir, "work")
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      workDir,
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", `echo artifact > "$ORCHESTRATOR_SCRATCH_DIR/build.bin" && echo package main > main.go`},
+	}
+	w := New(config, queue.New())
+
+	testTicket := &ticket.Ticket{
+		ID:        "feat-scratch",
+		Title:     "Writes an artifact",
+		Priority:  1,
+		CreatedAt: time.Now(),
+	}
+
+	if err := w.processTicket(testTicket); err != nil {
+		t.Fatalf("processTicket failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(scratch.Dir(workDir, testTicket.ID), "build.bin")); err != nil {
+		t.Errorf("Expected artifact in scratch directory: %v", err)
+	}
+
+	output, err := exec.Command("git", "--git-dir", repoPath, "ls-tree", "-r", "--name-only", "agent-1/feat-scratch").Output()
+	if err != nil {
+		t.Fatalf("Failed to list committed files: %v", err)
+	}
+	files := strings.Fields(string(output))
+	for _, f := range files {
+		if f == "build.bin" {
+			t.Errorf("Scratch artifact was committed: %v", files)
+		}
+	}
+	if !strings.Contains(string(output), "main.go") {
+		t.Errorf("Expected main.go to be committed, got %v", files)
+	}
+}
@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ThreadRegistry maps ticket context groups to amp thread IDs so related
+// tickets continue the same agent conversation. It is shared by all workers.
+type ThreadRegistry struct {
+	path    string
+	threads map[string]string
+	mu      sync.Mutex
+}
+
+// NewThreadRegistry creates a registry persisted to path
+// An empty path keeps the registry in memory only
+func NewThreadRegistry(path string) (*ThreadRegistry, error) {
+	r := &ThreadRegistry{
+		path:    path,
+		threads: make(map[string]string),
+	}
+
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("failed to read thread registry: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &r.threads); err != nil {
+		return nil, fmt.Errorf("failed to parse thread registry: %w", err)
+	}
+
+	return r, nil
+}
+
+// Get returns the thread ID for a context group
+func (r *ThreadRegistry) Get(group string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.threads[group]
+	return id, ok
+}
+
+// GetOrCreate returns the thread for a group, calling create if none exists yet
+// The lock is held while creating so concurrent workers share one thread
+func (r *ThreadRegistry) GetOrCreate(group string, create func() (string, error)) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.threads[group]; ok {
+		return id, nil
+	}
+
+	id, err := create()
+	if err != nil {
+		return "", err
+	}
+
+	r.threads[group] = id
+	if err := r.save(); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Forget removes a group's thread, e.g. when amp no longer recognises it
+func (r *ThreadRegistry) Forget(group string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.threads, group)
+	return r.save()
+}
+
+// save persists the registry; callers must hold the lock
+func (r *ThreadRegistry) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r.threads, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal thread registry: %w", err)
+	}
+
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write thread registry: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("failed to write thread registry: %w", err)
+	}
+
+	return nil
+}
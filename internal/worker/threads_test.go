@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestThreadRegistryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "threads.json")
+
+	registry, err := NewThreadRegistry(path)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	calls := 0
+	create := func() (string, error) {
+		calls++
+		return "T-123", nil
+	}
+
+	for i := 0; i < 2; i++ {
+		id, err := registry.GetOrCreate("avatar", create)
+		if err != nil {
+			t.Fatalf("GetOrCreate failed: %v", err)
+		}
+		if id != "T-123" {
+			t.Errorf("Expected thread T-123, got %s", id)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected thread to be created once, got %d", calls)
+	}
+
+	// A new registry loads the saved mapping
+	reloaded, err := NewThreadRegistry(path)
+	if err != nil {
+		t.Fatalf("Failed to reload registry: %v", err)
+	}
+	if id, ok := reloaded.Get("avatar"); !ok || id != "T-123" {
+		t.Errorf("Expected reloaded thread T-123, got %q (found=%v)", id, ok)
+	}
+
+	if err := reloaded.Forget("avatar"); err != nil {
+		t.Fatalf("Forget failed: %v", err)
+	}
+	if _, ok := reloaded.Get("avatar"); ok {
+		t.Error("Expected group to be forgotten")
+	}
+}
+
+func TestThreadRegistryCreateError(t *testing.T) {
+	registry, err := NewThreadRegistry("")
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	_, err = registry.GetOrCreate("avatar", func() (string, error) {
+		return "", errors.New("amp unavailable")
+	})
+	if err == nil {
+		t.Fatal("Expected error from failed thread creation, got nil")
+	}
+	if _, ok := registry.Get("avatar"); ok {
+		t.Error("Failed creation should not register a thread")
+	}
+}
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"log"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticketlog"
+)
+
+// openTicketLog starts the ticket's log file and logs the worker's commands
+// to it until closeTicketLog
+func (w *Worker) openTicketLog(t *ticket.Ticket) {
+	if w.ticketLogDir == "" {
+		return
+	}
+	l, err := ticketlog.Open(w.ticketLogDir, t.ID, w.ID, w.cipher)
+	if err != nil {
+		log.Printf("Worker %d failed to open log for %s: %v", w.ID, t.ID, err)
+		return
+	}
+	w.ticketLog = l
+	w.logRunner.SetLog(l)
+	l.Printf("worker", "Worker %d processing ticket %s: %s (attempt %d)", w.ID, t.ID, t.Title, t.Attempt+1)
+}
+
+// closeTicketLog ends the ticket's log with how the run ended
+func (w *Worker) closeTicketLog(err error) {
+	if w.ticketLog == nil {
+		return
+	}
+	if err != nil {
+		w.ticketLog.Printf("worker", "Ticket ended: %v", err)
+	} else {
+		w.ticketLog.Printf("worker", "Ticket completed")
+	}
+	w.logRunner.SetLog(nil)
+	w.ticketLog.Close()
+	w.ticketLog = nil
+}
+
+// logAgentOutput keeps output of an agent that did not run through the
+// worker's runner, such as a job's, in the ticket's log
+func (w *Worker) logAgentOutput(output []byte) {
+	w.ticketLog.Output("agent", output)
+}
+
+// logCIResult keeps a CI run's outcome and output in the ticket's log
+func (w *Worker) logCIResult(status *ci.Status) {
+	w.ticketLog.Printf("ci", "CI %s for %.8s", status.Status, status.Commit)
+	w.ticketLog.Output("ci", []byte(status.Output))
+}
@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticketlog"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func TestWorkerWritesTicketLog(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	logDir := filepath.Join(tmpDir, "logs")
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		CIStatusDir:  filepath.Join(tmpDir, "ci-status"),
+		SkipCI:       true,
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", "echo agent says hello && echo package main > main.go"},
+		TicketLogDir: logDir,
+	}
+	w := New(config, queue.New())
+
+	testTicket := &ticket.Ticket{ID: "feat-log", Title: "Logs output", Priority: 1, CreatedAt: time.Now()}
+	if err := w.processTicket(testTicket); err != nil {
+		t.Fatalf("processTicket failed: %v", err)
+	}
+
+	files, err := ticketlog.Files(logDir, "feat-log")
+	if err != nil || len(files) != 1 || !strings.HasSuffix(files[0], "-agent-1.log") {
+		t.Fatalf("Expected one log file for the run, got %v (err %v)", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read ticket log: %v", err)
+	}
+	text := string(data)
+	for _, want := range []string{
+		"[worker] Worker 1 processing ticket feat-log: Logs output (attempt 1)",
+		"[sh] agent says hello",
+		"[git] $ git push",
+		"[worker] Ticket completed",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Ticket log should contain %q:\n%s", want, text)
+		}
+	}
+}
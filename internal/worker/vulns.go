@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+)
+
+// ErrVulnerable indicates CI found critical dependency vulnerabilities on
+// the ticket's branch that main doesn't have
+var ErrVulnerable = errors.New("new critical vulnerabilities")
+
+// checkVulnerabilities fails the ticket when its CI status records critical
+// vulnerabilities missing from main's latest status, if configured to
+func (w *Worker) checkVulnerabilities(t *ticket.Ticket, commitHash string) error {
+	if !w.vulnScan.Enabled || !w.vulnScan.FailOnNew {
+		return nil
+	}
+	status, err := w.ciStatusReader.GetStatus(commitHash)
+	if err != nil {
+		return err
+	}
+
+	// Main's findings were there before the agent; without a status for main
+	// every critical finding counts as new
+	var baseline *ci.Status
+	if branch, err := w.repo.MainBranch(); err == nil {
+		baseline, _ = w.ciStatusReader.GetLatestForBranch(branch)
+	}
+	found := ci.NewCritical(status, baseline)
+	if len(found) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(found))
+	for i, v := range found {
+		descriptions[i] = v.String()
+	}
+	message := "the changes introduce " + strings.Join(descriptions, ", ")
+	log.Printf("Worker %d vulnerability scan for %s: %s", w.ID, t.ID, message)
+	return fmt.Errorf("%w: %s", ErrVulnerable, message)
+}
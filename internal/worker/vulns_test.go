@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// vulnerableBackend passes CI but records scanner findings
+type vulnerableBackend struct {
+	statusDir string
+	vulns     []ci.Vulnerability
+}
+
+func (b *vulnerableBackend) Run(ctx context.Context, run ci.Run) error {
+	return ci.WriteStatus(b.statusDir, &ci.Status{Ref: "refs/heads/" + run.Branch, Commit: run.Commit, Status: "PASS", Vulnerabilities: b.vulns})
+}
+
+func TestWorkerFailsOnNewCriticalVulnerabilities(t *testing.T) {
+	known := ci.Vulnerability{Tool: "govulncheck", ID: "GO-2024-2687", Package: "golang.org/x/net", Severity: "critical"}
+	introduced := ci.Vulnerability{Tool: "npm", ID: "GHSA-p6mc-m468-83gw", Package: "lodash", Severity: "critical"}
+	tests := []struct {
+		name     string
+		vulns    []ci.Vulnerability
+		scan     ci.VulnScanConfig
+		wantFail bool
+	}{
+		{"known on main", []ci.Vulnerability{known}, ci.VulnScanConfig{Enabled: true, FailOnNew: true}, false},
+		{"new critical", []ci.Vulnerability{known, introduced}, ci.VulnScanConfig{Enabled: true, FailOnNew: true}, true},
+		{"recorded only", []ci.Vulnerability{introduced}, ci.VulnScanConfig{Enabled: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			repoPath := filepath.Join(tmpDir, "test.git")
+			if err := gitutils.InitBareRepo(repoPath); err != nil {
+				t.Fatalf("Failed to init bare repo: %v", err)
+			}
+			repo := gitutils.NewRepo(repoPath)
+			if err := repo.CreateInitialCommit(); err != nil {
+				t.Fatalf("Failed to create initial commit: %v", err)
+			}
+
+			// Main's latest scan already carries the known finding
+			statusDir := filepath.Join(tmpDir, "ci-status")
+			mainBranch, err := repo.MainBranch()
+			if err != nil {
+				t.Fatalf("Failed to find main branch: %v", err)
+			}
+			mainCommit, err := repo.GetBranchCommit(mainBranch)
+			if err != nil {
+				t.Fatalf("Failed to resolve %s: %v", mainBranch, err)
+			}
+			mainStatus := &ci.Status{Ref: "refs/heads/" + mainBranch, Commit: mainCommit, Status: "PASS", Vulnerabilities: []ci.Vulnerability{known}}
+			if err := ci.WriteStatus(statusDir, mainStatus); err != nil {
+				t.Fatalf("Failed to write main's status: %v", err)
+			}
+
+			config := Config{
+				ID:           1,
+				RepoPath:     repoPath,
+				WorkDir:      filepath.Join(tmpDir, "work"),
+				CIStatusDir:  statusDir,
+				CIBackend:    &vulnerableBackend{statusDir: statusDir, vulns: tt.vulns},
+				VulnScan:     tt.scan,
+				AgentCommand: "sh",
+				AgentArgs:    []string{"-c", "echo code > main.go"},
+			}
+			w := New(config, queue.New())
+
+			tk := &ticket.Ticket{ID: "feat-1", Title: "Add dependency", CreatedAt: time.Now()}
+			err = w.processTicket(tk)
+			if !tt.wantFail {
+				if err != nil {
+					t.Fatalf("processTicket failed: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrVulnerable) {
+				t.Fatalf("Expected ErrVulnerable, got %v", err)
+			}
+			if code := FailureCode(err); code != "vulnerable" {
+				t.Errorf("Expected code vulnerable, got %s", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+// warmupTimeout bounds each prerequisite check
+const warmupTimeout = 30 * time.Second
+
+// warmupRetry is how long a worker that failed its checks waits before
+// trying again
+var warmupRetry = time.Minute
+
+// Warmup verifies the worker can do its job before it takes a ticket: the
+// repository and those of its environments are reachable, a scratch
+// worktree can be created and removed, the agent CLI runs and the CI script
+// parses
+func (w *Worker) Warmup(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+	defer cancel()
+
+	repo := w.defaultRepo.WithContext(ctx)
+	if _, err := repo.ListBranches(); err != nil {
+		return fmt.Errorf("repository %s is not reachable: %w", repo.Path, err)
+	}
+	for name, settings := range w.environments {
+		if settings.Repository == "" {
+			continue
+		}
+		if _, err := (&gitutils.GitRepo{Path: settings.Repository, Runner: w.runner}).WithContext(ctx).ListBranches(); err != nil {
+			return fmt.Errorf("repository %s for environment %s is not reachable: %w", settings.Repository, name, err)
+		}
+	}
+
+	scratchPath := filepath.Join(w.workDir, fmt.Sprintf("agent-%d", w.ID), "warmup")
+	os.RemoveAll(scratchPath)
+	if err := repo.AddDetachedWorktree(scratchPath); err != nil {
+		return fmt.Errorf("cannot create a worktree: %w", err)
+	}
+	if err := repo.RemoveWorktree(scratchPath); err != nil {
+		return fmt.Errorf("cannot remove a worktree: %w", err)
+	}
+
+	// Agents run in pods when jobs are configured
+	if !w.skipAmp && w.jobs == nil {
+		cmd := command.Context(ctx, w.agentCommand, "--version")
+		cmd.Env = append(os.Environ(), w.env...)
+		if output, err := w.runner.CombinedOutput(cmd); err != nil {
+			return fmt.Errorf("%s --version failed: %w\n%s", w.agentCommand, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if !w.skipCI {
+		if p, ok := w.ciBackend.(ci.Preflighter); ok {
+			if err := p.Preflight(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// warmUp runs Warmup until it passes, reporting the worker as not ready in
+// the meantime; a restart retries at once. It returns false if ctx is
+// cancelled first
+func (w *Worker) warmUp(ctx context.Context) bool {
+	for {
+		err := w.Warmup(ctx)
+		w.setWarmupError(err)
+		if err == nil {
+			return true
+		}
+
+		if w.eventPublisher != nil {
+			w.eventPublisher("not_ready", w.ID, nil, fmt.Sprintf("Prerequisite check failed: %v", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(warmupRetry):
+		case <-w.restart:
+		}
+	}
+}
+
+// setWarmupError records why the worker isn't ready; nil marks it ready
+func (w *Worker) setWarmupError(err error) {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+	w.ready = err == nil
+	w.warmupErr = err
+}
+
+// Ready reports whether the worker passed its prerequisite checks, and why
+// not if it failed them
+func (w *Worker) Ready() (bool, error) {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+	return w.ready, w.warmupErr
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
+)
+
+func newWarmupTestRepo(t *testing.T) (string, string) {
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	return tmpDir, repoPath
+}
+
+func TestWarmup(t *testing.T) {
+	tmpDir, repoPath := newWarmupTestRepo(t)
+
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		SkipCI:       true,
+		AgentCommand: "true",
+	}
+	if err := New(config, queue.New()).Warmup(context.Background()); err != nil {
+		t.Fatalf("Expected prerequisites to pass, got %v", err)
+	}
+	// The scratch worktree leaves no branch behind
+	if branches, _ := gitutils.NewRepo(repoPath).ListBranches(); len(branches) != 1 {
+		t.Errorf("Expected only the main branch, got %v", branches)
+	}
+
+	config.AgentCommand = "false"
+	if err := New(config, queue.New()).Warmup(context.Background()); err == nil || !strings.Contains(err.Error(), "false --version failed") {
+		t.Errorf("Expected the agent check to fail, got %v", err)
+	}
+
+	config.AgentCommand = "true"
+	config.RepoPath = filepath.Join(tmpDir, "missing.git")
+	if err := New(config, queue.New()).Warmup(context.Background()); err == nil || !strings.Contains(err.Error(), "not reachable") {
+		t.Errorf("Expected the repository check to fail, got %v", err)
+	}
+}
+
+func TestWorkerNotReadyTakesNoTickets(t *testing.T) {
+	tmpDir, repoPath := newWarmupTestRepo(t)
+	defer func(d time.Duration) { warmupRetry = d }(warmupRetry)
+	warmupRetry = 50 * time.Millisecond
+
+	q := queue.New()
+	q.Push(&ticket.Ticket{ID: "feat-1", Title: "Waits", Priority: 1, CreatedAt: time.Now()})
+
+	config := Config{
+		ID:           1,
+		RepoPath:     repoPath,
+		WorkDir:      filepath.Join(tmpDir, "work"),
+		SkipCI:       true,
+		AgentCommand: "false",
+	}
+	w := New(config, q)
+
+	var mu sync.Mutex
+	var published []string
+	w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, eventType)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Start(ctx) }()
+
+	time.Sleep(300 * time.Millisecond)
+	status := w.GetStatus()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if status.Ready || !strings.Contains(status.NotReady, "--version failed") {
+		t.Errorf("Expected the worker to report why it isn't ready, got %+v", status)
+	}
+	if q.Len() != 1 {
+		t.Error("Expected the ticket to stay queued while the worker isn't ready")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(published) < 2 || published[0] != "not_ready" {
+		t.Errorf("Expected repeated not_ready events, got %v", published)
+	}
+	for _, e := range published {
+		if e != "not_ready" {
+			t.Errorf("Expected only not_ready events, got %v", published)
+			break
+		}
+	}
+}
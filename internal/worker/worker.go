@@ -2,33 +2,147 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
+	"github.com/brettsmith212/amp-orchestrator/internal"
+	"github.com/brettsmith212/amp-orchestrator/internal/artifacts"
+	"github.com/brettsmith212/amp-orchestrator/internal/chaos"
 	"github.com/brettsmith212/amp-orchestrator/internal/ci"
+	"github.com/brettsmith212/amp-orchestrator/internal/claim"
+	"github.com/brettsmith212/amp-orchestrator/internal/encryption"
+	"github.com/brettsmith212/amp-orchestrator/internal/environment"
+	"github.com/brettsmith212/amp-orchestrator/internal/limits"
+	"github.com/brettsmith212/amp-orchestrator/internal/locks"
 	"github.com/brettsmith212/amp-orchestrator/internal/queue"
+	"github.com/brettsmith212/amp-orchestrator/internal/quota"
+	"github.com/brettsmith212/amp-orchestrator/internal/ratelimit"
+	"github.com/brettsmith212/amp-orchestrator/internal/scratch"
+	"github.com/brettsmith212/amp-orchestrator/internal/storage"
 	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
+	"github.com/brettsmith212/amp-orchestrator/internal/ticketlog"
+	"github.com/brettsmith212/amp-orchestrator/internal/watch"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
 	"github.com/brettsmith212/amp-orchestrator/pkg/gitutils"
 )
 
+// ErrCIFailed indicates the agent produced a commit but CI did not pass
+var ErrCIFailed = errors.New("CI failed")
+
 // Worker represents an Amp coding agent worker
 type Worker struct {
 	ID             int
-	repo           *gitutils.GitRepo
+	repo           *gitutils.GitRepo // Repository of the current ticket's environment
+	defaultRepo    *gitutils.GitRepo // For tickets without an environment, and idle chores
+	runner         command.Runner
+	procs          *command.Group // Process groups of commands the runner started, when it runs them for real
+	environments   environment.Environments
+	chaos          *chaos.Monkey
 	workDir        string
 	queue          *queue.Queue
 	isRunning      bool
 	currentTask    *ticket.Ticket
 	worktreePath   string
 	ciStatusReader *ci.StatusReader
+	ciBackend      ci.Backend
+	ciProfiles     []ci.Profile
+	vulnScan       ci.VulnScanConfig
+	quickTests     bool
+	docsPaths      *watch.IgnoreMatcher
+	regression     RegressionTestConfig
+	commits        CommitConfig
+	fileGuard      FileGuardConfig
+	attribution    AttributionConfig
+	licenseScan    LicenseScanConfig
+	precheckConfig PrecheckConfig
+	prePush        PrePushConfig
+	retry          RetryConfig
+	metricsDir     string
+	ticketLogDir   string
+	ticketLog      *ticketlog.Log     // Current ticket's log; nil when not kept
+	logRunner      *ticketlog.Runner  // Wraps runner while ticket logs are kept
 	skipCI         bool
 	skipAmp        bool
+	threads        *ThreadRegistry
+	branchPrefix   string
+	promptTemplate *template.Template
+	agentCommand   string
+	agentArgs      []string
+	ctx            context.Context
+	globalLimiter  *ratelimit.Limiter
+	workerLimiter  *ratelimit.Limiter
+	limitRetries   int
+	limitBackoff   time.Duration
+	pause          *PauseGate
+	lowDisk        *PauseGate
+	standby        *PauseGate
+	held           *PauseGate // Closed by an operator pausing this worker alone
+	mainRed        *PauseGate
+	slots          *queue.Slots
+	retryBranch    string
+	env            []string
+	scratchDir     string // Current ticket's scratch directory
+	artifactStore  storage.Store
+	objectStore    storage.Store
+	ciStatusStore  ci.StatusStore
+	cipher         *encryption.Cipher
+	claims         *claim.Claimer
+	quotas         *quota.Enforcer
+	locks          *locks.Manager
+	jobs           JobRunner
+	ciStatusDir    string
+	limits         limits.Limits
+	overQuota      bool
 	eventPublisher func(eventType string, workerID int, ticket *ticket.Ticket, message string) // Optional event publisher
+
+	agentVersionOnce sync.Once
+	agentVersionText string // The agent's --version, for provenance files
+
+	// Health and running totals reported in WorkerStatus
+	healthMu      sync.Mutex
+	ready         bool  // Prerequisite checks passed
+	warmupErr     error // Why they failed
+	failing       bool  // Too many tickets failed in a row; no more are taken until restarted
+	failedTickets int
+	failedInARow  int
+	maxFailures   int
+	lastError     string
+	lastErrorAt   time.Time
+	lastErrorOn   string // Ticket that failed last
+	restart       chan struct{}
+	startedAt     time.Time
+	phase         string // Of the current ticket
+	ciStep        string // Of the CI run being waited on
+	ciStarted     time.Time
+	completed     int
+	completedTime time.Duration
+
+	// Background chore run while idle; only touched by the Start loop
+	housekeeper *Housekeeper
+	cancelChore context.CancelFunc
+	choreDone   chan struct{}
+
+	// The running agent, which a Preemptor may stop
+	agentMu      sync.Mutex
+	agentCtx     context.Context
+	cancelAgent  context.CancelFunc
+	agentTicket  *ticket.Ticket
+	agentStarted time.Time
+	preempted    bool
+
+	// The ticket being processed, which an operator may cancel
+	runningTicket string
+	cancelled     chan struct{}
+	cancelReason  string
 }
 
 // Config holds worker configuration
@@ -37,29 +151,191 @@ type Config struct {
 	RepoPath    string
 	WorkDir     string
 	CIStatusDir string
-	SkipCI      bool // For testing - skips CI wait
-	SkipAmp     bool // For testing - skips amp CLI and creates mock files
+	CIBackend   ci.Backend      // Defaults to running ci.sh locally
+	CIProfiles  []ci.Profile    // Optional; selected by ticket tags when CI runs
+	VulnScan    ci.VulnScanConfig // Optional; fails tickets whose CI finds critical vulnerabilities new to main
+	QuickTests  bool            // Run the quick CI tier for tickets; the full tier runs once they complete
+	DocsPaths   []string        // Optional gitignore-style patterns; CI is skipped when only these change
+	MetricsDir  string          // Optional; flaky CI results and artifact history are recorded here
+	TicketLogDir string         // Optional; each ticket's commands and their output are logged under <dir>/<ticket-id>/
+	SkipCI      bool            // For testing - skips CI wait
+	SkipAmp     bool            // For testing - skips amp CLI and creates mock files
+	Threads     *ThreadRegistry // Optional shared registry for context group threads
+	Pause       *PauseGate      // Optional gate shared by the pool; closed on agent auth errors
+	LowDisk     *PauseGate      // Optional gate shared by the pool; closed while disk space is low
+	Standby     *PauseGate      // Optional gate for this worker alone; closed while it is not needed
+	MainRed     *PauseGate      // Optional gate shared by the pool; closed while CI on main is red
+	Slots       *queue.Slots    // Optional slots shared by the pool; some are held back for urgent tickets
+	Limits      limits.Limits   // Resource limits for agent processes and the worker directory
+	Env         []string        // Extra environment variables for agent processes, e.g. Go caches
+	MaxFailures int             // Tickets failing in a row before the worker stops taking more; 0 disables
+	RetryBranch string          // RetryReset (default) or RetryAttempt, for branches left by earlier attempts
+	Regression  RegressionTestConfig // Optional; fixes for bug tickets must add or change a test
+	Commits     CommitConfig    // How the agent's work is split into commits; defaults to one commit
+	FileGuard   FileGuardConfig // Optional; oversized files, binaries and blocked paths the agent may not commit
+	Attribution AttributionConfig // Optional; commits a provenance file with the agent's changes
+	LicenseScan LicenseScanConfig // Optional; fails tickets adding disallowed licenses or copied code
+	Precheck    PrecheckConfig  // Optional; a quick CI run in the worktree before committing
+	PrePush     PrePushConfig   // Optional; a policy command that can veto the changes before they are pushed
+	Retry       RetryConfig     // Optional; failed tickets are requeued until the attempts run out
+	Housekeeper *Housekeeper    // Optional chores shared by the pool, run while no ticket is queued
+	Runner      command.Runner  // Runs git, the agent and builds; defaults to command.Default
+
+	// Optional per-environment repositories and CI profiles, picked by the ticket's environment
+	Environments environment.Environments
+
+	// Optional chaos mode; injects agent failures for testing recovery
+	Chaos *chaos.Monkey
+
+	// Optional store for files matching a ticket's artifacts globs, published after CI passes
+	ArtifactStore storage.Store
+	// Optional object storage for per-ticket agent logs and CI outputs
+	ObjectStore storage.Store
+	// Optional shared CI status store; each ticket's status is copied there
+	// from CIStatusDir so the daemon sees it without sharing the directory
+	CIStatusStore ci.StatusStore
+	// Optional cipher; agent logs and CI outputs are encrypted before upload
+	Cipher *encryption.Cipher
+	// Optional claims shared with other daemons; completed tickets are marked
+	// done and failed ones released
+	Claims *claim.Claimer
+	// Optional per-tag quotas the queue dispatches under; told when each ticket finishes
+	Quotas *quota.Enforcer
+	// Optional ticket locks the queue dispatches under; released when each ticket finishes
+	Locks *locks.Manager
+	// Optional runner that executes the agent elsewhere, e.g. as a Kubernetes Job
+	Jobs JobRunner
+
+	// Optional overrides, mainly used by benchmark experiments
+	BranchPrefix   string             // Defaults to agent-<ID>
+	PromptTemplate *template.Template // Rendered with the ticket; defaults to the built-in prompt
+	AgentCommand   string             // Defaults to amp
+	AgentArgs      []string           // Extra arguments, e.g. model selection
+
+	// Agent rate limiting
+	GlobalLimiter    *ratelimit.Limiter // Shared by all workers; nil means unlimited
+	WorkerMaxPerHour int                // Per-worker invocations per hour; 0 means unlimited
+	RateLimitRetries int                // Retries when the agent reports rate limiting
+	RateLimitBackoff time.Duration      // Base delay for jittered exponential backoff
 }
 
 // New creates a new worker instance
 func New(config Config, q *queue.Queue) *Worker {
+	var procs *command.Group
+	runner := config.Runner
+	if runner == nil {
+		procs = command.NewGroup()
+		runner = procs
+	}
+	// CI output is logged from its status, whichever backend runs it
+	ciRunner := runner
+	var logRunner *ticketlog.Runner
+	if config.TicketLogDir != "" {
+		logRunner = ticketlog.NewRunner(runner)
+		runner = logRunner
+	}
 	repo := gitutils.NewRepo(config.RepoPath)
+	repo.Runner = runner
 	ciStatusReader := ci.NewStatusReader(config.CIStatusDir)
 
+	branchPrefix := config.BranchPrefix
+	if branchPrefix == "" {
+		branchPrefix = fmt.Sprintf("agent-%d", config.ID)
+	}
+
+	retryBranch := config.RetryBranch
+	if retryBranch == "" {
+		retryBranch = RetryReset
+	}
+
+	ciBackend := config.CIBackend
+	if ciBackend == nil {
+		ciBackend = ci.NewLocalBackend(ci.BackendConfig{StatusDir: config.CIStatusDir, TestRetries: ci.DefaultTestRetries, Runner: ciRunner})
+	}
+
+	agentCommand := config.AgentCommand
+	if agentCommand == "" {
+		agentCommand = "amp"
+	}
+
 	return &Worker{
 		ID:             config.ID,
 		repo:           repo,
+		defaultRepo:    repo,
+		runner:         runner,
+		procs:          procs,
+		environments:   config.Environments,
+		chaos:          config.Chaos,
 		workDir:        config.WorkDir,
 		queue:          q,
 		ciStatusReader: ciStatusReader,
+		ciBackend:      ciBackend,
+		metricsDir:     config.MetricsDir,
+		ticketLogDir:   config.TicketLogDir,
+		logRunner:      logRunner,
 		skipCI:         config.SkipCI,
 		skipAmp:        config.SkipAmp,
+		threads:        config.Threads,
+		branchPrefix:   branchPrefix,
+		promptTemplate: config.PromptTemplate,
+		agentCommand:   agentCommand,
+		agentArgs:      config.AgentArgs,
+		ctx:            context.Background(),
+		globalLimiter:  config.GlobalLimiter,
+		workerLimiter:  ratelimit.New(config.WorkerMaxPerHour, 0),
+		limitRetries:   config.RateLimitRetries,
+		limitBackoff:   config.RateLimitBackoff,
+		pause:          config.Pause,
+		env:            config.Env,
+		artifactStore:  config.ArtifactStore,
+		objectStore:    config.ObjectStore,
+		ciStatusStore:  config.CIStatusStore,
+		cipher:         config.Cipher,
+		claims:         config.Claims,
+		quotas:         config.Quotas,
+		locks:          config.Locks,
+		jobs:           config.Jobs,
+		ciStatusDir:    config.CIStatusDir,
+		ciProfiles:     config.CIProfiles,
+		vulnScan:       config.VulnScan,
+		quickTests:     config.QuickTests,
+		docsPaths:      watch.NewIgnoreMatcher(config.DocsPaths),
+		regression:     config.Regression,
+		commits:        config.Commits,
+		fileGuard:      config.FileGuard,
+		attribution:    config.Attribution,
+		licenseScan:    config.LicenseScan,
+		precheckConfig: config.Precheck,
+		prePush:        config.PrePush,
+		retry:          config.Retry,
+		lowDisk:        config.LowDisk,
+		standby:        config.Standby,
+		mainRed:        config.MainRed,
+		slots:          config.Slots,
+		limits:         config.Limits,
+		maxFailures:    config.MaxFailures,
+		retryBranch:    retryBranch,
+		restart:        make(chan struct{}, 1),
+		held:           NewPauseGate(),
+		housekeeper:    config.Housekeeper,
 	}
 }
 
+// bindContext makes ctx the context the worker's git, agent and CI commands
+// are cancelled by
+func (w *Worker) bindContext(ctx context.Context) {
+	w.ctx = ctx
+	w.defaultRepo = w.defaultRepo.WithContext(ctx)
+	w.repo = w.defaultRepo
+}
+
 // Start begins the worker's main loop
 func (w *Worker) Start(ctx context.Context) error {
+	w.bindContext(ctx)
 	w.isRunning = true
+	w.healthMu.Lock()
+	w.startedAt = time.Now()
+	w.healthMu.Unlock()
 	log.Printf("Worker %d starting...", w.ID)
 
 	// Create worker's base directory
@@ -68,9 +344,15 @@ func (w *Worker) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to create worker directory: %w", err)
 	}
 
+	// Verify prerequisites so a broken setup doesn't fail the first ticket
+	if !w.warmUp(ctx) {
+		w.isRunning = false
+		return nil
+	}
+
 	// Send initial status event
 	if w.eventPublisher != nil {
-		w.eventPublisher("started", w.ID, nil, "Worker ready")
+		w.eventPublisher("started", w.ID, nil, "Worker ready: prerequisites verified")
 	}
 
 	// Main worker loop
@@ -81,16 +363,66 @@ func (w *Worker) Start(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			log.Printf("Worker %d stopping...", w.ID)
+			w.stopChore()
 			w.isRunning = false
 			w.cleanup()
 			return nil
 
+		case <-w.restart:
+			log.Printf("Worker %d restarting...", w.ID)
+			if !w.warmUp(ctx) {
+				w.isRunning = false
+				return nil
+			}
+			if w.eventPublisher != nil {
+				w.eventPublisher("started", w.ID, nil, "Worker restarted: prerequisites verified")
+			}
+
 		case <-ticker.C:
+			if w.Failing() {
+				continue
+			}
+			if paused, _ := w.pause.Paused(); paused {
+				continue
+			}
+			if paused, _ := w.lowDisk.Paused(); paused {
+				continue
+			}
+			if paused, _ := w.standby.Paused(); paused {
+				continue
+			}
+			if paused, _ := w.mainRed.Paused(); paused {
+				continue
+			}
+			if paused, _ := w.held.Paused(); paused {
+				// Idle means idle while paused for maintenance
+				w.stopChore()
+				continue
+			}
+
+			if w.currentTask == nil && !w.withinDiskQuota(workerDir) {
+				continue
+			}
+
 			if w.currentTask == nil {
 				// Try to get a new ticket from the queue
-				if ticket := w.queue.Pop(); ticket != nil {
+				ticket := w.queue.PopFor(w.slots)
+				if ticket == nil {
+					w.startChore(ctx)
+				} else {
+					w.stopChore()
 					log.Printf("Worker %d picked up ticket: %s", w.ID, ticket.ID)
-					w.processTicket(ticket)
+					// Failures are already logged by processTicket
+					err := w.processTicket(ticket)
+					w.slots.Release()
+					w.quotas.Finish(ticket)
+					w.locks.Release(ticket)
+					switch {
+					case err == nil:
+						w.completeClaim(ticket)
+					case !errors.Is(err, ErrAgentAuth) && !errors.Is(err, ErrPreempted) && !errors.Is(err, ErrRetrying):
+						w.releaseClaim(ticket)
+					}
 				}
 			}
 		}
@@ -98,8 +430,13 @@ func (w *Worker) Start(ctx context.Context) error {
 }
 
 // processTicket handles a ticket from start to finish
-func (w *Worker) processTicket(t *ticket.Ticket) {
+// Failures are logged and returned; CI failures wrap ErrCIFailed
+func (w *Worker) processTicket(t *ticket.Ticket) (err error) {
 	w.currentTask = t
+	w.beginTicket(t)
+	defer w.endTicket()
+	w.openTicketLog(t)
+	defer func() { w.closeTicketLog(err) }()
 
 	log.Printf("Worker %d processing ticket %s: %s", w.ID, t.ID, t.Title)
 	
@@ -107,9 +444,37 @@ func (w *Worker) processTicket(t *ticket.Ticket) {
 	if w.eventPublisher != nil {
 		w.eventPublisher("started", w.ID, t, fmt.Sprintf("Started processing ticket %s", t.ID))
 	}
-
-	// Generate branch name
-	branchName := fmt.Sprintf("agent-%d/%s", w.ID, t.ID)
+	started := time.Now()
+	defer func() {
+		if err == nil {
+			return
+		}
+		failing := w.recordError(t, err)
+		// Auth failures are already back on the queue, waiting for amp login
+		failed := !errors.Is(err, ErrPreempted) && !errors.Is(err, ErrCancelled) && !errors.Is(err, ErrAgentAuth)
+		if failed {
+			code := FailureCode(err)
+			if delay, ok := w.requeueForRetry(t, code); ok {
+				failed = false
+				if w.eventPublisher != nil {
+					w.eventPublisher("retrying", w.ID, t, fmt.Sprintf("%s on attempt %d of %d, retrying in %v: %v",
+						code, t.Attempt, w.retry.MaxAttempts, delay.Round(time.Second), err))
+				}
+				err = fmt.Errorf("%w: %w", ErrRetrying, err)
+			} else {
+				t.FailureCode = string(code)
+			}
+		}
+		if w.eventPublisher == nil {
+			return
+		}
+		if failed {
+			w.eventPublisher("failed", w.ID, t, err.Error())
+		}
+		if failing {
+			w.eventPublisher("error", w.ID, t, w.errorMessage(t, err))
+		}
+	}()
 
 	// Create worktree for this ticket
 	worktreePath := filepath.Join(w.workDir, fmt.Sprintf("agent-%d", w.ID), t.ID)
@@ -119,22 +484,95 @@ func (w *Worker) processTicket(t *ticket.Ticket) {
 		w.cleanupWorktree()
 	}
 
+	// Work in the repository of the ticket's environment
+	settings, err := w.environments.Lookup(t.Environment)
+	if err != nil {
+		w.currentTask = nil
+		return err
+	}
+	w.repo = w.defaultRepo
+	if settings.Repository != "" {
+		w.repo = gitutils.NewRepo(settings.Repository).WithContext(w.ctx)
+		w.repo.Runner = w.runner
+	}
+
+	// Pick the branch, dealing with any left by an earlier attempt
+	branchName, err := w.prepareBranch(t, worktreePath)
+	if err != nil {
+		log.Printf("Worker %d failed to prepare branch for %s: %v", w.ID, t.ID, err)
+		w.currentTask = nil
+		return err
+	}
+
+	// Agents running as jobs push the branch themselves; the worktree is
+	// checked out from it afterwards for CI and artifacts
+	if w.jobs != nil {
+		w.publishPhase(t, "agent")
+		w.startAgent(t)
+		err := w.runJob(t, branchName)
+		if err == nil {
+			err = w.chaos.AgentFailure()
+		}
+		preempted := w.stopAgent()
+		if w.isCancelled() {
+			return w.abandonCancelled(t)
+		}
+		if preempted && err != nil {
+			return w.requeuePreempted(t, branchName)
+		}
+		if err != nil {
+			log.Printf("Worker %d failed to complete work on %s: %v", w.ID, t.ID, err)
+			w.currentTask = nil
+			if errors.Is(err, ErrAgentAuth) {
+				w.handleAuthError(t, err)
+			}
+			return err
+		}
+	}
+
 	// Create new worktree
 	resultPath, err := w.repo.AddWorktree(worktreePath, branchName)
 	if err != nil {
 		log.Printf("Worker %d failed to create worktree for %s: %v", w.ID, t.ID, err)
 		w.currentTask = nil
-		return
+		return fmt.Errorf("failed to create worktree: %w", err)
 	}
 
 	w.worktreePath = resultPath
 	log.Printf("Worker %d created worktree at %s for branch %s", w.ID, resultPath, branchName)
 
-	// Implement the feature using amp CLI
-	if err := w.implementFeature(t); err != nil {
-		log.Printf("Worker %d failed to complete work on %s: %v", w.ID, t.ID, err)
+	// Large generated artifacts go in the scratch directory, outside the worktree
+	w.scratchDir, err = scratch.Create(w.workDir, t.ID)
+	if err != nil {
+		log.Printf("Worker %d failed to create scratch directory for %s: %v", w.ID, t.ID, err)
 		w.cleanup()
-		return
+		return err
+	}
+
+	// Implement the feature using amp CLI
+	if w.jobs == nil {
+		w.publishPhase(t, "agent")
+		w.startAgent(t)
+		err := w.implementFeature(t)
+		if err == nil {
+			err = w.chaos.AgentFailure()
+		}
+		preempted := w.stopAgent()
+		if w.isCancelled() {
+			return w.abandonCancelled(t)
+		}
+		if preempted && err != nil {
+			return w.requeuePreempted(t, branchName)
+		}
+		if err != nil {
+			log.Printf("Worker %d failed to complete work on %s: %v", w.ID, t.ID, err)
+			w.cleanup()
+			if errors.Is(err, ErrAgentAuth) {
+				w.handleAuthError(t, err)
+			}
+			w.reportLimitExceeded(t, err)
+			return err
+		}
 	}
 
 	// Trigger CI and wait for results (unless skipped for testing)
@@ -143,26 +581,45 @@ func (w *Worker) processTicket(t *ticket.Ticket) {
 		if err != nil {
 			log.Printf("Worker %d failed to get commit hash for %s: %v", w.ID, t.ID, err)
 			w.cleanup()
-			return
+			return fmt.Errorf("failed to get commit hash: %w", err)
 		}
 
-		// Trigger CI manually since git hooks might not be reliable from worktrees
-		if err := w.triggerCI(branchName, commitHash); err != nil {
-			log.Printf("Worker %d failed to trigger CI for %s: %v", w.ID, t.ID, err)
-			w.cleanup()
-			return
-		}
-
-		if err := w.waitForCI(commitHash, branchName); err != nil {
-			log.Printf("Worker %d CI failed for %s: %v", w.ID, t.ID, err)
-			w.cleanup()
-			return
+		if reason := w.ciSkipReason(t, branchName); reason != "" {
+			if err := w.recordCISkip(t, branchName, commitHash, reason); err != nil {
+				log.Printf("Worker %d failed to record CI skip for %s: %v", w.ID, t.ID, err)
+				w.cleanup()
+				return fmt.Errorf("%w: %v", ErrCIFailed, err)
+			}
+		} else {
+			// Trigger CI manually since git hooks might not be reliable from worktrees
+			w.publishPhase(t, "ci")
+			err = w.waitForCI(t, commitHash, branchName, w.dispatchCI(branchName, commitHash, t))
+			if w.isCancelled() {
+				return w.abandonCancelled(t)
+			}
+			w.uploadCIOutput(t, commitHash)
+			w.publishCIStatus(t, commitHash)
+			if err == nil {
+				err = w.checkVulnerabilities(t, commitHash)
+			}
+			if err != nil {
+				log.Printf("Worker %d CI failed for %s: %v", w.ID, t.ID, err)
+				w.cleanup()
+				return fmt.Errorf("%w: %w", ErrCIFailed, err)
+			}
 		}
 	} else {
 		log.Printf("Worker %d: CI skipped for testing", w.ID)
 	}
 
+	if w.isCancelled() {
+		return w.abandonCancelled(t)
+	}
+	w.publishPhase(t, "artifacts")
+	w.publishArtifacts(t, branchName)
+
 	log.Printf("Worker %d completed ticket %s", w.ID, t.ID)
+	w.recordSuccess(time.Since(started))
 
 	// Publish ticket completed event
 	if w.eventPublisher != nil {
@@ -171,40 +628,144 @@ func (w *Worker) processTicket(t *ticket.Ticket) {
 
 	// Mark task as complete
 	w.currentTask = nil
+	return nil
+}
+
+// Run processes a single ticket synchronously outside the queue loop,
+// stopping its git, agent and CI commands once ctx is done
+// It is used by experiment runners that need the outcome of each run
+func (w *Worker) Run(ctx context.Context, t *ticket.Ticket) RunResult {
+	w.bindContext(ctx)
+	start := time.Now()
+	err := w.processTicket(t)
+	w.cleanup()
+
+	result := RunResult{
+		Branch:   t.Branch,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+
+	if commitHash, commitErr := w.repo.GetBranchCommit(result.Branch); commitErr == nil {
+		result.Commit = commitHash
+	}
+	result.Implemented = err == nil || errors.Is(err, ErrCIFailed)
+	result.CIPassed = err == nil && !w.skipCI
+
+	return result
+}
+
+// branchName returns the branch used for a ticket, continuing a preempted
+// ticket on the branch it was checkpointed to
+func (w *Worker) branchName(t *ticket.Ticket) string {
+	if t.Checkpoint != "" {
+		return t.Checkpoint
+	}
+	return w.branchPrefix + "/" + t.ID
 }
 
 // implementFeature uses the amp CLI to generate actual code for the ticket
 func (w *Worker) implementFeature(t *ticket.Ticket) error {
 	if w.skipAmp {
-		// For testing: create mock files instead of using amp CLI
+		// For testing: log the agent command a real run would use, then
+		// create mock files instead of using amp CLI
+		command.DryRun{Prefix: fmt.Sprintf("Worker %d", w.ID)}.Run(w.agentCmd(w.agentArgs, t.Description))
 		return w.createMockImplementation(t)
 	}
 
+	// A bug's reproduce command shows the agent the failure it is fixing
+	before, err := w.reproduceBefore(t)
+	if err != nil {
+		return err
+	}
+
 	// Create a detailed prompt for the amp agent
-	prompt := w.createPrompt(t)
+	prompt, err := w.renderPrompt(t)
+	if err != nil {
+		return err
+	}
+	prompt += reproductionSection(before)
+	// A retry after a vetoed push says what the policy refused
+	prompt += vetoSection(t.PushVeto)
 
 	// Use amp CLI to generate the actual implementation
-	log.Printf("Worker %d generating code using amp CLI for ticket %s", w.ID, t.ID)
+	log.Printf("Worker %d generating code using %s for ticket %s", w.ID, w.agentCommand, t.ID)
 
-	cmd := exec.Command("amp", "--no-notifications")
-	cmd.Dir = w.worktreePath
-	cmd.Stdin = strings.NewReader(prompt)
+	args := w.agentArgs
+	if w.agentCommand == "amp" {
+		args = append(w.ampArgs(t), w.agentArgs...)
+	}
 
-	output, err := cmd.CombinedOutput()
+	agentStarted := time.Now()
+	output, err := w.runAgent(args, prompt)
+	agentFinished := time.Now()
+	w.uploadAgentLog(t, output)
 	if err != nil {
 		log.Printf("Worker %d amp CLI error output: %s", w.ID, string(output))
+		// Drop the group's thread so the next ticket starts a fresh one
+		if t.ContextGroup != "" && w.threads != nil {
+			if forgetErr := w.threads.Forget(t.ContextGroup); forgetErr != nil {
+				log.Printf("Worker %d failed to reset amp thread for group %s: %v", w.ID, t.ContextGroup, forgetErr)
+			}
+		}
+		if isAuthError(string(output)) {
+			return fmt.Errorf("%w: %v", ErrAgentAuth, err)
+		}
 		return fmt.Errorf("amp CLI failed: %w", err)
 	}
 
 	log.Printf("Worker %d amp CLI completed successfully", w.ID)
 
+	t.Summary = extractSummary(string(output))
+	if t.Summary != "" {
+		log.Printf("Worker %d summary for %s: %s", w.ID, t.ID, t.Summary)
+	}
+
+	// A bug is only handed to CI once its reproduce command passes
+	if err := w.verifyFix(t); err != nil {
+		return err
+	}
+
 	// Add all generated files to git
 	if err := w.addAllChanges(); err != nil {
 		return fmt.Errorf("failed to add generated files: %w", err)
 	}
 
-	// Commit all the changes
-	commitMessage := fmt.Sprintf("Implement %s\n\n%s\n\nGenerated by Agent %d using amp CLI", t.Title, t.Description, w.ID)
+	// A bug fix comes with a test that keeps the bug from returning
+	if err := w.requireRegressionTest(t, args); err != nil {
+		return err
+	}
+
+	// Bounce obviously broken changes back to the agent before they reach a branch
+	if err := w.precheck(t, args); err != nil {
+		return err
+	}
+
+	// Keep oversized files, binaries and dependencies out of the branch
+	if err := w.guardFiles(t); err != nil {
+		return err
+	}
+
+	// Nor copyleft licenses and code copied from elsewhere
+	if err := w.scanLicenses(t); err != nil {
+		return err
+	}
+
+	// Nor anything the repository's own pre-push policy refuses
+	if err := w.checkPrePush(t); err != nil {
+		return err
+	}
+
+	// Commit all the changes, split into smaller commits if configured
+	commitMessage := fmt.Sprintf("Implement %s\n\n%s\n\n%sGenerated by Agent %d using amp CLI", t.Title, t.Description, summarySection(t.Summary), w.ID)
+	commitMessage, err = w.splitCommits(t, commitMessage, args)
+	if err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	// The provenance file goes in the last commit, which describes the ticket
+	if err := w.writeAttribution(t, prompt, agentStarted, agentFinished); err != nil {
+		return err
+	}
 	commitHash, err := w.commitAllChanges(commitMessage)
 	if err != nil {
 		return fmt.Errorf("failed to commit changes: %w", err)
@@ -214,6 +775,205 @@ func (w *Worker) implementFeature(t *ticket.Ticket) error {
 	return nil
 }
 
+// agentCmd prepares the agent's process in the worktree, with the prompt on
+// stdin
+func (w *Worker) agentCmd(args []string, prompt string) *exec.Cmd {
+	cmd := command.Context(w.agentContext(), w.agentCommand, args...)
+	cmd.Dir = w.worktreePath
+	cmd.Stdin = strings.NewReader(prompt)
+	cmd.Env = append(os.Environ(), w.env...)
+	if w.scratchDir != "" {
+		cmd.Env = append(cmd.Env, scratch.EnvVar+"="+w.scratchDir)
+	}
+	w.limits.Apply(cmd)
+	return cmd
+}
+
+// runAgent invokes the agent within the configured rate limits, retrying with
+// jittered exponential backoff when the service reports rate limiting
+func (w *Worker) runAgent(args []string, prompt string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		release, err := w.acquireAgentSlot()
+		if err != nil {
+			return nil, fmt.Errorf("waiting for agent rate limit: %w", err)
+		}
+
+		output, err := w.runner.CombinedOutput(w.agentCmd(args, prompt))
+		release()
+
+		if limitErr := w.limits.Check(w.agentContext(), err, output); limitErr != nil {
+			return output, limitErr
+		}
+
+		if err == nil || !ratelimit.IsRateLimited(string(output)) || attempt >= w.limitRetries {
+			return output, err
+		}
+
+		delay := ratelimit.Backoff(w.limitBackoff, attempt)
+		log.Printf("Worker %d rate limited by %s, retrying in %v (attempt %d/%d)",
+			w.ID, w.agentCommand, delay.Round(time.Second), attempt+1, w.limitRetries)
+
+		select {
+		case <-time.After(delay):
+		case <-w.agentContext().Done():
+			return output, w.agentContext().Err()
+		}
+	}
+}
+
+// acquireAgentSlot waits for both the global and the per-worker limiter
+func (w *Worker) acquireAgentSlot() (func(), error) {
+	// Rate tokens first, so waiting for one never holds a concurrency slot
+	if err := w.globalLimiter.Reserve(w.agentContext()); err != nil {
+		return nil, err
+	}
+	if err := w.workerLimiter.Reserve(w.agentContext()); err != nil {
+		return nil, err
+	}
+
+	releaseGlobal, err := w.globalLimiter.Slot(w.agentContext())
+	if err != nil {
+		return nil, err
+	}
+
+	releaseWorker, err := w.workerLimiter.Slot(w.agentContext())
+	if err != nil {
+		releaseGlobal()
+		return nil, err
+	}
+
+	return func() {
+		releaseWorker()
+		releaseGlobal()
+	}, nil
+}
+
+// completeClaim marks the ticket's claim done so other daemons never retry it
+func (w *Worker) completeClaim(t *ticket.Ticket) {
+	if err := w.claims.Complete(t.ID); err != nil {
+		log.Printf("Worker %d failed to complete claim on %s: %v", w.ID, t.ID, err)
+	}
+}
+
+// releaseClaim gives up the claim on a ticket that failed for good, so it
+// can be claimed again once it is re-enqueued
+func (w *Worker) releaseClaim(t *ticket.Ticket) {
+	if err := w.claims.Release(t.ID); err != nil {
+		log.Printf("Worker %d failed to release claim on %s: %v", w.ID, t.ID, err)
+	}
+}
+
+// handleAuthError puts the ticket back on the queue and pauses the pool
+// Nothing will succeed until the agent is logged in again
+func (w *Worker) handleAuthError(t *ticket.Ticket, err error) {
+	w.queue.Push(t)
+	log.Printf("Worker %d requeued ticket %s after agent authentication failure", w.ID, t.ID)
+
+	if w.pause == nil || !w.pause.Pause(err.Error()) {
+		return
+	}
+
+	log.Printf("Worker %d paused the worker pool: %v", w.ID, err)
+	if w.eventPublisher != nil {
+		w.eventPublisher("auth_error", w.ID, t,
+			fmt.Sprintf("%s authentication failed; pool paused, log in and send SIGHUP to resume", w.agentCommand))
+	}
+}
+
+// withinDiskQuota reports whether the worker directory is under its quota
+// The first check over quota is reported; the worker then idles until
+// space is freed
+func (w *Worker) withinDiskQuota(workerDir string) bool {
+	err := w.limits.CheckDisk(workerDir)
+	if err == nil {
+		if w.overQuota {
+			log.Printf("Worker %d is back under its disk quota", w.ID)
+			w.overQuota = false
+		}
+		return true
+	}
+
+	if !errors.Is(err, limits.ErrExceeded) {
+		log.Printf("Worker %d failed to check disk quota: %v", w.ID, err)
+		return true
+	}
+
+	if !w.overQuota {
+		w.overQuota = true
+		log.Printf("Worker %d not taking tickets: %v", w.ID, err)
+		if w.eventPublisher != nil {
+			w.eventPublisher("limit_exceeded", w.ID, nil, err.Error())
+		}
+	}
+	return false
+}
+
+// reportLimitExceeded publishes an event when a ticket was killed for
+// exceeding a resource limit
+func (w *Worker) reportLimitExceeded(t *ticket.Ticket, err error) {
+	if !errors.Is(err, limits.ErrExceeded) {
+		return
+	}
+
+	log.Printf("Worker %d killed ticket %s: %v", w.ID, t.ID, err)
+	if w.eventPublisher != nil {
+		w.eventPublisher("limit_exceeded", w.ID, t, err.Error())
+	}
+}
+
+// ampArgs returns the amp CLI arguments for a ticket
+// Tickets with a context group continue the group's shared thread
+func (w *Worker) ampArgs(t *ticket.Ticket) []string {
+	args := []string{"--no-notifications"}
+	if t.ContextGroup == "" || w.threads == nil {
+		return args
+	}
+
+	threadID, err := w.threads.GetOrCreate(t.ContextGroup, w.newAmpThread)
+	if err != nil {
+		log.Printf("Worker %d failed to get amp thread for group %s, using a fresh session: %v", w.ID, t.ContextGroup, err)
+		return args
+	}
+
+	log.Printf("Worker %d continuing amp thread %s for group %s", w.ID, threadID, t.ContextGroup)
+	return append(args, "threads", "continue", threadID)
+}
+
+// newAmpThread creates a new amp thread and returns its ID
+func (w *Worker) newAmpThread() (string, error) {
+	cmd := command.Context(w.ctx, "amp", "threads", "new")
+	cmd.Dir = w.worktreePath
+
+	output, err := w.runner.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("amp threads new failed: %w", err)
+	}
+
+	threadID := strings.TrimSpace(string(output))
+	if threadID == "" {
+		return "", fmt.Errorf("amp threads new returned no thread ID")
+	}
+
+	return threadID, nil
+}
+
+// renderPrompt returns the prompt for a ticket, using the configured template if any
+// Conflict resolution tickets always get the built-in resolution prompt
+func (w *Worker) renderPrompt(t *ticket.Ticket) (string, error) {
+	if t.Resolves != nil {
+		return w.createResolvePrompt(t), nil
+	}
+	if w.promptTemplate == nil {
+		return w.createPrompt(t), nil
+	}
+
+	var b strings.Builder
+	if err := w.promptTemplate.Execute(&b, t); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return b.String(), nil
+}
+
 // createPrompt generates a detailed prompt for the amp agent based on the ticket
 func (w *Worker) createPrompt(t *ticket.Ticket) string {
 	prompt := fmt.Sprintf(`You are an AI coding agent working on ticket %s: %s
@@ -223,6 +983,11 @@ Priority: %d
 
 `, t.ID, t.Title, t.Description, t.Priority)
 
+	// Say what kind of work this is when it isn't a feature
+	if t.Type != "" && t.Type != ticket.TypeFeature {
+		prompt += fmt.Sprintf("Type: %s\n\n", t.Type)
+	}
+
 	// Add dependencies context if they exist
 	if len(t.Dependencies) > 0 {
 		prompt += "Dependencies (these should already be implemented):\n"
@@ -241,6 +1006,11 @@ Priority: %d
 		prompt += "\n"
 	}
 
+	// Remind the agent that earlier related work lives in this thread
+	if t.ContextGroup != "" {
+		prompt += fmt.Sprintf("This ticket is part of the %q feature group. Build on the work from earlier tickets in this conversation.\n\n", t.ContextGroup)
+	}
+
 	// Add tags context if they exist
 	if len(t.Tags) > 0 {
 		prompt += "Tags: "
@@ -261,17 +1031,78 @@ Priority: %d
 
 Make sure the implementation is production-ready, includes proper error handling, and follows Go best practices.
 
-Work in the current directory. Do not explain what you're doing, just implement the solution.`
+Work in the current directory. Do not explain what you're doing, just implement the solution.
+
+When you are finished, end your reply with a single line starting with "SUMMARY:" followed by one or two sentences describing what you implemented.`
 
 	return prompt
 }
 
+// maxSummaryLength caps the stored summary so events and commits stay readable
+const maxSummaryLength = 500
+
+// summaryMarker introduces the agent's summary, in any case
+const summaryMarker = "SUMMARY:"
+
+// extractSummary pulls the agent's summary out of the last "SUMMARY:" line
+// of its output; output without one has no summary, as whatever it ended
+// with (a stack trace, a file listing) would only mislead
+func extractSummary(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	summary := ""
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if idx := indexFold(line, summaryMarker); idx >= 0 {
+			summary = strings.TrimSpace(line[idx+len(summaryMarker):])
+			// Allow the summary to wrap onto following lines
+			for _, next := range lines[i+1:] {
+				if next = strings.TrimSpace(next); next != "" {
+					summary += " " + next
+				}
+			}
+			break
+		}
+	}
+
+	if len(summary) > maxSummaryLength {
+		// Cut on a rune boundary so the summary stays valid UTF-8
+		cut := maxSummaryLength - len("...")
+		for cut > 0 && !utf8.RuneStart(summary[cut]) {
+			cut--
+		}
+		summary = summary[:cut] + "..."
+	}
+
+	return summary
+}
+
+// indexFold returns the byte offset of the first case-insensitive match of
+// the ASCII marker in s, or -1. Unlike searching strings.ToUpper(s), the
+// offset is valid in s even when upper-casing would change its length.
+func indexFold(s, marker string) int {
+	for i := 0; i+len(marker) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(marker)], marker) {
+			return i
+		}
+	}
+	return -1
+}
+
+// summarySection formats a summary for inclusion in a commit message body
+func summarySection(summary string) string {
+	if summary == "" {
+		return ""
+	}
+	return "Summary: " + summary + "\n\n"
+}
+
 // addAllChanges adds all modified and new files to git
 func (w *Worker) addAllChanges() error {
-	cmd := exec.Command("git", "add", ".")
+	cmd := command.Context(w.ctx, "git", "add", ".")
 	cmd.Dir = w.worktreePath
 
-	output, err := cmd.CombinedOutput()
+	output, err := w.runner.CombinedOutput(cmd)
 	if err != nil {
 		log.Printf("Worker %d git add error: %s", w.ID, string(output))
 		return fmt.Errorf("git add failed: %w", err)
@@ -300,8 +1131,8 @@ func (w *Worker) commitAllChanges(commitMessage string) (string, error) {
 	}
 
 	// Check if there are changes to commit
-	statusCmd := exec.Command("git", "status", "--porcelain")
-	statusOutput, err := statusCmd.CombinedOutput()
+	statusCmd := command.Context(w.ctx, "git", "status", "--porcelain")
+	statusOutput, err := w.runner.CombinedOutput(statusCmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to check git status: %w", err)
 	}
@@ -311,15 +1142,15 @@ func (w *Worker) commitAllChanges(commitMessage string) (string, error) {
 	}
 
 	// Commit the changes
-	commitCmd := exec.Command("git", "commit", "-m", commitMessage)
-	if output, err := commitCmd.CombinedOutput(); err != nil {
+	commitCmd := command.Context(w.ctx, "git", "commit", "-m", commitMessage)
+	if output, err := w.runner.CombinedOutput(commitCmd); err != nil {
 		log.Printf("Worker %d git commit error: %s", w.ID, string(output))
 		return "", fmt.Errorf("git commit failed: %w", err)
 	}
 
 	// Get the commit hash
-	hashCmd := exec.Command("git", "rev-parse", "HEAD")
-	hashOutput, err := hashCmd.CombinedOutput()
+	hashCmd := command.Context(w.ctx, "git", "rev-parse", "HEAD")
+	hashOutput, err := w.runner.CombinedOutput(hashCmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to get commit hash: %w", err)
 	}
@@ -327,8 +1158,8 @@ func (w *Worker) commitAllChanges(commitMessage string) (string, error) {
 	commitHash := strings.TrimSpace(string(hashOutput))
 
 	// Get current branch name
-	branchCmd := exec.Command("git", "branch", "--show-current")
-	branchOutput, err := branchCmd.CombinedOutput()
+	branchCmd := command.Context(w.ctx, "git", "branch", "--show-current")
+	branchOutput, err := w.runner.CombinedOutput(branchCmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
@@ -336,21 +1167,24 @@ func (w *Worker) commitAllChanges(commitMessage string) (string, error) {
 	currentBranch := strings.TrimSpace(string(branchOutput))
 
 	// Configure the remote to point to the bare repository
-	remoteCmd := exec.Command("git", "remote", "add", "origin", absRepoPath)
-	if _, err := remoteCmd.CombinedOutput(); err != nil {
+	remoteCmd := command.Context(w.ctx, "git", "remote", "add", "origin", absRepoPath)
+	if _, err := w.runner.CombinedOutput(remoteCmd); err != nil {
 		// Remote might already exist, try to set the URL instead
-		remoteCmd = exec.Command("git", "remote", "set-url", "origin", absRepoPath)
-		if output, err := remoteCmd.CombinedOutput(); err != nil {
+		remoteCmd = command.Context(w.ctx, "git", "remote", "set-url", "origin", absRepoPath)
+		if output, err := w.runner.CombinedOutput(remoteCmd); err != nil {
 			log.Printf("Worker %d git remote error: %s", w.ID, string(output))
 			return "", fmt.Errorf("failed to configure git remote: %w", err)
 		}
 	}
 
 	// Push the commit
-	pushCmd := exec.Command("git", "push", "origin", currentBranch)
-	if output, err := pushCmd.CombinedOutput(); err != nil {
+	pushCmd := command.Context(w.ctx, "git", "push", "origin", currentBranch)
+	if output, err := w.runner.CombinedOutput(pushCmd); err != nil {
 		log.Printf("Worker %d git push error: %s", w.ID, string(output))
-		return "", fmt.Errorf("git push failed: %w", err)
+		if strings.Contains(string(output), "[rejected]") {
+			err = fmt.Errorf("%w: %s was updated by someone else", ErrConflict, currentBranch)
+		}
+		return "", internal.NewGitError("push", w.worktreePath, err)
 	}
 
 	return commitHash, nil
@@ -401,13 +1235,15 @@ Generated by Agent %d.
 
 	log.Printf("Worker %d created mock implementation for testing", w.ID)
 
+	t.Summary = fmt.Sprintf("Mock implementation of %s with main.go, go.mod and README.md", t.Title)
+
 	// Add all generated files to git
 	if err := w.addAllChanges(); err != nil {
 		return fmt.Errorf("failed to add generated files: %w", err)
 	}
 
 	// Commit all the changes
-	commitMessage := fmt.Sprintf("Implement %s\n\n%s\n\nMock implementation by Agent %d for testing", t.Title, t.Description, w.ID)
+	commitMessage := fmt.Sprintf("Implement %s\n\n%s\n\n%sMock implementation by Agent %d for testing", t.Title, t.Description, summarySection(t.Summary), w.ID)
 	commitHash, err := w.commitAllChanges(commitMessage)
 	if err != nil {
 		return fmt.Errorf("failed to commit changes: %w", err)
@@ -417,96 +1253,212 @@ Generated by Agent %d.
 	return nil
 }
 
-// waitForCI waits for CI to complete and checks the result
-func (w *Worker) waitForCI(commitHash, branchName string) error {
-	log.Printf("Worker %d waiting for CI to complete for branch %s (commit %s)", w.ID, branchName, commitHash[:8])
+// ciSkipReason explains why the ticket doesn't need CI, or returns "" when
+// it does: the ticket opts out, its tags select a profile that skips CI, or
+// the agent only changed documentation
+func (w *Worker) ciSkipReason(t *ticket.Ticket, branchName string) string {
+	if t.SkipCI {
+		return "ticket sets skip_ci"
+	}
+	if profile := w.ciProfile(t); profile != nil && profile.Skip {
+		return fmt.Sprintf("profile %s skips CI", profile.Name)
+	}
 
-	// Use reasonable timeout and polling interval
-	maxWaitTime := 30 * time.Second
-	pollInterval := 1 * time.Second
-	timeout := time.After(maxWaitTime)
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
+	if w.docsPaths.Len() == 0 {
+		return ""
+	}
+	files, err := w.repo.GetChangedFiles(branchName)
+	if err != nil || len(files) == 0 {
+		return ""
+	}
+	for _, file := range files {
+		if !w.docsPaths.Match(file, false) {
+			return ""
+		}
+	}
+	return "only documentation paths changed"
+}
 
-	for {
-		select {
-		case <-timeout:
-			return fmt.Errorf("timeout waiting for CI results after %v", maxWaitTime)
+// recordCISkip writes a SKIPPED status in place of a CI run and announces
+// the skip so it is kept in the audit journal
+func (w *Worker) recordCISkip(t *ticket.Ticket, branchName, commitHash, reason string) error {
+	log.Printf("Worker %d skipping CI for %s: %s", w.ID, branchName, reason)
 
-		case <-ticker.C:
-			// Check if CI status exists
-			if w.ciStatusReader.HasStatus(commitHash) {
-				// Check if CI passed
-				passing, err := w.ciStatusReader.IsPassing(commitHash)
-				if err != nil {
-					return fmt.Errorf("failed to check CI status: %w", err)
-				}
+	run := ci.Run{Branch: branchName, Commit: commitHash, TicketID: t.ID, Profile: w.ciProfile(t)}
+	if err := ci.WriteSkipped(w.ciStatusDir, run, reason); err != nil {
+		return err
+	}
+	w.publishCIStatus(t, commitHash)
+	if w.eventPublisher != nil {
+		w.eventPublisher("ci_skipped", w.ID, t, reason)
+	}
+	return nil
+}
 
-				if passing {
-					log.Printf("Worker %d: CI passed for %s", w.ID, branchName)
-					return nil
-				} else {
-					// Get detailed status for logging
-					status, err := w.ciStatusReader.GetStatus(commitHash)
-					if err != nil {
-						return fmt.Errorf("CI failed and unable to get details: %w", err)
-					}
-					return fmt.Errorf("CI failed for %s: %s", branchName, status.Output)
-				}
+// ciProfile returns the CI profile for a ticket: its environment's profile
+// if it names one, otherwise the first one its tags select
+func (w *Worker) ciProfile(t *ticket.Ticket) *ci.Profile {
+	if settings, err := w.environments.Lookup(t.Environment); err == nil && settings.CIProfile != "" {
+		for i := range w.ciProfiles {
+			if w.ciProfiles[i].Name == settings.CIProfile {
+				return &w.ciProfiles[i]
 			}
-			// CI status not ready yet, continue polling
 		}
 	}
+	return ci.SelectProfile(w.ciProfiles, t.Tags)
 }
 
-// triggerCI manually triggers the CI script for a branch and commit
-func (w *Worker) triggerCI(branchName, commitHash string) error {
-	log.Printf("Worker %d triggering CI for branch %s (commit %s)", w.ID, branchName, commitHash[:8])
+// dispatchCI runs CI for a branch and commit through the configured backend
+// in the background, using the CI profile of the ticket's environment or
+// tags; the returned channel receives the backend's result once it finishes
+func (w *Worker) dispatchCI(branchName, commitHash string, t *ticket.Ticket) <-chan error {
+	run := ci.Run{RepoPath: w.repo.Path, Branch: branchName, Commit: commitHash, TicketID: t.ID, ScratchDir: w.scratchDir}
+	run.Profile = w.ciProfile(t)
+	if w.quickTests {
+		run.Tier = ci.TierQuick
+	}
+	if run.Profile != nil {
+		log.Printf("Worker %d triggering CI profile %s for branch %s (commit %s)", w.ID, run.Profile.Name, branchName, commitHash[:8])
+	} else {
+		log.Printf("Worker %d triggering CI for branch %s (commit %s)", w.ID, branchName, commitHash[:8])
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.ciBackend.Run(w.ctx, run)
+	}()
+	return done
+}
 
-	// Find the ci.sh script path
-	execPath, err := os.Executable()
+// publishArtifacts stores the files matching the ticket's artifact globs and
+// links them from the ticket; failures are logged since the work itself passed
+func (w *Worker) publishArtifacts(t *ticket.Ticket, branchName string) {
+	t.ArtifactURLs = nil
+	if len(t.Artifacts) == 0 || w.artifactStore == nil {
+		return
+	}
+
+	published, err := artifacts.Publish(w.ctx, w.artifactStore, t.ID, w.worktreePath, t.Artifacts)
 	if err != nil {
-		return fmt.Errorf("failed to determine executable path: %w", err)
+		log.Printf("Worker %d failed to publish artifacts for %s: %v", w.ID, t.ID, err)
+	}
+	if len(published) == 0 {
+		log.Printf("Worker %d: no artifacts published for %s", w.ID, t.ID)
+		return
 	}
 
-	// Assume ci.sh is in the project root (parent of bin/)
-	projectRoot := filepath.Dir(filepath.Dir(execPath))
-	ciScriptPath := filepath.Join(projectRoot, "ci.sh")
+	for _, artifact := range published {
+		t.ArtifactURLs = append(t.ArtifactURLs, artifact.URL)
+	}
+	log.Printf("Worker %d published %d artifacts for %s", w.ID, len(published), t.ID)
 
-	// Check if ci.sh exists, if not use the current directory
-	if _, err := os.Stat(ciScriptPath); os.IsNotExist(err) {
-		// Fall back to current working directory
-		ciScriptPath = "ci.sh"
+	if w.metricsDir == "" {
+		return
+	}
+	commitHash, _ := w.repo.GetBranchCommit(branchName)
+	record := artifacts.Record{TicketID: t.ID, Commit: commitHash, PublishedAt: time.Now().UTC(), Artifacts: published}
+	if err := artifacts.AppendHistory(w.metricsDir, record); err != nil {
+		log.Printf("Worker %d failed to record artifacts for %s: %v", w.ID, t.ID, err)
 	}
+}
 
-	// Get absolute path to repository
-	repoPath, err := filepath.Abs(w.repo.Path)
+// uploadAgentLog keeps the agent's output for a ticket in object storage
+func (w *Worker) uploadAgentLog(t *ticket.Ticket, output []byte) {
+	if w.objectStore == nil || len(output) == 0 {
+		return
+	}
+
+	key := fmt.Sprintf("%s%s/%s-agent-%d.log", storage.LogsPrefix, t.ID, time.Now().UTC().Format("20060102T150405Z"), w.ID)
+	data, err := w.cipher.Encrypt(output)
+	if err != nil {
+		log.Printf("Worker %d failed to encrypt agent log for %s: %v", w.ID, t.ID, err)
+		return
+	}
+	url, err := storage.PutBytes(w.ctx, w.objectStore, key, data)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute repo path: %w", err)
+		log.Printf("Worker %d failed to upload agent log for %s: %v", w.ID, t.ID, err)
+		return
+	}
+	t.LogURLs = append(t.LogURLs, url)
+}
+
+// publishCIStatus copies the commit's CI status to the shared status store
+func (w *Worker) publishCIStatus(t *ticket.Ticket, commitHash string) {
+	if err := ci.PublishStatus(w.ciStatusStore, w.ciStatusDir, commitHash); err != nil && !errors.Is(err, ci.ErrStatusNotFound) {
+		log.Printf("Worker %d failed to publish CI status for %s: %v", w.ID, t.ID, err)
 	}
+}
 
-	// Run the CI script: ci.sh <repo_path> <ref_name> <commit_hash>
-	refName := "refs/heads/" + branchName
-	cmd := exec.Command(ciScriptPath, repoPath, refName, commitHash)
+// uploadCIOutput copies the commit's CI status, output included, to object
+// storage, then drops the output from the local status so status_path
+// doesn't keep growing with copies of what the bucket holds
+func (w *Worker) uploadCIOutput(t *ticket.Ticket, commitHash string) {
+	if w.objectStore == nil {
+		return
+	}
 
-	output, err := cmd.CombinedOutput()
+	path := filepath.Join(w.ciStatusDir, commitHash+".json")
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+
+	key := fmt.Sprintf("%s%s/%s.json", storage.CIPrefix, t.ID, commitHash)
+	var url string
+	var err error
+	if !w.cipher.Encrypts() {
+		url, err = w.objectStore.Put(w.ctx, key, path)
+	} else {
+		var data []byte
+		data, err = os.ReadFile(path)
+		if err == nil {
+			data, err = w.cipher.Encrypt(data)
+		}
+		if err == nil {
+			url, err = storage.PutBytes(w.ctx, w.objectStore, key, data)
+		}
+	}
 	if err != nil {
-		log.Printf("Worker %d CI script output: %s", w.ID, string(output))
-		return fmt.Errorf("CI script failed: %w", err)
+		log.Printf("Worker %d failed to upload CI output for %s: %v", w.ID, t.ID, err)
+		return
 	}
 
-	log.Printf("Worker %d: CI triggered successfully for %s", w.ID, branchName)
-	return nil
+	local := &ci.FileStatusStore{Dir: w.ciStatusDir}
+	status, err := local.Get(commitHash)
+	if err == nil {
+		status.Output = "Output uploaded to " + url
+		err = local.Put(status)
+	}
+	if err != nil {
+		log.Printf("Worker %d failed to trim the local CI output for %s: %v", w.ID, t.ID, err)
+	}
 }
 
 // cleanup cleans up worker resources
 func (w *Worker) cleanup() {
+	w.killLeftovers()
 	if w.worktreePath != "" {
 		w.cleanupWorktree()
 	}
+	// The scratch directory itself is kept until pruned
+	w.scratchDir = ""
 	w.currentTask = nil
 }
 
+// killLeftovers stops processes the ticket's commands left running, such as
+// a server the agent started in the background, before its worktree goes
+func (w *Worker) killLeftovers() {
+	if w.procs == nil {
+		return
+	}
+	if killed := w.procs.Kill(); len(killed) > 0 {
+		ticketID := "idle chores"
+		if w.currentTask != nil {
+			ticketID = w.currentTask.ID
+		}
+		log.Printf("Worker %d killed %d process groups left running by %s: %v", w.ID, len(killed), ticketID, killed)
+	}
+}
+
 // cleanupWorktree removes the current worktree
 func (w *Worker) cleanupWorktree() {
 	if w.worktreePath == "" {
@@ -528,11 +1480,47 @@ func (w *Worker) GetStatus() WorkerStatus {
 		ID:        w.ID,
 		IsRunning: w.isRunning,
 	}
+	w.healthMu.Lock()
+	status.Ready = w.ready
+	if w.warmupErr != nil {
+		status.NotReady = w.warmupErr.Error()
+	}
+	status.FailedTicketCount = w.failedTickets
+	status.TicketsCompleted = w.completed
+	if w.completed > 0 {
+		status.AverageDuration = w.completedTime / time.Duration(w.completed)
+	}
+	status.Phase = w.phase
+	status.CIStep = w.ciStep
+	if !w.ciStarted.IsZero() {
+		status.CIElapsed = time.Since(w.ciStarted)
+	}
+	if !w.startedAt.IsZero() {
+		status.Uptime = time.Since(w.startedAt)
+	}
+	status.LastError = w.lastError
+	status.LastErrorTicket = w.lastErrorOn
+	if !w.lastErrorAt.IsZero() {
+		status.LastErrorAt = &w.lastErrorAt
+	}
+	failing := w.failing
+	w.healthMu.Unlock()
+	status.Paused, status.PausedReason = w.held.Paused()
+
+	switch {
+	case failing || status.NotReady != "":
+		status.Status = "error"
+	case w.currentTask != nil:
+		status.Status = "working"
+	default:
+		status.Status = "idle"
+	}
 
 	if w.currentTask != nil {
 		status.CurrentTicket = &TicketInfo{
-			ID:    w.currentTask.ID,
-			Title: w.currentTask.Title,
+			ID:     w.currentTask.ID,
+			Title:  w.currentTask.Title,
+			Ticket: w.currentTask,
 		}
 		status.WorktreePath = w.worktreePath
 	}
@@ -540,6 +1528,15 @@ func (w *Worker) GetStatus() WorkerStatus {
 	return status
 }
 
+// publishPhase reports a ticket entering a phase of its processing
+func (w *Worker) publishPhase(t *ticket.Ticket, phase string) {
+	w.setPhase(phase)
+	w.ticketLog.Printf("worker", "Phase: %s", phase)
+	if w.eventPublisher != nil {
+		w.eventPublisher("phase", w.ID, t, phase)
+	}
+}
+
 // SetEventPublisher sets the event publisher function
 func (w *Worker) SetEventPublisher(publisher func(eventType string, workerID int, ticket *ticket.Ticket, message string)) {
 	w.eventPublisher = publisher
@@ -551,10 +1548,42 @@ type WorkerStatus struct {
 	IsRunning     bool        `json:"is_running"`
 	CurrentTicket *TicketInfo `json:"current_ticket,omitempty"`
 	WorktreePath  string      `json:"worktree_path,omitempty"`
+	Status        string      `json:"status"`              // idle, working or error
+	Ready         bool        `json:"ready"`               // Prerequisite checks passed
+	NotReady      string      `json:"not_ready,omitempty"` // Why the checks failed
+	Paused        bool        `json:"paused,omitempty"`    // Takes no new tickets until resumed
+	PausedReason  string      `json:"paused_reason,omitempty"`
+
+	// Ticket failures; the worker is in the error state after too many in a row
+	FailedTicketCount int        `json:"failed_ticket_count"`
+	LastError         string     `json:"last_error,omitempty"`
+	LastErrorTicket   string     `json:"last_error_ticket,omitempty"`
+	LastErrorAt       *time.Time `json:"last_error_at,omitempty"`
+
+	// Running totals since Start
+	TicketsCompleted int           `json:"tickets_completed"`
+	AverageDuration  time.Duration `json:"average_duration"` // Of completed tickets
+	Phase            string        `json:"phase,omitempty"`  // Of the current ticket
+	Uptime           time.Duration `json:"uptime"`
+
+	// The CI run being waited on, if any
+	CIStep    string        `json:"ci_step,omitempty"`
+	CIElapsed time.Duration `json:"ci_elapsed,omitempty"`
+}
+
+// RunResult describes the outcome of a single synchronous run
+type RunResult struct {
+	Branch      string
+	Commit      string
+	Implemented bool // The agent produced and pushed a commit
+	CIPassed    bool
+	Duration    time.Duration
+	Err         error
 }
 
 // TicketInfo holds basic ticket information for status reporting
 type TicketInfo struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
+	ID     string         `json:"id"`
+	Title  string         `json:"title"`
+	Ticket *ticket.Ticket `json:"-"` // For callers in the daemon, e.g. orchestrator list
 }
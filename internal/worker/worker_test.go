@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/brettsmith212/amp-orchestrator/internal/queue"
 	"github.com/brettsmith212/amp-orchestrator/internal/ticket"
@@ -34,6 +35,48 @@ func createMockCIStatus(statusDir, commitHash, ref, status string) error {
 	return os.WriteFile(statusFile, data, 0644)
 }
 
+// newWorktreeWorker returns a worker built from cfg, with its repository and
+// work directories in t's temporary directory, whose worktree is an empty
+// git repository
+func newWorktreeWorker(t *testing.T, cfg Config) *Worker {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+	if cfg.ID == 0 {
+		cfg.ID = 1
+	}
+	cfg.RepoPath, cfg.WorkDir = t.TempDir(), t.TempDir()
+
+	w := New(cfg, queue.New())
+	w.worktreePath = t.TempDir()
+	worktreeGit(t, w, "init", "-q")
+	return w
+}
+
+// worktreeGit runs git in w's worktree through the worker's runner
+func worktreeGit(t *testing.T, w *Worker, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = w.worktreePath
+	if output, err := w.runner.CombinedOutput(cmd); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+// worktreeWrite writes a file into w's worktree, creating its directory
+func worktreeWrite(t *testing.T, w *Worker, name, content string) {
+	t.Helper()
+	path := filepath.Join(w.worktreePath, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", name, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
 func TestWorkerCreatesBranch(t *testing.T) {
 	// Create test environment
 	tmpDir := t.TempDir()
@@ -342,6 +385,14 @@ func TestWorkerCITrigger(t *testing.T) {
 		t.Fatalf("Failed to create CI status directory: %v", err)
 	}
 
+	// Workers check ci.sh is runnable before taking tickets; the status is
+	// written below instead
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "ci.sh"), []byte("#!/bin/bash\nexit 0\n"), 0755); err != nil {
+		t.Fatalf("Failed to write ci.sh: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
 	// Create worker
 	config := Config{
 		ID:          4,
@@ -546,4 +597,91 @@ func TestBranchNaming(t *testing.T) {
 	if !strings.Contains(branchOutput, expectedPattern) {
 		t.Errorf("Expected branch pattern %s not found in output: %s", expectedPattern, branchOutput)
 	}
-}
\ No newline at end of file
+}
+func TestExtractSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name:     "summary line",
+			output:   "Creating main.go\nRunning go build\nSUMMARY: Added a calculator CLI with tests.",
+			expected: "Added a calculator CLI with tests.",
+		},
+		{
+			name:     "wrapped summary",
+			output:   "done\nSummary: Added a calculator CLI\nwith add and subtract.\n",
+			expected: "Added a calculator CLI with add and subtract.",
+		},
+		{
+			name:     "no summary line",
+			output:   "Creating main.go\nAll files written\n\n",
+			expected: "",
+		},
+		{
+			name:     "text that changes length when upper-cased",
+			output:   "ıııı summary: Fixed the parser.",
+			expected: "Fixed the parser.",
+		},
+		{
+			name:     "empty output",
+			output:   "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractSummary(tt.output); got != tt.expected {
+				t.Errorf("Expected summary %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	long := "SUMMARY: " + strings.Repeat("x", maxSummaryLength*2)
+	if got := extractSummary(long); len(got) != maxSummaryLength {
+		t.Errorf("Expected summary truncated to %d chars, got %d", maxSummaryLength, len(got))
+	}
+
+	wide := "SUMMARY: " + strings.Repeat("é", maxSummaryLength)
+	if got := extractSummary(wide); !utf8.ValidString(got) || len(got) > maxSummaryLength {
+		t.Errorf("Expected a valid summary of at most %d bytes, got %d bytes (valid %v)", maxSummaryLength, len(got), utf8.ValidString(got))
+	}
+}
+
+func TestWorkerPublishesPhases(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := gitutils.InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := gitutils.NewRepo(repoPath).CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	w := New(Config{
+		ID:          1,
+		RepoPath:    repoPath,
+		WorkDir:     filepath.Join(tmpDir, "work"),
+		CIStatusDir: filepath.Join(tmpDir, "ci-status"),
+		SkipCI:      true,
+		SkipAmp:     true,
+	}, queue.New())
+
+	var published []string
+	w.SetEventPublisher(func(eventType string, workerID int, t *ticket.Ticket, message string) {
+		if eventType == "phase" {
+			eventType += ":" + message
+		}
+		published = append(published, eventType)
+	})
+
+	if err := w.processTicket(&ticket.Ticket{ID: "feat-phases", Title: "Phases", Priority: 1}); err != nil {
+		t.Fatalf("processTicket failed: %v", err)
+	}
+	if got := strings.Join(published, " "); got != "started phase:agent phase:artifacts completed" {
+		t.Errorf("Unexpected events %q", got)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build linux
+
+package command
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// groupAlive reports whether a process group has members that haven't
+// exited; zombies waiting on a slow init to reap them don't count
+func groupAlive(pgid int) bool {
+	if syscall.Kill(-pgid, 0) != nil {
+		return false
+	}
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return true
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		stat, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// The command name may hold spaces, so fields start after its ')':
+		// state, parent, process group
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		if len(fields) > 2 && fields[2] == strconv.Itoa(pgid) && fields[0] != "Z" {
+			return true
+		}
+	}
+	return false
+}
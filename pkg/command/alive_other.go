@@ -0,0 +1,10 @@
+//go:build unix && !linux
+
+package command
+
+import "syscall"
+
+// groupAlive reports whether a process group still has members
+func groupAlive(pgid int) bool {
+	return syscall.Kill(-pgid, 0) == nil
+}
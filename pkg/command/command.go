@@ -0,0 +1,166 @@
+// Package command runs external programs behind an interface, so callers
+// can be tested without git, amp or ci.sh and simulated without side effects
+package command
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Runner runs a prepared command, as the exec.Cmd methods of the same names do
+type Runner interface {
+	Run(cmd *exec.Cmd) error
+	Output(cmd *exec.Cmd) ([]byte, error)
+	CombinedOutput(cmd *exec.Cmd) ([]byte, error)
+}
+
+// Default runs commands for callers given no runner
+var Default Runner = Exec{}
+
+// Or returns runner, or Default when it is nil
+func Or(runner Runner) Runner {
+	if runner == nil {
+		return Default
+	}
+	return runner
+}
+
+// ExitStatus is an error stubs return to fake a command exiting with Code
+type ExitStatus struct {
+	Code int
+}
+
+func (e *ExitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the code a command exited with, from an *exec.ExitError
+// or *ExitStatus in err, or -1 if it has none
+func ExitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	var status *ExitStatus
+	if errors.As(err, &status) {
+		return status.Code
+	}
+	return -1
+}
+
+// Exec runs commands for real
+type Exec struct{}
+
+func (Exec) Run(cmd *exec.Cmd) error                      { return cmd.Run() }
+func (Exec) Output(cmd *exec.Cmd) ([]byte, error)         { return cmd.Output() }
+func (Exec) CombinedOutput(cmd *exec.Cmd) ([]byte, error) { return cmd.CombinedOutput() }
+
+// Call is a command a Recorder or DryRun was asked to run
+type Call struct {
+	Args []string // Program base name first, e.g. git status --porcelain
+	Dir  string
+}
+
+// String joins the call's arguments with spaces
+func (c Call) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+func newCall(cmd *exec.Cmd) Call {
+	args := append([]string{filepath.Base(cmd.Path)}, cmd.Args[1:]...)
+	return Call{Args: args, Dir: cmd.Dir}
+}
+
+// stub is a canned reply to calls starting with prefix
+type stub struct {
+	prefix string
+	output []byte
+	err    error
+}
+
+// Recorder records commands instead of running them and replies with stubbed
+// output; calls no stub matches succeed with no output
+type Recorder struct {
+	mu    sync.Mutex
+	stubs []stub
+	calls []Call
+}
+
+// NewRecorder returns a recorder with no stubs
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Stub replies to calls whose arguments start with prefix, e.g.
+// "git rev-parse", with output and err; the latest matching stub wins
+func (r *Recorder) Stub(prefix, output string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stubs = append(r.stubs, stub{prefix: prefix, output: []byte(output), err: err})
+}
+
+// Calls returns the recorded calls, oldest first
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+func (r *Recorder) Run(cmd *exec.Cmd) error {
+	_, err := r.CombinedOutput(cmd)
+	return err
+}
+
+func (r *Recorder) Output(cmd *exec.Cmd) ([]byte, error) {
+	return r.CombinedOutput(cmd)
+}
+
+func (r *Recorder) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	call := newCall(cmd)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+	line := call.String()
+	for i := len(r.stubs) - 1; i >= 0; i-- {
+		s := r.stubs[i]
+		if line == s.prefix || strings.HasPrefix(line, s.prefix+" ") {
+			return append([]byte(nil), s.output...), s.err
+		}
+	}
+	return nil, nil
+}
+
+// DryRun logs each command instead of running it, and reports success with
+// no output
+type DryRun struct {
+	Prefix string // Starts each log line, e.g. "Worker 1"
+}
+
+func (d DryRun) Run(cmd *exec.Cmd) error {
+	_, err := d.CombinedOutput(cmd)
+	return err
+}
+
+func (d DryRun) Output(cmd *exec.Cmd) ([]byte, error) {
+	return d.CombinedOutput(cmd)
+}
+
+func (d DryRun) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	call := newCall(cmd)
+	where := ""
+	if call.Dir != "" {
+		where = fmt.Sprintf(" (in %s)", call.Dir)
+	}
+	prefix := "Dry run"
+	if d.Prefix != "" {
+		prefix = d.Prefix + " dry run"
+	}
+	log.Printf("%s: %s%s", prefix, call, where)
+	return nil, nil
+}
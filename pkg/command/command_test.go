@@ -0,0 +1,66 @@
+package command
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	r.Stub("git rev-parse", "abc123\n", nil)
+	r.Stub("git push", "! [rejected]", errors.New("exit status 1"))
+	r.Stub("git rev-parse --verify", "", errors.New("exit status 1"))
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = "/work"
+	if output, err := r.Output(cmd); err != nil || string(output) != "abc123\n" {
+		t.Errorf("Expected the stubbed hash, got %q (err %v)", output, err)
+	}
+	if _, err := r.CombinedOutput(exec.Command("git", "rev-parse", "--verify", "main")); err == nil {
+		t.Error("Expected the later, more specific stub to fail")
+	}
+	if err := r.Run(exec.Command("git", "push", "origin", "main")); err == nil {
+		t.Error("Expected the push stub's error")
+	}
+	if output, err := r.CombinedOutput(exec.Command("git", "rev-parsed")); err != nil || output != nil {
+		t.Errorf("Expected an unstubbed call to succeed with no output, got %q (err %v)", output, err)
+	}
+
+	calls := r.Calls()
+	if len(calls) != 4 {
+		t.Fatalf("Expected 4 calls, got %v", calls)
+	}
+	if calls[0].String() != "git rev-parse HEAD" || calls[0].Dir != "/work" {
+		t.Errorf("Unexpected first call %+v", calls[0])
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	output, err := DryRun{Prefix: "Worker 1"}.CombinedOutput(exec.Command("git", "push", "origin", "main"))
+	if err != nil || output != nil {
+		t.Errorf("Expected a dry run to succeed with no output, got %q (err %v)", output, err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(&ExitStatus{Code: 1}); code != 1 {
+		t.Errorf("Expected a stubbed exit code of 1, got %d", code)
+	}
+	if code := ExitCode(exec.Command("false").Run()); code != 1 {
+		t.Errorf("Expected false to exit with 1, got %d", code)
+	}
+	if code := ExitCode(errors.New("not started")); code != -1 {
+		t.Errorf("Expected -1 without an exit code, got %d", code)
+	}
+}
+
+func TestOr(t *testing.T) {
+	if _, ok := Or(nil).(Exec); !ok {
+		t.Error("Expected the default runner for nil")
+	}
+	r := NewRecorder()
+	if Or(r) != Runner(r) {
+		t.Error("Expected the given runner")
+	}
+}
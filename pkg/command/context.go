@@ -0,0 +1,28 @@
+package command
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+const (
+	// KillDelay is how long a cancelled command's process group has to exit
+	// after SIGTERM before it is sent SIGKILL
+	KillDelay = 2 * time.Second
+
+	// WaitDelay is how long a cancelled command's output is drained before
+	// Wait gives up on descendants still holding it open
+	WaitDelay = 5 * time.Second
+)
+
+// Context prepares a command that stops when ctx is done, as
+// exec.CommandContext does, but in its own process group so that cancelling
+// also stops whatever it spawned, such as git's helpers or a CI script's
+// build: the group gets SIGTERM, then SIGKILL after KillDelay
+func Context(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = WaitDelay
+	setProcessGroup(cmd)
+	return cmd
+}
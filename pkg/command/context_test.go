@@ -0,0 +1,41 @@
+//go:build unix
+
+package command
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := Context(ctx, "sh", "-c", "sleep 30 & echo $!; wait")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read the child's pid: %v", err)
+	}
+	child, _ := strconv.Atoi(strings.TrimSpace(line))
+
+	start := time.Now()
+	cancel()
+	if err := cmd.Wait(); err == nil {
+		t.Error("Expected a cancelled command to fail")
+	}
+	if elapsed := time.Since(start); elapsed > WaitDelay {
+		t.Errorf("Expected cancel to return promptly, took %v", elapsed)
+	}
+
+	// The orphaned sleep is killed with its parent rather than left running
+	waitGone(t, child, 2*time.Second)
+}
@@ -0,0 +1,131 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Group runs commands for real, each in its own process group, and remembers
+// groups whose leader exited while descendants kept running, so that Kill
+// can stop what a ticket's commands left behind
+type Group struct {
+	mu      sync.Mutex
+	running map[int]bool // Process group IDs; false once the leader exited
+}
+
+// NewGroup returns a group that has started no commands
+func NewGroup() *Group {
+	return &Group{running: make(map[int]bool)}
+}
+
+func (g *Group) Run(cmd *exec.Cmd) error {
+	return g.run(cmd)
+}
+
+func (g *Group) Output(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	captureStderr := cmd.Stderr == nil
+	if captureStderr {
+		cmd.Stderr = &stderr
+	}
+	err := g.run(cmd)
+	var exitErr *exec.ExitError
+	if captureStderr && errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+func (g *Group) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		return nil, errors.New("exec: Stdout or Stderr already set")
+	}
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := g.run(cmd)
+	return output.Bytes(), err
+}
+
+func (g *Group) run(cmd *exec.Cmd) error {
+	ensureProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	pgid := cmd.Process.Pid
+	g.mu.Lock()
+	g.running[pgid] = true
+	g.mu.Unlock()
+
+	err := cmd.Wait()
+
+	g.mu.Lock()
+	if groupAlive(pgid) {
+		g.running[pgid] = false
+	} else {
+		delete(g.running, pgid)
+	}
+	g.mu.Unlock()
+	return err
+}
+
+// Orphaned returns the process groups whose leader exited but which still
+// have members
+func (g *Group) Orphaned() []int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var pgids []int
+	for pgid, running := range g.running {
+		if !running {
+			pgids = append(pgids, pgid)
+		}
+	}
+	sort.Ints(pgids)
+	return pgids
+}
+
+// Kill stops the orphaned process groups: each gets SIGTERM, then SIGKILL if
+// it has members left after KillDelay, and members orphaned to this process
+// are reaped. It returns the groups it signalled; commands still running are
+// left to their context
+func (g *Group) Kill() []int {
+	pgids := g.Orphaned()
+	var signalled []int
+	for _, pgid := range pgids {
+		if termGroup(pgid) == nil {
+			signalled = append(signalled, pgid)
+		}
+	}
+
+	deadline := time.Now().Add(KillDelay)
+	for _, pgid := range signalled {
+		for {
+			reapGroup(pgid)
+			if !groupAlive(pgid) {
+				break
+			}
+			if time.Now().After(deadline) {
+				killGroup(pgid)
+				time.Sleep(10 * time.Millisecond)
+				reapGroup(pgid)
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	g.mu.Lock()
+	for _, pgid := range pgids {
+		delete(g.running, pgid)
+	}
+	g.mu.Unlock()
+	return signalled
+}
@@ -0,0 +1,127 @@
+//go:build unix
+
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// alive reports whether pid is running; zombies awaiting their reaper count
+// as gone
+func alive(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err == nil {
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		return len(fields) > 0 && fields[0] != "Z"
+	}
+	if _, statErr := os.Stat("/proc/self"); statErr == nil {
+		return false
+	}
+	return syscall.Kill(pid, 0) == nil
+}
+
+// waitGone fails the test unless pid exits within timeout
+func waitGone(t *testing.T, pid int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for alive(pid) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("Expected process %d to be killed", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// startOrphan runs a shell that leaves script running in the background and
+// returns its pid
+func startOrphan(t *testing.T, g *Group, script string) int {
+	t.Helper()
+	output, err := g.Output(exec.Command("sh", "-c", "("+script+") >/dev/null 2>&1 & echo $!"))
+	if err != nil {
+		t.Fatalf("Failed to start orphan: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		t.Fatalf("Unexpected output %q", output)
+	}
+	return pid
+}
+
+func TestGroupKillsOrphans(t *testing.T) {
+	g := NewGroup()
+	if err := g.Run(exec.Command("true")); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if orphaned := g.Orphaned(); len(orphaned) != 0 {
+		t.Fatalf("Expected a finished command to leave nothing behind, got %v", orphaned)
+	}
+
+	pid := startOrphan(t, g, "sleep 30")
+	if orphaned := g.Orphaned(); len(orphaned) != 1 {
+		t.Fatalf("Expected one orphaned group, got %v", orphaned)
+	}
+	if killed := g.Kill(); len(killed) != 1 {
+		t.Errorf("Expected one group killed, got %v", killed)
+	}
+	waitGone(t, pid, time.Second)
+	if orphaned := g.Orphaned(); len(orphaned) != 0 {
+		t.Errorf("Expected killed groups to be forgotten, got %v", orphaned)
+	}
+}
+
+func TestGroupEscalatesToSIGKILL(t *testing.T) {
+	g := NewGroup()
+	pid := startOrphan(t, g, `trap "" TERM; exec sleep 30`)
+
+	start := time.Now()
+	g.Kill()
+	waitGone(t, pid, time.Second)
+	if elapsed := time.Since(start); elapsed < KillDelay {
+		t.Errorf("Expected SIGTERM to be given %v, took %v", KillDelay, elapsed)
+	}
+}
+
+func TestContextEscalatesToSIGKILL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := Context(ctx, "sh", "-c", `trap "" TERM; sleep 30 & echo $!; wait`)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	buf := make([]byte, 32)
+	n, _ := stdout.Read(buf)
+	child, _ := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+
+	cancel()
+	cmd.Wait()
+	waitGone(t, child, KillDelay+time.Second)
+}
+
+func TestGroupOutput(t *testing.T) {
+	g := NewGroup()
+	output, err := g.Output(exec.Command("sh", "-c", "echo out; echo err >&2; exit 3"))
+	if string(output) != "out\n" || ExitCode(err) != 3 {
+		t.Errorf("Expected stdout and exit code 3, got %q (err %v)", output, err)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || string(exitErr.Stderr) != "err\n" {
+		t.Errorf("Expected stderr on the exit error, got %v", err)
+	}
+
+	combined, err := g.CombinedOutput(exec.Command("sh", "-c", "echo out; echo err >&2"))
+	if err != nil || string(combined) != "out\nerr\n" {
+		t.Errorf("Expected combined output, got %q (err %v)", combined, err)
+	}
+}
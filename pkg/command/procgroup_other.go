@@ -0,0 +1,20 @@
+//go:build !unix
+
+package command
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup leaves cmd to exec's default of killing only the process
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func ensureProcessGroup(cmd *exec.Cmd) {}
+
+// Without process groups there is nothing left to signal once a command's
+// own process is gone
+func termGroup(pgid int) error { return os.ErrProcessDone }
+func killGroup(pgid int) error { return os.ErrProcessDone }
+func groupAlive(pgid int) bool { return false }
+func reapGroup(pgid int)       {}
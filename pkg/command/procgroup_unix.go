@@ -0,0 +1,68 @@
+//go:build unix
+
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts cmd as the leader of a new process group and stops
+// the whole group on cancel
+func setProcessGroup(cmd *exec.Cmd) {
+	ensureProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return terminate(cmd.Process.Pid)
+	}
+}
+
+// ensureProcessGroup starts cmd as the leader of a new process group
+func ensureProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// terminate sends SIGTERM to a process group and SIGKILL to whatever is left
+// of it after KillDelay
+func terminate(pgid int) error {
+	if err := termGroup(pgid); err != nil {
+		return err
+	}
+	time.AfterFunc(KillDelay, func() {
+		if groupAlive(pgid) {
+			killGroup(pgid)
+		}
+	})
+	return nil
+}
+
+func termGroup(pgid int) error { return signalGroup(pgid, syscall.SIGTERM) }
+func killGroup(pgid int) error { return signalGroup(pgid, syscall.SIGKILL) }
+
+// signalGroup signals every process in a group, reporting os.ErrProcessDone
+// when none is left
+func signalGroup(pgid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pgid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// reapGroup collects the exit status of group members that were orphaned to
+// this process, e.g. when it runs as PID 1 in a container, so they don't
+// linger as zombies; only call it once the leader has been waited for
+func reapGroup(pgid int) {
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-pgid, &status, syscall.WNOHANG, nil)
+		if pid <= 0 || err != nil {
+			return
+		}
+	}
+}
@@ -1,18 +1,26 @@
 package gitutils
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha1"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/brettsmith212/amp-orchestrator/internal"
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
 )
 
 // GitRepo represents a git repository
 type GitRepo struct {
-	Path string // Path to the bare repository
+	Path   string         // Path to the bare repository
+	Runner command.Runner // Runs git; nil uses command.Default
+
+	ctx context.Context // Cancels running git commands; nil never does
 }
 
 // NewRepo creates a new GitRepo instance
@@ -22,6 +30,27 @@ func NewRepo(repoPath string) *GitRepo {
 	}
 }
 
+// WithContext returns a copy of the repository whose git commands are
+// killed, with everything they spawned, once ctx is done
+func (r *GitRepo) WithContext(ctx context.Context) *GitRepo {
+	repo := *r
+	repo.ctx = ctx
+	return &repo
+}
+
+// context returns the context git commands run under
+func (r *GitRepo) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// runner returns the repository's command runner
+func (r *GitRepo) runner() command.Runner {
+	return command.Or(r.Runner)
+}
+
 // AddWorktree creates a new git worktree for the given branch
 // Returns the path to the created worktree
 func (r *GitRepo) AddWorktree(worktreePath, branchName string) (string, error) {
@@ -36,7 +65,7 @@ func (r *GitRepo) AddWorktree(worktreePath, branchName string) (string, error) {
 	}
 
 	// Check if branch already exists in the repository
-	branchExists, err := r.branchExists(branchName)
+	branchExists, err := r.BranchExists(branchName)
 	if err != nil {
 		return "", err
 	}
@@ -44,17 +73,17 @@ func (r *GitRepo) AddWorktree(worktreePath, branchName string) (string, error) {
 	var cmd *exec.Cmd
 	if branchExists {
 		// Checkout existing branch
-		cmd = exec.Command("git", "--git-dir", r.Path, "worktree", "add", worktreePath, branchName)
+		cmd = command.Context(r.context(), "git", "--git-dir", r.Path, "worktree", "add", worktreePath, branchName)
 	} else {
 		// Create new branch from main/master
 		mainBranch, err := r.getMainBranch()
 		if err != nil {
 			return "", err
 		}
-		cmd = exec.Command("git", "--git-dir", r.Path, "worktree", "add", "-b", branchName, worktreePath, mainBranch)
+		cmd = command.Context(r.context(), "git", "--git-dir", r.Path, "worktree", "add", "-b", branchName, worktreePath, mainBranch)
 	}
 
-	output, err := cmd.CombinedOutput()
+	output, err := r.runner().CombinedOutput(cmd)
 	if err != nil {
 		return "", internal.NewGitError("add-worktree", worktreePath, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
@@ -63,10 +92,32 @@ func (r *GitRepo) AddWorktree(worktreePath, branchName string) (string, error) {
 	return worktreePath, nil
 }
 
+// AddDetachedWorktree checks out the main branch's tip at worktreePath
+// without creating a branch; a path left registered by an earlier crash is
+// reused
+func (r *GitRepo) AddDetachedWorktree(worktreePath string) error {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(worktreePath), 0755); err != nil {
+		return internal.NewGitError("mkdir", worktreePath, err)
+	}
+
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "worktree", "add", "--force", "--detach", worktreePath, mainBranch)
+	output, err := r.runner().CombinedOutput(cmd)
+	if err != nil {
+		return internal.NewGitError("add-worktree", worktreePath,
+			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
 // RemoveWorktree removes a git worktree
 func (r *GitRepo) RemoveWorktree(worktreePath string) error {
-	cmd := exec.Command("git", "--git-dir", r.Path, "worktree", "remove", worktreePath, "--force")
-	output, err := cmd.CombinedOutput()
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "worktree", "remove", worktreePath, "--force")
+	output, err := r.runner().CombinedOutput(cmd)
 	if err != nil {
 		return internal.NewGitError("remove-worktree", worktreePath, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
@@ -95,15 +146,15 @@ func (r *GitRepo) CommitFile(worktreePath, filePath, commitMessage string) (stri
 	}
 
 	// Add the file
-	addCmd := exec.Command("git", "add", filePath)
-	if output, err := addCmd.CombinedOutput(); err != nil {
+	addCmd := command.Context(r.context(), "git", "add", filePath)
+	if output, err := r.runner().CombinedOutput(addCmd); err != nil {
 		return "", internal.NewGitError("add", filePath, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
 	}
 
 	// Check if there are changes to commit
-	statusCmd := exec.Command("git", "status", "--porcelain")
-	statusOutput, err := statusCmd.CombinedOutput()
+	statusCmd := command.Context(r.context(), "git", "status", "--porcelain")
+	statusOutput, err := r.runner().CombinedOutput(statusCmd)
 	if err != nil {
 		return "", internal.NewGitError("status", worktreePath, err)
 	}
@@ -113,15 +164,15 @@ func (r *GitRepo) CommitFile(worktreePath, filePath, commitMessage string) (stri
 	}
 
 	// Commit the file
-	commitCmd := exec.Command("git", "commit", "-m", commitMessage)
-	if output, err := commitCmd.CombinedOutput(); err != nil {
+	commitCmd := command.Context(r.context(), "git", "commit", "-m", commitMessage)
+	if output, err := r.runner().CombinedOutput(commitCmd); err != nil {
 		return "", internal.NewGitError("commit", worktreePath, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
 	}
 
 	// Get the commit hash
-	hashCmd := exec.Command("git", "rev-parse", "HEAD")
-	hashOutput, err := hashCmd.CombinedOutput()
+	hashCmd := command.Context(r.context(), "git", "rev-parse", "HEAD")
+	hashOutput, err := r.runner().CombinedOutput(hashCmd)
 	if err != nil {
 		return "", internal.NewGitError("rev-parse", worktreePath, err)
 	}
@@ -129,8 +180,8 @@ func (r *GitRepo) CommitFile(worktreePath, filePath, commitMessage string) (stri
 	commitHash := strings.TrimSpace(string(hashOutput))
 
 	// Push the commit
-	getCurrentBranchCmd := exec.Command("git", "branch", "--show-current")
-	branchOutput, err := getCurrentBranchCmd.CombinedOutput()
+	getCurrentBranchCmd := command.Context(r.context(), "git", "branch", "--show-current")
+	branchOutput, err := r.runner().CombinedOutput(getCurrentBranchCmd)
 	if err != nil {
 		return "", internal.NewGitError("branch", worktreePath, err)
 	}
@@ -138,18 +189,18 @@ func (r *GitRepo) CommitFile(worktreePath, filePath, commitMessage string) (stri
 	currentBranch := strings.TrimSpace(string(branchOutput))
 	
 	// Configure the remote to point to the bare repository
-	remoteCmd := exec.Command("git", "remote", "add", "origin", absRepoPath)
-	if _, err := remoteCmd.CombinedOutput(); err != nil {
+	remoteCmd := command.Context(r.context(), "git", "remote", "add", "origin", absRepoPath)
+	if _, err := r.runner().CombinedOutput(remoteCmd); err != nil {
 		// Remote might already exist, try to set the URL instead
-		remoteCmd = exec.Command("git", "remote", "set-url", "origin", absRepoPath)
-		if output, err := remoteCmd.CombinedOutput(); err != nil {
+		remoteCmd = command.Context(r.context(), "git", "remote", "set-url", "origin", absRepoPath)
+		if output, err := r.runner().CombinedOutput(remoteCmd); err != nil {
 			return "", internal.NewGitError("remote", worktreePath, 
 				fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
 		}
 	}
 	
-	pushCmd := exec.Command("git", "push", "origin", currentBranch)
-	if output, err := pushCmd.CombinedOutput(); err != nil {
+	pushCmd := command.Context(r.context(), "git", "push", "origin", currentBranch)
+	if output, err := r.runner().CombinedOutput(pushCmd); err != nil {
 		return "", internal.NewGitError("push", worktreePath, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
 	}
@@ -159,8 +210,8 @@ func (r *GitRepo) CommitFile(worktreePath, filePath, commitMessage string) (stri
 
 // GetCommitCount returns the number of commits on the given branch
 func (r *GitRepo) GetCommitCount(branchName string) (int, error) {
-	cmd := exec.Command("git", "--git-dir", r.Path, "rev-list", "--count", branchName)
-	output, err := cmd.CombinedOutput()
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "rev-list", "--count", branchName)
+	output, err := r.runner().CombinedOutput(cmd)
 	if err != nil {
 		return 0, internal.NewGitError("rev-list", r.Path, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
@@ -176,8 +227,8 @@ func (r *GitRepo) GetCommitCount(branchName string) (int, error) {
 
 // ListBranches returns a list of all branches in the repository
 func (r *GitRepo) ListBranches() ([]string, error) {
-	cmd := exec.Command("git", "--git-dir", r.Path, "branch", "-a")
-	output, err := cmd.CombinedOutput()
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "branch", "-a")
+	output, err := r.runner().CombinedOutput(cmd)
 	if err != nil {
 		return nil, internal.NewGitError("branch", r.Path, err)
 	}
@@ -203,13 +254,87 @@ func (r *GitRepo) ListBranches() ([]string, error) {
 	return branches, nil
 }
 
-// branchExists checks if a branch exists in the repository
-func (r *GitRepo) branchExists(branchName string) (bool, error) {
-	cmd := exec.Command("git", "--git-dir", r.Path, "show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
-	err := cmd.Run()
+// FindAgentBranch returns the agent-X/<ticket-id> branch for a ticket, if any
+// When a retry left attempt-suffixed branches, the latest attempt is returned
+func FindAgentBranch(branches []string, ticketID string) string {
+	if found := FindAgentBranches(branches, ticketID); len(found) > 0 {
+		return found[0]
+	}
+	return ""
+}
+
+// FindAgentBranches returns every agent branch holding the ticket's latest
+// attempt, in the order given; more than one means several workers ran the
+// same attempt and the caller has to choose
+func FindAgentBranches(branches []string, ticketID string) []string {
+	var found []string
+	latest := -1
+	for _, branch := range branches {
+		attempt := agentBranchAttempt(branch, ticketID)
+		switch {
+		case attempt < 0:
+		case attempt > latest:
+			found, latest = []string{branch}, attempt
+		case attempt == latest:
+			found = append(found, branch)
+		}
+	}
+	return found
+}
+
+// agentBranchAttempt returns the attempt number of an agent branch for the
+// ticket, 0 for the first attempt, or -1 if the branch isn't the ticket's
+func agentBranchAttempt(branch, ticketID string) int {
+	if !strings.HasPrefix(branch, "agent-") {
+		return -1
+	}
+	if strings.HasSuffix(branch, "/"+ticketID) {
+		return 0
+	}
+	i := strings.LastIndex(branch, "/"+ticketID+attemptSuffix)
+	if i < 0 {
+		return -1
+	}
+	attempt, err := strconv.Atoi(branch[i+len(ticketID)+len(attemptSuffix)+1:])
+	if err != nil || attempt < 0 {
+		return -1
+	}
+	return attempt
+}
+
+// attemptSuffix separates a branch from its attempt number
+// A ref can't be both a branch and a directory of branches, so retries
+// can't live under the original branch as <branch>/attempt-N
+const attemptSuffix = "-attempt-"
+
+// AttemptBranch returns the branch for a retry of branch, e.g.
+// agent-1/feat-x-attempt-2
+func AttemptBranch(branch string, attempt int) string {
+	return branch + attemptSuffix + strconv.Itoa(attempt)
+}
+
+// ResetBranch points an existing branch back at the main branch's tip,
+// dropping the commits made on it
+func (r *GitRepo) ResetBranch(branchName string) error {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return err
+	}
+
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "branch", "--force", branchName, mainBranch)
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("reset-branch", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// BranchExists checks if a branch exists in the repository
+func (r *GitRepo) BranchExists(branchName string) (bool, error) {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
+	err := r.runner().Run(cmd)
 	if err != nil {
 		// Exit code 1 means branch doesn't exist, which is not an error
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		if command.ExitCode(err) == 1 {
 			return false, nil
 		}
 		return false, internal.NewGitError("show-ref", r.Path, err)
@@ -220,14 +345,14 @@ func (r *GitRepo) branchExists(branchName string) (bool, error) {
 // getMainBranch determines the main branch (main or master)
 func (r *GitRepo) getMainBranch() (string, error) {
 	// Try 'main' first (modern default)
-	if exists, err := r.branchExists("main"); err != nil {
+	if exists, err := r.BranchExists("main"); err != nil {
 		return "", err
 	} else if exists {
 		return "main", nil
 	}
 
 	// Fall back to 'master'
-	if exists, err := r.branchExists("master"); err != nil {
+	if exists, err := r.BranchExists("master"); err != nil {
 		return "", err
 	} else if exists {
 		return "master", nil
@@ -243,8 +368,8 @@ func InitBareRepo(repoPath string) error {
 		return internal.NewGitError("mkdir", repoPath, err)
 	}
 
-	cmd := exec.Command("git", "init", "--bare", repoPath)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	cmd := command.Context(context.Background(), "git", "init", "--bare", repoPath)
+	if output, err := command.Default.CombinedOutput(cmd); err != nil {
 		return internal.NewGitError("init", repoPath, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
 	}
@@ -261,8 +386,8 @@ func (r *GitRepo) CreateInitialCommit() error {
 	defer os.RemoveAll(tmpDir)
 
 	// Clone the bare repo
-	cloneCmd := exec.Command("git", "clone", r.Path, tmpDir+"/repo")
-	if output, err := cloneCmd.CombinedOutput(); err != nil {
+	cloneCmd := command.Context(r.context(), "git", "clone", r.Path, tmpDir+"/repo")
+	if output, err := r.runner().CombinedOutput(cloneCmd); err != nil {
 		return internal.NewGitError("clone", r.Path, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
 	}
@@ -286,23 +411,23 @@ func (r *GitRepo) CreateInitialCommit() error {
 	}
 
 	// Configure git user (required for commits)
-	exec.Command("git", "config", "user.name", "Amp Orchestrator").Run()
-	exec.Command("git", "config", "user.email", "orchestrator@localhost").Run()
+	r.runner().Run(command.Context(r.context(), "git", "config", "user.name", "Amp Orchestrator"))
+	r.runner().Run(command.Context(r.context(), "git", "config", "user.email", "orchestrator@localhost"))
 
 	// Add, commit, and push
-	if output, err := exec.Command("git", "add", "README.md").CombinedOutput(); err != nil {
+	if output, err := r.runner().CombinedOutput(command.Context(r.context(), "git", "add", "README.md")); err != nil {
 		return internal.NewGitError("add", "README.md", 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
 	}
 
-	if output, err := exec.Command("git", "commit", "-m", "Initial commit").CombinedOutput(); err != nil {
+	if output, err := r.runner().CombinedOutput(command.Context(r.context(), "git", "commit", "-m", "Initial commit")); err != nil {
 		return internal.NewGitError("commit", repoDir, 
 			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
 	}
 
-	if _, err := exec.Command("git", "push", "origin", "main").CombinedOutput(); err != nil {
+	if _, err := r.runner().CombinedOutput(command.Context(r.context(), "git", "push", "origin", "main")); err != nil {
 		// Try master if main fails
-		if masterOutput, masterErr := exec.Command("git", "push", "origin", "master").CombinedOutput(); masterErr != nil {
+		if masterOutput, masterErr := r.runner().CombinedOutput(command.Context(r.context(), "git", "push", "origin", "master")); masterErr != nil {
 			return internal.NewGitError("push", repoDir, 
 				fmt.Errorf("%s: %s", masterErr, strings.TrimSpace(string(masterOutput))))
 		}
@@ -313,12 +438,423 @@ func (r *GitRepo) CreateInitialCommit() error {
 
 // GetBranchCommit returns the latest commit hash for a given branch
 func (r *GitRepo) GetBranchCommit(branchName string) (string, error) {
-	cmd := exec.Command("git", "--git-dir", r.Path, "rev-parse", branchName)
-	output, err := cmd.Output()
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "rev-parse", branchName)
+	output, err := r.runner().Output(cmd)
 	if err != nil {
 		return "", internal.NewGitError("rev-parse", r.Path, err)
 	}
 	
 	commitHash := strings.TrimSpace(string(output))
 	return commitHash, nil
-}
\ No newline at end of file
+}
+
+// GC prunes stale worktree metadata and garbage-collects the repository.
+// Unreachable objects get git's default two-week grace period, since
+// workers, remote workers and other daemons may be writing objects to the
+// shared repository that aren't referenced yet
+func (r *GitRepo) GC() error {
+	if err := r.PruneWorktrees(r.context()); err != nil {
+		return err
+	}
+
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "gc", "--quiet")
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("gc", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// PruneWorktrees drops the metadata of worktrees whose directories are gone
+func (r *GitRepo) PruneWorktrees(ctx context.Context) error {
+	cmd := command.Context(ctx, "git", "--git-dir", r.Path, "worktree", "prune")
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("worktree-prune", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// Maintain garbage-collects the repository like GC but keeps recent
+// unreachable objects, so it is safe while agents are committing
+func (r *GitRepo) Maintain(ctx context.Context) error {
+	cmd := command.Context(ctx, "git", "--git-dir", r.Path, "gc", "--quiet")
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("gc", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// Prefetch downloads a remote's branches into refs/prefetch/ without
+// touching local branches, so later fetches have little left to transfer
+func (r *GitRepo) Prefetch(ctx context.Context, remote string) error {
+	cmd := command.Context(ctx, "git", "--git-dir", r.Path, "fetch", "--quiet", "--no-tags", "--prune", remote, "+refs/heads/*:refs/prefetch/heads/*")
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("prefetch", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// PushBranch pushes a branch to a remote, which may be a remote name or URL.
+// Rebased branches are replaced, but only while the remote branch is still
+// where this repo last pushed it (or does not exist yet), so a push made by
+// anyone else is never overwritten.
+func (r *GitRepo) PushBranch(remote, branchName string) error {
+	commit, err := r.GetBranchCommit(branchName)
+	if err != nil {
+		return err
+	}
+	leaseRef := pushedRef(remote, branchName)
+	expected, err := r.ResolveRef(leaseRef)
+	if err != nil {
+		return err
+	}
+
+	refspec := fmt.Sprintf("%s:refs/heads/%s", commit, branchName)
+	lease := fmt.Sprintf("--force-with-lease=refs/heads/%s:%s", branchName, expected)
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "push", lease, remote, refspec)
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("push", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+
+	cmd = command.Context(r.context(), "git", "--git-dir", r.Path, "update-ref", leaseRef, commit)
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("update-ref", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// pushedRef names the local ref recording what PushBranch last pushed to a
+// remote branch, keyed by a hash of the remote so names and URLs both fit
+func pushedRef(remote, branchName string) string {
+	sum := sha1.Sum([]byte(remote))
+	return fmt.Sprintf("refs/orchestrator/pushed/%x/%s", sum[:6], branchName)
+}
+
+// CloneBare clones a repository, which may be a path or URL, into a new bare
+// repository at repoPath
+func CloneBare(ctx context.Context, url, repoPath string) (*GitRepo, error) {
+	if err := os.MkdirAll(filepath.Dir(repoPath), 0755); err != nil {
+		return nil, internal.NewGitError("mkdir", repoPath, err)
+	}
+
+	cmd := command.Context(ctx, "git", "clone", "--bare", "--quiet", url, repoPath)
+	if output, err := command.Default.CombinedOutput(cmd); err != nil {
+		return nil, internal.NewGitError("clone", url, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return NewRepo(repoPath), nil
+}
+
+// FetchBranches force-updates every branch from a remote, which may be a
+// remote name or URL; local branches missing from the remote are kept
+func (r *GitRepo) FetchBranches(remote string) error {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "fetch", "--quiet", remote, "+refs/heads/*:refs/heads/*")
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("fetch", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// HashObject writes data to the object store as a blob and returns its hash
+func (r *GitRepo) HashObject(data []byte) (string, error) {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "hash-object", "-w", "--stdin")
+	cmd.Stdin = bytes.NewReader(data)
+	output, err := r.runner().Output(cmd)
+	if err != nil {
+		return "", internal.NewGitError("hash-object", r.Path, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ReadBlob returns the contents of a blob
+func (r *GitRepo) ReadBlob(hash string) ([]byte, error) {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "cat-file", "blob", hash)
+	output, err := r.runner().Output(cmd)
+	if err != nil {
+		return nil, internal.NewGitError("cat-file", r.Path, err)
+	}
+	return output, nil
+}
+
+// ResolveRef returns the object a ref points to, or "" if it does not exist
+func (r *GitRepo) ResolveRef(ref string) (string, error) {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "rev-parse", "--verify", "--quiet", ref)
+	output, err := r.runner().Output(cmd)
+	if err != nil {
+		if command.ExitCode(err) == 1 {
+			return "", nil
+		}
+		return "", internal.NewGitError("rev-parse", r.Path, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// UpdateRef atomically points ref at newHash, but only if it currently points
+// at oldHash; an empty oldHash requires that the ref does not exist yet
+func (r *GitRepo) UpdateRef(ref, newHash, oldHash string) error {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "update-ref", ref, newHash, oldHash)
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("update-ref", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// DeleteRef atomically deletes ref, but only if it currently points at oldHash
+func (r *GitRepo) DeleteRef(ref, oldHash string) error {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "update-ref", "-d", ref, oldHash)
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		return internal.NewGitError("update-ref", r.Path, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// MainBranch returns the name of the main branch, main or master
+func (r *GitRepo) MainBranch() (string, error) {
+	return r.getMainBranch()
+}
+
+// IsMerged reports whether commit is reachable from the main branch
+func (r *GitRepo) IsMerged(commit string) (bool, error) {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return false, err
+	}
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "merge-base", "--is-ancestor", commit, mainBranch)
+	if err := r.runner().Run(cmd); err != nil {
+		if command.ExitCode(err) == 1 {
+			return false, nil
+		}
+		return false, internal.NewGitError("merge-base", r.Path, err)
+	}
+	return true, nil
+}
+
+// MergedBy returns the merge commit in the main branch's first-parent history
+// that brought commit in, or "" if commit reached main without a merge
+func (r *GitRepo) MergedBy(commit string) (string, error) {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return "", err
+	}
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "rev-list", "--first-parent", "--merges",
+		"--ancestry-path", "--reverse", commit+".."+mainBranch)
+	output, err := r.runner().Output(cmd)
+	if err != nil {
+		return "", internal.NewGitError("rev-list", r.Path, err)
+	}
+	merges := strings.Fields(string(output))
+	if len(merges) == 0 {
+		return "", nil
+	}
+	return merges[0], nil
+}
+
+// LastMerge returns the most recent merge commit in commit's first-parent
+// history, commit itself included, or "" if there is none
+func (r *GitRepo) LastMerge(commit string) (string, error) {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "rev-list", "--first-parent", "--merges", "-n", "1", commit)
+	output, err := r.runner().Output(cmd)
+	if err != nil {
+		return "", internal.NewGitError("rev-list", r.Path, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// MergeConflicts merges branch into the main branch in memory, without
+// touching either, and returns the files git could not merge along with its
+// messages about them; a branch that merges cleanly has no files
+func (r *GitRepo) MergeConflicts(branch string) ([]string, string, error) {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return nil, "", err
+	}
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "merge-tree", "--write-tree", "--name-only", "--messages", mainBranch, branch)
+	output, err := r.runner().Output(cmd)
+	if err == nil {
+		return nil, "", nil
+	}
+	// Exit status 1 means conflicts, unless git wrote nothing, as it does for
+	// a branch it cannot find
+	if command.ExitCode(err) != 1 || len(output) == 0 {
+		return nil, "", internal.NewGitError("merge-tree", r.Path, err)
+	}
+
+	// The merged tree's hash, a line per conflicted file, a blank line, then messages
+	sections := strings.SplitN(string(output), "\n\n", 2)
+	lines := strings.Split(sections[0], "\n")
+	var files []string
+	seen := make(map[string]bool)
+	for _, file := range lines[1:] {
+		if file != "" && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	messages := ""
+	if len(sections) > 1 {
+		messages = strings.TrimSpace(sections[1])
+	}
+	return files, messages, nil
+}
+
+// Parents returns a commit's parents, first parent first
+func (r *GitRepo) Parents(commit string) ([]string, error) {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "rev-list", "--parents", "-n", "1", commit)
+	output, err := r.runner().Output(cmd)
+	if err != nil {
+		return nil, internal.NewGitError("rev-list", r.Path, err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return nil, internal.NewGitError("rev-list", r.Path, fmt.Errorf("commit %s not found", commit))
+	}
+	return fields[1:], nil
+}
+
+// RevertOnMain reverts commit in a detached worktree checked out at the main
+// branch's tip, and moves main to the revert if main has not moved since
+// Merge commits are reverted against their first parent
+func (r *GitRepo) RevertOnMain(worktreePath, commit string) (string, error) {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return "", err
+	}
+	base, err := r.revParse(worktreePath, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	parents, err := r.revParse(worktreePath, commit+"^@")
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"-c", "user.name=Amp Orchestrator", "-c", "user.email=orchestrator@localhost", "revert", "--no-edit"}
+	if len(strings.Fields(parents)) > 1 {
+		args = append(args, "-m", "1")
+	}
+	cmd := command.Context(r.context(), "git", append(args, commit)...)
+	cmd.Dir = worktreePath
+	if output, err := r.runner().CombinedOutput(cmd); err != nil {
+		abort := command.Context(context.WithoutCancel(r.context()), "git", "revert", "--abort")
+		abort.Dir = worktreePath
+		r.runner().Run(abort)
+		return "", internal.NewGitError("revert", worktreePath, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output))))
+	}
+
+	reverted, err := r.revParse(worktreePath, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if err := r.UpdateRef("refs/heads/"+mainBranch, reverted, base); err != nil {
+		return "", err
+	}
+	return reverted, nil
+}
+
+// revParse resolves a revision in a worktree
+func (r *GitRepo) revParse(worktreePath, rev string) (string, error) {
+	cmd := command.Context(r.context(), "git", "rev-parse", rev)
+	cmd.Dir = worktreePath
+	output, err := r.runner().Output(cmd)
+	if err != nil {
+		return "", internal.NewGitError("rev-parse", worktreePath, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListRefs returns the refs under prefix and the objects they point to
+func (r *GitRepo) ListRefs(prefix string) (map[string]string, error) {
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "for-each-ref", "--format=%(objectname) %(refname)", prefix)
+	output, err := r.runner().Output(cmd)
+	if err != nil {
+		return nil, internal.NewGitError("for-each-ref", r.Path, err)
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if hash, ref, ok := strings.Cut(line, " "); ok {
+			refs[ref] = hash
+		}
+	}
+	return refs, nil
+}
+
+// DiffStat summarises the changes a branch introduces relative to main
+type DiffStat struct {
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+}
+
+// GetDiffStat returns the size of the diff between the main branch and the given branch
+func (r *GitRepo) GetDiffStat(branchName string) (*DiffStat, error) {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "diff", "--shortstat", mainBranch+"..."+branchName)
+	output, err := r.runner().CombinedOutput(cmd)
+	if err != nil {
+		return nil, internal.NewGitError("diff", r.Path,
+			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
+	}
+
+	// Output looks like: " 3 files changed, 45 insertions(+), 2 deletions(-)"
+	stat := &DiffStat{}
+	for _, part := range strings.Split(strings.TrimSpace(string(output)), ",") {
+		var n int
+		var kind string
+		if _, err := fmt.Sscanf(strings.TrimSpace(part), "%d %s", &n, &kind); err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(kind, "file"):
+			stat.FilesChanged = n
+		case strings.HasPrefix(kind, "insertion"):
+			stat.Insertions = n
+		case strings.HasPrefix(kind, "deletion"):
+			stat.Deletions = n
+		}
+	}
+
+	return stat, nil
+}
+
+// GetDiffStatText returns git's per-file summary of the diff between the
+// main branch and the given branch, as printed by git diff --stat
+func (r *GitRepo) GetDiffStatText(branchName string) (string, error) {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "diff", "--stat=100", mainBranch+"..."+branchName)
+	output, err := r.runner().CombinedOutput(cmd)
+	if err != nil {
+		return "", internal.NewGitError("diff", r.Path,
+			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// GetChangedFiles returns the paths changed between the main branch and the given branch
+func (r *GitRepo) GetChangedFiles(branchName string) ([]string, error) {
+	mainBranch, err := r.getMainBranch()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := command.Context(r.context(), "git", "--git-dir", r.Path, "diff", "--name-only", mainBranch+"..."+branchName)
+	output, err := r.runner().CombinedOutput(cmd)
+	if err != nil {
+		return nil, internal.NewGitError("diff", r.Path,
+			fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output))))
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
@@ -1,12 +1,16 @@
 package gitutils
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/brettsmith212/amp-orchestrator/pkg/command"
 )
 
 func TestAddWorktree(t *testing.T) {
@@ -278,6 +282,75 @@ func TestListBranches(t *testing.T) {
 	}
 }
 
+func TestFindAgentBranch(t *testing.T) {
+	branches := []string{"main", "agent-1/feat-a", "agent-2/feat-b", "bench/x/feat-a"}
+
+	if got := FindAgentBranch(branches, "feat-b"); got != "agent-2/feat-b" {
+		t.Errorf("Expected agent-2/feat-b, got %q", got)
+	}
+	if got := FindAgentBranch(branches, "feat-c"); got != "" {
+		t.Errorf("Expected no branch for unknown ticket, got %q", got)
+	}
+
+	retried := []string{"agent-1/feat-a-attempt-3", "agent-1/feat-a", "agent-2/feat-a-attempt-10", "agent-1/feat-a-attempt-x"}
+	if got := FindAgentBranch(retried, "feat-a"); got != "agent-2/feat-a-attempt-10" {
+		t.Errorf("Expected the latest attempt branch, got %q", got)
+	}
+
+	tied := []string{"agent-1/feat-a", "agent-3/feat-a-attempt-2", "agent-2/feat-a-attempt-2"}
+	if got := FindAgentBranches(tied, "feat-a"); !slices.Equal(got, []string{"agent-3/feat-a-attempt-2", "agent-2/feat-a-attempt-2"}) {
+		t.Errorf("Expected both attempt-2 branches, got %v", got)
+	}
+	if got := FindAgentBranches(branches, "feat-c"); len(got) != 0 {
+		t.Errorf("Expected no branches for unknown ticket, got %v", got)
+	}
+}
+
+func TestResetBranch(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	mainCommit, err := repo.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("Failed to resolve main: %v", err)
+	}
+
+	worktreePath := filepath.Join(tmpDir, "worktree")
+	if _, err := repo.AddWorktree(worktreePath, "agent-1/feat-x"); err != nil {
+		t.Fatalf("Failed to add worktree: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktreePath, "old.txt"), []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := repo.CommitFile(worktreePath, "old.txt", "Old attempt"); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	if err := repo.RemoveWorktree(worktreePath); err != nil {
+		t.Fatalf("Failed to remove worktree: %v", err)
+	}
+
+	if err := repo.ResetBranch("agent-1/feat-x"); err != nil {
+		t.Fatalf("Failed to reset branch: %v", err)
+	}
+	if commit, _ := repo.GetBranchCommit("agent-1/feat-x"); commit != mainCommit {
+		t.Errorf("Expected branch reset to %s, got %s", mainCommit, commit)
+	}
+
+	attempt := AttemptBranch("agent-1/feat-x", 2)
+	if attempt != "agent-1/feat-x-attempt-2" {
+		t.Errorf("Unexpected attempt branch %q", attempt)
+	}
+	if _, err := repo.AddWorktree(worktreePath, attempt); err != nil {
+		t.Errorf("Expected an attempt branch next to the original, got %v", err)
+	}
+}
+
 func TestRemoveWorktree(t *testing.T) {
 	// Create a temporary directory for the test
 	tmpDir := t.TempDir()
@@ -402,4 +475,388 @@ func TestIntegrationAddWorktreeAndCommit(t *testing.T) {
 	if err := repo.RemoveWorktree(worktreePath); err != nil {
 		t.Errorf("Failed to clean up worktree: %v", err)
 	}
-}
\ No newline at end of file
+}
+func TestGetDiffStat(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+
+	repo := NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	worktreePath := filepath.Join(tmpDir, "worktree")
+	branchName := "agent-1/diff-stat"
+	if _, err := repo.AddWorktree(worktreePath, branchName); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	defer repo.RemoveWorktree(worktreePath)
+
+	content := "line one\nline two\nline three\n"
+	if err := os.WriteFile(filepath.Join(worktreePath, "new.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := repo.CommitFile(worktreePath, "new.txt", "Add new file"); err != nil {
+		t.Fatalf("CommitFile failed: %v", err)
+	}
+
+	stat, err := repo.GetDiffStat(branchName)
+	if err != nil {
+		t.Fatalf("GetDiffStat failed: %v", err)
+	}
+
+	if stat.FilesChanged != 1 {
+		t.Errorf("Expected 1 file changed, got %d", stat.FilesChanged)
+	}
+	if stat.Insertions != 3 {
+		t.Errorf("Expected 3 insertions, got %d", stat.Insertions)
+	}
+	if stat.Deletions != 0 {
+		t.Errorf("Expected 0 deletions, got %d", stat.Deletions)
+	}
+
+	text, err := repo.GetDiffStatText(branchName)
+	if err != nil {
+		t.Fatalf("GetDiffStatText failed: %v", err)
+	}
+	if !strings.Contains(text, "new.txt | 3 +++") || !strings.Contains(text, "1 file changed, 3 insertions(+)") {
+		t.Errorf("Unexpected diffstat:\n%s", text)
+	}
+
+	files, err := repo.GetChangedFiles(branchName)
+	if err != nil {
+		t.Fatalf("GetChangedFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "new.txt" {
+		t.Errorf("Expected new.txt to be the only changed file, got %v", files)
+	}
+}
+
+func TestPushBranch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	worktreePath := filepath.Join(tmpDir, "worktree")
+	branchName := "agent-1/feat-push"
+	if _, err := repo.AddWorktree(worktreePath, branchName); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktreePath, "push.txt"), []byte("push\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, err := repo.CommitFile(worktreePath, "push.txt", "Add push test file"); err != nil {
+		t.Fatalf("CommitFile failed: %v", err)
+	}
+
+	remotePath := filepath.Join(tmpDir, "remote.git")
+	if err := InitBareRepo(remotePath); err != nil {
+		t.Fatalf("Failed to init remote repo: %v", err)
+	}
+
+	if err := repo.PushBranch(remotePath, branchName); err != nil {
+		t.Fatalf("PushBranch failed: %v", err)
+	}
+
+	local, err := repo.GetBranchCommit(branchName)
+	if err != nil {
+		t.Fatalf("Failed to get local commit: %v", err)
+	}
+	pushed, err := NewRepo(remotePath).GetBranchCommit(branchName)
+	if err != nil {
+		t.Fatalf("Branch not found on remote: %v", err)
+	}
+	if pushed != local {
+		t.Errorf("Expected remote branch at %s, got %s", local, pushed)
+	}
+
+	// A rebased branch replaces what this repo pushed before
+	if err := repo.RemoveWorktree(worktreePath); err != nil {
+		t.Fatalf("RemoveWorktree failed: %v", err)
+	}
+	if err := repo.ResetBranch(branchName); err != nil {
+		t.Fatalf("ResetBranch failed: %v", err)
+	}
+	if err := repo.PushBranch(remotePath, branchName); err != nil {
+		t.Fatalf("Expected a rewritten branch to replace our own push, got %v", err)
+	}
+
+	// but not a commit someone else pushed in the meantime
+	if err := exec.Command("git", "--git-dir", remotePath, "update-ref", "refs/heads/"+branchName, local).Run(); err != nil {
+		t.Fatalf("Failed to move remote branch: %v", err)
+	}
+	if err := repo.PushBranch(remotePath, branchName); err == nil {
+		t.Error("Expected push over someone else's commit to be rejected")
+	}
+
+	if err := repo.PushBranch(filepath.Join(tmpDir, "missing.git"), branchName); err == nil {
+		t.Error("Expected error pushing to a missing remote, got nil")
+	}
+}
+
+func TestCloneBareAndFetchBranches(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	originPath := filepath.Join(tmpDir, "origin.git")
+	if err := InitBareRepo(originPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	origin := NewRepo(originPath)
+	if err := origin.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	mirror, err := CloneBare(context.Background(), originPath, filepath.Join(tmpDir, "remote", "repo.git"))
+	if err != nil {
+		t.Fatalf("CloneBare failed: %v", err)
+	}
+	main, err := mirror.getMainBranch()
+	if err != nil {
+		t.Fatalf("Clone has no main branch: %v", err)
+	}
+
+	// A branch added to the origin after cloning arrives with the next fetch
+	worktreePath := filepath.Join(tmpDir, "worktree")
+	if _, err := origin.AddWorktree(worktreePath, "agent-1/feat-fetch"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktreePath, "fetch.txt"), []byte("fetch\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	commit, err := origin.CommitFile(worktreePath, "fetch.txt", "Add fetch test file")
+	if err != nil {
+		t.Fatalf("CommitFile failed: %v", err)
+	}
+
+	// Prefetching downloads the branch without creating it locally
+	if err := mirror.Prefetch(context.Background(), originPath); err != nil {
+		t.Fatalf("Prefetch failed: %v", err)
+	}
+	if prefetched, err := mirror.ResolveRef("refs/prefetch/heads/agent-1/feat-fetch"); err != nil || prefetched != commit {
+		t.Errorf("Expected prefetched ref at %s, got %q (err %v)", commit, prefetched, err)
+	}
+	if exists, _ := mirror.BranchExists("agent-1/feat-fetch"); exists {
+		t.Error("Expected prefetch to leave local branches alone")
+	}
+
+	if err := mirror.FetchBranches(originPath); err != nil {
+		t.Fatalf("FetchBranches failed: %v", err)
+	}
+	if fetched, err := mirror.GetBranchCommit("agent-1/feat-fetch"); err != nil || fetched != commit {
+		t.Errorf("Expected fetched branch at %s, got %s (err %v)", commit, fetched, err)
+	}
+	if _, err := mirror.GetBranchCommit(main); err != nil {
+		t.Errorf("Expected %s to survive the fetch: %v", main, err)
+	}
+
+	if _, err := CloneBare(context.Background(), filepath.Join(tmpDir, "missing.git"), filepath.Join(tmpDir, "other.git")); err == nil {
+		t.Error("Expected error cloning a missing repository, got nil")
+	}
+}
+
+func TestGC(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	// A worktree deleted behind git's back leaves stale metadata
+	worktreePath := filepath.Join(tmpDir, "worktree")
+	if _, err := repo.AddWorktree(worktreePath, "agent-1/feat-gc"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if err := os.RemoveAll(worktreePath); err != nil {
+		t.Fatalf("Failed to remove worktree: %v", err)
+	}
+
+	if err := repo.GC(); err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(repoPath, "worktrees"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to read worktrees directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected stale worktree metadata to be pruned, found %d entries", len(entries))
+	}
+
+	if err := repo.Maintain(context.Background()); err != nil {
+		t.Errorf("Maintain failed: %v", err)
+	}
+}
+
+func TestUpdateRefCompareAndSwap(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "test.git")
+	if err := InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := NewRepo(repoPath)
+	ref := "refs/orchestrator/claims/feat-1"
+
+	if hash, err := repo.ResolveRef(ref); err != nil || hash != "" {
+		t.Fatalf("Expected missing ref, got %q (err %v)", hash, err)
+	}
+
+	first, err := repo.HashObject([]byte("node-a\n"))
+	if err != nil {
+		t.Fatalf("HashObject failed: %v", err)
+	}
+	second, _ := repo.HashObject([]byte("node-b\n"))
+
+	if err := repo.UpdateRef(ref, first, ""); err != nil {
+		t.Fatalf("UpdateRef failed: %v", err)
+	}
+	// Creating it again must fail
+	if err := repo.UpdateRef(ref, second, ""); err == nil {
+		t.Error("Expected creating an existing ref to fail")
+	}
+	// Swapping from a stale value must fail
+	if err := repo.UpdateRef(ref, second, second); err == nil {
+		t.Error("Expected update with the wrong old value to fail")
+	}
+	if err := repo.UpdateRef(ref, second, first); err != nil {
+		t.Fatalf("UpdateRef with the current value failed: %v", err)
+	}
+
+	if hash, _ := repo.ResolveRef(ref); hash != second {
+		t.Errorf("Expected ref at %s, got %s", second, hash)
+	}
+	if data, err := repo.ReadBlob(second); err != nil || string(data) != "node-b\n" {
+		t.Errorf("ReadBlob returned %q (err %v)", data, err)
+	}
+
+	refs, err := repo.ListRefs("refs/orchestrator/claims/")
+	if err != nil {
+		t.Fatalf("ListRefs failed: %v", err)
+	}
+	if len(refs) != 1 || refs[ref] != second {
+		t.Errorf("Unexpected refs %v", refs)
+	}
+}
+
+func TestRecordedRunner(t *testing.T) {
+	// No repository exists; every git call is answered by the recorder
+	runner := command.NewRecorder()
+	runner.Stub("git --git-dir /repos/app.git show-ref", "", &command.ExitStatus{Code: 1})
+	runner.Stub("git --git-dir /repos/app.git show-ref --verify --quiet refs/heads/main", "", nil)
+	runner.Stub("git --git-dir /repos/app.git merge-base --is-ancestor", "", &command.ExitStatus{Code: 128})
+	repo := &GitRepo{Path: "/repos/app.git", Runner: runner}
+
+	exists, err := repo.BranchExists("agent-1/feat-b")
+	if err != nil || exists {
+		t.Errorf("Expected a missing branch for exit code 1, got %v (err %v)", exists, err)
+	}
+	if _, err := repo.IsMerged("abc123"); err == nil {
+		t.Error("Expected an error for exit code 128")
+	}
+
+	calls := runner.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 git calls, got %v", calls)
+	}
+	if want := "git --git-dir /repos/app.git merge-base --is-ancestor abc123 main"; calls[2].String() != want {
+		t.Errorf("Expected %q, got %q", want, calls[2])
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.WithContext(ctx).ListBranches(); err == nil {
+		t.Error("Expected git to fail under a cancelled context")
+	}
+	if _, err := CloneBare(ctx, repoPath, filepath.Join(tmpDir, "clone.git")); err == nil {
+		t.Error("Expected a clone under a cancelled context to fail")
+	}
+
+	// The original repository is unaffected
+	if _, err := repo.ListBranches(); err != nil {
+		t.Errorf("Expected the original repository to keep working, got %v", err)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repoPath := filepath.Join(tmpDir, "test.git")
+	if err := InitBareRepo(repoPath); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	repo := NewRepo(repoPath)
+	if err := repo.CreateInitialCommit(); err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	main, err := repo.getMainBranch()
+	if err != nil {
+		t.Fatalf("No main branch: %v", err)
+	}
+	base, _ := repo.GetBranchCommit(main)
+
+	// Two branches write the same file differently; one of them lands on main
+	commits := map[string]string{}
+	for _, branch := range []string{"agent-1/feat-a", "agent-2/feat-b"} {
+		worktreePath := filepath.Join(tmpDir, filepath.Base(branch))
+		if _, err := repo.AddWorktree(worktreePath, branch); err != nil {
+			t.Fatalf("AddWorktree failed: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(worktreePath, "shared.txt"), []byte(branch+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		commit, err := repo.CommitFile(worktreePath, "shared.txt", "Write shared file on "+branch)
+		if err != nil {
+			t.Fatalf("CommitFile failed: %v", err)
+		}
+		commits[branch] = commit
+	}
+
+	if files, _, err := repo.MergeConflicts("agent-1/feat-a"); err != nil || len(files) != 0 {
+		t.Fatalf("Expected a clean merge into an untouched main, got %v (err %v)", files, err)
+	}
+	if err := repo.UpdateRef("refs/heads/"+main, commits["agent-1/feat-a"], base); err != nil {
+		t.Fatalf("Failed to advance main: %v", err)
+	}
+
+	files, messages, err := repo.MergeConflicts("agent-2/feat-b")
+	if err != nil {
+		t.Fatalf("MergeConflicts failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "shared.txt" {
+		t.Errorf("Expected shared.txt to conflict, got %v", files)
+	}
+	if !strings.Contains(messages, "CONFLICT") {
+		t.Errorf("Expected git's conflict messages, got %q", messages)
+	}
+
+	if _, _, err := repo.MergeConflicts("agent-3/missing"); err == nil {
+		t.Error("Expected an error for a missing branch")
+	}
+}
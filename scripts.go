@@ -0,0 +1,10 @@
+// Package orchestrator ships files kept at the repository root inside the
+// binaries, so there is exactly one copy of each
+package orchestrator
+
+import _ "embed"
+
+// CIScript is ci.sh, which init writes into new projects
+//
+//go:embed ci.sh
+var CIScript string
@@ -25,8 +25,9 @@ echo "$(date): Post-receive hook triggered" >> "$HOOK_LOG"
 while read oldrev newrev refname; do
   echo "$(date): Processing ref update: $oldrev $newrev $refname" >> "$HOOK_LOG"
   
-  # Only run CI for branch updates
-  if [[ $refname == refs/heads/* ]]; then
+  # Run CI for every branch update, main included so its health is tracked;
+  # deleted branches have nothing to test
+  if [[ $refname == refs/heads/* && ! $newrev =~ ^0+$ ]]; then
     branch=$(echo $refname | sed 's|^refs/heads/||')
     echo "$(date): Running CI for $branch..." >> "$HOOK_LOG"
     echo "Running CI for $branch..."